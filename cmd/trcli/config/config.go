@@ -53,7 +53,7 @@ func ParseTransfer(yaml []byte) (*model.Transfer, error) {
 	transfer := transfer(source, target, tr)
 
 	transfer.FillDependentFields()
-	if tr.Transformation != nil && len(tr.Transformation.Transformers) > 0 {
+	if tr.Transformation != nil && (len(tr.Transformation.Transformers) > 0 || tr.Transformation.ErrorsOutput != nil) {
 		transfer.Transformation = &model.Transformation{
 			Transformers:      tr.Transformation,
 			ExtraTransformers: nil,
@@ -163,6 +163,9 @@ func ParseTransferYaml(rawData []byte) (*TransferYamlView, error) {
 				tr[k] = convertMap(v)
 			}
 		}
+		if errorsOutput := transfer.Transformation.ErrorsOutput; errorsOutput != nil {
+			errorsOutput.Config = substituteEnv(convertMap(errorsOutput.Config))
+		}
 	}
 	return &transfer, nil
 }
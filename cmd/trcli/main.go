@@ -10,6 +10,7 @@ import (
 	"github.com/transferia/transferia/cmd/trcli/activate"
 	"github.com/transferia/transferia/cmd/trcli/check"
 	"github.com/transferia/transferia/cmd/trcli/describe"
+	"github.com/transferia/transferia/cmd/trcli/replay"
 	"github.com/transferia/transferia/cmd/trcli/replicate"
 	"github.com/transferia/transferia/cmd/trcli/upload"
 	"github.com/transferia/transferia/cmd/trcli/validate"
@@ -134,6 +135,7 @@ func main() {
 	cobraaux.RegisterCommand(rootCommand, check.CheckCommand())
 	cobraaux.RegisterCommand(rootCommand, replicate.ReplicateCommand(&cp, &rt, registry))
 	cobraaux.RegisterCommand(rootCommand, upload.UploadCommand(&cp, &rt, registry))
+	cobraaux.RegisterCommand(rootCommand, replay.ReplayCommand(&cp, &rt, registry))
 	cobraaux.RegisterCommand(rootCommand, validate.ValidateCommand())
 	cobraaux.RegisterCommand(rootCommand, describe.DescribeCommand())
 
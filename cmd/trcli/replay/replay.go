@@ -0,0 +1,192 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/transferia/transferia/cmd/trcli/config"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/coordinator"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/deadletter"
+	"github.com/transferia/transferia/pkg/middlewares"
+	"github.com/transferia/transferia/pkg/parsers"
+	"github.com/transferia/transferia/pkg/sink"
+	"github.com/transferia/transferia/pkg/stats"
+	"github.com/transferia/transferia/pkg/storage"
+	"go.ytsaurus.tech/library/go/core/log"
+	"gopkg.in/yaml.v3"
+)
+
+// DeadLetters describes the endpoint the dead letter table is read from
+type DeadLetters struct {
+	Type   abstract.ProviderType `yaml:"type"`
+	Params any                   `yaml:"params"`
+	Schema string                `yaml:"schema"`
+	Table  string                `yaml:"table"`
+}
+
+func ReplayCommand(cp *coordinator.Coordinator, rt abstract.Runtime, registry metrics.Registry) *cobra.Command {
+	var transferParams string
+	var deadLettersParams string
+
+	replayCommand := &cobra.Command{
+		Use:     "replay",
+		Short:   "Replay dead letters through the transfer pipeline",
+		Example: "./trcli replay --transfer ./transfer.yaml --dead-letters ./dead_letters.yaml",
+		RunE:    replay(cp, rt, &transferParams, &deadLettersParams, registry),
+	}
+	replayCommand.Flags().StringVar(&transferParams, "transfer", "./transfer.yaml", "path to yaml file with transfer configuration")
+	replayCommand.Flags().StringVar(&deadLettersParams, "dead-letters", "./dead_letters.yaml", "path to yaml file with the source endpoint of dead letters")
+
+	return replayCommand
+}
+
+func replay(cp *coordinator.Coordinator, rt abstract.Runtime, transferYaml, deadLettersYaml *string, registry metrics.Registry) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		transfer, err := config.TransferFromYaml(transferYaml)
+		if err != nil {
+			return xerrors.Errorf("unable to load transfer: %w", err)
+		}
+		transfer.Runtime = rt
+
+		rawData, err := os.ReadFile(*deadLettersYaml)
+		if err != nil {
+			return xerrors.Errorf("unable to read dead letters config file: %w", err)
+		}
+		deadLetters, err := ParseDeadLettersYaml(rawData)
+		if err != nil {
+			return xerrors.Errorf("unable to parse dead letters config file: %w", err)
+		}
+		src, err := deadLetters.Source()
+		if err != nil {
+			return xerrors.Errorf("unable to build dead letters source: %w", err)
+		}
+
+		replayed, err := RunReplay(*cp, transfer, src, deadLetters.TableDescription(), registry)
+		if err != nil {
+			return xerrors.Errorf("replay failed after %d items: %w", replayed, err)
+		}
+		logger.Log.Info("dead letters are replayed", log.Int("count", replayed))
+		return nil
+	}
+}
+
+func ParseDeadLettersYaml(rawData []byte) (*DeadLetters, error) {
+	var deadLetters DeadLetters
+	if err := yaml.Unmarshal(rawData, &deadLetters); err != nil {
+		return nil, xerrors.Errorf("unable to unmarshal yaml: %w", err)
+	}
+	if deadLetters.Type == "" {
+		return nil, xerrors.New("type of dead letters endpoint is required")
+	}
+	if deadLetters.Table == "" {
+		deadLetters.Table = deadletter.DefaultTable
+	}
+	return &deadLetters, nil
+}
+
+// Source builds the endpoint dead letters are read from
+func (d *DeadLetters) Source() (model.Source, error) {
+	params, err := json.Marshal(d.Params)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to marshal params: %w", err)
+	}
+	src, err := model.NewSource(d.Type, string(params))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to make %s source: %w", d.Type, err)
+	}
+	return src, nil
+}
+
+func (d *DeadLetters) TableDescription() abstract.TableDescription {
+	return abstract.TableDescription{Name: d.Table, Schema: d.Schema, Filter: "", EtaRow: 0, Offset: 0}
+}
+
+// RunReplay loads the dead letter table from src & pushes its items through the pipeline of the transfer:
+// items failed to transform are pushed as is, messages failed to parse are parsed again with the parser of the transfer source.
+// It returns the number of replayed dead letters
+func RunReplay(cp coordinator.Coordinator, transfer *model.Transfer, src model.Source, table abstract.TableDescription, registry metrics.Registry) (int, error) {
+	registry = registry.WithTags(map[string]string{
+		"resource_id": transfer.ID,
+		"name":        transfer.TransferName,
+	})
+
+	var parser parsers.Parser
+	if parseable, ok := transfer.Src.(model.Parseable); ok && parseable.Parser() != nil {
+		var err error
+		parser, err = parsers.NewParserFromMap(parseable.Parser(), false, logger.Log, stats.NewSourceStats(registry))
+		if err != nil {
+			return 0, xerrors.Errorf("unable to make parser: %w", err)
+		}
+	}
+
+	dlqTransfer := *transfer
+	dlqTransfer.Src = src
+	dlqStorage, err := storage.NewStorage(&dlqTransfer, cp, registry)
+	if err != nil {
+		return 0, xerrors.Errorf("unable to create storage of dead letters: %w", err)
+	}
+	defer dlqStorage.Close()
+
+	pipeline, err := sink.MakeAsyncSink(transfer, logger.Log, registry, cp, middlewares.MakeConfig(middlewares.AtReplicationStage))
+	if err != nil {
+		return 0, xerrors.Errorf("unable to make sink: %w", err)
+	}
+	defer pipeline.Close()
+
+	replayed := 0
+	err = dlqStorage.LoadTable(context.Background(), table, func(items []abstract.ChangeItem) error {
+		batch := make([]abstract.ChangeItem, 0, len(items))
+		rows := 0
+		for _, item := range items {
+			if !item.IsRowEvent() {
+				continue
+			}
+			rows++
+			row, err := deadletter.ParseRow(item)
+			if err != nil {
+				return xerrors.Errorf("unable to parse dead letter: %w", err)
+			}
+			restored, err := restore(row, parser)
+			if err != nil {
+				return xerrors.Errorf("unable to restore dead letter of %s written at %v: %w", row.Table.Fqtn(), row.Timestamp, err)
+			}
+			batch = append(batch, restored...)
+		}
+		if len(batch) > 0 {
+			if err := <-pipeline.AsyncPush(batch); err != nil {
+				return xerrors.Errorf("unable to push %d items: %w", len(batch), err)
+			}
+		}
+		replayed += rows
+		return nil
+	})
+	if err != nil {
+		return replayed, xerrors.Errorf("unable to load table %s: %w", table.Fqtn(), err)
+	}
+	return replayed, nil
+}
+
+func restore(row *deadletter.Row, parser parsers.Parser) ([]abstract.ChangeItem, error) {
+	switch row.Origin {
+	case deadletter.OriginTransformer:
+		item, err := row.ChangeItem()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to restore item: %w", err)
+		}
+		return []abstract.ChangeItem{*item}, nil
+	case deadletter.OriginParser:
+		if parser == nil {
+			return nil, xerrors.New("source of the transfer has no parser")
+		}
+		return parser.Do(row.Message()), nil
+	default:
+		return nil, xerrors.Errorf("unknown origin %q", row.Origin)
+	}
+}
@@ -42,6 +42,7 @@ const (
 	ParsingFormatLine     = ParsingFormat("LINE")
 	ParsingFormatORC      = ParsingFormat("ORC")
 	ParsingFormatPARQUET  = ParsingFormat("PARQUET")
+	ParsingFormatAVRO     = ParsingFormat("AVRO")
 	ParsingFormatDebezium = ParsingFormat("Debezium")
 	ParsingFormatRaw      = ParsingFormat("RAW")
 )
@@ -0,0 +1,305 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+	"type": "record",
+	"name": "Value",
+	"namespace": "dbserver1.public.users",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "name", "type": ["null", "string"], "default": null},
+		{"name": "balance", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+		{"name": "created_at", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+		{"name": "birthday", "type": ["null", {"type": "int", "logicalType": "date"}]},
+		{"name": "uid", "type": {"type": "string", "logicalType": "uuid"}},
+		{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["ACTIVE", "BLOCKED"]}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "attrs", "type": {"type": "map", "values": "double"}},
+		{"name": "next", "type": ["null", "Value"]}
+	]
+}`
+
+func TestRoundTrip(t *testing.T) {
+	schema, err := ParseSchema(testSchema)
+	require.NoError(t, err)
+	require.Equal(t, "dbserver1.public.users.Value", schema.Name)
+	require.Equal(t, "Value", schema.ShortName())
+	require.Equal(t, "dbserver1.public.users", schema.Namespace())
+	require.Equal(t, "dbserver1.public.users.Status", schema.Fields[6].Type.Name)
+	require.Equal(t, schema, schema.Fields[9].Type.Branches[1]) // recursive reference
+
+	createdAt := time.Date(2024, 5, 1, 12, 30, 0, 123000, time.UTC)
+	birthday := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+	in := map[string]any{
+		"id":         int64(1),
+		"name":       "John",
+		"balance":    "-1234.50",
+		"created_at": createdAt,
+		"birthday":   birthday,
+		"uid":        "4c5a3e4e-8f0a-4b7e-9a1d-0d1f2c3b4a59",
+		"status":     "BLOCKED",
+		"tags":       []any{"a", "b"},
+		"attrs":      map[string]any{"x": 1.5},
+		"next": map[string]any{
+			"id":         json.Number("2"),
+			"balance":    json.Number("0.01"),
+			"created_at": createdAt,
+			"birthday":   nil,
+			"uid":        "",
+			"status":     "ACTIVE",
+			"tags":       []any{},
+			"attrs":      map[string]any{},
+			"next":       nil,
+		},
+	}
+	encoded, err := Encode(schema, in)
+	require.NoError(t, err)
+
+	out, n, err := Decode(schema, append(encoded, 0xff))
+	require.NoError(t, err)
+	require.Equal(t, len(encoded), n)
+
+	outMap := out.(map[string]any)
+	require.Equal(t, int64(1), outMap["id"])
+	require.Equal(t, "John", outMap["name"])
+	require.Equal(t, "-1234.50", outMap["balance"])
+	require.Equal(t, createdAt, outMap["created_at"])
+	require.Equal(t, birthday, outMap["birthday"])
+	require.Equal(t, "BLOCKED", outMap["status"])
+	require.Equal(t, []any{"a", "b"}, outMap["tags"])
+	require.Equal(t, map[string]any{"x": 1.5}, outMap["attrs"])
+
+	next := outMap["next"].(map[string]any)
+	require.Equal(t, int64(2), next["id"])
+	require.Nil(t, next["name"])
+	require.Equal(t, "0.01", next["balance"])
+	require.Nil(t, next["birthday"])
+	require.Nil(t, next["next"])
+}
+
+func TestDecimalFixed(t *testing.T) {
+	schema, err := ParseSchema(`{"type": "fixed", "name": "dec", "size": 8, "logicalType": "decimal", "precision": 18, "scale": 3}`)
+	require.NoError(t, err)
+	for _, val := range []string{"0.000", "1.000", "-1.000", "123456.789", "-999999999.999"} {
+		encoded, err := Encode(schema, val)
+		require.NoError(t, err)
+		require.Len(t, encoded, 8)
+		decoded, _, err := Decode(schema, encoded)
+		require.NoError(t, err)
+		require.Equal(t, val, decoded)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	refs := map[string]string{
+		"com.example.Address": `{"type": "record", "name": "Address", "namespace": "com.example", "fields": [{"name": "city", "type": "string"}, {"name": "country", "type": "com.example.Country"}]}`,
+		"com.example.Country": `{"type": "enum", "name": "Country", "namespace": "com.example", "symbols": ["US", "NL"]}`,
+	}
+	names := NewNames(func(fullName string) (string, error) {
+		return refs[fullName], nil
+	})
+	schema, err := names.Parse(`{"type": "record", "name": "User", "namespace": "com.example", "fields": [{"name": "address", "type": "Address"}]}`)
+	require.NoError(t, err)
+	require.Equal(t, TypeRecord, schema.Fields[0].Type.Type)
+	require.Equal(t, TypeEnum, schema.Fields[0].Type.Fields[1].Type.Type)
+
+	encoded, err := Encode(schema, map[string]any{"address": map[string]any{"city": "Amsterdam", "country": "NL"}})
+	require.NoError(t, err)
+	decoded, _, err := Decode(schema, encoded)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"address": map[string]any{"city": "Amsterdam", "country": "NL"}}, decoded)
+
+	_, err = ParseSchema(`{"type": "record", "name": "User", "fields": [{"name": "address", "type": "Address"}]}`)
+	require.Error(t, err)
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	schema, err := ParseSchema(`{"type": "record", "name": "r", "fields": [{"name": "s", "type": "string"}]}`)
+	require.NoError(t, err)
+	_, _, err = Decode(schema, []byte{0x0a, 'a'})
+	require.Error(t, err)
+}
+
+func TestDecodeCorrupted(t *testing.T) {
+	varint := func(v int64) []byte { return binary.AppendVarint(nil, v) }
+	for _, tc := range []struct {
+		name   string
+		schema string
+		buf    []byte
+	}{
+		{name: "string length overflows position", schema: `"string"`, buf: append(varint(math.MaxInt64), 'a')},
+		{name: "negative string length", schema: `"bytes"`, buf: varint(-1)},
+		{name: "huge block count", schema: `{"type": "array", "items": "long"}`, buf: append(varint(math.MaxInt64), 0)},
+		{name: "huge negative block count", schema: `{"type": "map", "values": "null"}`, buf: append(varint(math.MinInt64), 2, 0)},
+		{name: "block count exceeds buffer", schema: `{"type": "array", "items": "long"}`, buf: append(varint(3), 2, 4)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			schema, err := ParseSchema(tc.schema)
+			require.NoError(t, err)
+			_, _, err = Decode(schema, tc.buf)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestDecodeZeroWidthItems(t *testing.T) {
+	varint := func(v int64) []byte { return binary.AppendVarint(nil, v) }
+	for _, tc := range []struct {
+		name   string
+		schema string
+		item   any
+	}{
+		{name: "nulls", schema: `"null"`, item: nil},
+		{name: "records without fields", schema: `{"type": "record", "name": "empty", "fields": []}`, item: map[string]any{}},
+		{name: "empty fixed", schema: `{"type": "fixed", "name": "empty", "size": 0}`, item: []byte{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			schema, err := ParseSchema(fmt.Sprintf(`{"type": "array", "items": %s}`, tc.schema))
+			require.NoError(t, err)
+			// 5 items in one block, then 3 items in a block with size, all of them take no bytes
+			buf := append(varint(5), varint(-3)...)
+			buf = append(buf, varint(0)...)
+			buf = append(buf, 0)
+			val, n, err := Decode(schema, buf)
+			require.NoError(t, err)
+			require.Equal(t, len(buf), n)
+			require.Len(t, val, 8)
+			for _, item := range val.([]any) {
+				require.Equal(t, tc.item, item)
+			}
+		})
+	}
+}
+
+func TestRawAndProps(t *testing.T) {
+	schema, err := ParseSchema(`{"type": "record", "name": "r", "fields": [
+		{"name": "amount", "aliases": ["amount-value"], "type": {"type": "bytes", "logicalType": "decimal", "precision": 5, "scale": 2, "connect.name": "org.apache.kafka.connect.data.Decimal"}},
+		{"name": "day", "type": {"type": "int", "logicalType": "date"}}
+	]}`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"amount-value"}, schema.Fields[0].Aliases)
+	require.Equal(t, map[string]any{"connect.name": "org.apache.kafka.connect.data.Decimal"}, schema.Fields[0].Type.Props)
+	require.Nil(t, schema.Fields[1].Type.Props)
+
+	// 12.34 -> unscaled 1234 -> 0x04d2 -> "BNI=" in base64
+	encoded, err := EncodeRaw(schema, map[string]any{"amount-value": "BNI=", "day": json.Number("19000")})
+	require.NoError(t, err)
+
+	raw, _, err := DecodeRaw(schema, encoded)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"amount": []byte{0x04, 0xd2}, "day": int32(19000)}, raw)
+
+	decoded, _, err := Decode(schema, encoded)
+	require.NoError(t, err)
+	require.Equal(t, "12.34", decoded.(map[string]any)["amount"])
+	require.Equal(t, time.Date(2022, 1, 8, 0, 0, 0, 0, time.UTC), decoded.(map[string]any)["day"])
+}
+
+func TestOCF(t *testing.T) {
+	rawSchema := `{"type": "record", "name": "r", "fields": [{"name": "id", "type": "long"}, {"name": "name", "type": ["null", "string"]}]}`
+	for _, codec := range []Codec{CodecNull, CodecDeflate, CodecSnappy, CodecZstandard} {
+		t.Run(string(codec), func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := NewOCFWriter(&buf, rawSchema, codec, map[string][]byte{"user-key": []byte("user-value")})
+			require.NoError(t, err)
+			for i := 0; i < 3; i++ {
+				require.NoError(t, writer.Append(map[string]any{"id": int64(i), "name": "a"}))
+			}
+			require.NoError(t, writer.Flush())
+			require.NoError(t, writer.Append(map[string]any{"id": int64(3), "name": nil}))
+			require.NoError(t, writer.Close())
+
+			reader, err := NewOCFReader(&buf)
+			require.NoError(t, err)
+			require.Equal(t, map[string][]byte{"user-key": []byte("user-value")}, reader.Metadata())
+			require.Equal(t, TypeRecord, reader.Schema().Type)
+			var values []any
+			for {
+				val, err := reader.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				values = append(values, val)
+			}
+			require.Len(t, values, 4)
+			require.Equal(t, map[string]any{"id": int64(3), "name": nil}, values[3])
+		})
+	}
+
+	_, err := NewOCFReader(bytes.NewReader([]byte("not avro")))
+	require.Error(t, err)
+}
+
+func TestOCFBlocks(t *testing.T) {
+	rawSchema := `{"type": "record", "name": "r", "fields": [{"name": "id", "type": "long"}]}`
+	var buf bytes.Buffer
+	writer, err := NewOCFWriter(&buf, rawSchema, CodecDeflate, nil)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, writer.Append(map[string]any{"id": int64(i)}))
+		if i%3 == 2 {
+			require.NoError(t, writer.Flush())
+		}
+	}
+	require.NoError(t, writer.Close())
+	file := buf.Bytes()
+	size := int64(len(file))
+
+	reader, err := NewOCFReader(bytes.NewReader(file))
+	require.NoError(t, err)
+	require.Equal(t, reader.HeaderSize(), reader.NextOffset())
+	var offsets []int64
+	for {
+		offset := reader.NextOffset()
+		if _, err := reader.Next(); err == io.EOF {
+			break
+		}
+		if len(offsets) == 0 || offsets[len(offsets)-1] != offset {
+			offsets = append(offsets, offset)
+		}
+	}
+	require.Len(t, offsets, 4)
+	require.Equal(t, size, reader.NextOffset())
+
+	for i, offset := range offsets {
+		found, err := reader.FindBlock(bytes.NewReader(file), size, offset)
+		require.NoError(t, err)
+		require.Equal(t, offset, found)
+		found, err = reader.FindBlock(bytes.NewReader(file), size, offset-1)
+		require.NoError(t, err)
+		require.Equal(t, offset, found)
+		found, err = reader.FindBlock(bytes.NewReader(file), size, offset+1)
+		require.NoError(t, err)
+		if i+1 < len(offsets) {
+			require.Equal(t, offsets[i+1], found)
+		} else {
+			require.Equal(t, size, found)
+		}
+	}
+
+	// the last two blocks are read by the resumed reader
+	resumed := reader.Resume(bytes.NewReader(file[offsets[2]:]), offsets[2])
+	var ids []any
+	for {
+		val, err := resumed.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, val.(map[string]any)["id"])
+	}
+	require.Equal(t, []any{int64(6), int64(7), int64(8), int64(9)}, ids)
+}
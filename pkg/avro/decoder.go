@@ -0,0 +1,323 @@
+package avro
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+)
+
+// Decoded values have the following golang types:
+//   - null: nil
+//   - boolean: bool
+//   - int: int32
+//   - long: int64
+//   - float: float32
+//   - double: float64
+//   - bytes, fixed: []byte
+//   - string, enum: string
+//   - record, map: map[string]any
+//   - array: []any
+//   - union: value of the matched branch
+//
+// Logical types are converted as:
+//   - decimal: string with decimal representation of number (to not lose precision)
+//   - uuid: string
+//   - date, timestamp-*, local-timestamp-*: time.Time in UTC
+//   - time-millis, time-micros: time.Duration
+//
+// DecodeRaw ignores logical types and returns values of the underlying avro types.
+
+type decoder struct {
+	buf []byte
+	pos int
+	raw bool
+}
+
+// Decode decodes one binary-encoded value of schema from buf.
+// Returns decoded value and number of consumed bytes.
+func Decode(schema *Schema, buf []byte) (any, int, error) {
+	return decode(&decoder{buf: buf, pos: 0, raw: false}, schema)
+}
+
+// DecodeRaw is like Decode, but doesn't convert logical types.
+// For example, decimal is returned as bytes with unscaled value, and date - as number of days.
+func DecodeRaw(schema *Schema, buf []byte) (any, int, error) {
+	return decode(&decoder{buf: buf, pos: 0, raw: true}, schema)
+}
+
+func decode(d *decoder, schema *Schema) (any, int, error) {
+	val, err := d.decode(schema)
+	if err != nil {
+		return nil, 0, xerrors.Errorf("unable to decode value at position %d, err: %w", d.pos, err)
+	}
+	return val, d.pos, nil
+}
+
+func (d *decoder) decode(schema *Schema) (any, error) {
+	switch schema.Type {
+	case TypeNull:
+		return nil, nil
+	case TypeBoolean:
+		b, err := d.readByte()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read boolean, err: %w", err)
+		}
+		return b != 0, nil
+	case TypeInt:
+		val, err := d.readLong()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read int, err: %w", err)
+		}
+		if d.raw {
+			return int32(val), nil
+		}
+		return applyIntLogicalType(schema, int32(val)), nil
+	case TypeLong:
+		val, err := d.readLong()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read long, err: %w", err)
+		}
+		if d.raw {
+			return val, nil
+		}
+		return applyLongLogicalType(schema, val), nil
+	case TypeFloat:
+		raw, err := d.readN(4)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read float, err: %w", err)
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(raw)), nil
+	case TypeDouble:
+		raw, err := d.readN(8)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read double, err: %w", err)
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(raw)), nil
+	case TypeBytes:
+		raw, err := d.readBytes()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read bytes, err: %w", err)
+		}
+		if schema.LogicalType == LogicalTypeDecimal && !d.raw {
+			return decimalToString(raw, schema.Scale), nil
+		}
+		return raw, nil
+	case TypeString:
+		raw, err := d.readBytes()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read string, err: %w", err)
+		}
+		return string(raw), nil
+	case TypeFixed:
+		raw, err := d.readN(schema.Size)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read fixed %s, err: %w", schema.Name, err)
+		}
+		if schema.LogicalType == LogicalTypeDecimal && !d.raw {
+			return decimalToString(raw, schema.Scale), nil
+		}
+		result := make([]byte, len(raw))
+		copy(result, raw)
+		return result, nil
+	case TypeEnum:
+		idx, err := d.readLong()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read enum index, err: %w", err)
+		}
+		if idx < 0 || int(idx) >= len(schema.Symbols) {
+			return nil, xerrors.Errorf("enum %s index %d is out of range", schema.Name, idx)
+		}
+		return schema.Symbols[idx], nil
+	case TypeRecord:
+		result := make(map[string]any, len(schema.Fields))
+		for _, field := range schema.Fields {
+			val, err := d.decode(field.Type)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to decode field %s.%s, err: %w", schema.Name, field.Name, err)
+			}
+			result[field.Name] = val
+		}
+		return result, nil
+	case TypeArray:
+		result := make([]any, 0)
+		err := d.readBlocks(zeroWidth(schema.Items, map[*Schema]bool{}), func() error {
+			val, err := d.decode(schema.Items)
+			if err != nil {
+				return xerrors.Errorf("unable to decode array item, err: %w", err)
+			}
+			result = append(result, val)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	case TypeMap:
+		result := make(map[string]any)
+		err := d.readBlocks(false, func() error {
+			key, err := d.readBytes()
+			if err != nil {
+				return xerrors.Errorf("unable to decode map key, err: %w", err)
+			}
+			val, err := d.decode(schema.Values)
+			if err != nil {
+				return xerrors.Errorf("unable to decode map value, err: %w", err)
+			}
+			result[string(key)] = val
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	case TypeUnion:
+		idx, err := d.readLong()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read union index, err: %w", err)
+		}
+		if idx < 0 || int(idx) >= len(schema.Branches) {
+			return nil, xerrors.Errorf("union index %d is out of range", idx)
+		}
+		return d.decode(schema.Branches[idx])
+	default:
+		return nil, xerrors.Errorf("unknown type: %s", schema.Type)
+	}
+}
+
+// readBlocks reads blocks of array or map, calling readItem for every item.
+// Unless items are zero-width, each of them takes at least one byte, so a larger block count means a corrupted buffer.
+func (d *decoder) readBlocks(zeroWidthItems bool, readItem func() error) error {
+	for {
+		count, err := d.readLong()
+		if err != nil {
+			return xerrors.Errorf("unable to read block count, err: %w", err)
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			// negative count is followed by block size in bytes, which we don't need
+			count = -count
+			if _, err := d.readLong(); err != nil {
+				return xerrors.Errorf("unable to read block size, err: %w", err)
+			}
+		}
+		if count < 0 {
+			return xerrors.Errorf("invalid block count %d", count)
+		}
+		if !zeroWidthItems && count > int64(len(d.buf)-d.pos) {
+			return xerrors.Errorf("block count %d exceeds %d remaining bytes", count, len(d.buf)-d.pos)
+		}
+		for i := int64(0); i < count; i++ {
+			if err := readItem(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// zeroWidth checks if values of the schema are encoded into no bytes, like nulls, records without fields or fixed(0)
+func zeroWidth(schema *Schema, visited map[*Schema]bool) bool {
+	switch schema.Type {
+	case TypeNull:
+		return true
+	case TypeFixed:
+		return schema.Size == 0
+	case TypeRecord:
+		if visited[schema] {
+			return false
+		}
+		visited[schema] = true
+		for _, field := range schema.Fields {
+			if !zeroWidth(field.Type, visited) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func (d *decoder) readByte() (byte, error) {
+	if d.pos >= len(d.buf) {
+		return 0, xerrors.New("unexpected end of buffer")
+	}
+	b := d.buf[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *decoder) readN(n int) ([]byte, error) {
+	if n < 0 || n > len(d.buf)-d.pos {
+		return nil, xerrors.Errorf("unexpected end of buffer, need %d bytes, have %d", n, len(d.buf)-d.pos)
+	}
+	result := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return result, nil
+}
+
+func (d *decoder) readLong() (int64, error) {
+	val, n := binary.Varint(d.buf[d.pos:])
+	if n <= 0 {
+		return 0, xerrors.New("invalid varint")
+	}
+	d.pos += n
+	return val, nil
+}
+
+func (d *decoder) readBytes() ([]byte, error) {
+	length, err := d.readLong()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read length, err: %w", err)
+	}
+	raw, err := d.readN(int(length))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]byte, len(raw))
+	copy(result, raw)
+	return result, nil
+}
+
+func applyIntLogicalType(schema *Schema, val int32) any {
+	switch schema.LogicalType {
+	case LogicalTypeDate:
+		return time.Unix(int64(val)*24*60*60, 0).UTC()
+	case LogicalTypeTimeMillis:
+		return time.Duration(val) * time.Millisecond
+	default:
+		return val
+	}
+}
+
+func applyLongLogicalType(schema *Schema, val int64) any {
+	switch schema.LogicalType {
+	case LogicalTypeTimeMicros:
+		return time.Duration(val) * time.Microsecond
+	case LogicalTypeTimestampMillis, LogicalTypeLocalTimestampMillis:
+		return time.UnixMilli(val).UTC()
+	case LogicalTypeTimestampMicros, LogicalTypeLocalTimestampMicros:
+		return time.UnixMicro(val).UTC()
+	case LogicalTypeTimestampNanos, LogicalTypeLocalTimestampNanos:
+		return time.Unix(0, val).UTC()
+	default:
+		return val
+	}
+}
+
+// decimalToString converts big-endian two's-complement unscaled value into decimal string
+func decimalToString(raw []byte, scale int) string {
+	unscaled := new(big.Int).SetBytes(raw)
+	if len(raw) > 0 && raw[0]&0x80 != 0 {
+		// negative number - subtract 2^(8*len)
+		unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(len(raw)*8)))
+	}
+	if scale <= 0 {
+		return unscaled.String()
+	}
+	return new(big.Rat).SetFrac(unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)).FloatString(scale)
+}
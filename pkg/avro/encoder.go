@@ -0,0 +1,395 @@
+package avro
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+)
+
+// Encode encodes val into avro binary format.
+// It accepts values, which Decode returns, as well as values after generic json unmarshalling
+// (json.Number, float64, string with base64 for bytes and so on), so it can be used to convert json documents into avro.
+func Encode(schema *Schema, val any) ([]byte, error) {
+	result, err := AppendEncoded(nil, schema, val)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to encode value, err: %w", err)
+	}
+	return result, nil
+}
+
+// EncodeRaw is like Encode, but ignores logical types - val must contain values of the underlying avro types.
+// It's the opposite of DecodeRaw, for example decimal is expected as unscaled value in bytes (or base64 string).
+func EncodeRaw(schema *Schema, val any) ([]byte, error) {
+	result, err := encoder{raw: true}.append(nil, schema, val)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to encode value, err: %w", err)
+	}
+	return result, nil
+}
+
+// AppendEncoded encodes val and appends it to buf.
+func AppendEncoded(buf []byte, schema *Schema, val any) ([]byte, error) {
+	return encoder{raw: false}.append(buf, schema, val)
+}
+
+type encoder struct {
+	raw bool
+}
+
+func (e encoder) logicalType(schema *Schema) LogicalType {
+	if e.raw {
+		return ""
+	}
+	return schema.LogicalType
+}
+
+func (e encoder) append(buf []byte, schema *Schema, val any) ([]byte, error) {
+	switch schema.Type {
+	case TypeNull:
+		if val != nil {
+			return nil, xerrors.Errorf("expected nil for null type, got %T", val)
+		}
+		return buf, nil
+	case TypeBoolean:
+		b, ok := val.(bool)
+		if !ok {
+			return nil, xerrors.Errorf("expected bool, got %T", val)
+		}
+		if b {
+			return append(buf, 1), nil
+		}
+		return append(buf, 0), nil
+	case TypeInt, TypeLong:
+		num, err := toLong(schema.Type, e.logicalType(schema), val)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to convert %T to %s, err: %w", val, schema.Type, err)
+		}
+		if schema.Type == TypeInt && (num < math.MinInt32 || num > math.MaxInt32) {
+			return nil, xerrors.Errorf("value %d overflows int", num)
+		}
+		return binary.AppendVarint(buf, num), nil
+	case TypeFloat:
+		num, err := toDouble(val)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to convert %T to float, err: %w", val, err)
+		}
+		return binary.LittleEndian.AppendUint32(buf, math.Float32bits(float32(num))), nil
+	case TypeDouble:
+		num, err := toDouble(val)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to convert %T to double, err: %w", val, err)
+		}
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(num)), nil
+	case TypeBytes:
+		raw, err := e.toBytes(schema, val)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to convert %T to bytes, err: %w", val, err)
+		}
+		buf = binary.AppendVarint(buf, int64(len(raw)))
+		return append(buf, raw...), nil
+	case TypeString:
+		var str string
+		switch t := val.(type) {
+		case string:
+			str = t
+		case []byte:
+			str = string(t)
+		case json.Number:
+			str = t.String()
+		case fmt.Stringer:
+			str = t.String()
+		default:
+			return nil, xerrors.Errorf("expected string, got %T", val)
+		}
+		buf = binary.AppendVarint(buf, int64(len(str)))
+		return append(buf, str...), nil
+	case TypeFixed:
+		raw, err := e.toBytes(schema, val)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to convert %T to fixed %s, err: %w", val, schema.Name, err)
+		}
+		if len(raw) != schema.Size {
+			return nil, xerrors.Errorf("fixed %s expects %d bytes, got %d", schema.Name, schema.Size, len(raw))
+		}
+		return append(buf, raw...), nil
+	case TypeEnum:
+		str, ok := val.(string)
+		if !ok {
+			return nil, xerrors.Errorf("expected string for enum %s, got %T", schema.Name, val)
+		}
+		for i, symbol := range schema.Symbols {
+			if symbol == str {
+				return binary.AppendVarint(buf, int64(i)), nil
+			}
+		}
+		return nil, xerrors.Errorf("enum %s doesn't contain symbol %q", schema.Name, str)
+	case TypeRecord:
+		obj, ok := val.(map[string]any)
+		if !ok {
+			return nil, xerrors.Errorf("expected map[string]any for record %s, got %T", schema.Name, val)
+		}
+		var err error
+		for _, field := range schema.Fields {
+			fieldVal, ok := obj[field.Name]
+			for i := 0; !ok && i < len(field.Aliases); i++ {
+				fieldVal, ok = obj[field.Aliases[i]]
+			}
+			if !ok && field.HasDefault {
+				fieldVal = field.Default
+			}
+			buf, err = e.append(buf, field.Type, fieldVal)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to encode field %s.%s, err: %w", schema.Name, field.Name, err)
+			}
+		}
+		return buf, nil
+	case TypeArray:
+		arr, ok := val.([]any)
+		if !ok {
+			return nil, xerrors.Errorf("expected []any for array, got %T", val)
+		}
+		var err error
+		if len(arr) > 0 {
+			buf = binary.AppendVarint(buf, int64(len(arr)))
+			for _, item := range arr {
+				buf, err = e.append(buf, schema.Items, item)
+				if err != nil {
+					return nil, xerrors.Errorf("unable to encode array item, err: %w", err)
+				}
+			}
+		}
+		return binary.AppendVarint(buf, 0), nil
+	case TypeMap:
+		obj, ok := val.(map[string]any)
+		if !ok {
+			return nil, xerrors.Errorf("expected map[string]any for map, got %T", val)
+		}
+		var err error
+		if len(obj) > 0 {
+			buf = binary.AppendVarint(buf, int64(len(obj)))
+			for key, item := range obj {
+				buf = binary.AppendVarint(buf, int64(len(key)))
+				buf = append(buf, key...)
+				buf, err = e.append(buf, schema.Values, item)
+				if err != nil {
+					return nil, xerrors.Errorf("unable to encode map value %s, err: %w", key, err)
+				}
+			}
+		}
+		return binary.AppendVarint(buf, 0), nil
+	case TypeUnion:
+		for i, branch := range schema.Branches {
+			if !isMatched(branch, val) {
+				continue
+			}
+			encoded, err := e.append(binary.AppendVarint(buf, int64(i)), branch, val)
+			if err != nil {
+				continue // try next branch - for example, ["int", "string"] and value "abc"
+			}
+			return encoded, nil
+		}
+		return nil, xerrors.Errorf("value of type %T doesn't match any union branch", val)
+	default:
+		return nil, xerrors.Errorf("unknown type: %s", schema.Type)
+	}
+}
+
+// isMatched is a cheap check, which union branch can hold the value
+func isMatched(schema *Schema, val any) bool {
+	if val == nil {
+		return schema.Type == TypeNull
+	}
+	switch schema.Type {
+	case TypeNull:
+		return false
+	case TypeBoolean:
+		_, ok := val.(bool)
+		return ok
+	case TypeRecord, TypeMap:
+		_, ok := val.(map[string]any)
+		return ok
+	case TypeArray:
+		_, ok := val.([]any)
+		return ok
+	}
+	return true
+}
+
+func toLong(avroType Type, logicalType LogicalType, val any) (int64, error) {
+	switch t := val.(type) {
+	case time.Time:
+		switch logicalType {
+		case LogicalTypeDate:
+			return int64(math.Floor(float64(t.Unix()) / (24 * 60 * 60))), nil
+		case LogicalTypeTimestampMillis, LogicalTypeLocalTimestampMillis:
+			return t.UnixMilli(), nil
+		case LogicalTypeTimestampMicros, LogicalTypeLocalTimestampMicros:
+			return t.UnixMicro(), nil
+		case LogicalTypeTimestampNanos, LogicalTypeLocalTimestampNanos:
+			return t.UnixNano(), nil
+		default:
+			return 0, xerrors.Errorf("time.Time can't be encoded into %s without logical type", avroType)
+		}
+	case time.Duration:
+		switch logicalType {
+		case LogicalTypeTimeMillis:
+			return t.Milliseconds(), nil
+		case LogicalTypeTimeMicros:
+			return t.Microseconds(), nil
+		default:
+			return 0, xerrors.Errorf("time.Duration can't be encoded into %s without logical type", avroType)
+		}
+	case int:
+		return int64(t), nil
+	case int8:
+		return int64(t), nil
+	case int16:
+		return int64(t), nil
+	case int32:
+		return int64(t), nil
+	case int64:
+		return t, nil
+	case uint:
+		return int64(t), nil
+	case uint8:
+		return int64(t), nil
+	case uint16:
+		return int64(t), nil
+	case uint32:
+		return int64(t), nil
+	case uint64:
+		if t > math.MaxInt64 {
+			return 0, xerrors.Errorf("value %d overflows long", t)
+		}
+		return int64(t), nil
+	case float64:
+		if t != math.Trunc(t) {
+			return 0, xerrors.Errorf("value %v is not an integer", t)
+		}
+		return int64(t), nil
+	case json.Number:
+		return t.Int64()
+	default:
+		return 0, xerrors.Errorf("unsupported type %T", val)
+	}
+}
+
+func toDouble(val any) (float64, error) {
+	switch t := val.(type) {
+	case float32:
+		return float64(t), nil
+	case float64:
+		return t, nil
+	case json.Number:
+		return t.Float64()
+	case int:
+		return float64(t), nil
+	case int32:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	case uint32:
+		return float64(t), nil
+	case uint64:
+		return float64(t), nil
+	default:
+		return 0, xerrors.Errorf("unsupported type %T", val)
+	}
+}
+
+func (e encoder) toBytes(schema *Schema, val any) ([]byte, error) {
+	if e.logicalType(schema) == LogicalTypeDecimal {
+		if raw, ok := val.([]byte); ok {
+			return raw, nil
+		}
+		raw, err := decimalToBytes(val, schema.Scale)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to encode decimal, err: %w", err)
+		}
+		if schema.Type == TypeFixed {
+			return padTwosComplement(raw, schema.Size)
+		}
+		return raw, nil
+	}
+	switch t := val.(type) {
+	case []byte:
+		return t, nil
+	case string:
+		// bytes in json documents are base64-encoded
+		raw, err := base64.StdEncoding.DecodeString(t)
+		if err != nil {
+			return []byte(t), nil
+		}
+		return raw, nil
+	default:
+		return nil, xerrors.Errorf("unsupported type %T", val)
+	}
+}
+
+// decimalToBytes converts number into big-endian two's-complement representation of unscaled value
+func decimalToBytes(val any, scale int) ([]byte, error) {
+	rat := new(big.Rat)
+	switch t := val.(type) {
+	case string:
+		if _, ok := rat.SetString(t); !ok {
+			return nil, xerrors.Errorf("invalid decimal: %s", t)
+		}
+	case json.Number:
+		if _, ok := rat.SetString(t.String()); !ok {
+			return nil, xerrors.Errorf("invalid decimal: %s", t.String())
+		}
+	case float64:
+		rat.SetFloat64(t)
+	case int64:
+		rat.SetInt64(t)
+	case int:
+		rat.SetInt64(int64(t))
+	default:
+		return nil, xerrors.Errorf("unsupported type %T", val)
+	}
+	scaled := rat.Mul(rat, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	unscaled := new(big.Int).Quo(scaled.Num(), scaled.Denom())
+	return bigIntToTwosComplement(unscaled), nil
+}
+
+func bigIntToTwosComplement(in *big.Int) []byte {
+	if in.Sign() >= 0 {
+		raw := in.Bytes()
+		if len(raw) == 0 || raw[0]&0x80 != 0 {
+			raw = append([]byte{0}, raw...)
+		}
+		return raw
+	}
+	// for negative numbers: 2^(8*n) + in, where n is enough to hold the value with sign bit
+	n := len(in.Bytes()) + 1
+	raw := new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), uint(n*8)), in).Bytes()
+	for len(raw) < n {
+		raw = append([]byte{0xff}, raw...)
+	}
+	for len(raw) > 1 && raw[0] == 0xff && raw[1]&0x80 != 0 {
+		raw = raw[1:]
+	}
+	return raw
+}
+
+func padTwosComplement(raw []byte, size int) ([]byte, error) {
+	if len(raw) > size {
+		return nil, xerrors.Errorf("decimal needs %d bytes, but fixed size is %d", len(raw), size)
+	}
+	padding := byte(0)
+	if len(raw) > 0 && raw[0]&0x80 != 0 {
+		padding = 0xff
+	}
+	result := make([]byte, size)
+	for i := 0; i < size-len(raw); i++ {
+		result[i] = padding
+	}
+	copy(result[size-len(raw):], raw)
+	return result, nil
+}
@@ -0,0 +1,464 @@
+package avro
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+)
+
+// Object container files: https://avro.apache.org/docs/1.11.1/specification/#object-container-files
+
+type Codec string
+
+const (
+	CodecNull      Codec = "null"
+	CodecDeflate   Codec = "deflate"
+	CodecSnappy    Codec = "snappy"
+	CodecZstandard Codec = "zstandard"
+)
+
+const (
+	ocfSyncSize          = 16
+	ocfDefaultBlockBytes = 1024 * 1024
+	ocfFindBufferSize    = 64 * 1024
+
+	ocfMetaSchema = "avro.schema"
+	ocfMetaCodec  = "avro.codec"
+)
+
+var ocfMagic = []byte{'O', 'b', 'j', 1}
+
+// metadata of container file is encoded as avro map of bytes
+var ocfMetaSchemaNode = &Schema{Type: TypeMap, Values: &Schema{Type: TypeBytes}}
+
+// OCFWriter writes values into avro object container file.
+type OCFWriter struct {
+	w      io.Writer
+	schema *Schema
+	codec  Codec
+	sync   [ocfSyncSize]byte
+
+	block      []byte
+	blockCount int64
+}
+
+// Append encodes value into the current block, block is flushed when it's big enough
+func (w *OCFWriter) Append(val any) error {
+	var err error
+	w.block, err = AppendEncoded(w.block, w.schema, val)
+	if err != nil {
+		return xerrors.Errorf("unable to encode value: %w", err)
+	}
+	w.blockCount++
+	if len(w.block) >= ocfDefaultBlockBytes {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes the current block
+func (w *OCFWriter) Flush() error {
+	if w.blockCount == 0 {
+		return nil
+	}
+	compressed, err := compressBlock(w.codec, w.block)
+	if err != nil {
+		return xerrors.Errorf("unable to compress block: %w", err)
+	}
+	header := binary.AppendVarint(nil, w.blockCount)
+	header = binary.AppendVarint(header, int64(len(compressed)))
+	for _, part := range [][]byte{header, compressed, w.sync[:]} {
+		if _, err := w.w.Write(part); err != nil {
+			return xerrors.Errorf("unable to write block: %w", err)
+		}
+	}
+	w.block = w.block[:0]
+	w.blockCount = 0
+	return nil
+}
+
+// Close flushes the last block, underlying writer is not closed
+func (w *OCFWriter) Close() error {
+	return w.Flush()
+}
+
+// NewOCFWriter writes file header & returns writer.
+// meta contains user metadata, which is stored in file header along with schema & codec.
+func NewOCFWriter(w io.Writer, rawSchema string, codec Codec, meta map[string][]byte) (*OCFWriter, error) {
+	schema, err := ParseSchema(rawSchema)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to parse schema: %w", err)
+	}
+	if codec == "" {
+		codec = CodecNull
+	}
+	if !isKnownCodec(codec) {
+		return nil, xerrors.Errorf("unsupported codec: %s", codec)
+	}
+
+	writer := &OCFWriter{
+		w:          w,
+		schema:     schema,
+		codec:      codec,
+		sync:       [ocfSyncSize]byte{},
+		block:      nil,
+		blockCount: 0,
+	}
+	if _, err := rand.Read(writer.sync[:]); err != nil {
+		return nil, xerrors.Errorf("unable to generate sync marker: %w", err)
+	}
+
+	headerMeta := make(map[string]any, len(meta)+2)
+	for k, v := range meta {
+		headerMeta[k] = v
+	}
+	headerMeta[ocfMetaSchema] = []byte(rawSchema)
+	headerMeta[ocfMetaCodec] = []byte(codec)
+	header, err := AppendEncoded(append([]byte{}, ocfMagic...), ocfMetaSchemaNode, headerMeta)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to encode header: %w", err)
+	}
+	header = append(header, writer.sync[:]...)
+	if _, err := w.Write(header); err != nil {
+		return nil, xerrors.Errorf("unable to write header: %w", err)
+	}
+	return writer, nil
+}
+
+// OCFReader reads values from avro object container file.
+type OCFReader struct {
+	r      *ocfInput
+	schema *Schema
+	codec  Codec
+	meta   map[string][]byte
+	sync   [ocfSyncSize]byte
+	raw    bool
+
+	block       []byte
+	blockCount  int64
+	blockOffset int64
+	headerSize  int64
+}
+
+func (r *OCFReader) Schema() *Schema {
+	return r.schema
+}
+
+// Metadata returns user metadata from file header
+func (r *OCFReader) Metadata() map[string][]byte {
+	return r.meta
+}
+
+// SyncMarker returns the marker, which follows the header & every block of the file
+func (r *OCFReader) SyncMarker() []byte {
+	return r.sync[:]
+}
+
+// HeaderSize returns the size of file header, it's the offset of the first block
+func (r *OCFReader) HeaderSize() int64 {
+	return r.headerSize
+}
+
+// NextOffset returns the offset of the block, which contains the value returned by the next call of Next.
+// Blocks are independently readable, so their offsets can be used to read the file by parts, see Resume
+func (r *OCFReader) NextOffset() int64 {
+	if r.blockCount > 0 {
+		return r.blockOffset
+	}
+	return r.r.offset
+}
+
+// Resume returns reader of blocks of the same file, in must be positioned at the start of the block at offset
+func (r *OCFReader) Resume(in io.Reader, offset int64) *OCFReader {
+	return &OCFReader{
+		r:           newOCFInput(in, offset),
+		schema:      r.schema,
+		codec:       r.codec,
+		meta:        r.meta,
+		sync:        r.sync,
+		raw:         r.raw,
+		block:       nil,
+		blockCount:  0,
+		blockOffset: offset,
+		headerSize:  r.headerSize,
+	}
+}
+
+// FindBlock returns the offset of the first block, which starts at or after offset, or size if there is no such block.
+// Blocks are found by the sync marker, which precedes every block
+func (r *OCFReader) FindBlock(in io.ReaderAt, size int64, offset int64) (int64, error) {
+	if offset <= r.headerSize {
+		return r.headerSize, nil
+	}
+	buf := make([]byte, ocfFindBufferSize)
+	for pos := offset - ocfSyncSize; pos < size; pos += int64(len(buf) - ocfSyncSize + 1) {
+		n, err := in.ReadAt(buf, pos)
+		if err != nil && !xerrors.Is(err, io.EOF) {
+			return 0, xerrors.Errorf("unable to read at %d: %w", pos, err)
+		}
+		if idx := bytes.Index(buf[:n], r.sync[:]); idx >= 0 {
+			return pos + int64(idx) + ocfSyncSize, nil
+		}
+		if n < len(buf) {
+			break
+		}
+	}
+	return size, nil
+}
+
+// Next returns the next value, or io.EOF at the end of file
+func (r *OCFReader) Next() (any, error) {
+	for r.blockCount == 0 {
+		if err := r.readBlock(); err != nil {
+			return nil, err
+		}
+	}
+	d := &decoder{buf: r.block, pos: 0, raw: r.raw}
+	val, err := d.decode(r.schema)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to decode value: %w", err)
+	}
+	r.block = r.block[d.pos:]
+	r.blockCount--
+	return val, nil
+}
+
+func (r *OCFReader) readBlock() error {
+	r.blockOffset = r.r.offset
+	count, err := binary.ReadVarint(r.r)
+	if err != nil {
+		if xerrors.Is(err, io.EOF) {
+			return io.EOF
+		}
+		return xerrors.Errorf("unable to read block count: %w", err)
+	}
+	size, err := binary.ReadVarint(r.r)
+	if err != nil {
+		return xerrors.Errorf("unable to read block size: %w", err)
+	}
+	if count < 0 || size < 0 {
+		return xerrors.Errorf("invalid block header: count %d, size %d", count, size)
+	}
+	compressed := make([]byte, size)
+	if _, err := io.ReadFull(r.r, compressed); err != nil {
+		return xerrors.Errorf("unable to read block: %w", err)
+	}
+	var sync [ocfSyncSize]byte
+	if _, err := io.ReadFull(r.r, sync[:]); err != nil {
+		return xerrors.Errorf("unable to read sync marker: %w", err)
+	}
+	if sync != r.sync {
+		return xerrors.New("invalid sync marker")
+	}
+	r.block, err = decompressBlock(r.codec, compressed)
+	if err != nil {
+		return xerrors.Errorf("unable to decompress block: %w", err)
+	}
+	r.blockCount = count
+	return nil
+}
+
+// NewOCFReader reads file header & returns reader
+func NewOCFReader(in io.Reader) (*OCFReader, error) {
+	return newOCFReader(in, false)
+}
+
+// NewOCFReaderRaw is like NewOCFReader, but values are decoded like DecodeRaw does
+func NewOCFReaderRaw(in io.Reader) (*OCFReader, error) {
+	return newOCFReader(in, true)
+}
+
+func newOCFReader(in io.Reader, raw bool) (*OCFReader, error) {
+	r := newOCFInput(in, 0)
+	magic := make([]byte, len(ocfMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, xerrors.Errorf("unable to read magic: %w", err)
+	}
+	if !bytes.Equal(magic, ocfMagic) {
+		return nil, xerrors.New("not an avro object container file")
+	}
+
+	meta := make(map[string][]byte)
+	for {
+		count, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read header: %w", err)
+		}
+		if count == 0 {
+			break
+		}
+		if count < 0 {
+			count = -count
+			if _, err := binary.ReadVarint(r); err != nil {
+				return nil, xerrors.Errorf("unable to read header: %w", err)
+			}
+		}
+		for i := int64(0); i < count; i++ {
+			key, err := readOCFBytes(r)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to read header key: %w", err)
+			}
+			val, err := readOCFBytes(r)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to read header value: %w", err)
+			}
+			meta[string(key)] = val
+		}
+	}
+
+	reader := &OCFReader{
+		r:           r,
+		schema:      nil,
+		codec:       CodecNull,
+		meta:        meta,
+		sync:        [ocfSyncSize]byte{},
+		raw:         raw,
+		block:       nil,
+		blockCount:  0,
+		blockOffset: 0,
+		headerSize:  0,
+	}
+	if _, err := io.ReadFull(r, reader.sync[:]); err != nil {
+		return nil, xerrors.Errorf("unable to read sync marker: %w", err)
+	}
+	reader.headerSize = r.offset
+	reader.blockOffset = r.offset
+	if codec, ok := meta[ocfMetaCodec]; ok && len(codec) > 0 {
+		reader.codec = Codec(codec)
+	}
+	if !isKnownCodec(reader.codec) {
+		return nil, xerrors.Errorf("unsupported codec: %s", reader.codec)
+	}
+	schema, err := ParseSchema(string(meta[ocfMetaSchema]))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to parse schema from header: %w", err)
+	}
+	reader.schema = schema
+	delete(meta, ocfMetaSchema)
+	delete(meta, ocfMetaCodec)
+	return reader, nil
+}
+
+// ocfInput counts consumed bytes to know offsets of blocks
+type ocfInput struct {
+	r      *bufio.Reader
+	offset int64
+}
+
+func (in *ocfInput) Read(p []byte) (int, error) {
+	n, err := in.r.Read(p)
+	in.offset += int64(n)
+	return n, err
+}
+
+func (in *ocfInput) ReadByte() (byte, error) {
+	b, err := in.r.ReadByte()
+	if err == nil {
+		in.offset++
+	}
+	return b, err
+}
+
+func newOCFInput(in io.Reader, offset int64) *ocfInput {
+	return &ocfInput{
+		r:      bufio.NewReader(in),
+		offset: offset,
+	}
+}
+
+func readOCFBytes(r *ocfInput) ([]byte, error) {
+	length, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if length < 0 {
+		return nil, xerrors.Errorf("negative length: %d", length)
+	}
+	result := make([]byte, length)
+	if _, err := io.ReadFull(r, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func isKnownCodec(codec Codec) bool {
+	switch codec {
+	case CodecNull, CodecDeflate, CodecSnappy, CodecZstandard:
+		return true
+	default:
+		return false
+	}
+}
+
+func compressBlock(codec Codec, data []byte) ([]byte, error) {
+	switch codec {
+	case CodecNull:
+		return data, nil
+	case CodecDeflate:
+		var buf bytes.Buffer
+		writer, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CodecSnappy:
+		// snappy block is followed by big-endian crc32 of uncompressed data
+		return binary.BigEndian.AppendUint32(snappy.Encode(nil, data), crc32.ChecksumIEEE(data)), nil
+	case CodecZstandard:
+		encoder, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer encoder.Close()
+		return encoder.EncodeAll(data, nil), nil
+	default:
+		return nil, xerrors.Errorf("unknown codec: %s", codec)
+	}
+}
+
+func decompressBlock(codec Codec, data []byte) ([]byte, error) {
+	switch codec {
+	case CodecNull:
+		return data, nil
+	case CodecDeflate:
+		return io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	case CodecSnappy:
+		if len(data) == 0 {
+			return nil, nil
+		}
+		if len(data) < 4 {
+			return nil, xerrors.New("snappy block is too short")
+		}
+		result, err := snappy.Decode(nil, data[:len(data)-4])
+		if err != nil {
+			return nil, err
+		}
+		if crc32.ChecksumIEEE(result) != binary.BigEndian.Uint32(data[len(data)-4:]) {
+			return nil, xerrors.New("snappy block checksum mismatch")
+		}
+		return result, nil
+	case CodecZstandard:
+		decoder, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		return decoder.DecodeAll(data, nil)
+	default:
+		return nil, xerrors.Errorf("unknown codec: %s", codec)
+	}
+}
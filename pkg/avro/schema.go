@@ -0,0 +1,432 @@
+package avro
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+)
+
+// Avro specification: https://avro.apache.org/docs/1.11.1/specification/
+
+type Type string
+
+const (
+	TypeNull    Type = "null"
+	TypeBoolean Type = "boolean"
+	TypeInt     Type = "int"
+	TypeLong    Type = "long"
+	TypeFloat   Type = "float"
+	TypeDouble  Type = "double"
+	TypeBytes   Type = "bytes"
+	TypeString  Type = "string"
+
+	TypeRecord Type = "record"
+	TypeEnum   Type = "enum"
+	TypeArray  Type = "array"
+	TypeMap    Type = "map"
+	TypeFixed  Type = "fixed"
+	TypeUnion  Type = "union" // not a real avro type name - unions are represented by json arrays
+)
+
+func (t Type) String() string {
+	return string(t)
+}
+
+func (t Type) isPrimitive() bool {
+	switch t {
+	case TypeNull, TypeBoolean, TypeInt, TypeLong, TypeFloat, TypeDouble, TypeBytes, TypeString:
+		return true
+	}
+	return false
+}
+
+type LogicalType string
+
+const (
+	LogicalTypeDecimal              LogicalType = "decimal"
+	LogicalTypeUUID                 LogicalType = "uuid"
+	LogicalTypeDate                 LogicalType = "date"
+	LogicalTypeTimeMillis           LogicalType = "time-millis"
+	LogicalTypeTimeMicros           LogicalType = "time-micros"
+	LogicalTypeTimestampMillis      LogicalType = "timestamp-millis"
+	LogicalTypeTimestampMicros      LogicalType = "timestamp-micros"
+	LogicalTypeTimestampNanos       LogicalType = "timestamp-nanos"
+	LogicalTypeLocalTimestampMillis LogicalType = "local-timestamp-millis"
+	LogicalTypeLocalTimestampMicros LogicalType = "local-timestamp-micros"
+	LogicalTypeLocalTimestampNanos  LogicalType = "local-timestamp-nanos"
+	LogicalTypeDuration             LogicalType = "duration"
+)
+
+// Schema is a parsed avro schema node.
+// Named types (record, enum, fixed) are shared by pointer, so recursive records form a cyclic graph.
+type Schema struct {
+	Type        Type
+	Name        string // fully qualified name, only for named types
+	LogicalType LogicalType
+	Precision   int // decimal only
+	Scale       int // decimal only
+	Size        int // fixed only
+	Symbols     []string
+	Fields      []*Field
+	Items       *Schema   // array only
+	Values      *Schema   // map only
+	Branches    []*Schema // union only
+	Doc         string
+	Props       map[string]any // non-standard attributes, like 'connect.name' from kafka-connect
+}
+
+type Field struct {
+	Name       string
+	Aliases    []string
+	Type       *Schema
+	Default    any
+	HasDefault bool
+	Doc        string
+}
+
+// ShortName returns name without namespace.
+func (s *Schema) ShortName() string {
+	if idx := strings.LastIndex(s.Name, "."); idx != -1 {
+		return s.Name[idx+1:]
+	}
+	return s.Name
+}
+
+// Namespace returns namespace of the named type, or an empty string.
+func (s *Schema) Namespace() string {
+	if idx := strings.LastIndex(s.Name, "."); idx != -1 {
+		return s.Name[:idx]
+	}
+	return ""
+}
+
+// IsNullable returns true for unions, which contain 'null' branch.
+func (s *Schema) IsNullable() bool {
+	if s.Type == TypeNull {
+		return true
+	}
+	if s.Type != TypeUnion {
+		return false
+	}
+	for _, branch := range s.Branches {
+		if branch.Type == TypeNull {
+			return true
+		}
+	}
+	return false
+}
+
+// NotNullBranches returns all union branches except 'null'.
+// For non-union schemas it returns the schema itself.
+func (s *Schema) NotNullBranches() []*Schema {
+	if s.Type != TypeUnion {
+		return []*Schema{s}
+	}
+	result := make([]*Schema, 0, len(s.Branches))
+	for _, branch := range s.Branches {
+		if branch.Type != TypeNull {
+			result = append(result, branch)
+		}
+	}
+	return result
+}
+
+// Resolver is called by Names, when a schema refers to a named type, which is not defined yet.
+// It's used to load schema references (for example, from a schema registry) lazily.
+type Resolver func(fullName string) (string, error)
+
+// Names is a set of named types, which are visible during parsing.
+type Names struct {
+	types    map[string]*Schema
+	resolver Resolver
+}
+
+func (n *Names) lookup(fullName string) (*Schema, error) {
+	if schema, ok := n.types[fullName]; ok {
+		return schema, nil
+	}
+	if n.resolver == nil {
+		return nil, xerrors.Errorf("unknown type: %s", fullName)
+	}
+	rawSchema, err := n.resolver(fullName)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to resolve type %s, err: %w", fullName, err)
+	}
+	if _, err := n.Parse(rawSchema); err != nil {
+		return nil, xerrors.Errorf("unable to parse referenced schema %s, err: %w", fullName, err)
+	}
+	if schema, ok := n.types[fullName]; ok {
+		return schema, nil
+	}
+	return nil, xerrors.Errorf("referenced schema doesn't define type: %s", fullName)
+}
+
+// lookupReference resolves name in the enclosing namespace first, and in the null namespace after that
+func (n *Names) lookupReference(name, namespace string) (*Schema, error) {
+	qualifiedName := fullName(name, namespace)
+	if qualifiedName != name {
+		if schema, ok := n.types[qualifiedName]; ok {
+			return schema, nil
+		}
+		if schema, ok := n.types[name]; ok {
+			return schema, nil
+		}
+	}
+	return n.lookup(qualifiedName)
+}
+
+func (n *Names) define(schema *Schema) error {
+	if _, ok := n.types[schema.Name]; ok {
+		return xerrors.Errorf("type %s is already defined", schema.Name)
+	}
+	n.types[schema.Name] = schema
+	return nil
+}
+
+// Parse parses schema and registers all named types from it.
+func (n *Names) Parse(rawSchema string) (*Schema, error) {
+	var in any
+	if err := json.Unmarshal([]byte(rawSchema), &in); err != nil {
+		return nil, xerrors.Errorf("unable to unmarshal avro schema, err: %w", err)
+	}
+	schema, err := n.parse(in, "")
+	if err != nil {
+		return nil, xerrors.Errorf("unable to parse avro schema, err: %w", err)
+	}
+	return schema, nil
+}
+
+func (n *Names) parse(in any, namespace string) (*Schema, error) {
+	switch t := in.(type) {
+	case string:
+		if Type(t).isPrimitive() {
+			return &Schema{Type: Type(t)}, nil
+		}
+		return n.lookupReference(t, namespace)
+	case []any:
+		branches := make([]*Schema, 0, len(t))
+		for i, el := range t {
+			branch, err := n.parse(el, namespace)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to parse union branch #%d, err: %w", i, err)
+			}
+			if branch.Type == TypeUnion {
+				return nil, xerrors.New("unions may not immediately contain other unions")
+			}
+			branches = append(branches, branch)
+		}
+		return &Schema{Type: TypeUnion, Branches: branches}, nil
+	case map[string]any:
+		return n.parseComplex(t, namespace)
+	default:
+		return nil, xerrors.Errorf("unexpected schema node type: %T", in)
+	}
+}
+
+func (n *Names) parseComplex(in map[string]any, namespace string) (*Schema, error) {
+	typeName, ok := in["type"]
+	if !ok {
+		return nil, xerrors.New("schema object doesn't contain 'type' attribute")
+	}
+	typeStr, ok := typeName.(string)
+	if !ok {
+		// {"type": {"type": "string"}} and {"type": ["null", "string"]} are valid as well
+		return n.parse(typeName, namespace)
+	}
+	logicalType, _ := in["logicalType"].(string)
+	doc, _ := in["doc"].(string)
+	props := extractProps(in)
+
+	switch Type(typeStr) {
+	case TypeRecord, TypeEnum, TypeFixed:
+		name, ok := in["name"].(string)
+		if !ok || name == "" {
+			return nil, xerrors.Errorf("named type %s doesn't contain 'name' attribute", typeStr)
+		}
+		if ns, ok := in["namespace"].(string); ok && !strings.Contains(name, ".") {
+			namespace = ns
+		}
+		schema := &Schema{
+			Type:        Type(typeStr),
+			Name:        fullName(name, namespace),
+			LogicalType: LogicalType(logicalType),
+			Doc:         doc,
+			Props:       props,
+		}
+		if err := n.define(schema); err != nil {
+			return nil, xerrors.Errorf("unable to define type, err: %w", err)
+		}
+		childNamespace := schema.Namespace()
+		switch schema.Type {
+		case TypeRecord:
+			fields, err := n.parseFields(in, childNamespace)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to parse fields of record %s, err: %w", schema.Name, err)
+			}
+			schema.Fields = fields
+		case TypeEnum:
+			symbols, _ := in["symbols"].([]any)
+			for _, symbol := range symbols {
+				symbolStr, ok := symbol.(string)
+				if !ok {
+					return nil, xerrors.Errorf("enum %s contains non-string symbol: %v", schema.Name, symbol)
+				}
+				schema.Symbols = append(schema.Symbols, symbolStr)
+			}
+		case TypeFixed:
+			size, err := intAttribute(in, "size")
+			if err != nil {
+				return nil, xerrors.Errorf("invalid fixed %s, err: %w", schema.Name, err)
+			}
+			schema.Size = size
+			if err := fillDecimalAttributes(schema, in); err != nil {
+				return nil, xerrors.Errorf("invalid fixed %s, err: %w", schema.Name, err)
+			}
+		}
+		return schema, nil
+	case TypeArray:
+		items, ok := in["items"]
+		if !ok {
+			return nil, xerrors.New("array doesn't contain 'items' attribute")
+		}
+		itemsSchema, err := n.parse(items, namespace)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to parse array items, err: %w", err)
+		}
+		return &Schema{Type: TypeArray, Items: itemsSchema, LogicalType: LogicalType(logicalType), Doc: doc, Props: props}, nil
+	case TypeMap:
+		values, ok := in["values"]
+		if !ok {
+			return nil, xerrors.New("map doesn't contain 'values' attribute")
+		}
+		valuesSchema, err := n.parse(values, namespace)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to parse map values, err: %w", err)
+		}
+		return &Schema{Type: TypeMap, Values: valuesSchema, LogicalType: LogicalType(logicalType), Doc: doc, Props: props}, nil
+	default:
+		if !Type(typeStr).isPrimitive() {
+			// reference to named type, written as object
+			return n.lookupReference(typeStr, namespace)
+		}
+		schema := &Schema{Type: Type(typeStr), LogicalType: LogicalType(logicalType), Doc: doc, Props: props}
+		if err := fillDecimalAttributes(schema, in); err != nil {
+			return nil, xerrors.Errorf("invalid %s, err: %w", typeStr, err)
+		}
+		return schema, nil
+	}
+}
+
+func (n *Names) parseFields(in map[string]any, namespace string) ([]*Field, error) {
+	rawFields, ok := in["fields"].([]any)
+	if !ok {
+		return nil, xerrors.New("record doesn't contain 'fields' attribute")
+	}
+	fields := make([]*Field, 0, len(rawFields))
+	for i, rawField := range rawFields {
+		fieldObj, ok := rawField.(map[string]any)
+		if !ok {
+			return nil, xerrors.Errorf("field #%d is not an object", i)
+		}
+		name, ok := fieldObj["name"].(string)
+		if !ok {
+			return nil, xerrors.Errorf("field #%d doesn't contain 'name' attribute", i)
+		}
+		fieldType, ok := fieldObj["type"]
+		if !ok {
+			return nil, xerrors.Errorf("field %s doesn't contain 'type' attribute", name)
+		}
+		fieldSchema, err := n.parse(fieldType, namespace)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to parse type of field %s, err: %w", name, err)
+		}
+		defaultVal, hasDefault := fieldObj["default"]
+		doc, _ := fieldObj["doc"].(string)
+		var aliases []string
+		rawAliases, _ := fieldObj["aliases"].([]any)
+		for _, alias := range rawAliases {
+			if aliasStr, ok := alias.(string); ok {
+				aliases = append(aliases, aliasStr)
+			}
+		}
+		fields = append(fields, &Field{
+			Name:       name,
+			Aliases:    aliases,
+			Type:       fieldSchema,
+			Default:    defaultVal,
+			HasDefault: hasDefault,
+			Doc:        doc,
+		})
+	}
+	return fields, nil
+}
+
+var reservedAttributes = map[string]bool{
+	"type": true, "name": true, "namespace": true, "doc": true, "aliases": true, "logicalType": true,
+	"fields": true, "symbols": true, "default": true, "items": true, "values": true, "size": true,
+	"precision": true, "scale": true,
+}
+
+// extractProps returns attributes of schema object, which are not defined by avro specification
+func extractProps(in map[string]any) map[string]any {
+	var props map[string]any
+	for key, val := range in {
+		if reservedAttributes[key] {
+			continue
+		}
+		if props == nil {
+			props = make(map[string]any)
+		}
+		props[key] = val
+	}
+	return props
+}
+
+func fillDecimalAttributes(schema *Schema, in map[string]any) error {
+	if schema.LogicalType != LogicalTypeDecimal {
+		return nil
+	}
+	precision, err := intAttribute(in, "precision")
+	if err != nil {
+		return xerrors.Errorf("invalid decimal, err: %w", err)
+	}
+	schema.Precision = precision
+	if _, ok := in["scale"]; ok {
+		scale, err := intAttribute(in, "scale")
+		if err != nil {
+			return xerrors.Errorf("invalid decimal, err: %w", err)
+		}
+		schema.Scale = scale
+	}
+	return nil
+}
+
+func intAttribute(in map[string]any, name string) (int, error) {
+	val, ok := in[name].(float64)
+	if !ok {
+		return 0, xerrors.Errorf("attribute '%s' is absent or not a number", name)
+	}
+	return int(val), nil
+}
+
+func fullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// NewNames creates an empty set of named types.
+// resolver may be nil - then all named types must be defined before usage.
+func NewNames(resolver Resolver) *Names {
+	return &Names{
+		types:    make(map[string]*Schema),
+		resolver: resolver,
+	}
+}
+
+// ParseSchema parses a standalone schema.
+func ParseSchema(rawSchema string) (*Schema, error) {
+	return NewNames(nil).Parse(rawSchema)
+}
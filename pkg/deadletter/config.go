@@ -0,0 +1,42 @@
+package deadletter
+
+import (
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/util"
+)
+
+// Config is the config of the destination errors output
+type Config struct {
+	// Type & Params describe the dead letter destination the same way as the destination of a transfer:
+	// Params are the JSON or YAML params of the endpoint, or the destination model itself
+	Type   abstract.ProviderType `json:"type"`
+	Params any                   `json:"params"`
+	// Table is the name of the table dead letters are written to
+	Table string `json:"table"`
+}
+
+// ParseConfig reads the config of the errors output, which is kept untyped in transformation settings
+func ParseConfig(raw any) (*Config, error) {
+	if raw == nil {
+		return nil, xerrors.New("config of dead letter destination is required")
+	}
+	var cfg Config
+	switch c := raw.(type) {
+	case *Config:
+		cfg = *c
+	case Config:
+		cfg = c
+	default:
+		if err := util.MapFromJSON(raw, &cfg); err != nil {
+			return nil, xerrors.Errorf("unable to map %T to dead letter config: %w", raw, err)
+		}
+	}
+	if cfg.Type == "" {
+		return nil, xerrors.New("type of dead letter destination is required")
+	}
+	if cfg.Table == "" {
+		cfg.Table = DefaultTable
+	}
+	return &cfg, nil
+}
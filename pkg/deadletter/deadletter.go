@@ -0,0 +1,255 @@
+// Package deadletter converts items, which are failed to transform or parse, into rows of the dead letter table and back.
+package deadletter
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/changeitem"
+	"github.com/transferia/transferia/pkg/parsers"
+	"github.com/transferia/transferia/pkg/util/castx"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+type Origin string
+
+const (
+	// OriginTransformer rows carry a change item failed to transform, serialized as JSON
+	OriginTransformer = Origin("transformer")
+	// OriginParser rows carry a message failed to parse as is
+	OriginParser = Origin("parser")
+
+	DefaultTable = "dead_letter"
+
+	ColumnTimestamp     = "timestamp"
+	ColumnOrigin        = "origin"
+	ColumnTransformerID = "transformer_id"
+	ColumnError         = "error"
+	ColumnSchema        = "source_schema"
+	ColumnTable         = "source_table"
+	ColumnKind          = "kind"
+	ColumnPosition      = "position"
+	ColumnPayload       = "payload"
+
+	unparsedSuffix        = "_unparsed"
+	unparsedPartitionCol  = "_partition"
+	unparsedOffsetCol     = "_offset"
+	unparsedIdxCol        = "_idx"
+	unparsedPayloadColumn = "unparsed_row"
+	unparsedReasonColumn  = "reason"
+)
+
+var (
+	Columns = []string{
+		ColumnTimestamp,
+		ColumnOrigin,
+		ColumnTransformerID,
+		ColumnError,
+		ColumnSchema,
+		ColumnTable,
+		ColumnKind,
+		ColumnPosition,
+		ColumnPayload,
+	}
+	Schema = abstract.NewTableSchema(abstract.TableColumns{
+		abstract.NewColSchema(ColumnTimestamp, schema.TypeTimestamp, false),
+		abstract.NewColSchema(ColumnOrigin, schema.TypeString, false),
+		abstract.NewColSchema(ColumnTransformerID, schema.TypeString, false),
+		abstract.NewColSchema(ColumnError, schema.TypeString, false),
+		abstract.NewColSchema(ColumnSchema, schema.TypeString, false),
+		abstract.NewColSchema(ColumnTable, schema.TypeString, false),
+		abstract.NewColSchema(ColumnKind, schema.TypeString, false),
+		abstract.NewColSchema(ColumnPosition, schema.TypeString, false),
+		abstract.NewColSchema(ColumnPayload, schema.TypeString, false),
+	})
+)
+
+// Position is a position of a failed item in the source, it is stored as JSON
+type Position struct {
+	Partition *abstract.Partition `json:"partition,omitempty"`
+	Offset    uint64              `json:"offset,omitempty"`
+	Index     int                 `json:"index,omitempty"`
+	LSN       uint64              `json:"lsn,omitempty"`
+	TxID      string              `json:"tx_id,omitempty"`
+}
+
+// Row is a row of the dead letter table
+type Row struct {
+	Timestamp     time.Time
+	Origin        Origin
+	TransformerID string
+	Error         string
+	Table         abstract.TableID
+	Kind          abstract.Kind
+	Position      Position
+	Payload       string
+}
+
+// ChangeItem returns the item failed to transform
+func (r *Row) ChangeItem() (*abstract.ChangeItem, error) {
+	if r.Origin != OriginTransformer {
+		return nil, xerrors.Errorf("%s row carries no change item", r.Origin)
+	}
+	item, err := abstract.UnmarshalChangeItem([]byte(r.Payload))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to unmarshal payload: %w", err)
+	}
+	return item, nil
+}
+
+// Message returns the message failed to parse & its partition
+func (r *Row) Message() (parsers.Message, abstract.Partition) {
+	partition := abstract.Partition{Cluster: "", Partition: 0, Topic: strings.TrimSuffix(r.Table.Name, unparsedSuffix)}
+	if r.Position.Partition != nil {
+		partition = *r.Position.Partition
+	}
+	return parsers.Message{
+		Offset:     r.Position.Offset,
+		SeqNo:      0,
+		Key:        nil,
+		CreateTime: r.Timestamp,
+		WriteTime:  r.Timestamp,
+		Value:      []byte(r.Payload),
+		Headers:    nil,
+	}, partition
+}
+
+func (r *Row) toChangeItem(table string) abstract.ChangeItem {
+	position, _ := json.Marshal(r.Position)
+	values := []interface{}{
+		r.Timestamp,
+		string(r.Origin),
+		r.TransformerID,
+		r.Error,
+		r.Table.Namespace,
+		r.Table.Name,
+		string(r.Kind),
+		string(position),
+		r.Payload,
+	}
+	return abstract.ChangeItem{
+		ID:               0,
+		LSN:              r.Position.LSN,
+		CommitTime:       uint64(r.Timestamp.UnixNano()),
+		Counter:          0,
+		Kind:             abstract.InsertKind,
+		Schema:           "",
+		Table:            table,
+		PartID:           "",
+		ColumnNames:      Columns,
+		ColumnValues:     values,
+		TableSchema:      Schema,
+		OldKeys:          abstract.EmptyOldKeys(),
+		Size:             abstract.RawEventSize(uint64(len(r.Payload))),
+		TxID:             "",
+		Query:            "",
+		QueueMessageMeta: changeitem.QueueMessageMeta{TopicName: "", PartitionNum: 0, Offset: 0, Index: 0},
+	}
+}
+
+// FromTransformerError makes a row of the dead letter table from an item failed to transform
+func FromTransformerError(table string, errRow abstract.TransformerError, transformerID string, now time.Time) abstract.ChangeItem {
+	input := errRow.Input
+	position := Position{Partition: nil, Offset: 0, Index: 0, LSN: input.LSN, TxID: input.TxID}
+	if meta := input.QueueMessageMeta; meta.TopicName != "" {
+		position.Partition = &abstract.Partition{Cluster: "", Partition: uint32(meta.PartitionNum), Topic: meta.TopicName}
+		position.Offset = meta.Offset
+		position.Index = meta.Index
+	}
+	row := Row{
+		Timestamp:     now,
+		Origin:        OriginTransformer,
+		TransformerID: transformerID,
+		Error:         errorText(errRow.Error),
+		Table:         input.TableID(),
+		Kind:          input.Kind,
+		Position:      position,
+		Payload:       input.ToJSONString(),
+	}
+	return row.toChangeItem(table)
+}
+
+// FromUnparsed makes a row of the dead letter table from an item of an unparsed table produced by a parser
+func FromUnparsed(table string, item abstract.ChangeItem, now time.Time) abstract.ChangeItem {
+	values := item.AsMap()
+	position := Position{Partition: nil, Offset: 0, Index: 0, LSN: item.LSN, TxID: item.TxID}
+	if partitionStr, err := castx.ToStringE(values[unparsedPartitionCol]); err == nil {
+		var partition abstract.Partition
+		if err := json.Unmarshal([]byte(partitionStr), &partition); err == nil {
+			position.Partition = &partition
+		}
+	}
+	if offset, ok := values[unparsedOffsetCol].(uint64); ok {
+		position.Offset = offset
+	}
+	if idx, ok := values[unparsedIdxCol].(uint32); ok {
+		position.Index = int(idx)
+	}
+	payload, _ := castx.ToStringE(values[unparsedPayloadColumn])
+	reason, _ := castx.ToStringE(values[unparsedReasonColumn])
+	row := Row{
+		Timestamp:     now,
+		Origin:        OriginParser,
+		TransformerID: "",
+		Error:         reason,
+		Table:         item.TableID(),
+		Kind:          item.Kind,
+		Position:      position,
+		Payload:       payload,
+	}
+	return row.toChangeItem(table)
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// IsUnparsed reports whether an item belongs to an unparsed table of a parser
+func IsUnparsed(item abstract.ChangeItem) bool {
+	return item.IsRowEvent() && parsers.IsUnparsed(item)
+}
+
+// ParseRow reads a row of the dead letter table loaded from any storage, values may be strings or bytes
+func ParseRow(item abstract.ChangeItem) (*Row, error) {
+	values := item.AsMap()
+	str := func(column string) string {
+		result, _ := castx.ToStringE(values[column])
+		return result
+	}
+
+	var row Row
+	switch ts := values[ColumnTimestamp].(type) {
+	case time.Time:
+		row.Timestamp = ts
+	case nil:
+	default:
+		parsed, err := time.Parse(time.RFC3339Nano, str(ColumnTimestamp))
+		if err != nil {
+			return nil, xerrors.Errorf("unable to parse timestamp %v: %w", ts, err)
+		}
+		row.Timestamp = parsed
+	}
+	row.Origin = Origin(str(ColumnOrigin))
+	switch row.Origin {
+	case OriginTransformer, OriginParser:
+	default:
+		return nil, xerrors.Errorf("unknown origin %q", row.Origin)
+	}
+	row.TransformerID = str(ColumnTransformerID)
+	row.Error = str(ColumnError)
+	row.Table = abstract.TableID{Namespace: str(ColumnSchema), Name: str(ColumnTable)}
+	row.Kind = abstract.Kind(str(ColumnKind))
+	if position := str(ColumnPosition); position != "" {
+		if err := json.Unmarshal([]byte(position), &row.Position); err != nil {
+			return nil, xerrors.Errorf("unable to parse position: %w", err)
+		}
+	}
+	row.Payload = str(ColumnPayload)
+	return &row, nil
+}
@@ -0,0 +1,99 @@
+package deadletter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/changeitem"
+	"github.com/transferia/transferia/pkg/deadletter"
+	"github.com/transferia/transferia/pkg/parsers/generic"
+)
+
+func TestTransformerErrorRoundTrip(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	input := abstract.ChangeItemFromMap(map[string]interface{}{"id": "1", "value": "a"}, abstract.NewTableSchema([]abstract.ColSchema{
+		{ColumnName: "id", PrimaryKey: true},
+		{ColumnName: "value"},
+	}), "users", string(abstract.UpdateKind))
+	input.Schema = "public"
+	input.LSN = 17
+	input.QueueMessageMeta = changeitem.QueueMessageMeta{TopicName: "topic", PartitionNum: 2, Offset: 5, Index: 1}
+
+	item := deadletter.FromTransformerError("errors", abstract.TransformerError{Input: input, Error: xerrors.New("boom")}, "mask", now)
+	require.Equal(t, "errors", item.Table)
+	require.Equal(t, deadletter.Columns, item.ColumnNames)
+	require.True(t, item.IsRowEvent())
+	require.False(t, deadletter.IsUnparsed(item))
+
+	row, err := deadletter.ParseRow(item)
+	require.NoError(t, err)
+	require.Equal(t, now, row.Timestamp)
+	require.Equal(t, deadletter.OriginTransformer, row.Origin)
+	require.Equal(t, "mask", row.TransformerID)
+	require.Equal(t, "boom", row.Error)
+	require.Equal(t, abstract.TableID{Namespace: "public", Name: "users"}, row.Table)
+	require.Equal(t, abstract.UpdateKind, row.Kind)
+	require.Equal(t, deadletter.Position{
+		Partition: &abstract.Partition{Cluster: "", Partition: 2, Topic: "topic"},
+		Offset:    5,
+		Index:     1,
+		LSN:       17,
+		TxID:      "",
+	}, row.Position)
+
+	restored, err := row.ChangeItem()
+	require.NoError(t, err)
+	require.Equal(t, input.TableID(), restored.TableID())
+	require.Equal(t, input.Kind, restored.Kind)
+	require.Equal(t, input.AsMap(), restored.AsMap())
+}
+
+func TestUnparsedRoundTrip(t *testing.T) {
+	partition := abstract.Partition{Cluster: "", Partition: 3, Topic: "events"}
+	unparsed := generic.NewUnparsed(partition, "events", "not a json", "unexpected token", 4, 42, time.Now())
+	require.True(t, deadletter.IsUnparsed(unparsed))
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	item := deadletter.FromUnparsed(deadletter.DefaultTable, unparsed, now)
+
+	// storages without timestamps & positional types load values as strings
+	values := item.AsMap()
+	values[deadletter.ColumnTimestamp] = now.Format(time.RFC3339Nano)
+	loaded := abstract.ChangeItemFromMap(values, deadletter.Schema, deadletter.DefaultTable, string(abstract.InsertKind))
+
+	row, err := deadletter.ParseRow(loaded)
+	require.NoError(t, err)
+	require.Equal(t, now, row.Timestamp)
+	require.Equal(t, deadletter.OriginParser, row.Origin)
+	require.Equal(t, "unexpected token", row.Error)
+	require.Equal(t, "not a json", row.Payload)
+
+	_, err = row.ChangeItem()
+	require.Error(t, err)
+
+	msg, msgPartition := row.Message()
+	require.Equal(t, partition, msgPartition)
+	require.Equal(t, uint64(42), msg.Offset)
+	require.Equal(t, []byte("not a json"), msg.Value)
+}
+
+func TestParseRowUnknownOrigin(t *testing.T) {
+	item := abstract.ChangeItemFromMap(map[string]interface{}{deadletter.ColumnOrigin: "unknown"}, deadletter.Schema, deadletter.DefaultTable, string(abstract.InsertKind))
+	_, err := deadletter.ParseRow(item)
+	require.Error(t, err)
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := deadletter.ParseConfig(map[string]interface{}{"type": "kafka", "params": map[string]interface{}{"Topic": "dlq"}})
+	require.NoError(t, err)
+	require.Equal(t, abstract.ProviderType("kafka"), cfg.Type)
+	require.Equal(t, deadletter.DefaultTable, cfg.Table)
+
+	_, err = deadletter.ParseConfig(map[string]interface{}{"params": map[string]interface{}{}})
+	require.Error(t, err)
+	_, err = deadletter.ParseConfig(nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package deadletter
+
+import (
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/stats"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+// Sink writes dead letters into the sink of the dead letter destination
+type Sink struct {
+	sink    abstract.Sinker
+	table   string
+	logger  log.Logger
+	metrics *stats.DeadLetterStats
+}
+
+func (s *Sink) Close() error {
+	return s.sink.Close()
+}
+
+// PushTransformerErrors writes items failed to transform, transformerIDs are IDs of transformers which produced the errors
+func (s *Sink) PushTransformerErrors(errors []abstract.TransformerError, transformerIDs []string) error {
+	now := time.Now()
+	rows := make([]abstract.ChangeItem, len(errors))
+	for i, errRow := range errors {
+		rows[i] = FromTransformerError(s.table, errRow, transformerIDs[i], now)
+	}
+	if err := s.push(rows); err != nil {
+		return xerrors.Errorf("unable to push %d items failed to transform: %w", len(rows), err)
+	}
+	s.metrics.Transformer.Add(int64(len(rows)))
+	return nil
+}
+
+// PushUnparsed writes items of unparsed tables
+func (s *Sink) PushUnparsed(items []abstract.ChangeItem) error {
+	now := time.Now()
+	rows := make([]abstract.ChangeItem, len(items))
+	for i, item := range items {
+		rows[i] = FromUnparsed(s.table, item, now)
+	}
+	if err := s.push(rows); err != nil {
+		return xerrors.Errorf("unable to push %d items failed to parse: %w", len(rows), err)
+	}
+	s.metrics.Parser.Add(int64(len(rows)))
+	return nil
+}
+
+func (s *Sink) push(rows []abstract.ChangeItem) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	st := time.Now()
+	if err := s.sink.Push(rows); err != nil {
+		s.metrics.Failures.Inc()
+		return xerrors.Errorf("dead letter sink failed: %w", err)
+	}
+	s.metrics.Elapsed.RecordDuration(time.Since(st))
+	s.logger.Warn("items are written to dead letter destination", log.Int("len", len(rows)), log.String("table", s.table))
+	return nil
+}
+
+// NewSink wraps the sink of the dead letter destination, the sink is expected to retry pushes by itself
+func NewSink(sink abstract.Sinker, table string, logger log.Logger, registry metrics.Registry) *Sink {
+	return &Sink{
+		sink:    sink,
+		table:   table,
+		logger:  logger,
+		metrics: stats.NewDeadLetterStats(registry),
+	}
+}
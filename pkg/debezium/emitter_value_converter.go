@@ -9,6 +9,7 @@ import (
 	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/abstract"
 	debeziumcommon "github.com/transferia/transferia/pkg/debezium/common"
+	"github.com/transferia/transferia/pkg/debezium/mongo"
 	"github.com/transferia/transferia/pkg/debezium/mysql"
 	"github.com/transferia/transferia/pkg/debezium/packer"
 	debeziumparameters "github.com/transferia/transferia/pkg/debezium/parameters"
@@ -366,18 +367,37 @@ func (m *Emitter) buildSource(changeItem *abstract.ChangeItem, snapshot bool) ma
 		result["row"] = 0
 		result["server_id"] = 0
 		result["thread"] = nil
+	case debeziumparameters.SourceTypeMongo:
+		delete(result, "table")
+		result["db"] = changeItem.Schema
+		result["connector"] = mongo.Connector
+		result["collection"] = changeItem.Table
+		result["ord"] = changeItem.Counter
+		result["h"] = nil
+		result["lsid"] = nil
+		result["txnNumber"] = nil
 	default:
 	}
 	return result
 }
 
+func (m *Emitter) isMongo() bool {
+	return debeziumparameters.GetSourceType(m.connectorParameters) == debeziumparameters.SourceTypeMongo
+}
+
 func (m *Emitter) ToKafkaSchemaKey(changeItem *abstract.ChangeItem, snapshot bool) ([]byte, error) {
-	fieldsKey, err := arrColSchemaToFieldsDescrKeys(changeItem.TableSchema.Columns(), snapshot, m.connectorParameters)
-	if err != nil {
-		return nil, xerrors.Errorf("unable to get field keys: %w", err)
+	var fieldsKey []map[string]interface{}
+	if m.isMongo() {
+		fieldsKey = mongo.KeyFieldsDescr()
+	} else {
+		fieldsKeyDescr, err := arrColSchemaToFieldsDescrKeys(changeItem.TableSchema.Columns(), snapshot, m.connectorParameters)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to get field keys: %w", err)
+		}
+		fieldsKey = fieldsKeyDescr.V
 	}
 	resultMap := map[string]interface{}{
-		"fields":   fieldsKey.V,
+		"fields":   fieldsKey,
 		"name":     fmt.Sprintf("%s.%s.%s.Key", m.databaseServerName, changeItem.Schema, changeItem.Table),
 		"optional": false,
 		"type":     "struct",
@@ -391,6 +411,13 @@ func (m *Emitter) ToKafkaSchemaKey(changeItem *abstract.ChangeItem, snapshot boo
 
 // ToKafkaPayloadKey - generate schema for a key message
 func (m *Emitter) ToKafkaPayloadKey(changeItem *abstract.ChangeItem, snapshot bool, emitType emitType) ([]byte, error) {
+	if m.isMongo() {
+		key, err := mongo.KeyPayload(changeItem)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to make mongo key payload: %w", err)
+		}
+		return util.JSONMarshalUnescape(key)
+	}
 	afterKey, err := m.makeKey(changeItem, emitType == insertEventEmitType, m.ignoreUnknownSources, snapshot)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to make key payload: %w", err)
@@ -400,6 +427,10 @@ func (m *Emitter) ToKafkaPayloadKey(changeItem *abstract.ChangeItem, snapshot bo
 
 // ToKafkaSchemaVal - generate a schema for a val message
 func (m *Emitter) ToKafkaSchemaVal(changeItem *abstract.ChangeItem, snapshot bool) ([]byte, error) {
+	if m.isMongo() {
+		return m.toKafkaSchemaValMongo(changeItem)
+	}
+
 	fieldsVal, err := arrColSchemaToFieldsDescr(changeItem.TableSchema.Columns(), snapshot, m.connectorParameters)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to make fields description: %w", err)
@@ -441,6 +472,28 @@ func (m *Emitter) ToKafkaSchemaVal(changeItem *abstract.ChangeItem, snapshot boo
 	return result, nil
 }
 
+// toKafkaSchemaValMongo - documents of mongo-connector are extended JSON strings, so the schema doesn't depend on the collection
+func (m *Emitter) toKafkaSchemaValMongo(changeItem *abstract.ChangeItem) ([]byte, error) {
+	schemaFields := mongo.ValueFieldsDescr()
+	schemaFields = append(schemaFields, buildSourceSchemaDescr(debeziumparameters.SourceTypeMongo))
+	schemaFields = append(schemaFields, opSchema)
+	schemaFields = append(schemaFields, tsMsSchema)
+	schemaFields = append(schemaFields, transactionSchema)
+
+	resultMap := map[string]interface{}{
+		"type":     "struct",
+		"fields":   schemaFields,
+		"optional": false,
+		"name":     fmt.Sprintf("%s.%s.%s.Envelope", m.databaseServerName, changeItem.Schema, changeItem.Table),
+	}
+
+	result, err := util.JSONMarshalUnescape(resultMap)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to marshal ToKafkaSchemaVal, err: %w", err)
+	}
+	return result, nil
+}
+
 func (m *Emitter) ToKafkaPayloadVal(changeItem *abstract.ChangeItem, payloadTSMS time.Time, snapshot bool, emitType emitType) ([]byte, error) {
 	payloadObj, err := m.valPayload(changeItem, payloadTSMS, snapshot, emitType)
 	if err != nil {
@@ -455,6 +508,23 @@ func (m *Emitter) valPayload(changeItem *abstract.ChangeItem, payloadTSMS time.T
 	if err != nil {
 		return nil, xerrors.Errorf("unsupported kind: %w", err)
 	}
+	tsMs := int64(0)
+	if !payloadTSMS.IsZero() {
+		tsMs = payloadTSMS.UnixNano() / 1000000
+	}
+
+	if m.isMongo() {
+		result, err := mongo.ValuePayload(changeItem, op, m.connectorParameters)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to build mongo values, err: %w", err)
+		}
+		result["source"] = m.buildSource(changeItem, snapshot)
+		result["op"] = op
+		result["ts_ms"] = tsMs
+		result["transaction"] = nil
+		return result, nil
+	}
+
 	var after, before map[string]interface{}
 	if op == "d" {
 		after = nil
@@ -496,10 +566,6 @@ func (m *Emitter) valPayload(changeItem *abstract.ChangeItem, payloadTSMS time.T
 			before = nil
 		}
 	}
-	tsMs := int64(0)
-	if !payloadTSMS.IsZero() {
-		tsMs = payloadTSMS.UnixNano() / 1000000
-	}
 
 	return map[string]interface{}{
 		"before":      before,
@@ -624,7 +690,13 @@ func (m *Emitter) emitOneDebeziumMessage(
 
 // EmitKV - main exported method - generates kafka key & kafka value
 func (m *Emitter) emitKV(changeItem *abstract.ChangeItem, payloadTSMS time.Time, snapshot bool, sessionPackers packer.SessionPackers) ([]debeziumcommon.KeyValue, error) {
-	if changeItem.Kind != abstract.InsertKind && changeItem.Kind != abstract.UpdateKind && changeItem.Kind != abstract.DeleteKind {
+	switch changeItem.Kind {
+	case abstract.InsertKind, abstract.UpdateKind, abstract.DeleteKind:
+	case abstract.MongoUpdateDocumentKind:
+		if !m.isMongo() {
+			return []debeziumcommon.KeyValue{}, nil
+		}
+	default:
 		return []debeziumcommon.KeyValue{}, nil
 	}
 
@@ -1,6 +1,11 @@
 package debezium
 
-import debeziumparameters "github.com/transferia/transferia/pkg/debezium/parameters"
+import (
+	"slices"
+
+	"github.com/transferia/transferia/pkg/debezium/mongo"
+	debeziumparameters "github.com/transferia/transferia/pkg/debezium/parameters"
+)
 
 func buildSourceSchemaDescr(sourceType string) map[string]interface{} {
 	result := map[string]interface{}{
@@ -101,6 +106,35 @@ func buildSourceSchemaDescr(sourceType string) map[string]interface{} {
 				"field":    "thread",
 			},
 		}...)
+	case debeziumparameters.SourceTypeMongo:
+		result["name"] = mongo.SourceSchemaName
+		// mongo-connector has 'collection' instead of 'table', replica set name 'rs' is omitted - it's unknown here
+		fields = slices.DeleteFunc(fields, func(field map[string]interface{}) bool {
+			return field["field"] == "table"
+		})
+		fields = append(fields, []map[string]interface{}{
+			{
+				"type":     "string",
+				"optional": false,
+				"field":    "collection",
+			}, {
+				"type":     "int32",
+				"optional": false,
+				"field":    "ord",
+			}, {
+				"type":     "int64",
+				"optional": true,
+				"field":    "h",
+			}, {
+				"type":     "string",
+				"optional": true,
+				"field":    "lsid",
+			}, {
+				"type":     "int64",
+				"optional": true,
+				"field":    "txnNumber",
+			},
+		}...)
 	}
 	result["fields"] = fields
 	return result
@@ -25,6 +25,8 @@ func kindToOp(kind abstract.Kind, snapshot bool, emitType emitType) (string, err
 		}
 	} else if kind == abstract.DeleteKind {
 		return "d", nil
+	} else if kind == abstract.MongoUpdateDocumentKind {
+		return "u", nil
 	} else {
 		return "", xerrors.Errorf("unsupported kind: %s", kind)
 	}
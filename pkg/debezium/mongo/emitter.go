@@ -0,0 +1,256 @@
+package mongo
+
+import (
+	"encoding/json"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	debeziumparameters "github.com/transferia/transferia/pkg/debezium/parameters"
+	mongocommon "github.com/transferia/transferia/pkg/providers/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Debezium MongoDB connector doesn't describe documents field by field - 'before', 'after' & 'updateDescription.updatedFields'
+// are strings with documents in relaxed extended JSON, so the envelope is the same for every collection
+
+const (
+	Connector        = "mongodb"
+	SourceSchemaName = "io.debezium.connector.mongo.Source"
+
+	KeyField               = "id"
+	UpdateDescriptionField = "updateDescription"
+
+	jsonSchemaName              = "io.debezium.data.Json"
+	updateDescriptionSchemaName = "io.debezium.connector.mongodb.changestream.updatedescription"
+)
+
+func jsonFieldDescr(field string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "string",
+		"optional": true,
+		"name":     jsonSchemaName,
+		"version":  1,
+		"field":    field,
+	}
+}
+
+// KeyFieldsDescr - fields of the 'key' schema: the only field 'id' with '_id' of the document in extended JSON
+func KeyFieldsDescr() []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			"type":     "string",
+			"optional": false,
+			"field":    KeyField,
+		},
+	}
+}
+
+// ValueFieldsDescr - schemas of 'before', 'after' & 'updateDescription'
+func ValueFieldsDescr() []interface{} {
+	updateDescription := map[string]interface{}{
+		"type": "struct",
+		"fields": []map[string]interface{}{
+			{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type":     "string",
+					"optional": false,
+				},
+				"optional": true,
+				"field":    "removedFields",
+			},
+			jsonFieldDescr("updatedFields"),
+			{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "struct",
+					"fields": []map[string]interface{}{
+						{
+							"type":     "string",
+							"optional": false,
+							"field":    "field",
+						}, {
+							"type":     "int32",
+							"optional": false,
+							"field":    "size",
+						},
+					},
+					"optional": false,
+				},
+				"optional": true,
+				"field":    "truncatedArrays",
+			},
+		},
+		"optional": true,
+		"name":     updateDescriptionSchemaName,
+		"version":  1,
+		"field":    UpdateDescriptionField,
+	}
+	return []interface{}{
+		jsonFieldDescr("before"),
+		jsonFieldDescr("after"),
+		updateDescription,
+	}
+}
+
+// KeyPayload - builds 'key' payload
+func KeyPayload(changeItem *abstract.ChangeItem) (map[string]interface{}, error) {
+	id, err := documentID(changeItem)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get document id: %w", err)
+	}
+	idStr, err := idToExtJSON(id)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to serialize document id: %w", err)
+	}
+	return map[string]interface{}{KeyField: idStr}, nil
+}
+
+// ValuePayload - builds 'before', 'after' & 'updateDescription' of the 'val' payload
+//
+// 'before' is always null: mongo change items have no pre-image, the document key of updates & deletes is in the 'key'
+// 'after' holds the full document for inserts & replaces. For updates it's filled only in capture.mode=change_streams_update_full
+func ValuePayload(changeItem *abstract.ChangeItem, op string, connectorParameters map[string]string) (map[string]interface{}, error) {
+	result := map[string]interface{}{
+		"before":               nil,
+		"after":                nil,
+		UpdateDescriptionField: nil,
+	}
+
+	if op == "d" {
+		return result, nil
+	}
+	id, err := documentID(changeItem)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get document id: %w", err)
+	}
+
+	if changeItem.Kind == abstract.MongoUpdateDocumentKind {
+		updateDocument, err := mongocommon.NewUpdateDocumentChangeItem(changeItem)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid change item of kind %s: %w", changeItem.Kind, err)
+		}
+		updateDescription, err := buildUpdateDescription(updateDocument)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to build update description: %w", err)
+		}
+		result[UpdateDescriptionField] = updateDescription
+		if debeziumparameters.GetCaptureMode(connectorParameters) == debeziumparameters.CaptureModeChangeStreams || len(updateDocument.FullDocument()) == 0 {
+			return result, nil
+		}
+		after, err := docToExtJSON(documentWithID(id, updateDocument.FullDocument()))
+		if err != nil {
+			return nil, xerrors.Errorf("unable to serialize full document: %w", err)
+		}
+		result["after"] = after
+		return result, nil
+	}
+
+	doc, err := document(changeItem)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get document: %w", err)
+	}
+	after, err := docToExtJSON(documentWithID(id, doc))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to serialize document: %w", err)
+	}
+	result["after"] = after
+	return result, nil
+}
+
+func buildUpdateDescription(updateDocument *mongocommon.UpdateDocumentChangeItem) (map[string]interface{}, error) {
+	var removedFields []string
+	if len(updateDocument.RemovedFields()) != 0 {
+		removedFields = updateDocument.RemovedFields()
+	}
+
+	var updatedFields *string
+	if len(updateDocument.UpdatedFields()) != 0 {
+		updatedFieldsStr, err := docToExtJSON(updateDocument.UpdatedFields())
+		if err != nil {
+			return nil, xerrors.Errorf("unable to serialize updated fields: %w", err)
+		}
+		updatedFields = &updatedFieldsStr
+	}
+
+	var truncatedArrays []map[string]interface{}
+	for _, el := range updateDocument.TruncatedArrays() {
+		truncatedArrays = append(truncatedArrays, map[string]interface{}{
+			"field": el.Field,
+			"size":  el.NewSize,
+		})
+	}
+
+	return map[string]interface{}{
+		"removedFields":   removedFields,
+		"updatedFields":   updatedFields,
+		"truncatedArrays": truncatedArrays,
+	}, nil
+}
+
+// documentID - '_id' is the only key of mongo change items: deletes carry it in OldKeys, other kinds - in the first column
+func documentID(changeItem *abstract.ChangeItem) (interface{}, error) {
+	if changeItem.Kind == abstract.DeleteKind {
+		for i, keyName := range changeItem.OldKeys.KeyNames {
+			if keyName == mongocommon.ID && i < len(changeItem.OldKeys.KeyValues) {
+				return changeItem.OldKeys.KeyValues[i], nil
+			}
+		}
+		return nil, xerrors.Errorf("column %s is absent in old keys", mongocommon.ID)
+	}
+	idx := changeItem.ColumnNameIndex(mongocommon.ID)
+	if idx < 0 || idx >= len(changeItem.ColumnValues) {
+		return nil, xerrors.Errorf("column %s is absent", mongocommon.ID)
+	}
+	return changeItem.ColumnValues[idx], nil
+}
+
+func document(changeItem *abstract.ChangeItem) (bson.D, error) {
+	idx := changeItem.ColumnNameIndex(mongocommon.Document)
+	if idx < 0 || idx >= len(changeItem.ColumnValues) {
+		return nil, xerrors.Errorf("column %s is absent", mongocommon.Document)
+	}
+	switch v := changeItem.ColumnValues[idx].(type) {
+	case mongocommon.DValue:
+		return v.D, nil
+	case bson.D:
+		return v, nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, xerrors.Errorf("unexpected type %T of column %s", v, mongocommon.Document)
+	}
+}
+
+// documentWithID - documents in mongo change items don't contain '_id', but debezium documents do
+func documentWithID(id interface{}, doc bson.D) bson.D {
+	result := make(bson.D, 0, len(doc)+1)
+	result = append(result, bson.E{Key: mongocommon.ID, Value: id})
+	for _, el := range doc {
+		if el.Key != mongocommon.ID {
+			result = append(result, el)
+		}
+	}
+	return result
+}
+
+func docToExtJSON(doc bson.D) (string, error) {
+	result, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return "", xerrors.Errorf("unable to marshal extended json: %w", err)
+	}
+	return string(result), nil
+}
+
+// idToExtJSON - extended JSON can be built only for documents, so '_id' is serialized within the document and then cut out
+func idToExtJSON(id interface{}) (string, error) {
+	docStr, err := docToExtJSON(documentWithID(id, nil))
+	if err != nil {
+		return "", xerrors.Errorf("unable to serialize document key: %w", err)
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(docStr), &doc); err != nil {
+		return "", xerrors.Errorf("unable to unmarshal document key: %w", err)
+	}
+	return string(doc[mongocommon.ID]), nil
+}
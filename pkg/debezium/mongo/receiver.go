@@ -0,0 +1,176 @@
+package mongo
+
+import (
+	"bytes"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/changeitem"
+	mongocommon "github.com/transferia/transferia/pkg/providers/mongo"
+	"github.com/transferia/transferia/pkg/util/jsonx"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type Source struct {
+	Collection string `json:"collection"`
+	DB         string `json:"db"`
+	Ord        int    `json:"ord"`
+	TSMs       uint64 `json:"ts_ms"`
+}
+
+type TruncatedArray struct {
+	Field string `json:"field"`
+	Size  int    `json:"size"`
+}
+
+type UpdateDescription struct {
+	RemovedFields   []string         `json:"removedFields"`
+	UpdatedFields   *string          `json:"updatedFields"`
+	TruncatedArrays []TruncatedArray `json:"truncatedArrays"`
+}
+
+// Payload - 'payload' of debezium mongo-connector message. It can't be unmarshalled into common payload, bcs documents here are strings
+type Payload struct {
+	After             *string            `json:"after"`
+	Before            *string            `json:"before"`
+	UpdateDescription *UpdateDescription `json:"updateDescription"`
+	Op                string             `json:"op"`
+	Source            Source             `json:"source"`
+}
+
+func UnmarshalPayload(in []byte) (*Payload, error) {
+	var payload Payload
+	if err := jsonx.NewDefaultDecoder(bytes.NewReader(in)).Decode(&payload); err != nil {
+		return nil, xerrors.Errorf("unable to unmarshal payload - err: %w", err)
+	}
+	return &payload, nil
+}
+
+// UnmarshalKeyID - extracts '_id' of the document from the 'key' payload, where it's a value in relaxed extended JSON
+func UnmarshalKeyID(in []byte) (interface{}, error) {
+	var key map[string]*string
+	if err := jsonx.NewDefaultDecoder(bytes.NewReader(in)).Decode(&key); err != nil {
+		return nil, xerrors.Errorf("unable to unmarshal key payload - err: %w", err)
+	}
+	idStr, ok := key[KeyField]
+	if !ok || idStr == nil {
+		return nil, xerrors.Errorf("field '%s' is absent in key payload", KeyField)
+	}
+	docStr := `{"` + mongocommon.ID + `":` + *idStr + `}`
+	doc, err := unmarshalDocument(&docStr)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to unmarshal document key: %w", err)
+	}
+	id, _ := findID(doc)
+	return id, nil
+}
+
+// IsEnvelopeSchema - checks if the 'val' schema is an envelope of the mongo-connector, by the name of 'source' schema
+func IsEnvelopeSchema(schema []byte) bool {
+	return bytes.Contains(schema, []byte(`"`+SourceSchemaName+`"`))
+}
+
+// Receive - builds change item in the form produced by mongo source in homogeneous mode:
+// inserts, replaces & deletes have the document schema, updates with 'updateDescription' are MongoUpdateDocumentKind.
+// keyID is '_id' from the 'key' of the message, it's the only document key of events without 'after', nil if the key is unknown
+func Receive(payload *Payload, keyID interface{}, kind abstract.Kind) (*abstract.ChangeItem, error) {
+	after, err := unmarshalDocument(payload.After)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to unmarshal 'after': %w", err)
+	}
+	before, err := unmarshalDocument(payload.Before)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to unmarshal 'before': %w", err)
+	}
+	id, ok := findID(after)
+	if !ok {
+		id, ok = findID(before)
+	}
+	if !ok && keyID != nil {
+		id, ok = keyID, true
+	}
+	if !ok {
+		return nil, xerrors.Errorf("document key is absent in 'after', 'before' and 'key' of '%s' event", payload.Op)
+	}
+
+	result := &abstract.ChangeItem{
+		ID:               0,
+		LSN:              payload.Source.TSMs / 1000, // seconds of the cluster time
+		CommitTime:       payload.Source.TSMs * 1000000,
+		Counter:          payload.Source.Ord,
+		Kind:             kind,
+		Schema:           payload.Source.DB,
+		Table:            payload.Source.Collection,
+		PartID:           "",
+		ColumnNames:      nil,
+		ColumnValues:     nil,
+		TableSchema:      mongocommon.DocumentSchema.Columns,
+		OldKeys:          *new(abstract.OldKeysType),
+		Size:             abstract.EmptyEventSize(),
+		TxID:             "",
+		Query:            "",
+		QueueMessageMeta: changeitem.QueueMessageMeta{TopicName: "", PartitionNum: 0, Offset: 0, Index: 0},
+	}
+	if kind == abstract.UpdateKind || kind == abstract.DeleteKind {
+		result.OldKeys = abstract.OldKeysType{
+			KeyNames:  []string{mongocommon.ID},
+			KeyTypes:  nil,
+			KeyValues: []interface{}{id},
+		}
+	}
+
+	switch kind {
+	case abstract.InsertKind, abstract.UpdateKind:
+		if kind == abstract.UpdateKind && payload.UpdateDescription != nil {
+			updatedFields, err := unmarshalDocument(payload.UpdateDescription.UpdatedFields)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to unmarshal 'updateDescription.updatedFields': %w", err)
+			}
+			if updatedFields == nil {
+				updatedFields = bson.D{}
+			}
+			removedFields := payload.UpdateDescription.RemovedFields
+			if removedFields == nil {
+				removedFields = []string{}
+			}
+			truncatedArrays := make([]mongocommon.TruncatedArray, 0, len(payload.UpdateDescription.TruncatedArrays))
+			for _, el := range payload.UpdateDescription.TruncatedArrays {
+				truncatedArrays = append(truncatedArrays, mongocommon.TruncatedArray{Field: el.Field, NewSize: el.Size})
+			}
+			result.Kind = abstract.MongoUpdateDocumentKind
+			result.TableSchema = mongocommon.UpdateDocumentSchema.Columns
+			result.ColumnNames = mongocommon.UpdateDocumentSchema.ColumnsNames
+			result.ColumnValues = []interface{}{id, updatedFields, removedFields, truncatedArrays, mongocommon.DExt(after).RawValue()}
+			return result, nil
+		}
+		if after == nil {
+			return nil, xerrors.Errorf("'after' is absent in '%s' event", payload.Op)
+		}
+		result.ColumnNames = mongocommon.DocumentSchema.ColumnsNames
+		result.ColumnValues = []interface{}{id, mongocommon.DExt(after).Value(true, false)}
+	case abstract.DeleteKind:
+	default:
+		return nil, xerrors.Errorf("unsupported kind: %s", kind)
+	}
+	return result, nil
+}
+
+func unmarshalDocument(in *string) (bson.D, error) {
+	if in == nil {
+		return nil, nil
+	}
+	var result bson.D
+	if err := bson.UnmarshalExtJSON([]byte(*in), false, &result); err != nil {
+		return nil, xerrors.Errorf("unable to unmarshal extended json: %w", err)
+	}
+	return result, nil
+}
+
+func findID(doc bson.D) (interface{}, bool) {
+	for _, el := range doc {
+		if el.Key == mongocommon.ID {
+			return el.Value, true
+		}
+	}
+	return nil, false
+}
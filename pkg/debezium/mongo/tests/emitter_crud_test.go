@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"testing"
+
+	debeziumcommon "github.com/transferia/transferia/pkg/debezium/common"
+	debeziumparameters "github.com/transferia/transferia/pkg/debezium/parameters"
+	"github.com/transferia/transferia/pkg/debezium/testutil"
+)
+
+func TestEmitterCRUD(t *testing.T) {
+	source := func(ord string) string {
+		return `{"version":"1.1.2.Final","connector":"mongodb","name":"fulfillment","ts_ms":1700000000000,"snapshot":"false","db":"inventory","collection":"customers","ord":` + ord + `,"h":null,"lsid":null,"txnNumber":null}`
+	}
+
+	testSuite := []debeziumcommon.ChangeItemCanon{
+		{
+			ChangeItem: makeInsert(),
+			DebeziumEvents: []debeziumcommon.KeyValue{{
+				DebeziumKey: canonKey,
+				DebeziumVal: canonVal(`{"before":null,"after":"{\"_id\":{\"$oid\":\"596e275826f08b2730779e1f\"},\"name\":\"Sally\",\"age\":31,\"tags\":[\"a\",\"b\"]}","updateDescription":null,"source":` + source("3") + `,"op":"c","ts_ms":0,"transaction":null}`),
+			}},
+		},
+		{
+			ChangeItem: makeReplace(),
+			DebeziumEvents: []debeziumcommon.KeyValue{{
+				DebeziumKey: canonKey,
+				DebeziumVal: canonVal(`{"before":null,"after":"{\"_id\":{\"$oid\":\"596e275826f08b2730779e1f\"},\"name\":\"Sally\"}","updateDescription":null,"source":` + source("4") + `,"op":"u","ts_ms":0,"transaction":null}`),
+			}},
+		},
+		{
+			ChangeItem: makeUpdateDocument(),
+			DebeziumEvents: []debeziumcommon.KeyValue{{
+				DebeziumKey: canonKey,
+				DebeziumVal: canonVal(`{"before":null,"after":"{\"_id\":{\"$oid\":\"596e275826f08b2730779e1f\"},\"name\":\"Sally\",\"age\":32}","updateDescription":{"removedFields":["tags"],"updatedFields":"{\"age\":32}","truncatedArrays":[{"field":"history","size":2}]},"source":` + source("5") + `,"op":"u","ts_ms":0,"transaction":null}`),
+			}},
+		},
+		{
+			ChangeItem: makeDelete(),
+			DebeziumEvents: []debeziumcommon.KeyValue{
+				{
+					DebeziumKey: canonKey,
+					DebeziumVal: canonVal(`{"before":null,"after":null,"updateDescription":null,"source":` + source("6") + `,"op":"d","ts_ms":0,"transaction":null}`),
+				},
+				{
+					DebeziumKey: canonKey,
+					DebeziumVal: nil,
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testSuite {
+		testutil.CheckCanonizedDebeziumEvent(t, testCase.ChangeItem, "fulfillment", "inventory", debeziumparameters.SourceTypeMongo, false, testCase.DebeziumEvents)
+	}
+}
@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/debezium"
+	debeziumparameters "github.com/transferia/transferia/pkg/debezium/parameters"
+)
+
+func emitPayload(t *testing.T, changeItem *abstract.ChangeItem, additionalParams map[string]string) map[string]interface{} {
+	emitter, err := debezium.NewMessagesEmitter(getParams(additionalParams), "1.1.2.Final", false, logger.Log)
+	require.NoError(t, err)
+	currDebeziumKV, err := emitter.EmitKV(changeItem, time.Time{}, false, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, currDebeziumKV)
+	var result struct {
+		Payload map[string]interface{} `json:"payload"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(*currDebeziumKV[0].DebeziumVal), &result))
+	return result.Payload
+}
+
+func TestCaptureMode(t *testing.T) {
+	t.Run(debeziumparameters.CaptureModeChangeStreams, func(t *testing.T) {
+		params := map[string]string{debeziumparameters.CaptureMode: debeziumparameters.CaptureModeChangeStreams}
+
+		payload := emitPayload(t, makeUpdateDocument(), params)
+		require.Nil(t, payload["after"])
+		require.NotNil(t, payload["updateDescription"])
+
+		// inserts & replaces carry the whole document regardless of the capture mode
+		payload = emitPayload(t, makeInsert(), params)
+		require.NotNil(t, payload["after"])
+		payload = emitPayload(t, makeReplace(), params)
+		require.NotNil(t, payload["after"])
+		require.Nil(t, payload["updateDescription"])
+	})
+
+	t.Run(debeziumparameters.CaptureModeChangeStreamsUpdateFull, func(t *testing.T) {
+		params := map[string]string{debeziumparameters.CaptureMode: debeziumparameters.CaptureModeChangeStreamsUpdateFull}
+
+		payload := emitPayload(t, makeUpdateDocument(), params)
+		require.Equal(t, `{"_id":{"$oid":"596e275826f08b2730779e1f"},"name":"Sally","age":32}`, payload["after"])
+		require.NotNil(t, payload["updateDescription"])
+	})
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/debezium"
+	mongocommon "github.com/transferia/transferia/pkg/providers/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// insert event of vanilla debezium mongo-connector
+var debeziumMsg = `{"schema":{"type":"struct","fields":[{"type":"string","optional":true,"name":"io.debezium.data.Json","version":1,"field":"before"},{"type":"string","optional":true,"name":"io.debezium.data.Json","version":1,"field":"after"},{"type":"struct","fields":[{"type":"array","items":{"type":"string","optional":false},"optional":true,"field":"removedFields"},{"type":"string","optional":true,"name":"io.debezium.data.Json","version":1,"field":"updatedFields"},{"type":"array","items":{"type":"struct","fields":[{"type":"string","optional":false,"field":"field"},{"type":"int32","optional":false,"field":"size"}],"optional":false,"name":"io.debezium.connector.mongodb.changestream.truncatedarray","version":1},"optional":true,"field":"truncatedArrays"}],"optional":true,"name":"io.debezium.connector.mongodb.changestream.updatedescription","version":1,"field":"updateDescription"},{"type":"struct","fields":[{"type":"string","optional":false,"field":"version"},{"type":"string","optional":false,"field":"connector"},{"type":"string","optional":false,"field":"name"},{"type":"int64","optional":false,"field":"ts_ms"},{"type":"string","optional":true,"name":"io.debezium.data.Enum","version":1,"parameters":{"allowed":"true,last,false,incremental"},"default":"false","field":"snapshot"},{"type":"string","optional":false,"field":"db"},{"type":"string","optional":true,"field":"sequence"},{"type":"string","optional":false,"field":"rs"},{"type":"string","optional":false,"field":"collection"},{"type":"int32","optional":false,"field":"ord"},{"type":"string","optional":true,"field":"lsid"},{"type":"int64","optional":true,"field":"txnNumber"},{"type":"int64","optional":true,"field":"wallTime"}],"optional":false,"name":"io.debezium.connector.mongo.Source","field":"source"},{"type":"string","optional":true,"field":"op"},{"type":"int64","optional":true,"field":"ts_ms"},{"type":"struct","fields":[{"type":"string","optional":false,"field":"id"},{"type":"int64","optional":false,"field":"total_order"},{"type":"int64","optional":false,"field":"data_collection_order"}],"optional":true,"name":"event.block","version":1,"field":"transaction"}],"optional":false,"name":"fulfillment.inventory.customers.Envelope"},"payload":{"before":null,"after":"{\"_id\": {\"$oid\": \"596e275826f08b2730779e1f\"}, \"name\": \"Sally\", \"age\": 31, \"updated\": {\"$date\": \"2024-01-02T03:04:05Z\"}}","updateDescription":null,"source":{"version":"2.5.0.Final","connector":"mongodb","name":"fulfillment","ts_ms":1700000000000,"snapshot":"false","db":"inventory","sequence":null,"rs":"rs0","collection":"customers","ord":7,"lsid":null,"txnNumber":null,"wallTime":1700000000123},"op":"c","ts_ms":1700000000456,"transaction":null}}`
+
+func TestReceiveVanillaDebeziumMessage(t *testing.T) {
+	receiver := debezium.NewReceiver(nil, nil)
+	changeItem, err := receiver.Receive(debeziumMsg)
+	require.NoError(t, err)
+
+	require.Equal(t, abstract.InsertKind, changeItem.Kind)
+	require.Equal(t, "inventory", changeItem.Schema)
+	require.Equal(t, "customers", changeItem.Table)
+	require.Equal(t, uint64(1700000000), changeItem.LSN)
+	require.Equal(t, 7, changeItem.Counter)
+	require.True(t, mongocommon.IsNativeMongoSchema(changeItem.TableSchema.Columns()))
+	require.Equal(t, objectID, changeItem.ColumnValues[0])
+	require.Equal(t, bson.D{
+		{Key: "name", Value: "Sally"},
+		{Key: "age", Value: int32(31)},
+		{Key: "updated", Value: primitive.NewDateTimeFromTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))},
+	}, mongocommon.GetDocument(changeItem.ColumnValues))
+}
+
+func TestReceive(t *testing.T) {
+	receiver := debezium.NewReceiver(nil, nil)
+
+	receive := func(t *testing.T, changeItem *abstract.ChangeItem, snapshot bool) (*abstract.ChangeItem, error) {
+		emitter, err := debezium.NewMessagesEmitter(getParams(nil), "1.1.2.Final", false, logger.Log)
+		require.NoError(t, err)
+		currDebeziumKV, err := emitter.EmitKV(changeItem, time.Time{}, snapshot, nil)
+		require.NoError(t, err)
+		return receiver.ReceiveWithKey([]byte(currDebeziumKV[0].DebeziumKey), *currDebeziumKV[0].DebeziumVal)
+	}
+	checkCommon := func(t *testing.T, expected, actual *abstract.ChangeItem) {
+		require.Equal(t, expected.Kind, actual.Kind)
+		require.Equal(t, expected.Schema, actual.Schema)
+		require.Equal(t, expected.Table, actual.Table)
+		require.Equal(t, expected.LSN, actual.LSN)
+		require.Equal(t, expected.CommitTime, actual.CommitTime)
+		require.Equal(t, expected.Counter, actual.Counter)
+		require.Equal(t, expected.ColumnNames, actual.ColumnNames)
+		require.Equal(t, expected.OldKeys.KeyValues, actual.OldKeys.KeyValues)
+		require.Equal(t, expected.TableSchema.Columns(), actual.TableSchema.Columns())
+	}
+
+	t.Run("insert", func(t *testing.T) {
+		for _, snapshot := range []bool{false, true} {
+			expected := makeInsert()
+			actual, err := receive(t, expected, snapshot)
+			require.NoError(t, err)
+			checkCommon(t, expected, actual)
+			require.Equal(t, objectID, actual.ColumnValues[0])
+			require.Equal(t, bson.D{{Key: "name", Value: "Sally"}, {Key: "age", Value: int32(31)}, {Key: "tags", Value: bson.A{"a", "b"}}}, mongocommon.GetDocument(actual.ColumnValues))
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		expected := makeReplace()
+		actual, err := receive(t, expected, false)
+		require.NoError(t, err)
+		checkCommon(t, expected, actual)
+		require.Equal(t, bson.D{{Key: "name", Value: "Sally"}}, mongocommon.GetDocument(actual.ColumnValues))
+	})
+
+	t.Run("update document", func(t *testing.T) {
+		expected := makeUpdateDocument()
+		actual, err := receive(t, expected, false)
+		require.NoError(t, err)
+		checkCommon(t, expected, actual)
+		require.Equal(t, expected.ColumnValues, actual.ColumnValues)
+		_, err = mongocommon.NewUpdateDocumentChangeItem(actual)
+		require.NoError(t, err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		expected := makeDelete()
+		actual, err := receive(t, expected, false)
+		require.NoError(t, err)
+		checkCommon(t, expected, actual)
+		require.Nil(t, actual.ColumnValues)
+	})
+
+	t.Run("delete without key", func(t *testing.T) {
+		emitter, err := debezium.NewMessagesEmitter(getParams(nil), "1.1.2.Final", false, logger.Log)
+		require.NoError(t, err)
+		currDebeziumKV, err := emitter.EmitKV(makeDelete(), time.Time{}, false, nil)
+		require.NoError(t, err)
+		_, err = receiver.Receive(*currDebeziumKV[0].DebeziumVal)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"fmt"
+
+	"github.com/transferia/transferia/pkg/abstract"
+	debeziumparameters "github.com/transferia/transferia/pkg/debezium/parameters"
+	mongocommon "github.com/transferia/transferia/pkg/providers/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	keySchema = `{"type":"struct","fields":[{"type":"string","optional":false,"field":"id"}],"optional":false,"name":"fulfillment.inventory.customers.Key"}`
+	valSchema = `{"type":"struct","fields":[{"type":"string","optional":true,"name":"io.debezium.data.Json","version":1,"field":"before"},{"type":"string","optional":true,"name":"io.debezium.data.Json","version":1,"field":"after"},{"type":"struct","fields":[{"type":"array","items":{"type":"string","optional":false},"optional":true,"field":"removedFields"},{"type":"string","optional":true,"name":"io.debezium.data.Json","version":1,"field":"updatedFields"},{"type":"array","items":{"type":"struct","fields":[{"type":"string","optional":false,"field":"field"},{"type":"int32","optional":false,"field":"size"}],"optional":false},"optional":true,"field":"truncatedArrays"}],"optional":true,"name":"io.debezium.connector.mongodb.changestream.updatedescription","version":1,"field":"updateDescription"},{"type":"struct","fields":[{"type":"string","optional":false,"field":"version"},{"type":"string","optional":false,"field":"connector"},{"type":"string","optional":false,"field":"name"},{"type":"int64","optional":false,"field":"ts_ms"},{"type":"string","optional":true,"name":"io.debezium.data.Enum","version":1,"parameters":{"allowed":"true,last,false"},"default":"false","field":"snapshot"},{"type":"string","optional":false,"field":"db"},{"type":"string","optional":false,"field":"collection"},{"type":"int32","optional":false,"field":"ord"},{"type":"int64","optional":true,"field":"h"},{"type":"string","optional":true,"field":"lsid"},{"type":"int64","optional":true,"field":"txnNumber"}],"optional":false,"name":"io.debezium.connector.mongo.Source","field":"source"},{"type":"string","optional":false,"field":"op"},{"type":"int64","optional":true,"field":"ts_ms"},{"type":"struct","fields":[{"type":"string","optional":false,"field":"id"},{"type":"int64","optional":false,"field":"total_order"},{"type":"int64","optional":false,"field":"data_collection_order"}],"optional":true,"field":"transaction"}],"optional":false,"name":"fulfillment.inventory.customers.Envelope"}`
+
+	canonKey = `{"schema":` + keySchema + `,"payload":{"id":"{\"$oid\":\"596e275826f08b2730779e1f\"}"}}`
+)
+
+var objectID, _ = primitive.ObjectIDFromHex("596e275826f08b2730779e1f")
+
+func canonVal(payload string) *string {
+	result := fmt.Sprintf(`{"schema":%s,"payload":%s}`, valSchema, payload)
+	return &result
+}
+
+func getParams(additional map[string]string) map[string]string {
+	result := map[string]string{
+		debeziumparameters.DatabaseDBName:   "inventory",
+		debeziumparameters.TopicPrefix:      "fulfillment",
+		debeziumparameters.AddOriginalTypes: "false",
+		debeziumparameters.SourceType:       debeziumparameters.SourceTypeMongo,
+	}
+	for k, v := range additional {
+		result[k] = v
+	}
+	return result
+}
+
+// change items in the form of mongo source in homogeneous mode
+
+func makeInsert() *abstract.ChangeItem {
+	return &abstract.ChangeItem{
+		LSN:          1700000000,
+		CommitTime:   1700000000 * 1000000000,
+		Counter:      3,
+		Kind:         abstract.InsertKind,
+		Schema:       "inventory",
+		Table:        "customers",
+		ColumnNames:  mongocommon.DocumentSchema.ColumnsNames,
+		ColumnValues: []interface{}{objectID, mongocommon.MakeDValue(bson.D{{Key: "name", Value: "Sally"}, {Key: "age", Value: int32(31)}, {Key: "tags", Value: bson.A{"a", "b"}}}, true, false)},
+		TableSchema:  mongocommon.DocumentSchema.Columns,
+	}
+}
+
+func makeReplace() *abstract.ChangeItem {
+	return &abstract.ChangeItem{
+		LSN:          1700000000,
+		CommitTime:   1700000000 * 1000000000,
+		Counter:      4,
+		Kind:         abstract.UpdateKind,
+		Schema:       "inventory",
+		Table:        "customers",
+		ColumnNames:  mongocommon.DocumentSchema.ColumnsNames,
+		ColumnValues: []interface{}{objectID, mongocommon.MakeDValue(bson.D{{Key: "name", Value: "Sally"}}, true, false)},
+		TableSchema:  mongocommon.DocumentSchema.Columns,
+		OldKeys:      abstract.OldKeysType{KeyNames: []string{"_id"}, KeyTypes: nil, KeyValues: []interface{}{objectID}},
+	}
+}
+
+func makeUpdateDocument() *abstract.ChangeItem {
+	return &abstract.ChangeItem{
+		LSN:         1700000000,
+		CommitTime:  1700000000 * 1000000000,
+		Counter:     5,
+		Kind:        abstract.MongoUpdateDocumentKind,
+		Schema:      "inventory",
+		Table:       "customers",
+		ColumnNames: mongocommon.UpdateDocumentSchema.ColumnsNames,
+		ColumnValues: []interface{}{
+			objectID,
+			bson.D{{Key: "age", Value: int32(32)}},
+			[]string{"tags"},
+			[]mongocommon.TruncatedArray{{Field: "history", NewSize: 2}},
+			bson.D{{Key: "name", Value: "Sally"}, {Key: "age", Value: int32(32)}},
+		},
+		TableSchema: mongocommon.UpdateDocumentSchema.Columns,
+		OldKeys:     abstract.OldKeysType{KeyNames: []string{"_id"}, KeyTypes: nil, KeyValues: []interface{}{objectID}},
+	}
+}
+
+func makeDelete() *abstract.ChangeItem {
+	return &abstract.ChangeItem{
+		LSN:         1700000000,
+		CommitTime:  1700000000 * 1000000000,
+		Counter:     6,
+		Kind:        abstract.DeleteKind,
+		Schema:      "inventory",
+		Table:       "customers",
+		TableSchema: mongocommon.DocumentSchema.Columns,
+		OldKeys:     abstract.OldKeysType{KeyNames: []string{"_id"}, KeyTypes: nil, KeyValues: []interface{}{objectID}},
+	}
+}
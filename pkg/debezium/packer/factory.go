@@ -25,6 +25,15 @@ func NewKeyPackerFromDebeziumParameters(connectorParameters map[string]string, l
 			}
 			srClient.SetCredentials(userAndPassword[0], userAndPassword[1])
 		}
+		if debeziumparameters.GetKeyConverter(connectorParameters) == debeziumparameters.ConverterConfluentAvro {
+			return NewPackerCacheFinalSchema(NewPackerSchemaRegistryAvro(
+				srClient,
+				debeziumparameters.GetKeySubjectNameStrategy(connectorParameters),
+				true,
+				debeziumparameters.UseWriteIntoOneFullTopicName(connectorParameters),
+				debeziumparameters.GetTopicPrefix(connectorParameters),
+			)), nil
+		}
 
 		return NewPackerCacheFinalSchema(NewPackerSchemaRegistry(
 			srClient,
@@ -35,6 +44,9 @@ func NewKeyPackerFromDebeziumParameters(connectorParameters map[string]string, l
 			debeziumparameters.GetKeyConverterDTJSONGenerateClosedContentSchema(connectorParameters),
 		)), nil
 	}
+	if debeziumparameters.GetKeyConverter(connectorParameters) == debeziumparameters.ConverterConfluentAvro {
+		return nil, xerrors.Errorf("%s requires %s to be set", debeziumparameters.ConverterConfluentAvro, debeziumparameters.KeyConverterSchemaRegistryURL)
+	}
 	if debeziumparameters.IsKeySchemaDisabled(connectorParameters) {
 		return NewPackerSkipSchema(), nil
 	}
@@ -57,6 +69,15 @@ func NewValuePackerFromDebeziumParameters(connectorParameters map[string]string,
 			}
 			srClient.SetCredentials(userAndPassword[0], userAndPassword[1])
 		}
+		if debeziumparameters.GetValueConverter(connectorParameters) == debeziumparameters.ConverterConfluentAvro {
+			return NewPackerCacheFinalSchema(NewPackerSchemaRegistryAvro(
+				srClient,
+				debeziumparameters.GetValueSubjectNameStrategy(connectorParameters),
+				false,
+				debeziumparameters.UseWriteIntoOneFullTopicName(connectorParameters),
+				debeziumparameters.GetTopicPrefix(connectorParameters),
+			)), nil
+		}
 		return NewPackerCacheFinalSchema(NewPackerSchemaRegistry(
 			srClient,
 			debeziumparameters.GetValueSubjectNameStrategy(connectorParameters),
@@ -67,6 +88,9 @@ func NewValuePackerFromDebeziumParameters(connectorParameters map[string]string,
 		)), nil
 	}
 
+	if debeziumparameters.GetValueConverter(connectorParameters) == debeziumparameters.ConverterConfluentAvro {
+		return nil, xerrors.Errorf("%s requires %s to be set", debeziumparameters.ConverterConfluentAvro, debeziumparameters.ValueConverterSchemaRegistryURL)
+	}
 	if debeziumparameters.IsValueSchemaDisabled(connectorParameters) {
 		return NewPackerSkipSchema(), nil
 	}
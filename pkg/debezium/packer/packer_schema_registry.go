@@ -64,6 +64,11 @@ func (s *PackerSchemaRegistry) IsDropSchema() bool {
 }
 
 func (s *PackerSchemaRegistry) PackWithSchemaID(schemaID uint32, payload []byte) ([]byte, error) {
+	return packSchemaIDWithPayload(schemaID, payload)
+}
+
+// packSchemaIDWithPayload builds message in confluent wire format: magic byte 0, 4 bytes of schemaID, payload
+func packSchemaIDWithPayload(schemaID uint32, payload []byte) ([]byte, error) {
 	var resultBuf bytes.Buffer
 	resultBuf.WriteByte(0)
 	if err := binary.Write(&resultBuf, binary.BigEndian, schemaID); err != nil {
@@ -0,0 +1,135 @@
+package packer
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/avro"
+	"github.com/transferia/transferia/pkg/schemaregistry/confluent"
+	"github.com/transferia/transferia/pkg/schemaregistry/format"
+)
+
+// PackerSchemaRegistryAvro - analogue of confluent 'io.confluent.connect.avro.AvroConverter'
+// Schema is registered in avro format, payload is encoded into avro binary format.
+// Payload builders produce kafka-connect json, so it's converted into avro according to the registered schema.
+type PackerSchemaRegistryAvro struct {
+	schemaRegistryClient *confluent.SchemaRegistryClient
+	isKeyProcessor       bool
+	subjectNameStrategy  string
+	writeIntoOneTopic    bool
+	topic                string // full topic name or prefix
+
+	mutex      sync.Mutex
+	idToSchema map[uint32]*avro.Schema
+}
+
+func (s *PackerSchemaRegistryAvro) Pack(
+	changeItem *abstract.ChangeItem,
+	payloadBuilder BuilderFunc,
+	kafkaSchemaBuilder BuilderFunc,
+	maybeCachedRawSchema []byte,
+) ([]byte, error) {
+	rawPayload, err := payloadBuilder(changeItem)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to build rawPayload, err: %w", err)
+	}
+	if maybeCachedRawSchema == nil {
+		maybeCachedRawSchema, err = s.BuildFinalSchema(changeItem, kafkaSchemaBuilder)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to build final schema, err: %w", err)
+		}
+	}
+	schemaID, err := s.ResolveSchemaID(maybeCachedRawSchema, changeItem.TableID())
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get schemaID, err: %w", err)
+	}
+	return s.PackWithSchemaID(schemaID, rawPayload)
+}
+
+func (s *PackerSchemaRegistryAvro) BuildFinalSchema(changeItem *abstract.ChangeItem, kafkaSchemaBuilder BuilderFunc) ([]byte, error) {
+	schemaObj, err := kafkaSchemaBuilder(changeItem)
+	if err != nil {
+		return nil, xerrors.Errorf("can't build schemaObj object: %w", err)
+	}
+	kafkaSchema, err := format.KafkaJSONSchemaFromArr(schemaObj)
+	if err != nil {
+		return nil, xerrors.Errorf("can't convert map into kafka json schema: %w", err)
+	}
+	avroSchema, err := kafkaSchema.ToAvroSchema()
+	if err != nil {
+		return nil, xerrors.Errorf("can't convert kafka json schema into avro schema: %w", err)
+	}
+	rawSchema, err := json.Marshal(avroSchema)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to marshal schema in avro format: %w", err)
+	}
+	return rawSchema, nil
+}
+
+func (s *PackerSchemaRegistryAvro) IsDropSchema() bool {
+	return false
+}
+
+func (s *PackerSchemaRegistryAvro) PackWithSchemaID(schemaID uint32, payload []byte) ([]byte, error) {
+	s.mutex.Lock()
+	avroSchema, ok := s.idToSchema[schemaID]
+	s.mutex.Unlock()
+	if !ok {
+		return nil, xerrors.Errorf("avro schema with id %d wasn't resolved", schemaID)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var payloadObj any
+	if err := decoder.Decode(&payloadObj); err != nil {
+		return nil, xerrors.Errorf("can't unmarshal payload: %w", err)
+	}
+	// payload is kafka-connect json, so logical types (like decimal in base64) are already in avro representation
+	avroPayload, err := avro.EncodeRaw(avroSchema, payloadObj)
+	if err != nil {
+		return nil, xerrors.Errorf("can't encode payload into avro: %w", err)
+	}
+	return packSchemaIDWithPayload(schemaID, avroPayload)
+}
+
+func (s *PackerSchemaRegistryAvro) ResolveSchemaID(schema []byte, table abstract.TableID) (uint32, error) {
+	subjectName := makeSubjectName(table, s.topic, s.writeIntoOneTopic, s.isKeyProcessor, s.subjectNameStrategy)
+	schemaID, err := s.schemaRegistryClient.CreateSchema(subjectName, string(schema), confluent.AVRO)
+	if err != nil {
+		return 0, xerrors.Errorf("can't push schema into the schema registry: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.idToSchema[uint32(schemaID)]; !ok {
+		avroSchema, err := avro.ParseSchema(string(schema))
+		if err != nil {
+			return 0, xerrors.Errorf("can't parse avro schema: %w", err)
+		}
+		s.idToSchema[uint32(schemaID)] = avroSchema
+	}
+	return uint32(schemaID), nil
+}
+
+func (s *PackerSchemaRegistryAvro) GetSchemaIDResolver() SchemaIDResolver { return s }
+
+func NewPackerSchemaRegistryAvro(
+	srClient *confluent.SchemaRegistryClient,
+	subjectNameStrategy string,
+	isKeyProcessor bool,
+	writeIntoOneTopic bool,
+	topic string,
+) *PackerSchemaRegistryAvro {
+	return &PackerSchemaRegistryAvro{
+		schemaRegistryClient: srClient,
+		isKeyProcessor:       isKeyProcessor,
+		subjectNameStrategy:  subjectNameStrategy,
+		writeIntoOneTopic:    writeIntoOneTopic,
+		topic:                topic,
+		mutex:                sync.Mutex{},
+		idToSchema:           make(map[uint32]*avro.Schema),
+	}
+}
@@ -20,7 +20,7 @@ const (
 
 	UnknownTypesPolicy        = "dt.unknown.types.policy" // by default, debezium skips user-defined types. We are failing by default in this case, but can just skip
 	AddOriginalTypes          = "dt.add.original.type.info"
-	SourceType                = "dt.source.type" // common/mysql/pg - to emit database-specific fields in 'source'
+	SourceType                = "dt.source.type" // common/mysql/pg/ydb/mongo - to emit database-specific fields in 'source'
 	MysqlTimeZone             = "dt.mysql.timezone"
 	BatchingMaxSize           = "dt.batching.max.size"
 	WriteIntoOneFullTopicName = "dt.write.into.one.topic"
@@ -35,6 +35,7 @@ const (
 	BinaryHandlingMode          = "binary.handling.mode"
 	MoneyFractionDigits         = "money.fraction.digits"
 	UnavailableValuePlaceholder = "unavailable.value.placeholder"
+	CaptureMode                 = "capture.mode" // mongo-connector only
 
 	KeyConverter                                  = "key.converter"
 	KeyConverterSchemasEnable                     = "key.converter.schemas.enable"
@@ -68,6 +69,7 @@ const (
 	SourceTypePg    = "pg"
 	SourceTypeMysql = "mysql"
 	SourceTypeYDB   = "ydb"
+	SourceTypeMongo = "mongo"
 
 	MysqlTimeZoneUTC = "UTC"
 
@@ -89,6 +91,9 @@ const (
 	BinaryHandlingModeBase64 = "base64"
 	BinaryHandlingModeHex    = "hex"
 
+	CaptureModeChangeStreams           = "change_streams"             // update events carry only 'updateDescription'
+	CaptureModeChangeStreamsUpdateFull = "change_streams_update_full" // update events carry the full document in 'after' as well
+
 	ConverterApacheKafkaJSON   = "org.apache.kafka.connect.json.JsonConverter"
 	ConverterConfluentAvro     = "io.confluent.connect.avro.AvroConverter"
 	ConverterConfluentJSON     = "io.confluent.connect.json.JsonSchemaConverter"
@@ -130,7 +135,7 @@ var connectorSettings = []connectorSetting{
 	{TopicPrefix, []string{}, ""},
 	{UnknownTypesPolicy, []string{UnknownTypesPolicyFail, UnknownTypesPolicySkip, UnknownTypesPolicyToString}, UnknownTypesPolicyFail},
 	{AddOriginalTypes, []string{BoolFalse, BoolTrue}, BoolFalse},
-	{SourceType, []string{"", SourceTypePg, SourceTypeMysql, SourceTypeYDB}, ""},
+	{SourceType, []string{"", SourceTypePg, SourceTypeMysql, SourceTypeYDB, SourceTypeMongo}, ""},
 	{MysqlTimeZone, []string{}, MysqlTimeZoneUTC},
 	{BatchingMaxSize, []string{}, "0"},
 	{WriteIntoOneFullTopicName, []string{BoolFalse, BoolTrue}, BoolFalse},
@@ -143,11 +148,12 @@ var connectorSettings = []connectorSetting{
 	{BinaryHandlingMode, []string{BinaryHandlingModeBytes, BinaryHandlingModeBase64, BinaryHandlingModeHex}, BinaryHandlingModeBytes},
 	{MoneyFractionDigits, []string{}, "2"},
 	{UnavailableValuePlaceholder, []string{}, "__debezium_unavailable_value"},
+	{CaptureMode, []string{CaptureModeChangeStreams, CaptureModeChangeStreamsUpdateFull}, CaptureModeChangeStreamsUpdateFull},
 
 	// key/value stuff
 
-	{KeyConverter, []string{ConverterApacheKafkaJSON, ConverterConfluentJSON}, ConverterApacheKafkaJSON},
-	{ValueConverter, []string{ConverterApacheKafkaJSON, ConverterConfluentJSON}, ConverterApacheKafkaJSON},
+	{KeyConverter, []string{ConverterApacheKafkaJSON, ConverterConfluentJSON, ConverterConfluentAvro}, ConverterApacheKafkaJSON},
+	{ValueConverter, []string{ConverterApacheKafkaJSON, ConverterConfluentJSON, ConverterConfluentAvro}, ConverterApacheKafkaJSON},
 
 	{KeyConverterSchemasEnable, []string{BoolFalse, BoolTrue}, BoolTrue},
 	{ValueConverterSchemasEnable, []string{BoolFalse, BoolTrue}, BoolTrue},
@@ -229,6 +235,9 @@ func GetDecimalHandlingMode(in map[string]string) string {
 func GetTombstonesOnDelete(in map[string]string) string {
 	return in[TombstonesOnDelete]
 }
+func GetCaptureMode(in map[string]string) string {
+	return in[CaptureMode]
+}
 func GetKeyConverter(in map[string]string) string {
 	return in[KeyConverter]
 }
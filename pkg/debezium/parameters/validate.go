@@ -9,6 +9,9 @@ func Validate(connectorParameters map[string]string, dropKeys bool) error {
 			if GetValueConverterSchemaRegistryURL(connectorParameters) == "" {
 				return xerrors.New("dt.batching.max.size can be used ONLY with schema-registry for values encoding")
 			}
+			if GetValueConverter(connectorParameters) == ConverterConfluentAvro {
+				return xerrors.New("dt.batching.max.size can't be used with avro values encoding - avro messages can't be split")
+			}
 		} else {
 			return xerrors.New("dt.batching.max.size can be used only with lb/yds")
 		}
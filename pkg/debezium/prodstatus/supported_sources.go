@@ -2,6 +2,7 @@ package prodstatus
 
 import (
 	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/providers/mongo"
 	"github.com/transferia/transferia/pkg/providers/mysql"
 	"github.com/transferia/transferia/pkg/providers/postgres"
 	"github.com/transferia/transferia/pkg/providers/ydb"
@@ -10,6 +11,7 @@ import (
 var supportedSources = map[string]bool{
 	postgres.ProviderType.Name(): true,
 	mysql.ProviderType.Name():    true,
+	mongo.ProviderType.Name():    true,
 	ydb.ProviderType.Name():      true,
 }
 
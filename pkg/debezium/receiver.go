@@ -8,6 +8,7 @@ import (
 	"github.com/transferia/transferia/pkg/abstract"
 	"github.com/transferia/transferia/pkg/abstract/changeitem"
 	debeziumcommon "github.com/transferia/transferia/pkg/debezium/common"
+	"github.com/transferia/transferia/pkg/debezium/mongo"
 	debeziumparameters "github.com/transferia/transferia/pkg/debezium/parameters"
 	"github.com/transferia/transferia/pkg/debezium/unpacker"
 	"github.com/transferia/transferia/pkg/schemaregistry/confluent"
@@ -140,15 +141,25 @@ func (r *Receiver) convertSchemaFormat(schema []byte) ([]byte, error) {
 }
 
 func (r *Receiver) Receive(in string) (*abstract.ChangeItem, error) {
+	return r.ReceiveWithKey(nil, in)
+}
+
+// ReceiveWithKey - like Receive, but the key of the message is used for events which carry the key only there,
+// e.g. deletes of the mongo-connector. Empty key is ignored
+func (r *Receiver) ReceiveWithKey(key []byte, in string) (*abstract.ChangeItem, error) {
 	schema, payload, err := r.Unpacker.Unpack([]byte(in))
 	if err != nil {
 		return nil, xerrors.Errorf("can't unpack message: %w", err)
 	}
 
-	return r.receive(schema, payload)
+	return r.receive(key, schema, payload)
 }
 
-func (r *Receiver) receive(schema, payload []byte) (*abstract.ChangeItem, error) {
+func (r *Receiver) receive(key, schema, payload []byte) (*abstract.ChangeItem, error) {
+	if mongo.IsEnvelopeSchema(schema) {
+		return r.receiveMongo(key, payload)
+	}
+
 	payloadStruct, err := debeziumcommon.UnmarshalPayload(payload)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to unmarshal json payload: %s, err: %w", string(payload), err)
@@ -219,6 +230,35 @@ func (r *Receiver) receive(schema, payload []byte) (*abstract.ChangeItem, error)
 	return result, nil
 }
 
+// receiveMongo - mongo-connector envelope doesn't describe document fields, so the schema isn't needed
+func (r *Receiver) receiveMongo(key, payload []byte) (*abstract.ChangeItem, error) {
+	payloadStruct, err := mongo.UnmarshalPayload(payload)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to unmarshal json payload: %s, err: %w", string(payload), err)
+	}
+	kind, err := opToKind(payloadStruct.Op)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to determine kind, err: %w", err)
+	}
+	var keyID interface{}
+	if len(key) != 0 && payloadStruct.After == nil && payloadStruct.Before == nil {
+		_, keyPayload, err := r.Unpacker.Unpack(key)
+		if err != nil {
+			return nil, xerrors.Errorf("can't unpack key: %w", err)
+		}
+		keyID, err = mongo.UnmarshalKeyID(keyPayload)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to receive document key, err: %w", err)
+		}
+	}
+	result, err := mongo.Receive(payloadStruct, keyID, kind)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to receive mongo event, err: %w", err)
+	}
+	result.Size = abstract.RawEventSize(util.DeepSizeof(payload))
+	return result, nil
+}
+
 func NewReceiver(originalTypes map[abstract.TableID]map[string]*debeziumcommon.OriginalTypeInfo, schemaRegistryClient *confluent.SchemaRegistryClient) *Receiver {
 	currUnpacker := unpacker.NewMessageUnpacker(schemaRegistryClient)
 	var schemaFormat = debeziumparameters.ConverterApacheKafkaJSON
@@ -2,17 +2,29 @@ package unpacker
 
 import (
 	"encoding/binary"
+	"encoding/json"
+	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/transferia/transferia/internal/logger"
 	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/avro"
 	"github.com/transferia/transferia/pkg/schemaregistry/confluent"
+	"github.com/transferia/transferia/pkg/schemaregistry/format"
 	"github.com/transferia/transferia/pkg/util"
 )
 
 type SchemaRegistry struct {
 	schemaRegistryClient *confluent.SchemaRegistryClient
+
+	avroMutex      sync.Mutex
+	idToAvroSchema map[uint32]*avroSchemaItem
+}
+
+type avroSchemaItem struct {
+	schema              *avro.Schema
+	confluentJSONSchema []byte
 }
 
 func (s *SchemaRegistry) Unpack(message []byte) ([]byte, []byte, error) {
@@ -28,9 +40,71 @@ func (s *SchemaRegistry) Unpack(message []byte) ([]byte, []byte, error) {
 		return s.schemaRegistryClient.GetSchema(int(schemaID))
 	}, backoff.NewConstantBackOff(time.Second), util.BackoffLogger(logger.Log, "getting schema"))
 
+	if schema.SchemaType == confluent.AVRO {
+		return s.unpackAvro(schemaID, schema, message[5:])
+	}
 	return []byte(schema.Schema), message[5:], nil
 }
 
+// unpackAvro converts messages of 'io.confluent.connect.avro.AvroConverter' into the same form, as 'JsonSchemaConverter' has:
+// schema in confluent json-schema format, payload in json
+func (s *SchemaRegistry) unpackAvro(schemaID uint32, schema *confluent.Schema, payload []byte) ([]byte, []byte, error) {
+	item, err := s.avroSchema(schemaID, schema)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("unable to prepare avro schema with id %d: %w", schemaID, err)
+	}
+	decoded, _, err := avro.DecodeRaw(item.schema, payload)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("unable to decode avro payload: %w", err)
+	}
+	jsonPayload, err := json.Marshal(format.AvroValueToKafkaJSON(item.schema, decoded))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("unable to marshal payload into json: %w", err)
+	}
+	return item.confluentJSONSchema, jsonPayload, nil
+}
+
+func (s *SchemaRegistry) avroSchema(schemaID uint32, schema *confluent.Schema) (*avroSchemaItem, error) {
+	s.avroMutex.Lock()
+	defer s.avroMutex.Unlock()
+	if item, ok := s.idToAvroSchema[schemaID]; ok {
+		return item, nil
+	}
+
+	var refs map[string]confluent.Schema
+	if len(schema.References) > 0 {
+		var err error
+		refs, err = s.schemaRegistryClient.ResolveReferencesRecursive(schema.References)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to resolve references: %w", err)
+		}
+	}
+	avroSchema, err := avro.NewNames(func(fullName string) (string, error) {
+		ref, ok := refs[fullName]
+		if !ok {
+			return "", xerrors.Errorf("schema doesn't have reference with name %s", fullName)
+		}
+		return ref.Schema, nil
+	}).Parse(schema.Schema)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to parse avro schema: %w", err)
+	}
+	kafkaSchema, err := format.AvroToKafkaJSONSchema(avroSchema)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to convert avro schema: %w", err)
+	}
+	confluentJSONSchema, err := json.Marshal(kafkaSchema.ToConfluentSchema(false))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to marshal schema in confluent json format: %w", err)
+	}
+	item := &avroSchemaItem{
+		schema:              avroSchema,
+		confluentJSONSchema: confluentJSONSchema,
+	}
+	s.idToAvroSchema[schemaID] = item
+	return item, nil
+}
+
 func (s *SchemaRegistry) SchemaRegistryClient() *confluent.SchemaRegistryClient {
 	return s.schemaRegistryClient
 }
@@ -38,5 +112,7 @@ func (s *SchemaRegistry) SchemaRegistryClient() *confluent.SchemaRegistryClient
 func NewSchemaRegistry(srClient *confluent.SchemaRegistryClient) *SchemaRegistry {
 	return &SchemaRegistry{
 		schemaRegistryClient: srClient,
+		avroMutex:            sync.Mutex{},
+		idToAvroSchema:       make(map[uint32]*avroSchemaItem),
 	}
 }
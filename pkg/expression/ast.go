@@ -0,0 +1,167 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+// Expr represents a node of the expression abstract syntax tree.
+type Expr interface {
+	expr()
+	String() string
+}
+
+func (*Literal) expr() {}
+func (*Column) expr()  {}
+func (*Unary) expr()   {}
+func (*Binary) expr()  {}
+func (*InList) expr()  {}
+func (*IsNull) expr()  {}
+func (*Call) expr()    {}
+func (*Cast) expr()    {}
+func (*Case) expr()    {}
+
+// Literal represents a constant, Value is nil, bool, int64, float64, string or time.Time.
+type Literal struct {
+	Value any
+}
+
+// String returns a string representation of the literal.
+func (l *Literal) String() string {
+	switch v := l.Value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v))
+	case string:
+		return strconv.Quote(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Column represents a reference to a column of the row.
+type Column struct {
+	Name string
+}
+
+// String returns a string representation of the column reference.
+func (c *Column) String() string { return c.Name }
+
+// Unary represents NOT or an arithmetic negation.
+type Unary struct {
+	Op Token
+	X  Expr
+}
+
+// String returns a string representation of the unary expression.
+func (e *Unary) String() string {
+	if e.Op == NOT {
+		return fmt.Sprintf("NOT %s", e.X)
+	}
+	return fmt.Sprintf("%s%s", e.Op, e.X)
+}
+
+// Binary represents a logical, a comparison, an arithmetic or a concatenation operation.
+type Binary struct {
+	Op  Token
+	LHS Expr
+	RHS Expr
+}
+
+// String returns a string representation of the binary expression.
+func (e *Binary) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.LHS, e.Op, e.RHS)
+}
+
+// InList represents [NOT] IN with a list of values.
+type InList struct {
+	X     Expr
+	Items []Expr
+	Not   bool
+}
+
+// String returns a string representation of the IN expression.
+func (e *InList) String() string {
+	items := make([]string, len(e.Items))
+	for i, item := range e.Items {
+		items[i] = item.String()
+	}
+	op := "IN"
+	if e.Not {
+		op = "NOT IN"
+	}
+	return fmt.Sprintf("%s %s (%s)", e.X, op, strings.Join(items, ", "))
+}
+
+// IsNull represents IS [NOT] NULL.
+type IsNull struct {
+	X   Expr
+	Not bool
+}
+
+// String returns a string representation of the IS NULL expression.
+func (e *IsNull) String() string {
+	if e.Not {
+		return fmt.Sprintf("%s IS NOT NULL", e.X)
+	}
+	return fmt.Sprintf("%s IS NULL", e.X)
+}
+
+// Call represents a call of a built-in function.
+type Call struct {
+	Name string
+	Args []Expr
+}
+
+// String returns a string representation of the call.
+func (e *Call) String() string {
+	args := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("%s(%s)", e.Name, strings.Join(args, ", "))
+}
+
+// Cast represents CAST(x AS type), the type is a column type of the transfer type system.
+type Cast struct {
+	X    Expr
+	Type schema.Type
+}
+
+// String returns a string representation of the cast.
+func (e *Cast) String() string {
+	return fmt.Sprintf("CAST(%s AS %s)", e.X, e.Type)
+}
+
+// When is a branch of CASE.
+type When struct {
+	Cond Expr
+	Then Expr
+}
+
+// Case represents CASE WHEN ... THEN ... [ELSE ...] END, Else is nil if it's omitted.
+type Case struct {
+	Whens []When
+	Else  Expr
+}
+
+// String returns a string representation of the CASE expression.
+func (e *Case) String() string {
+	var result strings.Builder
+	result.WriteString("CASE")
+	for _, when := range e.Whens {
+		fmt.Fprintf(&result, " WHEN %s THEN %s", when.Cond, when.Then)
+	}
+	if e.Else != nil {
+		fmt.Fprintf(&result, " ELSE %s", e.Else)
+	}
+	result.WriteString(" END")
+	return result.String()
+}
@@ -0,0 +1,715 @@
+package expression
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+// evalFunc evaluates a node for a row, the result is a runtime value of the kind of the node type or nil
+type evalFunc func(item *abstract.ChangeItem) (any, error)
+
+type compiled struct {
+	typ  schema.Type
+	eval evalFunc
+}
+
+// Program is an expression compiled for a table schema.
+type Program struct {
+	expr Expr
+	typ  schema.Type
+	eval evalFunc
+}
+
+// Type returns the column type of the values of the program.
+func (p *Program) Type() schema.Type {
+	return p.typ
+}
+
+// Eval evaluates the program for a row of the table schema, the result is either nil or a value of Type.
+func (p *Program) Eval(item *abstract.ChangeItem) (any, error) {
+	value, err := p.eval(item)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to evaluate %s: %w", p.expr, err)
+	}
+	return denormalize(value, p.typ), nil
+}
+
+// Compile resolves the columns of the expression in the table schema & checks the types of its operands.
+func Compile(expr Expr, tableSchema *abstract.TableSchema) (*Program, error) {
+	c := &compiler{
+		columns: tableSchema.Columns(),
+		indexes: make(map[string]int, len(tableSchema.Columns())),
+	}
+	for i, col := range c.columns {
+		c.indexes[col.ColumnName] = i
+	}
+	result, err := c.compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	typ := result.typ
+	if typ == typeNull {
+		typ = schema.TypeAny
+	}
+	return &Program{expr: expr, typ: typ, eval: result.eval}, nil
+}
+
+type compiler struct {
+	columns abstract.TableColumns
+	indexes map[string]int
+}
+
+func (c *compiler) compile(expr Expr) (compiled, error) {
+	switch e := expr.(type) {
+	case *Literal:
+		return constant(e.Value), nil
+	case *Column:
+		return c.column(e.Name)
+	case *Unary:
+		return c.unary(e)
+	case *Binary:
+		return c.binary(e)
+	case *InList:
+		return c.in(e)
+	case *IsNull:
+		return c.isNull(e)
+	case *Call:
+		return c.call(e)
+	case *Cast:
+		return c.cast(e)
+	case *Case:
+		return c.caseWhen(e)
+	}
+	return compiled{}, xerrors.Errorf("unexpected expression %T", expr)
+}
+
+func (c *compiler) compileAll(exprs []Expr) ([]compiled, error) {
+	result := make([]compiled, len(exprs))
+	for i, expr := range exprs {
+		arg, err := c.compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = arg
+	}
+	return result, nil
+}
+
+func constant(value any) compiled {
+	typ := typeNull
+	switch value.(type) {
+	case bool:
+		typ = schema.TypeBoolean
+	case int64:
+		typ = schema.TypeInt64
+	case float64:
+		typ = schema.TypeFloat64
+	case string:
+		typ = schema.TypeString
+	case time.Time:
+		typ = schema.TypeTimestamp
+	}
+	return compiled{typ: typ, eval: func(*abstract.ChangeItem) (any, error) { return value, nil }}
+}
+
+func (c *compiler) column(name string) (compiled, error) {
+	index, ok := c.indexes[name]
+	if !ok {
+		return compiled{}, xerrors.Errorf("column %s is not found", name)
+	}
+	typ := columnType(c.columns[index].DataType)
+	return compiled{typ: typ, eval: func(item *abstract.ChangeItem) (any, error) {
+		// rows usually follow the order of the schema, partial rows are searched by name
+		i := index
+		if i >= len(item.ColumnNames) || item.ColumnNames[i] != name {
+			if i = item.ColumnNameIndex(name); i < 0 {
+				return nil, xerrors.Errorf("column %s is missing in the row", name)
+			}
+		}
+		value, err := normalize(item.ColumnValues[i], typ)
+		if err != nil {
+			return nil, xerrors.Errorf("column %s: %w", name, err)
+		}
+		return value, nil
+	}}, nil
+}
+
+// implicit converts a node to the type if the conversion does not lose the meaning of the value,
+// values of any type are converted at runtime
+func implicit(node compiled, to schema.Type) (compiled, error) {
+	from := node.typ
+	switch {
+	case from == to:
+		return node, nil
+	case from == typeNull:
+		return compiled{typ: to, eval: node.eval}, nil
+	case kindOf(from) == kindOf(to) && to != schema.TypeDate && kindOf(to) != kindInt:
+		return compiled{typ: to, eval: node.eval}, nil
+	case kindOf(from) == kindInt && to == schema.TypeInt64:
+		return compiled{typ: to, eval: node.eval}, nil
+	case kindOf(from) == kindInt && kindOf(to) == kindFloat,
+		isText(from) && isText(to),
+		kindOf(from) == kindUtf8 && (kindOf(to) == kindTime || kindOf(to) == kindInterval),
+		kindOf(from) == kindAny,
+		kindOf(to) == kindAny:
+		return convert(node, to), nil
+	}
+	return compiled{}, xerrors.Errorf("expected %s, got %s", to, from)
+}
+
+// convert wraps a node into the conversion of its values to the type
+func convert(node compiled, to schema.Type) compiled {
+	from := node.typ
+	return compiled{typ: to, eval: func(item *abstract.ChangeItem) (any, error) {
+		value, err := node.eval(item)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		return castValue(value, from, to)
+	}}
+}
+
+func (c *compiler) unary(e *Unary) (compiled, error) {
+	x, err := c.compile(e.X)
+	if err != nil {
+		return compiled{}, err
+	}
+	if e.Op == NOT {
+		if x, err = implicit(x, schema.TypeBoolean); err != nil {
+			return compiled{}, xerrors.Errorf("NOT: %w", err)
+		}
+		return compiled{typ: schema.TypeBoolean, eval: func(item *abstract.ChangeItem) (any, error) {
+			value, err := x.eval(item)
+			if err != nil || value == nil {
+				return nil, err
+			}
+			return !value.(bool), nil
+		}}, nil
+	}
+	switch kindOf(x.typ) {
+	case kindInt, kindFloat, kindInterval, kindNull:
+	default:
+		return compiled{}, xerrors.Errorf("unable to negate %s", x.typ)
+	}
+	if kindOf(x.typ) == kindInt {
+		x = compiled{typ: schema.TypeInt64, eval: x.eval}
+	}
+	return compiled{typ: x.typ, eval: func(item *abstract.ChangeItem) (any, error) {
+		value, err := x.eval(item)
+		if err != nil {
+			return nil, err
+		}
+		switch v := value.(type) {
+		case int64:
+			return -v, nil
+		case float64:
+			return -v, nil
+		case time.Duration:
+			return -v, nil
+		}
+		return nil, nil
+	}}, nil
+}
+
+func (c *compiler) binary(e *Binary) (compiled, error) {
+	lhs, err := c.compile(e.LHS)
+	if err != nil {
+		return compiled{}, err
+	}
+	rhs, err := c.compile(e.RHS)
+	if err != nil {
+		return compiled{}, err
+	}
+	var result compiled
+	switch e.Op {
+	case AND, OR:
+		result, err = logical(e.Op, lhs, rhs)
+	case EQ, NEQ, LT, LTE, GT, GTE:
+		result, err = comparison(e.Op, lhs, rhs)
+	case MATCH, NOTMATCH:
+		result, err = match(e.Op == NOTMATCH, lhs, rhs)
+	case CONCAT:
+		result = concat(convert(lhs, schema.TypeString), convert(rhs, schema.TypeString))
+	default:
+		result, err = arithmetic(e.Op, lhs, rhs)
+	}
+	if err != nil {
+		return compiled{}, xerrors.Errorf("%s: %w", e, err)
+	}
+	return result, nil
+}
+
+// logical implements the three-valued logic of SQL: NULL AND FALSE is FALSE, NULL OR TRUE is TRUE
+func logical(op Token, lhs, rhs compiled) (compiled, error) {
+	lhs, err := implicit(lhs, schema.TypeBoolean)
+	if err != nil {
+		return compiled{}, err
+	}
+	rhs, err = implicit(rhs, schema.TypeBoolean)
+	if err != nil {
+		return compiled{}, err
+	}
+	// the result of the operation is known if an operand is shortcut
+	shortcut := op == OR
+	return compiled{typ: schema.TypeBoolean, eval: func(item *abstract.ChangeItem) (any, error) {
+		left, err := lhs.eval(item)
+		if err != nil {
+			return nil, err
+		}
+		if left == shortcut {
+			return shortcut, nil
+		}
+		right, err := rhs.eval(item)
+		if err != nil {
+			return nil, err
+		}
+		if right == shortcut {
+			return shortcut, nil
+		}
+		if left == nil || right == nil {
+			return nil, nil
+		}
+		return !shortcut, nil
+	}}, nil
+}
+
+// comparisonType returns the type both operands are converted to for a comparison
+func comparisonType(a, b schema.Type) (schema.Type, error) {
+	canonical := func(typ schema.Type) schema.Type {
+		switch kindOf(typ) {
+		case kindInt:
+			return schema.TypeInt64
+		case kindFloat:
+			return schema.TypeFloat64
+		case kindUtf8, kindBytes:
+			return schema.TypeString
+		case kindTime:
+			return schema.TypeTimestamp
+		}
+		return typ
+	}
+	switch {
+	case kindOf(a) == kindAny && kindOf(b) == kindAny:
+		return schema.TypeString, nil
+	case kindOf(a) == kindAny && isNumeric(b), kindOf(b) == kindAny && isNumeric(a):
+		return schema.TypeFloat64, nil
+	case kindOf(a) == kindAny:
+		return canonical(b), nil
+	case kindOf(b) == kindAny:
+		return canonical(a), nil
+	case kindOf(a) == kindInt && kindOf(b) == kindInt:
+		return schema.TypeInt64, nil
+	case isNumeric(a) && isNumeric(b):
+		return schema.TypeFloat64, nil
+	case isText(a) && isText(b):
+		return schema.TypeString, nil
+	case kindOf(a) == kindTime && (kindOf(b) == kindTime || kindOf(b) == kindUtf8),
+		kindOf(b) == kindTime && kindOf(a) == kindUtf8:
+		return schema.TypeTimestamp, nil
+	case kindOf(a) == kindInterval && (kindOf(b) == kindInterval || kindOf(b) == kindUtf8),
+		kindOf(b) == kindInterval && kindOf(a) == kindUtf8:
+		return schema.TypeInterval, nil
+	case kindOf(a) == kindOf(b):
+		return a, nil
+	}
+	return "", xerrors.Errorf("unable to compare %s with %s", a, b)
+}
+
+// comparator converts both operands to a common type & returns a function comparing their values
+func comparator(lhs, rhs compiled) (func(item *abstract.ChangeItem) (int, bool, error), error) {
+	typ, err := comparisonType(lhs.typ, rhs.typ)
+	if err != nil {
+		return nil, err
+	}
+	if lhs, err = implicit(lhs, typ); err != nil {
+		return nil, err
+	}
+	if rhs, err = implicit(rhs, typ); err != nil {
+		return nil, err
+	}
+	return func(item *abstract.ChangeItem) (int, bool, error) {
+		left, err := lhs.eval(item)
+		if err != nil || left == nil {
+			return 0, false, err
+		}
+		right, err := rhs.eval(item)
+		if err != nil || right == nil {
+			return 0, false, err
+		}
+		result, err := compareValues(left, right)
+		return result, err == nil, err
+	}, nil
+}
+
+// comparison returns NULL if any of operands is NULL, except for `= NULL` & `!= NULL` which are IS [NOT] NULL like in the filter syntax
+func comparison(op Token, lhs, rhs compiled) (compiled, error) {
+	if op == EQ || op == NEQ {
+		if rhs.typ == typeNull {
+			return isNull(lhs, op == NEQ), nil
+		}
+		if lhs.typ == typeNull {
+			return isNull(rhs, op == NEQ), nil
+		}
+	}
+	if lhs.typ == typeNull || rhs.typ == typeNull {
+		return compiled{typ: schema.TypeBoolean, eval: func(*abstract.ChangeItem) (any, error) { return nil, nil }}, nil
+	}
+	compare, err := comparator(lhs, rhs)
+	if err != nil {
+		return compiled{}, err
+	}
+	return compiled{typ: schema.TypeBoolean, eval: func(item *abstract.ChangeItem) (any, error) {
+		result, ok, err := compare(item)
+		if err != nil || !ok {
+			return nil, err
+		}
+		switch op {
+		case EQ:
+			return result == 0, nil
+		case NEQ:
+			return result != 0, nil
+		case LT:
+			return result < 0, nil
+		case LTE:
+			return result <= 0, nil
+		case GT:
+			return result > 0, nil
+		default:
+			return result >= 0, nil
+		}
+	}}, nil
+}
+
+// match checks whether the left operand contains the right one like ~ of the filter syntax does
+func match(not bool, lhs, rhs compiled) (compiled, error) {
+	lhs, err := implicit(lhs, schema.TypeString)
+	if err != nil {
+		return compiled{}, err
+	}
+	rhs, err = implicit(rhs, schema.TypeString)
+	if err != nil {
+		return compiled{}, err
+	}
+	return compiled{typ: schema.TypeBoolean, eval: func(item *abstract.ChangeItem) (any, error) {
+		values, err := evalAll(item, lhs, rhs)
+		if err != nil || values == nil {
+			return nil, err
+		}
+		return strings.Contains(values[0].(string), values[1].(string)) != not, nil
+	}}, nil
+}
+
+func concat(lhs, rhs compiled) compiled {
+	return compiled{typ: schema.TypeString, eval: func(item *abstract.ChangeItem) (any, error) {
+		values, err := evalAll(item, lhs, rhs)
+		if err != nil || values == nil {
+			return nil, err
+		}
+		return values[0].(string) + values[1].(string), nil
+	}}
+}
+
+// evalAll evaluates the nodes, the result is nil if any of values is NULL
+func evalAll(item *abstract.ChangeItem, nodes ...compiled) ([]any, error) {
+	values := make([]any, len(nodes))
+	for i, node := range nodes {
+		value, err := node.eval(item)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func arithmetic(op Token, lhs, rhs compiled) (compiled, error) {
+	a, b := lhs.typ, rhs.typ
+	if a == typeNull {
+		a = b
+	}
+	if b == typeNull {
+		b = a
+	}
+	var typ schema.Type
+	switch {
+	case a == typeNull:
+		typ = typeNull
+	case kindOf(a) == kindInt && kindOf(b) == kindInt && op != DIV:
+		typ = schema.TypeInt64
+	case isNumeric(a) && isNumeric(b):
+		typ = schema.TypeFloat64
+	case kindOf(a) == kindTime && kindOf(b) == kindInterval && (op == PLUS || op == MINUS):
+		typ = a
+	case kindOf(a) == kindInterval && kindOf(b) == kindTime && op == PLUS:
+		typ = b
+	case kindOf(a) == kindTime && kindOf(b) == kindTime && op == MINUS:
+		typ = schema.TypeInterval
+	case kindOf(a) == kindInterval && kindOf(b) == kindInterval && (op == PLUS || op == MINUS):
+		typ = schema.TypeInterval
+	case kindOf(a) == kindInterval && isNumeric(b) && (op == MUL || op == DIV),
+		isNumeric(a) && kindOf(b) == kindInterval && op == MUL:
+		typ = schema.TypeInterval
+	default:
+		return compiled{}, xerrors.Errorf("operator %s is not defined for %s and %s", op, lhs.typ, rhs.typ)
+	}
+	if isNumeric(a) && isNumeric(b) {
+		// operands are converted to the type of the result, so both of them are either int64 or float64
+		var err error
+		if lhs, err = implicit(lhs, typ); err != nil {
+			return compiled{}, err
+		}
+		if rhs, err = implicit(rhs, typ); err != nil {
+			return compiled{}, err
+		}
+		if op == DIV {
+			typ = schema.TypeFloat64
+		}
+	}
+	return compiled{typ: typ, eval: func(item *abstract.ChangeItem) (any, error) {
+		values, err := evalAll(item, lhs, rhs)
+		if err != nil || values == nil {
+			return nil, err
+		}
+		return calculate(op, values[0], values[1])
+	}}, nil
+}
+
+func calculate(op Token, a, b any) (any, error) {
+	switch x := a.(type) {
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return calculateInt(op, x, y)
+		case time.Duration:
+			return time.Duration(x) * y, nil
+		}
+	case float64:
+		switch y := b.(type) {
+		case float64:
+			return calculateFloat(op, x, y)
+		case time.Duration:
+			return time.Duration(x * float64(y)), nil
+		}
+	case time.Time:
+		switch y := b.(type) {
+		case time.Duration:
+			if op == MINUS {
+				return x.Add(-y), nil
+			}
+			return x.Add(y), nil
+		case time.Time:
+			return x.Sub(y), nil
+		}
+	case time.Duration:
+		switch y := b.(type) {
+		case time.Duration:
+			if op == MINUS {
+				return x - y, nil
+			}
+			return x + y, nil
+		case time.Time:
+			return y.Add(x), nil
+		case int64:
+			return calculateInterval(op, x, float64(y))
+		case float64:
+			return calculateInterval(op, x, y)
+		}
+	}
+	return nil, xerrors.Errorf("operator %s is not defined for %s and %s", op, describeValue(a), describeValue(b))
+}
+
+func calculateInt(op Token, a, b int64) (any, error) {
+	switch op {
+	case PLUS:
+		return a + b, nil
+	case MINUS:
+		return a - b, nil
+	case MUL:
+		return a * b, nil
+	case DIV:
+		return calculateFloat(op, float64(a), float64(b))
+	}
+	if b == 0 {
+		return nil, xerrors.New("division by zero")
+	}
+	return a % b, nil
+}
+
+func calculateFloat(op Token, a, b float64) (any, error) {
+	switch op {
+	case PLUS:
+		return a + b, nil
+	case MINUS:
+		return a - b, nil
+	case MUL:
+		return a * b, nil
+	}
+	if b == 0 {
+		return nil, xerrors.New("division by zero")
+	}
+	if op == DIV {
+		return a / b, nil
+	}
+	return math.Mod(a, b), nil
+}
+
+func calculateInterval(op Token, a time.Duration, b float64) (any, error) {
+	if op == MUL {
+		return time.Duration(float64(a) * b), nil
+	}
+	if b == 0 {
+		return nil, xerrors.New("division by zero")
+	}
+	return time.Duration(float64(a) / b), nil
+}
+
+// in returns NULL if the value is NULL or it's not found while the list contains NULL, like SQL does
+func (c *compiler) in(e *InList) (compiled, error) {
+	x, err := c.compile(e.X)
+	if err != nil {
+		return compiled{}, err
+	}
+	items, err := c.compileAll(e.Items)
+	if err != nil {
+		return compiled{}, err
+	}
+	comparators := make([]func(item *abstract.ChangeItem) (int, bool, error), 0, len(items))
+	hasNull := false
+	for _, item := range items {
+		if item.typ == typeNull || x.typ == typeNull {
+			hasNull = true
+			continue
+		}
+		compare, err := comparator(x, item)
+		if err != nil {
+			return compiled{}, xerrors.Errorf("%s: %w", e, err)
+		}
+		comparators = append(comparators, compare)
+	}
+	return compiled{typ: schema.TypeBoolean, eval: func(item *abstract.ChangeItem) (any, error) {
+		value, err := x.eval(item)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		sawNull := hasNull
+		for _, compare := range comparators {
+			result, ok, err := compare(item)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				sawNull = true
+				continue
+			}
+			if result == 0 {
+				return !e.Not, nil
+			}
+		}
+		if sawNull {
+			return nil, nil
+		}
+		return e.Not, nil
+	}}, nil
+}
+
+func (c *compiler) isNull(e *IsNull) (compiled, error) {
+	x, err := c.compile(e.X)
+	if err != nil {
+		return compiled{}, err
+	}
+	return isNull(x, e.Not), nil
+}
+
+func isNull(x compiled, not bool) compiled {
+	return compiled{typ: schema.TypeBoolean, eval: func(item *abstract.ChangeItem) (any, error) {
+		value, err := x.eval(item)
+		if err != nil {
+			return nil, err
+		}
+		return (value == nil) != not, nil
+	}}
+}
+
+func (c *compiler) cast(e *Cast) (compiled, error) {
+	x, err := c.compile(e.X)
+	if err != nil {
+		return compiled{}, err
+	}
+	if x.typ == e.Type {
+		return x, nil
+	}
+	result := convert(x, e.Type)
+	return compiled{typ: e.Type, eval: func(item *abstract.ChangeItem) (any, error) {
+		value, err := result.eval(item)
+		if err != nil {
+			return nil, xerrors.Errorf("%s: %w", e, err)
+		}
+		return value, nil
+	}}, nil
+}
+
+func (c *compiler) caseWhen(e *Case) (compiled, error) {
+	conds := make([]compiled, len(e.Whens))
+	thens := make([]compiled, len(e.Whens))
+	for i, when := range e.Whens {
+		cond, err := c.compile(when.Cond)
+		if err != nil {
+			return compiled{}, err
+		}
+		if conds[i], err = implicit(cond, schema.TypeBoolean); err != nil {
+			return compiled{}, xerrors.Errorf("WHEN %s: %w", when.Cond, err)
+		}
+		if thens[i], err = c.compile(when.Then); err != nil {
+			return compiled{}, err
+		}
+	}
+	elseNode := constant(nil)
+	if e.Else != nil {
+		var err error
+		if elseNode, err = c.compile(e.Else); err != nil {
+			return compiled{}, err
+		}
+	}
+	branches, typ, err := unifyAll(append(thens, elseNode))
+	if err != nil {
+		return compiled{}, xerrors.Errorf("%s: %w", e, err)
+	}
+	return compiled{typ: typ, eval: func(item *abstract.ChangeItem) (any, error) {
+		for i, cond := range conds {
+			value, err := cond.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			if value == true {
+				return branches[i].eval(item)
+			}
+		}
+		return branches[len(branches)-1].eval(item)
+	}}, nil
+}
+
+// unifyAll converts the nodes to a common type
+func unifyAll(nodes []compiled) ([]compiled, schema.Type, error) {
+	typ := typeNull
+	for _, node := range nodes {
+		unified, ok := unify(typ, node.typ)
+		if !ok {
+			return nil, "", xerrors.Errorf("incompatible types %s and %s", typ, node.typ)
+		}
+		typ = unified
+	}
+	result := make([]compiled, len(nodes))
+	for i, node := range nodes {
+		converted, err := implicit(node, typ)
+		if err != nil {
+			converted = convert(node, typ)
+		}
+		result[i] = converted
+	}
+	return result, typ, nil
+}
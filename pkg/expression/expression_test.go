@@ -0,0 +1,191 @@
+package expression
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/abstract"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+var testSchema = abstract.NewTableSchema(abstract.TableColumns{
+	abstract.NewColSchema("id", schema.TypeInt32, true),
+	abstract.NewColSchema("first", schema.TypeString, false),
+	abstract.NewColSchema("last", schema.TypeString, false),
+	abstract.NewColSchema("amount", schema.TypeFloat64, false),
+	abstract.NewColSchema("ts", schema.TypeTimestamp, false),
+	abstract.NewColSchema("payload", schema.TypeBytes, false),
+	abstract.NewColSchema("doc", schema.TypeAny, false),
+	abstract.NewColSchema("nothing", schema.TypeString, false),
+})
+
+func testItem() *abstract.ChangeItem {
+	return &abstract.ChangeItem{
+		Kind:        abstract.InsertKind,
+		Table:       "users",
+		ColumnNames: testSchema.Columns().ColumnNames(),
+		ColumnValues: []interface{}{
+			int32(7),
+			"Ada",
+			"Lovelace",
+			1500.5,
+			time.Date(2024, time.March, 15, 13, 45, 30, 0, time.UTC),
+			[]byte(`{"tags":["a","b"]}`),
+			map[string]interface{}{"address": map[string]interface{}{"city": "London"}, "age": 36},
+			nil,
+		},
+		TableSchema: testSchema,
+	}
+}
+
+func eval(t *testing.T, text string) (any, schema.Type) {
+	expr, err := Parse(text)
+	require.NoError(t, err)
+	program, err := Compile(expr, testSchema)
+	require.NoError(t, err)
+	value, err := program.Eval(testItem())
+	require.NoError(t, err)
+	return value, program.Type()
+}
+
+func TestEval(t *testing.T) {
+	for _, tc := range []struct {
+		expr  string
+		value any
+		typ   schema.Type
+	}{
+		{`first || ' ' || last`, "Ada Lovelace", schema.TypeString},
+		{`amount > 1000`, true, schema.TypeBoolean},
+		{`toDate(ts)`, time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC), schema.TypeDate},
+		{`id * 2 + 1`, int64(15), schema.TypeInt64},
+		{`id / 2`, 3.5, schema.TypeFloat64},
+		{`id % 4`, int64(3), schema.TypeInt64},
+		{`-amount`, -1500.5, schema.TypeFloat64},
+		{`CAST(amount AS int32)`, int32(1500), schema.TypeInt32},
+		{`CAST(id AS utf8)`, "7", schema.TypeString},
+		{`CAST('2024-01-02' AS date)`, time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC), schema.TypeDate},
+		{`upper(substring(first, 2))`, "DA", schema.TypeString},
+		{`length(last)`, int64(8), schema.TypeInt64},
+		{`split_part('a@b.c', '@', 2)`, "b.c", schema.TypeString},
+		{`round(amount / 7, 2)`, 214.36, schema.TypeFloat64},
+		{`CASE WHEN amount > 1000 THEN 'big' WHEN amount > 100 THEN 'medium' ELSE 'small' END`, "big", schema.TypeString},
+		{`if(id > 10, id, amount)`, 1500.5, schema.TypeFloat64},
+		{`coalesce(nothing, first)`, "Ada", schema.TypeString},
+		{`nothing || first`, nil, schema.TypeString},
+		{`concat(nothing, first, id)`, "Ada7", schema.TypeString},
+		{`nothing IS NULL AND first IS NOT NULL`, true, schema.TypeBoolean},
+		{`nothing = 'x' OR id = 7`, true, schema.TypeBoolean},
+		{`nothing = 'x' AND id = 7`, nil, schema.TypeBoolean},
+		{`id IN (1, 7) AND first NOT IN ('Bob')`, true, schema.TypeBoolean},
+		{`first ~ 'd' AND last !~ 'x'`, true, schema.TypeBoolean},
+		{`ts >= 2024-03-15 AND ts < '2024-03-16'`, true, schema.TypeBoolean},
+		{`ts + CAST('1h' AS interval)`, time.Date(2024, time.March, 15, 14, 45, 30, 0, time.UTC), schema.TypeTimestamp},
+		{`ts - 2024-03-15T13:00:00Z`, 45*time.Minute + 30*time.Second, schema.TypeInterval},
+		{`date_trunc('month', ts)`, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), schema.TypeTimestamp},
+		{`year(ts) * 100 + month(ts)`, int64(202403), schema.TypeInt64},
+		{`day_of_week(ts)`, int64(5), schema.TypeInt64},
+		{`json_value(doc, '$.address.city')`, "London", schema.TypeAny},
+		{`CAST(json_value(doc, 'age') AS int64) + 1`, int64(37), schema.TypeInt64},
+		{`json_value(payload, '$.tags[1]')`, "b", schema.TypeAny},
+		{`json_value(payload, '$.missing[0]')`, nil, schema.TypeAny},
+		{`json_value(doc, '$.address.city') = 'London'`, true, schema.TypeBoolean},
+		{`NULL`, nil, schema.TypeAny},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			value, typ := eval(t, tc.expr)
+			require.Equal(t, tc.typ, typ)
+			if f, ok := tc.value.(float64); ok {
+				require.InDelta(t, f, value, 1e-9)
+				return
+			}
+			require.Equal(t, tc.value, value)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, text := range []string{
+		``,
+		`a = `,
+		`a = 'unterminated`,
+		`(a = 1`,
+		`a IN ()`,
+		`CAST(a AS decimal)`,
+		`CASE END`,
+		`a = 1 b`,
+		`a # 1`,
+	} {
+		_, err := Parse(text)
+		require.Error(t, err, text)
+		var syntaxErr *SyntaxError
+		require.ErrorAs(t, err, &syntaxErr, text)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, text := range []string{
+		`missing + 1`,
+		`first + 1`,
+		`first > 1`,
+		`NOT amount`,
+		`unknown(first)`,
+		`lower(first, last)`,
+		`lower(id)`,
+		`CASE WHEN id > 1 THEN first ELSE ts END`,
+		`date_trunc(first, ts)`,
+		`json_value(doc, first)`,
+	} {
+		expr, err := Parse(text)
+		require.NoError(t, err, text)
+		_, err = Compile(expr, testSchema)
+		require.Error(t, err, text)
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	for _, text := range []string{
+		`id / 0`,
+		`CAST(first AS int64)`,
+		`CAST(amount * 1000 AS int8)`,
+	} {
+		expr, err := Parse(text)
+		require.NoError(t, err, text)
+		program, err := Compile(expr, testSchema)
+		require.NoError(t, err, text)
+		_, err = program.Eval(testItem())
+		require.Error(t, err, text)
+	}
+}
+
+func TestTerms(t *testing.T) {
+	expr, err := Parse(`a > -10 AND b IN ("x", 'y') AND c = NULL AND d NOT IN (2020-01-01, 2021-01-01T10:00:00+03:00) AND e ~ "z"`)
+	require.NoError(t, err)
+	terms, ok := Terms(expr)
+	require.True(t, ok)
+	require.Len(t, terms, 5)
+
+	require.Equal(t, "a", terms[0].Attribute)
+	require.Equal(t, Greater, terms[0].Operator)
+	require.Equal(t, int64(-10), terms[0].Value.AsInt())
+	require.Equal(t, In, terms[1].Operator)
+	require.Equal(t, []string{"x", "y"}, terms[1].Value.AsStringList())
+	require.True(t, terms[2].Value.IsNull())
+	require.Equal(t, NotIn, terms[3].Operator)
+	require.True(t, terms[3].Value.IsTimeList())
+	require.Equal(t, time.Date(2021, time.January, 1, 7, 0, 0, 0, time.UTC), terms[3].Value.AsTimeList()[1].UTC())
+	require.Equal(t, Match, terms[4].Operator)
+
+	for _, text := range []string{
+		`a = 1 OR b = 2`,
+		`a + 1 = 2`,
+		`a IN (1, 'x')`,
+		`1 = a`,
+		`a > NULL`,
+	} {
+		expr, err := Parse(text)
+		require.NoError(t, err, text)
+		_, ok := Terms(expr)
+		require.False(t, ok, text)
+	}
+}
@@ -0,0 +1,484 @@
+package expression
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+// function is a built-in function, maxArgs is negative for variadic functions
+type function struct {
+	minArgs int
+	maxArgs int
+	// compile checks the arguments, exprs are passed for arguments which must be literals
+	compile func(args []compiled, exprs []Expr) (compiled, error)
+}
+
+// functionName makes names case-insensitive & underscores optional, so to_date, toDate & TODATE are the same function
+func functionName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", "")
+}
+
+var functions = map[string]function{
+	// strings
+	"lower":      {1, 1, scalar(schema.TypeString, params(schema.TypeString), stringFunc(strings.ToLower))},
+	"upper":      {1, 1, scalar(schema.TypeString, params(schema.TypeString), stringFunc(strings.ToUpper))},
+	"trim":       {1, 1, scalar(schema.TypeString, params(schema.TypeString), stringFunc(strings.TrimSpace))},
+	"ltrim":      {1, 1, scalar(schema.TypeString, params(schema.TypeString), stringFunc(trimLeft))},
+	"rtrim":      {1, 1, scalar(schema.TypeString, params(schema.TypeString), stringFunc(trimRight))},
+	"length":     {1, 1, scalar(schema.TypeInt64, params(schema.TypeString), length)},
+	"substring":  {2, 3, scalar(schema.TypeString, params(schema.TypeString, schema.TypeInt64, schema.TypeInt64), substring)},
+	"substr":     {2, 3, scalar(schema.TypeString, params(schema.TypeString, schema.TypeInt64, schema.TypeInt64), substring)},
+	"replace":    {3, 3, scalar(schema.TypeString, params(schema.TypeString, schema.TypeString, schema.TypeString), replace)},
+	"startswith": {2, 2, scalar(schema.TypeBoolean, params(schema.TypeString, schema.TypeString), startsWith)},
+	"endswith":   {2, 2, scalar(schema.TypeBoolean, params(schema.TypeString, schema.TypeString), endsWith)},
+	"splitpart":  {3, 3, scalar(schema.TypeString, params(schema.TypeString, schema.TypeString, schema.TypeInt64), splitPart)},
+	"concat":     {1, -1, concatFunc},
+	// numbers
+	"abs":   {1, 1, abs},
+	"round": {1, 2, scalar(schema.TypeFloat64, params(schema.TypeFloat64, schema.TypeInt64), round)},
+	"floor": {1, 1, scalar(schema.TypeFloat64, params(schema.TypeFloat64), floatFunc(math.Floor))},
+	"ceil":  {1, 1, scalar(schema.TypeFloat64, params(schema.TypeFloat64), floatFunc(math.Ceil))},
+	// dates & times
+	"todate":      {1, 1, castFunc(schema.TypeDate)},
+	"todatetime":  {1, 1, castFunc(schema.TypeDatetime)},
+	"totimestamp": {1, 1, castFunc(schema.TypeTimestamp)},
+	"datetrunc":   {2, 2, dateTrunc},
+	"year":        {1, 1, scalar(schema.TypeInt64, params(schema.TypeTimestamp), timePart(func(t time.Time) int { return t.Year() }))},
+	"month":       {1, 1, scalar(schema.TypeInt64, params(schema.TypeTimestamp), timePart(func(t time.Time) int { return int(t.Month()) }))},
+	"day":         {1, 1, scalar(schema.TypeInt64, params(schema.TypeTimestamp), timePart(time.Time.Day))},
+	"hour":        {1, 1, scalar(schema.TypeInt64, params(schema.TypeTimestamp), timePart(time.Time.Hour))},
+	"minute":      {1, 1, scalar(schema.TypeInt64, params(schema.TypeTimestamp), timePart(time.Time.Minute))},
+	"second":      {1, 1, scalar(schema.TypeInt64, params(schema.TypeTimestamp), timePart(time.Time.Second))},
+	"dayofweek":   {1, 1, scalar(schema.TypeInt64, params(schema.TypeTimestamp), timePart(isoWeekday))},
+	// conditions
+	"if":       {3, 3, ifFunc},
+	"coalesce": {1, -1, coalesce},
+	"nullif":   {2, 2, nullIf},
+	// JSON
+	"jsonvalue": {2, 2, jsonValue},
+}
+
+func (c *compiler) call(e *Call) (compiled, error) {
+	fn, ok := functions[functionName(e.Name)]
+	if !ok {
+		return compiled{}, xerrors.Errorf("unknown function %s", e.Name)
+	}
+	if len(e.Args) < fn.minArgs || (fn.maxArgs >= 0 && len(e.Args) > fn.maxArgs) {
+		return compiled{}, xerrors.Errorf("%s: unexpected number of arguments %d", e, len(e.Args))
+	}
+	args, err := c.compileAll(e.Args)
+	if err != nil {
+		return compiled{}, err
+	}
+	result, err := fn.compile(args, e.Args)
+	if err != nil {
+		return compiled{}, xerrors.Errorf("%s: %w", e, err)
+	}
+	return compiled{typ: result.typ, eval: func(item *abstract.ChangeItem) (any, error) {
+		value, err := result.eval(item)
+		if err != nil {
+			return nil, xerrors.Errorf("%s: %w", e.Name, err)
+		}
+		return value, nil
+	}}, nil
+}
+
+func params(types ...schema.Type) []schema.Type {
+	return types
+}
+
+// scalar declares a function of the arguments converted to the params, the result is NULL if any of arguments is NULL
+func scalar(result schema.Type, params []schema.Type, f func(args []any) (any, error)) func([]compiled, []Expr) (compiled, error) {
+	return func(args []compiled, _ []Expr) (compiled, error) {
+		converted := make([]compiled, len(args))
+		for i, arg := range args {
+			var err error
+			if converted[i], err = implicit(arg, params[i]); err != nil {
+				return compiled{}, xerrors.Errorf("argument %d: %w", i+1, err)
+			}
+		}
+		return compiled{typ: result, eval: func(item *abstract.ChangeItem) (any, error) {
+			values, err := evalAll(item, converted...)
+			if err != nil || values == nil {
+				return nil, err
+			}
+			return f(values)
+		}}, nil
+	}
+}
+
+func stringFunc(f func(string) string) func([]any) (any, error) {
+	return func(args []any) (any, error) {
+		return f(args[0].(string)), nil
+	}
+}
+
+func floatFunc(f func(float64) float64) func([]any) (any, error) {
+	return func(args []any) (any, error) {
+		return f(args[0].(float64)), nil
+	}
+}
+
+func trimLeft(value string) string {
+	return strings.TrimLeft(value, " \t\r\n")
+}
+
+func trimRight(value string) string {
+	return strings.TrimRight(value, " \t\r\n")
+}
+
+func length(args []any) (any, error) {
+	return int64(utf8.RuneCountInString(args[0].(string))), nil
+}
+
+// substring counts characters from 1 like SQL does, the length is optional
+func substring(args []any) (any, error) {
+	runes := []rune(args[0].(string))
+	begin := args[1].(int64) - 1
+	end := int64(len(runes))
+	if len(args) > 2 {
+		if args[2].(int64) < 0 {
+			return nil, xerrors.Errorf("negative length %d", args[2])
+		}
+		end = min(end, begin+args[2].(int64))
+	}
+	begin = max(begin, 0)
+	if begin >= end {
+		return "", nil
+	}
+	return string(runes[begin:end]), nil
+}
+
+func replace(args []any) (any, error) {
+	return strings.ReplaceAll(args[0].(string), args[1].(string), args[2].(string)), nil
+}
+
+func startsWith(args []any) (any, error) {
+	return strings.HasPrefix(args[0].(string), args[1].(string)), nil
+}
+
+func endsWith(args []any) (any, error) {
+	return strings.HasSuffix(args[0].(string), args[1].(string)), nil
+}
+
+// splitPart returns the n-th part of the string counting from 1, or an empty string if there is no such part
+func splitPart(args []any) (any, error) {
+	n := args[2].(int64)
+	if n < 1 {
+		return nil, xerrors.Errorf("part number %d must be positive", n)
+	}
+	parts := strings.Split(args[0].(string), args[1].(string))
+	if n > int64(len(parts)) {
+		return "", nil
+	}
+	return parts[n-1], nil
+}
+
+// concatFunc skips NULL arguments unlike ||
+func concatFunc(args []compiled, _ []Expr) (compiled, error) {
+	converted := make([]compiled, len(args))
+	for i, arg := range args {
+		converted[i] = convert(arg, schema.TypeString)
+	}
+	return compiled{typ: schema.TypeString, eval: func(item *abstract.ChangeItem) (any, error) {
+		var result strings.Builder
+		for _, arg := range converted {
+			value, err := arg.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			if value != nil {
+				result.WriteString(value.(string))
+			}
+		}
+		return result.String(), nil
+	}}, nil
+}
+
+func abs(args []compiled, _ []Expr) (compiled, error) {
+	typ := schema.TypeFloat64
+	if kindOf(args[0].typ) == kindInt {
+		typ = schema.TypeInt64
+	}
+	return scalar(typ, params(typ), func(values []any) (any, error) {
+		if v, ok := values[0].(int64); ok {
+			if v < 0 {
+				return -v, nil
+			}
+			return v, nil
+		}
+		return math.Abs(values[0].(float64)), nil
+	})(args, nil)
+}
+
+func round(args []any) (any, error) {
+	if len(args) == 1 {
+		return math.Round(args[0].(float64)), nil
+	}
+	scale := math.Pow10(int(args[1].(int64)))
+	return math.Round(args[0].(float64)*scale) / scale, nil
+}
+
+func castFunc(typ schema.Type) func([]compiled, []Expr) (compiled, error) {
+	return func(args []compiled, _ []Expr) (compiled, error) {
+		return convert(args[0], typ), nil
+	}
+}
+
+func timePart(f func(time.Time) int) func([]any) (any, error) {
+	return func(args []any) (any, error) {
+		return int64(f(args[0].(time.Time).UTC())), nil
+	}
+}
+
+// isoWeekday numbers days from Monday as 1 to Sunday as 7
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+var truncUnits = map[string]func(time.Time) time.Time{
+	"second": func(t time.Time) time.Time { return t.Truncate(time.Second) },
+	"minute": func(t time.Time) time.Time { return t.Truncate(time.Minute) },
+	"hour":   func(t time.Time) time.Time { return t.Truncate(time.Hour) },
+	"day":    truncateDate,
+	"week": func(t time.Time) time.Time {
+		return truncateDate(t).AddDate(0, 0, 1-isoWeekday(t))
+	},
+	"month": func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	},
+	"quarter": func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month()-(t.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	},
+	"year": func(t time.Time) time.Time {
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	},
+}
+
+// dateTrunc truncates a time in UTC to the unit, which must be a literal
+func dateTrunc(args []compiled, exprs []Expr) (compiled, error) {
+	unit, err := stringLiteral(exprs[0])
+	if err != nil {
+		return compiled{}, xerrors.Errorf("argument 1: %w", err)
+	}
+	trunc, ok := truncUnits[strings.ToLower(unit)]
+	if !ok {
+		return compiled{}, xerrors.Errorf("unknown unit %s", unit)
+	}
+	return scalar(schema.TypeTimestamp, params(schema.TypeString, schema.TypeTimestamp), func(values []any) (any, error) {
+		return trunc(values[1].(time.Time).UTC()), nil
+	})(args, exprs)
+}
+
+func stringLiteral(expr Expr) (string, error) {
+	if literal, ok := expr.(*Literal); ok {
+		if value, ok := literal.Value.(string); ok {
+			return value, nil
+		}
+	}
+	return "", xerrors.Errorf("expected a string literal, got %s", expr)
+}
+
+func ifFunc(args []compiled, _ []Expr) (compiled, error) {
+	cond, err := implicit(args[0], schema.TypeBoolean)
+	if err != nil {
+		return compiled{}, xerrors.Errorf("argument 1: %w", err)
+	}
+	branches, typ, err := unifyAll(args[1:])
+	if err != nil {
+		return compiled{}, err
+	}
+	return compiled{typ: typ, eval: func(item *abstract.ChangeItem) (any, error) {
+		value, err := cond.eval(item)
+		if err != nil {
+			return nil, err
+		}
+		if value == true {
+			return branches[0].eval(item)
+		}
+		return branches[1].eval(item)
+	}}, nil
+}
+
+func coalesce(args []compiled, _ []Expr) (compiled, error) {
+	branches, typ, err := unifyAll(args)
+	if err != nil {
+		return compiled{}, err
+	}
+	return compiled{typ: typ, eval: func(item *abstract.ChangeItem) (any, error) {
+		for _, branch := range branches {
+			value, err := branch.eval(item)
+			if err != nil || value != nil {
+				return value, err
+			}
+		}
+		return nil, nil
+	}}, nil
+}
+
+func nullIf(args []compiled, _ []Expr) (compiled, error) {
+	if args[1].typ == typeNull {
+		return args[0], nil
+	}
+	compare, err := comparator(args[0], args[1])
+	if err != nil {
+		return compiled{}, err
+	}
+	return compiled{typ: args[0].typ, eval: func(item *abstract.ChangeItem) (any, error) {
+		result, ok, err := compare(item)
+		if err != nil {
+			return nil, err
+		}
+		if ok && result == 0 {
+			return nil, nil
+		}
+		return args[0].eval(item)
+	}}, nil
+}
+
+// jsonValue extracts a value from a JSON document by a path like $.a.b[0]["c"], the path must be a literal.
+// The document is a value of any type or a text with JSON, the result is NULL if the path is not found.
+func jsonValue(args []compiled, exprs []Expr) (compiled, error) {
+	path, err := stringLiteral(exprs[1])
+	if err != nil {
+		return compiled{}, xerrors.Errorf("argument 2: %w", err)
+	}
+	steps, err := parsePath(path)
+	if err != nil {
+		return compiled{}, xerrors.Errorf("invalid path %s: %w", path, err)
+	}
+	doc := args[0]
+	if kindOf(doc.typ) != kindAny && !isText(doc.typ) && doc.typ != typeNull {
+		return compiled{}, xerrors.Errorf("argument 1: expected any or text, got %s", doc.typ)
+	}
+	return compiled{typ: schema.TypeAny, eval: func(item *abstract.ChangeItem) (any, error) {
+		value, err := doc.eval(item)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		if isText(doc.typ) {
+			if value, err = parseJSON(value); err != nil {
+				return nil, err
+			}
+		}
+		return walkPath(value, steps)
+	}}, nil
+}
+
+func parseJSON(value any) (any, error) {
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	}
+	var result any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
+		return nil, xerrors.Errorf("unable to parse JSON: %w", err)
+	}
+	return result, nil
+}
+
+// parsePath splits a path into keys (strings) & indexes (ints), the leading $ is optional
+func parsePath(path string) ([]any, error) {
+	var steps []any
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	if rest != "" && rest[0] != '.' && rest[0] != '[' {
+		rest = "." + rest
+	}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			if end == 0 {
+				return nil, xerrors.New("empty key")
+			}
+			steps = append(steps, rest[1:end+1])
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, xerrors.New("unterminated [")
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			if inner != "" && (inner[0] == '"' || inner[0] == '\'') {
+				key, err := unquote(inner)
+				if err != nil {
+					return nil, xerrors.Errorf("invalid key %s", inner)
+				}
+				steps = append(steps, key)
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, xerrors.Errorf("invalid index %s", inner)
+				}
+				steps = append(steps, index)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, xerrors.Errorf("unexpected %q", rest[0])
+		}
+	}
+	return steps, nil
+}
+
+func walkPath(value any, steps []any) (any, error) {
+	for i, step := range steps {
+		switch v := value.(type) {
+		case map[string]any:
+			key, ok := step.(string)
+			if !ok {
+				return nil, nil
+			}
+			value = v[key]
+		case []any:
+			index, ok := step.(int)
+			if !ok {
+				return nil, nil
+			}
+			if index < 0 {
+				index += len(v)
+			}
+			if index < 0 || index >= len(v) {
+				return nil, nil
+			}
+			value = v[index]
+		case nil:
+			return nil, nil
+		default:
+			// documents of other Go types are walked through their JSON representation
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to marshal %T: %w", v, err)
+			}
+			generic, err := parseJSON(data)
+			if err != nil {
+				return nil, err
+			}
+			switch generic.(type) {
+			case map[string]any, []any:
+				return walkPath(generic, steps[i:])
+			}
+			return nil, nil
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,431 @@
+package expression
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+// typeNames are the names of the types accepted by CAST
+var typeNames = map[string]schema.Type{
+	"int8":      schema.TypeInt8,
+	"int16":     schema.TypeInt16,
+	"int32":     schema.TypeInt32,
+	"int64":     schema.TypeInt64,
+	"uint8":     schema.TypeUint8,
+	"uint16":    schema.TypeUint16,
+	"uint32":    schema.TypeUint32,
+	"uint64":    schema.TypeUint64,
+	"float":     schema.TypeFloat32,
+	"double":    schema.TypeFloat64,
+	"bool":      schema.TypeBoolean,
+	"boolean":   schema.TypeBoolean,
+	"utf8":      schema.TypeString,
+	"string":    schema.TypeBytes,
+	"date":      schema.TypeDate,
+	"datetime":  schema.TypeDatetime,
+	"timestamp": schema.TypeTimestamp,
+	"interval":  schema.TypeInterval,
+	"any":       schema.TypeAny,
+}
+
+// ParseType returns the column type of a name accepted by CAST, names are case-insensitive
+func ParseType(name string) (schema.Type, bool) {
+	typ, ok := typeNames[strings.ToLower(name)]
+	return typ, ok
+}
+
+// Parse parses an expression. The syntax is a superset of the filter syntax of filter_rows:
+// `a = 1 AND b IN ("x", "y")` is a valid expression as well as `CASE WHEN a > 1 THEN upper(b) ELSE b || '!' END`.
+func Parse(text string) (Expr, error) {
+	lexemes, err := lex(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{lexemes: lexemes, pos: 0}
+	if p.peek().tok == EOF {
+		return nil, newSyntaxError(0, "empty expression")
+	}
+	result, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.tok != EOF {
+		return nil, p.unexpected(next)
+	}
+	return result, nil
+}
+
+// parser is a recursive descent parser, each parse method handles a level of the operator precedence:
+// OR, AND, NOT, comparisons, ||, + -, * / %, unary - and primary expressions.
+type parser struct {
+	lexemes []lexeme
+	pos     int
+}
+
+func (p *parser) peek() lexeme {
+	return p.lexemes[p.pos]
+}
+
+func (p *parser) next() lexeme {
+	item := p.lexemes[p.pos]
+	if item.tok != EOF {
+		p.pos++
+	}
+	return item
+}
+
+func (p *parser) expect(tok Token) (lexeme, error) {
+	item := p.next()
+	if item.tok != tok {
+		return item, newSyntaxError(item.pos, "expected %s, got %s", tok, describe(item))
+	}
+	return item, nil
+}
+
+func (p *parser) unexpected(item lexeme) error {
+	return newSyntaxError(item.pos, "unexpected %s", describe(item))
+}
+
+func describe(item lexeme) string {
+	if item.tok == EOF {
+		return "end of expression"
+	}
+	return strconv.Quote(item.text)
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	lhs, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().tok == OR {
+		p.next()
+		rhs, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &Binary{Op: OR, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	lhs, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().tok == AND {
+		p.next()
+		rhs, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &Binary{Op: AND, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.peek().tok != NOT {
+		return p.parseComparison()
+	}
+	p.next()
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return &Unary{Op: NOT, X: x}, nil
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	lhs, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.peek(); op.tok {
+	case EQ, NEQ, LT, LTE, GT, GTE, MATCH, NOTMATCH:
+		p.next()
+		rhs, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		return &Binary{Op: op.tok, LHS: lhs, RHS: rhs}, nil
+	case NOT:
+		p.next()
+		if _, err := p.expect(IN); err != nil {
+			return nil, err
+		}
+		return p.parseIn(lhs, true)
+	case IN:
+		p.next()
+		return p.parseIn(lhs, false)
+	case IS:
+		p.next()
+		not := p.peek().tok == NOT
+		if not {
+			p.next()
+		}
+		if _, err := p.expect(NULL); err != nil {
+			return nil, err
+		}
+		return &IsNull{X: lhs, Not: not}, nil
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseIn(x Expr, not bool) (Expr, error) {
+	if _, err := p.expect(LPAREN); err != nil {
+		return nil, err
+	}
+	items, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, newSyntaxError(p.lexemes[p.pos-1].pos, "empty list")
+	}
+	return &InList{X: x, Items: items, Not: not}, nil
+}
+
+// parseList parses comma separated expressions till the closing parenthesis
+func (p *parser) parseList() ([]Expr, error) {
+	var items []Expr
+	if p.peek().tok == RPAREN {
+		p.next()
+		return items, nil
+	}
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		switch sep := p.next(); sep.tok {
+		case COMMA:
+			continue
+		case RPAREN:
+			return items, nil
+		default:
+			return nil, newSyntaxError(sep.pos, "expected , or ), got %s", describe(sep))
+		}
+	}
+}
+
+func (p *parser) parseConcat() (Expr, error) {
+	lhs, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().tok == CONCAT {
+		p.next()
+		rhs, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &Binary{Op: CONCAT, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseAdditive() (Expr, error) {
+	lhs, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op.tok == PLUS || op.tok == MINUS; op = p.peek() {
+		p.next()
+		rhs, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &Binary{Op: op.tok, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseMultiplicative() (Expr, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op.tok == MUL || op.tok == DIV || op.tok == MOD; op = p.peek() {
+		p.next()
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &Binary{Op: op.tok, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+// parseUnary folds signs of numeric literals, so `-5` is a literal as it is in the filter syntax
+func (p *parser) parseUnary() (Expr, error) {
+	op := p.peek()
+	if op.tok != MINUS && op.tok != PLUS {
+		return p.parsePrimary()
+	}
+	p.next()
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if op.tok == PLUS {
+		return x, nil
+	}
+	if literal, ok := x.(*Literal); ok {
+		switch v := literal.Value.(type) {
+		case int64:
+			return &Literal{Value: -v}, nil
+		case float64:
+			return &Literal{Value: -v}, nil
+		}
+	}
+	return &Unary{Op: MINUS, X: x}, nil
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	item := p.next()
+	switch item.tok {
+	case INT:
+		value, err := strconv.ParseInt(item.text, 10, 64)
+		if err != nil {
+			return nil, newSyntaxError(item.pos, "invalid integer %s: %v", item.text, xerrors.Unwrap(err))
+		}
+		return &Literal{Value: value}, nil
+	case FLOAT:
+		value, err := strconv.ParseFloat(item.text, 64)
+		if err != nil {
+			return nil, newSyntaxError(item.pos, "invalid number %s: %v", item.text, xerrors.Unwrap(err))
+		}
+		return &Literal{Value: value}, nil
+	case STRING:
+		value, err := unquote(item.text)
+		if err != nil {
+			return nil, newSyntaxError(item.pos, "invalid string %s", item.text)
+		}
+		return &Literal{Value: value}, nil
+	case DATETIME:
+		value, err := time.Parse(dateTimeLayout(item.text), item.text)
+		if err != nil {
+			return nil, newSyntaxError(item.pos, "invalid date %s: %v", item.text, err)
+		}
+		return &Literal{Value: value}, nil
+	case TRUE, FALSE:
+		return &Literal{Value: item.tok == TRUE}, nil
+	case NULL:
+		return &Literal{Value: nil}, nil
+	case LPAREN:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(RPAREN); err != nil {
+			return nil, err
+		}
+		return x, nil
+	case CASE:
+		return p.parseCase()
+	case CAST:
+		return p.parseCast()
+	case IDENT:
+		if strings.HasPrefix(item.text, "`") {
+			return &Column{Name: item.text[1 : len(item.text)-1]}, nil
+		}
+		if p.peek().tok != LPAREN {
+			return &Column{Name: item.text}, nil
+		}
+		p.next()
+		args, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &Call{Name: item.text, Args: args}, nil
+	}
+	return nil, p.unexpected(item)
+}
+
+func (p *parser) parseCase() (Expr, error) {
+	result := &Case{Whens: nil, Else: nil}
+	for p.peek().tok == WHEN {
+		p.next()
+		cond, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(THEN); err != nil {
+			return nil, err
+		}
+		then, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		result.Whens = append(result.Whens, When{Cond: cond, Then: then})
+	}
+	if len(result.Whens) == 0 {
+		return nil, p.unexpected(p.peek())
+	}
+	if p.peek().tok == ELSE {
+		p.next()
+		elseExpr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		result.Else = elseExpr
+	}
+	if _, err := p.expect(END); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (p *parser) parseCast() (Expr, error) {
+	if _, err := p.expect(LPAREN); err != nil {
+		return nil, err
+	}
+	x, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(AS); err != nil {
+		return nil, err
+	}
+	name, err := p.expect(IDENT)
+	if err != nil {
+		return nil, err
+	}
+	typ, ok := ParseType(name.text)
+	if !ok {
+		return nil, newSyntaxError(name.pos, "unknown type %s", name.text)
+	}
+	if _, err := p.expect(RPAREN); err != nil {
+		return nil, err
+	}
+	return &Cast{X: x, Type: typ}, nil
+}
+
+// dateTimeLayout returns the layout of a date literal, literals without a time zone are in UTC
+func dateTimeLayout(value string) string {
+	timeIndex := strings.Index(value, "T")
+	if timeIndex < 0 {
+		return time.DateOnly
+	}
+	timePart := value[timeIndex:]
+	tzLayout := ""
+	if tzIndex := strings.IndexAny(timePart, "+-Z"); tzIndex >= 0 {
+		tzLayout = "Z07"
+		if strings.Contains(timePart[tzIndex:], ":") {
+			tzLayout = "Z07:00"
+		}
+		timePart = timePart[:tzIndex]
+	}
+	timeLayout := "T15:04"
+	if strings.Count(timePart, ":") == 2 {
+		timeLayout = "T15:04:05"
+	}
+	return time.DateOnly + timeLayout + tzLayout
+}
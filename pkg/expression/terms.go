@@ -0,0 +1,284 @@
+package expression
+
+import (
+	"time"
+)
+
+// OperatorType is an operator of a Term.
+type OperatorType int
+
+const (
+	Equals OperatorType = iota
+	NotEquals
+	Less
+	LessOrEquals
+	Greater
+	GreaterOrEquals
+	In
+	NotIn
+	Match
+	NotMatch
+)
+
+func (o OperatorType) String() string {
+	switch o {
+	case Equals:
+		return "="
+	case NotEquals:
+		return "!="
+	case Greater:
+		return ">"
+	case GreaterOrEquals:
+		return ">="
+	case Less:
+		return "<"
+	case LessOrEquals:
+		return "<="
+	case In:
+		return "IN"
+	case NotIn:
+		return "NOT IN"
+	case Match:
+		return "~"
+	case NotMatch:
+		return "!~"
+	}
+	return "Unknown"
+}
+
+var termOperators = map[Token]OperatorType{
+	EQ:       Equals,
+	NEQ:      NotEquals,
+	LT:       Less,
+	LTE:      LessOrEquals,
+	GT:       Greater,
+	GTE:      GreaterOrEquals,
+	MATCH:    Match,
+	NOTMATCH: NotMatch,
+}
+
+// Term is a `column operator literal` comparison, a conjunction of terms is the filter syntax of filter_rows.
+type Term struct {
+	Attribute string
+	Operator  OperatorType
+	Value     Value
+}
+
+// Value is a literal of a term or a list of literals of the same type for [NOT] IN.
+type Value struct {
+	value any
+	list  []any
+}
+
+func (v Value) Type() string {
+	switch {
+	case v.IsBool():
+		return "bool"
+	case v.IsString():
+		return "string"
+	case v.IsFloat():
+		return "float"
+	case v.IsInt():
+		return "int"
+	case v.IsTime():
+		return "time"
+	case v.IsNull():
+		return "null"
+	case v.IsBoolList():
+		return "bool list"
+	case v.IsStringList():
+		return "string list"
+	case v.IsFloatList():
+		return "float list"
+	case v.IsIntList():
+		return "int list"
+	case v.IsTimeList():
+		return "time list"
+	}
+	return "unknown"
+}
+
+func (v Value) IsBool() bool {
+	_, ok := v.value.(bool)
+	return ok
+}
+
+func (v Value) AsBool() bool {
+	return v.value.(bool)
+}
+
+func (v Value) IsString() bool {
+	_, ok := v.value.(string)
+	return ok
+}
+
+func (v Value) AsString() string {
+	return v.value.(string)
+}
+
+func (v Value) IsFloat() bool {
+	_, ok := v.value.(float64)
+	return ok
+}
+
+func (v Value) AsFloat() float64 {
+	return v.value.(float64)
+}
+
+func (v Value) IsInt() bool {
+	_, ok := v.value.(int64)
+	return ok
+}
+
+func (v Value) AsInt() int64 {
+	return v.value.(int64)
+}
+
+func (v Value) IsTime() bool {
+	_, ok := v.value.(time.Time)
+	return ok
+}
+
+func (v Value) AsTime() time.Time {
+	return v.value.(time.Time)
+}
+
+func (v Value) IsNull() bool {
+	return v.value == nil && v.list == nil
+}
+
+func (v Value) IsBoolList() bool {
+	return isList[bool](v.list)
+}
+
+func (v Value) AsBoolList() []bool {
+	return asList[bool](v.list)
+}
+
+func (v Value) IsStringList() bool {
+	return isList[string](v.list)
+}
+
+func (v Value) AsStringList() []string {
+	return asList[string](v.list)
+}
+
+func (v Value) IsFloatList() bool {
+	return isList[float64](v.list)
+}
+
+func (v Value) AsFloatList() []float64 {
+	return asList[float64](v.list)
+}
+
+func (v Value) IsIntList() bool {
+	return isList[int64](v.list)
+}
+
+func (v Value) AsIntList() []int64 {
+	return asList[int64](v.list)
+}
+
+func (v Value) IsTimeList() bool {
+	return isList[time.Time](v.list)
+}
+
+func (v Value) AsTimeList() []time.Time {
+	return asList[time.Time](v.list)
+}
+
+func isList[T any](list []any) bool {
+	if len(list) == 0 {
+		return false
+	}
+	_, ok := list[0].(T)
+	return ok
+}
+
+func asList[T any](list []any) []T {
+	result := make([]T, len(list))
+	for i, item := range list {
+		result[i] = item.(T)
+	}
+	return result
+}
+
+// Terms returns the terms of an expression if it's a conjunction of terms, i.e. it's written in the filter syntax.
+// Lists of IN must consist of non-NULL literals of the same type & NULL is compared by = and != only.
+func Terms(expr Expr) ([]Term, bool) {
+	var result []Term
+	for _, conjunct := range conjuncts(expr) {
+		term, ok := toTerm(conjunct)
+		if !ok {
+			return nil, false
+		}
+		result = append(result, term)
+	}
+	return result, true
+}
+
+func conjuncts(expr Expr) []Expr {
+	if binary, ok := expr.(*Binary); ok && binary.Op == AND {
+		return append(conjuncts(binary.LHS), conjuncts(binary.RHS)...)
+	}
+	return []Expr{expr}
+}
+
+func toTerm(expr Expr) (Term, bool) {
+	switch e := expr.(type) {
+	case *Binary:
+		column, isColumn := e.LHS.(*Column)
+		literal, isLiteral := e.RHS.(*Literal)
+		op, isTermOperator := termOperators[e.Op]
+		if !isColumn || !isLiteral || !isTermOperator {
+			return Term{}, false
+		}
+		if literal.Value == nil && op != Equals && op != NotEquals {
+			return Term{}, false
+		}
+		return Term{Attribute: column.Name, Operator: op, Value: Value{value: literal.Value, list: nil}}, true
+	case *InList:
+		column, isColumn := e.X.(*Column)
+		if !isColumn {
+			return Term{}, false
+		}
+		list := make([]any, len(e.Items))
+		for i, item := range e.Items {
+			literal, isLiteral := item.(*Literal)
+			if !isLiteral || literal.Value == nil || !sameType(literal.Value, list[0]) {
+				return Term{}, false
+			}
+			list[i] = literal.Value
+		}
+		op := In
+		if e.Not {
+			op = NotIn
+		}
+		return Term{Attribute: column.Name, Operator: op, Value: Value{value: nil, list: list}}, true
+	}
+	return Term{}, false
+}
+
+func sameType(value, head any) bool {
+	if head == nil {
+		return true
+	}
+	switch head.(type) {
+	case bool:
+		_, ok := value.(bool)
+		return ok
+	case int64:
+		_, ok := value.(int64)
+		return ok
+	case float64:
+		_, ok := value.(float64)
+		return ok
+	case string:
+		_, ok := value.(string)
+		return ok
+	case time.Time:
+		_, ok := value.(time.Time)
+		return ok
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package expression
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token represents a lexical token.
+type Token int
+
+const (
+	// ILLEGAL token represents an illegal token found in the expression
+	ILLEGAL Token = iota
+	// EOF token represents the end of the expression
+	EOF
+
+	// Literals & names
+	IDENT    // column, `quoted column`, function
+	INT      // 12345
+	FLOAT    // 12345.67
+	STRING   // 'abc', "abc"
+	DATETIME // 2006-01-02T15:04:05Z
+
+	LPAREN // (
+	RPAREN // )
+	COMMA  // ,
+
+	// Operators
+	EQ       // =, ==
+	NEQ      // !=, <>
+	LT       // <
+	LTE      // <=
+	GT       // >
+	GTE      // >=
+	MATCH    // ~
+	NOTMATCH // !~
+	PLUS     // +
+	MINUS    // -
+	MUL      // *
+	DIV      // /
+	MOD      // %
+	CONCAT   // ||
+
+	// Keywords
+	AND   // AND
+	OR    // OR
+	NOT   // NOT
+	IN    // IN
+	IS    // IS
+	NULL  // NULL, NIL
+	TRUE  // TRUE
+	FALSE // FALSE
+	CASE  // CASE
+	WHEN  // WHEN
+	THEN  // THEN
+	ELSE  // ELSE
+	END   // END
+	CAST  // CAST
+	AS    // AS
+)
+
+var tokens = [...]string{
+	ILLEGAL: "ILLEGAL",
+	EOF:     "EOF",
+
+	IDENT:    "IDENT",
+	INT:      "INT",
+	FLOAT:    "FLOAT",
+	STRING:   "STRING",
+	DATETIME: "DATETIME",
+
+	LPAREN: "(",
+	RPAREN: ")",
+	COMMA:  ",",
+
+	EQ:       "=",
+	NEQ:      "!=",
+	LT:       "<",
+	LTE:      "<=",
+	GT:       ">",
+	GTE:      ">=",
+	MATCH:    "~",
+	NOTMATCH: "!~",
+	PLUS:     "+",
+	MINUS:    "-",
+	MUL:      "*",
+	DIV:      "/",
+	MOD:      "%",
+	CONCAT:   "||",
+
+	AND:   "AND",
+	OR:    "OR",
+	NOT:   "NOT",
+	IN:    "IN",
+	IS:    "IS",
+	NULL:  "NULL",
+	TRUE:  "TRUE",
+	FALSE: "FALSE",
+	CASE:  "CASE",
+	WHEN:  "WHEN",
+	THEN:  "THEN",
+	ELSE:  "ELSE",
+	END:   "END",
+	CAST:  "CAST",
+	AS:    "AS",
+}
+
+// String returns the string representation of the token.
+func (tok Token) String() string {
+	if tok >= 0 && tok < Token(len(tokens)) {
+		return tokens[tok]
+	}
+	return ""
+}
+
+// keywords are case-insensitive, NIL is an alias of NULL
+var keywords = map[string]Token{
+	"and":   AND,
+	"or":    OR,
+	"not":   NOT,
+	"in":    IN,
+	"is":    IS,
+	"null":  NULL,
+	"nil":   NULL,
+	"true":  TRUE,
+	"false": FALSE,
+	"case":  CASE,
+	"when":  WHEN,
+	"then":  THEN,
+	"else":  ELSE,
+	"end":   END,
+	"cast":  CAST,
+	"as":    AS,
+}
+
+// SyntaxError is returned by Parse for a malformed expression, Pos is a 1-based column of the error.
+type SyntaxError struct {
+	Pos     int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at or near column %d: %s", e.Pos, e.Message)
+}
+
+func newSyntaxError(pos int, format string, args ...any) error {
+	return &SyntaxError{Pos: pos + 1, Message: fmt.Sprintf(format, args...)}
+}
+
+// lexeme is a token read from the expression, pos is a byte offset of the token.
+type lexeme struct {
+	tok  Token
+	text string
+	pos  int
+}
+
+var (
+	// the same date & time literals as the ones of the filter syntax, the literal is not quoted
+	dateTimeRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(T\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d+(:\d+)?)?)?`)
+	numberRe   = regexp.MustCompile(`^\d+(\.\d+)?([eE][+-]?\d+)?`)
+)
+
+// lex splits the expression into lexemes, the last one is EOF.
+func lex(text string) ([]lexeme, error) {
+	var result []lexeme
+	pos := 0
+	for {
+		for pos < len(text) {
+			r, size := utf8.DecodeRuneInString(text[pos:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			pos += size
+		}
+		if pos == len(text) {
+			return append(result, lexeme{tok: EOF, text: "", pos: pos}), nil
+		}
+		item, err := lexOne(text, pos)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+		pos += len(item.text)
+	}
+}
+
+func lexOne(text string, pos int) (lexeme, error) {
+	rest := text[pos:]
+	ch := rest[0]
+	switch {
+	case ch >= '0' && ch <= '9':
+		if match := dateTimeRe.FindString(rest); match != "" {
+			return lexeme{tok: DATETIME, text: match, pos: pos}, nil
+		}
+		match := numberRe.FindString(rest)
+		if strings.ContainsAny(match, ".eE") {
+			return lexeme{tok: FLOAT, text: match, pos: pos}, nil
+		}
+		return lexeme{tok: INT, text: match, pos: pos}, nil
+	case isIdentStart(ch):
+		end := 1
+		for end < len(rest) && isIdentPart(rest[end]) {
+			end++
+		}
+		if tok, ok := keywords[strings.ToLower(rest[:end])]; ok {
+			return lexeme{tok: tok, text: rest[:end], pos: pos}, nil
+		}
+		return lexeme{tok: IDENT, text: rest[:end], pos: pos}, nil
+	case ch == '`':
+		end := strings.IndexByte(rest[1:], '`')
+		if end < 0 {
+			return lexeme{}, newSyntaxError(pos, "unterminated quoted identifier")
+		}
+		return lexeme{tok: IDENT, text: rest[:end+2], pos: pos}, nil
+	case ch == '\'' || ch == '"':
+		for end := 1; end < len(rest); end++ {
+			switch rest[end] {
+			case '\\':
+				end++
+			case ch:
+				return lexeme{tok: STRING, text: rest[:end+1], pos: pos}, nil
+			}
+		}
+		return lexeme{}, newSyntaxError(pos, "unterminated string")
+	}
+	for _, op := range []struct {
+		text string
+		tok  Token
+	}{
+		{"==", EQ}, {"!=", NEQ}, {"<>", NEQ}, {"<=", LTE}, {">=", GTE}, {"!~", NOTMATCH}, {"||", CONCAT},
+		{"=", EQ}, {"<", LT}, {">", GT}, {"~", MATCH}, {"+", PLUS}, {"-", MINUS}, {"*", MUL}, {"/", DIV}, {"%", MOD},
+		{"(", LPAREN}, {")", RPAREN}, {",", COMMA},
+	} {
+		if strings.HasPrefix(rest, op.text) {
+			return lexeme{tok: op.tok, text: op.text, pos: pos}, nil
+		}
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return lexeme{}, newSyntaxError(pos, "unexpected character %q", r)
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+// isIdentPart allows dots for nested names like the filter syntax does
+func isIdentPart(ch byte) bool {
+	return isIdentStart(ch) || (ch >= '0' && ch <= '9') || ch == '.'
+}
+
+// unquote handles both single & double quoted strings with Go escape sequences
+func unquote(text string) (string, error) {
+	quote := text[0]
+	body := text[1 : len(text)-1]
+	var result strings.Builder
+	for body != "" {
+		value, multibyte, tail, err := strconv.UnquoteChar(body, quote)
+		if err != nil {
+			return "", err
+		}
+		if value < utf8.RuneSelf || multibyte {
+			result.WriteRune(value)
+		} else {
+			result.WriteByte(byte(value))
+		}
+		body = tail
+	}
+	return result.String(), nil
+}
@@ -0,0 +1,548 @@
+package expression
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+// typeNull is the type of the NULL literal, it's never a type of a compiled program
+const typeNull = schema.Type("null")
+
+// kind groups types with the same runtime representation of values:
+// integers are int64, floats are float64, utf8 is string, bytes are []byte,
+// date, datetime & timestamp are time.Time and intervals are time.Duration
+type kind int
+
+const (
+	kindNull kind = iota
+	kindInt
+	kindFloat
+	kindBool
+	kindUtf8
+	kindBytes
+	kindTime
+	kindInterval
+	kindAny
+)
+
+func kindOf(typ schema.Type) kind {
+	switch typ {
+	case typeNull:
+		return kindNull
+	case schema.TypeInt8, schema.TypeInt16, schema.TypeInt32, schema.TypeInt64,
+		schema.TypeUint8, schema.TypeUint16, schema.TypeUint32, schema.TypeUint64:
+		return kindInt
+	case schema.TypeFloat32, schema.TypeFloat64:
+		return kindFloat
+	case schema.TypeBoolean:
+		return kindBool
+	case schema.TypeString:
+		return kindUtf8
+	case schema.TypeBytes:
+		return kindBytes
+	case schema.TypeDate, schema.TypeDatetime, schema.TypeTimestamp:
+		return kindTime
+	case schema.TypeInterval:
+		return kindInterval
+	default:
+		return kindAny
+	}
+}
+
+func isNumeric(typ schema.Type) bool {
+	return kindOf(typ) == kindInt || kindOf(typ) == kindFloat
+}
+
+func isText(typ schema.Type) bool {
+	return kindOf(typ) == kindUtf8 || kindOf(typ) == kindBytes
+}
+
+// columnType maps a data type of a column to the type of the expression
+func columnType(dataType string) schema.Type {
+	typ := schema.Type(strings.ToLower(dataType))
+	if kindOf(typ) == kindAny {
+		return schema.TypeAny
+	}
+	return typ
+}
+
+// unify returns a type which values of both types are cast to, e.g. for branches of CASE
+func unify(a, b schema.Type) (schema.Type, bool) {
+	switch {
+	case a == b:
+		return a, true
+	case a == typeNull:
+		return b, true
+	case b == typeNull:
+		return a, true
+	case kindOf(a) == kindAny || kindOf(b) == kindAny:
+		return schema.TypeAny, true
+	case kindOf(a) == kindInt && kindOf(b) == kindInt:
+		return schema.TypeInt64, true
+	case isNumeric(a) && isNumeric(b):
+		return schema.TypeFloat64, true
+	case isText(a) && isText(b):
+		return schema.TypeString, true
+	case kindOf(a) == kindTime && kindOf(b) == kindTime:
+		return schema.TypeTimestamp, true
+	}
+	return "", false
+}
+
+// normalize converts a value of a column into the runtime representation of its kind
+func normalize(value any, typ schema.Type) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch kindOf(typ) {
+	case kindInt:
+		return toInt64(value)
+	case kindFloat:
+		return toFloat64(value)
+	case kindBool:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case kindUtf8:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		}
+	case kindBytes:
+		switch v := value.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		}
+	case kindTime:
+		switch v := value.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			return parseTime(v)
+		}
+	case kindInterval:
+		if v, ok := value.(time.Duration); ok {
+			return v, nil
+		}
+	case kindAny:
+		return value, nil
+	}
+	return nil, xerrors.Errorf("unexpected value %v of type %T for %s", value, value, typ)
+}
+
+// denormalize converts a runtime value into the value of a column of the type
+func denormalize(value any, typ schema.Type) any {
+	switch v := value.(type) {
+	case int64:
+		switch typ {
+		case schema.TypeInt8:
+			return int8(v)
+		case schema.TypeInt16:
+			return int16(v)
+		case schema.TypeInt32:
+			return int32(v)
+		case schema.TypeUint8:
+			return uint8(v)
+		case schema.TypeUint16:
+			return uint16(v)
+		case schema.TypeUint32:
+			return uint32(v)
+		case schema.TypeUint64:
+			return uint64(v)
+		}
+	case float64:
+		if typ == schema.TypeFloat32 {
+			return float32(v)
+		}
+	case time.Time:
+		if typ == schema.TypeDate {
+			return truncateDate(v)
+		}
+	}
+	return value
+}
+
+func truncateDate(value time.Time) time.Time {
+	return time.Date(value.Year(), value.Month(), value.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func toInt64(value any) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return uintToInt64(uint64(v))
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return uintToInt64(v)
+	case json.Number:
+		return v.Int64()
+	}
+	return 0, xerrors.Errorf("unexpected value %v of type %T for an integer", value, value)
+}
+
+func uintToInt64(value uint64) (int64, error) {
+	if value > math.MaxInt64 {
+		return 0, xerrors.Errorf("value %d overflows int64", value)
+	}
+	return int64(value), nil
+}
+
+func toFloat64(value any) (float64, error) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case json.Number:
+		return v.Float64()
+	}
+	i, err := toInt64(value)
+	if err != nil {
+		return 0, xerrors.Errorf("unexpected value %v of type %T for a float", value, value)
+	}
+	return float64(i), nil
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	time.DateTime,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05 -0700 MST",
+	time.DateOnly,
+}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if result, err := time.Parse(layout, value); err == nil {
+			return result, nil
+		}
+	}
+	return time.Time{}, xerrors.Errorf("unable to parse time %q", value)
+}
+
+// Convert converts a value of any Go type, e.g. a field of a JSON document, into the value of a column of the type like CAST does
+func Convert(value any, typ schema.Type) (any, error) {
+	result, err := castValue(value, schema.TypeAny, typ)
+	if err != nil {
+		return nil, err
+	}
+	return denormalize(result, typ), nil
+}
+
+// castValue converts a runtime value of the type from into a runtime value of the type to
+func castValue(value any, from, to schema.Type) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch kindOf(to) {
+	case kindInt:
+		result, err := castInt(value)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkRange(result, to); err != nil {
+			return nil, err
+		}
+		return result, nil
+	case kindFloat:
+		return castFloat(value)
+	case kindBool:
+		return castBool(value)
+	case kindUtf8:
+		return format(value, from)
+	case kindBytes:
+		if v, ok := value.([]byte); ok {
+			return v, nil
+		}
+		text, err := format(value, from)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(text), nil
+	case kindTime:
+		result, err := castTime(value)
+		if err != nil {
+			return nil, err
+		}
+		if to == schema.TypeDate {
+			return truncateDate(result), nil
+		}
+		return result, nil
+	case kindInterval:
+		return castInterval(value)
+	default:
+		return value, nil
+	}
+}
+
+func castInt(value any) (int64, error) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case float32, float64:
+		f, _ := toFloat64(v)
+		if math.IsNaN(f) || f >= math.MaxInt64 || f < math.MinInt64 {
+			return 0, xerrors.Errorf("value %v overflows int64", f)
+		}
+		return int64(f), nil
+	case string:
+		return parseInt(v)
+	case []byte:
+		return parseInt(string(v))
+	case time.Time:
+		return v.Unix(), nil
+	case time.Duration:
+		return int64(v / time.Second), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return parseInt(v.String())
+	}
+	return toInt64(value)
+}
+
+func parseInt(text string) (int64, error) {
+	text = strings.TrimSpace(text)
+	if result, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return result, nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, xerrors.Errorf("unable to parse integer %q", text)
+	}
+	return castInt(f)
+}
+
+func checkRange(value int64, typ schema.Type) error {
+	var minValue, maxValue int64
+	switch typ {
+	case schema.TypeInt8:
+		minValue, maxValue = math.MinInt8, math.MaxInt8
+	case schema.TypeInt16:
+		minValue, maxValue = math.MinInt16, math.MaxInt16
+	case schema.TypeInt32:
+		minValue, maxValue = math.MinInt32, math.MaxInt32
+	case schema.TypeUint8:
+		minValue, maxValue = 0, math.MaxUint8
+	case schema.TypeUint16:
+		minValue, maxValue = 0, math.MaxUint16
+	case schema.TypeUint32:
+		minValue, maxValue = 0, math.MaxUint32
+	case schema.TypeUint64:
+		minValue, maxValue = 0, math.MaxInt64
+	default:
+		return nil
+	}
+	if value < minValue || value > maxValue {
+		return xerrors.Errorf("value %d is out of range of %s", value, typ)
+	}
+	return nil
+}
+
+func castFloat(value any) (float64, error) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return parseFloat(v)
+	case []byte:
+		return parseFloat(string(v))
+	case time.Time:
+		return float64(v.UnixMicro()) / 1e6, nil
+	case time.Duration:
+		return v.Seconds(), nil
+	}
+	return toFloat64(value)
+}
+
+func parseFloat(text string) (float64, error) {
+	result, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil {
+		return 0, xerrors.Errorf("unable to parse number %q", text)
+	}
+	return result, nil
+}
+
+func castBool(value any) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return parseBool(v)
+	case []byte:
+		return parseBool(string(v))
+	}
+	if f, err := toFloat64(value); err == nil {
+		return f != 0, nil
+	}
+	return false, xerrors.Errorf("unable to cast %v of type %T to boolean", value, value)
+}
+
+func parseBool(text string) (bool, error) {
+	result, err := strconv.ParseBool(strings.TrimSpace(text))
+	if err != nil {
+		return false, xerrors.Errorf("unable to parse boolean %q", text)
+	}
+	return result, nil
+}
+
+func castTime(value any) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return parseTime(strings.TrimSpace(v))
+	case []byte:
+		return parseTime(strings.TrimSpace(string(v)))
+	case float32, float64:
+		f, _ := toFloat64(v)
+		return time.UnixMicro(int64(f * 1e6)).UTC(), nil
+	}
+	seconds, err := toInt64(value)
+	if err != nil {
+		return time.Time{}, xerrors.Errorf("unable to cast %v of type %T to time", value, value)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+func castInterval(value any) (time.Duration, error) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		return parseDuration(v)
+	case []byte:
+		return parseDuration(string(v))
+	case float32, float64:
+		f, _ := toFloat64(v)
+		return time.Duration(f * float64(time.Second)), nil
+	}
+	seconds, err := toInt64(value)
+	if err != nil {
+		return 0, xerrors.Errorf("unable to cast %v of type %T to interval", value, value)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func parseDuration(text string) (time.Duration, error) {
+	result, err := time.ParseDuration(strings.TrimSpace(text))
+	if err != nil {
+		return 0, xerrors.Errorf("unable to parse interval %q", text)
+	}
+	return result, nil
+}
+
+// format returns a text representation of a value, values of any type except strings are formatted as JSON
+func format(value any, from schema.Type) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		if from == schema.TypeDate {
+			return v.UTC().Format(time.DateOnly), nil
+		}
+		return v.UTC().Format(time.RFC3339Nano), nil
+	case time.Duration:
+		return v.String(), nil
+	case json.Number:
+		return v.String(), nil
+	}
+	result, err := json.Marshal(value)
+	if err != nil {
+		return "", xerrors.Errorf("unable to format %v of type %T: %w", value, value, err)
+	}
+	return string(result), nil
+}
+
+// compareValues compares runtime values of the same kind
+func compareValues(a, b any) (int, error) {
+	switch x := a.(type) {
+	case int64:
+		if y, ok := b.(int64); ok {
+			return compareOrdered(x, y), nil
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			return compareOrdered(x, y), nil
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			return compareOrdered(boolToInt(x), boolToInt(y)), nil
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y), nil
+		}
+	case time.Duration:
+		if y, ok := b.(time.Duration); ok {
+			return compareOrdered(x, y), nil
+		}
+	}
+	return 0, xerrors.Errorf("unable to compare %s with %s", describeValue(a), describeValue(b))
+}
+
+func compareOrdered[T int | int64 | float64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func describeValue(value any) string {
+	return fmt.Sprintf("%v of type %T", value, value)
+}
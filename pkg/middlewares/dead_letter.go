@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/deadletter"
+)
+
+// DeadLetter routes rows of unparsed tables into the dead letter destination and pushes the rest downstream.
+// The dead letter sink is shared and is not closed by the middleware
+func DeadLetter(deadLetters *deadletter.Sink) func(abstract.Sinker) abstract.Sinker {
+	return func(s abstract.Sinker) abstract.Sinker {
+		return newDeadLetterRouter(s, deadLetters)
+	}
+}
+
+type deadLetterRouter struct {
+	sink        abstract.Sinker
+	deadLetters *deadletter.Sink
+}
+
+func newDeadLetterRouter(s abstract.Sinker, deadLetters *deadletter.Sink) *deadLetterRouter {
+	return &deadLetterRouter{
+		sink:        s,
+		deadLetters: deadLetters,
+	}
+}
+
+func (r *deadLetterRouter) Close() error {
+	return r.sink.Close()
+}
+
+func (r *deadLetterRouter) Push(items []abstract.ChangeItem) error {
+	hasUnparsed := false
+	for i := range items {
+		if deadletter.IsUnparsed(items[i]) {
+			hasUnparsed = true
+			break
+		}
+	}
+	// Do not copy the entire batch unless there is at least one unparsed item
+	if !hasUnparsed {
+		return r.sink.Push(items)
+	}
+
+	unparsed := make([]abstract.ChangeItem, 0)
+	parsed := make([]abstract.ChangeItem, 0, len(items))
+	for _, item := range items {
+		if deadletter.IsUnparsed(item) {
+			unparsed = append(unparsed, item)
+			continue
+		}
+		parsed = append(parsed, item)
+	}
+	if err := r.deadLetters.PushUnparsed(unparsed); err != nil {
+		return xerrors.Errorf("failed to push %d unparsed items: %w", len(unparsed), err)
+	}
+	if len(parsed) == 0 {
+		return nil
+	}
+	return r.sink.Push(parsed)
+}
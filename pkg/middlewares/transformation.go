@@ -5,11 +5,13 @@ import (
 	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/abstract"
 	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/deadletter"
 	"github.com/transferia/transferia/pkg/transformer"
 	"go.ytsaurus.tech/library/go/core/log"
 )
 
-func Transformation(transfer *model.Transfer, logger log.Logger, metrics metrics.Registry) (func(abstract.Sinker) abstract.Sinker, error) {
+// Transformation applies transformers of the transfer, deadLetters receives failed items in the destination errors output mode and may be nil otherwise
+func Transformation(transfer *model.Transfer, logger log.Logger, metrics metrics.Registry, deadLetters *deadletter.Sink) (func(abstract.Sinker) abstract.Sinker, error) {
 	if transfer.HasTransformation() {
 		var transformChain []abstract.Transformer
 		for _, cfg := range transfer.TransformationConfigs() {
@@ -21,7 +23,8 @@ func Transformation(transfer *model.Transfer, logger log.Logger, metrics metrics
 		}
 		transformChain = append(transformChain, transfer.Transformation.ExtraTransformers...)
 		return transformer.Sinker(
-			nil,
+			errorsOutputConfig(transfer),
+			deadLetters,
 			abstract.TransformationRuntimeOpts{JobIndex: transfer.CurrentJobIndex()},
 			transformChain,
 			logger,
@@ -32,3 +35,13 @@ func Transformation(transfer *model.Transfer, logger log.Logger, metrics metrics
 		return s
 	}, nil
 }
+
+// errorsOutputConfig returns the transformers config only for the destination errors output,
+// other errors outputs have never been applied here, so their transfers keep pushing errors into the sink
+func errorsOutputConfig(transfer *model.Transfer) *transformer.Transformers {
+	config := transfer.Transformation.Transformers
+	if config == nil || config.ErrorsOutput == nil || config.ErrorsOutput.Type != transformer.DestinationErrorsOutput {
+		return nil
+	}
+	return config
+}
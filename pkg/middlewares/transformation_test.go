@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/transformer"
+)
+
+type failingTransformer struct{}
+
+func (t failingTransformer) Apply(input []abstract.ChangeItem) abstract.TransformerResult {
+	errors := make([]abstract.TransformerError, 0, len(input))
+	for _, item := range input {
+		errors = append(errors, abstract.TransformerError{Input: item, Error: xerrors.New("broken row")})
+	}
+	return abstract.TransformerResult{Transformed: nil, Errors: errors}
+}
+
+func (t failingTransformer) Suitable(table abstract.TableID, schema *abstract.TableSchema) bool {
+	return true
+}
+
+func (t failingTransformer) ResultSchema(original *abstract.TableSchema) (*abstract.TableSchema, error) {
+	return original, nil
+}
+
+func (t failingTransformer) Description() string {
+	return "failing transformer"
+}
+
+func (t failingTransformer) Type() abstract.TransformerType {
+	return "failing"
+}
+
+func TestTransformationKeepsSinkErrorsForNonDestinationOutputs(t *testing.T) {
+	// errors outputs other than destination push transformer errors into the sink as before
+	for _, output := range []transformer.OutputType{transformer.SinkErrorsOutput, transformer.DevnullErrorsOutput} {
+		transfer := &model.Transfer{Transformation: &model.Transformation{
+			Transformers: &transformer.Transformers{
+				DebugMode:    false,
+				Transformers: nil,
+				ErrorsOutput: &transformer.ErrorsOutput{Type: output, Config: nil},
+			},
+			ExtraTransformers: []abstract.Transformer{failingTransformer{}},
+			RuntimeJobIndex:   0,
+		}}
+		wrapper, err := Transformation(transfer, logger.Log, solomon.NewRegistry(solomon.NewRegistryOpts()), nil)
+		require.NoError(t, err)
+
+		mock := NewMockSinker()
+		item := abstract.ChangeItem{
+			Kind:         abstract.InsertKind,
+			Schema:       "public",
+			Table:        "t",
+			ColumnNames:  []string{"id"},
+			ColumnValues: []any{1},
+			TableSchema:  abstract.NewTableSchema([]abstract.ColSchema{{ColumnName: "id", DataType: "int64", PrimaryKey: true}}),
+		}
+		require.NoError(t, wrapper(mock).Push([]abstract.ChangeItem{item}))
+		require.Len(t, mock.Items, 1, output)
+		require.Equal(t, []string{"id", "__transform_error"}, mock.Items[0].ColumnNames, output)
+	}
+}
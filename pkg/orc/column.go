@@ -0,0 +1,516 @@
+package orc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+)
+
+// Values of columns have the following golang types:
+//   - boolean: bool
+//   - tinyint, smallint, int, bigint: int8, int16, int32, int64
+//   - float, double: float32, float64
+//   - string, varchar, char: string
+//   - binary: []byte
+//   - decimal: string with decimal representation of number (to not lose precision)
+//   - date, timestamp, timestamp with local time zone: time.Time in UTC
+//   - array: []any
+//   - map: map[string]any, keys are formatted by fmt.Sprint
+//   - struct: map[string]any
+//   - uniontype: value of the matched child
+//
+// Timestamps are stored as seconds & nanoseconds since 2015-01-01 00:00:00 in the writer time zone,
+// so they are returned as wall clock time of the writer, like Hive does
+
+// timestampBase is the epoch of timestamps, 2015-01-01 00:00:00 UTC
+const timestampBase = 1420070400
+
+type columnReader interface {
+	next() (any, error)
+}
+
+// stripeStreams are decompressed streams of the stripe by column & kind
+type stripeStreams struct {
+	streams   map[streamKey]byteStream
+	encodings []columnEncoding
+}
+
+type streamKey struct {
+	column uint64
+	kind   streamKind
+}
+
+func (s *stripeStreams) get(t *Type, kind streamKind) (byteStream, error) {
+	stream, ok := s.streams[streamKey{column: uint64(t.id), kind: kind}]
+	if !ok {
+		return nil, xerrors.Errorf("missing stream %d of column %d (%s)", kind, t.id, t)
+	}
+	return stream, nil
+}
+
+func (s *stripeStreams) encoding(t *Type) encodingKind {
+	if t.id < len(s.encodings) {
+		return s.encodings[t.id].kind
+	}
+	return encodingDirect
+}
+
+func (s *stripeStreams) intReader(t *Type, kind streamKind, signed bool) (intReader, error) {
+	stream, err := s.get(t, kind)
+	if err != nil {
+		return nil, err
+	}
+	return newIntReader(stream, signed, s.encoding(t)), nil
+}
+
+// presence wraps the column reader with the PRESENT stream, if any.
+// Children of nested types have values only for rows, which are not null in the parent
+type presence struct {
+	present *boolReader
+	reader  columnReader
+}
+
+func (p *presence) next() (any, error) {
+	present, err := p.present.next()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read presence: %w", unexpectedEOF(err))
+	}
+	if !present {
+		return nil, nil
+	}
+	return p.reader.next()
+}
+
+func newColumnReader(t *Type, streams *stripeStreams) (columnReader, error) {
+	reader, err := newValueReader(t, streams)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read column %d (%s): %w", t.id, t, err)
+	}
+	if present, ok := streams.streams[streamKey{column: uint64(t.id), kind: streamPresent}]; ok {
+		return &presence{present: &boolReader{bytes: &byteRLEReader{in: present, values: nil}, current: 0, left: 0}, reader: reader}, nil
+	}
+	return reader, nil
+}
+
+func newValueReader(t *Type, streams *stripeStreams) (columnReader, error) {
+	switch t.Kind {
+	case KindBoolean:
+		data, err := streams.get(t, streamData)
+		if err != nil {
+			return nil, err
+		}
+		return &booleanColumn{values: &boolReader{bytes: &byteRLEReader{in: data, values: nil}, current: 0, left: 0}}, nil
+	case KindByte:
+		data, err := streams.get(t, streamData)
+		if err != nil {
+			return nil, err
+		}
+		return &byteColumn{values: &byteRLEReader{in: data, values: nil}}, nil
+	case KindShort, KindInt, KindLong, KindDate:
+		values, err := streams.intReader(t, streamData, true)
+		if err != nil {
+			return nil, err
+		}
+		return &intColumn{kind: t.Kind, values: values}, nil
+	case KindFloat, KindDouble:
+		data, err := streams.get(t, streamData)
+		if err != nil {
+			return nil, err
+		}
+		return &floatColumn{kind: t.Kind, data: data}, nil
+	case KindString, KindVarchar, KindChar, KindBinary:
+		return newStringColumn(t, streams)
+	case KindTimestamp, KindTimestampInstant:
+		seconds, err := streams.intReader(t, streamData, true)
+		if err != nil {
+			return nil, err
+		}
+		nanos, err := streams.intReader(t, streamSecondary, false)
+		if err != nil {
+			return nil, err
+		}
+		return &timestampColumn{seconds: seconds, nanos: nanos}, nil
+	case KindDecimal:
+		data, err := streams.get(t, streamData)
+		if err != nil {
+			return nil, err
+		}
+		scales, err := streams.intReader(t, streamSecondary, true)
+		if err != nil {
+			return nil, err
+		}
+		return &decimalColumn{data: data, scales: scales}, nil
+	case KindStruct:
+		children, err := newChildReaders(t, streams)
+		if err != nil {
+			return nil, err
+		}
+		return &structColumn{names: t.FieldNames, children: children}, nil
+	case KindList, KindMap:
+		lengths, err := streams.intReader(t, streamLength, false)
+		if err != nil {
+			return nil, err
+		}
+		children, err := newChildReaders(t, streams)
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind == KindList {
+			return &listColumn{lengths: lengths, items: children[0]}, nil
+		}
+		return &mapColumn{lengths: lengths, keys: children[0], values: children[1]}, nil
+	case KindUnion:
+		data, err := streams.get(t, streamData)
+		if err != nil {
+			return nil, err
+		}
+		children, err := newChildReaders(t, streams)
+		if err != nil {
+			return nil, err
+		}
+		return &unionColumn{tags: &byteRLEReader{in: data, values: nil}, children: children}, nil
+	default:
+		return nil, xerrors.Errorf("unsupported type: %s", t.Kind)
+	}
+}
+
+func newChildReaders(t *Type, streams *stripeStreams) ([]columnReader, error) {
+	expected := 0
+	switch t.Kind {
+	case KindList:
+		expected = 1
+	case KindMap:
+		expected = 2
+	case KindStruct:
+		expected = len(t.FieldNames)
+	}
+	if expected != 0 && len(t.Children) != expected {
+		return nil, xerrors.Errorf("%s has %d children", t.Kind, len(t.Children))
+	}
+	result := make([]columnReader, len(t.Children))
+	for i, child := range t.Children {
+		var err error
+		result[i], err = newColumnReader(child, streams)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+type booleanColumn struct {
+	values *boolReader
+}
+
+func (c *booleanColumn) next() (any, error) {
+	return c.values.next()
+}
+
+type byteColumn struct {
+	values *byteRLEReader
+}
+
+func (c *byteColumn) next() (any, error) {
+	value, err := c.values.next()
+	return int8(value), err
+}
+
+type intColumn struct {
+	kind   Kind
+	values intReader
+}
+
+func (c *intColumn) next() (any, error) {
+	value, err := c.values.next()
+	if err != nil {
+		return nil, err
+	}
+	switch c.kind {
+	case KindShort:
+		return int16(value), nil
+	case KindInt:
+		return int32(value), nil
+	case KindDate:
+		return time.Unix(value*24*60*60, 0).UTC(), nil
+	default:
+		return value, nil
+	}
+}
+
+type floatColumn struct {
+	kind Kind
+	data byteStream
+}
+
+func (c *floatColumn) next() (any, error) {
+	if c.kind == KindFloat {
+		var buf [4]byte
+		if _, err := io.ReadFull(c.data, buf[:]); err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(buf[:])), nil
+	}
+	var buf [8]byte
+	if _, err := io.ReadFull(c.data, buf[:]); err != nil {
+		return nil, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(buf[:])), nil
+}
+
+// stringColumn reads values of DIRECT encoding from DATA by LENGTH, and values of DICTIONARY encoding -
+// from the dictionary by indexes from DATA
+type stringColumn struct {
+	binary     bool
+	data       byteStream
+	lengths    intReader
+	indexes    intReader
+	dictionary [][]byte
+}
+
+func (c *stringColumn) next() (any, error) {
+	var value []byte
+	if c.dictionary != nil {
+		idx, err := c.indexes.next()
+		if err != nil {
+			return nil, err
+		}
+		if idx < 0 || idx >= int64(len(c.dictionary)) {
+			return nil, xerrors.Errorf("dictionary index %d is out of dictionary of %d values", idx, len(c.dictionary))
+		}
+		value = c.dictionary[idx]
+	} else {
+		length, err := c.lengths.next()
+		if err != nil {
+			return nil, err
+		}
+		value, err = readN(c.data, length)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read value: %w", err)
+		}
+	}
+	if c.binary {
+		return value, nil
+	}
+	return string(value), nil
+}
+
+func newStringColumn(t *Type, streams *stripeStreams) (columnReader, error) {
+	lengths, err := streams.intReader(t, streamLength, false)
+	if err != nil {
+		return nil, err
+	}
+	data, err := streams.get(t, streamData)
+	if err != nil {
+		return nil, err
+	}
+	column := &stringColumn{binary: t.Kind == KindBinary, data: data, lengths: lengths, indexes: nil, dictionary: nil}
+	if !streams.encoding(t).isDictionary() {
+		return column, nil
+	}
+
+	column.indexes = newIntReader(data, false, streams.encoding(t))
+	dictionaryData, ok := streams.streams[streamKey{column: uint64(t.id), kind: streamDictionaryData}]
+	size := streams.encodings[t.id].dictionarySize
+	if !ok && size > 0 {
+		return nil, xerrors.New("missing dictionary data")
+	}
+	column.dictionary = make([][]byte, size)
+	for i := range column.dictionary {
+		length, err := lengths.next()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read length of dictionary value: %w", unexpectedEOF(err))
+		}
+		column.dictionary[i], err = readN(dictionaryData, length)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read dictionary value: %w", err)
+		}
+	}
+	return column, nil
+}
+
+type timestampColumn struct {
+	seconds intReader
+	nanos   intReader
+}
+
+func (c *timestampColumn) next() (any, error) {
+	seconds, err := c.seconds.next()
+	if err != nil {
+		return nil, err
+	}
+	encodedNanos, err := c.nanos.next()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read nanoseconds: %w", unexpectedEOF(err))
+	}
+	// 3 low bits are the number of trailing decimal zeros, which are cut, minus one
+	nanos := encodedNanos >> 3
+	if zeros := encodedNanos & 0x7; zeros != 0 {
+		for i := int64(0); i <= zeros; i++ {
+			nanos *= 10
+		}
+	}
+	seconds += timestampBase
+	// seconds of negative timestamps are truncated toward zero by writers
+	if seconds < 0 && nanos > 999999 {
+		seconds--
+	}
+	return time.Unix(seconds, nanos).UTC(), nil
+}
+
+// decimalColumn reads unscaled values as unbounded zigzag varints from DATA & their scales from SECONDARY
+type decimalColumn struct {
+	data   byteStream
+	scales intReader
+}
+
+func (c *decimalColumn) next() (any, error) {
+	unscaled, err := readBigVarint(c.data)
+	if err != nil {
+		return nil, err
+	}
+	scale, err := c.scales.next()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read scale: %w", unexpectedEOF(err))
+	}
+	return formatDecimal(unscaled, int(scale)), nil
+}
+
+type structColumn struct {
+	names    []string
+	children []columnReader
+}
+
+func (c *structColumn) next() (any, error) {
+	result := make(map[string]any, len(c.children))
+	for i, child := range c.children {
+		value, err := child.next()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read field %s: %w", c.names[i], unexpectedEOF(err))
+		}
+		result[c.names[i]] = value
+	}
+	return result, nil
+}
+
+type listColumn struct {
+	lengths intReader
+	items   columnReader
+}
+
+func (c *listColumn) next() (any, error) {
+	length, err := c.lengths.next()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]any, length)
+	for i := range result {
+		result[i], err = c.items.next()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read item: %w", unexpectedEOF(err))
+		}
+	}
+	return result, nil
+}
+
+type mapColumn struct {
+	lengths intReader
+	keys    columnReader
+	values  columnReader
+}
+
+func (c *mapColumn) next() (any, error) {
+	length, err := c.lengths.next()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]any, length)
+	for i := int64(0); i < length; i++ {
+		key, err := c.keys.next()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read key: %w", unexpectedEOF(err))
+		}
+		value, err := c.values.next()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read value: %w", unexpectedEOF(err))
+		}
+		result[fmt.Sprint(key)] = value
+	}
+	return result, nil
+}
+
+type unionColumn struct {
+	tags     *byteRLEReader
+	children []columnReader
+}
+
+func (c *unionColumn) next() (any, error) {
+	tag, err := c.tags.next()
+	if err != nil {
+		return nil, err
+	}
+	if int(tag) >= len(c.children) {
+		return nil, xerrors.Errorf("union tag %d is out of %d children", tag, len(c.children))
+	}
+	value, err := c.children[tag].next()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read child %d: %w", tag, unexpectedEOF(err))
+	}
+	return value, nil
+}
+
+func readN(in byteStream, n int64) ([]byte, error) {
+	if n < 0 {
+		return nil, xerrors.Errorf("negative length: %d", n)
+	}
+	result := make([]byte, n)
+	if _, err := io.ReadFull(in, result); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return result, nil
+}
+
+// readBigVarint reads zigzag base 128 varint of arbitrary length
+func readBigVarint(in byteStream) (*big.Int, error) {
+	result := new(big.Int)
+	var shift uint
+	for {
+		b, err := in.ReadByte()
+		if err != nil {
+			if shift == 0 {
+				return nil, err
+			}
+			return nil, unexpectedEOF(err)
+		}
+		result.Or(result, new(big.Int).Lsh(big.NewInt(int64(b&0x7f)), shift))
+		shift += 7
+		if b < 0x80 {
+			break
+		}
+	}
+	negative := result.Bit(0) == 1
+	result.Rsh(result, 1)
+	if negative {
+		result.Neg(result).Sub(result, big.NewInt(1))
+	}
+	return result, nil
+}
+
+func formatDecimal(unscaled *big.Int, scale int) string {
+	if scale <= 0 {
+		return new(big.Int).Mul(unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-scale)), nil)).String()
+	}
+	digits := new(big.Int).Abs(unscaled).String()
+	for len(digits) <= scale {
+		digits = "0" + digits
+	}
+	sign := ""
+	if unscaled.Sign() < 0 {
+		sign = "-"
+	}
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
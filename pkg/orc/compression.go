@@ -0,0 +1,161 @@
+package orc
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+)
+
+// Compression of the file, values are the same as in the postscript.
+// Compressed data is split into chunks, each one is prefixed with 3-byte header:
+// https://orc.apache.org/specification/ORCv1/#compression
+type Compression int
+
+const (
+	CompressionNone Compression = iota
+	CompressionZlib
+	CompressionSnappy
+	CompressionLZO
+	CompressionLZ4
+	CompressionZstd
+)
+
+const (
+	chunkHeaderSize         = 3
+	defaultCompressionBlock = 256 * 1024
+)
+
+var compressionNames = map[Compression]string{
+	CompressionNone:   "none",
+	CompressionZlib:   "zlib",
+	CompressionSnappy: "snappy",
+	CompressionLZO:    "lzo",
+	CompressionLZ4:    "lz4",
+	CompressionZstd:   "zstd",
+}
+
+func (c Compression) String() string {
+	if name, ok := compressionNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(c))
+}
+
+func (c Compression) isSupported() bool {
+	switch c {
+	case CompressionNone, CompressionZlib, CompressionSnappy, CompressionLZ4, CompressionZstd:
+		return true
+	default:
+		return false
+	}
+}
+
+// zstd decoder is safe for concurrent use of DecodeAll, so it's shared
+var zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+	return zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+})
+
+func decompressChunk(compression Compression, data []byte, blockSize int) ([]byte, error) {
+	switch compression {
+	case CompressionZlib:
+		return io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	case CompressionSnappy:
+		return snappy.Decode(nil, data)
+	case CompressionLZ4:
+		result := make([]byte, blockSize)
+		n, err := lz4.UncompressBlock(data, result)
+		if err != nil {
+			return nil, err
+		}
+		return result[:n], nil
+	case CompressionZstd:
+		decoder, err := zstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+		return decoder.DecodeAll(data, nil)
+	default:
+		return nil, xerrors.Errorf("unsupported compression: %s", compression)
+	}
+}
+
+// chunkedReader decompresses chunks of the stream on demand
+type chunkedReader struct {
+	compression Compression
+	blockSize   int
+	data        []byte
+	chunk       []byte
+}
+
+func (r *chunkedReader) nextChunk() error {
+	for len(r.chunk) == 0 {
+		if len(r.data) == 0 {
+			return io.EOF
+		}
+		if len(r.data) < chunkHeaderSize {
+			return xerrors.New("truncated chunk header")
+		}
+		header := int(r.data[0]) | int(r.data[1])<<8 | int(r.data[2])<<16
+		length := header >> 1
+		if len(r.data) < chunkHeaderSize+length {
+			return xerrors.Errorf("truncated chunk of %d bytes", length)
+		}
+		raw := r.data[chunkHeaderSize : chunkHeaderSize+length]
+		r.data = r.data[chunkHeaderSize+length:]
+		if header&1 == 1 {
+			r.chunk = raw
+			continue
+		}
+		var err error
+		r.chunk, err = decompressChunk(r.compression, raw, r.blockSize)
+		if err != nil {
+			return xerrors.Errorf("unable to decompress chunk: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if err := r.nextChunk(); err != nil {
+		return 0, err
+	}
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
+
+func (r *chunkedReader) ReadByte() (byte, error) {
+	if err := r.nextChunk(); err != nil {
+		return 0, err
+	}
+	b := r.chunk[0]
+	r.chunk = r.chunk[1:]
+	return b, nil
+}
+
+// byteStream is the input of decoders
+type byteStream interface {
+	io.Reader
+	io.ByteReader
+}
+
+func newByteStream(compression Compression, blockSize int, data []byte) byteStream {
+	if compression == CompressionNone {
+		return bytes.NewReader(data)
+	}
+	return &chunkedReader{compression: compression, blockSize: blockSize, data: data, chunk: nil}
+}
+
+func decompress(compression Compression, blockSize int, data []byte) ([]byte, error) {
+	if compression == CompressionNone {
+		return data, nil
+	}
+	return io.ReadAll(newByteStream(compression, blockSize, data))
+}
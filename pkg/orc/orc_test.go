@@ -0,0 +1,251 @@
+package orc
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readInts(t *testing.T, reader intReader) []int64 {
+	var result []int64
+	for {
+		v, err := reader.next()
+		if err == io.EOF {
+			return result
+		}
+		require.NoError(t, err)
+		result = append(result, v)
+	}
+}
+
+// examples are from the specification
+func TestIntRLEv2(t *testing.T) {
+	cases := []struct {
+		name     string
+		encoded  []byte
+		expected []int64
+	}{
+		{
+			name:     "short repeat",
+			encoded:  []byte{0x0a, 0x27, 0x10},
+			expected: []int64{10000, 10000, 10000, 10000, 10000},
+		},
+		{
+			name:     "direct",
+			encoded:  []byte{0x5e, 0x03, 0x5c, 0xa1, 0xab, 0x1e, 0xde, 0xad, 0xbe, 0xef},
+			expected: []int64{23713, 43806, 57005, 48879},
+		},
+		{
+			name: "patched base",
+			encoded: []byte{
+				0x8e, 0x13, 0x2b, 0x21, 0x07, 0xd0, 0x1e, 0x00, 0x14, 0x70, 0x28, 0x32, 0x3c, 0x46,
+				0x50, 0x5a, 0x64, 0x6e, 0x78, 0x82, 0x8c, 0x96, 0xa0, 0xaa, 0xb4, 0xbe, 0xfc, 0xe8,
+			},
+			expected: []int64{
+				2030, 2000, 2020, 1000000, 2040, 2050, 2060, 2070, 2080, 2090,
+				2100, 2110, 2120, 2130, 2140, 2150, 2160, 2170, 2180, 2190,
+			},
+		},
+		{
+			name:     "delta",
+			encoded:  []byte{0xc6, 0x09, 0x02, 0x02, 0x22, 0x42, 0x42, 0x46},
+			expected: []int64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29},
+		},
+		{
+			name:     "fixed delta",
+			encoded:  []byte{0xc0, 0x04, 0x0a, 0x03},
+			expected: []int64{10, 8, 6, 4, 2},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, readInts(t, newIntReader(bytes.NewReader(tc.encoded), false, encodingDirectV2)))
+		})
+	}
+
+	_, err := newIntReader(bytes.NewReader([]byte{0x5e, 0x03, 0x5c}), false, encodingDirectV2).next()
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestIntRLEv1(t *testing.T) {
+	require.Equal(t, []int64{7, 7, 7, 7, 7}, readInts(t, newIntReader(bytes.NewReader([]byte{0x02, 0x00, 0x07}), false, encodingDirect)))
+	require.Equal(t, []int64{2, 3, 6, 7, 11}, readInts(t, newIntReader(bytes.NewReader([]byte{0xfb, 0x02, 0x03, 0x06, 0x07, 0xb}), false, encodingDirect)))
+
+	values := []int64{0, 0, 0, 1, 2, 3, 4, -5, 100, math.MaxInt64, math.MinInt64, math.MinInt64 + 1}
+	for i := 0; i < 1000; i++ {
+		values = append(values, rand.Int63n(200)-100)
+	}
+	for i := 0; i < 300; i++ {
+		values = append(values, int64(i*3))
+	}
+	require.Equal(t, values, readInts(t, newIntReader(bytes.NewReader(appendIntRLEv1(nil, values, true)), true, encodingDirect)))
+}
+
+func TestByteRLE(t *testing.T) {
+	reader := &byteRLEReader{in: bytes.NewReader([]byte{0x61, 0x00, 0xfe, 0x44, 0x45}), values: nil}
+	var values []byte
+	for {
+		v, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		values = append(values, v)
+	}
+	require.Equal(t, append(make([]byte, 100), 0x44, 0x45), values)
+
+	bools := &boolReader{bytes: &byteRLEReader{in: bytes.NewReader([]byte{0xff, 0x80}), values: nil}, current: 0, left: 0}
+	for i := 0; i < 8; i++ {
+		v, err := bools.next()
+		require.NoError(t, err)
+		require.Equal(t, i == 0, v)
+	}
+	_, err := bools.next()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestDecimal(t *testing.T) {
+	for _, v := range []string{"0", "1", "-1", "63", "-64", "12345678901234567890123456789", "-98765432109876543210"} {
+		value, _ := new(big.Int).SetString(v, 10)
+		decoded, err := readBigVarint(bytes.NewReader(appendBigVarint(nil, value)))
+		require.NoError(t, err)
+		require.Equal(t, v, decoded.String())
+	}
+	require.Equal(t, "-0.05", formatDecimal(big.NewInt(-5), 2))
+	require.Equal(t, "123.450", formatDecimal(big.NewInt(123450), 3))
+	require.Equal(t, "1200", formatDecimal(big.NewInt(12), -2))
+}
+
+func testSchema() *Type {
+	return Struct(
+		[]string{"id", "flag", "tiny", "small", "num", "ratio", "score", "name", "code", "payload", "day", "ts", "amount", "tags", "attrs", "address"},
+		[]*Type{
+			Primitive(KindLong),
+			Primitive(KindBoolean),
+			Primitive(KindByte),
+			Primitive(KindShort),
+			Primitive(KindInt),
+			Primitive(KindFloat),
+			Primitive(KindDouble),
+			Primitive(KindString),
+			{Kind: KindVarchar, MaxLength: 8},
+			Primitive(KindBinary),
+			Primitive(KindDate),
+			Primitive(KindTimestamp),
+			Decimal(20, 2),
+			List(Primitive(KindString)),
+			Map(Primitive(KindString), Primitive(KindLong)),
+			Struct([]string{"city", "zip"}, []*Type{Primitive(KindString), Primitive(KindInt)}),
+		},
+	)
+}
+
+func testRow(i int) []any {
+	row := []any{
+		int64(i),
+		i%3 == 0,
+		int8(i % 100),
+		int16(-i),
+		int32(i * 1000),
+		float32(i) / 2,
+		float64(i) / 3,
+		[]string{"alpha", "beta", "gamma"}[i%3],
+		"c" + string(rune('a'+i%26)),
+		[]byte{byte(i), byte(i >> 8)},
+		time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i-50),
+		time.Date(1969, 12, 31, 23, 59, 0, 0, time.UTC).Add(time.Duration(i) * 1500 * time.Millisecond),
+		new(big.Rat).SetFrac64(int64(i)*101-5000, 100).FloatString(2),
+		[]any{"x", "y"}[:i%3],
+		map[string]any{"k": int64(i), "m": nil},
+		map[string]any{"city": "Amsterdam", "zip": int32(i)},
+	}
+	if i%7 == 0 {
+		for _, col := range []int{1, 5, 7, 9, 11, 12, 13, 15} {
+			row[col] = nil
+		}
+	}
+	if i%11 == 0 {
+		row[15] = map[string]any{"city": nil, "zip": int32(i)}
+	}
+	return row
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, compression := range []Compression{CompressionNone, CompressionZlib, CompressionSnappy, CompressionLZ4, CompressionZstd} {
+		t.Run(compression.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := NewWriter(&buf, testSchema(), WriterOptions{
+				Compression: compression,
+				StripeRows:  40,
+				Metadata:    map[string][]byte{"origin": []byte("test")},
+			})
+			require.NoError(t, err)
+			for i := 0; i < 100; i++ {
+				require.NoError(t, writer.Write(testRow(i)))
+			}
+			require.NoError(t, writer.Close())
+
+			file, err := Open(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			require.NoError(t, err)
+			require.Equal(t, compression, file.Compression())
+			require.Equal(t, uint64(100), file.NumRows())
+			require.Equal(t, map[string][]byte{"origin": []byte("test")}, file.Metadata())
+			require.Equal(t, testSchema().String(), file.Schema().String())
+			require.Equal(t, "struct<id:bigint,flag:boolean,tiny:tinyint,small:smallint,num:int,ratio:float,score:double,"+
+				"name:string,code:varchar(8),payload:binary,day:date,ts:timestamp,amount:decimal(20,2),tags:array<string>,"+
+				"attrs:map<string,bigint>,address:struct<city:string,zip:int>>", file.Schema().String())
+			require.Len(t, file.Stripes(), 3)
+			require.Equal(t, uint64(20), file.Stripes()[2].NumberOfRows)
+
+			i := 0
+			for idx := range file.Stripes() {
+				rows, err := file.Stripe(idx)
+				require.NoError(t, err)
+				for {
+					row, err := rows.Next()
+					if err == io.EOF {
+						break
+					}
+					require.NoError(t, err)
+					require.Equal(t, testRow(i), row, "row %d", i)
+					i++
+				}
+			}
+			require.Equal(t, 100, i)
+		})
+	}
+}
+
+func TestDictionary(t *testing.T) {
+	schema := Struct([]string{"v"}, []*Type{Primitive(KindString)})
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, schema, WriterOptions{Compression: CompressionNone, StripeRows: 0, Metadata: nil})
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, writer.Write([]any{[]string{"b", "a"}[i%2]}))
+	}
+	require.NoError(t, writer.Close())
+
+	file, err := Open(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	rows, err := file.Stripe(0)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		row, err := rows.Next()
+		require.NoError(t, err)
+		require.Equal(t, []any{[]string{"b", "a"}[i%2]}, row)
+	}
+}
+
+func TestInvalidFile(t *testing.T) {
+	_, err := Open(bytes.NewReader([]byte("not an orc file")), 15)
+	require.Error(t, err)
+	_, err = NewWriter(io.Discard, Primitive(KindInt), WriterOptions{Compression: CompressionNone, StripeRows: 0, Metadata: nil})
+	require.Error(t, err)
+}
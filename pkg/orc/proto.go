@@ -0,0 +1,338 @@
+package orc
+
+import (
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Messages of the file tail & stripe footers: https://orc.apache.org/specification/ORCv1/#file-tail
+// Only fields, which are needed to read data, are decoded
+
+type streamKind uint64
+
+const (
+	streamPresent        streamKind = 0
+	streamData           streamKind = 1
+	streamLength         streamKind = 2
+	streamDictionaryData streamKind = 3
+	streamSecondary      streamKind = 5
+)
+
+type encodingKind uint64
+
+const (
+	encodingDirect       encodingKind = 0
+	encodingDictionary   encodingKind = 1
+	encodingDirectV2     encodingKind = 2
+	encodingDictionaryV2 encodingKind = 3
+)
+
+func (k encodingKind) isV2() bool {
+	return k == encodingDirectV2 || k == encodingDictionaryV2
+}
+
+func (k encodingKind) isDictionary() bool {
+	return k == encodingDictionary || k == encodingDictionaryV2
+}
+
+type postScript struct {
+	footerLength         uint64
+	compression          Compression
+	compressionBlockSize uint64
+	version              []uint32
+	metadataLength       uint64
+	magic                string
+}
+
+// StripeInformation describes location & size of the stripe in the file
+type StripeInformation struct {
+	Offset       uint64
+	IndexLength  uint64
+	DataLength   uint64
+	FooterLength uint64
+	NumberOfRows uint64
+}
+
+type footer struct {
+	headerLength   uint64
+	contentLength  uint64
+	stripes        []StripeInformation
+	types          []*Type
+	metadata       map[string][]byte
+	numberOfRows   uint64
+	rowIndexStride uint64
+	statistics     []columnStatistics
+}
+
+type columnStatistics struct {
+	numberOfValues uint64
+	hasNull        bool
+}
+
+type stream struct {
+	kind   streamKind
+	column uint64
+	length uint64
+}
+
+type columnEncoding struct {
+	kind           encodingKind
+	dictionarySize uint64
+}
+
+type stripeFooter struct {
+	streams        []stream
+	columns        []columnEncoding
+	writerTimezone string
+}
+
+// field is a decoded field of message, value is set for length-delimited fields, number - for varint ones
+type field struct {
+	num    protowire.Number
+	typ    protowire.Type
+	value  []byte
+	number uint64
+}
+
+func parseMessage(buf []byte, onField func(f field) error) error {
+	for len(buf) > 0 {
+		num, typ, n := protowire.ConsumeTag(buf)
+		if n < 0 {
+			return xerrors.Errorf("unable to parse tag: %w", protowire.ParseError(n))
+		}
+		buf = buf[n:]
+		f := field{num: num, typ: typ, value: nil, number: 0}
+		switch typ {
+		case protowire.VarintType:
+			f.number, n = protowire.ConsumeVarint(buf)
+		case protowire.BytesType:
+			f.value, n = protowire.ConsumeBytes(buf)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, buf)
+		}
+		if n < 0 {
+			return xerrors.Errorf("unable to parse field %d: %w", num, protowire.ParseError(n))
+		}
+		buf = buf[n:]
+		if err := onField(f); err != nil {
+			return xerrors.Errorf("unable to parse field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+// appendRepeated appends values of repeated integer field, which may be packed or not
+func (f field) appendRepeated(values []uint64) ([]uint64, error) {
+	if f.typ == protowire.VarintType {
+		return append(values, f.number), nil
+	}
+	buf := f.value
+	for len(buf) > 0 {
+		v, n := protowire.ConsumeVarint(buf)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		values = append(values, v)
+		buf = buf[n:]
+	}
+	return values, nil
+}
+
+func parsePostScript(buf []byte) (*postScript, error) {
+	result := new(postScript)
+	err := parseMessage(buf, func(f field) error {
+		switch f.num {
+		case 1:
+			result.footerLength = f.number
+		case 2:
+			result.compression = Compression(f.number)
+		case 3:
+			result.compressionBlockSize = f.number
+		case 4:
+			versions, err := f.appendRepeated(nil)
+			if err != nil {
+				return err
+			}
+			for _, v := range versions {
+				result.version = append(result.version, uint32(v))
+			}
+		case 5:
+			result.metadataLength = f.number
+		case 8000:
+			result.magic = string(f.value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func parseStripeInformation(buf []byte) (StripeInformation, error) {
+	var result StripeInformation
+	err := parseMessage(buf, func(f field) error {
+		switch f.num {
+		case 1:
+			result.Offset = f.number
+		case 2:
+			result.IndexLength = f.number
+		case 3:
+			result.DataLength = f.number
+		case 4:
+			result.FooterLength = f.number
+		case 5:
+			result.NumberOfRows = f.number
+		}
+		return nil
+	})
+	return result, err
+}
+
+type rawType struct {
+	typ      *Type
+	subtypes []uint64
+}
+
+func parseType(buf []byte) (*rawType, error) {
+	result := &rawType{typ: new(Type), subtypes: nil}
+	err := parseMessage(buf, func(f field) error {
+		var err error
+		switch f.num {
+		case 1:
+			result.typ.Kind = Kind(f.number)
+		case 2:
+			result.subtypes, err = f.appendRepeated(result.subtypes)
+		case 3:
+			result.typ.FieldNames = append(result.typ.FieldNames, string(f.value))
+		case 4:
+			result.typ.MaxLength = uint32(f.number)
+		case 5:
+			result.typ.Precision = uint32(f.number)
+		case 6:
+			result.typ.Scale = uint32(f.number)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func parseFooter(buf []byte) (*footer, error) {
+	result := &footer{
+		headerLength:   0,
+		contentLength:  0,
+		stripes:        nil,
+		types:          nil,
+		metadata:       map[string][]byte{},
+		numberOfRows:   0,
+		rowIndexStride: 0,
+		statistics:     nil,
+	}
+	var rawTypes []*rawType
+	err := parseMessage(buf, func(f field) error {
+		switch f.num {
+		case 1:
+			result.headerLength = f.number
+		case 2:
+			result.contentLength = f.number
+		case 3:
+			stripe, err := parseStripeInformation(f.value)
+			if err != nil {
+				return xerrors.Errorf("unable to parse stripe: %w", err)
+			}
+			result.stripes = append(result.stripes, stripe)
+		case 4:
+			typ, err := parseType(f.value)
+			if err != nil {
+				return xerrors.Errorf("unable to parse type: %w", err)
+			}
+			rawTypes = append(rawTypes, typ)
+		case 5:
+			var name string
+			var value []byte
+			if err := parseMessage(f.value, func(f field) error {
+				switch f.num {
+				case 1:
+					name = string(f.value)
+				case 2:
+					value = f.value
+				}
+				return nil
+			}); err != nil {
+				return xerrors.Errorf("unable to parse metadata: %w", err)
+			}
+			result.metadata[name] = value
+		case 6:
+			result.numberOfRows = f.number
+		case 8:
+			result.rowIndexStride = f.number
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rawTypes) == 0 {
+		return nil, xerrors.New("footer has no types")
+	}
+	for i, raw := range rawTypes {
+		raw.typ.id = i
+		for _, subtype := range raw.subtypes {
+			// subtypes follow the type in pre-order, so there are no cycles
+			if subtype <= uint64(i) || subtype >= uint64(len(rawTypes)) {
+				return nil, xerrors.Errorf("invalid subtype %d of type %d", subtype, i)
+			}
+			raw.typ.Children = append(raw.typ.Children, rawTypes[subtype].typ)
+		}
+		result.types = append(result.types, raw.typ)
+	}
+	return result, nil
+}
+
+func parseStripeFooter(buf []byte) (*stripeFooter, error) {
+	result := &stripeFooter{streams: nil, columns: nil, writerTimezone: ""}
+	err := parseMessage(buf, func(f field) error {
+		switch f.num {
+		case 1:
+			var s stream
+			if err := parseMessage(f.value, func(f field) error {
+				switch f.num {
+				case 1:
+					s.kind = streamKind(f.number)
+				case 2:
+					s.column = f.number
+				case 3:
+					s.length = f.number
+				}
+				return nil
+			}); err != nil {
+				return xerrors.Errorf("unable to parse stream: %w", err)
+			}
+			result.streams = append(result.streams, s)
+		case 2:
+			var e columnEncoding
+			if err := parseMessage(f.value, func(f field) error {
+				switch f.num {
+				case 1:
+					e.kind = encodingKind(f.number)
+				case 2:
+					e.dictionarySize = f.number
+				}
+				return nil
+			}); err != nil {
+				return xerrors.Errorf("unable to parse column encoding: %w", err)
+			}
+			result.columns = append(result.columns, e)
+		case 3:
+			result.writerTimezone = string(f.value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,199 @@
+package orc
+
+import (
+	"io"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+)
+
+// Files: https://orc.apache.org/specification/ORCv1/
+
+const (
+	magic = "ORC"
+
+	// tailReadSize is enough to read postscript & footer of most files by one read
+	tailReadSize      = 16 * 1024
+	maxPostScriptSize = 0xff
+)
+
+// File is an opened ORC file, it reads the tail on open & stripes on demand
+type File struct {
+	in          io.ReaderAt
+	size        int64
+	compression Compression
+	blockSize   int
+	footer      *footer
+}
+
+// Open reads the file tail: postscript & footer
+func Open(in io.ReaderAt, size int64) (*File, error) {
+	if size < int64(len(magic))+1 {
+		return nil, xerrors.Errorf("file of %d bytes is too small", size)
+	}
+	tailSize := min(size, tailReadSize)
+	tail := make([]byte, tailSize)
+	if err := readAt(in, tail, size-tailSize); err != nil {
+		return nil, xerrors.Errorf("unable to read tail: %w", err)
+	}
+
+	psLength := int64(tail[len(tail)-1])
+	if psLength == 0 || psLength+1 > tailSize {
+		return nil, xerrors.Errorf("invalid postscript length: %d", psLength)
+	}
+	ps, err := parsePostScript(tail[tailSize-1-psLength : tailSize-1])
+	if err != nil {
+		return nil, xerrors.Errorf("unable to parse postscript: %w", err)
+	}
+	if ps.magic != magic {
+		return nil, xerrors.New("not an ORC file")
+	}
+	if !ps.compression.isSupported() {
+		return nil, xerrors.Errorf("unsupported compression: %s", ps.compression)
+	}
+
+	footerEnd := size - 1 - psLength
+	footerStart := footerEnd - int64(ps.footerLength)
+	if footerStart < int64(len(magic)) {
+		return nil, xerrors.Errorf("invalid footer length: %d", ps.footerLength)
+	}
+	var rawFooter []byte
+	if tailStart := size - tailSize; footerStart >= tailStart {
+		rawFooter = tail[footerStart-tailStart : footerEnd-tailStart]
+	} else {
+		rawFooter = make([]byte, ps.footerLength)
+		if err := readAt(in, rawFooter, footerStart); err != nil {
+			return nil, xerrors.Errorf("unable to read footer: %w", err)
+		}
+	}
+
+	blockSize := int(ps.compressionBlockSize)
+	if blockSize == 0 {
+		blockSize = defaultCompressionBlock
+	}
+	rawFooter, err = decompress(ps.compression, blockSize, rawFooter)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to decompress footer: %w", err)
+	}
+	ftr, err := parseFooter(rawFooter)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to parse footer: %w", err)
+	}
+	if ftr.types[0].Kind != KindStruct {
+		return nil, xerrors.Errorf("root type must be struct, but got %s", ftr.types[0].Kind)
+	}
+	return &File{
+		in:          in,
+		size:        size,
+		compression: ps.compression,
+		blockSize:   blockSize,
+		footer:      ftr,
+	}, nil
+}
+
+// Schema returns the root struct, its fields are columns of rows
+func (f *File) Schema() *Type {
+	return f.footer.types[0]
+}
+
+func (f *File) NumRows() uint64 {
+	return f.footer.numberOfRows
+}
+
+func (f *File) Compression() Compression {
+	return f.compression
+}
+
+// Metadata returns user metadata from the footer
+func (f *File) Metadata() map[string][]byte {
+	return f.footer.metadata
+}
+
+func (f *File) Stripes() []StripeInformation {
+	return f.footer.stripes
+}
+
+// Stripe reads the stripe & returns reader of its rows.
+// Data of the stripe is read at once, so memory usage is about the size of stripe
+func (f *File) Stripe(idx int) (*RowReader, error) {
+	if idx < 0 || idx >= len(f.footer.stripes) {
+		return nil, xerrors.Errorf("stripe %d is out of %d stripes", idx, len(f.footer.stripes))
+	}
+	info := f.footer.stripes[idx]
+	end := info.Offset + info.IndexLength + info.DataLength + info.FooterLength
+	if end > uint64(f.size) {
+		return nil, xerrors.Errorf("stripe %d ends at %d, after the end of file", idx, end)
+	}
+
+	raw := make([]byte, info.DataLength+info.FooterLength)
+	if err := readAt(f.in, raw, int64(info.Offset+info.IndexLength)); err != nil {
+		return nil, xerrors.Errorf("unable to read stripe %d: %w", idx, err)
+	}
+	rawFooter, err := decompress(f.compression, f.blockSize, raw[info.DataLength:])
+	if err != nil {
+		return nil, xerrors.Errorf("unable to decompress footer of stripe %d: %w", idx, err)
+	}
+	sf, err := parseStripeFooter(rawFooter)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to parse footer of stripe %d: %w", idx, err)
+	}
+
+	// streams are laid out in the order of the footer, index streams go first
+	streams := &stripeStreams{streams: map[streamKey]byteStream{}, encodings: sf.columns}
+	pos := info.Offset
+	for _, s := range sf.streams {
+		start := pos
+		pos += s.length
+		if start < info.Offset+info.IndexLength {
+			continue
+		}
+		if pos > info.Offset+info.IndexLength+info.DataLength {
+			return nil, xerrors.Errorf("stream %d of column %d is out of data of stripe %d", s.kind, s.column, idx)
+		}
+		dataStart := start - info.Offset - info.IndexLength
+		streams.streams[streamKey{column: s.column, kind: s.kind}] = newByteStream(f.compression, f.blockSize, raw[dataStart:dataStart+s.length])
+	}
+
+	root := f.Schema()
+	columns := make([]columnReader, len(root.Children))
+	for i, child := range root.Children {
+		columns[i], err = newColumnReader(child, streams)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read stripe %d: %w", idx, err)
+		}
+	}
+	return &RowReader{columns: columns, left: info.NumberOfRows}, nil
+}
+
+// RowReader reads rows of a stripe
+type RowReader struct {
+	columns []columnReader
+	left    uint64
+}
+
+// Next returns values of root struct fields, or io.EOF after the last row of the stripe
+func (r *RowReader) Next() ([]any, error) {
+	if r.left == 0 {
+		return nil, io.EOF
+	}
+	row := make([]any, len(r.columns))
+	for i, column := range r.columns {
+		var err error
+		row[i], err = column.next()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read column %d: %w", i, unexpectedEOF(err))
+		}
+	}
+	r.left--
+	return row, nil
+}
+
+func readAt(in io.ReaderAt, buf []byte, offset int64) error {
+	n, err := in.ReadAt(buf, offset)
+	if n == len(buf) {
+		return nil
+	}
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
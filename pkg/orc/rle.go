@@ -0,0 +1,378 @@
+package orc
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+)
+
+// Run length encodings: https://orc.apache.org/specification/ORCv1/#run-length-encoding
+
+// byteRLEReader decodes byte runs: control byte [0, 127] is a run of (control + 3) copies of the next byte,
+// control byte [-128, -1] is followed by -control literal bytes
+type byteRLEReader struct {
+	in     byteStream
+	values []byte
+}
+
+func (r *byteRLEReader) next() (byte, error) {
+	if len(r.values) == 0 {
+		control, err := r.in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if control < 0x80 {
+			value, err := r.in.ReadByte()
+			if err != nil {
+				return 0, xerrors.Errorf("unable to read run value: %w", unexpectedEOF(err))
+			}
+			r.values = r.values[:0]
+			for i := 0; i < int(control)+3; i++ {
+				r.values = append(r.values, value)
+			}
+		} else {
+			r.values = make([]byte, 0x100-int(control))
+			if _, err := io.ReadFull(r.in, r.values); err != nil {
+				return 0, xerrors.Errorf("unable to read literals: %w", unexpectedEOF(err))
+			}
+		}
+	}
+	value := r.values[0]
+	r.values = r.values[1:]
+	return value, nil
+}
+
+// boolReader decodes bits of byte runs, the most significant bit goes first
+type boolReader struct {
+	bytes   *byteRLEReader
+	current byte
+	left    int
+}
+
+func (r *boolReader) next() (bool, error) {
+	if r.left == 0 {
+		var err error
+		r.current, err = r.bytes.next()
+		if err != nil {
+			return false, err
+		}
+		r.left = 8
+	}
+	r.left--
+	return r.current&(1<<r.left) != 0, nil
+}
+
+type intReader interface {
+	next() (int64, error)
+}
+
+// intRLEv1Reader decodes integer runs of version 1: control byte [0, 127] is a run of (control + 3) values,
+// which is followed by delta byte & base varint, control byte [-128, -1] is followed by -control literal varints
+type intRLEv1Reader struct {
+	in     byteStream
+	signed bool
+	values []int64
+}
+
+func (r *intRLEv1Reader) next() (int64, error) {
+	if len(r.values) == 0 {
+		control, err := r.in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		r.values = r.values[:0]
+		if control < 0x80 {
+			delta, err := r.in.ReadByte()
+			if err != nil {
+				return 0, xerrors.Errorf("unable to read run delta: %w", unexpectedEOF(err))
+			}
+			base, err := readVarint(r.in, r.signed)
+			if err != nil {
+				return 0, xerrors.Errorf("unable to read run base: %w", err)
+			}
+			for i := 0; i < int(control)+3; i++ {
+				r.values = append(r.values, base+int64(i)*int64(int8(delta)))
+			}
+		} else {
+			for i := 0; i < 0x100-int(control); i++ {
+				value, err := readVarint(r.in, r.signed)
+				if err != nil {
+					return 0, xerrors.Errorf("unable to read literal: %w", err)
+				}
+				r.values = append(r.values, value)
+			}
+		}
+	}
+	value := r.values[0]
+	r.values = r.values[1:]
+	return value, nil
+}
+
+const (
+	rleV2ShortRepeat = 0
+	rleV2Direct      = 1
+	rleV2PatchedBase = 2
+	rleV2Delta       = 3
+)
+
+// intRLEv2Reader decodes integer runs of version 2, the run encoding is in the 2 high bits of the first byte
+type intRLEv2Reader struct {
+	in     byteStream
+	signed bool
+	values []int64
+	pos    int
+}
+
+func (r *intRLEv2Reader) next() (int64, error) {
+	if r.pos == len(r.values) {
+		first, err := r.in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		r.values = r.values[:0]
+		r.pos = 0
+		switch first >> 6 {
+		case rleV2ShortRepeat:
+			err = r.readShortRepeat(first)
+		case rleV2Direct:
+			err = r.readDirect(first)
+		case rleV2PatchedBase:
+			err = r.readPatchedBase(first)
+		case rleV2Delta:
+			err = r.readDelta(first)
+		}
+		if err != nil {
+			return 0, xerrors.Errorf("unable to decode run: %w", unexpectedEOF(err))
+		}
+		if len(r.values) == 0 {
+			return 0, xerrors.New("empty run")
+		}
+	}
+	value := r.values[r.pos]
+	r.pos++
+	return value, nil
+}
+
+func (r *intRLEv2Reader) decodeSign(value uint64) int64 {
+	if r.signed {
+		return unZigZag(value)
+	}
+	return int64(value)
+}
+
+func (r *intRLEv2Reader) readShortRepeat(first byte) error {
+	width := int(first>>3&0x7) + 1
+	count := int(first&0x7) + 3
+	value, err := readBigEndian(r.in, width)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		r.values = append(r.values, r.decodeSign(value))
+	}
+	return nil
+}
+
+func (r *intRLEv2Reader) readLength(first byte) (int, error) {
+	second, err := r.in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	return (int(first&1)<<8 | int(second)) + 1, nil
+}
+
+func (r *intRLEv2Reader) readDirect(first byte) error {
+	width := decodeBitWidth(first >> 1 & 0x1f)
+	length, err := r.readLength(first)
+	if err != nil {
+		return err
+	}
+	packed, err := readPacked(r.in, length, width)
+	if err != nil {
+		return err
+	}
+	for _, value := range packed {
+		r.values = append(r.values, r.decodeSign(value))
+	}
+	return nil
+}
+
+func (r *intRLEv2Reader) readPatchedBase(first byte) error {
+	width := decodeBitWidth(first >> 1 & 0x1f)
+	length, err := r.readLength(first)
+	if err != nil {
+		return err
+	}
+	var header [2]byte
+	if _, err := io.ReadFull(r.in, header[:]); err != nil {
+		return err
+	}
+	baseWidth := int(header[0]>>5) + 1
+	patchWidth := decodeBitWidth(header[0] & 0x1f)
+	patchGapWidth := int(header[1]>>5) + 1
+	patchListLength := int(header[1] & 0x1f)
+
+	rawBase, err := readBigEndian(r.in, baseWidth)
+	if err != nil {
+		return err
+	}
+	// base is stored in sign-magnitude form
+	signMask := uint64(1) << (baseWidth*8 - 1)
+	base := int64(rawBase &^ signMask)
+	if rawBase&signMask != 0 {
+		base = -base
+	}
+	packed, err := readPacked(r.in, length, width)
+	if err != nil {
+		return err
+	}
+	patches, err := readPacked(r.in, patchListLength, closestFixedBits(patchWidth+patchGapWidth))
+	if err != nil {
+		return err
+	}
+	// patch entries are (gap, patch) pairs, gaps longer than 255 are split into entries with empty patch
+	idx := 0
+	for _, entry := range patches {
+		idx += int(entry >> patchWidth)
+		patch := entry & (uint64(1)<<patchWidth - 1)
+		if patch == 0 {
+			continue
+		}
+		if idx >= length {
+			return xerrors.Errorf("patch position %d is out of run of %d values", idx, length)
+		}
+		packed[idx] |= patch << width
+	}
+	for _, value := range packed {
+		r.values = append(r.values, base+int64(value))
+	}
+	return nil
+}
+
+func (r *intRLEv2Reader) readDelta(first byte) error {
+	width := 0
+	if code := first >> 1 & 0x1f; code != 0 {
+		width = decodeBitWidth(code)
+	}
+	length, err := r.readLength(first)
+	if err != nil {
+		return err
+	}
+	base, err := readVarint(r.in, r.signed)
+	if err != nil {
+		return err
+	}
+	deltaBase, err := readVarint(r.in, true)
+	if err != nil {
+		return err
+	}
+	r.values = append(r.values, base)
+	if width == 0 {
+		// all deltas are equal to the delta base
+		for i := 1; i < length; i++ {
+			r.values = append(r.values, r.values[i-1]+deltaBase)
+		}
+		return nil
+	}
+	r.values = append(r.values, base+deltaBase)
+	// the rest deltas are stored as magnitudes, the sign is the sign of the delta base
+	deltas, err := readPacked(r.in, length-2, width)
+	if err != nil {
+		return err
+	}
+	for _, delta := range deltas {
+		prev := r.values[len(r.values)-1]
+		if deltaBase < 0 {
+			r.values = append(r.values, prev-int64(delta))
+		} else {
+			r.values = append(r.values, prev+int64(delta))
+		}
+	}
+	return nil
+}
+
+func newIntReader(in byteStream, signed bool, encoding encodingKind) intReader {
+	if encoding.isV2() {
+		return &intRLEv2Reader{in: in, signed: signed, values: nil, pos: 0}
+	}
+	return &intRLEv1Reader{in: in, signed: signed, values: nil}
+}
+
+func unZigZag(value uint64) int64 {
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+func readVarint(in byteStream, signed bool) (int64, error) {
+	value, err := binary.ReadUvarint(in)
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	if signed {
+		return unZigZag(value), nil
+	}
+	return int64(value), nil
+}
+
+func readBigEndian(in byteStream, width int) (uint64, error) {
+	var result uint64
+	for i := 0; i < width; i++ {
+		b, err := in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		result = result<<8 | uint64(b)
+	}
+	return result, nil
+}
+
+// readPacked reads count values of width bits, which are packed in big-endian order. The run is padded to whole bytes
+func readPacked(in byteStream, count int, width int) ([]uint64, error) {
+	result := make([]uint64, count)
+	var current byte
+	left := 0
+	for i := range result {
+		var value uint64
+		for need := width; need > 0; {
+			if left == 0 {
+				b, err := in.ReadByte()
+				if err != nil {
+					return nil, err
+				}
+				current, left = b, 8
+			}
+			take := min(need, left)
+			value = value<<take | uint64(current>>(left-take))&(1<<take-1)
+			left -= take
+			need -= take
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+var bitWidths = [32]int{
+	1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16,
+	17, 18, 19, 20, 21, 22, 23, 24, 26, 28, 30, 32, 40, 48, 56, 64,
+}
+
+func decodeBitWidth(code byte) int {
+	return bitWidths[code&0x1f]
+}
+
+func closestFixedBits(width int) int {
+	for _, w := range bitWidths {
+		if w >= width {
+			return w
+		}
+	}
+	return 64
+}
+
+// unexpectedEOF is used when the stream ends in the middle of a value
+func unexpectedEOF(err error) error {
+	if xerrors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,146 @@
+package orc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kind is the kind of ORC type, values are the same as in the file footer
+type Kind int
+
+const (
+	KindBoolean Kind = iota
+	KindByte
+	KindShort
+	KindInt
+	KindLong
+	KindFloat
+	KindDouble
+	KindString
+	KindBinary
+	KindTimestamp
+	KindList
+	KindMap
+	KindStruct
+	KindUnion
+	KindDecimal
+	KindDate
+	KindVarchar
+	KindChar
+	KindTimestampInstant
+)
+
+var kindNames = map[Kind]string{
+	KindBoolean:          "boolean",
+	KindByte:             "tinyint",
+	KindShort:            "smallint",
+	KindInt:              "int",
+	KindLong:             "bigint",
+	KindFloat:            "float",
+	KindDouble:           "double",
+	KindString:           "string",
+	KindBinary:           "binary",
+	KindTimestamp:        "timestamp",
+	KindList:             "array",
+	KindMap:              "map",
+	KindStruct:           "struct",
+	KindUnion:            "uniontype",
+	KindDecimal:          "decimal",
+	KindDate:             "date",
+	KindVarchar:          "varchar",
+	KindChar:             "char",
+	KindTimestampInstant: "timestamp with local time zone",
+}
+
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(k))
+}
+
+// Type is a node of the file schema. Columns of the file are nodes of the schema tree in pre-order,
+// so the root struct is the column 0
+type Type struct {
+	Kind       Kind
+	Children   []*Type
+	FieldNames []string // names of struct fields, in the order of Children
+	MaxLength  uint32   // of varchar & char
+	Precision  uint32   // of decimal
+	Scale      uint32   // of decimal
+
+	id int
+}
+
+// ID returns the column of the type
+func (t *Type) ID() int {
+	return t.id
+}
+
+// String returns the type in the Hive notation, like struct<id:bigint,name:string>
+func (t *Type) String() string {
+	switch t.Kind {
+	case KindList:
+		return fmt.Sprintf("array<%s>", t.Children[0])
+	case KindMap:
+		return fmt.Sprintf("map<%s,%s>", t.Children[0], t.Children[1])
+	case KindStruct:
+		fields := make([]string, len(t.Children))
+		for i, child := range t.Children {
+			fields[i] = fmt.Sprintf("%s:%s", t.FieldNames[i], child)
+		}
+		return fmt.Sprintf("struct<%s>", strings.Join(fields, ","))
+	case KindUnion:
+		children := make([]string, len(t.Children))
+		for i, child := range t.Children {
+			children[i] = child.String()
+		}
+		return fmt.Sprintf("uniontype<%s>", strings.Join(children, ","))
+	case KindDecimal:
+		return fmt.Sprintf("decimal(%d,%d)", t.Precision, t.Scale)
+	case KindVarchar, KindChar:
+		return fmt.Sprintf("%s(%d)", t.Kind, t.MaxLength)
+	default:
+		return t.Kind.String()
+	}
+}
+
+// flatten returns types in pre-order & assigns their ids
+func (t *Type) flatten() []*Type {
+	var result []*Type
+	var walk func(node *Type)
+	walk = func(node *Type) {
+		node.id = len(result)
+		result = append(result, node)
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(t)
+	return result
+}
+
+// Primitive returns a type without children
+func Primitive(kind Kind) *Type {
+	return &Type{Kind: kind, Children: nil, FieldNames: nil, MaxLength: 0, Precision: 0, Scale: 0, id: 0}
+}
+
+// Decimal returns decimal type of the precision & scale
+func Decimal(precision, scale uint32) *Type {
+	return &Type{Kind: KindDecimal, Children: nil, FieldNames: nil, MaxLength: 0, Precision: precision, Scale: scale, id: 0}
+}
+
+// Struct returns struct type of the fields, names & types are paired by position
+func Struct(names []string, types []*Type) *Type {
+	return &Type{Kind: KindStruct, Children: types, FieldNames: names, MaxLength: 0, Precision: 0, Scale: 0, id: 0}
+}
+
+// List returns list type of the items
+func List(items *Type) *Type {
+	return &Type{Kind: KindList, Children: []*Type{items}, FieldNames: nil, MaxLength: 0, Precision: 0, Scale: 0, id: 0}
+}
+
+// Map returns map type of the keys & values
+func Map(keys, values *Type) *Type {
+	return &Type{Kind: KindMap, Children: []*Type{keys, values}, FieldNames: nil, MaxLength: 0, Precision: 0, Scale: 0, id: 0}
+}
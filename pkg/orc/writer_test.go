@@ -0,0 +1,741 @@
+package orc
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"maps"
+	"math"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const defaultStripeRows = 100_000
+
+// WriterOptions are options of Writer
+type WriterOptions struct {
+	Compression Compression
+	StripeRows  int               // rows per stripe, 100000 by default
+	Metadata    map[string][]byte // user metadata, which is stored in the footer
+}
+
+// Writer writes rows into ORC file, it's used by tests to produce files for the reader. Rows of a stripe are kept in memory until the stripe is flushed,
+// the writer must not be used after an error.
+// Integers are written by RLE v1, strings - by DIRECT or DICTIONARY encoding, what is smaller. Unions are not supported.
+// Writer accepts values of the same golang types as the reader returns, and also:
+//   - any integer types for integer columns
+//   - []byte for string columns & string for binary ones
+//   - *big.Rat & integers for decimal columns
+type Writer struct {
+	out       io.Writer
+	opts      WriterOptions
+	types     []*Type
+	columns   []*columnWriter
+	offset    uint64
+	stripes   []StripeInformation
+	rows      uint64
+	stripeLen int
+	stats     []columnStatistics
+}
+
+// NewWriter writes the file header & returns writer of rows of schema, which must be struct
+func NewWriter(out io.Writer, schema *Type, opts WriterOptions) (*Writer, error) {
+	if schema.Kind != KindStruct {
+		return nil, xerrors.Errorf("root type must be struct, but got %s", schema.Kind)
+	}
+	if opts.Compression == CompressionLZO || !opts.Compression.isSupported() {
+		return nil, xerrors.Errorf("unsupported compression: %s", opts.Compression)
+	}
+	if opts.StripeRows <= 0 {
+		opts.StripeRows = defaultStripeRows
+	}
+	types := schema.flatten()
+	for _, t := range types {
+		if t.Kind == KindUnion {
+			return nil, xerrors.New("unions are not supported by writer")
+		}
+		if t.Kind == KindStruct && len(t.FieldNames) != len(t.Children) {
+			return nil, xerrors.Errorf("struct has %d names of %d fields", len(t.FieldNames), len(t.Children))
+		}
+	}
+	if _, err := out.Write([]byte(magic)); err != nil {
+		return nil, xerrors.Errorf("unable to write header: %w", err)
+	}
+	writer := &Writer{
+		out:       out,
+		opts:      opts,
+		types:     types,
+		columns:   make([]*columnWriter, len(types)),
+		offset:    uint64(len(magic)),
+		stripes:   nil,
+		rows:      0,
+		stripeLen: 0,
+		stats:     make([]columnStatistics, len(types)),
+	}
+	for i, t := range types {
+		writer.columns[i] = &columnWriter{t: t, present: nil, hasNull: false, values: nil}
+	}
+	return writer, nil
+}
+
+// Write appends the row, values are fields of the root struct in order
+func (w *Writer) Write(row []any) error {
+	root := w.types[0]
+	if len(row) != len(root.Children) {
+		return xerrors.Errorf("row has %d values of %d columns", len(row), len(root.Children))
+	}
+	w.columns[0].present = append(w.columns[0].present, true)
+	for i, child := range root.Children {
+		if err := w.add(child, row[i]); err != nil {
+			return xerrors.Errorf("invalid value of column %s: %w", root.FieldNames[i], err)
+		}
+	}
+	w.stripeLen++
+	if w.stripeLen >= w.opts.StripeRows {
+		return w.flush()
+	}
+	return nil
+}
+
+// Close flushes the last stripe & writes the file tail, underlying writer is not closed
+func (w *Writer) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	ftr := &footer{
+		headerLength:   uint64(len(magic)),
+		contentLength:  w.offset - uint64(len(magic)),
+		stripes:        w.stripes,
+		types:          w.types,
+		metadata:       w.opts.Metadata,
+		numberOfRows:   w.rows,
+		rowIndexStride: 0,
+		statistics:     w.stats,
+	}
+	rawFooter, err := compress(w.opts.Compression, defaultCompressionBlock, ftr.marshal())
+	if err != nil {
+		return xerrors.Errorf("unable to compress footer: %w", err)
+	}
+	ps := &postScript{
+		footerLength:         uint64(len(rawFooter)),
+		compression:          w.opts.Compression,
+		compressionBlockSize: defaultCompressionBlock,
+		version:              []uint32{0, 11},
+		metadataLength:       0,
+		magic:                magic,
+	}
+	rawPostScript := ps.marshal()
+	tail := append(rawFooter, rawPostScript...)
+	tail = append(tail, byte(len(rawPostScript)))
+	if _, err := w.out.Write(tail); err != nil {
+		return xerrors.Errorf("unable to write tail: %w", err)
+	}
+	return nil
+}
+
+func (w *Writer) add(t *Type, value any) error {
+	column := w.columns[t.id]
+	if value == nil {
+		column.present = append(column.present, false)
+		column.hasNull = true
+		return nil
+	}
+	column.present = append(column.present, true)
+	switch t.Kind {
+	case KindStruct:
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return xerrors.Errorf("struct value must be map[string]any, but got %T", value)
+		}
+		for i, child := range t.Children {
+			if err := w.add(child, fields[t.FieldNames[i]]); err != nil {
+				return xerrors.Errorf("invalid value of field %s: %w", t.FieldNames[i], err)
+			}
+		}
+		return nil
+	case KindList:
+		items, ok := value.([]any)
+		if !ok {
+			return xerrors.Errorf("array value must be []any, but got %T", value)
+		}
+		column.values = append(column.values, int64(len(items)))
+		for _, item := range items {
+			if err := w.add(t.Children[0], item); err != nil {
+				return xerrors.Errorf("invalid item: %w", err)
+			}
+		}
+		return nil
+	case KindMap:
+		entries, ok := value.(map[string]any)
+		if !ok {
+			return xerrors.Errorf("map value must be map[string]any, but got %T", value)
+		}
+		column.values = append(column.values, int64(len(entries)))
+		for _, key := range slices.Sorted(maps.Keys(entries)) {
+			if err := w.add(t.Children[0], key); err != nil {
+				return xerrors.Errorf("invalid key: %w", err)
+			}
+			if err := w.add(t.Children[1], entries[key]); err != nil {
+				return xerrors.Errorf("invalid value of key %s: %w", key, err)
+			}
+		}
+		return nil
+	default:
+		converted, err := convertValue(t, value)
+		if err != nil {
+			return err
+		}
+		column.values = append(column.values, converted)
+		return nil
+	}
+}
+
+func (w *Writer) flush() error {
+	if w.stripeLen == 0 {
+		return nil
+	}
+	sf := &stripeFooter{streams: nil, columns: nil, writerTimezone: "UTC"}
+	var data []byte
+	for i, column := range w.columns {
+		streams, encoding, err := column.encode()
+		if err != nil {
+			return xerrors.Errorf("unable to encode column %d: %w", i, err)
+		}
+		sf.columns = append(sf.columns, encoding)
+		for _, s := range streams {
+			compressed, err := compress(w.opts.Compression, defaultCompressionBlock, s.data)
+			if err != nil {
+				return xerrors.Errorf("unable to compress stream: %w", err)
+			}
+			sf.streams = append(sf.streams, stream{kind: s.kind, column: uint64(i), length: uint64(len(compressed))})
+			data = append(data, compressed...)
+		}
+		for _, present := range column.present {
+			if present {
+				w.stats[i].numberOfValues++
+			}
+		}
+		w.stats[i].hasNull = w.stats[i].hasNull || column.hasNull
+		column.present, column.hasNull, column.values = nil, false, nil
+	}
+	rawFooter, err := compress(w.opts.Compression, defaultCompressionBlock, sf.marshal())
+	if err != nil {
+		return xerrors.Errorf("unable to compress stripe footer: %w", err)
+	}
+	if _, err := w.out.Write(append(data, rawFooter...)); err != nil {
+		return xerrors.Errorf("unable to write stripe: %w", err)
+	}
+	w.stripes = append(w.stripes, StripeInformation{
+		Offset:       w.offset,
+		IndexLength:  0,
+		DataLength:   uint64(len(data)),
+		FooterLength: uint64(len(rawFooter)),
+		NumberOfRows: uint64(w.stripeLen),
+	})
+	w.offset += uint64(len(data) + len(rawFooter))
+	w.rows += uint64(w.stripeLen)
+	w.stripeLen = 0
+	return nil
+}
+
+// columnWriter keeps presence of values & converted not null values of the current stripe.
+// Values are int64 for integers, dates, lengths of arrays & maps, and also are float64, bool, []byte, time.Time & *big.Int
+type columnWriter struct {
+	t       *Type
+	present []bool
+	hasNull bool
+	values  []any
+}
+
+type encodedStream struct {
+	kind streamKind
+	data []byte
+}
+
+func (c *columnWriter) encode() ([]encodedStream, columnEncoding, error) {
+	encoding := columnEncoding{kind: encodingDirect, dictionarySize: 0}
+	var streams []encodedStream
+	if c.hasNull {
+		streams = append(streams, encodedStream{kind: streamPresent, data: appendBoolRLE(nil, c.present)})
+	}
+	switch c.t.Kind {
+	case KindStruct:
+	case KindBoolean:
+		values := make([]bool, len(c.values))
+		for i, v := range c.values {
+			values[i] = v.(bool)
+		}
+		streams = append(streams, encodedStream{kind: streamData, data: appendBoolRLE(nil, values)})
+	case KindByte:
+		values := make([]byte, len(c.values))
+		for i, v := range c.values {
+			values[i] = byte(v.(int64))
+		}
+		streams = append(streams, encodedStream{kind: streamData, data: appendByteRLE(nil, values)})
+	case KindShort, KindInt, KindLong, KindDate:
+		streams = append(streams, encodedStream{kind: streamData, data: appendIntRLEv1(nil, int64Values(c.values), true)})
+	case KindList, KindMap:
+		streams = append(streams, encodedStream{kind: streamLength, data: appendIntRLEv1(nil, int64Values(c.values), false)})
+	case KindFloat, KindDouble:
+		var data []byte
+		for _, v := range c.values {
+			if c.t.Kind == KindFloat {
+				data = binary.LittleEndian.AppendUint32(data, math.Float32bits(float32(v.(float64))))
+			} else {
+				data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v.(float64)))
+			}
+		}
+		streams = append(streams, encodedStream{kind: streamData, data: data})
+	case KindString, KindVarchar, KindChar, KindBinary:
+		var stringStreams []encodedStream
+		stringStreams, encoding = encodeStrings(c.t, c.values)
+		streams = append(streams, stringStreams...)
+	case KindTimestamp, KindTimestampInstant:
+		seconds := make([]int64, len(c.values))
+		nanos := make([]int64, len(c.values))
+		for i, v := range c.values {
+			seconds[i], nanos[i] = encodeTimestamp(v.(time.Time))
+		}
+		streams = append(streams,
+			encodedStream{kind: streamData, data: appendIntRLEv1(nil, seconds, true)},
+			encodedStream{kind: streamSecondary, data: appendIntRLEv1(nil, nanos, false)},
+		)
+	case KindDecimal:
+		var data []byte
+		scales := make([]int64, len(c.values))
+		for i, v := range c.values {
+			data = appendBigVarint(data, v.(*big.Int))
+			scales[i] = int64(c.t.Scale)
+		}
+		streams = append(streams,
+			encodedStream{kind: streamData, data: data},
+			encodedStream{kind: streamSecondary, data: appendIntRLEv1(nil, scales, true)},
+		)
+	default:
+		return nil, encoding, xerrors.Errorf("unsupported type: %s", c.t.Kind)
+	}
+	return streams, encoding, nil
+}
+
+// encodeStrings uses dictionary, if there are at least two values per dictionary entry
+func encodeStrings(t *Type, values []any) ([]encodedStream, columnEncoding) {
+	distinct := map[string]int64{}
+	for _, v := range values {
+		distinct[string(v.([]byte))] = 0
+	}
+	if t.Kind == KindBinary || len(values) == 0 || 2*len(distinct) > len(values) {
+		var data []byte
+		lengths := make([]int64, len(values))
+		for i, v := range values {
+			data = append(data, v.([]byte)...)
+			lengths[i] = int64(len(v.([]byte)))
+		}
+		return []encodedStream{
+			{kind: streamData, data: data},
+			{kind: streamLength, data: appendIntRLEv1(nil, lengths, false)},
+		}, columnEncoding{kind: encodingDirect, dictionarySize: 0}
+	}
+
+	dictionary := make([]string, 0, len(distinct))
+	for v := range distinct {
+		dictionary = append(dictionary, v)
+	}
+	slices.Sort(dictionary)
+	var dictionaryData []byte
+	lengths := make([]int64, len(dictionary))
+	for i, v := range dictionary {
+		distinct[v] = int64(i)
+		dictionaryData = append(dictionaryData, v...)
+		lengths[i] = int64(len(v))
+	}
+	indexes := make([]int64, len(values))
+	for i, v := range values {
+		indexes[i] = distinct[string(v.([]byte))]
+	}
+	return []encodedStream{
+		{kind: streamData, data: appendIntRLEv1(nil, indexes, false)},
+		{kind: streamLength, data: appendIntRLEv1(nil, lengths, false)},
+		{kind: streamDictionaryData, data: dictionaryData},
+	}, columnEncoding{kind: encodingDictionary, dictionarySize: uint64(len(dictionary))}
+}
+
+// encodeTimestamp returns seconds since the base, which are truncated toward zero like other writers do, & encoded nanoseconds
+func encodeTimestamp(t time.Time) (int64, int64) {
+	seconds := t.Unix()
+	nanos := int64(t.Nanosecond())
+	if seconds < 0 && nanos > 999999 {
+		seconds++
+	}
+	if nanos == 0 {
+		return seconds - timestampBase, 0
+	}
+	zeros := int64(0)
+	for nanos%10 == 0 && zeros < 8 {
+		nanos /= 10
+		zeros++
+	}
+	if zeros < 2 {
+		return seconds - timestampBase, int64(t.Nanosecond()) << 3
+	}
+	return seconds - timestampBase, nanos<<3 | (zeros - 1)
+}
+
+func int64Values(values []any) []int64 {
+	result := make([]int64, len(values))
+	for i, v := range values {
+		result[i] = v.(int64)
+	}
+	return result
+}
+
+func convertValue(t *Type, value any) (any, error) {
+	switch t.Kind {
+	case KindBoolean:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case KindByte, KindShort, KindInt, KindLong:
+		if v, ok := toInt64(value); ok {
+			return v, nil
+		}
+	case KindDate:
+		if v, ok := value.(time.Time); ok {
+			return int64(math.Floor(float64(v.Unix()) / (24 * 60 * 60))), nil
+		}
+	case KindFloat, KindDouble:
+		switch v := value.(type) {
+		case float32:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		}
+	case KindString, KindVarchar, KindChar, KindBinary:
+		switch v := value.(type) {
+		case string:
+			return []byte(v), nil
+		case []byte:
+			return v, nil
+		}
+	case KindTimestamp, KindTimestampInstant:
+		if v, ok := value.(time.Time); ok {
+			return v, nil
+		}
+	case KindDecimal:
+		return convertDecimal(t, value)
+	}
+	return nil, xerrors.Errorf("unsupported value of %s: %T", t, value)
+}
+
+func convertDecimal(t *Type, value any) (any, error) {
+	var rat *big.Rat
+	switch v := value.(type) {
+	case string:
+		var ok bool
+		if rat, ok = new(big.Rat).SetString(v); !ok {
+			return nil, xerrors.Errorf("invalid decimal: %s", v)
+		}
+	case *big.Rat:
+		rat = v
+	default:
+		i, ok := toInt64(value)
+		if !ok {
+			return nil, xerrors.Errorf("unsupported value of %s: %T", t, value)
+		}
+		rat = new(big.Rat).SetInt64(i)
+	}
+	scaled := new(big.Rat).Mul(rat, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Scale)), nil)))
+	if !scaled.IsInt() {
+		return nil, xerrors.Errorf("decimal %s doesn't fit scale %d", rat.FloatString(int(t.Scale)+1), t.Scale)
+	}
+	return new(big.Int).Set(scaled.Num()), nil
+}
+
+func toInt64(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func appendBoolRLE(buf []byte, values []bool) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return appendByteRLE(buf, packed)
+}
+
+const (
+	minRun      = 3
+	maxRun      = 130
+	maxLiterals = 128
+)
+
+func appendByteRLE(buf []byte, values []byte) []byte {
+	isRun := func(i int) bool {
+		return i+minRun <= len(values) && values[i] == values[i+1] && values[i] == values[i+2]
+	}
+	for i := 0; i < len(values); {
+		if isRun(i) {
+			n := minRun
+			for i+n < len(values) && n < maxRun && values[i+n] == values[i] {
+				n++
+			}
+			buf = append(buf, byte(n-minRun), values[i])
+			i += n
+			continue
+		}
+		start := i
+		for i < len(values) && i-start < maxLiterals && !isRun(i) {
+			i++
+		}
+		buf = append(buf, byte(-(i - start)))
+		buf = append(buf, values[start:i]...)
+	}
+	return buf
+}
+
+func appendIntRLEv1(buf []byte, values []int64, signed bool) []byte {
+	appendValue := func(buf []byte, v int64) []byte {
+		if signed {
+			return binary.AppendUvarint(buf, zigZag(v))
+		}
+		return binary.AppendUvarint(buf, uint64(v))
+	}
+	isRun := func(i int) bool {
+		if i+minRun > len(values) {
+			return false
+		}
+		delta := values[i+1] - values[i]
+		return delta >= math.MinInt8 && delta <= math.MaxInt8 && values[i+2]-values[i+1] == delta
+	}
+	for i := 0; i < len(values); {
+		if isRun(i) {
+			delta := values[i+1] - values[i]
+			n := minRun
+			for i+n < len(values) && n < maxRun && values[i+n]-values[i+n-1] == delta {
+				n++
+			}
+			buf = append(buf, byte(n-minRun), byte(int8(delta)))
+			buf = appendValue(buf, values[i])
+			i += n
+			continue
+		}
+		start := i
+		for i < len(values) && i-start < maxLiterals && !isRun(i) {
+			i++
+		}
+		buf = append(buf, byte(-(i - start)))
+		for _, v := range values[start:i] {
+			buf = appendValue(buf, v)
+		}
+	}
+	return buf
+}
+
+func appendVarintField(buf []byte, num protowire.Number, v uint64) []byte {
+	buf = protowire.AppendTag(buf, num, protowire.VarintType)
+	return protowire.AppendVarint(buf, v)
+}
+
+func appendBytesField(buf []byte, num protowire.Number, v []byte) []byte {
+	buf = protowire.AppendTag(buf, num, protowire.BytesType)
+	return protowire.AppendBytes(buf, v)
+}
+
+func (p *postScript) marshal() []byte {
+	var buf []byte
+	buf = appendVarintField(buf, 1, p.footerLength)
+	buf = appendVarintField(buf, 2, uint64(p.compression))
+	buf = appendVarintField(buf, 3, p.compressionBlockSize)
+	var versions []byte
+	for _, v := range p.version {
+		versions = protowire.AppendVarint(versions, uint64(v))
+	}
+	buf = appendBytesField(buf, 4, versions)
+	buf = appendVarintField(buf, 5, p.metadataLength)
+	return appendBytesField(buf, 8000, []byte(p.magic))
+}
+
+func (f *footer) marshal() []byte {
+	var buf []byte
+	buf = appendVarintField(buf, 1, f.headerLength)
+	buf = appendVarintField(buf, 2, f.contentLength)
+	for _, s := range f.stripes {
+		var msg []byte
+		msg = appendVarintField(msg, 1, s.Offset)
+		msg = appendVarintField(msg, 2, s.IndexLength)
+		msg = appendVarintField(msg, 3, s.DataLength)
+		msg = appendVarintField(msg, 4, s.FooterLength)
+		msg = appendVarintField(msg, 5, s.NumberOfRows)
+		buf = appendBytesField(buf, 3, msg)
+	}
+	for _, t := range f.types {
+		var msg []byte
+		msg = appendVarintField(msg, 1, uint64(t.Kind))
+		if len(t.Children) > 0 {
+			var subtypes []byte
+			for _, child := range t.Children {
+				subtypes = protowire.AppendVarint(subtypes, uint64(child.id))
+			}
+			msg = appendBytesField(msg, 2, subtypes)
+		}
+		for _, name := range t.FieldNames {
+			msg = appendBytesField(msg, 3, []byte(name))
+		}
+		switch t.Kind {
+		case KindVarchar, KindChar:
+			msg = appendVarintField(msg, 4, uint64(t.MaxLength))
+		case KindDecimal:
+			msg = appendVarintField(msg, 5, uint64(t.Precision))
+			msg = appendVarintField(msg, 6, uint64(t.Scale))
+		}
+		buf = appendBytesField(buf, 4, msg)
+	}
+	for _, name := range slices.Sorted(maps.Keys(f.metadata)) {
+		var msg []byte
+		msg = appendBytesField(msg, 1, []byte(name))
+		msg = appendBytesField(msg, 2, f.metadata[name])
+		buf = appendBytesField(buf, 5, msg)
+	}
+	buf = appendVarintField(buf, 6, f.numberOfRows)
+	for _, s := range f.statistics {
+		var msg []byte
+		msg = appendVarintField(msg, 1, s.numberOfValues)
+		if s.hasNull {
+			msg = appendVarintField(msg, 10, 1)
+		}
+		buf = appendBytesField(buf, 7, msg)
+	}
+	return appendVarintField(buf, 8, f.rowIndexStride)
+}
+
+func (f *stripeFooter) marshal() []byte {
+	var buf []byte
+	for _, s := range f.streams {
+		var msg []byte
+		msg = appendVarintField(msg, 1, uint64(s.kind))
+		msg = appendVarintField(msg, 2, s.column)
+		msg = appendVarintField(msg, 3, s.length)
+		buf = appendBytesField(buf, 1, msg)
+	}
+	for _, e := range f.columns {
+		var msg []byte
+		msg = appendVarintField(msg, 1, uint64(e.kind))
+		if e.kind.isDictionary() {
+			msg = appendVarintField(msg, 2, e.dictionarySize)
+		}
+		buf = appendBytesField(buf, 2, msg)
+	}
+	if f.writerTimezone != "" {
+		buf = appendBytesField(buf, 3, []byte(f.writerTimezone))
+	}
+	return buf
+}
+
+func appendBigVarint(buf []byte, value *big.Int) []byte {
+	encoded := new(big.Int).Lsh(value, 1)
+	if value.Sign() < 0 {
+		encoded.Neg(encoded).Sub(encoded, big.NewInt(1))
+	}
+	for encoded.BitLen() > 7 {
+		buf = append(buf, byte(encoded.Uint64()&0x7f|0x80))
+		encoded.Rsh(encoded, 7)
+	}
+	return append(buf, byte(encoded.Uint64()))
+}
+
+func zigZag(value int64) uint64 {
+	return uint64(value<<1) ^ uint64(value>>63)
+}
+
+// compress splits data into chunks, chunks, which are not shrunk by compression, are stored as is
+func compress(compression Compression, blockSize int, data []byte) ([]byte, error) {
+	if compression == CompressionNone {
+		return data, nil
+	}
+	var result []byte
+	for len(data) > 0 {
+		chunk := data[:min(blockSize, len(data))]
+		data = data[len(chunk):]
+		compressed, err := compressChunk(compression, chunk)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to compress chunk: %w", err)
+		}
+		header := len(compressed) << 1
+		if len(compressed) >= len(chunk) {
+			compressed = chunk
+			header = len(chunk)<<1 | 1
+		}
+		result = append(result, byte(header), byte(header>>8), byte(header>>16))
+		result = append(result, compressed...)
+	}
+	return result, nil
+}
+
+// zstdEncoder is safe for concurrent use of EncodeAll, so it's shared
+var zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+	return zstd.NewWriter(nil)
+})
+
+func compressChunk(compression Compression, data []byte) ([]byte, error) {
+	switch compression {
+	case CompressionZlib:
+		var buf bytes.Buffer
+		writer, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressionSnappy:
+		return snappy.Encode(nil, data), nil
+	case CompressionLZ4:
+		result := make([]byte, lz4.CompressBlockBound(len(data)))
+		n, err := lz4.CompressBlock(data, result, nil)
+		if err != nil {
+			return nil, err
+		}
+		return result[:n], nil
+	case CompressionZstd:
+		encoder, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+		return encoder.EncodeAll(data, nil), nil
+	default:
+		return nil, xerrors.Errorf("unsupported compression: %s", compression)
+	}
+}
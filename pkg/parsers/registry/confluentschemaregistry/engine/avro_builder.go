@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"sync"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/avro"
+	"github.com/transferia/transferia/pkg/schemaregistry/confluent"
+)
+
+type avroBuilder struct {
+	mutex      sync.Mutex
+	idToSchema map[int]*avro.Schema
+}
+
+func (b *avroBuilder) toSchema(schema *confluent.Schema, refs map[string]confluent.Schema) (*avro.Schema, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if cachedSchema, ok := b.idToSchema[schema.ID]; ok {
+		return cachedSchema, nil
+	}
+
+	// in avro, reference name is a fully qualified name of the referenced type
+	names := avro.NewNames(func(fullName string) (string, error) {
+		ref, ok := refs[fullName]
+		if !ok {
+			return "", xerrors.Errorf("schema doesn't have reference with name %s", fullName)
+		}
+		return ref.Schema, nil
+	})
+	avroSchema, err := names.Parse(schema.Schema)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to parse avro schema, err: %w", err)
+	}
+	if avroSchema.Type != avro.TypeRecord {
+		return nil, xerrors.Errorf("top-level avro schema must be a record, got: %s", avroSchema.Type)
+	}
+
+	if schema.ID >= 0 {
+		b.idToSchema[schema.ID] = avroSchema
+	}
+	return avroSchema, nil
+}
+
+func newAvroBuilder() *avroBuilder {
+	return &avroBuilder{
+		mutex:      sync.Mutex{},
+		idToSchema: make(map[int]*avro.Schema),
+	}
+}
@@ -24,6 +24,7 @@ type ConfluentSrImpl struct {
 	isGenerateUpdates         bool
 	sendSrNotFoundToUnparsed  bool
 	inMDBuilder               *mdBuilder
+	inAvroBuilder             *avroBuilder
 }
 
 func (p *ConfluentSrImpl) doWithSchema(partition abstract.Partition, schema *confluent.Schema, refs map[string]confluent.Schema, name string, buf []byte, offset uint64, writeTime time.Time, isCloudevents bool) ([]byte, []abstract.ChangeItem) {
@@ -36,8 +37,11 @@ func (p *ConfluentSrImpl) doWithSchema(partition abstract.Partition, schema *con
 	case confluent.PROTOBUF:
 		changeItems, err = makeChangeItemsFromMessageWithProtobuf(p.inMDBuilder, schema, refs, name, buf, offset, writeTime, isCloudevents, p.isGenerateUpdates)
 		msgLen = len(buf)
+	case confluent.AVRO:
+		changeItems, err = makeChangeItemsFromMessageWithAvro(p.inAvroBuilder, schema, refs, buf, offset, writeTime, p.isGenerateUpdates)
+		msgLen = len(buf)
 	default:
-		err = xerrors.Errorf("Schema type is not JSON/PROTOBUF (%v) (currently only the json & protobuf schema is supported)", schema.SchemaType)
+		err = xerrors.Errorf("Schema type is not JSON/PROTOBUF/AVRO (%v) (currently only the json, protobuf & avro schemas are supported)", schema.SchemaType)
 	}
 	if err != nil {
 		errStr := xerrors.Errorf("Can't make change item from message %w", err).Error()
@@ -67,11 +71,6 @@ func (p *ConfluentSrImpl) DoWithSchemaID(partition abstract.Partition, schemaID
 		return nil, []abstract.ChangeItem{genericparser.NewUnparsed(partition, partition.Topic, string(buf), errStr, 0, offset, writeTime)}
 	}
 
-	if currSchema.SchemaType.String() == "" {
-		errStr := xerrors.Errorf("Schema type for schema (id: %v) not defined", schemaID).Error()
-		return nil, []abstract.ChangeItem{genericparser.NewUnparsed(partition, partition.Topic, string(buf), errStr, 0, offset, writeTime)}
-	}
-
 	// handle 'references', if present
 	var refs map[string]confluent.Schema = nil
 	var err error
@@ -145,5 +144,6 @@ func NewConfluentSchemaRegistryImpl(srURL string, caCert string, username string
 		isGenerateUpdates:         isGenerateUpdates,
 		sendSrNotFoundToUnparsed:  sendSrNotFoundToUnparsed,
 		inMDBuilder:               newMDBuilder(),
+		inAvroBuilder:             newAvroBuilder(),
 	}
 }
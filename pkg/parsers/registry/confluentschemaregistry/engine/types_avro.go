@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"github.com/transferia/transferia/pkg/avro"
+	ytschema "go.ytsaurus.tech/yt/go/schema"
+)
+
+var avroSchemaTypes = map[avro.Type]ytschema.Type{
+	avro.TypeNull:    ytschema.TypeAny,
+	avro.TypeBoolean: ytschema.TypeBoolean,
+	avro.TypeInt:     ytschema.TypeInt32,
+	avro.TypeLong:    ytschema.TypeInt64,
+	avro.TypeFloat:   ytschema.TypeFloat32,
+	avro.TypeDouble:  ytschema.TypeFloat64,
+	avro.TypeBytes:   ytschema.TypeBytes,
+	avro.TypeString:  ytschema.TypeString,
+	avro.TypeRecord:  ytschema.TypeAny,
+	avro.TypeEnum:    ytschema.TypeString,
+	avro.TypeArray:   ytschema.TypeAny,
+	avro.TypeMap:     ytschema.TypeAny,
+	avro.TypeFixed:   ytschema.TypeBytes,
+	avro.TypeUnion:   ytschema.TypeAny,
+}
+
+var avroLogicalTypes = map[avro.LogicalType]ytschema.Type{
+	avro.LogicalTypeDecimal:              ytschema.TypeString, // to not lose precision, as debezium receiver does
+	avro.LogicalTypeUUID:                 ytschema.TypeString,
+	avro.LogicalTypeDate:                 ytschema.TypeDate,
+	avro.LogicalTypeTimeMillis:           ytschema.TypeInterval,
+	avro.LogicalTypeTimeMicros:           ytschema.TypeInterval,
+	avro.LogicalTypeTimestampMillis:      ytschema.TypeTimestamp,
+	avro.LogicalTypeTimestampMicros:      ytschema.TypeTimestamp,
+	avro.LogicalTypeTimestampNanos:       ytschema.TypeTimestamp,
+	avro.LogicalTypeLocalTimestampMillis: ytschema.TypeTimestamp,
+	avro.LogicalTypeLocalTimestampMicros: ytschema.TypeTimestamp,
+	avro.LogicalTypeLocalTimestampNanos:  ytschema.TypeTimestamp,
+}
+
+// avroToYtType returns yt type of column & 'required' flag
+// Unions like ["null", T] are nullable columns of type T, any other unions are columns of type 'any'
+func avroToYtType(schema *avro.Schema) (ytschema.Type, bool) {
+	isRequired := !schema.IsNullable()
+	notNullBranches := schema.NotNullBranches()
+	if len(notNullBranches) != 1 {
+		return ytschema.TypeAny, isRequired
+	}
+	currSchema := notNullBranches[0]
+	if currSchema.LogicalType != "" {
+		if ytType, ok := avroLogicalTypes[currSchema.LogicalType]; ok && logicalTypeIsApplicable(currSchema) {
+			return ytType, isRequired
+		}
+	}
+	return avroSchemaTypes[currSchema.Type], isRequired
+}
+
+// logicalTypeIsApplicable checks the underlying type, bcs avro says:
+// 'Language implementations must ignore unknown logical types when reading, and should use the underlying Avro type'
+func logicalTypeIsApplicable(schema *avro.Schema) bool {
+	switch schema.LogicalType {
+	case avro.LogicalTypeDecimal:
+		return schema.Type == avro.TypeBytes || schema.Type == avro.TypeFixed
+	case avro.LogicalTypeUUID:
+		return schema.Type == avro.TypeString
+	case avro.LogicalTypeDate, avro.LogicalTypeTimeMillis:
+		return schema.Type == avro.TypeInt
+	default:
+		return schema.Type == avro.TypeLong
+	}
+}
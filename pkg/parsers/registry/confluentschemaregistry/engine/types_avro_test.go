@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/changeitem/strictify"
+	"github.com/transferia/transferia/pkg/avro"
+	confluentsrmock "github.com/transferia/transferia/tests/helpers/confluent_schema_registry_mock"
+	ytschema "go.ytsaurus.tech/yt/go/schema"
+)
+
+const avroAddressSchema = `{
+	"type": "record",
+	"name": "Address",
+	"namespace": "com.example",
+	"fields": [
+		{"name": "city", "type": "string"},
+		{"name": "zip", "type": ["null", "string"], "default": null}
+	]
+}`
+
+const avroUserSchema = `{
+	"type": "record",
+	"name": "users",
+	"namespace": "com.example",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "name", "type": ["null", "string"], "default": null},
+		{"name": "score", "type": "double"},
+		{"name": "balance", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+		{"name": "created_at", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+		{"name": "birthday", "type": ["null", {"type": "int", "logicalType": "date"}]},
+		{"name": "uid", "type": {"type": "string", "logicalType": "uuid"}},
+		{"name": "address", "type": "Address"},
+		{"name": "tags", "type": {"type": "array", "items": "string"}}
+	]
+}`
+
+func makeAvroMessage(t *testing.T, schemaID uint32, schema *avro.Schema, val map[string]any) []byte {
+	payload, err := avro.Encode(schema, val)
+	require.NoError(t, err)
+	buf := []byte{0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(buf[1:5], schemaID)
+	return append(buf, payload...)
+}
+
+func TestAvro(t *testing.T) {
+	userSchemaResponse, err := json.Marshal(map[string]any{
+		"schema": avroUserSchema,
+		// 'schemaType' is omitted intentionally - schema registry doesn't return it for avro
+		"references": []map[string]any{{"name": "com.example.Address", "subject": "address-value", "version": 1}},
+	})
+	require.NoError(t, err)
+	addressSchemaResponse, err := json.Marshal(map[string]any{"schema": avroAddressSchema})
+	require.NoError(t, err)
+
+	schemaRegistryMock := confluentsrmock.NewConfluentSRMock(map[int]string{1: string(addressSchemaResponse), 2: string(userSchemaResponse)}, nil)
+	defer schemaRegistryMock.Close()
+	schemaRegistryMock.AddSchema(t, "address-value", 1, 3, string(addressSchemaResponse))
+
+	names := avro.NewNames(nil)
+	_, err = names.Parse(avroAddressSchema)
+	require.NoError(t, err)
+	userSchema, err := names.Parse(avroUserSchema)
+	require.NoError(t, err)
+
+	createdAt := time.Date(2024, 5, 1, 12, 30, 0, 123000, time.UTC)
+	msg := makeAvroMessage(t, 2, userSchema, map[string]any{
+		"id":         int64(1),
+		"name":       "John",
+		"score":      0.5,
+		"balance":    "12.34",
+		"created_at": createdAt,
+		"birthday":   nil,
+		"uid":        "4c5a3e4e-8f0a-4b7e-9a1d-0d1f2c3b4a59",
+		"address":    map[string]any{"city": "Amsterdam", "zip": nil},
+		"tags":       []any{"a", "b"},
+	})
+
+	parser := NewConfluentSchemaRegistryImpl(schemaRegistryMock.URL(), "", "uname", "pass", false, false, logger.Log)
+	result := parser.Do(makePersqueueReadMessage(0, msg), abstract.Partition{Cluster: "", Partition: 0, Topic: "users"})
+	require.Len(t, result, 1)
+	abstract.Dump(result)
+
+	changeItem := result[0]
+	require.Equal(t, abstract.InsertKind, changeItem.Kind)
+	require.Equal(t, "com.example", changeItem.Schema)
+	require.Equal(t, "users", changeItem.Table)
+
+	expectedTypes := map[string]ytschema.Type{
+		"id":         ytschema.TypeInt64,
+		"name":       ytschema.TypeString,
+		"score":      ytschema.TypeFloat64,
+		"balance":    ytschema.TypeString,
+		"created_at": ytschema.TypeTimestamp,
+		"birthday":   ytschema.TypeDate,
+		"uid":        ytschema.TypeString,
+		"address":    ytschema.TypeAny,
+		"tags":       ytschema.TypeAny,
+	}
+	for _, col := range changeItem.TableSchema.Columns() {
+		require.Equal(t, expectedTypes[col.ColumnName].String(), col.DataType, col.ColumnName)
+	}
+	require.False(t, changeItem.TableSchema.Columns()[1].Required)
+	require.True(t, changeItem.TableSchema.Columns()[0].Required)
+
+	values := changeItem.AsMap()
+	require.Equal(t, int64(1), values["id"])
+	require.Equal(t, "John", values["name"])
+	require.Equal(t, "12.34", values["balance"])
+	require.Equal(t, createdAt, values["created_at"])
+	require.Nil(t, values["birthday"])
+	require.Equal(t, map[string]any{"city": "Amsterdam", "zip": nil}, values["address"])
+	require.NoError(t, strictify.Strictify(&changeItem, abstract.MakeFastTableSchema(changeItem.TableSchema.Columns())))
+
+	// broken message goes into unparsed
+	result = parser.Do(makePersqueueReadMessage(1, msg[:len(msg)-3]), abstract.Partition{Cluster: "", Partition: 0, Topic: "users"})
+	require.Len(t, result, 1)
+	require.Equal(t, "users_unparsed", result[0].Table)
+
+	// avro messages are not delimited, so trailing bytes are not parsed as the next message
+	result = parser.Do(makePersqueueReadMessage(2, append(append([]byte{}, msg...), msg...)), abstract.Partition{Cluster: "", Partition: 0, Topic: "users"})
+	require.Len(t, result, 1)
+	require.Equal(t, "users_unparsed", result[0].Table)
+}
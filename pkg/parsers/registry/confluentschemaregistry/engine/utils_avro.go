@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/changeitem"
+	"github.com/transferia/transferia/pkg/avro"
+	"github.com/transferia/transferia/pkg/schemaregistry/confluent"
+)
+
+// makeChangeItemsFromMessageWithAvro decodes one avro message (without wire-format prefix)
+// Unlike json, avro binary encoding is not self-delimited, so the message must take the whole buffer.
+func makeChangeItemsFromMessageWithAvro(
+	inAvroBuilder *avroBuilder,
+	schema *confluent.Schema,
+	refs map[string]confluent.Schema,
+	buf []byte,
+	offset uint64,
+	writeTime time.Time,
+	isGenerateUpdates bool,
+) ([]abstract.ChangeItem, error) {
+	avroSchema, err := inAvroBuilder.toSchema(schema, refs)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to build avro schema, err: %w", err)
+	}
+
+	decoded, msgLen, err := avro.Decode(avroSchema, buf)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to decode avro message, err: %w", err)
+	}
+	// avro messages are not delimited, so they are never concatenated, see warmup.WarmUpSRCache
+	if msgLen != len(buf) {
+		return nil, xerrors.Errorf("avro message takes %d bytes, but %d bytes are left after it", msgLen, len(buf)-msgLen)
+	}
+	record, ok := decoded.(map[string]any)
+	if !ok {
+		return nil, xerrors.Errorf("decoded avro record has unexpected type: %T", decoded)
+	}
+
+	// record 'com.example.users' becomes table 'users' in schema 'com.example'
+	schemaName := avroSchema.Namespace()
+	tableName := avroSchema.ShortName()
+
+	tableColumns, names, values := unpackAvroRecord(schemaName, tableName, avroSchema, record)
+	kind := abstract.InsertKind
+	if isGenerateUpdates {
+		kind = abstract.UpdateKind
+	}
+	changeItem := abstract.ChangeItem{
+		ID:               0,
+		LSN:              offset,
+		CommitTime:       uint64(writeTime.UnixNano()),
+		Counter:          0,
+		Kind:             kind,
+		Schema:           schemaName,
+		Table:            tableName,
+		PartID:           "",
+		ColumnNames:      names,
+		ColumnValues:     values,
+		TableSchema:      tableColumns,
+		OldKeys:          abstract.OldKeysType{KeyNames: nil, KeyTypes: nil, KeyValues: nil},
+		Size:             abstract.RawEventSize(uint64(msgLen)),
+		TxID:             "",
+		Query:            "",
+		QueueMessageMeta: changeitem.QueueMessageMeta{TopicName: "", PartitionNum: 0, Offset: 0, Index: 0},
+	}
+	return []abstract.ChangeItem{changeItem}, nil
+}
+
+func unpackAvroRecord(schemaName, tableName string, avroSchema *avro.Schema, record map[string]any) (*abstract.TableSchema, []string, []interface{}) {
+	colSchema := make([]abstract.ColSchema, 0, len(avroSchema.Fields))
+	names := make([]string, 0, len(avroSchema.Fields))
+	values := make([]interface{}, 0, len(avroSchema.Fields))
+	for _, field := range avroSchema.Fields {
+		colType, isRequired := avroToYtType(field.Type)
+		colSchema = append(colSchema, abstract.ColSchema{
+			TableSchema:  schemaName,
+			TableName:    tableName,
+			Path:         "",
+			ColumnName:   field.Name,
+			DataType:     colType.String(),
+			PrimaryKey:   false,
+			FakeKey:      false,
+			Required:     isRequired,
+			Expression:   "",
+			OriginalType: "",
+			Properties:   nil,
+		})
+		names = append(names, field.Name)
+		values = append(values, record[field.Name])
+	}
+	return abstract.NewTableSchema(colSchema), names, values
+}
@@ -19,6 +19,10 @@ import (
 	"go.ytsaurus.tech/library/go/core/log"
 )
 
+type schemaRegistryClientGetter interface {
+	SchemaRegistryClient() *confluent.SchemaRegistryClient
+}
+
 type DebeziumImpl struct {
 	logger           log.Logger
 	debeziumReceiver *debezium.Receiver
@@ -31,9 +35,14 @@ type DebeziumImpl struct {
 // Contains multiple debezium events only if messages are
 // serialized using schema registry and started with magic zero-byte
 func (p *DebeziumImpl) DoOne(partition abstract.Partition, buf []byte, offset uint64, writeTime time.Time) ([]byte, abstract.ChangeItem) {
+	return p.doOne(partition, nil, buf, offset, writeTime)
+}
+
+// doOne - the key of the message is passed to the receiver, some events carry the document key only there
+func (p *DebeziumImpl) doOne(partition abstract.Partition, key, buf []byte, offset uint64, writeTime time.Time) ([]byte, abstract.ChangeItem) {
 	msgLen := len(buf)
 	if len(buf) != 0 {
-		if buf[0] == 0 {
+		if buf[0] == 0 && !p.isAvroMessage(buf) {
 			zeroIndex := bytes.Index(buf[5:], []byte{0})
 			if zeroIndex != -1 {
 				msgLen = 5 + zeroIndex
@@ -41,7 +50,7 @@ func (p *DebeziumImpl) DoOne(partition abstract.Partition, buf []byte, offset ui
 		}
 	}
 
-	changeItem, err := p.debeziumReceiver.Receive(string(buf[0:msgLen]))
+	changeItem, err := p.debeziumReceiver.ReceiveWithKey(key, string(buf[0:msgLen]))
 	if err != nil {
 		var rawData string
 		if len(buf) > 5 && buf[0] == 0 {
@@ -56,7 +65,22 @@ func (p *DebeziumImpl) DoOne(partition abstract.Partition, buf []byte, offset ui
 	return buf[msgLen:], *changeItem
 }
 
+// isAvroMessage checks if message is serialized by 'io.confluent.connect.avro.AvroConverter'
+// Avro payload is binary and can contain zero bytes, so such messages are never batched
+func (p *DebeziumImpl) isAvroMessage(buf []byte) bool {
+	sr, ok := p.debeziumReceiver.Unpacker.(schemaRegistryClientGetter)
+	if !ok || len(buf) < 5 {
+		return false
+	}
+	schema, err := sr.SchemaRegistryClient().GetSchema(int(binary.BigEndian.Uint32(buf[1:5])))
+	return err == nil && schema.SchemaType == confluent.AVRO
+}
+
 func (p *DebeziumImpl) DoBuf(partition abstract.Partition, buf []byte, offset uint64, writeTime time.Time) []abstract.ChangeItem {
+	return p.doBuf(partition, nil, buf, offset, writeTime)
+}
+
+func (p *DebeziumImpl) doBuf(partition abstract.Partition, key, buf []byte, offset uint64, writeTime time.Time) []abstract.ChangeItem {
 	result := make([]abstract.ChangeItem, 0, 1)
 	leastBuf := buf
 	for {
@@ -64,7 +88,7 @@ func (p *DebeziumImpl) DoBuf(partition abstract.Partition, buf []byte, offset ui
 			break
 		}
 		var changeItem abstract.ChangeItem
-		leastBuf, changeItem = p.DoOne(partition, leastBuf, offset, writeTime)
+		leastBuf, changeItem = p.doOne(partition, key, leastBuf, offset, writeTime)
 		result = append(result, changeItem)
 	}
 	return result
@@ -96,7 +120,7 @@ func (p *DebeziumImpl) doMultiThread(batch parsers.MessageBatch) []abstract.Chan
 }
 
 func (p *DebeziumImpl) Do(msg parsers.Message, partition abstract.Partition) []abstract.ChangeItem {
-	result := p.DoBuf(partition, msg.Value, msg.Offset, msg.WriteTime)
+	result := p.doBuf(partition, msg.Key, msg.Value, msg.Offset, msg.WriteTime)
 	for i := range result {
 		result[i].FillQueueMessageMeta(partition.Topic, int(partition.Partition), msg.Offset, i)
 	}
@@ -105,12 +129,8 @@ func (p *DebeziumImpl) Do(msg parsers.Message, partition abstract.Partition) []a
 
 // It's important to warn-up Schema-Registry cache single-thread, to not to DDoS Schema-Registry
 func (p *DebeziumImpl) warmUpSRCache(batch parsers.MessageBatch) {
-	type SRClient interface {
-		SchemaRegistryClient() *confluent.SchemaRegistryClient
-	}
-
 	var schemaRegistryClient *confluent.SchemaRegistryClient
-	if sr, ok := p.debeziumReceiver.Unpacker.(SRClient); ok {
+	if sr, ok := p.debeziumReceiver.Unpacker.(schemaRegistryClientGetter); ok {
 		schemaRegistryClient = sr.SchemaRegistryClient()
 	} else {
 		return
@@ -12,6 +12,7 @@ import (
 	"github.com/transferia/transferia/library/go/test/canon"
 	"github.com/transferia/transferia/pkg/abstract"
 	"github.com/transferia/transferia/pkg/parsers"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var rawLines []string
@@ -88,3 +89,15 @@ func TestMultiThreading(t *testing.T) {
 	changeItemsMultiThread := parserMultiThread.DoBatch(batch)
 	require.Equal(t, changeItemsSingleThread, changeItemsMultiThread)
 }
+
+func TestMongoDeleteWithKey(t *testing.T) {
+	parser := NewDebeziumImpl(logger.Log, nil, 1)
+	msg := makePersqueueReadMessage(0, `{"schema":{"type":"struct","fields":[{"type":"struct","fields":[],"optional":false,"name":"io.debezium.connector.mongo.Source","field":"source"}],"optional":false,"name":"fulfillment.inventory.customers.Envelope"},"payload":{"before":null,"after":null,"updateDescription":null,"source":{"db":"inventory","collection":"customers","ord":1,"ts_ms":1700000000000},"op":"d"}}`)
+	msg.Key = []byte(`{"schema":{"type":"struct","fields":[{"type":"string","optional":false,"field":"id"}],"optional":false,"name":"fulfillment.inventory.customers.Key"},"payload":{"id":"{\"$oid\": \"596e275826f08b2730779e1f\"}"}}`)
+	result := parser.Do(msg, abstract.Partition{Cluster: "", Partition: 0, Topic: "my-topic-name"})
+	require.Len(t, result, 1)
+	require.Equal(t, abstract.DeleteKind, result[0].Kind)
+	require.Equal(t, "customers", result[0].Table)
+	require.Len(t, result[0].OldKeys.KeyValues, 1)
+	require.Equal(t, "596e275826f08b2730779e1f", result[0].OldKeys.KeyValues[0].(primitive.ObjectID).Hex())
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/transferia/transferia/pkg/abstract"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/async/model/db"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/columntypes"
+	"github.com/transferia/transferia/pkg/providers/clickhouse/commits"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/schema/engines"
 	"go.ytsaurus.tech/library/go/core/log"
 )
@@ -95,6 +96,14 @@ func (d *DDLDAO) CreateTable(db, table string, schema []abstract.ColSchema) erro
 	})
 }
 
+// CreateCommitsTable creates the service table of ExactlyOnce mode in the database
+func (d *DDLDAO) CreateCommitsTable(db string) error {
+	d.lgr.Infof("Creating table %s.%s", db, commits.TableName)
+	return d.db.ExecDDL(func(distributed bool, cluster string) (string, error) {
+		return commits.CreateTableDDL(db, distributed, cluster), nil
+	})
+}
+
 // DDLDAO is universal mechanism, which can be used over cluster/shard/host
 //
 // Let's me remind you - 'pkg/clickhouse/async' mechanism works with temporary tables in clickhouse.
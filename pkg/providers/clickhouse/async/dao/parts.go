@@ -8,6 +8,8 @@ import (
 	"github.com/cenkalti/backoff/v4"
 	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/async/model/db"
+	"github.com/transferia/transferia/pkg/providers/clickhouse/commits"
+	"github.com/transferia/transferia/pkg/util/positions"
 	"go.ytsaurus.tech/library/go/core/log"
 )
 
@@ -26,25 +28,65 @@ func (d *PartsDAO) AttachTablePartsTo(dstDB, dstTable, srcDB, srcTable string) e
 		log.String("table", srcTable), log.Strings("partitions", partitions))
 
 	for _, p := range partitions {
-		q := fmt.Sprintf(`ALTER TABLE "%s"."%s" ATTACH PARTITION ID '%s' FROM "%s"."%s"`,
-			dstDB, dstTable, p, srcDB, srcTable)
-		d.lgr.Info(fmt.Sprintf("Attaching partition %s", p), log.String("sql", q))
+		if err := d.attachPartition(dstDB, dstTable, srcDB, srcTable, p); err != nil {
+			return xerrors.Errorf("error attaching table partition: %w", err)
+		}
+	}
+	return nil
+}
+
+// AttachTablePartsOnce attaches partitions like AttachTablePartsTo, but skips partitions of the part
+// which are committed to the journal by previous attempts, so the retry of the merge does not duplicate data
+func (d *PartsDAO) AttachTablePartsOnce(dstDB, dstTable, srcDB, srcTable string, journal commits.Journal, partKey string) error {
+	d.lgr.Infof("Attaching partitions from %s.%s to %s.%s once", srcDB, srcTable, dstDB, dstTable)
+	partitions, err := d.getPartitionList(srcDB, srcTable)
+	if err != nil {
+		return xerrors.Errorf("error getting table %s partitions: %w", srcTable, err)
+	}
+	attached, _, err := journal.Load(context.Background(), dstTable)
+	if err != nil {
+		return xerrors.Errorf("error loading attached partitions of table %s: %w", dstTable, err)
+	}
 
-		err := backoff.RetryNotify(
-			func() error {
-				_, err := d.db.ExecContext(context.Background(), q)
-				return err
-			},
-			backoff.NewExponentialBackOff(backoff.WithMaxElapsedTime(10*time.Minute)),
-			func(err error, dur time.Duration) {
-				d.lgr.Error(fmt.Sprintf("Got Attach Partition error, retrying after %v", dur), log.Error(err))
-			},
-		)
-		if err != nil {
+	for _, p := range partitions {
+		stream := attachedPartitionStream(partKey, p)
+		if _, ok := attached[stream]; ok {
+			d.lgr.Info(fmt.Sprintf("Partition %s is already attached, skip it", p), log.String("part", partKey))
+			continue
+		}
+		if err := d.attachPartition(dstDB, dstTable, srcDB, srcTable, p); err != nil {
 			return xerrors.Errorf("error attaching table partition: %w", err)
 		}
-		d.lgr.Info(fmt.Sprintf("Attached partition %s", p), log.String("sql", q))
+		if err := journal.Store(context.Background(), dstTable, positions.Watermarks{stream: {Offset: 1, Index: 0}}); err != nil {
+			return xerrors.Errorf("error committing attached partition %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+func attachedPartitionStream(partKey, partitionID string) string {
+	return fmt.Sprintf("%s%s:%s", commits.PartSourcePrefix, partKey, partitionID)
+}
+
+func (d *PartsDAO) attachPartition(dstDB, dstTable, srcDB, srcTable, partitionID string) error {
+	q := fmt.Sprintf(`ALTER TABLE "%s"."%s" ATTACH PARTITION ID '%s' FROM "%s"."%s"`,
+		dstDB, dstTable, partitionID, srcDB, srcTable)
+	d.lgr.Info(fmt.Sprintf("Attaching partition %s", partitionID), log.String("sql", q))
+
+	err := backoff.RetryNotify(
+		func() error {
+			_, err := d.db.ExecContext(context.Background(), q)
+			return err
+		},
+		backoff.NewExponentialBackOff(backoff.WithMaxElapsedTime(10*time.Minute)),
+		func(err error, dur time.Duration) {
+			d.lgr.Error(fmt.Sprintf("Got Attach Partition error, retrying after %v", dur), log.Error(err))
+		},
+	)
+	if err != nil {
+		return err
 	}
+	d.lgr.Info(fmt.Sprintf("Attached partition %s", partitionID), log.String("sql", q))
 	return nil
 }
 
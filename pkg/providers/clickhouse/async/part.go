@@ -12,6 +12,7 @@ import (
 	"github.com/transferia/transferia/pkg/providers/clickhouse/async/dao"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/async/model/parts"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/columntypes"
+	"github.com/transferia/transferia/pkg/providers/clickhouse/commits"
 	chsink "github.com/transferia/transferia/pkg/providers/clickhouse/errors"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/sharding"
 	"go.ytsaurus.tech/library/go/core/log"
@@ -30,6 +31,7 @@ type part struct {
 	transferID  string
 	tableCols   map[abstract.TableID]columntypes.TypeMapping
 	tableColsMu sync.Mutex
+	exactlyOnce bool
 }
 
 const TMPPrefix = "dt_tmp_part"
@@ -92,6 +94,11 @@ func (p *part) Commit() error {
 	// Merge tmp tables to dst since all of them are ready.
 	for shardID, shard := range p.shards {
 		if err := shard.Merge(); err != nil {
+			if p.exactlyOnce {
+				// Attached partitions are committed, so the retry of the part skips them.
+				return xerrors.Errorf("error merging part %s of table %s for shard %d: %w",
+					p.id.PartID, p.id.Name, shardID, err)
+			}
 			// Error on Merge() marks that tmp table was not attached (merged) with dst.
 			// Since now, retry of part could cause data duplication, because some of data was
 			// already merged with dst in previous iterations of that cycle.
@@ -197,8 +204,14 @@ func (p *part) createShardPart(shardID sharding.ShardID) (*shardPart, error) {
 		return nil, xerrors.Errorf("error getting table cols for table '%s': %w", p.id.Name, err)
 	}
 
+	var journal commits.Journal
+	if p.exactlyOnce {
+		journal = commits.NewJournal(hostDB, p.dbName, p.transferID)
+	}
+	partKey := fmt.Sprintf("%s/%d", p.id.PartID, shardID)
+
 	shardLgr := log.With(p.lgr, log.String("shardID", fmt.Sprint(shardID)))
-	shardPart, err := newShardPart(shardLgr, p.dbName, p.id.Name, p.dbName, p.tmpTableName(), p.query, hostDB, cols)
+	shardPart, err := newShardPart(shardLgr, p.dbName, p.id.Name, p.dbName, p.tmpTableName(), p.query, hostDB, cols, journal, partKey)
 	if err != nil {
 		return nil, xerrors.Errorf("error making new part for shard %d: %w", shardID, err)
 	}
@@ -216,7 +229,7 @@ func (p *part) shardAppend(shardID sharding.ShardID, row abstract.ChangeItem) er
 
 func NewPart(
 	partID abstract.TablePartID, dbName string, cl ClusterClient, dao *dao.DDLDAO,
-	sharder sharding.Sharder, lgr log.Logger, transferID string,
+	sharder sharding.Sharder, lgr log.Logger, transferID string, exactlyOnce bool,
 ) parts.Part {
 	return &part{
 		cluster:     cl,
@@ -231,5 +244,6 @@ func NewPart(
 		transferID:  transferID,
 		tableCols:   make(map[abstract.TableID]columntypes.TypeMapping),
 		tableColsMu: sync.Mutex{},
+		exactlyOnce: exactlyOnce,
 	}
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/transferia/transferia/pkg/providers/clickhouse/async/dao"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/async/model/db"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/columntypes"
+	"github.com/transferia/transferia/pkg/providers/clickhouse/commits"
 	"github.com/transferia/transferia/pkg/util"
 	"go.ytsaurus.tech/library/go/core/log"
 )
@@ -31,6 +32,9 @@ type shardPart struct {
 	marshaller db.ChangeItemMarshaller
 	closeOnce  sync.Once
 	cols       columntypes.TypeMapping
+	// journal is nil unless ExactlyOnce mode is enabled
+	journal commits.Journal
+	partKey string
 }
 
 func (s *shardPart) initQueryWMarshaller(row abstract.ChangeItem) error {
@@ -78,6 +82,12 @@ func (s *shardPart) Merge() error {
 			s.lgr.Error("error closing shardPart", log.Error(err))
 		}
 	}()
+	if s.journal != nil {
+		if err := s.partsDao.AttachTablePartsOnce(s.baseDB, s.baseTable, s.tmpDB, s.tmpTable, s.journal, s.partKey); err != nil {
+			return xerrors.Errorf("error attaching parts from tmp table: %w", err)
+		}
+		return s.dao.DropTable(s.tmpDB, s.tmpTable)
+	}
 	if err := s.partsDao.AttachTablePartsTo(s.baseDB, s.baseTable, s.tmpDB, s.tmpTable); err != nil {
 		return xerrors.Errorf("error attaching parts from tmp table: %w", err)
 	}
@@ -105,6 +115,7 @@ func (s *shardPart) Close() error {
 
 func newShardPart(
 	lgr log.Logger, baseDB, baseTable, tmpDB, tmpTable, query string, hostDB DDLStreamingClient, cols columntypes.TypeMapping,
+	journal commits.Journal, partKey string,
 ) (*shardPart, error) {
 	ddldao := dao.NewDDLDAO(hostDB, lgr)
 	if err := ddldao.DropTable(tmpDB, tmpTable); err != nil {
@@ -127,5 +138,7 @@ func newShardPart(
 		marshaller: nil,
 		closeOnce:  sync.Once{},
 		cols:       cols,
+		journal:    journal,
+		partKey:    partKey,
 	}, nil
 }
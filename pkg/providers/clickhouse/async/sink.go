@@ -1,6 +1,8 @@
 package async
 
 import (
+	"context"
+
 	"github.com/transferia/transferia/library/go/core/metrics"
 	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/library/go/core/xerrors/multierr"
@@ -9,6 +11,7 @@ import (
 	"github.com/transferia/transferia/pkg/providers/clickhouse/async/dao"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/async/model/db"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/async/model/parts"
+	"github.com/transferia/transferia/pkg/providers/clickhouse/commits"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/errors"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/model"
 	sharding "github.com/transferia/transferia/pkg/providers/clickhouse/sharding"
@@ -118,7 +121,27 @@ func (s *sink) cleanup(head abstract.ChangeItem) error {
 	default:
 		return xerrors.Errorf("unknown kind of cleanup event %s", head.Kind)
 	}
-	return fn(s.cfg.Database(), head.Table)
+	if err := fn(s.cfg.Database(), head.Table); err != nil {
+		return err
+	}
+	if s.cfg.ExactlyOnce() {
+		return s.forgetCommits(head.Table)
+	}
+	return nil
+}
+
+// forgetCommits drops attached partitions of the table from journals of all shards
+func (s *sink) forgetCommits(table string) error {
+	if err := s.dao.CreateCommitsTable(s.cfg.Database()); err != nil {
+		return xerrors.Errorf("unable to create commits table: %w", err)
+	}
+	for _, shardID := range s.cl.Shards() {
+		journal := commits.NewJournal(s.cl.Shard(shardID), s.cfg.Database(), s.transferID)
+		if err := journal.Forget(context.Background(), table); err != nil {
+			return xerrors.Errorf("unable to forget commits of table %s on shard %d: %w", table, shardID, err)
+		}
+	}
+	return nil
 }
 
 func (s *sink) createPart(partID abstract.TablePartID) error {
@@ -127,7 +150,7 @@ func (s *sink) createPart(partID abstract.TablePartID) error {
 		return xerrors.Errorf("part %s of table %s already exists", partID.PartID, partID.Fqtn())
 	}
 	s.lgr.Infof("Adding part %s for table %s", partID.PartID, tableID.Name)
-	prt := NewPart(partID, s.cfg.Database(), s.cl, s.dao, s.sharder, s.lgr, s.transferID)
+	prt := NewPart(partID, s.cfg.Database(), s.cl, s.dao, s.sharder, s.lgr, s.transferID, s.cfg.ExactlyOnce())
 	s.parts.Add(partID, prt)
 	return nil
 }
@@ -190,6 +213,11 @@ func (s *sink) ensureTargetTable(item abstract.ChangeItem) error {
 	if err != nil {
 		return xerrors.Errorf("error checking for table %s.%s existance: %w", s.cfg.Database(), item.Table, err)
 	}
+	if s.cfg.ExactlyOnce() {
+		if err := s.dao.CreateCommitsTable(s.cfg.Database()); err != nil {
+			return xerrors.Errorf("error creating commits table: %w", err)
+		}
+	}
 	if exists {
 		s.lgr.Infof("Table %s.%s already exists, skip creating it", s.cfg.Database(), item.Table)
 		return nil
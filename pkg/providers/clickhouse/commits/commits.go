@@ -0,0 +1,239 @@
+// Package commits
+//
+// Exactly-once delivery to ClickHouse is built of three parts:
+//   - every insert carries 'insert_deduplication_token' derived from source positions of its rows,
+//     so the retry of the same batch is deduplicated by ClickHouse itself;
+//   - the last committed source position of every table is stored in the service table '__data_transfer_commits',
+//     so rows redelivered after restart of a worker are skipped before the insert;
+//   - the intent of the insert is stored before it, so the insert interrupted before the commit of positions
+//     is replayed after restart with the same token.
+//
+// The replay is exact only if the source redelivers the same batch. If the batch is recut and holds only a part
+// of rows of the intent, the part is replayed with the token of the intent and the rest of rows is written later
+// as new rows, so they may be duplicated if the interrupted insert has reached the table.
+package commits
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/util/positions"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const (
+	TableName = "__data_transfer_commits"
+
+	// DeduplicationWindow is a value of 'non_replicated_deduplication_window' of non-replicated tables
+	DeduplicationWindow = 1000
+)
+
+// DeduplicationToken identifies the batch by ranges of source positions of its rows.
+// Empty token is returned if any row has no position, such batches are not deduplicated.
+func DeduplicationToken(transferID, table string, items []abstract.ChangeItem) string {
+	first := positions.Watermarks{}
+	last := positions.Watermarks{}
+	for i := range items {
+		stream, position, ok := positions.RowPosition(&items[i])
+		if !ok {
+			return ""
+		}
+		if current, found := first[stream]; !found || position.Less(current) {
+			first[stream] = position
+		}
+		if current, found := last[stream]; !found || current.Less(position) {
+			last[stream] = position
+		}
+	}
+	if len(first) == 0 {
+		return ""
+	}
+	streams := make([]string, 0, len(first))
+	for stream := range first {
+		streams = append(streams, stream)
+	}
+	sort.Strings(streams)
+	ranges := make([]string, 0, len(streams))
+	for _, stream := range streams {
+		ranges = append(ranges, fmt.Sprintf("%s@%s-%s", stream, first[stream], last[stream]))
+	}
+	hash := sha256.Sum256([]byte(strings.Join(ranges, ";")))
+	return fmt.Sprintf("%s:%s:%d:%s", transferID, table, len(items), hex.EncodeToString(hash[:16]))
+}
+
+// Intent is the insert which is started but not committed yet.
+// It's stored before the insert, so the worker restarted after the crash replays exactly the same block of rows
+// with the same token, and ClickHouse deduplicates it if the first attempt has reached the table.
+type Intent struct {
+	Token string               `json:"token"`
+	Last  positions.Watermarks `json:"last"`
+	Rows  int                  `json:"rows"`
+}
+
+// Covers reports whether the row is in the block of the intent, rows before watermarks must be filtered out in advance
+func (i *Intent) Covers(row *abstract.ChangeItem) bool {
+	stream, position, ok := positions.RowPosition(row)
+	if !ok {
+		return false
+	}
+	last, found := i.Last[stream]
+	return found && !last.Less(position)
+}
+
+// Journal stores watermarks and intents of tables
+type Journal interface {
+	Load(ctx context.Context, table string) (positions.Watermarks, *Intent, error)
+	// Prepare stores the intent of the insert
+	Prepare(ctx context.Context, table string, intent Intent) error
+	// Store commits positions of streams and resolves the intent of the table at once
+	Store(ctx context.Context, table string, watermarks positions.Watermarks) error
+	Forget(ctx context.Context, table string) error
+}
+
+// InsertFunc inserts rows with the deduplication token, empty token disables deduplication
+type InsertFunc func(items []abstract.ChangeItem, token string) error
+
+// tableState is guarded by its own mutex, so batches of different tables are written concurrently
+type tableState struct {
+	mu         sync.Mutex
+	loaded     bool
+	watermarks positions.Watermarks
+	intent     *Intent
+}
+
+// Log applies batches exactly once, it caches watermarks of tables between batches
+type Log struct {
+	transferID string
+	logger     log.Logger
+	mu         sync.Mutex
+	tables     map[string]*tableState
+}
+
+func (l *Log) table(table string) *tableState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.tables[table]
+	if !ok {
+		state = &tableState{mu: sync.Mutex{}, loaded: false, watermarks: nil, intent: nil}
+		l.tables[table] = state
+	}
+	return state
+}
+
+// Write skips already committed rows, inserts the others and commits their positions.
+// Failed writes reset the cached state of the table, so it's reloaded from the journal on retry.
+func (l *Log) Write(ctx context.Context, journal Journal, table string, items []abstract.ChangeItem, insert InsertFunc) error {
+	state := l.table(table)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if !state.loaded {
+		watermarks, intent, err := journal.Load(ctx, table)
+		if err != nil {
+			return xerrors.Errorf("unable to load commits of table %s: %w", table, err)
+		}
+		state.watermarks = watermarks
+		state.intent = intent
+		state.loaded = true
+	}
+	if err := l.write(ctx, journal, table, state, items, insert); err != nil {
+		state.loaded = false
+		return err
+	}
+	return nil
+}
+
+func (l *Log) write(ctx context.Context, journal Journal, table string, state *tableState, items []abstract.ChangeItem, insert InsertFunc) error {
+	pending := state.watermarks.Filter(items)
+	if state.intent != nil && len(pending) > 0 {
+		replay := make([]abstract.ChangeItem, 0, state.intent.Rows)
+		rest := make([]abstract.ChangeItem, 0, len(pending))
+		for i := range pending {
+			if state.intent.Covers(&pending[i]) {
+				replay = append(replay, pending[i])
+			} else {
+				rest = append(rest, pending[i])
+			}
+		}
+		if len(replay) < state.intent.Rows {
+			l.logger.Warn(
+				"batch contains only a part of rows of the uncommitted insert, the rest of rows may be duplicated",
+				log.String("table", table), log.Int("replayed", len(replay)), log.Int("rows", state.intent.Rows),
+			)
+		}
+		if len(replay) > 0 {
+			if err := l.commit(ctx, journal, table, state, replay, state.intent.Token, insert); err != nil {
+				return xerrors.Errorf("unable to replay uncommitted insert: %w", err)
+			}
+		}
+		pending = rest
+	}
+
+	if len(pending) > 0 {
+		token := DeduplicationToken(l.transferID, table, pending)
+		if token != "" {
+			intent := Intent{Token: token, Last: positions.Advance(pending), Rows: len(pending)}
+			if err := journal.Prepare(ctx, table, intent); err != nil {
+				return xerrors.Errorf("unable to store intent of table %s: %w", table, err)
+			}
+		}
+		if err := l.commit(ctx, journal, table, state, pending, token, insert); err != nil {
+			return xerrors.Errorf("unable to insert rows: %w", err)
+		}
+	}
+	return nil
+}
+
+// commit inserts rows and advances watermarks of the state
+func (l *Log) commit(ctx context.Context, journal Journal, table string, state *tableState, items []abstract.ChangeItem, token string, insert InsertFunc) error {
+	if err := insert(items, token); err != nil {
+		return xerrors.Errorf("unable to insert %d rows: %w", len(items), err)
+	}
+	advanced := positions.Advance(items)
+	if len(advanced) > 0 {
+		if err := journal.Store(ctx, table, advanced); err != nil {
+			return xerrors.Errorf("unable to commit positions of table %s: %w", table, err)
+		}
+	}
+
+	next := make(positions.Watermarks, len(state.watermarks)+len(advanced))
+	for stream, position := range state.watermarks {
+		next[stream] = position
+	}
+	for stream, position := range advanced {
+		next[stream] = position
+	}
+	state.watermarks = next
+	state.intent = nil
+	return nil
+}
+
+// Forget drops watermarks of the table, it's called when the table is truncated or dropped
+func (l *Log) Forget(ctx context.Context, journal Journal, table string) error {
+	state := l.table(table)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.loaded = false
+	if err := journal.Forget(ctx, table); err != nil {
+		return xerrors.Errorf("unable to forget commits of table %s: %w", table, err)
+	}
+	return nil
+}
+
+func NewLog(transferID string, logger log.Logger) *Log {
+	return &Log{
+		transferID: transferID,
+		logger:     logger,
+		mu:         sync.Mutex{},
+		tables:     map[string]*tableState{},
+	}
+}
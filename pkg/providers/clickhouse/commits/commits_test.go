@@ -0,0 +1,277 @@
+package commits
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/util/positions"
+)
+
+func queueRow(partition int, offset uint64, id int) abstract.ChangeItem {
+	row := abstract.ChangeItem{
+		Kind:         abstract.InsertKind,
+		Table:        "t",
+		ColumnNames:  []string{"id"},
+		ColumnValues: []any{id},
+	}
+	row.FillQueueMessageMeta("topic", partition, offset, 0)
+	return row
+}
+
+func TestDeduplicationToken(t *testing.T) {
+	items := []abstract.ChangeItem{queueRow(0, 1, 1), queueRow(0, 2, 2), queueRow(1, 5, 3)}
+	token := DeduplicationToken("dtt", "t", items)
+	require.NotEmpty(t, token)
+	require.Equal(t, token, DeduplicationToken("dtt", "t", []abstract.ChangeItem{items[2], items[1], items[0]}))
+	require.NotEqual(t, token, DeduplicationToken("dtt", "t", items[:2]))
+	require.NotEqual(t, token, DeduplicationToken("dtt", "other", items))
+
+	require.Empty(t, DeduplicationToken("dtt", "t", append(items, abstract.ChangeItem{Kind: abstract.InsertKind})))
+	require.Empty(t, DeduplicationToken("dtt", "t", nil))
+}
+
+var errCrash = xerrors.New("crash")
+
+type memoryJournal struct {
+	mu      sync.Mutex
+	commits map[string]positions.Watermarks
+	intents map[string]Intent
+}
+
+func (j *memoryJournal) Load(_ context.Context, table string) (positions.Watermarks, *Intent, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	result := positions.Watermarks{}
+	for stream, position := range j.commits[table] {
+		result[stream] = position
+	}
+	if intent, ok := j.intents[table]; ok {
+		return result, &intent, nil
+	}
+	return result, nil, nil
+}
+
+func (j *memoryJournal) Prepare(_ context.Context, table string, intent Intent) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.intents[table] = intent
+	return nil
+}
+
+func (j *memoryJournal) Store(_ context.Context, table string, watermarks positions.Watermarks) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.commits[table] == nil {
+		j.commits[table] = positions.Watermarks{}
+	}
+	for stream, position := range watermarks {
+		j.commits[table][stream] = position
+	}
+	delete(j.intents, table)
+	return nil
+}
+
+func (j *memoryJournal) Forget(_ context.Context, table string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.commits, table)
+	delete(j.intents, table)
+	return nil
+}
+
+func newMemoryJournal() *memoryJournal {
+	return &memoryJournal{mu: sync.Mutex{}, commits: map[string]positions.Watermarks{}, intents: map[string]Intent{}}
+}
+
+// memoryTable deduplicates inserts by tokens like ClickHouse does
+type memoryTable struct {
+	tokens map[string]bool
+	rows   []int
+}
+
+func (m *memoryTable) insert(items []abstract.ChangeItem, token string) {
+	if token != "" {
+		if m.tokens[token] {
+			return
+		}
+		m.tokens[token] = true
+	}
+	for _, item := range items {
+		m.rows = append(m.rows, item.ColumnValues[0].(int))
+	}
+}
+
+// runChaos crashes the worker at random steps of the write and resumes it from the last acked offset,
+// redelivered rows are skipped by watermarks or replayed by intents. If recut is set, batches are cut
+// anew after every restart, otherwise the source redelivers the same batches.
+func runChaos(t *testing.T, recut bool) *memoryTable {
+	const (
+		partitions       = 3
+		rowsPerPartition = 500
+	)
+	rnd := rand.New(rand.NewSource(42))
+
+	source := make([][]abstract.ChangeItem, partitions)
+	id := 0
+	for p := 0; p < partitions; p++ {
+		for offset := 0; offset < rowsPerPartition; offset++ {
+			source[p] = append(source[p], queueRow(p, uint64(offset), id))
+			id++
+		}
+	}
+
+	journal := newMemoryJournal()
+	table := &memoryTable{tokens: map[string]bool{}, rows: nil}
+	acked := make([]int, partitions)
+	crashes := 0
+	// batch sizes of the source by the first acked offset of the batch
+	cuts := map[int]int{}
+
+	done := func() bool {
+		for p := range acked {
+			if acked[p] < rowsPerPartition {
+				return false
+			}
+		}
+		return true
+	}
+
+	for !done() {
+		// restart of the worker: the cache of watermarks is lost, the source is reread from acked offsets
+		log := NewLog("dtt", logger.Log)
+		read := append([]int{}, acked...)
+		for !done() {
+			batchSize, ok := cuts[read[0]]
+			if !ok || recut {
+				batchSize = 1 + rnd.Intn(40)
+				cuts[read[0]] = batchSize
+			}
+			var batch []abstract.ChangeItem
+			for p := range read {
+				end := read[p] + batchSize
+				if end > rowsPerPartition {
+					end = rowsPerPartition
+				}
+				batch = append(batch, source[p][read[p]:end]...)
+				read[p] = end
+			}
+
+			crashStep := rnd.Intn(8)
+			err := log.Write(context.Background(), journal, "t", batch, func(items []abstract.ChangeItem, token string) error {
+				if crashStep == 0 {
+					return errCrash
+				}
+				table.insert(items, token)
+				if crashStep == 1 {
+					return errCrash
+				}
+				return nil
+			})
+			if err == nil && crashStep == 2 {
+				err = errCrash
+			}
+			if err != nil {
+				require.ErrorIs(t, err, errCrash)
+				crashes++
+				break
+			}
+			copy(acked, read)
+		}
+	}
+
+	require.Greater(t, crashes, 0)
+	seen := map[int]bool{}
+	for _, row := range table.rows {
+		seen[row] = true
+	}
+	require.Len(t, seen, partitions*rowsPerPartition, "rows are lost")
+	return table
+}
+
+func TestExactlyOnceChaos(t *testing.T) {
+	table := runChaos(t, false)
+	seen := map[int]bool{}
+	for _, row := range table.rows {
+		require.False(t, seen[row], "row %d is duplicated", row)
+		seen[row] = true
+	}
+}
+
+// TestExactlyOnceChaosRecut checks that batches recut after restart are never stuck and never lose rows
+func TestExactlyOnceChaosRecut(t *testing.T) {
+	runChaos(t, true)
+}
+
+func TestPartialReplay(t *testing.T) {
+	journal := newMemoryJournal()
+	table := &memoryTable{tokens: map[string]bool{}, rows: nil}
+	batch := []abstract.ChangeItem{queueRow(0, 1, 1), queueRow(0, 2, 2), queueRow(0, 3, 3), queueRow(1, 1, 4)}
+
+	crashed := NewLog("dtt", logger.Log)
+	require.ErrorIs(t, crashed.Write(context.Background(), journal, "t", batch, func([]abstract.ChangeItem, string) error {
+		return errCrash
+	}), errCrash)
+
+	// the source redelivers the batch recut in two parts
+	log := NewLog("dtt", logger.Log)
+	insert := func(items []abstract.ChangeItem, token string) error {
+		table.insert(items, token)
+		return nil
+	}
+	require.NoError(t, log.Write(context.Background(), journal, "t", batch[:2], insert))
+	require.Equal(t, []int{1, 2}, table.rows)
+	_, intent, err := journal.Load(context.Background(), "t")
+	require.NoError(t, err)
+	require.Nil(t, intent)
+
+	require.NoError(t, log.Write(context.Background(), journal, "t", batch, insert))
+	require.Equal(t, []int{1, 2, 3, 4}, table.rows)
+}
+
+func TestWriteLocksPerTable(t *testing.T) {
+	journal := newMemoryJournal()
+	log := NewLog("dtt", logger.Log)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- log.Write(context.Background(), journal, "slow", []abstract.ChangeItem{queueRow(0, 1, 1)}, func([]abstract.ChangeItem, string) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	// the insert into the other table is not blocked by the slow one
+	require.NoError(t, log.Write(context.Background(), journal, "fast", []abstract.ChangeItem{queueRow(0, 1, 1)}, func([]abstract.ChangeItem, string) error {
+		return nil
+	}))
+	close(release)
+	require.NoError(t, <-done)
+}
+
+func TestForget(t *testing.T) {
+	journal := newMemoryJournal()
+	log := NewLog("dtt", logger.Log)
+	var inserted int
+	insert := func(items []abstract.ChangeItem, _ string) error {
+		inserted += len(items)
+		return nil
+	}
+	batch := []abstract.ChangeItem{queueRow(0, 1, 1), queueRow(0, 2, 2)}
+
+	require.NoError(t, log.Write(context.Background(), journal, "t", batch, insert))
+	require.NoError(t, log.Write(context.Background(), journal, "t", batch, insert))
+	require.Equal(t, 2, inserted)
+
+	require.NoError(t, log.Forget(context.Background(), journal, "t"))
+	require.NoError(t, log.Write(context.Background(), journal, "t", batch, insert))
+	require.Equal(t, 4, inserted)
+}
@@ -0,0 +1,148 @@
+package commits
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/util/positions"
+)
+
+const (
+	// intentSource is the source of the row of the table intent, empty intent resolves the previous one
+	intentSource = "__intent__"
+
+	// PartSourcePrefix is the prefix of sources of partitions attached by parts of async sinks
+	PartSourcePrefix = "part:"
+	// PartRetentionDays is the lifetime of commits of attached partitions, it exceeds any retry of the merge of a part
+	PartRetentionDays = 7
+)
+
+type Client interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// CreateTableDDL returns DDL of the service table of commits in the database
+func CreateTableDDL(database string, distributed bool, cluster string) string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s`.`%s`", database, TableName))
+	if distributed {
+		result.WriteString(fmt.Sprintf(" ON CLUSTER `%s`", cluster))
+	}
+	result.WriteString(" (`transfer_id` String, `table_name` String, `source` String, `offset` UInt64, `index` UInt64, `intent` String, `committed_at` DateTime64(6))")
+	if distributed {
+		result.WriteString(fmt.Sprintf(" ENGINE=ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/%s.%s', '{replica}', `committed_at`)", database, TableName))
+	} else {
+		result.WriteString(" ENGINE=ReplacingMergeTree(`committed_at`)")
+	}
+	result.WriteString(" ORDER BY (`transfer_id`, `table_name`, `source`)")
+	// every part adds its own sources, they are useless once the part is merged
+	result.WriteString(fmt.Sprintf(
+		" TTL toDateTime(`committed_at`) + INTERVAL %d DAY DELETE WHERE startsWith(`source`, '%s')",
+		PartRetentionDays, PartSourcePrefix,
+	))
+	return result.String()
+}
+
+// sqlJournal stores watermarks in the service table
+type sqlJournal struct {
+	client     Client
+	database   string
+	transferID string
+}
+
+func (j *sqlJournal) Load(ctx context.Context, table string) (positions.Watermarks, *Intent, error) {
+	q := fmt.Sprintf(
+		"SELECT `source`, `offset`, `index`, `intent` FROM `%s`.`%s` FINAL WHERE `transfer_id` = ? AND `table_name` = ?",
+		j.database, TableName,
+	)
+	rows, err := j.client.QueryContext(ctx, q, j.transferID, table)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("unable to query commits: %w", err)
+	}
+	defer rows.Close()
+	watermarks := positions.Watermarks{}
+	var intent *Intent
+	for rows.Next() {
+		var stream, rawIntent string
+		var position positions.Position
+		if err := rows.Scan(&stream, &position.Offset, &position.Index, &rawIntent); err != nil {
+			return nil, nil, xerrors.Errorf("unable to scan commit: %w", err)
+		}
+		if stream != intentSource {
+			watermarks[stream] = position
+			continue
+		}
+		if rawIntent == "" {
+			continue
+		}
+		intent = new(Intent)
+		if err := json.Unmarshal([]byte(rawIntent), intent); err != nil {
+			return nil, nil, xerrors.Errorf("unable to parse intent: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, xerrors.Errorf("unable to read commits: %w", err)
+	}
+	return watermarks, intent, nil
+}
+
+func (j *sqlJournal) Prepare(ctx context.Context, table string, intent Intent) error {
+	rawIntent, err := json.Marshal(intent)
+	if err != nil {
+		return xerrors.Errorf("unable to serialize intent: %w", err)
+	}
+	return j.insert(ctx, table, nil, string(rawIntent))
+}
+
+func (j *sqlJournal) Store(ctx context.Context, table string, watermarks positions.Watermarks) error {
+	return j.insert(ctx, table, watermarks, "")
+}
+
+// insert writes positions of streams and the intent of the table in one block, so they are committed atomically
+func (j *sqlJournal) insert(ctx context.Context, table string, watermarks positions.Watermarks, intent string) error {
+	values := make([]string, 0, len(watermarks)+1)
+	args := make([]any, 0, (len(watermarks)+1)*7)
+	committedAt := time.Now()
+	for stream, position := range watermarks {
+		values = append(values, "(?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, j.transferID, table, stream, position.Offset, position.Index, "", committedAt)
+	}
+	values = append(values, "(?, ?, ?, ?, ?, ?, ?)")
+	args = append(args, j.transferID, table, intentSource, uint64(0), uint64(0), intent, committedAt)
+	q := fmt.Sprintf(
+		"INSERT INTO `%s`.`%s` (`transfer_id`, `table_name`, `source`, `offset`, `index`, `intent`, `committed_at`) VALUES %s",
+		j.database, TableName, strings.Join(values, ", "),
+	)
+	if _, err := j.client.ExecContext(ctx, q, args...); err != nil {
+		return xerrors.Errorf("unable to insert commits: %w", err)
+	}
+	return nil
+}
+
+func (j *sqlJournal) Forget(ctx context.Context, table string) error {
+	q := fmt.Sprintf(
+		"ALTER TABLE `%s`.`%s` DELETE WHERE `transfer_id` = ? AND `table_name` = ?",
+		j.database, TableName,
+	)
+	// wait for the mutation on all replicas, otherwise rows of the table may be skipped by stale commits
+	mutationCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{"mutations_sync": 2}))
+	if _, err := j.client.ExecContext(mutationCtx, q, j.transferID, table); err != nil {
+		return xerrors.Errorf("unable to delete commits: %w", err)
+	}
+	return nil
+}
+
+func NewJournal(client Client, database, transferID string) Journal {
+	return &sqlJournal{
+		client:     client,
+		database:   database,
+		transferID: transferID,
+	}
+}
@@ -63,6 +63,7 @@ func UploadCIBatch(
 	batch []abstract.ChangeItem,
 	rules *MarshallingRules,
 	config model.ChSinkServerParams,
+	insertParams model.InsertParams,
 	table string,
 	avgRowSize int,
 	lgr log.Logger) (*UploadStats, error) {
@@ -77,7 +78,7 @@ func UploadCIBatch(
 		return nil, xerrors.Errorf("error getting HTTP client: %w", err)
 	}
 
-	q := newInsertQuery(config.InsertSettings(), config.Database(), table, len(batch), getPoolForTable(table))
+	q := newInsertQuery(insertParams, config.Database(), table, len(batch), getPoolForTable(table))
 	defer q.Close()
 	if err := marshalQuery(batch, rules, q, avgRowSize, uint64(runtime.GOMAXPROCS(0)*2)); err != nil {
 		return nil, xerrors.Errorf("error marshalling rows to JSON: %w", err)
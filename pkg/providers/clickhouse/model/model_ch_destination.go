@@ -69,6 +69,8 @@ type ChDestination struct {
 	SystemColumnsFirst      bool
 	IsUpdateable            bool
 	UpsertAbsentToastedRows bool
+	// ExactlyOnce deduplicates rows redelivered after retries and restarts of replication, see ChSinkServerParams
+	ExactlyOnce bool
 
 	// Insert settings
 	InsertParams InsertParams
@@ -102,6 +104,15 @@ type ChDestination struct {
 
 type InsertParams struct {
 	MaterializedViewsIgnoreErrors bool
+
+	// deduplicationToken is set by the sink for every insert in ExactlyOnce mode
+	deduplicationToken string
+}
+
+// WithDeduplicationToken returns settings of the insert which is deduplicated by the token
+func (p InsertParams) WithDeduplicationToken(token string) InsertParams {
+	p.deduplicationToken = token
+	return p
 }
 
 func (p InsertParams) AsQueryPart() string {
@@ -109,6 +120,9 @@ func (p InsertParams) AsQueryPart() string {
 	if p.MaterializedViewsIgnoreErrors {
 		settings = append(settings, "materialized_views_ignore_errors = '1'")
 	}
+	if p.deduplicationToken != "" {
+		settings = append(settings, fmt.Sprintf("insert_deduplication_token = '%s'", strings.ReplaceAll(p.deduplicationToken, "'", "\\'")))
+	}
 	if len(settings) > 0 {
 		return fmt.Sprintf("SETTINGS %s", strings.Join(settings, ","))
 	}
@@ -120,6 +134,9 @@ func (p InsertParams) ToQueryOption() clickhouse.QueryOption {
 	if p.MaterializedViewsIgnoreErrors {
 		settings["materialized_views_ignore_errors"] = "1"
 	}
+	if p.deduplicationToken != "" {
+		settings["insert_deduplication_token"] = p.deduplicationToken
+	}
 	return clickhouse.WithSettings(settings)
 }
 
@@ -229,6 +246,7 @@ func (d *ChDestination) shallUseJSON(transfer *model.Transfer) bool {
 func (d *ChDestination) ToSinkParams(transfer *model.Transfer) (ChDestinationWrapper, error) {
 	wrapper := newChDestinationWrapper(*d)
 	wrapper.useJSON = d.shallUseJSON(transfer)
+	wrapper.transferID = transfer.ID
 	connectionParams, err := ConnectionParamsFromDestination(d)
 	if err != nil {
 		return ChDestinationWrapper{}, xerrors.Errorf("unable to resolve connection params from destination: %w", err)
@@ -260,6 +278,7 @@ type ChDestinationWrapper struct {
 	hosts            []*chConn.Host
 	useJSON          bool // useJSON is calculated in runtime, not by the model
 	migrationOpts    ChSinkMigrationOptions
+	transferID       string
 }
 
 func (d ChDestinationWrapper) InsertSettings() InsertParams {
@@ -295,6 +314,7 @@ func newChDestinationWrapper(model ChDestination) *ChDestinationWrapper {
 		hosts:         make([]*chConn.Host, 0),
 		useJSON:       false,
 		migrationOpts: migrationOpts,
+		transferID:    "",
 	}
 }
 
@@ -364,6 +384,14 @@ func (d ChDestinationWrapper) UpsertAbsentToastedRows() bool {
 	return d.Model.UpsertAbsentToastedRows
 }
 
+func (d ChDestinationWrapper) ExactlyOnce() bool {
+	return d.Model.ExactlyOnce
+}
+
+func (d ChDestinationWrapper) TransferID() string {
+	return d.transferID
+}
+
 func (d ChDestinationWrapper) InferSchema() bool {
 	return d.Model.InferSchema
 }
@@ -449,6 +477,7 @@ func (d ChDestinationWrapper) MakeChildServerParams(host *chConn.Host) ChSinkSer
 		hosts:            d.hosts,
 		useJSON:          d.useJSON,
 		migrationOpts:    d.MigrationOptions(),
+		transferID:       d.transferID,
 	}
 	return newChDestinationWrapper
 }
@@ -462,6 +491,7 @@ func (d ChDestinationWrapper) MakeChildShardParams(altHosts []*chConn.Host) ChSi
 		hosts:            altHosts,
 		useJSON:          d.useJSON,
 		migrationOpts:    d.MigrationOptions(),
+		transferID:       d.transferID,
 	}
 	newChDestinationWrapper.connectionParams.Hosts = altHosts
 
@@ -56,6 +56,15 @@ type ChSource struct {
 	IOHomoFormat     ClickhouseIOFormat // one of - https://clickhouse.com/docs/en/interfaces/formats
 	RootCACertPaths  []string
 	ConnectionID     string
+
+	// CursorColumn is a monotonically increasing column used by replication, for example insertion time.
+	// If empty, MergeTree tables are replicated by block numbers of inserted data (`_block_number` or `_part`).
+	CursorColumn string
+	// PollInterval is a pause between replication polls, which found no new rows
+	PollInterval time.Duration
+	// BlockLag is the age of data parts, after which their blocks are replicated. Inserts may commit block numbers
+	// out of order, so rows of a block committed later than BlockLag after a newer one are not replicated.
+	BlockLag time.Duration
 }
 
 func (s *ChSource) Describe() model.Doc {
@@ -79,6 +88,12 @@ func (s *ChSource) WithDefaults() {
 	if s.BufferSize == 0 {
 		s.BufferSize = 50 * 1024 * 1024
 	}
+	if s.PollInterval == 0 {
+		s.PollInterval = 10 * time.Second
+	}
+	if s.BlockLag == 0 {
+		s.BlockLag = time.Minute
+	}
 }
 
 func (*ChSource) IsSource()                      {}
@@ -258,6 +273,14 @@ func (s ChSourceWrapper) UpsertAbsentToastedRows() bool {
 	return false
 }
 
+func (s ChSourceWrapper) ExactlyOnce() bool {
+	return false
+}
+
+func (s ChSourceWrapper) TransferID() string {
+	return ""
+}
+
 func (s ChSourceWrapper) InferSchema() bool {
 	return false
 }
@@ -56,6 +56,12 @@ type ChSinkServerParams interface {
 	//  1. YDB Source with 'Updates' changefeed mode
 	//  2. Any IncrementOnly transfer in ClickHouse which can bring update for inexistent document (for instance PG->CH)
 	UpsertAbsentToastedRows() bool
+	// ExactlyOnce
+	// deduplicates rows redelivered after retries and restarts by their source positions,
+	// positions are committed to the '__data_transfer_commits' service table of the database
+	ExactlyOnce() bool
+	// TransferID scopes commits of ExactlyOnce mode
+	TransferID() string
 	InferSchema() bool // If table exists - get it schema
 	// MigrationOptions
 	// Sink table modification settings
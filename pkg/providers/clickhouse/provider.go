@@ -41,6 +41,7 @@ const ProviderType = abstract.ProviderType("ch")
 // To verify providers contract implementation
 var (
 	_ providers.Snapshot          = (*Provider)(nil)
+	_ providers.Replication       = (*Provider)(nil)
 	_ providers.Abstract2Provider = (*Provider)(nil)
 	_ providers.AsyncSinker       = (*Provider)(nil)
 	_ providers.Sinker            = (*Provider)(nil)
@@ -104,6 +105,18 @@ func (p *Provider) Storage() (abstract.Storage, error) {
 	return storage, nil
 }
 
+func (p *Provider) Source() (abstract.Source, error) {
+	src, ok := p.transfer.Src.(*model.ChSource)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected source type: %T", p.transfer.Src)
+	}
+	chOpts := []StorageOpt{WithMetrics(p.registry), WithTableFilter(src)}
+	if _, ok := p.transfer.Dst.(*model.ChDestination); ok {
+		chOpts = append(chOpts, WithHomo())
+	}
+	return NewSource(src, p.logger, p.registry, p.cp, p.transfer, chOpts...)
+}
+
 func (p *Provider) DataProvider() (base.DataProvider, error) {
 	specificConfig, ok := p.transfer.Src.(*model.ChSource)
 	if !ok {
@@ -115,21 +128,48 @@ func (p *Provider) DataProvider() (base.DataProvider, error) {
 	return NewClickhouseProvider(p.logger, p.registry, specificConfig, p.transfer)
 }
 
-func (p *Provider) Activate(_ context.Context, _ *dp_model.TransferOperation, tables abstract.TableMap, callbacks providers.ActivateCallbacks) error {
+func (p *Provider) Activate(ctx context.Context, _ *dp_model.TransferOperation, tables abstract.TableMap, callbacks providers.ActivateCallbacks) error {
 	if !p.transfer.SnapshotOnly() {
-		return xerrors.New("Only allowed mode for CH source is snapshot")
-	}
-	if err := callbacks.Cleanup(tables); err != nil {
-		return xerrors.Errorf("Sinker cleanup failed: %w", err)
+		// cursors are captured before the snapshot, so rows inserted during the snapshot are replicated
+		if err := p.initReplicationCursors(ctx, tables); err != nil {
+			return xerrors.Errorf("unable to init replication cursors: %w", err)
+		}
 	}
-	if err := callbacks.CheckIncludes(tables); err != nil {
-		return xerrors.Errorf("Failed in accordance with configuration: %w", err)
+	if !p.transfer.IncrementOnly() {
+		if err := callbacks.Cleanup(tables); err != nil {
+			return xerrors.Errorf("Sinker cleanup failed: %w", err)
+		}
+		if err := callbacks.CheckIncludes(tables); err != nil {
+			return xerrors.Errorf("Failed in accordance with configuration: %w", err)
+		}
 	}
 	if err := p.loadClickHouseSchema(); err != nil {
 		return xerrors.Errorf("Cannot load schema from source database: %w", err)
 	}
-	if err := callbacks.Upload(tables); err != nil {
-		return xerrors.Errorf("Snapshot loading failed: %w", err)
+	if !p.transfer.IncrementOnly() {
+		if err := callbacks.Upload(tables); err != nil {
+			return xerrors.Errorf("Snapshot loading failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p *Provider) initReplicationCursors(ctx context.Context, tables abstract.TableMap) error {
+	src, ok := p.transfer.Src.(*model.ChSource)
+	if !ok {
+		return xerrors.Errorf("unexpected source type: %T", p.transfer.Src)
+	}
+	storage, err := p.Storage()
+	if err != nil {
+		return xerrors.Errorf("unable to create storage: %w", err)
+	}
+	defer storage.Close()
+	chStorage, ok := storage.(ClickhouseStorage)
+	if !ok {
+		return xerrors.Errorf("unexpected storage type: %T", storage)
+	}
+	if err := InitReplicationCursors(ctx, chStorage, p.cp, p.transfer.ID, src.CursorColumn, src.BlockLag, tables); err != nil {
+		return xerrors.Errorf("unable to store cursors: %w", err)
 	}
 	return nil
 }
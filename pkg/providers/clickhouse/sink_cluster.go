@@ -13,6 +13,7 @@ import (
 	"github.com/transferia/transferia/library/go/ptr"
 	yslices "github.com/transferia/transferia/library/go/slices"
 	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/providers/clickhouse/commits"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/errors"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/model"
 	topology2 "github.com/transferia/transferia/pkg/providers/clickhouse/topology"
@@ -31,6 +32,11 @@ type sinkCluster struct {
 
 	distributedDDLMu      sync.Mutex
 	distributedDDLEnabled *bool
+
+	// commits is nil unless ExactlyOnce mode is enabled
+	commits           *commits.Log
+	commitsTableMu    sync.Mutex
+	commitsTableReady bool
 }
 
 func (c *sinkCluster) bestSinkServer() *SinkServer {
@@ -60,6 +66,9 @@ func (c *sinkCluster) Insert(spec *TableSpec, rows []abstract.ChangeItem) error
 
 func (c *sinkCluster) TruncateTable(tableName string) error {
 	ctx := context.TODO()
+	if err := c.forgetCommits(ctx, tableName); err != nil {
+		return xerrors.Errorf("cannot forget commits of table: %w", err)
+	}
 	if c.perHostDDL() {
 		errs := util.NewErrs()
 		for _, ss := range c.sinkServers {
@@ -81,6 +90,9 @@ func (c *sinkCluster) TruncateTable(tableName string) error {
 
 func (c *sinkCluster) DropTable(tableName string) error {
 	ctx := context.TODO()
+	if err := c.forgetCommits(ctx, tableName); err != nil {
+		return xerrors.Errorf("cannot forget commits of table: %w", err)
+	}
 	if c.perHostDDL() {
 		errs := util.NewErrs()
 		for _, ss := range c.sinkServers {
@@ -145,6 +157,39 @@ func (c *sinkCluster) execDDL(executor func(distributed bool) error) error {
 	return nil
 }
 
+// ensureCommitsTable creates the service table of ExactlyOnce mode once per cluster
+func (c *sinkCluster) ensureCommitsTable() error {
+	c.commitsTableMu.Lock()
+	defer c.commitsTableMu.Unlock()
+	if c.commitsTableReady {
+		return nil
+	}
+	err := c.execDDL(func(distributed bool) error {
+		server := c.bestSinkServer()
+		ddl := commits.CreateTableDDL(server.config.Database(), distributed, c.topology.ClusterName())
+		if err := server.ExecDDL(context.Background(), ddl); err != nil {
+			return xerrors.Errorf("cannot create commits table (distributed=%v): %w", distributed, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("unable to create table %s: %w", commits.TableName, err)
+	}
+	c.commitsTableReady = true
+	return nil
+}
+
+func (c *sinkCluster) forgetCommits(ctx context.Context, tableName string) error {
+	if c.commits == nil {
+		return nil
+	}
+	if err := c.ensureCommitsTable(); err != nil {
+		return err
+	}
+	server := c.bestSinkServer()
+	return c.commits.Forget(ctx, commits.NewJournal(server.db, server.config.Database(), server.config.TransferID()), tableName)
+}
+
 func (c *sinkCluster) perHostDDL() bool {
 	return c.config.ShardByTransferID()
 }
@@ -220,6 +265,9 @@ func newSinkCluster(config model.ChSinkClusterParams, lgr log.Logger, metrics *s
 	cl.logger = lgr
 	cl.config = config
 	cl.topology = topology
+	if config.ExactlyOnce() {
+		cl.commits = commits.NewLog(config.TransferID(), lgr)
+	}
 
 	if err := cl.Init(); err != nil {
 		return nil, err
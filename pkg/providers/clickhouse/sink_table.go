@@ -20,6 +20,7 @@ import (
 	"github.com/transferia/transferia/pkg/abstract/changeitem"
 	"github.com/transferia/transferia/pkg/errors/codes"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/columntypes"
+	"github.com/transferia/transferia/pkg/providers/clickhouse/commits"
 	cherrors "github.com/transferia/transferia/pkg/providers/clickhouse/errors"
 	httpuploader2 "github.com/transferia/transferia/pkg/providers/clickhouse/httpuploader"
 	"github.com/transferia/transferia/pkg/providers/clickhouse/model"
@@ -54,6 +55,11 @@ func normalizeTableName(table string) string {
 func (t *sinkTable) Init(cols *abstract.TableSchema) error {
 	sch := NewSchema(cols.Columns(), t.config.SystemColumnsFirst(), t.tableName)
 	t.cols = cols
+	if t.cluster.commits != nil {
+		if err := t.cluster.ensureCommitsTable(); err != nil {
+			return xerrors.Errorf("failed to init exactly once mode: %w", err)
+		}
+	}
 	exist, err := t.checkExist()
 	if err != nil {
 		return xerrors.Errorf("failed to check existing of table %s: %w", t.tableName, err)
@@ -175,8 +181,16 @@ func (t *sinkTable) generateDDL(cols []abstract.ColSchema, distributed bool) str
 	if t.config.TTL() != "" {
 		result.WriteString(fmt.Sprintf(" TTL %s", t.config.TTL()))
 	}
+	var settings []string
 	if keyIsNullable {
-		result.WriteString(" SETTINGS allow_nullable_key = 1")
+		settings = append(settings, "allow_nullable_key = 1")
+	}
+	if t.config.ExactlyOnce() && !distributed {
+		// replicated tables deduplicate inserts by default, others require deduplication window
+		settings = append(settings, fmt.Sprintf("non_replicated_deduplication_window = %d", commits.DeduplicationWindow))
+	}
+	if len(settings) > 0 {
+		result.WriteString(fmt.Sprintf(" SETTINGS %s", strings.Join(settings, ", ")))
 	}
 
 	return result.String()
@@ -287,11 +301,25 @@ func (t *sinkTable) applyBatch(items []abstract.ChangeItem) error {
 		}
 		t.cols = items[0].TableSchema
 	}
+	if t.cluster.commits == nil {
+		return t.insertBatch(items, t.config.InsertSettings())
+	}
+	journal := commits.NewJournal(t.server.db, t.config.Database(), t.config.TransferID())
+	return t.cluster.commits.Write(context.Background(), journal, t.tableName, items, func(items []abstract.ChangeItem, token string) error {
+		insertSettings := t.config.InsertSettings()
+		if token != "" {
+			insertSettings = insertSettings.WithDeduplicationToken(token)
+		}
+		return t.insertBatch(items, insertSettings)
+	})
+}
+
+func (t *sinkTable) insertBatch(items []abstract.ChangeItem, insertSettings model.InsertParams) error {
 	if t.config.UploadAsJSON() {
 		if faultyItem := abstract.FindItemOfKind(items, abstract.UpdateKind, abstract.DeleteKind); faultyItem != nil {
 			return abstract.NewFatalError(xerrors.Errorf("ch-sink is configured as Prefer HTTP, but got update/delete changeItem: %s", faultyItem.ToJSONString()))
 		}
-		return t.uploadAsJSON(items)
+		return t.uploadAsJSON(items, insertSettings)
 	}
 	if !t.config.IsUpdateable() {
 		if faultyItem := abstract.FindItemOfKind(items, abstract.UpdateKind, abstract.DeleteKind); faultyItem != nil {
@@ -315,7 +343,7 @@ func (t *sinkTable) applyBatch(items []abstract.ChangeItem) error {
 	})
 	defer txRollbacks.Do()
 
-	if err := doOperation(t, tx, items); err != nil {
+	if err := doOperation(t, tx, items, insertSettings); err != nil {
 		if cherrors.IsFatalClickhouseError(err) {
 			return abstract.NewFatalError(err)
 		}
@@ -337,7 +365,7 @@ func (t *sinkTable) applyBatch(items []abstract.ChangeItem) error {
 	return nil
 }
 
-func (t *sinkTable) uploadAsJSON(rows []abstract.ChangeItem) error {
+func (t *sinkTable) uploadAsJSON(rows []abstract.ChangeItem, insertSettings model.InsertParams) error {
 	currSchema, err := getSchema(t, rows)
 	if err != nil {
 		return xerrors.Errorf("Cannot build schema from rows: %w", err)
@@ -356,7 +384,7 @@ func (t *sinkTable) uploadAsJSON(rows []abstract.ChangeItem) error {
 		abstract.MakeMapColNameToIndex(currSchema),
 		t.colTypes,
 		t.config.AnyAsString(),
-	), t.config, t.tableName, t.avgRowSize, t.logger)
+	), t.config, insertSettings, t.tableName, t.avgRowSize, t.logger)
 	if err != nil {
 		return err
 	}
@@ -596,7 +624,7 @@ func getSchema(t *sinkTable, changeItems []abstract.ChangeItem) ([]abstract.ColS
 	return buildSchemaFromChangeItem(t, *masterChangeItem), nil
 }
 
-func doOperation(t *sinkTable, tx *sql.Tx, items []abstract.ChangeItem) (err error) {
+func doOperation(t *sinkTable, tx *sql.Tx, items []abstract.ChangeItem, insertSettings model.InsertParams) (err error) {
 	if len(items) == 0 {
 		return nil
 	}
@@ -649,7 +677,7 @@ func doOperation(t *sinkTable, tx *sql.Tx, items []abstract.ChangeItem) (err err
 		strings.Join(colVals, ","),
 	)
 
-	insertCtx := clickhouse.Context(context.Background(), t.config.InsertSettings().ToQueryOption())
+	insertCtx := clickhouse.Context(context.Background(), insertSettings.ToQueryOption())
 	insertQuery, err := tx.PrepareContext(insertCtx, q)
 	if err != nil {
 		if err.Error() == "Decimal128 is not supported" {
@@ -163,7 +163,7 @@ func TestTable_doOperation_updatable_delete(t *testing.T) {
 	err = json.Unmarshal([]byte(changeItemJSON), &changeItem)
 	require.NoError(t, err)
 
-	err = doOperation(table, tx, []abstract.ChangeItem{changeItem})
+	err = doOperation(table, tx, []abstract.ChangeItem{changeItem}, table.config.InsertSettings())
 	require.NoError(t, err)
 }
 
@@ -252,7 +252,7 @@ func TestTable_doOperation_failed_on_TOAST(t *testing.T) {
 	err = json.Unmarshal([]byte(changeItemJSONs[1]), &changeItems[1])
 	require.NoError(t, err)
 
-	err = doOperation(table, tx, changeItems)
+	err = doOperation(table, tx, changeItems, table.config.InsertSettings())
 	require.NoError(t, err)
 }
 
@@ -309,7 +309,7 @@ func TestTable_doOperation_ok_on_TOAST_alias_and_materialized(t *testing.T) {
 	err = json.Unmarshal([]byte(changeItemJSON), &changeItem)
 	require.NoError(t, err)
 
-	err = doOperation(table, tx, []abstract.ChangeItem{changeItem})
+	err = doOperation(table, tx, []abstract.ChangeItem{changeItem}, table.config.InsertSettings())
 	require.NoError(t, err)
 }
 
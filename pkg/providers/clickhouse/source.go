@@ -0,0 +1,479 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/coordinator"
+	dp_model "github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/providers/clickhouse/model"
+	"github.com/transferia/transferia/pkg/providers/clickhouse/topology"
+	"github.com/transferia/transferia/pkg/stats"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const cursorStateKeyPrefix = "ch_cursor:"
+
+var _ abstract.Source = (*Source)(nil)
+
+// Source polls tables for rows inserted after the last seen cursor, which is either a value of the cursor column
+// or the max block number of every partition of a MergeTree table. Every poll selects rows between the stored cursor
+// and the current upper bound, the upper bound is stored in the transfer state once the rows are pushed.
+//
+// Cursors of local tables are kept per shard, Distributed tables are read through a single shard.
+type Source struct {
+	shards   map[string]*Storage
+	cfg      *model.ChSource
+	cp       coordinator.Coordinator
+	transfer *dp_model.Transfer
+	logger   log.Logger
+	metrics  *stats.SourceStats
+
+	// cursors by transfer state key
+	cursors map[string]tableCursor
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel func()
+}
+
+// tableCursor is the last read position of a table on a shard
+type tableCursor struct {
+	// Value is the max read value of the cursor column as string
+	Value *string `json:"value,omitempty"`
+	// Blocks are max read block numbers by partition id
+	Blocks map[string]int64 `json:"blocks,omitempty"`
+}
+
+func cursorStateKey(shard string, table abstract.TableID) string {
+	return fmt.Sprintf("%s%s:%s.%s", cursorStateKeyPrefix, shard, table.Namespace, table.Name)
+}
+
+func (s *Source) Run(sink abstract.AsyncSink) error {
+	s.wg.Add(1)
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.ctx.Done():
+			s.logger.Info("Stopping run")
+			return nil
+		default:
+		}
+
+		found, err := s.poll(sink)
+		if err != nil {
+			return xerrors.Errorf("unable to poll tables: %w", err)
+		}
+		if found > 0 {
+			s.logger.Infof("Done %v rows, poll again", found)
+			continue
+		}
+		select {
+		case <-s.ctx.Done():
+		case <-time.After(s.cfg.PollInterval):
+		}
+	}
+}
+
+func (s *Source) Stop() {
+	s.cancel()
+	s.wg.Wait()
+	for _, shard := range s.shards {
+		shard.Close()
+	}
+}
+
+func (s *Source) poll(sink abstract.AsyncSink) (int, error) {
+	tables, err := defaultShard(s.shards).TableList(s.transfer)
+	if err != nil {
+		return 0, xerrors.Errorf("unable to list tables: %w", err)
+	}
+	total := 0
+	for _, description := range tables.ConvertToTableDescriptions() {
+		id := description.ID()
+		if tables[id].IsView {
+			continue
+		}
+		targets, err := tableShards(s.shards, id)
+		if err != nil {
+			return total, xerrors.Errorf("unable to resolve shards of table %s: %w", id.Fqtn(), err)
+		}
+		for _, shard := range sortedShardNames(targets) {
+			found, err := s.pollTable(shard, targets[shard], id, tables[id].Schema, sink)
+			if err != nil {
+				return total, xerrors.Errorf("unable to poll table %s on shard %q: %w", id.Fqtn(), shard, err)
+			}
+			total += found
+		}
+	}
+	return total, nil
+}
+
+func (s *Source) pollTable(shard string, storage *Storage, table abstract.TableID, tableSchema *abstract.TableSchema, sink abstract.AsyncSink) (int, error) {
+	tailer, err := storage.tableCursorer(table, tableSchema, s.cfg.CursorColumn, s.cfg.BlockLag)
+	if err != nil {
+		return 0, xerrors.Errorf("unable to resolve cursor: %w", err)
+	}
+	key := cursorStateKey(shard, table)
+	from := s.cursors[key]
+	to, found, err := storage.nextCursor(s.ctx, tailer, table, from)
+	if err != nil {
+		return 0, xerrors.Errorf("unable to get upper bound of new rows: %w", err)
+	}
+	if !found {
+		return 0, nil
+	}
+
+	total := 0
+	description := abstract.TableDescription{
+		Schema: table.Namespace,
+		Name:   table.Name,
+		Filter: abstract.WhereStatement(tailer.rangeFilter(from, to)),
+		EtaRow: 0,
+		Offset: 0,
+	}
+	if err := storage.LoadTable(s.ctx, description, func(items []abstract.ChangeItem) error {
+		for i := range items {
+			// filter is changed by every poll, so rows are not a part of table snapshot
+			items[i].PartID = ""
+		}
+		if err := <-sink.AsyncPush(items); err != nil {
+			return xerrors.Errorf("unable to push rows: %w", err)
+		}
+		total += len(items)
+		s.metrics.ChangeItems.Add(int64(len(items)))
+		return nil
+	}); err != nil {
+		return total, xerrors.Errorf("unable to load new rows: %w", err)
+	}
+	if err := s.storeCursor(key, to); err != nil {
+		return total, xerrors.Errorf("unable to store cursor: %w", err)
+	}
+	return total, nil
+}
+
+func (s *Source) storeCursor(key string, cursor tableCursor) error {
+	s.cursors[key] = cursor
+	if err := s.cp.SetTransferState(s.transfer.ID, map[string]*coordinator.TransferStateData{
+		key: {Generic: cursor},
+	}); err != nil {
+		return xerrors.Errorf("unable to set transfer state: %w", err)
+	}
+	return nil
+}
+
+// cursorer describes how new rows of a table are found, either by cursor column or by block numbers
+type cursorer struct {
+	table abstract.TableID
+	// column & columnType are set when the table is tailed by cursor column
+	column     string
+	columnType string
+	// blockExpr is an expression for the block number of a row, set when the table is tailed by block numbers
+	blockExpr string
+	// blockLag is the age of data parts, after which their blocks are read
+	blockLag time.Duration
+}
+
+// upperBoundQuery selects the upper bound of rows after the cursor: number of rows & max cursor value as string
+// or max block number per partition.
+//
+// Block numbers are allocated before inserts are committed, so an insert may commit block N after block N+1
+// is read, and its rows would be left behind the cursor. So the upper bound is taken only from data parts
+// older than blockLag: a block committed within blockLag after the newer one is still read by the same poll.
+func (c *cursorer) upperBoundQuery(from tableCursor) string {
+	if c.blockExpr != "" {
+		return fmt.Sprintf(
+			"SELECT partition_id, max(max_block_number) FROM system.parts "+
+				"WHERE database = %s AND table = %s AND active AND modification_time <= now() - INTERVAL %d SECOND AND max_block_number > %s "+
+				"GROUP BY partition_id",
+			quoteString(c.table.Namespace), quoteString(c.table.Name), int64(c.blockLag.Seconds()), blocksByPartition("partition_id", from.Blocks),
+		)
+	}
+	cond := fmt.Sprintf("`%s` IS NOT NULL", c.column)
+	if from.Value != nil {
+		cond += fmt.Sprintf(" AND `%s` > %s", c.column, c.castValue(*from.Value))
+	}
+	return fmt.Sprintf("SELECT count(), toString(max(`%s`)) FROM `%s`.`%s` WHERE %s", c.column, c.table.Namespace, c.table.Name, cond)
+}
+
+// rangeFilter selects rows after the cursor `from` up to the cursor `to` inclusively
+func (c *cursorer) rangeFilter(from, to tableCursor) string {
+	if c.blockExpr != "" {
+		return fmt.Sprintf("%s > %s AND %s <= %s", c.blockExpr, blocksByPartition("_partition_id", from.Blocks), c.blockExpr, blocksByPartition("_partition_id", to.Blocks))
+	}
+	filter := fmt.Sprintf("`%s` <= %s", c.column, c.castValue(*to.Value))
+	if from.Value != nil {
+		filter = fmt.Sprintf("`%s` > %s AND %s", c.column, c.castValue(*from.Value), filter)
+	}
+	return filter
+}
+
+func (c *cursorer) castValue(value string) string {
+	return fmt.Sprintf("CAST(%s AS %s)", quoteString(value), c.columnType)
+}
+
+// blocksByPartition builds an expression for the block number of the partition by its id, -1 for unknown partitions
+func blocksByPartition(partitionID string, blocks map[string]int64) string {
+	if len(blocks) == 0 {
+		return "-1"
+	}
+	partitions := make([]string, 0, len(blocks))
+	for partition := range blocks {
+		partitions = append(partitions, partition)
+	}
+	sort.Strings(partitions)
+	args := make([]string, 0, 2*len(blocks)+1)
+	for _, partition := range partitions {
+		args = append(args, fmt.Sprintf("%s = %s", partitionID, quoteString(partition)), fmt.Sprint(blocks[partition]))
+	}
+	args = append(args, "-1")
+	return fmt.Sprintf("multiIf(%s)", strings.Join(args, ", "))
+}
+
+func quoteString(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// blockNumberExpr returns an expression for the block number of the row of MergeTree table.
+// Without `_block_number` column the max block number of the row data part is used,
+// so rows of parts merged with new ones are read again.
+// Rows of blocks committed later than blockLag after newer blocks are lost, see cursorer.upperBoundQuery.
+func blockNumberExpr(engineFull string) string {
+	if strings.Contains(strings.ReplaceAll(engineFull, " ", ""), "enable_block_number_column=1") {
+		return "_block_number"
+	}
+	// part name is `<partition id>_<min block>_<max block>_<level>[_<mutation>]`
+	return "toInt64(splitByChar('_', substring(_part, length(_partition_id) + 2))[2])"
+}
+
+func (s *Storage) tableEngine(table abstract.TableID) (string, string, error) {
+	row := s.db.QueryRow(`SELECT engine, engine_full FROM system.tables WHERE database=? AND name=?;`, table.Namespace, table.Name)
+	var engine, engineFull string
+	if err := row.Scan(&engine, &engineFull); err != nil {
+		return "", "", xerrors.Errorf("error while getting engine of table %s : %w", table.Fqtn(), err)
+	}
+	return engine, engineFull, nil
+}
+
+func (s *Storage) tableCursorer(table abstract.TableID, tableSchema *abstract.TableSchema, cursorColumn string, blockLag time.Duration) (*cursorer, error) {
+	if cursorColumn != "" {
+		for _, col := range tableSchema.Columns() {
+			if col.ColumnName == cursorColumn {
+				return &cursorer{
+					table:      table,
+					column:     cursorColumn,
+					columnType: strings.TrimPrefix(col.OriginalType, "ch:"),
+					blockExpr:  "",
+					blockLag:   0,
+				}, nil
+			}
+		}
+		return nil, abstract.NewFatalError(xerrors.Errorf("table %s has no cursor column %s", table.Fqtn(), cursorColumn))
+	}
+	engine, engineFull, err := s.tableEngine(table)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get table engine: %w", err)
+	}
+	if !strings.HasSuffix(engine, "MergeTree") {
+		return nil, abstract.NewFatalError(xerrors.Errorf("table %s with engine %s can be replicated only by cursor column", table.Fqtn(), engine))
+	}
+	return &cursorer{
+		table:      table,
+		column:     "",
+		columnType: "",
+		blockExpr:  blockNumberExpr(engineFull),
+		blockLag:   blockLag,
+	}, nil
+}
+
+// nextCursor returns the cursor of the latest row after the given one, false if there are no such rows
+func (s *Storage) nextCursor(ctx context.Context, tailer *cursorer, table abstract.TableID, from tableCursor) (tableCursor, bool, error) {
+	query := tailer.upperBoundQuery(from)
+	if tailer.blockExpr == "" {
+		var count uint64
+		var value sql.NullString
+		if err := s.db.QueryRowContext(ctx, query).Scan(&count, &value); err != nil {
+			return from, false, xerrors.Errorf("unable to select max cursor value of table %s: %w", table.Fqtn(), err)
+		}
+		if count == 0 || !value.Valid {
+			return from, false, nil
+		}
+		return tableCursor{Value: &value.String, Blocks: nil}, true, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return from, false, xerrors.Errorf("unable to select max block numbers of table %s: %w", table.Fqtn(), err)
+	}
+	defer rows.Close()
+	next := tableCursor{Value: nil, Blocks: map[string]int64{}}
+	for partition, block := range from.Blocks {
+		next.Blocks[partition] = block
+	}
+	found := false
+	for rows.Next() {
+		var partition string
+		var block int64
+		if err := rows.Scan(&partition, &block); err != nil {
+			return from, false, xerrors.Errorf("unable to scan max block number: %w", err)
+		}
+		next.Blocks[partition] = block
+		found = true
+	}
+	if err := rows.Err(); err != nil {
+		return from, false, xerrors.Errorf("unable to read max block numbers: %w", err)
+	}
+	return next, found, nil
+}
+
+func defaultShard(shards map[string]*Storage) *Storage {
+	return shards[sortedShardNames(shards)[0]]
+}
+
+func sortedShardNames(shards map[string]*Storage) []string {
+	names := make([]string, 0, len(shards))
+	for name := range shards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tableShards returns storages to read the table from by shard names, Distributed table is read by a single shard with empty name
+func tableShards(shards map[string]*Storage, table abstract.TableID) (map[string]*Storage, error) {
+	if len(shards) == 1 {
+		return map[string]*Storage{"": defaultShard(shards)}, nil
+	}
+	distributed, err := defaultShard(shards).isDistributed(table)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to check table engine: %w", err)
+	}
+	if distributed {
+		return map[string]*Storage{"": defaultShard(shards)}, nil
+	}
+	return shards, nil
+}
+
+func storageShards(storage ClickhouseStorage) (map[string]*Storage, error) {
+	switch st := storage.(type) {
+	case *Storage:
+		return map[string]*Storage{"": st}, nil
+	case *ShardStorage:
+		return st.shards, nil
+	default:
+		return nil, xerrors.Errorf("unexpected storage type: %T", storage)
+	}
+}
+
+// InitReplicationCursors stores cursors of the latest rows of tables, so replication starts after the activation moment.
+// Empty tables and tables created later are replicated from the beginning.
+func InitReplicationCursors(ctx context.Context, storage ClickhouseStorage, cp coordinator.Coordinator, transferID string, cursorColumn string, blockLag time.Duration, tables abstract.TableMap) error {
+	shards, err := storageShards(storage)
+	if err != nil {
+		return xerrors.Errorf("unable to get shards: %w", err)
+	}
+	state := map[string]*coordinator.TransferStateData{}
+	for id, info := range tables {
+		if info.IsView {
+			continue
+		}
+		targets, err := tableShards(shards, id)
+		if err != nil {
+			return xerrors.Errorf("unable to resolve shards of table %s: %w", id.Fqtn(), err)
+		}
+		for shard, shardStorage := range targets {
+			tailer, err := shardStorage.tableCursorer(id, info.Schema, cursorColumn, blockLag)
+			if err != nil {
+				return xerrors.Errorf("unable to resolve cursor: %w", err)
+			}
+			cursor, found, err := shardStorage.nextCursor(ctx, tailer, id, tableCursor{Value: nil, Blocks: nil})
+			if err != nil {
+				return xerrors.Errorf("unable to get cursor of table %s on shard %q: %w", id.Fqtn(), shard, err)
+			}
+			if !found {
+				continue
+			}
+			state[cursorStateKey(shard, id)] = &coordinator.TransferStateData{Generic: cursor}
+		}
+	}
+	if len(state) == 0 {
+		return nil
+	}
+	if err := cp.SetTransferState(transferID, state); err != nil {
+		return xerrors.Errorf("unable to set transfer state: %w", err)
+	}
+	return nil
+}
+
+func loadCursors(cp coordinator.Coordinator, transferID string) (map[string]tableCursor, error) {
+	state, err := cp.GetTransferState(transferID)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get transfer state: %w", err)
+	}
+	cursors := map[string]tableCursor{}
+	for key, value := range state {
+		if !strings.HasPrefix(key, cursorStateKeyPrefix) || value == nil {
+			continue
+		}
+		// state may be deserialized as a generic map
+		data, err := json.Marshal(value.Generic)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to marshal cursor %s: %w", key, err)
+		}
+		var cursor tableCursor
+		if err := json.Unmarshal(data, &cursor); err != nil {
+			return nil, xerrors.Errorf("unable to unmarshal cursor %s: %w", key, err)
+		}
+		cursors[key] = cursor
+	}
+	return cursors, nil
+}
+
+func NewSource(src *model.ChSource, lgr log.Logger, registry metrics.Registry, cp coordinator.Coordinator, transfer *dp_model.Transfer, opts ...StorageOpt) (*Source, error) {
+	storageParams, err := src.ToStorageParams()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to resolve storage params: %w", err)
+	}
+	storage, err := NewStorage(storageParams, transfer, opts...)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create storage: %w", err)
+	}
+	shards, err := storageShards(storage)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get shards: %w", err)
+	}
+
+	cursors, err := loadCursors(cp, transfer.ID)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to load cursors: %w", err)
+	}
+	lgr.Info(
+		"clickhouse replication source constructed",
+		log.Any("cursors", cursors),
+		log.Int("shards", len(shards)),
+		log.Bool("single_node", topology.IsSingleNode(storageParams.ConnectionParams.Shards)),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Source{
+		shards:   shards,
+		cfg:      src,
+		cp:       cp,
+		transfer: transfer,
+		logger:   lgr,
+		metrics:  stats.NewSourceStats(registry),
+		cursors:  cursors,
+		wg:       sync.WaitGroup{},
+		ctx:      ctx,
+		cancel:   cancel,
+	}, nil
+}
@@ -0,0 +1,96 @@
+package clickhouse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/coordinator"
+)
+
+func TestCursorColumnQueries(t *testing.T) {
+	tailer := &cursorer{
+		table:      abstract.TableID{Namespace: "db", Name: "events"},
+		column:     "ts",
+		columnType: "DateTime64(3, 'UTC')",
+		blockExpr:  "",
+		blockLag:   0,
+	}
+	from := "2024-01-02 03:04:05.000"
+	to := "2024-01-02 03:05:00.123"
+
+	require.Equal(t,
+		"SELECT count(), toString(max(`ts`)) FROM `db`.`events` WHERE `ts` IS NOT NULL",
+		tailer.upperBoundQuery(tableCursor{Value: nil, Blocks: nil}),
+	)
+	require.Equal(t,
+		"SELECT count(), toString(max(`ts`)) FROM `db`.`events` WHERE `ts` IS NOT NULL AND `ts` > CAST('2024-01-02 03:04:05.000' AS DateTime64(3, 'UTC'))",
+		tailer.upperBoundQuery(tableCursor{Value: &from, Blocks: nil}),
+	)
+	require.Equal(t,
+		"`ts` <= CAST('2024-01-02 03:05:00.123' AS DateTime64(3, 'UTC'))",
+		tailer.rangeFilter(tableCursor{Value: nil, Blocks: nil}, tableCursor{Value: &to, Blocks: nil}),
+	)
+	require.Equal(t,
+		"`ts` > CAST('2024-01-02 03:04:05.000' AS DateTime64(3, 'UTC')) AND `ts` <= CAST('2024-01-02 03:05:00.123' AS DateTime64(3, 'UTC'))",
+		tailer.rangeFilter(tableCursor{Value: &from, Blocks: nil}, tableCursor{Value: &to, Blocks: nil}),
+	)
+}
+
+func TestBlockNumberQueries(t *testing.T) {
+	require.Equal(t, "_block_number", blockNumberExpr("MergeTree ORDER BY id SETTINGS enable_block_number_column = 1, index_granularity = 8192"))
+	partExpr := blockNumberExpr("ReplicatedMergeTree('/clickhouse/tables/{shard}/events', '{replica}') PARTITION BY toYYYYMM(ts) ORDER BY id")
+	require.Equal(t, "toInt64(splitByChar('_', substring(_part, length(_partition_id) + 2))[2])", partExpr)
+
+	tailer := &cursorer{
+		table:      abstract.TableID{Namespace: "db", Name: "events"},
+		column:     "",
+		columnType: "",
+		blockExpr:  "_block_number",
+		blockLag:   time.Minute,
+	}
+	from := tableCursor{Value: nil, Blocks: map[string]int64{"202402": 7, "202401": 3}}
+	to := tableCursor{Value: nil, Blocks: map[string]int64{"202402": 9, "202401": 3, "202403": 1}}
+
+	require.Equal(t,
+		"SELECT partition_id, max(max_block_number) FROM system.parts "+
+			"WHERE database = 'db' AND table = 'events' AND active AND modification_time <= now() - INTERVAL 60 SECOND AND max_block_number > -1 "+
+			"GROUP BY partition_id",
+		tailer.upperBoundQuery(tableCursor{Value: nil, Blocks: nil}),
+	)
+	require.Equal(t,
+		"SELECT partition_id, max(max_block_number) FROM system.parts "+
+			"WHERE database = 'db' AND table = 'events' AND active AND modification_time <= now() - INTERVAL 60 SECOND "+
+			"AND max_block_number > multiIf(partition_id = '202401', 3, partition_id = '202402', 7, -1) "+
+			"GROUP BY partition_id",
+		tailer.upperBoundQuery(from),
+	)
+	require.Equal(t,
+		"_block_number > multiIf(_partition_id = '202401', 3, _partition_id = '202402', 7, -1) AND "+
+			"_block_number <= multiIf(_partition_id = '202401', 3, _partition_id = '202402', 9, _partition_id = '202403', 1, -1)",
+		tailer.rangeFilter(from, to),
+	)
+}
+
+func TestQuoteString(t *testing.T) {
+	require.Equal(t, `'it\'s a \\ test'`, quoteString(`it's a \ test`))
+}
+
+func TestLoadCursors(t *testing.T) {
+	cp := coordinator.NewStatefulFakeClient()
+	value := "42"
+	table := abstract.TableID{Namespace: "db", Name: "events"}
+	require.NoError(t, cp.SetTransferState("dtt", map[string]*coordinator.TransferStateData{
+		cursorStateKey("", table):       {Generic: tableCursor{Value: &value, Blocks: nil}},
+		cursorStateKey("shard1", table): {Generic: map[string]any{"blocks": map[string]any{"all": 12}}},
+		"unrelated":                     {Generic: "value"},
+	}))
+
+	cursors, err := loadCursors(cp, "dtt")
+	require.NoError(t, err)
+	require.Equal(t, map[string]tableCursor{
+		"ch_cursor::db.events":       {Value: &value, Blocks: nil},
+		"ch_cursor:shard1:db.events": {Value: nil, Blocks: map[string]int64{"all": 12}},
+	}, cursors)
+}
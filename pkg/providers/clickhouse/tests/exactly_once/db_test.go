@@ -0,0 +1,81 @@
+package exactlyonce
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/middlewares"
+	"github.com/transferia/transferia/pkg/providers/clickhouse"
+	chrecipe "github.com/transferia/transferia/pkg/providers/clickhouse/recipe"
+	"github.com/transferia/transferia/tests/helpers"
+)
+
+var (
+	Source = model.MockSource{}
+	Target = *chrecipe.MustTarget(chrecipe.WithDatabase("test"), chrecipe.WithInitFile("init.sql"))
+)
+
+func makeRows(schema *abstract.TableSchema, partition int, from, to int) []abstract.ChangeItem {
+	var rows []abstract.ChangeItem
+	for offset := from; offset < to; offset++ {
+		row := abstract.ChangeItem{
+			Kind:         abstract.InsertKind,
+			Schema:       "test",
+			Table:        "test",
+			ColumnNames:  []string{"id", "value"},
+			ColumnValues: []any{int32(partition*1000 + offset), fmt.Sprintf("value %d", offset)},
+			TableSchema:  schema,
+		}
+		row.FillQueueMessageMeta("topic", partition, uint64(offset), 0)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func newSink(t *testing.T) abstract.Sinker {
+	transfer := helpers.MakeTransfer(helpers.TransferID, &Source, &Target, abstract.TransferTypeIncrementOnly)
+	sinker, err := clickhouse.NewSink(transfer, logger.Log, solomon.NewRegistry(solomon.NewRegistryOpts()), middlewares.MakeConfig())
+	require.NoError(t, err)
+	return sinker
+}
+
+func rowsCount(t *testing.T) uint64 {
+	storageParams, err := Target.ToStorageParams()
+	require.NoError(t, err)
+	storage, err := clickhouse.NewStorage(storageParams, nil)
+	require.NoError(t, err)
+	defer storage.Close()
+	count, err := storage.ExactTableRowsCount(abstract.TableID{Namespace: "test", Name: "test"})
+	require.NoError(t, err)
+	return count
+}
+
+// TestExactlyOnce pushes redelivered batches through sinks of restarted workers, commits of the sink skip them
+func TestExactlyOnce(t *testing.T) {
+	Source.WithDefaults()
+	Target.WithDefaults()
+	Target.ExactlyOnce = true
+	schema := abstract.NewTableSchema([]abstract.ColSchema{
+		{TableName: "test", ColumnName: "id", DataType: "int32", PrimaryKey: true, OriginalType: "ch:Int32"},
+		{TableName: "test", ColumnName: "value", DataType: "utf8", OriginalType: "ch:String"},
+	})
+	batch := append(makeRows(schema, 0, 0, 10), makeRows(schema, 1, 0, 10)...)
+
+	sinker := newSink(t)
+	require.NoError(t, sinker.Push(batch))
+	require.NoError(t, sinker.Push(batch))
+	require.NoError(t, sinker.Close())
+	require.Equal(t, uint64(20), rowsCount(t))
+
+	// the restarted worker rereads the source from an older offset with batches cut anew
+	sinker = newSink(t)
+	require.NoError(t, sinker.Push(append(makeRows(schema, 0, 5, 15), makeRows(schema, 1, 5, 7)...)))
+	require.NoError(t, sinker.Push(makeRows(schema, 1, 0, 15)))
+	require.NoError(t, sinker.Close())
+	require.Equal(t, uint64(30), rowsCount(t))
+}
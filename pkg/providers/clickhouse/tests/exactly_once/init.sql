@@ -0,0 +1,7 @@
+CREATE DATABASE IF NOT EXISTS test;
+CREATE TABLE IF NOT EXISTS test.test (
+    id Int32,
+    value String
+) ENGINE = MergeTree
+ORDER BY id
+SETTINGS non_replicated_deduplication_window = 1000;
@@ -0,0 +1,130 @@
+package delta
+
+import (
+	"strings"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/middlewares/async/bufferer"
+	"github.com/transferia/transferia/pkg/providers/delta/store"
+)
+
+// To verify providers contract implementation
+var (
+	_ model.Destination = (*DeltaDestination)(nil)
+)
+
+type WriteMode string
+
+const (
+	// WriteModeAppend appends every inserted row, updates & deletes are not supported
+	WriteModeAppend = WriteMode("append")
+	// WriteModeMergeOnRead applies updates & deletes by primary key: data files, which contain changed rows,
+	// are replaced by their filtered copies within the same commit (pair of remove & add actions).
+	WriteModeMergeOnRead = WriteMode("merge_on_read")
+)
+
+type DeltaDestination struct {
+	// Bucket of S3 storage, if empty - PathPrefix is a path in local file system
+	Bucket           string
+	AccessKey        string
+	S3ForcePathStyle bool
+	SecretKey        model.SecretString
+	Endpoint         string
+	UseSSL           bool
+	VerifySSL        bool
+	Region           string
+
+	// PathPrefix is a root of delta tables, each table is stored at `PathPrefix/<namespace>/<name>`
+	PathPrefix string
+
+	WriteMode WriteMode
+	// CheckpointInterval is a number of commits between checkpoints, table property `checkpointInterval` is used if zero
+	CheckpointInterval int
+
+	Cleanup        model.CleanupType
+	BufferSize     model.BytesSize
+	BufferInterval time.Duration
+}
+
+func (d *DeltaDestination) GetProviderType() abstract.ProviderType {
+	return ProviderType
+}
+
+func (d *DeltaDestination) Validate() error {
+	if d.PathPrefix == "" && d.Bucket == "" {
+		return xerrors.New("path prefix is required for local delta tables")
+	}
+	switch d.WriteMode {
+	case WriteModeAppend, WriteModeMergeOnRead:
+	default:
+		return xerrors.Errorf("unknown write mode: %s", d.WriteMode)
+	}
+	if d.CheckpointInterval < 0 {
+		return xerrors.Errorf("checkpoint interval must not be negative: %d", d.CheckpointInterval)
+	}
+	return nil
+}
+
+func (d *DeltaDestination) WithDefaults() {
+	if d.WriteMode == "" {
+		d.WriteMode = WriteModeAppend
+	}
+	if d.Cleanup == "" {
+		d.Cleanup = model.Drop
+	}
+	if d.BufferSize == 0 {
+		d.BufferSize = 64 * 1024 * 1024
+	}
+	if d.BufferInterval == 0 {
+		d.BufferInterval = time.Minute
+	}
+}
+
+func (d *DeltaDestination) BuffererConfig() *bufferer.BuffererConfig {
+	return &bufferer.BuffererConfig{
+		TriggingCount:    0,
+		TriggingSize:     uint64(d.BufferSize),
+		TriggingInterval: d.BufferInterval,
+	}
+}
+
+func (d *DeltaDestination) CleanupMode() model.CleanupType {
+	return d.Cleanup
+}
+
+func (d *DeltaDestination) IsDestination() {
+}
+
+// TablePath returns root of delta table
+func (d *DeltaDestination) TablePath(table abstract.TableID) string {
+	path := strings.TrimRight(d.PathPrefix, "/")
+	if table.Namespace != "" {
+		path += "/" + table.Namespace
+	}
+	path += "/" + table.Name
+	if d.Bucket != "" {
+		// keys of objects have no leading slash
+		path = strings.TrimLeft(path, "/")
+	}
+	return path
+}
+
+func (d *DeltaDestination) StoreConfig(table abstract.TableID) store.StoreConfig {
+	if d.Bucket == "" {
+		return &store.LocalConfig{Path: d.TablePath(table)}
+	}
+	return &store.S3Config{
+		Endpoint:         d.Endpoint,
+		TablePath:        d.TablePath(table),
+		Region:           d.Region,
+		AccessKey:        d.AccessKey,
+		S3ForcePathStyle: d.S3ForcePathStyle,
+		Secret:           string(d.SecretKey),
+		Bucket:           d.Bucket,
+		UseSSL:           d.UseSSL,
+		VerifySSL:        d.VerifySSL,
+	}
+}
@@ -3,6 +3,7 @@ package protocol
 import (
 	"encoding/json"
 	"sort"
+	"strings"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/transferia/transferia/internal/logger"
@@ -88,7 +89,7 @@ func LastCheckpoint(s store.Store) (*CheckpointMetaData, error) {
 
 func LoadMetadataFromFile(s store.Store) (*CheckpointMetaData, error) {
 	checkpoint, err := backoff.RetryWithData(func() (*CheckpointMetaData, error) {
-		lines, err := s.Read(LastCheckpointPath)
+		lines, err := s.Read(strings.TrimRight(s.Root(), "/") + "/_delta_log/" + LastCheckpointPath)
 		if err != nil {
 			if xerrors.Is(err, store.ErrFileNotFound) {
 				return nil, nil
@@ -212,7 +213,8 @@ func LatestCompleteCheckpoint(instances []*CheckpointInstance, notLaterThan Chec
 
 	var res []*CheckpointInstance
 	for k, v := range grouped {
-		if k.NumParts != len(v) {
+		// single-file checkpoint has no parts, multi-part checkpoint is complete only with all of its parts
+		if k.HasParts && k.NumParts != len(v) {
 			continue
 		}
 		res = append(res, k.toInstance())
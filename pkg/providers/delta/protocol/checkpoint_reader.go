@@ -1,8 +1,6 @@
 package protocol
 
 import (
-	"strings"
-
 	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/providers/delta/action"
 	"github.com/transferia/transferia/pkg/providers/delta/store"
@@ -24,20 +22,12 @@ type StoreCheckpointReader struct {
 }
 
 func (l *StoreCheckpointReader) Read(path string) (iter.Iter[action.Container], error) {
-	data, err := l.store.Read(path)
+	data, err := l.store.ReadAll(path)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to read: %s: %w", path, err)
 	}
 
-	var rows []string
-	for data.Next() {
-		row, err := data.Value()
-		if err != nil {
-			return nil, err
-		}
-		rows = append(rows, row)
-	}
-	pf := buffer.NewBufferFileFromBytes([]byte(strings.Join(rows, "\n")))
+	pf := buffer.NewBufferFileFromBytes(data)
 	pr, err := reader.NewParquetReader(pf, nil, 4)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to read parquet fail: %w", err)
@@ -61,12 +51,13 @@ func (p *localParquetIterater) Next() bool {
 }
 
 func (p *localParquetIterater) Value() (action.Container, error) {
-	res := new(action.Single)
+	// reader fills slice up to its length
+	res := make([]checkpointAction, 1)
 	if err := p.reader.Read(&res); err != nil {
 		return nil, xerrors.Errorf("unable to read val: %w", err)
 	}
 	p.cur++
-	return res.Unwrap(), nil
+	return res[0].container(), nil
 }
 
 func (p *localParquetIterater) Close() error {
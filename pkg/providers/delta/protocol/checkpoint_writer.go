@@ -0,0 +1,249 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/providers/delta/action"
+	"github.com/transferia/transferia/pkg/providers/delta/store"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// checkpointAction is a parquet representation of action.Single, names of columns follow delta protocol,
+// so checkpoints can be read by any delta reader.
+// Reader without schema matches columns to fields by capitalized column name, so `id` goes to `Id`, not `ID`.
+type checkpointAction struct {
+	Txn      *checkpointTxn      `parquet:"name=txn"`
+	Add      *checkpointAdd      `parquet:"name=add"`
+	Remove   *checkpointRemove   `parquet:"name=remove"`
+	MetaData *checkpointMetadata `parquet:"name=metaData"`
+	Protocol *checkpointProtocol `parquet:"name=protocol"`
+}
+
+type checkpointTxn struct {
+	AppId       string `parquet:"name=appId, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Version     int64  `parquet:"name=version, type=INT64"`
+	LastUpdated *int64 `parquet:"name=lastUpdated, type=INT64"`
+}
+
+type checkpointAdd struct {
+	Path             string            `parquet:"name=path, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PartitionValues  map[string]string `parquet:"name=partitionValues, type=MAP, convertedtype=MAP, keytype=BYTE_ARRAY, keyconvertedtype=UTF8, valuetype=BYTE_ARRAY, valueconvertedtype=UTF8"`
+	Size             int64             `parquet:"name=size, type=INT64"`
+	ModificationTime int64             `parquet:"name=modificationTime, type=INT64"`
+	DataChange       bool              `parquet:"name=dataChange, type=BOOLEAN"`
+	Stats            *string           `parquet:"name=stats, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Tags             map[string]string `parquet:"name=tags, type=MAP, convertedtype=MAP, keytype=BYTE_ARRAY, keyconvertedtype=UTF8, valuetype=BYTE_ARRAY, valueconvertedtype=UTF8"`
+}
+
+type checkpointRemove struct {
+	Path                 string            `parquet:"name=path, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeletionTimestamp    *int64            `parquet:"name=deletionTimestamp, type=INT64"`
+	DataChange           bool              `parquet:"name=dataChange, type=BOOLEAN"`
+	ExtendedFileMetadata bool              `parquet:"name=extendedFileMetadata, type=BOOLEAN"`
+	PartitionValues      map[string]string `parquet:"name=partitionValues, type=MAP, convertedtype=MAP, keytype=BYTE_ARRAY, keyconvertedtype=UTF8, valuetype=BYTE_ARRAY, valueconvertedtype=UTF8"`
+	Size                 *int64            `parquet:"name=size, type=INT64"`
+}
+
+type checkpointFormat struct {
+	Provider string            `parquet:"name=provider, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Options  map[string]string `parquet:"name=options, type=MAP, convertedtype=MAP, keytype=BYTE_ARRAY, keyconvertedtype=UTF8, valuetype=BYTE_ARRAY, valueconvertedtype=UTF8"`
+}
+
+type checkpointMetadata struct {
+	Id               string            `parquet:"name=id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Name             *string           `parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Description      *string           `parquet:"name=description, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Format           checkpointFormat  `parquet:"name=format"`
+	SchemaString     string            `parquet:"name=schemaString, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PartitionColumns []string          `parquet:"name=partitionColumns, type=LIST, valuetype=BYTE_ARRAY, valueconvertedtype=UTF8"`
+	Configuration    map[string]string `parquet:"name=configuration, type=MAP, convertedtype=MAP, keytype=BYTE_ARRAY, keyconvertedtype=UTF8, valuetype=BYTE_ARRAY, valueconvertedtype=UTF8"`
+	CreatedTime      *int64            `parquet:"name=createdTime, type=INT64"`
+}
+
+type checkpointProtocol struct {
+	MinReaderVersion int32 `parquet:"name=minReaderVersion, type=INT32"`
+	MinWriterVersion int32 `parquet:"name=minWriterVersion, type=INT32"`
+}
+
+// container converts checkpoint row back into action, nil if row has no known action
+func (a *checkpointAction) container() action.Container {
+	switch {
+	case a.Add != nil:
+		return &action.AddFile{
+			Path:             a.Add.Path,
+			DataChange:       a.Add.DataChange,
+			PartitionValues:  a.Add.PartitionValues,
+			Size:             a.Add.Size,
+			ModificationTime: a.Add.ModificationTime,
+			Stats:            stringValue(a.Add.Stats),
+			Tags:             a.Add.Tags,
+		}
+	case a.Remove != nil:
+		return &action.RemoveFile{
+			Path:                 a.Remove.Path,
+			DataChange:           a.Remove.DataChange,
+			DeletionTimestamp:    a.Remove.DeletionTimestamp,
+			ExtendedFileMetadata: a.Remove.ExtendedFileMetadata,
+			PartitionValues:      a.Remove.PartitionValues,
+			Size:                 a.Remove.Size,
+			Tags:                 nil,
+		}
+	case a.MetaData != nil:
+		return &action.Metadata{
+			ID:               a.MetaData.Id,
+			Name:             stringValue(a.MetaData.Name),
+			Description:      stringValue(a.MetaData.Description),
+			Format:           action.Format{Provider: a.MetaData.Format.Provider, Options: a.MetaData.Format.Options},
+			SchemaString:     a.MetaData.SchemaString,
+			PartitionColumns: a.MetaData.PartitionColumns,
+			Configuration:    a.MetaData.Configuration,
+			CreatedTime:      a.MetaData.CreatedTime,
+		}
+	case a.Txn != nil:
+		return &action.SetTransaction{
+			AppID:       a.Txn.AppId,
+			Version:     a.Txn.Version,
+			LastUpdated: a.Txn.LastUpdated,
+		}
+	case a.Protocol != nil:
+		return &action.Protocol{
+			MinReaderVersion: a.Protocol.MinReaderVersion,
+			MinWriterVersion: a.Protocol.MinWriterVersion,
+		}
+	default:
+		return nil
+	}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func nonNilMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+func checkpointActions(snapshot *Snapshot) ([]*checkpointAction, error) {
+	var res []*checkpointAction
+	res = append(res, &checkpointAction{Protocol: &checkpointProtocol{
+		MinReaderVersion: snapshot.protocol.MinReaderVersion,
+		MinWriterVersion: snapshot.protocol.MinWriterVersion,
+	}})
+	meta := snapshot.metadata
+	res = append(res, &checkpointAction{MetaData: &checkpointMetadata{
+		Id:               meta.ID,
+		Name:             optionalString(meta.Name),
+		Description:      optionalString(meta.Description),
+		Format:           checkpointFormat{Provider: meta.Format.Provider, Options: nonNilMap(meta.Format.Options)},
+		SchemaString:     meta.SchemaString,
+		PartitionColumns: meta.PartitionColumns,
+		Configuration:    nonNilMap(meta.Configuration),
+		CreatedTime:      meta.CreatedTime,
+	}})
+	for _, txn := range snapshot.setTransactions() {
+		res = append(res, &checkpointAction{Txn: &checkpointTxn{
+			AppId:       txn.AppID,
+			Version:     txn.Version,
+			LastUpdated: txn.LastUpdated,
+		}})
+	}
+	for _, add := range snapshot.activeFiles {
+		res = append(res, &checkpointAction{Add: &checkpointAdd{
+			Path:             add.Path,
+			PartitionValues:  nonNilMap(add.PartitionValues),
+			Size:             add.Size,
+			ModificationTime: add.ModificationTime,
+			DataChange:       false, // checkpoint holds state, not changes
+			Stats:            optionalString(add.Stats),
+			Tags:             nonNilMap(add.Tags),
+		}})
+	}
+	tombstones, err := snapshot.tombstones()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get tombstones: %w", err)
+	}
+	for _, remove := range tombstones {
+		res = append(res, &checkpointAction{Remove: &checkpointRemove{
+			Path:                 remove.Path,
+			DeletionTimestamp:    remove.DeletionTimestamp,
+			DataChange:           false,
+			ExtendedFileMetadata: remove.ExtendedFileMetadata,
+			PartitionValues:      nonNilMap(remove.PartitionValues),
+			Size:                 remove.Size,
+		}})
+	}
+	return res, nil
+}
+
+// Checkpoint writes state of the snapshot into a single-part checkpoint and points `_last_checkpoint` to it.
+// Readers don't need to replay all the commits before the checkpoint.
+func (l *TableLog) Checkpoint(snapshot *Snapshot) error {
+	actions, err := checkpointActions(snapshot)
+	if err != nil {
+		return xerrors.Errorf("unable to collect actions: %w", err)
+	}
+
+	var buf bytes.Buffer
+	pw, err := writer.NewParquetWriterFromWriter(&buf, new(checkpointAction), 1)
+	if err != nil {
+		return xerrors.Errorf("unable to init parquet writer: %w", err)
+	}
+	for _, a := range actions {
+		if err := pw.Write(a); err != nil {
+			return xerrors.Errorf("unable to write action: %w", err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		return xerrors.Errorf("unable to finish parquet: %w", err)
+	}
+
+	path := CheckpointFileSingular(l.logPath, snapshot.Version())
+	if err := l.store.Write(path, buf.Bytes(), true); err != nil {
+		return xerrors.Errorf("unable to write checkpoint: %s: %w", path, err)
+	}
+
+	last, err := json.Marshal(CheckpointMetaData{Version: snapshot.Version(), Size: int64(len(actions)), Parts: nil})
+	if err != nil {
+		return xerrors.Errorf("unable to marshal last checkpoint: %w", err)
+	}
+	if err := l.store.Write(l.logPath+LastCheckpointPath, last, true); err != nil {
+		return xerrors.Errorf("unable to write last checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Commit writes actions as the given version of the log.
+// Returns store.ErrFileAlreadyExists if the version is already committed by someone else.
+func (l *TableLog) Commit(version int64, actions []action.Container) error {
+	var buf bytes.Buffer
+	for _, a := range actions {
+		line, err := a.JSON()
+		if err != nil {
+			return xerrors.Errorf("unable to serialize action: %w", err)
+		}
+		buf.WriteString(line)
+		buf.WriteString("\n")
+	}
+	if err := l.store.Write(DeltaFile(l.logPath, version), buf.Bytes(), false); err != nil {
+		if xerrors.Is(err, store.ErrFileAlreadyExists) {
+			return err
+		}
+		return xerrors.Errorf("unable to write version: %v: %w", version, err)
+	}
+	return nil
+}
@@ -93,7 +93,15 @@ func NewInitialSnapshot(path string, store store2.Store, cpReader CheckpointRead
 	}
 
 	var err error
-	s.state = new(snapshotState)
+	s.state = &snapshotState{
+		setTransactions:      nil,
+		activeFiles:          iter.FromSlice[*action.AddFile](),
+		tombstones:           iter.FromSlice[*action.RemoveFile](),
+		sizeInBytes:          0,
+		numOfFiles:           0,
+		numOfRemoves:         0,
+		numOfSetTransactions: 0,
+	}
 	s.activeFiles, err = s.loadActiveFiles()
 	if err != nil {
 		return nil, xerrors.Errorf("unable to load active files: %w", err)
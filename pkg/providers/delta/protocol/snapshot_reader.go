@@ -57,7 +57,7 @@ func (r *SnapshotReader) minRetentionTS() (int64, error) {
 		return 0, xerrors.Errorf("unable to get snapshot meta: %w", err)
 	}
 	// in milliseconds
-	tombstoneRetention, err := TombstoneRetentionProp.fromMetadata(metadata)
+	tombstoneRetention, err := TombstoneRetentionProp.FromMetadata(metadata)
 	if err != nil {
 		return 0, xerrors.Errorf("unable to get retention from table meta: %w", err)
 	}
@@ -164,7 +164,7 @@ func (r *SnapshotReader) logSegmentForVersion(startCheckpoint int64, versionToLo
 	}
 
 	if len(newFiles) == 0 && startCheckpoint <= 0 {
-		return nil, xerrors.Errorf("empty dir: %s", r.logStore.Root())
+		return nil, xerrors.Errorf("empty dir: %s: %w", r.logStore.Root(), store.ErrFileNotFound)
 	} else if len(newFiles) == 0 {
 		// The directory may be deleted and recreated and we may have stale state in our DeltaLog
 		// singleton, so try listing from the first version
@@ -250,7 +250,7 @@ func (r *SnapshotReader) segmentFromCheckpoint(
 	checkpoints []*store.FileMeta,
 ) (*LogSegment, error) {
 	newCheckpointVersion := latestCheckpoint.Version
-	newCheckpointPaths := set.New(latestCheckpoint.GetCorrespondingFiles(r.logStore.Root())...)
+	newCheckpointPaths := set.New(latestCheckpoint.GetCorrespondingFiles(r.logStore.Root() + "/_delta_log/")...)
 
 	deltasAfterCheckpoint := yslices.Filter(deltas, func(f *store.FileMeta) bool {
 		ver, err := LogVersion(f.Path())
@@ -341,6 +341,9 @@ func (r *SnapshotReader) updateInternal() (*Snapshot, error) {
 		r.currentSnapshot.Store(newSnapshot)
 		return newSnapshot, nil
 	}
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get log segment: %w", err)
+	}
 
 	if !cur.segment.equal(verSegment) {
 		newSnapshot, err := r.createSnapshot(verSegment, verSegment.LastCommitTS.UnixMilli())
@@ -359,8 +362,8 @@ func verifyVersions(versions []int64) error {
 	if len(versions) == 0 {
 		return nil
 	}
-	for i := versions[0]; i <= versions[len(versions)-1]; i++ {
-		if i != versions[i] {
+	for i, version := range versions {
+		if version != versions[0]+int64(i) {
 			return xerrors.Errorf("version not continuous: %v", versions)
 		}
 	}
@@ -50,7 +50,7 @@ type TableConfig[T any] struct {
 	FromString   func(s string) (T, error)
 }
 
-func (t *TableConfig[T]) fromMetadata(metadata *action.Metadata) (T, error) {
+func (t *TableConfig[T]) FromMetadata(metadata *action.Metadata) (T, error) {
 	v, ok := metadata.Configuration[t.Key]
 	if !ok {
 		v = t.DefaultValue
@@ -4,7 +4,9 @@ import (
 	"github.com/transferia/transferia/library/go/core/metrics"
 	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/coordinator"
 	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/middlewares"
 	"github.com/transferia/transferia/pkg/providers"
 	"github.com/transferia/transferia/pkg/util/gobwrapper"
 	"go.ytsaurus.tech/library/go/core/log"
@@ -17,19 +19,28 @@ func init() {
 		return new(DeltaSource)
 	}
 
+	destinationFactory := func() model.Destination {
+		return new(DeltaDestination)
+	}
+
 	gobwrapper.Register(new(DeltaSource))
+	gobwrapper.Register(new(DeltaDestination))
 	model.RegisterSource(ProviderType, sourceFactory)
+	model.RegisterDestination(ProviderType, destinationFactory)
 	abstract.RegisterProviderName(ProviderType, "Delta Lake")
+	providers.Register(ProviderType, New)
 }
 
 // To verify providers contract implementation
 var (
 	_ providers.Snapshot = (*Provider)(nil)
+	_ providers.Sinker   = (*Provider)(nil)
 )
 
 type Provider struct {
 	logger   log.Logger
 	registry metrics.Registry
+	cp       coordinator.Coordinator
 	transfer *model.Transfer
 }
 
@@ -45,3 +56,20 @@ func (p Provider) Storage() (abstract.Storage, error) {
 
 	return NewStorage(src, p.logger, p.registry)
 }
+
+func (p Provider) Sink(config middlewares.Config) (abstract.Sinker, error) {
+	dst, ok := p.transfer.Dst.(*DeltaDestination)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected target type: %T", p.transfer.Dst)
+	}
+	return NewSink(dst, p.logger, p.registry)
+}
+
+func New(lgr log.Logger, registry metrics.Registry, cp coordinator.Coordinator, transfer *model.Transfer) providers.Provider {
+	return &Provider{
+		logger:   lgr,
+		registry: registry,
+		cp:       cp,
+		transfer: transfer,
+	}
+}
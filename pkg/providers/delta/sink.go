@@ -0,0 +1,118 @@
+package delta
+
+import (
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/stats"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+var _ abstract.Sinker = (*Sink)(nil)
+
+// Sink writes every pushed batch of a table as a single version of the table delta log.
+// Data files are written first, so the commit makes all of them visible to readers atomically.
+type Sink struct {
+	cfg     *DeltaDestination
+	logger  log.Logger
+	metrics *stats.SinkerStats
+
+	tables map[abstract.TableID]*deltaTable
+}
+
+func (s *Sink) table(id abstract.TableID) (*deltaTable, error) {
+	if table, ok := s.tables[id]; ok {
+		return table, nil
+	}
+	table, err := newDeltaTable(id, s.cfg, s.logger)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to open table %s: %w", id.Fqtn(), err)
+	}
+	s.tables[id] = table
+	return table, nil
+}
+
+func (s *Sink) Push(items []abstract.ChangeItem) error {
+	batches := map[abstract.TableID][]abstract.ChangeItem{}
+	var order []abstract.TableID
+	flush := func(id abstract.TableID) error {
+		batch, ok := batches[id]
+		if !ok {
+			return nil
+		}
+		delete(batches, id)
+		table, err := s.table(id)
+		if err != nil {
+			return err
+		}
+		if err := table.write(batch); err != nil {
+			return xerrors.Errorf("unable to write batch of table %s: %w", id.Fqtn(), err)
+		}
+		s.metrics.Table(id.Fqtn(), "rows", len(batch))
+		return nil
+	}
+
+	for _, item := range items {
+		id := item.TableID()
+		switch item.Kind {
+		case abstract.InsertKind, abstract.UpdateKind, abstract.DeleteKind:
+			if _, ok := batches[id]; !ok {
+				order = append(order, id)
+			}
+			batches[id] = append(batches[id], item)
+		case abstract.TruncateTableKind:
+			if err := flush(id); err != nil {
+				return err
+			}
+			table, err := s.table(id)
+			if err != nil {
+				return err
+			}
+			if err := table.truncate(); err != nil {
+				return xerrors.Errorf("unable to truncate table %s: %w", id.Fqtn(), err)
+			}
+		case abstract.DropTableKind:
+			delete(batches, id)
+			table, err := s.table(id)
+			if err != nil {
+				return err
+			}
+			if err := table.drop(); err != nil {
+				return xerrors.Errorf("unable to drop table %s: %w", id.Fqtn(), err)
+			}
+			// cached log of dropped table is stale
+			delete(s.tables, id)
+		case abstract.InitShardedTableLoad, abstract.InitTableLoad, abstract.DoneTableLoad, abstract.DoneShardedTableLoad, abstract.SynchronizeKind:
+			// not needed for now
+		case abstract.DDLKind,
+			abstract.PgDDLKind,
+			abstract.MongoCreateKind,
+			abstract.MongoRenameKind,
+			abstract.MongoDropKind,
+			abstract.ChCreateTableKind:
+			s.logger.Warnf("kind: %s not supported, skip", item.Kind)
+		default:
+			return xerrors.Errorf("kind: %v not supported", item.Kind)
+		}
+	}
+
+	for _, id := range order {
+		if err := flush(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Sink) Close() error {
+	return nil
+}
+
+func NewSink(cfg *DeltaDestination, lgr log.Logger, registry metrics.Registry) (*Sink, error) {
+	return &Sink{
+		cfg:     cfg,
+		logger:  lgr,
+		metrics: stats.NewSinkerStats(registry),
+		tables:  map[abstract.TableID]*deltaTable{},
+	}, nil
+}
@@ -0,0 +1,350 @@
+package delta
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/spf13/cast"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/providers/delta/types"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+// fileStats is a statistics of data file in the format of delta protocol, readers use it for data skipping
+type fileStats struct {
+	NumRecords int64          `json:"numRecords"`
+	MinValues  map[string]any `json:"minValues,omitempty"`
+	MaxValues  map[string]any `json:"maxValues,omitempty"`
+	NullCount  map[string]any `json:"nullCount,omitempty"`
+}
+
+// deltaValue converts value of column into go representation of delta field type:
+// bool, int32, int64, float32, float64, string or []byte. Dates are days since epoch, timestamps are microseconds.
+func deltaValue(dataType types.DataType, col abstract.ColSchema, val any) (any, error) {
+	if val == nil {
+		return nil, nil
+	}
+	switch dataType.(type) {
+	case *types.BooleanType:
+		return cast.ToBoolE(val)
+	case *types.ByteType, *types.ShortType, *types.IntegerType:
+		v, err := cast.ToInt64E(val)
+		if err != nil {
+			return nil, err
+		}
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return nil, xerrors.Errorf("value %d is out of %s range", v, dataType.Name())
+		}
+		return int32(v), nil
+	case *types.LongType:
+		switch v := val.(type) {
+		case uint64:
+			if v > math.MaxInt64 {
+				return nil, xerrors.Errorf("value %d is out of long range", v)
+			}
+			return int64(v), nil
+		case time.Duration:
+			return v.Microseconds(), nil
+		}
+		return cast.ToInt64E(val)
+	case *types.FloatType:
+		return cast.ToFloat32E(val)
+	case *types.DoubleType:
+		return cast.ToFloat64E(val)
+	case *types.StringType:
+		if schema.Type(col.DataType) == schema.TypeAny {
+			data, err := json.Marshal(val)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to marshal value: %w", err)
+			}
+			return string(data), nil
+		}
+		switch v := val.(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	case *types.BinaryType:
+		switch v := val.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		default:
+			return nil, xerrors.Errorf("unexpected value type for binary: %T", val)
+		}
+	case *types.DateType:
+		t, ok := val.(time.Time)
+		if !ok {
+			return nil, xerrors.Errorf("unexpected value type for date: %T", val)
+		}
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return int32(midnight.Unix() / (24 * 60 * 60)), nil
+	case *types.TimestampType:
+		t, ok := val.(time.Time)
+		if !ok {
+			return nil, xerrors.Errorf("unexpected value type for timestamp: %T", val)
+		}
+		return t.UnixMicro(), nil
+	default:
+		return nil, xerrors.Errorf("unsupported field type: %s", dataType.Name())
+	}
+}
+
+func parquetNode(dataType types.DataType) (parquet.Node, error) {
+	switch dataType.(type) {
+	case *types.BooleanType:
+		return parquet.Leaf(parquet.BooleanType), nil
+	case *types.ByteType:
+		return parquet.Int(8), nil
+	case *types.ShortType:
+		return parquet.Int(16), nil
+	case *types.IntegerType:
+		return parquet.Int(32), nil
+	case *types.LongType:
+		return parquet.Int(64), nil
+	case *types.FloatType:
+		return parquet.Leaf(parquet.FloatType), nil
+	case *types.DoubleType:
+		return parquet.Leaf(parquet.DoubleType), nil
+	case *types.StringType:
+		return parquet.String(), nil
+	case *types.BinaryType:
+		return parquet.Leaf(parquet.ByteArrayType), nil
+	case *types.DateType:
+		return parquet.Date(), nil
+	case *types.TimestampType:
+		return parquet.Timestamp(parquet.Microsecond), nil
+	default:
+		return nil, xerrors.Errorf("unsupported field type: %s", dataType.Name())
+	}
+}
+
+func parquetValue(val any) parquet.Value {
+	switch v := val.(type) {
+	case bool:
+		return parquet.BooleanValue(v)
+	case int32:
+		return parquet.Int32Value(v)
+	case int64:
+		return parquet.Int64Value(v)
+	case float32:
+		return parquet.FloatValue(v)
+	case float64:
+		return parquet.DoubleValue(v)
+	case string:
+		return parquet.ByteArrayValue([]byte(v))
+	case []byte:
+		return parquet.ByteArrayValue(v)
+	default:
+		return parquet.ValueOf(nil)
+	}
+}
+
+// goValue is the reverse of parquetValue, it returns value in the same representation as deltaValue
+func goValue(dataType types.DataType, val parquet.Value) any {
+	if val.IsNull() {
+		return nil
+	}
+	switch dataType.(type) {
+	case *types.BooleanType:
+		return val.Boolean()
+	case *types.ByteType, *types.ShortType, *types.IntegerType, *types.DateType:
+		return val.Int32()
+	case *types.LongType, *types.TimestampType:
+		return val.Int64()
+	case *types.FloatType:
+		return val.Float()
+	case *types.DoubleType:
+		return val.Double()
+	case *types.StringType:
+		return string(val.ByteArray())
+	default:
+		return bytes.Clone(val.ByteArray())
+	}
+}
+
+// statsValue returns representation of value in file statistics, nil if column has no min & max stats
+func statsValue(dataType types.DataType, val any) any {
+	switch dataType.(type) {
+	case *types.DateType:
+		return time.Unix(int64(val.(int32))*24*60*60, 0).UTC().Format(time.DateOnly)
+	case *types.TimestampType:
+		return time.UnixMicro(val.(int64)).UTC().Format("2006-01-02T15:04:05.000Z07:00")
+	case *types.BooleanType, *types.BinaryType:
+		return nil
+	default:
+		return val
+	}
+}
+
+// less compares values of the same orderable delta type
+func less(a, b any) bool {
+	switch v := a.(type) {
+	case int32:
+		return v < b.(int32)
+	case int64:
+		return v < b.(int64)
+	case float32:
+		return v < b.(float32)
+	case float64:
+		return v < b.(float64)
+	case string:
+		return v < b.(string)
+	default:
+		return false
+	}
+}
+
+// finite reports whether value can be used as min or max, json has no representation of NaN & infinities
+func finite(val any) bool {
+	switch v := val.(type) {
+	case float32:
+		return !math.IsNaN(float64(v)) && !math.IsInf(float64(v), 0)
+	case float64:
+		return !math.IsNaN(v) && !math.IsInf(v, 0)
+	default:
+		return true
+	}
+}
+
+func buildStats(fields []*types.StructField, rows []map[string]any) *fileStats {
+	stats := &fileStats{
+		NumRecords: int64(len(rows)),
+		MinValues:  map[string]any{},
+		MaxValues:  map[string]any{},
+		NullCount:  map[string]any{},
+	}
+	for _, field := range fields {
+		var minVal, maxVal any
+		nulls := 0
+		for _, row := range rows {
+			val := row[field.Name]
+			if val == nil {
+				nulls++
+				continue
+			}
+			if !finite(val) {
+				continue
+			}
+			if minVal == nil || less(val, minVal) {
+				minVal = val
+			}
+			if maxVal == nil || less(maxVal, val) {
+				maxVal = val
+			}
+		}
+		stats.NullCount[field.Name] = nulls
+		if minVal == nil || statsValue(field.DataType, minVal) == nil {
+			continue
+		}
+		stats.MinValues[field.Name] = statsValue(field.DataType, minVal)
+		stats.MaxValues[field.Name] = statsValue(field.DataType, maxVal)
+	}
+	return stats
+}
+
+// writeDataFile serializes rows of delta values into parquet file
+func writeDataFile(structType *types.StructType, rows []map[string]any) (result []byte, stats *fileStats, err error) {
+	// parquet library panics instead of returning errors
+	defer func() {
+		if r := recover(); r != nil {
+			err = xerrors.Errorf("was panic, recovered value: %v", r)
+		}
+	}()
+
+	group := parquet.Group{}
+	for _, field := range structType.Fields {
+		node, err := parquetNode(field.DataType)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("unable to build parquet node for %s: %w", field.Name, err)
+		}
+		if field.Nullable {
+			node = parquet.Optional(node)
+		}
+		group[field.Name] = node
+	}
+	pqSchema := parquet.NewSchema("spark_schema", group)
+
+	pqRows := make([]parquet.Row, len(rows))
+	for i, row := range rows {
+		pqRow := make(parquet.Row, 0, len(structType.Fields))
+		// columns of parquet group are sorted by name
+		for idx, pqField := range pqSchema.Fields() {
+			field, _ := structType.Get(pqField.Name())
+			val := row[field.Name]
+			defLevel := 0
+			if val == nil {
+				if !field.Nullable {
+					return nil, nil, xerrors.Errorf("non-nullable field %s is null", field.Name)
+				}
+			} else if field.Nullable {
+				defLevel = 1
+			}
+			pqRow = append(pqRow, parquetValue(val).Level(0, defLevel, idx))
+		}
+		pqRows[i] = pqRow
+	}
+
+	var buf bytes.Buffer
+	writer := parquet.NewGenericWriter[struct{}](&buf, pqSchema, parquet.Compression(&parquet.Snappy))
+	if _, err := writer.WriteRows(pqRows); err != nil {
+		return nil, nil, xerrors.Errorf("unable to write rows: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, nil, xerrors.Errorf("unable to close writer: %w", err)
+	}
+	return buf.Bytes(), buildStats(structType.Fields, rows), nil
+}
+
+// readDataFile reads rows of parquet file written by writeDataFile, columns missing in the file are null
+func readDataFile(structType *types.StructType, data []byte) (result []map[string]any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = xerrors.Errorf("was panic, recovered value: %v", r)
+		}
+	}()
+
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to open parquet file: %w", err)
+	}
+	fileFields := file.Schema().Fields()
+	reader := parquet.NewReader(file)
+	defer reader.Close()
+
+	rows := make([]parquet.Row, 128)
+	for {
+		n, err := reader.ReadRows(rows)
+		for _, row := range rows[:n] {
+			res := make(map[string]any, len(structType.Fields))
+			for _, field := range structType.Fields {
+				res[field.Name] = nil
+			}
+			for _, val := range row {
+				name := fileFields[val.Column()].Name()
+				field, err := structType.Get(name)
+				if err != nil {
+					continue // column is not known by current schema
+				}
+				res[name] = goValue(field.DataType, val)
+			}
+			result = append(result, res)
+		}
+		if err != nil {
+			if xerrors.Is(err, io.EOF) {
+				return result, nil
+			}
+			return nil, xerrors.Errorf("unable to read rows: %w", err)
+		}
+	}
+}
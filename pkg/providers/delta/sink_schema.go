@@ -0,0 +1,73 @@
+package delta
+
+import (
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/providers/delta/types"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+// deltaType maps column into delta primitive type
+func deltaType(col abstract.ColSchema) (types.DataType, error) {
+	switch schema.Type(col.DataType) {
+	case schema.TypeInt8:
+		return new(types.ByteType), nil
+	case schema.TypeInt16, schema.TypeUint8:
+		return new(types.ShortType), nil
+	case schema.TypeInt32, schema.TypeUint16:
+		return new(types.IntegerType), nil
+	case schema.TypeInt64, schema.TypeUint32, schema.TypeUint64:
+		// delta has no unsigned types, uint64 values above long range are rejected on write
+		return new(types.LongType), nil
+	case schema.TypeInterval:
+		// interval is stored as a number of microseconds
+		return new(types.LongType), nil
+	case schema.TypeFloat32:
+		return new(types.FloatType), nil
+	case schema.TypeFloat64:
+		return new(types.DoubleType), nil
+	case schema.TypeBoolean:
+		return new(types.BooleanType), nil
+	case schema.TypeString:
+		return new(types.StringType), nil
+	case schema.TypeBytes:
+		return new(types.BinaryType), nil
+	case schema.TypeDate:
+		return new(types.DateType), nil
+	case schema.TypeDatetime, schema.TypeTimestamp:
+		return new(types.TimestampType), nil
+	case schema.TypeAny:
+		// any is stored as json string
+		return new(types.StringType), nil
+	default:
+		return nil, xerrors.Errorf("column %s has unsupported type: %s", col.ColumnName, col.DataType)
+	}
+}
+
+// evolveSchema adds new columns to the existing table schema, columns missing in the source are kept.
+// Returns nil if table schema is not changed. Changing type of existing column is not supported.
+func evolveSchema(existing *types.StructType, columns []abstract.ColSchema) (*types.StructType, error) {
+	fields := existing.GetFields()
+	changed := false
+	for _, col := range columns {
+		dataType, err := deltaType(col)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to map column type: %w", err)
+		}
+		if field, err := existing.Get(col.ColumnName); err == nil {
+			if field.DataType.Name() != dataType.Name() {
+				return nil, xerrors.Errorf("type of column %s is changed from %s to %s, which is not supported",
+					col.ColumnName, field.DataType.Name(), dataType.Name())
+			}
+			continue
+		}
+		// delta readers fail on missing values of non-nullable columns, so only key columns of a new table are required
+		nullable := !col.IsKey() || len(existing.Fields) > 0
+		fields = append(fields, types.NewStructField(col.ColumnName, dataType, nullable))
+		changed = true
+	}
+	if !changed {
+		return nil, nil
+	}
+	return types.NewStructType(fields), nil
+}
@@ -0,0 +1,599 @@
+package delta
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/providers/delta/action"
+	"github.com/transferia/transferia/pkg/providers/delta/protocol"
+	"github.com/transferia/transferia/pkg/providers/delta/store"
+	"github.com/transferia/transferia/pkg/providers/delta/types"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const (
+	// maxCommitAttempts limits retries of commit, which lost the race for the log version to a concurrent writer
+	maxCommitAttempts = 5
+	engineInfo        = "transferia"
+)
+
+// tableBatch is a set of changes of a table, which is committed as a single version of delta log
+type tableBatch struct {
+	columns []abstract.ColSchema // union of columns of all items
+
+	order   []string                  // keys of rows in order of arrival
+	rows    map[string]map[string]any // last state of rows by key
+	touched map[string]map[string]any // key values of all changed keys, rows with these keys are removed from existing files
+}
+
+func (b *tableBatch) hasChanges() bool {
+	return len(b.rows) > 0 || len(b.touched) > 0
+}
+
+func (b *tableBatch) upsert(key string, row map[string]any) {
+	if _, ok := b.rows[key]; !ok {
+		b.order = append(b.order, key)
+	}
+	b.rows[key] = row
+}
+
+func (b *tableBatch) remove(key string) {
+	delete(b.rows, key)
+}
+
+// deltaTable writes commits into delta log of a single table
+type deltaTable struct {
+	id     abstract.TableID
+	cfg    *DeltaDestination
+	store  store.Store
+	log    *protocol.TableLog
+	logger log.Logger
+}
+
+func newDeltaTable(id abstract.TableID, cfg *DeltaDestination, lgr log.Logger) (*deltaTable, error) {
+	st, err := store.New(cfg.StoreConfig(id))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to init store: %w", err)
+	}
+	tableLog, err := protocol.NewTableLog(st.Root(), st)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to init table log: %w", err)
+	}
+	return &deltaTable{
+		id:     id,
+		cfg:    cfg,
+		store:  st,
+		log:    tableLog,
+		logger: lgr,
+	}, nil
+}
+
+func (t *deltaTable) path(name string) string {
+	return strings.TrimRight(t.store.Root(), "/") + "/" + name
+}
+
+// keyString is a canonical representation of key values, which is the same for values of changes & values read from files
+func keyString(keyNames []string, row map[string]any) (string, error) {
+	values := make([]any, len(keyNames))
+	for i, name := range keyNames {
+		values[i] = row[name]
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", xerrors.Errorf("unable to marshal key: %w", err)
+	}
+	return string(data), nil
+}
+
+func (t *deltaTable) keyNames(columns []abstract.ColSchema) []string {
+	var keys []string
+	for _, col := range columns {
+		if col.IsKey() {
+			keys = append(keys, col.ColumnName)
+		}
+	}
+	return keys
+}
+
+// rowValues converts values of item into delta values, only columns known by struct type are converted
+func rowValues(structType *types.StructType, columns []abstract.ColSchema, names []string, values []any) (map[string]any, error) {
+	colByName := make(map[string]abstract.ColSchema, len(columns))
+	for _, col := range columns {
+		colByName[col.ColumnName] = col
+	}
+	row := make(map[string]any, len(names))
+	for i, name := range names {
+		field, err := structType.Get(name)
+		if err != nil {
+			return nil, xerrors.Errorf("column %s is missing in table schema: %w", name, err)
+		}
+		val, err := deltaValue(field.DataType, colByName[name], values[i])
+		if err != nil {
+			return nil, xerrors.Errorf("unable to convert value of column %s: %w", name, err)
+		}
+		row[name] = val
+	}
+	return row, nil
+}
+
+func oldKeyValues(item *abstract.ChangeItem) ([]string, []any) {
+	if len(item.OldKeys.KeyNames) > 0 {
+		return item.OldKeys.KeyNames, item.OldKeys.KeyValues
+	}
+	keys := item.KeysAsMap()
+	names := make([]string, 0, len(keys))
+	values := make([]any, 0, len(keys))
+	for name, val := range keys {
+		names = append(names, name)
+		values = append(values, val)
+	}
+	return names, values
+}
+
+// buildBatch converts items into rows of given table schema, in merge on read mode rows are deduplicated by key
+func (t *deltaTable) buildBatch(structType *types.StructType, columns []abstract.ColSchema, items []abstract.ChangeItem) (*tableBatch, error) {
+	batch := &tableBatch{
+		columns: columns,
+		order:   nil,
+		rows:    map[string]map[string]any{},
+		touched: map[string]map[string]any{},
+	}
+	keyNames := t.keyNames(columns)
+	merge := t.cfg.WriteMode == WriteModeMergeOnRead && len(keyNames) > 0
+
+	for i := range items {
+		item := &items[i]
+		if item.Kind != abstract.InsertKind && t.cfg.WriteMode != WriteModeMergeOnRead {
+			return nil, xerrors.Errorf("%s of table %s requires %s write mode", item.Kind, t.id.Fqtn(), WriteModeMergeOnRead)
+		}
+		if item.Kind != abstract.InsertKind && len(keyNames) == 0 {
+			return nil, xerrors.Errorf("%s of table %s without primary key is not supported", item.Kind, t.id.Fqtn())
+		}
+		if item.Kind == abstract.UpdateKind && item.IsToasted() {
+			return nil, xerrors.Errorf("update of table %s has missing values of columns, full row images are required", t.id.Fqtn())
+		}
+
+		var row map[string]any
+		if item.Kind != abstract.DeleteKind {
+			var err error
+			row, err = rowValues(structType, item.TableSchema.Columns(), item.ColumnNames, item.ColumnValues)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to build row: %w", err)
+			}
+		}
+		if !merge {
+			batch.upsert(fmt.Sprint(len(batch.order)), row)
+			continue
+		}
+
+		if item.Kind != abstract.InsertKind {
+			names, values := oldKeyValues(item)
+			oldKey, err := rowValues(structType, item.TableSchema.Columns(), names, values)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to build old key: %w", err)
+			}
+			oldKeyStr, err := keyString(keyNames, oldKey)
+			if err != nil {
+				return nil, err
+			}
+			batch.touched[oldKeyStr] = oldKey
+			batch.remove(oldKeyStr)
+		}
+		if row != nil {
+			key, err := keyString(keyNames, row)
+			if err != nil {
+				return nil, err
+			}
+			batch.touched[key] = row
+			batch.upsert(key, row)
+		}
+	}
+	return batch, nil
+}
+
+// mayContain uses statistics of file to check if it may contain any of keys
+func mayContain(add *action.AddFile, keyFields []*types.StructField, keys []map[string]any) bool {
+	if add.Stats == "" {
+		return true
+	}
+	var stats struct {
+		MinValues map[string]any `json:"minValues"`
+		MaxValues map[string]any `json:"maxValues"`
+	}
+	decoder := json.NewDecoder(strings.NewReader(add.Stats))
+	decoder.UseNumber()
+	if err := decoder.Decode(&stats); err != nil {
+		return true
+	}
+	for _, key := range keys {
+		inRange := true
+		for _, field := range keyFields {
+			if !inStatsRange(key[field.Name], stats.MinValues[field.Name], stats.MaxValues[field.Name]) {
+				inRange = false
+				break
+			}
+		}
+		if inRange {
+			return true
+		}
+	}
+	return false
+}
+
+func inStatsRange(val any, minVal any, maxVal any) bool {
+	switch v := val.(type) {
+	case int32:
+		return inIntRange(int64(v), minVal, maxVal)
+	case int64:
+		return inIntRange(v, minVal, maxVal)
+	case string:
+		minStr, ok1 := minVal.(string)
+		maxStr, ok2 := maxVal.(string)
+		if !ok1 || !ok2 {
+			return true
+		}
+		return minStr <= v && v <= maxStr
+	default:
+		return true
+	}
+}
+
+func inIntRange(val int64, minVal any, maxVal any) bool {
+	minNum, ok1 := minVal.(json.Number)
+	maxNum, ok2 := maxVal.(json.Number)
+	if !ok1 || !ok2 {
+		return true
+	}
+	minInt, err1 := minNum.Int64()
+	maxInt, err2 := maxNum.Int64()
+	if err1 != nil || err2 != nil {
+		return true
+	}
+	return minInt <= val && val <= maxInt
+}
+
+// newAdd writes data file & returns action to add it
+func (t *deltaTable) newAdd(structType *types.StructType, rows []map[string]any) (*action.AddFile, error) {
+	data, stats, err := writeDataFile(structType, rows)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to write data file: %w", err)
+	}
+	statsJSON, err := json.Marshal(stats)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to marshal stats: %w", err)
+	}
+	name := fmt.Sprintf("part-00000-%s-c000.snappy.parquet", uuid.New().String())
+	if err := t.store.Write(t.path(name), data, true); err != nil {
+		return nil, xerrors.Errorf("unable to upload data file: %w", err)
+	}
+	return &action.AddFile{
+		Path:             name,
+		DataChange:       true,
+		PartitionValues:  map[string]string{},
+		Size:             int64(len(data)),
+		ModificationTime: time.Now().UnixMilli(),
+		Stats:            string(statsJSON),
+		Tags:             nil,
+	}, nil
+}
+
+func newRemove(add *action.AddFile) *action.RemoveFile {
+	now := time.Now().UnixMilli()
+	size := add.Size
+	return &action.RemoveFile{
+		Path:                 add.Path,
+		DataChange:           true,
+		DeletionTimestamp:    &now,
+		ExtendedFileMetadata: true,
+		PartitionValues:      add.PartitionValues,
+		Size:                 &size,
+		Tags:                 nil,
+	}
+}
+
+// rewrite removes rows with touched keys from existing files of snapshot,
+// returns remove actions of changed files & add actions of their filtered copies
+func (t *deltaTable) rewrite(snapshot *protocol.Snapshot, structType *types.StructType, batch *tableBatch) ([]action.Container, error) {
+	keyNames := t.keyNames(batch.columns)
+	keyFields := make([]*types.StructField, 0, len(keyNames))
+	for _, name := range keyNames {
+		field, err := structType.Get(name)
+		if err != nil {
+			return nil, xerrors.Errorf("key column %s is missing in table schema: %w", name, err)
+		}
+		keyFields = append(keyFields, field)
+	}
+	keys := make([]map[string]any, 0, len(batch.touched))
+	for _, key := range batch.touched {
+		keys = append(keys, key)
+	}
+
+	files, err := snapshot.AllFiles()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to list files: %w", err)
+	}
+	var result []action.Container
+	for _, file := range files {
+		if !mayContain(file, keyFields, keys) {
+			continue
+		}
+		data, err := t.store.ReadAll(t.path(file.Path))
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read data file %s: %w", file.Path, err)
+		}
+		rows, err := readDataFile(structType, data)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to parse data file %s: %w", file.Path, err)
+		}
+		kept := make([]map[string]any, 0, len(rows))
+		for _, row := range rows {
+			key, err := keyString(keyNames, row)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := batch.touched[key]; !ok {
+				kept = append(kept, row)
+			}
+		}
+		if len(kept) == len(rows) {
+			continue
+		}
+		result = append(result, newRemove(file))
+		if len(kept) > 0 {
+			add, err := t.newAdd(structType, kept)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to rewrite data file %s: %w", file.Path, err)
+			}
+			result = append(result, add)
+		}
+	}
+	return result, nil
+}
+
+// tableColumns returns union of columns of all items in order of appearance
+func tableColumns(items []abstract.ChangeItem) []abstract.ColSchema {
+	var result []abstract.ColSchema
+	seen := map[string]bool{}
+	var lastSchema *abstract.TableSchema
+	for _, item := range items {
+		if item.TableSchema == lastSchema {
+			continue
+		}
+		lastSchema = item.TableSchema
+		for _, col := range item.TableSchema.Columns() {
+			if !seen[col.ColumnName] {
+				seen[col.ColumnName] = true
+				result = append(result, col)
+			}
+		}
+	}
+	return result
+}
+
+func (t *deltaTable) commitInfo(operation string, readVersion int64, blindAppend bool, params map[string]string) *action.CommitInfo {
+	engine := engineInfo
+	info := &action.CommitInfo{
+		Version:             nil,
+		Timestamp:           time.Now().UnixMilli(),
+		UserID:              nil,
+		UserName:            nil,
+		Operation:           operation,
+		OperationParameters: params,
+		Job:                 nil,
+		Notebook:            nil,
+		ClusterID:           nil,
+		ReadVersion:         nil,
+		IsolationLevel:      nil,
+		IsBlindAppend:       &blindAppend,
+		OperationMetrics:    nil,
+		UserMetadata:        nil,
+		EngineInfo:          &engine,
+	}
+	if readVersion >= 0 {
+		info.ReadVersion = &readVersion
+	}
+	return info
+}
+
+// newMetadata returns metadata of a new table or a copy of existing metadata with evolved schema
+func (t *deltaTable) newMetadata(snapshot *protocol.Snapshot, structType *types.StructType) (*action.Metadata, error) {
+	schemaString, err := types.ToJSON(structType)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to serialize schema: %w", err)
+	}
+	if snapshot.Version() < 0 {
+		meta := action.DefaultMetadata()
+		meta.Name = t.id.Name
+		meta.SchemaString = schemaString
+		meta.PartitionColumns = []string{}
+		if t.cfg.CheckpointInterval > 0 {
+			meta.Configuration[protocol.DeltaConfigCheckpointInterval.Key] = fmt.Sprint(t.cfg.CheckpointInterval)
+		}
+		return meta, nil
+	}
+	current, err := snapshot.Metadata()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get metadata: %w", err)
+	}
+	meta := *current
+	meta.SchemaString = schemaString
+	return &meta, nil
+}
+
+// commit writes actions as the next version of log and makes checkpoint if it's time to
+func (t *deltaTable) commit(snapshot *protocol.Snapshot, actions []action.Container) error {
+	version := snapshot.Version() + 1
+	if err := t.log.Commit(version, actions); err != nil {
+		return err
+	}
+	updated, err := t.log.Update()
+	if err != nil {
+		return xerrors.Errorf("unable to load committed version: %w", err)
+	}
+	meta, err := updated.Metadata()
+	if err != nil {
+		return xerrors.Errorf("unable to get metadata: %w", err)
+	}
+	interval := t.cfg.CheckpointInterval
+	if interval == 0 {
+		interval, err = protocol.DeltaConfigCheckpointInterval.FromMetadata(meta)
+		if err != nil {
+			return xerrors.Errorf("unable to get checkpoint interval: %w", err)
+		}
+	}
+	if interval > 0 && version > 0 && version%int64(interval) == 0 {
+		if err := t.log.Checkpoint(updated); err != nil {
+			// checkpoint is an optimization for readers, table is consistent without it
+			t.logger.Warn("unable to write checkpoint", log.String("table", t.id.Fqtn()), log.Int64("version", version), log.Error(err))
+		}
+	}
+	return nil
+}
+
+// withRetries runs attempt against the latest snapshot until it's committed without conflicts.
+// Files written by failed attempt are deleted.
+func (t *deltaTable) withRetries(attempt func(snapshot *protocol.Snapshot) ([]action.Container, error)) error {
+	for i := 0; i < maxCommitAttempts; i++ {
+		snapshot, err := t.log.Update()
+		if err != nil {
+			return xerrors.Errorf("unable to load snapshot: %w", err)
+		}
+		actions, err := attempt(snapshot)
+		if err != nil {
+			return err
+		}
+		if len(actions) == 0 {
+			return nil
+		}
+		err = t.commit(snapshot, actions)
+		if err == nil {
+			return nil
+		}
+		if !xerrors.Is(err, store.ErrFileAlreadyExists) {
+			return xerrors.Errorf("unable to commit version %d: %w", snapshot.Version()+1, err)
+		}
+		t.logger.Info("version of delta log is committed concurrently, retry", log.String("table", t.id.Fqtn()), log.Int64("version", snapshot.Version()+1))
+		for _, a := range actions {
+			if add, ok := a.(*action.AddFile); ok {
+				if err := t.store.Delete(t.path(add.Path)); err != nil {
+					t.logger.Warn("unable to delete orphan data file", log.String("path", add.Path), log.Error(err))
+				}
+			}
+		}
+	}
+	return xerrors.Errorf("unable to commit into table %s after %d attempts", t.id.Fqtn(), maxCommitAttempts)
+}
+
+// write commits row items as a single version of the log
+func (t *deltaTable) write(items []abstract.ChangeItem) error {
+	columns := tableColumns(items)
+	return t.withRetries(func(snapshot *protocol.Snapshot) ([]action.Container, error) {
+		current, err := snapshot.Metadata()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to get metadata: %w", err)
+		}
+		structType, err := current.Schema()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to parse table schema: %w", err)
+		}
+		if snapshot.Version() < 0 {
+			structType = types.NewStructType(nil)
+		}
+
+		var actions []action.Container
+		evolved, err := evolveSchema(structType, columns)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to evolve schema of table %s: %w", t.id.Fqtn(), err)
+		}
+		if evolved != nil {
+			structType = evolved
+			if snapshot.Version() < 0 {
+				actions = append(actions, action.DefaultProtocol())
+			}
+			meta, err := t.newMetadata(snapshot, structType)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to build metadata: %w", err)
+			}
+			actions = append(actions, meta)
+		}
+
+		batch, err := t.buildBatch(structType, columns, items)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to build batch: %w", err)
+		}
+		if !batch.hasChanges() {
+			return nil, nil
+		}
+
+		removes := 0
+		if len(batch.touched) > 0 && snapshot.Version() >= 0 {
+			rewritten, err := t.rewrite(snapshot, structType, batch)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to remove changed rows: %w", err)
+			}
+			for _, a := range rewritten {
+				if _, ok := a.(*action.RemoveFile); ok {
+					removes++
+				}
+			}
+			actions = append(actions, rewritten...)
+		}
+		if len(batch.rows) > 0 {
+			rows := make([]map[string]any, 0, len(batch.rows))
+			for _, key := range batch.order {
+				if row, ok := batch.rows[key]; ok {
+					rows = append(rows, row)
+					// key may be in order twice, if it's deleted & inserted again
+					delete(batch.rows, key)
+				}
+			}
+			add, err := t.newAdd(structType, rows)
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, add)
+		}
+
+		operation, params := "WRITE", map[string]string{"mode": "Append", "partitionBy": "[]"}
+		if removes > 0 {
+			operation, params = "MERGE", map[string]string{"predicate": "primary key"}
+		}
+		actions = append(actions, t.commitInfo(operation, snapshot.Version(), removes == 0, params))
+		return actions, nil
+	})
+}
+
+// truncate commits removal of all files of the table
+func (t *deltaTable) truncate() error {
+	return t.withRetries(func(snapshot *protocol.Snapshot) ([]action.Container, error) {
+		if snapshot.Version() < 0 {
+			return nil, nil
+		}
+		files, err := snapshot.AllFiles()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to list files: %w", err)
+		}
+		if len(files) == 0 {
+			return nil, nil
+		}
+		actions := make([]action.Container, 0, len(files)+1)
+		for _, file := range files {
+			actions = append(actions, newRemove(file))
+		}
+		actions = append(actions, t.commitInfo("DELETE", snapshot.Version(), false, map[string]string{"predicate": "[]"}))
+		return actions, nil
+	})
+}
+
+// drop deletes all files of the table including log
+func (t *deltaTable) drop() error {
+	if err := t.store.Delete(strings.TrimRight(t.store.Root(), "/") + "/"); err != nil {
+		return xerrors.Errorf("unable to delete files of table %s: %w", t.id.Fqtn(), err)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package delta
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/providers/delta/action"
+	"github.com/transferia/transferia/pkg/providers/delta/protocol"
+	"github.com/transferia/transferia/pkg/providers/delta/store"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+var testColumns = []abstract.ColSchema{
+	{ColumnName: "id", DataType: schema.TypeInt32.String(), PrimaryKey: true},
+	{ColumnName: "name", DataType: schema.TypeString.String()},
+	{ColumnName: "created_at", DataType: schema.TypeTimestamp.String()},
+}
+
+func makeItem(kind abstract.Kind, columns []abstract.ColSchema, values ...any) abstract.ChangeItem {
+	names := make([]string, len(values))
+	for i := range values {
+		names[i] = columns[i].ColumnName
+	}
+	return abstract.ChangeItem{
+		Kind:         kind,
+		Schema:       "public",
+		Table:        "users",
+		ColumnNames:  names,
+		ColumnValues: values,
+		TableSchema:  abstract.NewTableSchema(columns),
+	}
+}
+
+func makeDelete(id any) abstract.ChangeItem {
+	item := makeItem(abstract.DeleteKind, testColumns)
+	item.OldKeys = abstract.OldKeysType{KeyNames: []string{"id"}, KeyTypes: nil, KeyValues: []any{id}}
+	return item
+}
+
+func newTestSink(t *testing.T, mode WriteMode, checkpointInterval int) (*Sink, *DeltaDestination) {
+	dst := &DeltaDestination{
+		PathPrefix:         t.TempDir(),
+		WriteMode:          mode,
+		CheckpointInterval: checkpointInterval,
+	}
+	dst.WithDefaults()
+	require.NoError(t, dst.Validate())
+	sink, err := NewSink(dst, logger.Log, solomon.NewRegistry(solomon.NewRegistryOpts()))
+	require.NoError(t, err)
+	return sink, dst
+}
+
+// readTable reads the latest snapshot of the table with a fresh log, like an independent reader does
+func readTable(t *testing.T, dst *DeltaDestination) (*protocol.Snapshot, []map[string]any) {
+	st := store.NewStoreLocal(&store.LocalConfig{Path: dst.TablePath(*abstract.NewTableID("public", "users"))})
+	table, err := protocol.NewTableLog(st.Root(), st)
+	require.NoError(t, err)
+	snapshot, err := table.Snapshot()
+	require.NoError(t, err)
+	meta, err := snapshot.Metadata()
+	require.NoError(t, err)
+	structType, err := meta.Schema()
+	require.NoError(t, err)
+
+	files, err := snapshot.AllFiles()
+	require.NoError(t, err)
+	var rows []map[string]any
+	for _, file := range files {
+		data, err := st.ReadAll(st.Root() + "/" + file.Path)
+		require.NoError(t, err)
+		fileRows, err := readDataFile(structType, data)
+		require.NoError(t, err)
+		rows = append(rows, fileRows...)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i]["id"].(int32) < rows[j]["id"].(int32)
+	})
+	return snapshot, rows
+}
+
+func readCommit(t *testing.T, dst *DeltaDestination, version int64) []action.Container {
+	st := store.NewStoreLocal(&store.LocalConfig{Path: dst.TablePath(*abstract.NewTableID("public", "users"))})
+	lines, err := st.Read(protocol.DeltaFile(st.Root()+"/_delta_log/", version))
+	require.NoError(t, err)
+	defer lines.Close()
+	var result []action.Container
+	for lines.Next() {
+		line, err := lines.Value()
+		require.NoError(t, err)
+		a, err := action.New(line)
+		require.NoError(t, err)
+		result = append(result, a)
+	}
+	return result
+}
+
+func TestSinkAppend(t *testing.T) {
+	sink, dst := newTestSink(t, WriteModeAppend, 2)
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		makeItem(abstract.InsertKind, testColumns, int32(1), "a", ts),
+		makeItem(abstract.InsertKind, testColumns, int32(2), "b", nil),
+	}))
+	require.NoError(t, sink.Push([]abstract.ChangeItem{makeItem(abstract.InsertKind, testColumns, int32(3), "c", nil)}))
+
+	// new column is added to the table
+	evolvedColumns := append(append([]abstract.ColSchema{}, testColumns...), abstract.ColSchema{ColumnName: "score", DataType: schema.TypeFloat64.String()})
+	require.NoError(t, sink.Push([]abstract.ChangeItem{makeItem(abstract.InsertKind, evolvedColumns, int32(4), "d", nil, 1.5)}))
+
+	snapshot, rows := readTable(t, dst)
+	require.Equal(t, int64(2), snapshot.Version())
+	require.Equal(t, []map[string]any{
+		{"id": int32(1), "name": "a", "created_at": ts.UnixMicro(), "score": nil},
+		{"id": int32(2), "name": "b", "created_at": nil, "score": nil},
+		{"id": int32(3), "name": "c", "created_at": nil, "score": nil},
+		{"id": int32(4), "name": "d", "created_at": nil, "score": 1.5},
+	}, rows)
+	meta, err := snapshot.Metadata()
+	require.NoError(t, err)
+	structType, err := meta.Schema()
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "name", "created_at", "score"}, structType.FieldNames())
+	id, err := structType.Get("id")
+	require.NoError(t, err)
+	require.False(t, id.Nullable)
+	require.Equal(t, "2", meta.Configuration["checkpointInterval"])
+
+	// checkpoint of version 2 is written & used by readers
+	_, err = os.Stat(filepath.Join(dst.TablePath(*abstract.NewTableID("public", "users")), "_delta_log", "00000000000000000002.checkpoint.parquet"))
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(dst.TablePath(*abstract.NewTableID("public", "users")), "_delta_log", "00000000000000000001.json")))
+	_, rowsFromCheckpoint := readTable(t, dst)
+	require.Equal(t, rows, rowsFromCheckpoint)
+
+	commit := readCommit(t, dst, 0)
+	require.IsType(t, &action.Protocol{}, commit[0])
+	require.IsType(t, &action.Metadata{}, commit[1])
+	require.IsType(t, &action.AddFile{}, commit[2])
+	require.Equal(t, "WRITE", commit[3].(*action.CommitInfo).Operation)
+
+	require.Error(t, sink.Push([]abstract.ChangeItem{makeDelete(int32(1))}))
+}
+
+func TestSinkMergeOnRead(t *testing.T) {
+	sink, dst := newTestSink(t, WriteModeMergeOnRead, 0)
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		makeItem(abstract.InsertKind, testColumns, int32(1), "a", nil),
+		makeItem(abstract.InsertKind, testColumns, int32(2), "b", nil),
+	}))
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		makeItem(abstract.InsertKind, testColumns, int32(3), "c", nil),
+		makeItem(abstract.InsertKind, testColumns, int32(4), "d", nil),
+	}))
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		makeItem(abstract.UpdateKind, testColumns, int32(1), "a2", nil),
+		makeItem(abstract.InsertKind, testColumns, int32(5), "e", nil),
+		makeItem(abstract.UpdateKind, testColumns, int32(5), "e2", nil),
+	}))
+
+	snapshot, rows := readTable(t, dst)
+	require.Equal(t, int64(2), snapshot.Version())
+	require.Equal(t, []map[string]any{
+		{"id": int32(1), "name": "a2", "created_at": nil},
+		{"id": int32(2), "name": "b", "created_at": nil},
+		{"id": int32(3), "name": "c", "created_at": nil},
+		{"id": int32(4), "name": "d", "created_at": nil},
+		{"id": int32(5), "name": "e2", "created_at": nil},
+	}, rows)
+
+	// only the first file contains changed keys, so it's replaced by its copy, second file is skipped by statistics
+	commit := readCommit(t, dst, 2)
+	var removes, adds int
+	for _, a := range commit {
+		switch a.(type) {
+		case *action.RemoveFile:
+			removes++
+		case *action.AddFile:
+			adds++
+		}
+	}
+	require.Equal(t, 1, removes)
+	require.Equal(t, 2, adds)
+	require.Equal(t, "MERGE", commit[len(commit)-1].(*action.CommitInfo).Operation)
+
+	// key is changed & rows are deleted
+	update := makeItem(abstract.UpdateKind, testColumns, int32(6), "c", nil)
+	update.OldKeys = abstract.OldKeysType{KeyNames: []string{"id"}, KeyTypes: nil, KeyValues: []any{int32(3)}}
+	require.NoError(t, sink.Push([]abstract.ChangeItem{update, makeDelete(int32(2)), makeDelete(int32(4))}))
+	_, rows = readTable(t, dst)
+	require.Equal(t, []map[string]any{
+		{"id": int32(1), "name": "a2", "created_at": nil},
+		{"id": int32(5), "name": "e2", "created_at": nil},
+		{"id": int32(6), "name": "c", "created_at": nil},
+	}, rows)
+}
+
+func TestSinkConcurrentWriters(t *testing.T) {
+	first, dst := newTestSink(t, WriteModeAppend, 0)
+	second, err := NewSink(dst, logger.Log, solomon.NewRegistry(solomon.NewRegistryOpts()))
+	require.NoError(t, err)
+
+	require.NoError(t, first.Push([]abstract.ChangeItem{makeItem(abstract.InsertKind, testColumns, int32(1), "a", nil)}))
+	require.NoError(t, second.Push([]abstract.ChangeItem{makeItem(abstract.InsertKind, testColumns, int32(2), "b", nil)}))
+	require.NoError(t, first.Push([]abstract.ChangeItem{makeItem(abstract.InsertKind, testColumns, int32(3), "c", nil)}))
+
+	snapshot, rows := readTable(t, dst)
+	require.Equal(t, int64(2), snapshot.Version())
+	require.Len(t, rows, 3)
+
+	// version is claimed only once
+	st := store.NewStoreLocal(&store.LocalConfig{Path: dst.TablePath(*abstract.NewTableID("public", "users"))})
+	err = st.Write(protocol.DeltaFile(st.Root()+"/_delta_log/", 2), []byte("{}"), false)
+	require.ErrorIs(t, err, store.ErrFileAlreadyExists)
+}
+
+func TestSinkTruncateAndDrop(t *testing.T) {
+	sink, dst := newTestSink(t, WriteModeAppend, 0)
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{makeItem(abstract.InsertKind, testColumns, int32(1), "a", nil)}))
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		{Kind: abstract.TruncateTableKind, Schema: "public", Table: "users"},
+		makeItem(abstract.InsertKind, testColumns, int32(2), "b", nil),
+	}))
+	_, rows := readTable(t, dst)
+	require.Equal(t, []map[string]any{{"id": int32(2), "name": "b", "created_at": nil}}, rows)
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{{Kind: abstract.DropTableKind, Schema: "public", Table: "users"}}))
+	entries, err := os.ReadDir(dst.TablePath(*abstract.NewTableID("public", "users")))
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{makeItem(abstract.InsertKind, testColumns, int32(3), "c", nil)}))
+	snapshot, rows := readTable(t, dst)
+	require.Equal(t, int64(0), snapshot.Version())
+	require.Len(t, rows, 1)
+}
@@ -6,7 +6,8 @@ import (
 )
 
 var (
-	ErrFileNotFound = xerrors.New("file not found")
+	ErrFileNotFound      = xerrors.New("file not found")
+	ErrFileAlreadyExists = xerrors.New("file already exists")
 )
 
 type StoreConfig interface {
@@ -33,6 +34,16 @@ type Store interface {
 	// ListFrom resolve the paths in the same directory that are lexicographically greater or equal to (UTF-8 sorting) the given `path`.
 	// The result should also be sorted by the file name.
 	ListFrom(path string) (iter.Iter[*FileMeta], error)
+
+	// ReadAll returns content of the given file as is, it's used for binary files like checkpoints.
+	ReadAll(path string) ([]byte, error)
+
+	// Write the given data to the file. If overwrite is false and the file already exists, ErrFileAlreadyExists is returned.
+	// Non-overwriting writes must be atomic: concurrent writers rely on them to claim a version of the log.
+	Write(path string, data []byte, overwrite bool) error
+
+	// Delete removes all files, which path starts with the given prefix.
+	Delete(prefix string) error
 }
 
 func New(config StoreConfig) (Store, error) {
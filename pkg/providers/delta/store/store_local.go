@@ -67,6 +67,72 @@ func (l *Local) ListFrom(path string) (iter.Iter[*FileMeta], error) {
 	return iter.FromSlice(res...), nil
 }
 
+func (l *Local) ReadAll(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrFileNotFound
+		}
+		return nil, xerrors.Errorf("local store read: %s:%w", path, err)
+	}
+	return data, nil
+}
+
+// Write puts data into a temporary file first, so readers never see partially written files.
+// Non-overwriting writes use hard link of temporary file, which fails if the target already exists.
+func (l *Local) Write(path string, data []byte, overwrite bool) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return xerrors.Errorf("local store mkdir: %s:%w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return xerrors.Errorf("local store create temp file: %s:%w", dir, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return xerrors.Errorf("local store write: %s:%w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return xerrors.Errorf("local store close: %s:%w", tmp.Name(), err)
+	}
+
+	if overwrite {
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return xerrors.Errorf("local store rename: %s:%w", path, err)
+		}
+		return nil
+	}
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if os.IsExist(err) {
+			return ErrFileAlreadyExists
+		}
+		return xerrors.Errorf("local store link: %s:%w", path, err)
+	}
+	return nil
+}
+
+func (l *Local) Delete(prefix string) error {
+	parent, startFile := filepath.Split(prefix)
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return xerrors.Errorf("local store list: %s:%w", parent, err)
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), startFile) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(parent, entry.Name())); err != nil {
+			return xerrors.Errorf("local store remove: %s:%w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
 func NewStoreLocal(cfg *LocalConfig) *Local {
 	return &Local{
 		Path: cfg.Path,
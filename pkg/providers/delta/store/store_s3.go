@@ -1,6 +1,9 @@
 package store
 
 import (
+	"bytes"
+	"io"
+	"net/http"
 	"path/filepath"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -78,6 +81,73 @@ func (s S3) ListFrom(path string) (iter.Iter[*FileMeta], error) {
 	})...), nil
 }
 
+func (s S3) ReadAll(path string) ([]byte, error) {
+	data, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(path),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read object: %s: %w", path, err)
+	}
+	defer data.Body.Close()
+	res, err := io.ReadAll(data.Body)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read object body: %s: %w", path, err)
+	}
+	return res, nil
+}
+
+// Write relies on conditional writes of S3 (`If-None-Match: *`) for non-overwriting puts
+func (s S3) Write(path string, data []byte, overwrite bool) error {
+	req, _ := s.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(path),
+		Body:   bytes.NewReader(data),
+	})
+	if !overwrite {
+		req.HTTPRequest.Header.Set("If-None-Match", "*")
+	}
+	if err := req.Send(); err != nil {
+		if rerr, ok := err.(awserr.RequestFailure); ok && !overwrite &&
+			(rerr.StatusCode() == http.StatusPreconditionFailed || rerr.StatusCode() == http.StatusConflict) {
+			return ErrFileAlreadyExists
+		}
+		return xerrors.Errorf("unable to put object: %s: %w", path, err)
+	}
+	return nil
+}
+
+func (s S3) Delete(prefix string) error {
+	var keys []*s3.ObjectIdentifier
+	err := s.client.ListObjectsPages(&s3.ListObjectsInput{
+		Bucket: aws.String(s.config.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsOutput, _ bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, &s3.ObjectIdentifier{Key: object.Key})
+		}
+		return true
+	})
+	if err != nil {
+		return xerrors.Errorf("unable to list objects: %s: %w", prefix, err)
+	}
+	// delete request is limited by 1000 keys
+	for len(keys) > 0 {
+		batch := keys[:min(len(keys), 1000)]
+		keys = keys[len(batch):]
+		if _, err := s.client.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(s.config.Bucket),
+			Delete: &s3.Delete{Objects: batch, Quiet: aws.Bool(true)},
+		}); err != nil {
+			return xerrors.Errorf("unable to delete objects: %s: %w", prefix, err)
+		}
+	}
+	return nil
+}
+
 func NewStoreS3(config *S3Config) (*S3, error) {
 	sess, err := session.NewSession(&aws.Config{
 		Endpoint:         aws.String(config.Endpoint),
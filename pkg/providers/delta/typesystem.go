@@ -29,4 +29,24 @@ func init() {
 			typesystem.RestPlaceholder,
 		},
 	})
+	typesystem.TargetRule(ProviderType, map[schema.Type]string{
+		schema.TypeInt64:     new(types.LongType).Name(),
+		schema.TypeInt32:     new(types.IntegerType).Name(),
+		schema.TypeInt16:     new(types.ShortType).Name(),
+		schema.TypeInt8:      new(types.ByteType).Name(),
+		schema.TypeUint64:    new(types.LongType).Name(),
+		schema.TypeUint32:    new(types.LongType).Name(),
+		schema.TypeUint16:    new(types.IntegerType).Name(),
+		schema.TypeUint8:     new(types.ShortType).Name(),
+		schema.TypeFloat32:   new(types.FloatType).Name(),
+		schema.TypeFloat64:   new(types.DoubleType).Name(),
+		schema.TypeBytes:     new(types.BinaryType).Name(),
+		schema.TypeString:    new(types.StringType).Name(),
+		schema.TypeBoolean:   new(types.BooleanType).Name(),
+		schema.TypeDate:      new(types.DateType).Name(),
+		schema.TypeDatetime:  new(types.TimestampType).Name(),
+		schema.TypeTimestamp: new(types.TimestampType).Name(),
+		schema.TypeInterval:  new(types.LongType).Name(),
+		schema.TypeAny:       new(types.StringType).Name(),
+	})
 }
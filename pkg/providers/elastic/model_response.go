@@ -7,10 +7,12 @@ type total struct {
 }
 
 type hit struct {
-	Index  string          `json:"_index"`
-	ID     string          `json:"_id"`
-	Type   string          `json:"_type"`
-	Source json.RawMessage `json:"_source"`
+	Index   string            `json:"_index"`
+	ID      string            `json:"_id"`
+	Type    string            `json:"_type"`
+	Version int64             `json:"_version"`
+	Source  json.RawMessage   `json:"_source"`
+	Sort    []json.RawMessage `json:"sort"`
 }
 type searchResults struct {
 	Hits  []hit `json:"hits"`
@@ -37,9 +39,16 @@ type healthResponse struct {
 
 type searchResponse struct {
 	ScrollID string        `json:"_scroll_id"`
+	PitID    string        `json:"pit_id"`
 	Hits     searchResults `json:"hits"`
 }
 
+// openPitResponse is a response of ElasticSearch (`id`) or OpenSearch (`pit_id`) point in time creation
+type openPitResponse struct {
+	ID    string `json:"id"`
+	PitID string `json:"pit_id"`
+}
+
 type countResponse struct {
 	Count uint64 `json:"count"`
 }
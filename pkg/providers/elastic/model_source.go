@@ -1,6 +1,8 @@
 package elastic
 
 import (
+	"time"
+
 	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/abstract"
 	"github.com/transferia/transferia/pkg/abstract/model"
@@ -17,6 +19,11 @@ type ElasticSearchSource struct {
 	SecurityGroupIDs     []string
 	DumpIndexWithMapping bool
 	ConnectionID         string
+
+	// CursorField is a monotonic field used by replication to find new documents, for example `@timestamp` or an ingestion time field
+	CursorField string
+	// PollInterval is a pause between replication polls, which found no new documents
+	PollInterval time.Duration
 }
 
 var _ model.Source = (*ElasticSearchSource)(nil)
@@ -75,4 +82,7 @@ func (s *ElasticSearchSource) Validate() error {
 }
 
 func (s *ElasticSearchSource) WithDefaults() {
+	if s.PollInterval == 0 {
+		s.PollInterval = DefaultPollInterval
+	}
 }
@@ -37,9 +37,10 @@ const ProviderType = abstract.ProviderType("elasticsearch")
 
 // To verify providers contract implementation
 var (
-	_ providers.Sinker    = (*Provider)(nil)
-	_ providers.Snapshot  = (*Provider)(nil)
-	_ providers.Activator = (*Provider)(nil)
+	_ providers.Sinker      = (*Provider)(nil)
+	_ providers.Snapshot    = (*Provider)(nil)
+	_ providers.Activator   = (*Provider)(nil)
+	_ providers.Replication = (*Provider)(nil)
 )
 
 type Provider struct {
@@ -54,20 +55,37 @@ func (p *Provider) Type() abstract.ProviderType {
 }
 
 func (p *Provider) Activate(ctx context.Context, task *model.TransferOperation, tables abstract.TableMap, callbacks providers.ActivateCallbacks) error {
-	if !p.transfer.SnapshotOnly() {
-		return abstract.NewFatalError(xerrors.Errorf("only snapshot mode is allowed for the Elastic source"))
-	}
-	if err := callbacks.Cleanup(tables); err != nil {
-		return xerrors.Errorf("failed to cleanup sink: %w", err)
+	src, ok := p.transfer.Src.(*ElasticSearchSource)
+	if !ok {
+		return xerrors.Errorf("unexpected source type: %T", p.transfer.Src)
 	}
-	if err := callbacks.CheckIncludes(tables); err != nil {
-		return xerrors.Errorf("failed in accordance with configuration: %w", err)
+	serverType := ElasticSearch
+	if !p.transfer.SnapshotOnly() {
+		if src.CursorField == "" {
+			return abstract.NewFatalError(xerrors.Errorf("cursor field is required for replication from the Elastic source"))
+		}
+		// cursors are stored before the snapshot, so documents added during the snapshot are replicated
+		storage, err := NewStorage(src, p.logger, p.registry, serverType)
+		if err != nil {
+			return xerrors.Errorf("unable to create storage: %w", err)
+		}
+		if err := InitReplicationCursors(ctx, storage, p.cp, p.transfer.ID, src.CursorField, tables); err != nil {
+			return xerrors.Errorf("unable to init replication cursors: %w", err)
+		}
 	}
 	if err := DumpIndexInfo(p.transfer, p.logger, p.registry); err != nil {
 		return xerrors.Errorf("failed to dump source indexes info: %w", err)
 	}
-	if err := callbacks.Upload(tables); err != nil {
-		return xerrors.Errorf("transfer (snapshot) failed: %w", err)
+	if !p.transfer.IncrementOnly() {
+		if err := callbacks.Cleanup(tables); err != nil {
+			return xerrors.Errorf("failed to cleanup sink: %w", err)
+		}
+		if err := callbacks.CheckIncludes(tables); err != nil {
+			return xerrors.Errorf("failed in accordance with configuration: %w", err)
+		}
+		if err := callbacks.Upload(tables); err != nil {
+			return xerrors.Errorf("transfer (snapshot) failed: %w", err)
+		}
 	}
 	return nil
 }
@@ -88,6 +106,17 @@ func (p *Provider) Storage() (abstract.Storage, error) {
 	return NewStorage(src, p.logger, p.registry, ElasticSearch)
 }
 
+func (p *Provider) Source() (abstract.Source, error) {
+	src, ok := p.transfer.Src.(*ElasticSearchSource)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected source type: %T", p.transfer.Src)
+	}
+	if _, ok := p.transfer.Dst.(IsElasticLikeDestination); ok {
+		return NewSource(src, ElasticSearch, p.logger, p.registry, p.cp, p.transfer, WithHomo())
+	}
+	return NewSource(src, ElasticSearch, p.logger, p.registry, p.cp, p.transfer)
+}
+
 func (p *Provider) Sink(middlewares.Config) (abstract.Sinker, error) {
 	dst, ok := p.transfer.Dst.(*ElasticSearchDestination)
 	if !ok {
@@ -0,0 +1,481 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/changeitem"
+	"github.com/transferia/transferia/pkg/abstract/coordinator"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/stats"
+	"github.com/transferia/transferia/pkg/util/jsonx"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const (
+	DefaultPollInterval = 10 * time.Second
+
+	replicationPageSize  = 1000
+	pointInTimeKeepAlive = "5m"
+	cursorStateKeyPrefix = "elastic_cursor:"
+)
+
+var _ abstract.Source = (*Source)(nil)
+
+// Source polls indices for documents after the last seen one in the order of the cursor field.
+// Every poll reads a consistent point in time view of the index page by page with `search_after`,
+// the cursor is stored in the transfer state after every pushed page.
+//
+// Documents with the last seen cursor value which are already pushed are excluded by their ids,
+// so a poll without new documents pushes nothing.
+type Source struct {
+	storage  *Storage
+	cfg      *ElasticSearchSource
+	cp       coordinator.Coordinator
+	transfer *model.Transfer
+	logger   log.Logger
+	metrics  *stats.SourceStats
+
+	cursors map[string]*cursor
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel func()
+}
+
+// cursor is the position of replication in an index: the sort value of the cursor field of the last pushed document
+// and ids of all pushed documents with this value
+type cursor struct {
+	Value json.RawMessage `json:"value"`
+	IDs   []string        `json:"ids"`
+}
+
+// next returns the cursor moved past the hits, which are sorted by the cursor field
+func (c *cursor) next(hits []hit) *cursor {
+	last := hits[len(hits)-1].Sort[0]
+	result := &cursor{Value: last, IDs: nil}
+	seen := map[string]bool{}
+	if c != nil && bytes.Equal(c.Value, last) {
+		for _, id := range c.IDs {
+			seen[id] = true
+			result.IDs = append(result.IDs, id)
+		}
+	}
+	for _, doc := range hits {
+		if len(doc.Sort) > 0 && bytes.Equal(doc.Sort[0], last) && !seen[doc.ID] {
+			seen[doc.ID] = true
+			result.IDs = append(result.IDs, doc.ID)
+		}
+	}
+	return result
+}
+
+// parseCursor parses a stored cursor, states written by earlier versions hold the raw sort value only
+func parseCursor(state string) *cursor {
+	var result cursor
+	if err := json.Unmarshal([]byte(state), &result); err != nil || result.Value == nil {
+		return &cursor{Value: json.RawMessage(state), IDs: nil}
+	}
+	return &result
+}
+
+func cursorStateKey(index string) string {
+	return cursorStateKeyPrefix + index
+}
+
+func (s *Source) Run(sink abstract.AsyncSink) error {
+	s.wg.Add(1)
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.ctx.Done():
+			s.logger.Info("Stopping run")
+			return nil
+		default:
+		}
+
+		found, err := s.poll(sink)
+		if err != nil {
+			return xerrors.Errorf("unable to poll indices: %w", err)
+		}
+		if found > 0 {
+			s.logger.Infof("Done %v documents, poll again", found)
+			continue
+		}
+		select {
+		case <-s.ctx.Done():
+		case <-time.After(s.cfg.PollInterval):
+		}
+	}
+}
+
+func (s *Source) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Source) poll(sink abstract.AsyncSink) (int, error) {
+	indices, err := s.storage.indexNames()
+	if err != nil {
+		return 0, xerrors.Errorf("unable to list indices: %w", err)
+	}
+	total := 0
+	for _, index := range indices {
+		if !s.transfer.Include(abstract.TableID{Namespace: "", Name: index}) {
+			continue
+		}
+		found, err := s.pollIndex(index, sink)
+		if err != nil {
+			return total, xerrors.Errorf("unable to poll index %s: %w", index, err)
+		}
+		total += found
+	}
+	return total, nil
+}
+
+func (s *Source) pollIndex(index string, sink abstract.AsyncSink) (int, error) {
+	schemaDescription, err := s.storage.getSchema(index)
+	if err != nil {
+		return 0, xerrors.Errorf("failed to fetch schema: %w", err)
+	}
+	tableSchema := abstract.NewTableSchema(schemaDescription.Columns)
+
+	pitID, err := s.storage.openPointInTime(s.ctx, index)
+	if err != nil {
+		return 0, xerrors.Errorf("unable to open point in time: %w", err)
+	}
+	defer func() {
+		if err := s.storage.closePointInTime(context.Background(), pitID); err != nil {
+			s.logger.Warn("unable to close point in time", log.String("index", index), log.Error(err))
+		}
+	}()
+
+	query := cursorQuery(s.cfg.CursorField, s.cursors[index], schemaDescription)
+	var searchAfter []json.RawMessage
+	total := 0
+	for s.ctx.Err() == nil {
+		result, err := s.storage.searchPointInTime(s.ctx, pitID, s.cfg.CursorField, query, searchAfter)
+		if err != nil {
+			return total, xerrors.Errorf("unable to search documents: %w", err)
+		}
+		if result.PitID != "" {
+			// id of point in time may change between requests
+			pitID = result.PitID
+		}
+		hits := result.Hits.Hits
+		if len(hits) == 0 {
+			break
+		}
+
+		st := time.Now()
+		items := make([]abstract.ChangeItem, 0, len(hits))
+		for _, doc := range hits {
+			names, values, err := extractColumnValues(schemaDescription, doc.Source, doc.ID)
+			if err != nil {
+				return total, xerrors.Errorf("failed to extract values, _id: %s, err: %w", doc.ID, err)
+			}
+			kind := abstract.InsertKind
+			if doc.Version > 1 {
+				// document was indexed more than once
+				kind = abstract.UpdateKind
+			}
+			items = append(items, abstract.ChangeItem{
+				ID:               0,
+				LSN:              0,
+				CommitTime:       uint64(st.UnixNano()),
+				Counter:          0,
+				Kind:             kind,
+				Schema:           "",
+				Table:            index,
+				PartID:           "",
+				ColumnNames:      names,
+				ColumnValues:     values,
+				TableSchema:      tableSchema,
+				OldKeys:          abstract.EmptyOldKeys(),
+				Size:             abstract.RawEventSize(uint64(len(doc.Source))),
+				TxID:             "",
+				Query:            "",
+				QueueMessageMeta: changeitem.QueueMessageMeta{TopicName: "", PartitionNum: 0, Offset: 0, Index: 0},
+			})
+			s.metrics.ChangeItems.Inc()
+			s.metrics.Size.Add(int64(len(doc.Source)))
+		}
+		if err := <-sink.AsyncPush(items); err != nil {
+			return total, xerrors.Errorf("unable to push documents: %w", err)
+		}
+		total += len(hits)
+
+		last := hits[len(hits)-1]
+		if len(last.Sort) == 0 {
+			return total, xerrors.Errorf("document %s has no sort values", last.ID)
+		}
+		if err := s.storeCursor(index, s.cursors[index].next(hits)); err != nil {
+			return total, xerrors.Errorf("unable to store cursor: %w", err)
+		}
+		searchAfter = last.Sort
+	}
+	return total, nil
+}
+
+func (s *Source) storeCursor(index string, c *cursor) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return xerrors.Errorf("unable to marshal cursor: %w", err)
+	}
+	s.cursors[index] = c
+	if err := s.cp.SetTransferState(s.transfer.ID, map[string]*coordinator.TransferStateData{
+		cursorStateKey(index): {Generic: string(data)},
+	}); err != nil {
+		return xerrors.Errorf("unable to set transfer state: %w", err)
+	}
+	return nil
+}
+
+// cursorQuery selects documents after the cursor: documents with a greater cursor value and documents with the same value
+// which are not pushed yet. Nil cursor selects all documents with cursor field
+func cursorQuery(field string, c *cursor, schemaDescription *SchemaDescription) map[string]any {
+	var filters []any
+	if !strings.HasPrefix(field, "_") {
+		// documents without the field are sorted last, their sort value must not become a cursor
+		filters = append(filters, map[string]any{"exists": map[string]any{"field": field}})
+	}
+	if c != nil {
+		if len(c.IDs) == 0 {
+			filters = append(filters, cursorRange(field, c.Value, schemaDescription, "gte"))
+		} else {
+			filters = append(filters, map[string]any{"bool": map[string]any{
+				"should": []any{
+					cursorRange(field, c.Value, schemaDescription, "gt"),
+					map[string]any{"bool": map[string]any{
+						"filter":   []any{cursorRange(field, c.Value, schemaDescription, "gte", "lte")},
+						"must_not": []any{map[string]any{"ids": map[string]any{"values": c.IDs}}},
+					}},
+				},
+				"minimum_should_match": 1,
+			}})
+		}
+	}
+	if len(filters) == 0 {
+		return map[string]any{"match_all": map[string]any{}}
+	}
+	return map[string]any{"bool": map[string]any{"filter": filters}}
+}
+
+// cursorRange returns the range query comparing the cursor field with the sort value by the operators
+func cursorRange(field string, value json.RawMessage, schemaDescription *SchemaDescription, operators ...string) map[string]any {
+	var bound any = value
+	format := ""
+	if schemaDescription != nil {
+		for _, col := range schemaDescription.Columns {
+			if col.ColumnName != field {
+				continue
+			}
+			// sort values of dates are epoch millis, of date_nanos - epoch nanos
+			switch strings.TrimPrefix(col.OriginalType, fmt.Sprintf("%s:", ProviderType)) {
+			case "date":
+				format = "epoch_millis"
+			case "date_nanos":
+				format = "epoch_millis"
+				bound = nanosToMillis(string(value))
+			}
+		}
+	}
+	rng := map[string]any{}
+	for _, operator := range operators {
+		rng[operator] = bound
+	}
+	if format != "" {
+		rng["format"] = format
+	}
+	return map[string]any{"range": map[string]any{field: rng}}
+}
+
+// nanosToMillis formats epoch nanos as fractional epoch millis, which keeps the precision of date_nanos
+func nanosToMillis(nanos string) string {
+	if len(nanos) <= 6 {
+		nanos = strings.Repeat("0", 7-len(nanos)) + nanos
+	}
+	return nanos[:len(nanos)-6] + "." + nanos[len(nanos)-6:]
+}
+
+// perform sends request, which is not covered by the client API in the same way for ElasticSearch & OpenSearch
+func (s *Storage) perform(ctx context.Context, method, path string, body any) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, path, reader)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to build request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := s.Client.Perform(req)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to perform elastic request: %w", err)
+	}
+	return getResponseBody(&esapi.Response{StatusCode: res.StatusCode, Header: res.Header, Body: res.Body}, nil)
+}
+
+func (s *Storage) openPointInTime(ctx context.Context, index string) (string, error) {
+	path := fmt.Sprintf("/%s/_pit?keep_alive=%s", index, pointInTimeKeepAlive)
+	if s.ServerType == OpenSearch {
+		path = fmt.Sprintf("/%s/_search/point_in_time?keep_alive=%s", index, pointInTimeKeepAlive)
+	}
+	body, err := s.perform(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return "", xerrors.Errorf("unable to open point in time, index: %s, err: %w", index, err)
+	}
+	var result openPitResponse
+	if err := jsonx.Unmarshal(body, &result); err != nil {
+		return "", xerrors.Errorf("failed to unmarshal point in time, index: %s, err: %w", index, err)
+	}
+	if result.PitID != "" {
+		return result.PitID, nil
+	}
+	if result.ID == "" {
+		return "", xerrors.Errorf("empty point in time id, index: %s", index)
+	}
+	return result.ID, nil
+}
+
+func (s *Storage) closePointInTime(ctx context.Context, pitID string) error {
+	var err error
+	if s.ServerType == OpenSearch {
+		_, err = s.perform(ctx, http.MethodDelete, "/_search/point_in_time", map[string]any{"pit_id": []string{pitID}})
+	} else {
+		_, err = s.perform(ctx, http.MethodDelete, "/_pit", map[string]any{"id": pitID})
+	}
+	return err
+}
+
+func (s *Storage) searchPointInTime(ctx context.Context, pitID string, cursorField string, query map[string]any, searchAfter []json.RawMessage) (*searchResponse, error) {
+	request := map[string]any{
+		"size":             replicationPageSize,
+		"query":            query,
+		"sort":             []any{map[string]any{cursorField: map[string]any{"order": "asc"}}},
+		"pit":              map[string]any{"id": pitID, "keep_alive": pointInTimeKeepAlive},
+		"version":          true,
+		"track_total_hits": false,
+	}
+	if len(searchAfter) > 0 {
+		// point in time adds implicit tie breaker, so all sort values of the last hit are used
+		request["search_after"] = searchAfter
+	}
+	body, err := s.perform(ctx, http.MethodPost, "/_search", request)
+	if err != nil {
+		return nil, err
+	}
+	var result searchResponse
+	if err := jsonx.Unmarshal(body, &result); err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal documents: %w", err)
+	}
+	return &result, nil
+}
+
+// lastCursor returns the cursor of the latest document in the index, nil if there are no documents
+func (s *Storage) lastCursor(ctx context.Context, index string, cursorField string) (*cursor, error) {
+	request := map[string]any{
+		"size":             1,
+		"query":            cursorQuery(cursorField, nil, nil),
+		"sort":             []any{map[string]any{cursorField: map[string]any{"order": "desc"}}},
+		"track_total_hits": false,
+	}
+	body, err := s.perform(ctx, http.MethodPost, fmt.Sprintf("/%s/_search", index), request)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to fetch last document, index: %s, err: %w", index, err)
+	}
+	var result searchResponse
+	if err := jsonx.Unmarshal(body, &result); err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal last document, index: %s, err: %w", index, err)
+	}
+	if len(result.Hits.Hits) == 0 || len(result.Hits.Hits[0].Sort) == 0 {
+		return nil, nil
+	}
+	return &cursor{Value: result.Hits.Hits[0].Sort[0], IDs: []string{result.Hits.Hits[0].ID}}, nil
+}
+
+// InitReplicationCursors stores cursors of the latest documents of tables, so replication starts after the activation moment.
+// Indices without documents and indices created later are replicated from the beginning.
+func InitReplicationCursors(ctx context.Context, storage *Storage, cp coordinator.Coordinator, transferID string, cursorField string, tables abstract.TableMap) error {
+	state := map[string]*coordinator.TransferStateData{}
+	for table := range tables {
+		c, err := storage.lastCursor(ctx, table.Name, cursorField)
+		if err != nil {
+			return xerrors.Errorf("unable to get cursor: %w", err)
+		}
+		if c == nil {
+			continue
+		}
+		data, err := json.Marshal(c)
+		if err != nil {
+			return xerrors.Errorf("unable to marshal cursor: %w", err)
+		}
+		state[cursorStateKey(table.Name)] = &coordinator.TransferStateData{Generic: string(data)}
+	}
+	if len(state) == 0 {
+		return nil
+	}
+	if err := cp.SetTransferState(transferID, state); err != nil {
+		return xerrors.Errorf("unable to set transfer state: %w", err)
+	}
+	return nil
+}
+
+func NewSource(src *ElasticSearchSource, serverType ServerType, lgr log.Logger, registry metrics.Registry, cp coordinator.Coordinator, transfer *model.Transfer, opts ...StorageOpt) (*Source, error) {
+	if src.CursorField == "" {
+		return nil, abstract.NewFatalError(xerrors.New("cursor field is required for replication"))
+	}
+	storage, err := NewStorage(src, lgr, registry, serverType, opts...)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create storage: %w", err)
+	}
+
+	state, err := cp.GetTransferState(transfer.ID)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get transfer state: %w", err)
+	}
+	cursors := map[string]*cursor{}
+	for key, value := range state {
+		index, ok := strings.CutPrefix(key, cursorStateKeyPrefix)
+		if !ok || value == nil {
+			continue
+		}
+		c, ok := value.Generic.(string)
+		if !ok {
+			return nil, xerrors.Errorf("unexpected cursor type of index %s: %T", index, value.Generic)
+		}
+		cursors[index] = parseCursor(c)
+	}
+	lgr.Info("elastic replication source constructed", log.Any("cursors", state))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Source{
+		storage:  storage,
+		cfg:      src,
+		cp:       cp,
+		transfer: transfer,
+		logger:   lgr,
+		metrics:  stats.NewSourceStats(registry),
+		cursors:  cursors,
+		wg:       sync.WaitGroup{},
+		ctx:      ctx,
+		cancel:   cancel,
+	}, nil
+}
@@ -0,0 +1,331 @@
+package elastic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/coordinator"
+	"github.com/transferia/transferia/pkg/abstract/model"
+)
+
+type fakeDoc struct {
+	id      string
+	ts      int64
+	version int64
+	msg     string
+}
+
+// fakeCluster serves the subset of OpenSearch API used by replication: single index `logs` with `ts` cursor field
+type fakeCluster struct {
+	mu       sync.Mutex
+	docs     []fakeDoc
+	openPits int
+}
+
+func (c *fakeCluster) hit(idx int) map[string]any {
+	doc := c.docs[idx]
+	return map[string]any{
+		"_index":   "logs",
+		"_id":      doc.id,
+		"_version": doc.version,
+		"_source":  map[string]any{"ts": doc.ts, "msg": doc.msg},
+		"sort":     []any{doc.ts, idx},
+	}
+}
+
+// matches evaluates the subset of query DSL built by cursorQuery on the document
+func matches(query map[string]any, doc fakeDoc) bool {
+	for kind, value := range query {
+		clause := value.(map[string]any)
+		switch kind {
+		case "match_all", "exists":
+		case "ids":
+			found := false
+			for _, id := range clause["values"].([]any) {
+				found = found || id == doc.id
+			}
+			if !found {
+				return false
+			}
+		case "range":
+			for operator, bound := range clause["ts"].(map[string]any) {
+				val, _ := bound.(json.Number).Int64()
+				switch {
+				case operator == "gt" && doc.ts <= val,
+					operator == "gte" && doc.ts < val,
+					operator == "lte" && doc.ts > val:
+					return false
+				}
+			}
+		case "bool":
+			for _, filter := range asList(clause["filter"]) {
+				if !matches(filter, doc) {
+					return false
+				}
+			}
+			for _, filter := range asList(clause["must_not"]) {
+				if matches(filter, doc) {
+					return false
+				}
+			}
+			if should := asList(clause["should"]); len(should) > 0 {
+				matched := false
+				for _, filter := range should {
+					matched = matched || matches(filter, doc)
+				}
+				if !matched {
+					return false
+				}
+			}
+		default:
+			panic(kind)
+		}
+	}
+	return true
+}
+
+func asList(value any) []map[string]any {
+	var result []map[string]any
+	list, _ := value.([]any)
+	for _, item := range list {
+		result = append(result, item.(map[string]any))
+	}
+	return result
+}
+
+func (c *fakeCluster) search(body map[string]any) []any {
+	query := body["query"].(map[string]any)
+	var after []int64
+	if searchAfter, ok := body["search_after"].([]any); ok {
+		for _, v := range searchAfter {
+			n, _ := v.(json.Number).Int64()
+			after = append(after, n)
+		}
+	}
+	size, _ := body["size"].(json.Number).Int64()
+
+	var hits []any
+	for idx, doc := range c.docs {
+		if !matches(query, doc) {
+			continue
+		}
+		if after != nil && (doc.ts < after[0] || doc.ts == after[0] && int64(idx) <= after[1]) {
+			continue
+		}
+		hits = append(hits, c.hit(idx))
+		if int64(len(hits)) == size {
+			break
+		}
+	}
+	return hits
+}
+
+func (c *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	var body map[string]any
+	if r.Body != nil {
+		data, _ := io.ReadAll(r.Body)
+		if len(data) > 0 {
+			dec := json.NewDecoder(strings.NewReader(string(data)))
+			dec.UseNumber()
+			_ = dec.Decode(&body)
+		}
+	}
+	var resp any
+	switch {
+	case r.URL.Path == "/_stats":
+		resp = map[string]any{"indices": map[string]any{"logs": map[string]any{}, ".internal": map[string]any{}}}
+	case r.URL.Path == "/logs/_mapping":
+		resp = map[string]any{"logs": map[string]any{"mappings": map[string]any{"properties": map[string]any{
+			"ts":  map[string]any{"type": "long"},
+			"msg": map[string]any{"type": "keyword"},
+		}}}}
+	case r.URL.Path == "/logs/_search" && body["track_total_hits"] != nil:
+		// the latest document
+		var hits []any
+		if len(c.docs) > 0 {
+			hits = append(hits, c.hit(len(c.docs)-1))
+		}
+		resp = map[string]any{"hits": map[string]any{"hits": hits}}
+	case r.URL.Path == "/logs/_search":
+		// sample document for schema
+		resp = map[string]any{"hits": map[string]any{"hits": []any{}}}
+	case r.URL.Path == "/logs/_search/point_in_time" && r.Method == http.MethodPost:
+		c.openPits++
+		resp = map[string]any{"pit_id": fmt.Sprintf("pit-%d", c.openPits)}
+	case r.URL.Path == "/_search/point_in_time" && r.Method == http.MethodDelete:
+		c.openPits--
+		resp = map[string]any{}
+	case r.URL.Path == "/_search":
+		resp = map[string]any{"pit_id": body["pit"].(map[string]any)["id"], "hits": map[string]any{"hits": c.search(body)}}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		resp = map[string]any{"error": r.URL.Path}
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+type recordingSink struct {
+	items []abstract.ChangeItem
+}
+
+func (s *recordingSink) AsyncPush(items []abstract.ChangeItem) chan error {
+	s.items = append(s.items, items...)
+	result := make(chan error, 1)
+	result <- nil
+	return result
+}
+
+func (s *recordingSink) Close() error {
+	return nil
+}
+
+func newFakeSource(t *testing.T, cluster *fakeCluster, cp coordinator.Coordinator) *Source {
+	server := httptest.NewServer(cluster)
+	t.Cleanup(server.Close)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(serverURL.Port())
+	require.NoError(t, err)
+
+	src := &ElasticSearchSource{
+		DataNodes:   []ElasticSearchHostPort{{Host: serverURL.Hostname(), Port: port}},
+		CursorField: "ts",
+	}
+	src.WithDefaults()
+	source, err := NewSource(src, OpenSearch, logger.Log, solomon.NewRegistry(solomon.NewRegistryOpts()), cp, &model.Transfer{ID: "dtt"})
+	require.NoError(t, err)
+	return source
+}
+
+func TestSourcePollResumesFromCursor(t *testing.T) {
+	cluster := &fakeCluster{}
+	for i := 0; i < replicationPageSize+1; i++ {
+		cluster.docs = append(cluster.docs, fakeDoc{id: fmt.Sprintf("doc-%d", i), ts: int64(i / 2), version: 1, msg: "a"})
+	}
+	cp := coordinator.NewStatefulFakeClient()
+
+	source := newFakeSource(t, cluster, cp)
+	sink := new(recordingSink)
+	found, err := source.poll(sink)
+	require.NoError(t, err)
+	require.Equal(t, replicationPageSize+1, found)
+	require.Len(t, sink.items, replicationPageSize+1)
+	require.Equal(t, abstract.InsertKind, sink.items[0].Kind)
+	require.Equal(t, "logs", sink.items[0].Table)
+	require.Equal(t, "doc-0", sink.items[0].ColumnValues[0])
+	require.Equal(t, 0, cluster.openPits)
+
+	requireCursor(t, cp, `{"value":500,"ids":["doc-1000"]}`)
+
+	// restarted source continues after the stored cursor, pushed documents with the cursor value are not read again
+	cluster.docs = append(cluster.docs, fakeDoc{id: "doc-0", ts: 501, version: 2, msg: "b"})
+	restarted := newFakeSource(t, cluster, cp)
+	sink = new(recordingSink)
+	found, err = restarted.poll(sink)
+	require.NoError(t, err)
+	require.Equal(t, 1, found)
+	require.Equal(t, abstract.UpdateKind, sink.items[0].Kind)
+	require.Equal(t, []string{"_id", "msg", "ts"}, sink.items[0].ColumnNames)
+	require.Equal(t, []any{"doc-0", "b", int64(501)}, sink.items[0].ColumnValues)
+	requireCursor(t, cp, `{"value":501,"ids":["doc-0"]}`)
+
+	// idle poll pushes nothing
+	sink = new(recordingSink)
+	found, err = restarted.poll(sink)
+	require.NoError(t, err)
+	require.Equal(t, 0, found)
+	require.Empty(t, sink.items)
+	requireCursor(t, cp, `{"value":501,"ids":["doc-0"]}`)
+
+	// a document with the cursor value indexed later is read
+	cluster.docs = append(cluster.docs, fakeDoc{id: "late", ts: 501, version: 1, msg: "c"})
+	sink = new(recordingSink)
+	found, err = restarted.poll(sink)
+	require.NoError(t, err)
+	require.Equal(t, 1, found)
+	require.Equal(t, "late", sink.items[0].ColumnValues[0])
+	requireCursor(t, cp, `{"value":501,"ids":["doc-0","late"]}`)
+}
+
+func TestSourcePollResumesFromLegacyCursor(t *testing.T) {
+	cluster := &fakeCluster{docs: []fakeDoc{
+		{id: "a", ts: 1, version: 1, msg: "a"},
+		{id: "b", ts: 2, version: 1, msg: "b"},
+	}}
+	cp := coordinator.NewStatefulFakeClient()
+	require.NoError(t, cp.SetTransferState("dtt", map[string]*coordinator.TransferStateData{cursorStateKey("logs"): {Generic: "2"}}))
+
+	// the raw cursor of earlier versions has no ids, so its documents are read once more
+	source := newFakeSource(t, cluster, cp)
+	sink := new(recordingSink)
+	found, err := source.poll(sink)
+	require.NoError(t, err)
+	require.Equal(t, 1, found)
+	requireCursor(t, cp, `{"value":2,"ids":["b"]}`)
+
+	found, err = source.poll(sink)
+	require.NoError(t, err)
+	require.Equal(t, 0, found)
+}
+
+func requireCursor(t *testing.T, cp coordinator.Coordinator, expected string) {
+	state, err := cp.GetTransferState("dtt")
+	require.NoError(t, err)
+	require.JSONEq(t, expected, state[cursorStateKey("logs")].Generic.(string))
+}
+
+func TestInitReplicationCursors(t *testing.T) {
+	cluster := &fakeCluster{docs: []fakeDoc{{id: "a", ts: 7, version: 1, msg: "a"}}}
+	cp := coordinator.NewStatefulFakeClient()
+	source := newFakeSource(t, cluster, cp)
+
+	tables := abstract.TableMap{abstract.TableID{Namespace: "", Name: "logs"}: abstract.TableInfo{}}
+	require.NoError(t, InitReplicationCursors(source.ctx, source.storage, cp, "dtt", "ts", tables))
+	requireCursor(t, cp, `{"value":7,"ids":["a"]}`)
+}
+
+func TestCursorQuery(t *testing.T) {
+	schemaDescription := &SchemaDescription{Columns: []abstract.ColSchema{
+		{ColumnName: "@timestamp", OriginalType: "elasticsearch:date"},
+		{ColumnName: "nanos", OriginalType: "elasticsearch:date_nanos"},
+	}}
+
+	require.Equal(t, map[string]any{"match_all": map[string]any{}}, cursorQuery("_id", nil, schemaDescription))
+
+	query, err := json.Marshal(cursorQuery("@timestamp", &cursor{Value: json.RawMessage("1704164645000"), IDs: []string{"a", "b"}}, schemaDescription))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"bool":{"filter":[
+		{"exists":{"field":"@timestamp"}},
+		{"bool":{"minimum_should_match":1,"should":[
+			{"range":{"@timestamp":{"gt":1704164645000,"format":"epoch_millis"}}},
+			{"bool":{
+				"filter":[{"range":{"@timestamp":{"gte":1704164645000,"lte":1704164645000,"format":"epoch_millis"}}}],
+				"must_not":[{"ids":{"values":["a","b"]}}]
+			}}
+		]}}
+	]}}`, string(query))
+
+	query, err = json.Marshal(cursorQuery("nanos", parseCursor("1704164645000000001"), schemaDescription))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"bool":{"filter":[
+		{"exists":{"field":"nanos"}},
+		{"range":{"nanos":{"gte":"1704164645000.000001","format":"epoch_millis"}}}
+	]}}`, string(query))
+
+	require.Equal(t, "0.000012", nanosToMillis("12"))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -26,10 +27,11 @@ const (
 )
 
 type Storage struct {
-	Cfg     *elasticsearch.Config
-	Client  *elasticsearch.Client
-	Metrics *stats.SourceStats
-	IsHomo  bool
+	Cfg        *elasticsearch.Config
+	Client     *elasticsearch.Client
+	Metrics    *stats.SourceStats
+	IsHomo     bool
+	ServerType ServerType
 }
 
 func (s *Storage) Close() {
@@ -150,7 +152,8 @@ func (s *Storage) TableExists(table abstract.TableID) (bool, error) {
 	return true, nil
 }
 
-func (s *Storage) TableList(includeTableFilter abstract.IncludeTableList) (abstract.TableMap, error) {
+// indexNames returns names of all user indices
+func (s *Storage) indexNames() ([]string, error) {
 	body, err := getResponseBody(s.Client.Indices.Stats())
 	if err != nil {
 		return nil, xerrors.Errorf("unable to fetch elastic stats: %w", err)
@@ -160,13 +163,27 @@ func (s *Storage) TableList(includeTableFilter abstract.IncludeTableList) (abstr
 		return nil, xerrors.Errorf("failed to unmarshal elastic stats: %w", err)
 	}
 
-	tables := make(abstract.TableMap)
-
+	var result []string
 	for index := range stats.Indices {
 		if strings.HasPrefix(index, ".") {
 			// skip internal indices like .geoip_databases for example
 			continue
 		}
+		result = append(result, index)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func (s *Storage) TableList(includeTableFilter abstract.IncludeTableList) (abstract.TableMap, error) {
+	indices, err := s.indexNames()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to list indices: %w", err)
+	}
+
+	tables := make(abstract.TableMap)
+
+	for _, index := range indices {
 		schema, err := s.getSchema(index)
 		if err != nil {
 			return nil, xerrors.Errorf("failed to fetch schema, index %s : %w", index, err)
@@ -268,9 +285,10 @@ func NewStorage(src *ElasticSearchSource, logger log.Logger, mRegistry metrics.R
 	}
 
 	return WithOpts(&Storage{
-		Cfg:     config,
-		Client:  client,
-		Metrics: stats.NewSourceStats(mRegistry),
-		IsHomo:  false,
+		Cfg:        config,
+		Client:     client,
+		Metrics:    stats.NewSourceStats(mRegistry),
+		IsHomo:     false,
+		ServerType: serverType,
 	}, opts...), nil
 }
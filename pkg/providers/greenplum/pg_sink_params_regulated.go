@@ -23,6 +23,7 @@ type PgSinkParamsRegulated struct {
 	FCopyUpload               bool
 	FIgnoreUniqueConstraint   bool
 	FDisableSQLFallback       bool
+	FBulkUpsert               bool
 	FQueryTimeout             time.Duration
 }
 
@@ -94,6 +95,10 @@ func (p PgSinkParamsRegulated) DisableSQLFallback() bool {
 	return p.FDisableSQLFallback
 }
 
+func (p PgSinkParamsRegulated) BulkUpsert() bool {
+	return p.FBulkUpsert
+}
+
 func (p PgSinkParamsRegulated) QueryTimeout() time.Duration {
 	return p.FQueryTimeout
 }
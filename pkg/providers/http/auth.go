@@ -0,0 +1,54 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const signaturePrefix = "sha256="
+
+// Sign returns the signature of a body, which is passed in the signature header
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// authorize sets the auth headers of a request with the given body
+func authorize(req *http.Request, auth *HTTPAuth, body []byte) {
+	if auth == nil {
+		return
+	}
+	switch auth.Type {
+	case AuthBasic:
+		req.SetBasicAuth(auth.User, string(auth.Password))
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+string(auth.Token))
+	case AuthHMAC:
+		req.Header.Set(auth.SignatureHeader, Sign(string(auth.Secret), body))
+	}
+}
+
+// verify checks the auth headers of a request with the given body
+func verify(req *http.Request, auth *HTTPAuth, body []byte) bool {
+	switch auth.Type {
+	case AuthBasic:
+		user, password, ok := req.BasicAuth()
+		return ok && equal(user, auth.User) && equal(password, string(auth.Password))
+	case AuthBearer:
+		token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		return ok && equal(token, string(auth.Token))
+	case AuthHMAC:
+		return equal(req.Header.Get(auth.SignatureHeader), Sign(string(auth.Secret), body))
+	default:
+		return true
+	}
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
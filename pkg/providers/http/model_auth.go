@@ -0,0 +1,62 @@
+package http
+
+import (
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract/model"
+)
+
+type AuthType string
+
+const (
+	AuthNone   = AuthType("")
+	AuthBasic  = AuthType("basic")
+	AuthBearer = AuthType("bearer")
+	AuthHMAC   = AuthType("hmac")
+
+	DefaultSignatureHeader = "X-Signature"
+)
+
+// HTTPAuth authenticates requests, the same settings are used to sign requests and to verify them
+type HTTPAuth struct {
+	Type AuthType
+
+	// User & Password are credentials of the basic auth
+	User     string
+	Password model.SecretString
+
+	// Token is a token of the bearer auth
+	Token model.SecretString
+
+	// Secret is a key of the HMAC-SHA256 signature of a request body.
+	// The signature is passed in SignatureHeader as 'sha256=<hex digest>'
+	Secret          model.SecretString
+	SignatureHeader string
+}
+
+func (a *HTTPAuth) WithDefaults() {
+	if a.Type == AuthHMAC && a.SignatureHeader == "" {
+		a.SignatureHeader = DefaultSignatureHeader
+	}
+}
+
+func (a *HTTPAuth) Validate() error {
+	switch a.Type {
+	case AuthNone:
+		return nil
+	case AuthBasic:
+		if a.User == "" {
+			return xerrors.New("user is required for basic auth")
+		}
+	case AuthBearer:
+		if a.Token == "" {
+			return xerrors.New("token is required for bearer auth")
+		}
+	case AuthHMAC:
+		if a.Secret == "" {
+			return xerrors.New("secret is required for HMAC auth")
+		}
+	default:
+		return xerrors.Errorf("unknown auth type: %s", a.Type)
+	}
+	return nil
+}
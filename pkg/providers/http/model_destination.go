@@ -0,0 +1,140 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	debezium_prod_status "github.com/transferia/transferia/pkg/debezium/prodstatus"
+	"github.com/transferia/transferia/pkg/middlewares/async/bufferer"
+)
+
+type Format string
+
+const (
+	FormatJSON     = Format("json")
+	FormatCSV      = Format("csv")
+	FormatRaw      = Format("raw")
+	FormatDebezium = Format("debezium")
+
+	defaultBatchSize    = 500
+	defaultTimeout      = 30 * time.Second
+	defaultRetryTimeout = 5 * time.Minute
+)
+
+var contentTypes = map[Format]string{
+	FormatJSON:     "application/x-ndjson",
+	FormatCSV:      "text/csv",
+	FormatRaw:      "application/octet-stream",
+	FormatDebezium: "application/x-ndjson",
+}
+
+type HTTPDestination struct {
+	URL string
+	// Method is an HTTP method of requests, POST by default
+	Method  string
+	Headers map[string]string
+	Auth    HTTPAuth
+
+	// Format is a serialization format of rows: JSON lines, CSV, raw messages of mirrored queues or Debezium values
+	Format           Format
+	DebeziumSettings map[string]string
+	AnyAsString      bool
+
+	// BodyTemplate is a text/template of a request body, which receives serialized rows of one table in .Items,
+	// see BodyTemplateData. Serialized rows are separated by new lines if empty
+	BodyTemplate string
+	Gzip         bool
+
+	// BatchSize is the max number of rows sent by one request
+	BatchSize int
+	Timeout   time.Duration
+	// RetryTimeout is the max duration of retries of a request, which fails with a network error, 408, 429 or 5xx status code
+	RetryTimeout time.Duration
+
+	// DeadLetterURL receives requests rejected with 4xx status codes, which are not retried.
+	// The rejected request is sent as is with the status & the response in X-Dead-Letter-* headers.
+	// Such requests fail the transfer if empty
+	DeadLetterURL string
+
+	BufferTriggingCount    int
+	BufferTriggingSize     uint64
+	BufferTriggingInterval time.Duration
+}
+
+var _ model.Destination = (*HTTPDestination)(nil)
+
+func (d *HTTPDestination) WithDefaults() {
+	if d.Method == "" {
+		d.Method = http.MethodPost
+	}
+	if d.Format == "" {
+		d.Format = FormatJSON
+	}
+	if d.BatchSize == 0 {
+		d.BatchSize = defaultBatchSize
+	}
+	if d.Timeout == 0 {
+		d.Timeout = defaultTimeout
+	}
+	if d.RetryTimeout == 0 {
+		d.RetryTimeout = defaultRetryTimeout
+	}
+	d.Auth.WithDefaults()
+}
+
+func (d *HTTPDestination) CleanupMode() model.CleanupType {
+	return model.DisabledCleanup
+}
+
+func (HTTPDestination) IsDestination() {}
+
+func (d *HTTPDestination) GetProviderType() abstract.ProviderType {
+	return ProviderType
+}
+
+func (d *HTTPDestination) Validate() error {
+	if d.URL == "" {
+		return xerrors.New("URL is required")
+	}
+	if _, ok := contentTypes[d.Format]; !ok {
+		return xerrors.Errorf("unknown format: %s", d.Format)
+	}
+	if d.BatchSize <= 0 {
+		return xerrors.Errorf("batch size must be positive, got %d", d.BatchSize)
+	}
+	if _, err := parseBodyTemplate(d.BodyTemplate); err != nil {
+		return xerrors.Errorf("invalid body template: %w", err)
+	}
+	if err := d.Auth.Validate(); err != nil {
+		return xerrors.Errorf("invalid auth: %w", err)
+	}
+	return nil
+}
+
+func (d *HTTPDestination) Compatible(src model.Source, transferType abstract.TransferType) error {
+	switch d.Format {
+	case FormatDebezium:
+		if debezium_prod_status.IsSupportedSource(src.GetProviderType().Name(), transferType) {
+			return nil
+		}
+		return xerrors.Errorf("in debezium format not supported source type: %s", src.GetProviderType().Name())
+	case FormatRaw:
+		if model.IsDefaultMirrorSource(src) {
+			return nil
+		}
+		return xerrors.New("raw format is supported only by default mirror source types")
+	default:
+		return nil
+	}
+}
+
+func (d *HTTPDestination) BuffererConfig() *bufferer.BuffererConfig {
+	return &bufferer.BuffererConfig{
+		TriggingCount:    d.BufferTriggingCount,
+		TriggingSize:     d.BufferTriggingSize,
+		TriggingInterval: d.BufferTriggingInterval,
+	}
+}
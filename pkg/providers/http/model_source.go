@@ -0,0 +1,119 @@
+package http
+
+import (
+	"strings"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/parsers"
+)
+
+const (
+	defaultListen      = ":8080"
+	defaultMaxBodySize = 10 * 1024 * 1024
+	defaultPushTimeout = time.Minute
+)
+
+var (
+	_ model.Source = (*HTTPSource)(nil)
+)
+
+type HTTPSource struct {
+	// Listen is an address of the server started by the replication worker
+	Listen string
+	// TLSCertFile & TLSKeyFile enable HTTPS
+	TLSCertFile string
+	TLSKeyFile  string
+	Auth        HTTPAuth
+
+	// Routes maps request paths to table names, any path is accepted as a table name without the leading slash if empty
+	Routes map[string]string
+
+	// MaxBodySize limits the size of a request body, larger requests are rejected with 413
+	MaxBodySize model.BytesSize
+	// BufferSize limits the size of bodies, which are received but not pushed yet, requests above it are rejected with 429
+	BufferSize model.BytesSize
+	// PushTimeout limits the time of a push, a request is rejected with 503 if the push is not completed in time
+	PushTimeout time.Duration
+
+	ParserConfig map[string]interface{}
+}
+
+func (s *HTTPSource) GetProviderType() abstract.ProviderType {
+	return ProviderType
+}
+
+func (s *HTTPSource) Validate() error {
+	if (s.TLSCertFile == "") != (s.TLSKeyFile == "") {
+		return xerrors.New("both TLS certificate and key files are required")
+	}
+	for path, table := range s.Routes {
+		if !strings.HasPrefix(path, "/") {
+			return xerrors.Errorf("route %q must start with '/'", path)
+		}
+		if table == "" {
+			return xerrors.Errorf("table of route %q is required", path)
+		}
+	}
+	if err := s.Auth.Validate(); err != nil {
+		return xerrors.Errorf("invalid auth: %w", err)
+	}
+	if s.ParserConfig != nil {
+		parserConfigStruct, err := parsers.ParserConfigMapToStruct(s.ParserConfig)
+		if err != nil {
+			return xerrors.Errorf("unable to create new parser config, err: %w", err)
+		}
+		return parserConfigStruct.Validate()
+	}
+	return nil
+}
+
+func (s *HTTPSource) WithDefaults() {
+	if s.Listen == "" {
+		s.Listen = defaultListen
+	}
+	if s.MaxBodySize == 0 {
+		s.MaxBodySize = defaultMaxBodySize
+	}
+	if s.BufferSize == 0 {
+		s.BufferSize = 100 * 1024 * 1024
+	}
+	if s.PushTimeout == 0 {
+		s.PushTimeout = defaultPushTimeout
+	}
+	s.Auth.WithDefaults()
+}
+
+func (s *HTTPSource) IsSource() {}
+
+func (s *HTTPSource) IsAppendOnly() bool {
+	if s.ParserConfig == nil {
+		return false
+	} else {
+		parserConfigStruct, _ := parsers.ParserConfigMapToStruct(s.ParserConfig)
+		if parserConfigStruct == nil {
+			return false
+		}
+		return parserConfigStruct.IsAppendOnly()
+	}
+}
+
+func (s *HTTPSource) IsDefaultMirror() bool {
+	return s.ParserConfig == nil
+}
+
+func (s *HTTPSource) Parser() map[string]interface{} {
+	return s.ParserConfig
+}
+
+// Table returns the table of a request path, false if the path is not routed
+func (s *HTTPSource) Table(path string) (string, bool) {
+	if len(s.Routes) == 0 {
+		table := strings.Trim(path, "/")
+		return table, table != ""
+	}
+	table, ok := s.Routes[path]
+	return table, ok
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"maps"
+
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/coordinator"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	debeziumparameters "github.com/transferia/transferia/pkg/debezium/parameters"
+	"github.com/transferia/transferia/pkg/middlewares"
+	"github.com/transferia/transferia/pkg/providers"
+	"github.com/transferia/transferia/pkg/util/gobwrapper"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+func init() {
+	gobwrapper.Register(new(HTTPSource))
+	gobwrapper.Register(new(HTTPDestination))
+	model.RegisterSource(ProviderType, func() model.Source {
+		return new(HTTPSource)
+	})
+	model.RegisterDestination(ProviderType, func() model.Destination {
+		return new(HTTPDestination)
+	})
+	abstract.RegisterProviderName(ProviderType, "HTTP")
+
+	providers.Register(ProviderType, New)
+}
+
+const ProviderType = abstract.ProviderType("http")
+
+// To verify providers contract implementation
+var (
+	_ providers.Replication = (*Provider)(nil)
+	_ providers.Sinker      = (*Provider)(nil)
+
+	_ providers.Activator = (*Provider)(nil)
+)
+
+type Provider struct {
+	logger   log.Logger
+	registry metrics.Registry
+	cp       coordinator.Coordinator
+	transfer *model.Transfer
+}
+
+func (p *Provider) Type() abstract.ProviderType {
+	return ProviderType
+}
+
+func (p *Provider) Source() (abstract.Source, error) {
+	src, ok := p.transfer.Src.(*HTTPSource)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected source type: %T", p.transfer.Src)
+	}
+	return NewSource(src, p.logger, p.registry)
+}
+
+func (p *Provider) Sink(middlewares.Config) (abstract.Sinker, error) {
+	dst, ok := p.transfer.Dst.(*HTTPDestination)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected target type: %T", p.transfer.Dst)
+	}
+	cfgCopy := *dst
+	cfgCopy.DebeziumSettings = inferDebeziumSettings(p.transfer.Src, dst.DebeziumSettings)
+	return NewSink(&cfgCopy, p.logger, p.registry)
+}
+
+// inferDebeziumSettings sets the source type of the debezium emitter for sources with known original types of columns
+func inferDebeziumSettings(src model.Source, settings map[string]string) map[string]string {
+	result := maps.Clone(settings)
+	if result == nil {
+		result = make(map[string]string)
+	}
+	if _, ok := result[debeziumparameters.SourceType]; ok || src == nil {
+		return result
+	}
+	switch name := src.GetProviderType().Name(); name {
+	case "pg", "mysql", "mongo":
+		result[debeziumparameters.SourceType] = name
+	}
+	return result
+}
+
+func (p *Provider) Activate(_ context.Context, _ *model.TransferOperation, _ abstract.TableMap, _ providers.ActivateCallbacks) error {
+	if p.transfer.SrcType() == ProviderType && !p.transfer.IncrementOnly() {
+		return xerrors.New("Only allowed mode for HTTP source is replication")
+	}
+	return nil
+}
+
+func New(lgr log.Logger, registry metrics.Registry, cp coordinator.Coordinator, transfer *model.Transfer) providers.Provider {
+	return &Provider{
+		logger:   lgr,
+		registry: registry,
+		cp:       cp,
+		transfer: transfer,
+	}
+}
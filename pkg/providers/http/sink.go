@@ -0,0 +1,295 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	debeziumparameters "github.com/transferia/transferia/pkg/debezium/parameters"
+	"github.com/transferia/transferia/pkg/serializer"
+	"github.com/transferia/transferia/pkg/serializer/queue"
+	"github.com/transferia/transferia/pkg/stats"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const (
+	DeadLetterStatusHeader   = "X-Dead-Letter-Status"
+	DeadLetterResponseHeader = "X-Dead-Letter-Response"
+	DeadLetterTableHeader    = "X-Dead-Letter-Table"
+
+	maxResponseSample = 1024
+)
+
+// BodyTemplateData is passed to BodyTemplate, `join` function is available to join .Items
+type BodyTemplateData struct {
+	Schema string
+	Table  string
+	// Items are serialized rows of the table without trailing new lines
+	Items []string
+}
+
+func parseBodyTemplate(text string) (*template.Template, error) {
+	return template.New("body").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+}
+
+// itemsSerializer serializes row events, the result is grouped by tables & keeps the order of rows of each table
+type itemsSerializer func(items []abstract.ChangeItem) (map[abstract.TableID][]string, error)
+
+type sink struct {
+	cfg       *HTTPDestination
+	logger    log.Logger
+	metrics   *stats.SinkerStats
+	client    *http.Client
+	serialize itemsSerializer
+	tmpl      *template.Template
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func (s *sink) Close() error {
+	s.cancel()
+	return nil
+}
+
+func (s *sink) Push(input []abstract.ChangeItem) error {
+	var rows []abstract.ChangeItem
+	var tableOrder []abstract.TableID
+	seen := map[abstract.TableID]bool{}
+	for _, item := range input {
+		if !item.IsRowEvent() {
+			continue
+		}
+		if !seen[item.TableID()] {
+			seen[item.TableID()] = true
+			tableOrder = append(tableOrder, item.TableID())
+		}
+		rows = append(rows, item)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	tableItems, err := s.serialize(rows)
+	if err != nil {
+		return xerrors.Errorf("unable to serialize %d rows: %w", len(rows), err)
+	}
+	for _, table := range tableOrder {
+		items := tableItems[table]
+		for i := 0; i < len(items); i += s.cfg.BatchSize {
+			end := min(i+s.cfg.BatchSize, len(items))
+			body, err := s.buildBody(table, items[i:end])
+			if err != nil {
+				return xerrors.Errorf("unable to build body of table %s: %w", table.Fqtn(), err)
+			}
+			if err := s.send(table, body); err != nil {
+				return xerrors.Errorf("unable to send %d rows of table %s: %w", end-i, table.Fqtn(), err)
+			}
+			s.metrics.Table(table.Fqtn(), "rows", end-i)
+		}
+	}
+	return nil
+}
+
+func (s *sink) buildBody(table abstract.TableID, items []string) ([]byte, error) {
+	var buf bytes.Buffer
+	if s.tmpl != nil {
+		if err := s.tmpl.Execute(&buf, BodyTemplateData{Schema: table.Namespace, Table: table.Name, Items: items}); err != nil {
+			return nil, xerrors.Errorf("unable to execute body template: %w", err)
+		}
+	} else {
+		for _, item := range items {
+			buf.WriteString(item)
+			buf.WriteByte('\n')
+		}
+	}
+	if !s.cfg.Gzip {
+		return buf.Bytes(), nil
+	}
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(buf.Bytes()); err != nil {
+		return nil, xerrors.Errorf("unable to compress body: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, xerrors.Errorf("unable to compress body: %w", err)
+	}
+	return compressed.Bytes(), nil
+}
+
+// send delivers a body to the URL, requests rejected with non-retryable status codes are sent to the dead letter URL if any
+func (s *sink) send(table abstract.TableID, body []byte) error {
+	status, response, err := s.post(s.cfg.URL, body, nil)
+	if err != nil {
+		return xerrors.Errorf("request failed: %w", err)
+	}
+	if isSuccess(status) {
+		return nil
+	}
+	if s.cfg.DeadLetterURL == "" {
+		return abstract.NewFatalError(xerrors.Errorf("request rejected: %d %s: %s", status, http.StatusText(status), response))
+	}
+
+	s.logger.Warn("request rejected, sending it to the dead letter URL",
+		log.String("table", table.Fqtn()), log.Int("status", status), log.String("response", response))
+	headers := map[string]string{
+		DeadLetterStatusHeader:   fmt.Sprint(status),
+		DeadLetterResponseHeader: strings.Join(strings.Fields(response), " "),
+		DeadLetterTableHeader:    table.Fqtn(),
+	}
+	status, response, err = s.post(s.cfg.DeadLetterURL, body, headers)
+	if err != nil {
+		return xerrors.Errorf("dead letter request failed: %w", err)
+	}
+	if !isSuccess(status) {
+		return abstract.NewFatalError(xerrors.Errorf("dead letter request rejected: %d %s: %s", status, http.StatusText(status), response))
+	}
+	return nil
+}
+
+// post sends a request retrying network errors & retryable status codes,
+// it returns the status code & the sample of the response of the last attempt
+func (s *sink) post(url string, body []byte, headers map[string]string) (int, string, error) {
+	var status int
+	var response string
+	b := backoff.NewExponentialBackOff(backoff.WithMaxElapsedTime(s.cfg.RetryTimeout))
+	err := backoff.Retry(func() error {
+		req, err := s.newRequest(url, body, headers)
+		if err != nil {
+			return backoff.Permanent(xerrors.Errorf("unable to make request: %w", err))
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return xerrors.Errorf("unable to send request: %w", err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSample))
+		if err != nil {
+			return xerrors.Errorf("unable to read response: %w", err)
+		}
+		status, response = resp.StatusCode, string(data)
+		if isRetryable(status) {
+			return xerrors.Errorf("retryable status: %d %s: %s", status, http.StatusText(status), response)
+		}
+		return nil
+	}, backoff.WithContext(b, s.ctx))
+	if err != nil {
+		return 0, "", xerrors.Errorf("retries exceeded: %w", err)
+	}
+	return status, response, nil
+}
+
+func (s *sink) newRequest(url string, body []byte, headers map[string]string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(s.ctx, s.cfg.Method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentTypes[s.cfg.Format])
+	if s.cfg.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for name, value := range s.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	authorize(req, &s.cfg.Auth, body)
+	return req, nil
+}
+
+func isSuccess(status int) bool {
+	return status >= 200 && status < 300
+}
+
+// isRetryable reports whether a request rejected with the status code may succeed later
+func isRetryable(status int) bool {
+	return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500
+}
+
+func newItemsSerializer(cfg *HTTPDestination, logger log.Logger) (itemsSerializer, error) {
+	var rowSerializer serializer.Serializer
+	switch cfg.Format {
+	case FormatJSON:
+		rowSerializer = serializer.NewJSONSerializer(&serializer.JSONSerializerConfig{
+			UnsupportedItemKinds: nil,
+			AddClosingNewLine:    false,
+			AnyAsString:          cfg.AnyAsString,
+		})
+	case FormatCSV:
+		rowSerializer = serializer.NewCsvSerializer()
+	case FormatRaw:
+		rowSerializer = serializer.NewRawSerializer(nil)
+	case FormatDebezium:
+		debeziumSerializer, err := queue.NewDebeziumSerializer(debeziumparameters.EnrichedWithDefaults(cfg.DebeziumSettings), false, false, false, logger)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to create debezium serializer: %w", err)
+		}
+		return func(items []abstract.ChangeItem) (map[abstract.TableID][]string, error) {
+			messages, err := debeziumSerializer.Serialize(items)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to serialize: %w", err)
+			}
+			result := make(map[abstract.TableID][]string)
+			for part, partMessages := range messages {
+				for _, message := range partMessages {
+					if message.Value == nil {
+						// tombstones make no sense for HTTP
+						continue
+					}
+					result[part.TableID] = append(result[part.TableID], string(message.Value))
+				}
+			}
+			return result, nil
+		}, nil
+	default:
+		return nil, xerrors.Errorf("unknown format: %s", cfg.Format)
+	}
+	return func(items []abstract.ChangeItem) (map[abstract.TableID][]string, error) {
+		result := make(map[abstract.TableID][]string)
+		for i := range items {
+			data, err := rowSerializer.Serialize(&items[i])
+			if err != nil {
+				return nil, xerrors.Errorf("unable to serialize row of table %s: %w", items[i].Fqtn(), err)
+			}
+			if data == nil {
+				continue
+			}
+			result[items[i].TableID()] = append(result[items[i].TableID()], strings.TrimSuffix(string(data), "\n"))
+		}
+		return result, nil
+	}, nil
+}
+
+func NewSink(cfg *HTTPDestination, logger log.Logger, registry metrics.Registry) (abstract.Sinker, error) {
+	var tmpl *template.Template
+	if cfg.BodyTemplate != "" {
+		var err error
+		if tmpl, err = parseBodyTemplate(cfg.BodyTemplate); err != nil {
+			return nil, xerrors.Errorf("unable to parse body template: %w", err)
+		}
+	}
+	serialize, err := newItemsSerializer(cfg, logger)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create serializer: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &sink{
+		cfg:       cfg,
+		logger:    logger,
+		metrics:   stats.NewSinkerStats(registry),
+		client:    &http.Client{Timeout: cfg.Timeout},
+		serialize: serialize,
+		tmpl:      tmpl,
+		ctx:       ctx,
+		cancel:    cancel,
+	}, nil
+}
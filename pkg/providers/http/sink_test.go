@@ -0,0 +1,171 @@
+package http
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/pkg/abstract"
+	debeziumparameters "github.com/transferia/transferia/pkg/debezium/parameters"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+type request struct {
+	header http.Header
+	body   string
+}
+
+// recorder records requests & responds with the given status codes in turn, the last one is repeated
+type recorder struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []request
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var reader io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reader = gz
+	}
+	body, _ := io.ReadAll(reader)
+	r.requests = append(r.requests, request{header: req.Header.Clone(), body: string(body)})
+	status := r.statuses[0]
+	if len(r.statuses) > 1 {
+		r.statuses = r.statuses[1:]
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(http.StatusText(status)))
+}
+
+func runServer(t *testing.T, statuses ...int) (*recorder, string) {
+	rec := &recorder{mu: sync.Mutex{}, statuses: statuses, requests: nil}
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+	return rec, srv.URL
+}
+
+var testTableSchema = abstract.NewTableSchema([]abstract.ColSchema{
+	{ColumnName: "id", DataType: schema.TypeInt32.String(), PrimaryKey: true, OriginalType: "pg:integer"},
+	{ColumnName: "name", DataType: schema.TypeString.String(), OriginalType: "pg:text"},
+})
+
+func makeItems(table string, count int) []abstract.ChangeItem {
+	var items []abstract.ChangeItem
+	for i := 1; i <= count; i++ {
+		items = append(items, abstract.ChangeItem{
+			Kind:         abstract.InsertKind,
+			Schema:       "public",
+			Table:        table,
+			ColumnNames:  []string{"id", "name"},
+			ColumnValues: []interface{}{int32(i), "name"},
+			TableSchema:  testTableSchema,
+		})
+	}
+	return items
+}
+
+func newTestSink(t *testing.T, dst *HTTPDestination) abstract.Sinker {
+	dst.WithDefaults()
+	require.NoError(t, dst.Validate())
+	sink, err := NewSink(dst, logger.Log, solomon.NewRegistry(nil))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, sink.Close()) })
+	return sink
+}
+
+func TestSink(t *testing.T) {
+	rec, url := runServer(t, http.StatusOK)
+	sink := newTestSink(t, &HTTPDestination{
+		URL:          url,
+		Headers:      map[string]string{"X-Source": "transfer"},
+		Auth:         HTTPAuth{Type: AuthHMAC, Secret: "secret"},
+		BodyTemplate: `{"table": "{{.Table}}", "rows": [{{join .Items ","}}]}`,
+		Gzip:         true,
+		BatchSize:    2,
+	})
+
+	items := append(makeItems("users", 3), abstract.ChangeItem{Kind: abstract.DoneTableLoad, Schema: "public", Table: "users"})
+	require.NoError(t, sink.Push(append(items, makeItems("orders", 1)...)))
+
+	require.Len(t, rec.requests, 3)
+	require.Equal(t, `{"table": "users", "rows": [{"id":1,"name":"name"},{"id":2,"name":"name"}]}`, rec.requests[0].body)
+	require.Equal(t, `{"table": "users", "rows": [{"id":3,"name":"name"}]}`, rec.requests[1].body)
+	require.Equal(t, `{"table": "orders", "rows": [{"id":1,"name":"name"}]}`, rec.requests[2].body)
+	require.Equal(t, "transfer", rec.requests[0].header.Get("X-Source"))
+	require.Equal(t, "application/x-ndjson", rec.requests[0].header.Get("Content-Type"))
+	require.Regexp(t, "^sha256=[0-9a-f]{64}$", rec.requests[0].header.Get(DefaultSignatureHeader))
+}
+
+func TestSinkFormats(t *testing.T) {
+	rec, url := runServer(t, http.StatusOK)
+	sink := newTestSink(t, &HTTPDestination{URL: url, Format: FormatCSV, Auth: HTTPAuth{Type: AuthBearer, Token: "token"}})
+	require.NoError(t, sink.Push(makeItems("users", 2)))
+	require.Len(t, rec.requests, 1)
+	require.Equal(t, "1,name\n2,name\n", rec.requests[0].body)
+	require.Equal(t, "Bearer token", rec.requests[0].header.Get("Authorization"))
+
+	rec, url = runServer(t, http.StatusOK)
+	sink = newTestSink(t, &HTTPDestination{URL: url, Format: FormatDebezium, DebeziumSettings: map[string]string{debeziumparameters.SourceType: "pg"}})
+	require.NoError(t, sink.Push(makeItems("users", 1)))
+	require.Len(t, rec.requests, 1)
+	require.Contains(t, rec.requests[0].body, `"after":{"id":1,"name":"name"}`)
+}
+
+func TestSinkRetries(t *testing.T) {
+	rec, url := runServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusNoContent)
+	sink := newTestSink(t, &HTTPDestination{URL: url, RetryTimeout: time.Minute})
+	require.NoError(t, sink.Push(makeItems("users", 1)))
+	require.Len(t, rec.requests, 3)
+
+	rec, url = runServer(t, http.StatusBadGateway)
+	sink = newTestSink(t, &HTTPDestination{URL: url, RetryTimeout: time.Second})
+	err := sink.Push(makeItems("users", 1))
+	require.Error(t, err)
+	require.False(t, abstract.IsFatal(err))
+	require.Greater(t, len(rec.requests), 1)
+}
+
+func TestSinkDeadLetter(t *testing.T) {
+	rec, url := runServer(t, http.StatusBadRequest)
+	sink := newTestSink(t, &HTTPDestination{URL: url})
+	err := sink.Push(makeItems("users", 1))
+	require.Error(t, err)
+	require.True(t, abstract.IsFatal(err))
+	require.Len(t, rec.requests, 1)
+
+	deadLetters, deadLetterURL := runServer(t, http.StatusOK)
+	rec, url = runServer(t, http.StatusUnprocessableEntity)
+	sink = newTestSink(t, &HTTPDestination{URL: url, DeadLetterURL: deadLetterURL})
+	require.NoError(t, sink.Push(makeItems("users", 1)))
+	require.Len(t, rec.requests, 1)
+	require.Len(t, deadLetters.requests, 1)
+	require.Equal(t, rec.requests[0].body, deadLetters.requests[0].body)
+	require.Equal(t, "422", deadLetters.requests[0].header.Get(DeadLetterStatusHeader))
+	require.Equal(t, "Unprocessable Entity", deadLetters.requests[0].header.Get(DeadLetterResponseHeader))
+	require.Equal(t, `"public"."users"`, deadLetters.requests[0].header.Get(DeadLetterTableHeader))
+}
+
+func TestDestinationBatchSize(t *testing.T) {
+	dst := &HTTPDestination{URL: "http://localhost"}
+	dst.WithDefaults()
+	require.NoError(t, dst.Validate())
+
+	dst.BatchSize = 0
+	require.Error(t, dst.Validate())
+	dst.BatchSize = -1
+	require.Error(t, dst.Validate())
+}
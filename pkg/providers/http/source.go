@@ -0,0 +1,292 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/parsers"
+	"github.com/transferia/transferia/pkg/serverutil"
+	"github.com/transferia/transferia/pkg/stats"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const pingPath = "/ping"
+
+var (
+	_ abstract.Source = (*Source)(nil)
+)
+
+// Source is an HTTP server, which parses request bodies & pushes them into the sink.
+// A request is responded with 200 only after its rows are pushed, so producers retry anything which is not pushed
+type Source struct {
+	config  *HTTPSource
+	logger  log.Logger
+	metrics *stats.SourceStats
+	parser  parsers.Parser
+	server  *serverutil.Server
+
+	// pushMutex keeps the order of pushes the same as the order of parsed requests
+	pushMutex sync.Mutex
+	sink      abstract.AsyncSink
+
+	inflightMutex sync.Mutex
+	inflightBytes int
+	offset        int64
+
+	errMutex  sync.Mutex
+	lastError error
+
+	ctx    context.Context
+	cancel func()
+}
+
+func (s *Source) setError(err error) {
+	s.errMutex.Lock()
+	defer s.errMutex.Unlock()
+	if s.lastError == nil {
+		s.lastError = err
+	}
+}
+
+func (s *Source) getError() error {
+	s.errMutex.Lock()
+	defer s.errMutex.Unlock()
+	return s.lastError
+}
+
+// acquire reserves the size of a body in the buffer, it returns the offset of the request or false if the buffer is full
+func (s *Source) acquire(size int) (int64, bool) {
+	s.inflightMutex.Lock()
+	defer s.inflightMutex.Unlock()
+	if s.inflightBytes > 0 && s.inflightBytes+size > int(s.config.BufferSize) {
+		return 0, false
+	}
+	s.inflightBytes += size
+	s.offset++
+	return s.offset, true
+}
+
+func (s *Source) release(size int) {
+	s.inflightMutex.Lock()
+	defer s.inflightMutex.Unlock()
+	s.inflightBytes -= size
+}
+
+func (s *Source) Run(sink abstract.AsyncSink) error {
+	s.sink = sink
+	s.metrics.Master.Set(1)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.server.ServeHandler(s, s.config.TLSCertFile, s.config.TLSKeyFile)
+	}()
+	s.logger.Info("server is ready", log.String("address", s.server.Addr().String()))
+
+	select {
+	case err := <-serveErr:
+		s.cancel()
+		if err != nil {
+			return xerrors.Errorf("unable to serve: %w", err)
+		}
+	case <-s.ctx.Done():
+		if err := s.server.Close(); err != nil {
+			s.logger.Warn("unable to close server", log.Error(err))
+		}
+	}
+	if err := s.getError(); err != nil {
+		return xerrors.Errorf("unable to push: %w", err)
+	}
+	return nil
+}
+
+func (s *Source) Stop() {
+	s.cancel()
+}
+
+// Addr returns the address of the server, which is useful when the port is chosen by the system
+func (s *Source) Addr() string {
+	return s.server.Addr().String()
+}
+
+func (s *Source) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == pingPath && r.Method == http.MethodGet {
+		serverutil.PingFunc(w, r)
+		return
+	}
+	table, ok := s.config.Table(r.URL.Path)
+	if !ok {
+		http.Error(w, "unknown path", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.ctx.Err() != nil {
+		http.Error(w, "source is stopped", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(s.config.MaxBodySize)))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if xerrors.As(err, &maxBytesErr) {
+			http.Error(w, "body is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "unable to read body", http.StatusBadRequest)
+		return
+	}
+	// the signature covers the body as it's sent, compressed or not
+	if !verify(r, &s.config.Auth, body) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		if body, err = decompress(body, int64(s.config.MaxBodySize)); err != nil {
+			http.Error(w, "unable to decompress body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	offset, ok := s.acquire(len(body))
+	if !ok {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "buffer is full", http.StatusTooManyRequests)
+		return
+	}
+	defer s.release(len(body))
+
+	rows, err := s.push(table, offset, body, r.Header)
+	if err != nil {
+		s.logger.Warn("unable to push request", log.String("table", table), log.Error(err))
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "unable to push rows", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]int{"rows": rows})
+}
+
+// push parses a body & pushes the rows into the sink, it returns the number of pushed rows
+func (s *Source) push(table string, offset int64, body []byte, header http.Header) (int, error) {
+	items := s.parse(table, offset, body, header)
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	pushSt := time.Now()
+	s.pushMutex.Lock()
+	errCh := s.sink.AsyncPush(items)
+	s.pushMutex.Unlock()
+
+	timer := time.NewTimer(s.config.PushTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			// a failed push breaks the order of rows, so the transfer is restarted
+			s.setError(err)
+			s.cancel()
+			return 0, xerrors.Errorf("push failed: %w", err)
+		}
+	case <-timer.C:
+		return 0, xerrors.Errorf("push is not completed in %v", s.config.PushTimeout)
+	case <-s.ctx.Done():
+		return 0, xerrors.New("source is stopped")
+	}
+	s.metrics.PushTime.RecordDuration(time.Since(pushSt))
+	return len(items), nil
+}
+
+func (s *Source) parse(table string, offset int64, body []byte, header http.Header) []abstract.ChangeItem {
+	now := time.Now()
+	s.metrics.Size.Add(int64(len(body)))
+	s.metrics.Count.Inc()
+	if s.parser == nil {
+		return []abstract.ChangeItem{abstract.MakeRawMessage(table, now, table, 0, offset, body)}
+	}
+
+	headers := make(map[string]string, len(header))
+	for k := range header {
+		headers[k] = header.Get(k)
+	}
+	result := s.parser.Do(parsers.Message{
+		Offset:     uint64(offset),
+		SeqNo:      0,
+		Key:        nil,
+		CreateTime: now,
+		WriteTime:  now,
+		Value:      body,
+		Headers:    headers,
+	}, abstract.Partition{
+		Cluster:   "",
+		Partition: 0,
+		Topic:     table,
+	})
+	s.metrics.DecodeTime.RecordDuration(time.Since(now))
+	s.metrics.ChangeItems.Add(int64(len(result)))
+	for _, ci := range result {
+		if ci.IsRowEvent() {
+			s.metrics.Parsed.Inc()
+		}
+	}
+	return result
+}
+
+func decompress(body []byte, limit int64) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read gzip header: %w", err)
+	}
+	defer reader.Close()
+	result, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to decompress: %w", err)
+	}
+	if int64(len(result)) > limit {
+		return nil, xerrors.Errorf("decompressed body exceeds %d bytes", limit)
+	}
+	return result, nil
+}
+
+func NewSource(cfg *HTTPSource, logger log.Logger, registry metrics.Registry) (*Source, error) {
+	var parser parsers.Parser
+	if cfg.ParserConfig != nil {
+		var err error
+		parser, err = parsers.NewParserFromMap(cfg.ParserConfig, false, logger, stats.NewSourceStats(registry))
+		if err != nil {
+			return nil, xerrors.Errorf("unable to make parser, err: %w", err)
+		}
+	}
+	server, err := serverutil.NewServer("tcp", cfg.Listen, logger)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to listen %s: %w", cfg.Listen, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Source{
+		config:        cfg,
+		logger:        logger,
+		metrics:       stats.NewSourceStats(registry),
+		parser:        parser,
+		server:        server,
+		pushMutex:     sync.Mutex{},
+		sink:          nil,
+		inflightMutex: sync.Mutex{},
+		inflightBytes: 0,
+		offset:        0,
+		errMutex:      sync.Mutex{},
+		lastError:     nil,
+		ctx:           ctx,
+		cancel:        cancel,
+	}, nil
+}
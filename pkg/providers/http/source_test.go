@@ -0,0 +1,173 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/parsers"
+	jsonparser "github.com/transferia/transferia/pkg/parsers/registry/json"
+	ytschema "go.ytsaurus.tech/yt/go/schema"
+)
+
+// mockSink completes pushes with err, a push blocks until release is closed if it's set
+type mockSink struct {
+	mutex   sync.Mutex
+	items   []abstract.ChangeItem
+	err     error
+	release chan struct{}
+}
+
+func (s *mockSink) AsyncPush(items []abstract.ChangeItem) chan error {
+	result := make(chan error, 1)
+	go func() {
+		if s.release != nil {
+			<-s.release
+		}
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if s.err == nil {
+			s.items = append(s.items, items...)
+		}
+		result <- s.err
+	}()
+	return result
+}
+
+func (s *mockSink) Close() error {
+	return nil
+}
+
+func (s *mockSink) rows() []abstract.ChangeItem {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.items
+}
+
+func runSource(t *testing.T, src *HTTPSource, sink abstract.AsyncSink) (string, chan error) {
+	src.Listen = "127.0.0.1:0"
+	src.WithDefaults()
+	require.NoError(t, src.Validate())
+	source, err := NewSource(src, logger.Log, solomon.NewRegistry(nil))
+	require.NoError(t, err)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- source.Run(sink)
+	}()
+	t.Cleanup(source.Stop)
+	return "http://" + source.Addr(), errCh
+}
+
+func post(t *testing.T, url string, body []byte, header map[string]string) (int, string) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, strings.TrimSpace(string(data))
+}
+
+func TestSource(t *testing.T) {
+	parserConfigMap, err := parsers.ParserConfigStructToMap(&jsonparser.ParserConfigJSONCommon{
+		Fields: []abstract.ColSchema{
+			{ColumnName: "id", DataType: ytschema.TypeInt32.String(), PrimaryKey: true},
+			{ColumnName: "val", DataType: ytschema.TypeString.String()},
+		},
+		AddRest:       false,
+		AddDedupeKeys: false,
+	})
+	require.NoError(t, err)
+	sink := &mockSink{}
+	url, _ := runSource(t, &HTTPSource{
+		Auth:         HTTPAuth{Type: AuthBearer, Token: "token"},
+		Routes:       map[string]string{"/v1/users": "users"},
+		ParserConfig: parserConfigMap,
+	}, sink)
+	auth := map[string]string{"Authorization": "Bearer token"}
+
+	status, body := post(t, url+"/v1/users", []byte("{\"id\":1,\"val\":\"a\"}\n{\"id\":2,\"val\":\"b\"}"), auth)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, `{"rows":2}`, body)
+	rows := sink.rows()
+	require.Len(t, rows, 2)
+	require.Equal(t, "users", rows[0].Table)
+	require.Equal(t, []interface{}{int32(1), "a"}, rows[0].ColumnValues[:2])
+	require.Equal(t, []interface{}{int32(2), "b"}, rows[1].ColumnValues[:2])
+
+	status, _ = post(t, url+"/v1/users", []byte(`{"id":3,"val":"c"}`), map[string]string{"Authorization": "Bearer wrong"})
+	require.Equal(t, http.StatusUnauthorized, status)
+	status, _ = post(t, url+"/v1/orders", []byte(`{"id":3,"val":"c"}`), auth)
+	require.Equal(t, http.StatusNotFound, status)
+
+	resp, err := http.Get(url + pingPath)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestSourceSignedGzip(t *testing.T) {
+	sink := &mockSink{}
+	url, _ := runSource(t, &HTTPSource{Auth: HTTPAuth{Type: AuthHMAC, Secret: "secret"}}, sink)
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte("raw message"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	header := map[string]string{"Content-Encoding": "gzip", DefaultSignatureHeader: Sign("secret", compressed.Bytes())}
+	status, _ := post(t, url+"/events", compressed.Bytes(), header)
+	require.Equal(t, http.StatusOK, status)
+	rows := sink.rows()
+	require.Len(t, rows, 1)
+	require.True(t, rows[0].IsMirror())
+	require.Equal(t, "events", rows[0].Table)
+	data, err := abstract.GetRawMessageData(rows[0])
+	require.NoError(t, err)
+	require.Equal(t, "raw message", string(data))
+
+	header[DefaultSignatureHeader] = Sign("wrong", compressed.Bytes())
+	status, _ = post(t, url+"/events", compressed.Bytes(), header)
+	require.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestSourceBackpressure(t *testing.T) {
+	sink := &mockSink{release: make(chan struct{})}
+	url, errCh := runSource(t, &HTTPSource{BufferSize: 10}, sink)
+
+	firstStatus := make(chan int, 1)
+	go func() {
+		status, _ := post(t, url+"/events", []byte("0123456789"), nil)
+		firstStatus <- status
+	}()
+	// the buffer is full until the first push is completed
+	require.Eventually(t, func() bool {
+		status, _ := post(t, url+"/events", []byte("x"), nil)
+		return status == http.StatusTooManyRequests
+	}, 10*time.Second, 10*time.Millisecond)
+	close(sink.release)
+	require.Equal(t, http.StatusOK, <-firstStatus)
+
+	// a failed push stops the source
+	sink.mutex.Lock()
+	sink.err = xerrors.New("push failed")
+	sink.mutex.Unlock()
+	status, _ := post(t, url+"/events", []byte("x"), nil)
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.ErrorContains(t, <-errCh, "push failed")
+}
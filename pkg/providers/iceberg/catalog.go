@@ -0,0 +1,55 @@
+package iceberg
+
+import (
+	"context"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+var (
+	ErrTableNotFound  = xerrors.NewSentinel("table not found")
+	ErrCommitConflict = xerrors.NewSentinel("table was concurrently modified")
+)
+
+type TableIdentifier struct {
+	Namespace string
+	Name      string
+}
+
+func (t TableIdentifier) String() string {
+	return t.Namespace + "." + t.Name
+}
+
+// Table is a loaded table metadata along with location of its metadata file
+type Table struct {
+	Identifier       TableIdentifier
+	Metadata         *TableMetadata
+	MetadataLocation string
+}
+
+// Catalog tracks current metadata of tables.
+// Commits are optimistic: CommitTable fails with ErrCommitConflict if table was changed after base was loaded.
+type Catalog interface {
+	// LoadTable returns ErrTableNotFound if table doesn't exist
+	LoadTable(ctx context.Context, id TableIdentifier) (*Table, error)
+	CreateTable(ctx context.Context, id TableIdentifier, schema *Schema) (*Table, error)
+	CommitTable(ctx context.Context, base *Table, update *TableUpdate) (*Table, error)
+	// DropTable removes table along with its data, it's not an error if table doesn't exist
+	DropTable(ctx context.Context, id TableIdentifier) error
+}
+
+func NewCatalog(cfg *IcebergDestination, fio fileIO, lgr log.Logger) (Catalog, error) {
+	switch cfg.CatalogType {
+	case CatalogFS:
+		return NewFSCatalog(cfg.Warehouse, fio, lgr), nil
+	case CatalogREST:
+		catalog, err := NewRESTCatalog(cfg.RESTURI, string(cfg.RESTToken), cfg.Warehouse, lgr)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to init rest catalog: %w", err)
+		}
+		return catalog, nil
+	default:
+		return nil, xerrors.Errorf("unknown catalog type: %s", cfg.CatalogType)
+	}
+}
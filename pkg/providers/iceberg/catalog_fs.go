@@ -0,0 +1,153 @@
+package iceberg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const versionHintFile = "version-hint.text"
+
+var _ Catalog = (*FSCatalog)(nil)
+
+// FSCatalog stores tables in `<warehouse>/<namespace>/<table>` like hadoop catalog does:
+// metadata files are `metadata/v<N>.metadata.json` & `metadata/version-hint.text` contains the current version.
+// Object storages have no atomic rename, so concurrent writers of one table are not supported.
+type FSCatalog struct {
+	warehouse string
+	fio       fileIO
+	logger    log.Logger
+}
+
+func (c *FSCatalog) tableLocation(id TableIdentifier) string {
+	return fmt.Sprintf("%s/%s/%s", c.warehouse, id.Namespace, id.Name)
+}
+
+func (c *FSCatalog) metadataLocation(id TableIdentifier, version int) string {
+	return fmt.Sprintf("%s/metadata/v%d.metadata.json", c.tableLocation(id), version)
+}
+
+func (c *FSCatalog) versionHintLocation(id TableIdentifier) string {
+	return fmt.Sprintf("%s/metadata/%s", c.tableLocation(id), versionHintFile)
+}
+
+func (c *FSCatalog) currentVersion(id TableIdentifier) (int, error) {
+	data, err := c.fio.Read(c.versionHintLocation(id))
+	if err != nil {
+		if xerrors.Is(err, errFileNotFound) {
+			return 0, ErrTableNotFound
+		}
+		return 0, xerrors.Errorf("unable to read version hint: %w", err)
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, xerrors.Errorf("invalid version hint %q: %w", string(data), err)
+	}
+	return version, nil
+}
+
+func metadataVersion(location string) (int, error) {
+	name := strings.TrimSuffix(strings.TrimPrefix(path.Base(location), "v"), ".metadata.json")
+	version, err := strconv.Atoi(name)
+	if err != nil {
+		return 0, xerrors.Errorf("unable to parse version of metadata file %s: %w", location, err)
+	}
+	return version, nil
+}
+
+func (c *FSCatalog) LoadTable(_ context.Context, id TableIdentifier) (*Table, error) {
+	version, err := c.currentVersion(id)
+	if err != nil {
+		return nil, err
+	}
+	location := c.metadataLocation(id, version)
+	data, err := c.fio.Read(location)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read metadata file: %w", err)
+	}
+	var meta TableMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, xerrors.Errorf("unable to parse metadata file %s: %w", location, err)
+	}
+	return &Table{Identifier: id, Metadata: &meta, MetadataLocation: location}, nil
+}
+
+func (c *FSCatalog) CreateTable(ctx context.Context, id TableIdentifier, schema *Schema) (*Table, error) {
+	if _, err := c.currentVersion(id); err == nil {
+		return nil, xerrors.Errorf("table %s already exists", id.String())
+	} else if !xerrors.Is(err, ErrTableNotFound) {
+		return nil, xerrors.Errorf("unable to check table existence: %w", err)
+	}
+	meta := newTableMetadata(c.tableLocation(id), schema)
+	location, err := c.writeVersion(id, 1, meta)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to write table metadata: %w", err)
+	}
+	c.logger.Info("iceberg table created", log.String("table", id.String()), log.String("metadata", location))
+	return &Table{Identifier: id, Metadata: meta, MetadataLocation: location}, nil
+}
+
+func (c *FSCatalog) CommitTable(_ context.Context, base *Table, update *TableUpdate) (*Table, error) {
+	baseVersion, err := metadataVersion(base.MetadataLocation)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get base version: %w", err)
+	}
+	currentVersion, err := c.currentVersion(base.Identifier)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get current version: %w", err)
+	}
+	if currentVersion != baseVersion {
+		return nil, xerrors.Errorf("base version %d, current version %d: %w", baseVersion, currentVersion, ErrCommitConflict)
+	}
+	meta, err := applyUpdate(base.Metadata, update, base.MetadataLocation)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to apply update: %w", err)
+	}
+	location, err := c.writeVersion(base.Identifier, baseVersion+1, meta)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to write table metadata: %w", err)
+	}
+	return &Table{Identifier: base.Identifier, Metadata: meta, MetadataLocation: location}, nil
+}
+
+func (c *FSCatalog) writeVersion(id TableIdentifier, version int, meta *TableMetadata) (string, error) {
+	location := c.metadataLocation(id, version)
+	if _, err := c.fio.Read(location); err == nil {
+		return "", xerrors.Errorf("metadata file %s already exists: %w", location, ErrCommitConflict)
+	} else if !xerrors.Is(err, errFileNotFound) {
+		return "", xerrors.Errorf("unable to check metadata file existence: %w", err)
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return "", xerrors.Errorf("unable to marshal metadata: %w", err)
+	}
+	if err := c.fio.Write(location, data); err != nil {
+		return "", xerrors.Errorf("unable to write metadata file: %w", err)
+	}
+	if err := c.fio.Write(c.versionHintLocation(id), []byte(strconv.Itoa(version))); err != nil {
+		return "", xerrors.Errorf("unable to write version hint: %w", err)
+	}
+	return location, nil
+}
+
+func (c *FSCatalog) DropTable(_ context.Context, id TableIdentifier) error {
+	if err := c.fio.DeletePrefix(c.tableLocation(id)); err != nil {
+		return xerrors.Errorf("unable to delete table files: %w", err)
+	}
+	c.logger.Info("iceberg table dropped", log.String("table", id.String()))
+	return nil
+}
+
+func NewFSCatalog(warehouse string, fio fileIO, lgr log.Logger) *FSCatalog {
+	return &FSCatalog{
+		warehouse: strings.TrimSuffix(warehouse, "/"),
+		fio:       fio,
+		logger:    lgr,
+	}
+}
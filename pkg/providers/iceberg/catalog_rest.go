@@ -0,0 +1,244 @@
+package iceberg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+var _ Catalog = (*RESTCatalog)(nil)
+
+// RESTCatalog implements client of iceberg REST catalog API:
+// https://github.com/apache/iceberg/blob/main/open-api/rest-catalog-open-api.yaml
+type RESTCatalog struct {
+	baseURL string // uri with version & prefix, like `http://host/v1/prefix`
+	token   string
+	client  *http.Client
+	logger  log.Logger
+}
+
+type restErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    int    `json:"code"`
+	} `json:"error"`
+}
+
+type restConfigResponse struct {
+	Defaults  map[string]string `json:"defaults"`
+	Overrides map[string]string `json:"overrides"`
+}
+
+type restLoadTableResponse struct {
+	MetadataLocation string         `json:"metadata-location"`
+	Metadata         *TableMetadata `json:"metadata"`
+}
+
+type restTableIdentifier struct {
+	Namespace []string `json:"namespace"`
+	Name      string   `json:"name"`
+}
+
+type restCommitRequest struct {
+	Identifier   restTableIdentifier `json:"identifier"`
+	Requirements []map[string]any    `json:"requirements"`
+	Updates      []map[string]any    `json:"updates"`
+}
+
+// httpError is returned for unexpected response statuses
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.status, e.message)
+}
+
+func hasStatus(err error, status int) bool {
+	var target *httpError
+	return xerrors.As(err, &target) && target.status == status
+}
+
+func (c *RESTCatalog) do(ctx context.Context, method, path string, query url.Values, body any, result any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return xerrors.Errorf("unable to marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	reqURL := c.baseURL + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, reqBody)
+	if err != nil {
+		return xerrors.Errorf("unable to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return xerrors.Errorf("unable to send request %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return xerrors.Errorf("unable to read response of %s %s: %w", method, path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message := string(respBody)
+		var errResp restErrorResponse
+		if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error.Message != "" {
+			message = errResp.Error.Type + ": " + errResp.Error.Message
+		}
+		return xerrors.Errorf("%s %s failed: %w", method, path, &httpError{status: resp.StatusCode, message: message})
+	}
+	if result != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return xerrors.Errorf("unable to parse response of %s %s: %w", method, path, err)
+		}
+	}
+	return nil
+}
+
+func tablePath(id TableIdentifier) string {
+	return fmt.Sprintf("/namespaces/%s/tables/%s", url.PathEscape(id.Namespace), url.PathEscape(id.Name))
+}
+
+func (c *RESTCatalog) toTable(id TableIdentifier, resp *restLoadTableResponse) (*Table, error) {
+	if resp.Metadata == nil {
+		return nil, xerrors.New("response has no table metadata")
+	}
+	return &Table{Identifier: id, Metadata: resp.Metadata, MetadataLocation: resp.MetadataLocation}, nil
+}
+
+func (c *RESTCatalog) LoadTable(ctx context.Context, id TableIdentifier) (*Table, error) {
+	var resp restLoadTableResponse
+	if err := c.do(ctx, http.MethodGet, tablePath(id), nil, nil, &resp); err != nil {
+		if hasStatus(err, http.StatusNotFound) {
+			return nil, ErrTableNotFound
+		}
+		return nil, xerrors.Errorf("unable to load table %s: %w", id.String(), err)
+	}
+	return c.toTable(id, &resp)
+}
+
+func (c *RESTCatalog) CreateTable(ctx context.Context, id TableIdentifier, schema *Schema) (*Table, error) {
+	createNamespace := map[string]any{
+		"namespace":  []string{id.Namespace},
+		"properties": map[string]string{},
+	}
+	if err := c.do(ctx, http.MethodPost, "/namespaces", nil, createNamespace, nil); err != nil && !hasStatus(err, http.StatusConflict) {
+		return nil, xerrors.Errorf("unable to create namespace %s: %w", id.Namespace, err)
+	}
+
+	createTable := map[string]any{
+		"name":           id.Name,
+		"schema":         schema,
+		"partition-spec": PartitionSpec{SpecID: 0, Fields: []json.RawMessage{}},
+		"write-order":    SortOrder{OrderID: 0, Fields: []json.RawMessage{}},
+		"stage-create":   false,
+		"properties":     map[string]string{"format-version": fmt.Sprint(formatVersion)},
+	}
+	var resp restLoadTableResponse
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/namespaces/%s/tables", url.PathEscape(id.Namespace)), nil, createTable, &resp); err != nil {
+		return nil, xerrors.Errorf("unable to create table %s: %w", id.String(), err)
+	}
+	c.logger.Info("iceberg table created", log.String("table", id.String()), log.String("metadata", resp.MetadataLocation))
+	return c.toTable(id, &resp)
+}
+
+func (c *RESTCatalog) CommitTable(ctx context.Context, base *Table, update *TableUpdate) (*Table, error) {
+	var currentSnapshotID any // null asserts that table has no snapshots
+	if snapshot := base.Metadata.CurrentSnapshot(); snapshot != nil {
+		currentSnapshotID = snapshot.SnapshotID
+	}
+	req := restCommitRequest{
+		Identifier: restTableIdentifier{Namespace: []string{base.Identifier.Namespace}, Name: base.Identifier.Name},
+		Requirements: []map[string]any{
+			{"type": "assert-table-uuid", "uuid": base.Metadata.TableUUID},
+			{"type": "assert-ref-snapshot-id", "ref": mainBranch, "snapshot-id": currentSnapshotID},
+			{"type": "assert-current-schema-id", "current-schema-id": base.Metadata.CurrentSchemaID},
+		},
+		Updates: nil,
+	}
+	if update.Schema != nil {
+		lastColumnID := base.Metadata.LastColumnID
+		for _, field := range update.Schema.Fields {
+			if field.ID > lastColumnID {
+				lastColumnID = field.ID
+			}
+		}
+		req.Updates = append(req.Updates,
+			map[string]any{"action": "add-schema", "schema": update.Schema, "last-column-id": lastColumnID},
+			map[string]any{"action": "set-current-schema", "schema-id": -1}, // -1 is the last added schema
+		)
+	}
+	if update.Snapshot != nil {
+		req.Updates = append(req.Updates,
+			map[string]any{"action": "add-snapshot", "snapshot": update.Snapshot},
+			map[string]any{"action": "set-snapshot-ref", "ref-name": mainBranch, "type": "branch", "snapshot-id": update.Snapshot.SnapshotID},
+		)
+	}
+
+	var resp restLoadTableResponse
+	if err := c.do(ctx, http.MethodPost, tablePath(base.Identifier), nil, req, &resp); err != nil {
+		if hasStatus(err, http.StatusConflict) {
+			return nil, xerrors.Errorf("unable to commit table %s: %v: %w", base.Identifier.String(), err, ErrCommitConflict)
+		}
+		return nil, xerrors.Errorf("unable to commit table %s: %w", base.Identifier.String(), err)
+	}
+	return c.toTable(base.Identifier, &resp)
+}
+
+func (c *RESTCatalog) DropTable(ctx context.Context, id TableIdentifier) error {
+	query := url.Values{"purgeRequested": []string{"true"}}
+	if err := c.do(ctx, http.MethodDelete, tablePath(id), query, nil, nil); err != nil && !hasStatus(err, http.StatusNotFound) {
+		return xerrors.Errorf("unable to drop table %s: %w", id.String(), err)
+	}
+	c.logger.Info("iceberg table dropped", log.String("table", id.String()))
+	return nil
+}
+
+// NewRESTCatalog requests catalog config, which may override prefix of all endpoints
+func NewRESTCatalog(uri, token, warehouse string, lgr log.Logger) (*RESTCatalog, error) {
+	catalog := &RESTCatalog{
+		baseURL: strings.TrimSuffix(uri, "/") + "/v1",
+		token:   token,
+		client:  &http.Client{Timeout: time.Minute},
+		logger:  lgr,
+	}
+	var query url.Values
+	if warehouse != "" {
+		query = url.Values{"warehouse": []string{warehouse}}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var config restConfigResponse
+	if err := catalog.do(ctx, http.MethodGet, "/config", query, nil, &config); err != nil {
+		return nil, xerrors.Errorf("unable to get catalog config: %w", err)
+	}
+	prefix := config.Defaults["prefix"]
+	if override, ok := config.Overrides["prefix"]; ok {
+		prefix = override
+	}
+	if prefix != "" {
+		catalog.baseURL += "/" + strings.Trim(prefix, "/")
+	}
+	return catalog, nil
+}
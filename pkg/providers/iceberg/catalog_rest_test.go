@@ -0,0 +1,150 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/pkg/abstract"
+)
+
+// fakeRESTCatalog keeps tables in memory, data & metadata files are written by sink into local warehouse
+type fakeRESTCatalog struct {
+	t         *testing.T
+	warehouse string
+	mutex     sync.Mutex
+	tables    map[string]*restLoadTableResponse
+}
+
+func (f *fakeRESTCatalog) writeError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"message": message, "type": "TestException", "code": status}})
+}
+
+func (f *fakeRESTCatalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	require.Equal(f.t, "Bearer secret", r.Header.Get("Authorization"))
+
+	path := r.URL.Path
+	switch {
+	case path == "/v1/config":
+		require.Equal(f.t, f.warehouse, r.URL.Query().Get("warehouse"))
+		_ = json.NewEncoder(w).Encode(restConfigResponse{Defaults: map[string]string{}, Overrides: map[string]string{"prefix": "ws"}})
+	case path == "/v1/ws/namespaces" && r.Method == http.MethodPost:
+		w.WriteHeader(http.StatusConflict)
+	case strings.HasSuffix(path, "/tables") && r.Method == http.MethodPost:
+		var req struct {
+			Name   string  `json:"name"`
+			Schema *Schema `json:"schema"`
+		}
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+		key := path + "/" + req.Name
+		meta := newTableMetadata(fmt.Sprintf("%s/%s", f.warehouse, req.Name), req.Schema)
+		f.tables[key] = &restLoadTableResponse{MetadataLocation: "v1", Metadata: meta}
+		_ = json.NewEncoder(w).Encode(f.tables[key])
+	default:
+		table, ok := f.tables[path]
+		switch {
+		case !ok:
+			f.writeError(w, http.StatusNotFound, "table not found")
+		case r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(table)
+		case r.Method == http.MethodDelete:
+			require.Equal(f.t, "true", r.URL.Query().Get("purgeRequested"))
+			delete(f.tables, path)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost:
+			f.commit(w, r, path, table)
+		}
+	}
+}
+
+func (f *fakeRESTCatalog) commit(w http.ResponseWriter, r *http.Request, path string, table *restLoadTableResponse) {
+	var req restCommitRequest
+	require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+	for _, requirement := range req.Requirements {
+		if requirement["type"] != "assert-ref-snapshot-id" {
+			continue
+		}
+		var current any
+		if snapshot := table.Metadata.CurrentSnapshot(); snapshot != nil {
+			current = float64(snapshot.SnapshotID)
+		}
+		if requirement["snapshot-id"] != current {
+			f.writeError(w, http.StatusConflict, "snapshot changed")
+			return
+		}
+	}
+	var update TableUpdate
+	for _, rawUpdate := range req.Updates {
+		data, err := json.Marshal(rawUpdate)
+		require.NoError(f.t, err)
+		switch rawUpdate["action"] {
+		case "add-schema":
+			var parsed struct {
+				Schema *Schema `json:"schema"`
+			}
+			require.NoError(f.t, json.Unmarshal(data, &parsed))
+			update.Schema = parsed.Schema
+		case "add-snapshot":
+			var parsed struct {
+				Snapshot *Snapshot `json:"snapshot"`
+			}
+			require.NoError(f.t, json.Unmarshal(data, &parsed))
+			update.Snapshot = parsed.Snapshot
+		}
+	}
+	meta, err := applyUpdate(table.Metadata, &update, table.MetadataLocation)
+	require.NoError(f.t, err)
+	f.tables[path] = &restLoadTableResponse{MetadataLocation: fmt.Sprintf("v%d", len(meta.MetadataLog)+1), Metadata: meta}
+	_ = json.NewEncoder(w).Encode(f.tables[path])
+}
+
+func TestRESTCatalog(t *testing.T) {
+	fake := &fakeRESTCatalog{t: t, warehouse: t.TempDir(), mutex: sync.Mutex{}, tables: map[string]*restLoadTableResponse{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	dst := &IcebergDestination{
+		CatalogType: CatalogREST,
+		Warehouse:   fake.warehouse,
+		RESTURI:     server.URL,
+		RESTToken:   "secret",
+	}
+	dst.WithDefaults()
+	require.NoError(t, dst.Validate())
+	sink, err := NewSink(dst, logger.Log, solomon.NewRegistry(solomon.NewRegistryOpts()))
+	require.NoError(t, err)
+	defer sink.Close()
+	id := TableIdentifier{Namespace: "public", Name: "users"}
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		makeItem(abstract.InsertKind, testColumns, int32(1), "a", nil),
+		makeItem(abstract.InsertKind, testColumns, int32(2), "b", nil),
+	}))
+	require.NoError(t, sink.Push([]abstract.ChangeItem{makeDelete(testColumns, int32(1))}))
+	require.Equal(t, []map[string]any{{"id": int32(2), "name": "b", "created_at": nil}}, readTable(t, sink, id))
+
+	// table is changed by someone else, commit based on cached metadata fails & table is reloaded on retry
+	fake.mutex.Lock()
+	stale := fake.tables["/v1/ws/namespaces/public/tables/users"]
+	stale.Metadata.Refs = nil
+	stale.Metadata.CurrentSnapshotID = nil
+	fake.mutex.Unlock()
+	err = sink.Push([]abstract.ChangeItem{makeItem(abstract.InsertKind, testColumns, int32(3), "c", nil)})
+	require.ErrorIs(t, err, ErrCommitConflict)
+	require.NoError(t, sink.Push([]abstract.ChangeItem{makeItem(abstract.InsertKind, testColumns, int32(3), "c", nil)}))
+	require.Len(t, readTable(t, sink, id), 1)
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{{Kind: abstract.DropTableKind, Schema: "public", Table: "users"}}))
+	_, err = sink.catalog.LoadTable(sink.ctx, id)
+	require.ErrorIs(t, err, ErrTableNotFound)
+}
@@ -0,0 +1,208 @@
+package iceberg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/spf13/cast"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+const (
+	contentData           = 0
+	contentEqualityDelete = 2
+
+	fileFormatParquet = "PARQUET"
+)
+
+// dataFile describes written data or delete file, it's stored in manifests
+type dataFile struct {
+	Content     int
+	Path        string
+	RecordCount int64
+	SizeInBytes int64
+	EqualityIDs []int
+}
+
+// icebergValue converts value of column into go representation of iceberg field type:
+// bool, int32, int64, float32, float64, string or []byte. Dates are days since epoch, timestamps are microseconds.
+func icebergValue(field *NestedField, col abstract.ColSchema, val any) (any, error) {
+	if val == nil {
+		return nil, nil
+	}
+	switch field.Type {
+	case typeBoolean:
+		return cast.ToBoolE(val)
+	case typeInt:
+		v, err := cast.ToInt64E(val)
+		if err != nil {
+			return nil, err
+		}
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return nil, xerrors.Errorf("value %d is out of int range", v)
+		}
+		return int32(v), nil
+	case typeLong:
+		switch v := val.(type) {
+		case uint64:
+			if v > math.MaxInt64 {
+				return nil, xerrors.Errorf("value %d is out of long range", v)
+			}
+			return int64(v), nil
+		case time.Duration:
+			return v.Microseconds(), nil
+		}
+		return cast.ToInt64E(val)
+	case typeFloat:
+		return cast.ToFloat32E(val)
+	case typeDouble:
+		return cast.ToFloat64E(val)
+	case typeString:
+		if schema.Type(col.DataType) == schema.TypeAny {
+			data, err := json.Marshal(val)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to marshal value: %w", err)
+			}
+			return string(data), nil
+		}
+		switch v := val.(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	case typeBinary:
+		switch v := val.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		default:
+			return nil, xerrors.Errorf("unexpected value type for binary: %T", val)
+		}
+	case typeDate:
+		t, ok := val.(time.Time)
+		if !ok {
+			return nil, xerrors.Errorf("unexpected value type for date: %T", val)
+		}
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return int32(midnight.Unix() / (24 * 60 * 60)), nil
+	case typeTimestampTZ:
+		t, ok := val.(time.Time)
+		if !ok {
+			return nil, xerrors.Errorf("unexpected value type for timestamp: %T", val)
+		}
+		return t.UnixMicro(), nil
+	default:
+		return nil, xerrors.Errorf("unsupported field type: %s", field.Type)
+	}
+}
+
+func parquetNode(fieldType string) (parquet.Node, error) {
+	switch fieldType {
+	case typeBoolean:
+		return parquet.Leaf(parquet.BooleanType), nil
+	case typeInt:
+		return parquet.Int(32), nil
+	case typeLong:
+		return parquet.Int(64), nil
+	case typeFloat:
+		return parquet.Leaf(parquet.FloatType), nil
+	case typeDouble:
+		return parquet.Leaf(parquet.DoubleType), nil
+	case typeString:
+		return parquet.String(), nil
+	case typeBinary:
+		return parquet.Leaf(parquet.ByteArrayType), nil
+	case typeDate:
+		return parquet.Date(), nil
+	case typeTimestampTZ:
+		return parquet.Timestamp(parquet.Microsecond), nil
+	default:
+		return nil, xerrors.Errorf("unsupported field type: %s", fieldType)
+	}
+}
+
+func parquetValue(val any) parquet.Value {
+	switch v := val.(type) {
+	case bool:
+		return parquet.BooleanValue(v)
+	case int32:
+		return parquet.Int32Value(v)
+	case int64:
+		return parquet.Int64Value(v)
+	case float32:
+		return parquet.FloatValue(v)
+	case float64:
+		return parquet.DoubleValue(v)
+	case string:
+		return parquet.ByteArrayValue([]byte(v))
+	case []byte:
+		return parquet.ByteArrayValue(v)
+	default:
+		return parquet.ValueOf(nil)
+	}
+}
+
+// writeParquet serializes rows of iceberg values into parquet file, columns are annotated with field ids
+func writeParquet(fields []*NestedField, rows []map[string]any) (result []byte, err error) {
+	// parquet library panics instead of returning errors
+	defer func() {
+		if r := recover(); r != nil {
+			err = xerrors.Errorf("was panic, recovered value: %v", r)
+		}
+	}()
+
+	group := parquet.Group{}
+	fieldByName := make(map[string]*NestedField, len(fields))
+	for _, field := range fields {
+		node, err := parquetNode(field.Type)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to build parquet node for %s: %w", field.Name, err)
+		}
+		if !field.Required {
+			node = parquet.Optional(node)
+		}
+		group[field.Name] = parquet.FieldID(node, field.ID)
+		fieldByName[field.Name] = field
+	}
+	pqSchema := parquet.NewSchema("table", group)
+
+	pqRows := make([]parquet.Row, len(rows))
+	for i, row := range rows {
+		pqRow := make(parquet.Row, 0, len(fields))
+		// columns of parquet group are sorted by name
+		for idx, pqField := range pqSchema.Fields() {
+			field := fieldByName[pqField.Name()]
+			val := row[field.Name]
+			defLevel := 0
+			if val == nil {
+				if field.Required {
+					return nil, xerrors.Errorf("required field %s is null", field.Name)
+				}
+			} else if !field.Required {
+				defLevel = 1
+			}
+			pqRow = append(pqRow, parquetValue(val).Level(0, defLevel, idx))
+		}
+		pqRows[i] = pqRow
+	}
+
+	var buf bytes.Buffer
+	writer := parquet.NewGenericWriter[struct{}](&buf, pqSchema)
+	if _, err := writer.WriteRows(pqRows); err != nil {
+		return nil, xerrors.Errorf("unable to write rows: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, xerrors.Errorf("unable to close writer: %w", err)
+	}
+	return buf.Bytes(), nil
+}
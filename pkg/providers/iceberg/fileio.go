@@ -0,0 +1,209 @@
+package iceberg
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/google/uuid"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	s3_provider "github.com/transferia/transferia/pkg/providers/s3"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+var errFileNotFound = xerrors.NewSentinel("file not found")
+
+const s3Scheme = "s3://"
+
+// fileIO reads and writes whole files by their location.
+// Locations are either `s3://bucket/key` or local paths (optionally with `file://` scheme).
+type fileIO interface {
+	Read(location string) ([]byte, error)
+	Write(location string, data []byte) error
+	DeletePrefix(location string) error
+}
+
+type multiFileIO struct {
+	local *localFileIO
+	s3    *s3FileIO
+}
+
+func (m *multiFileIO) pick(location string) fileIO {
+	if strings.HasPrefix(location, s3Scheme) {
+		return m.s3
+	}
+	return m.local
+}
+
+func (m *multiFileIO) Read(location string) ([]byte, error) {
+	return m.pick(location).Read(location)
+}
+
+func (m *multiFileIO) Write(location string, data []byte) error {
+	return m.pick(location).Write(location, data)
+}
+
+func (m *multiFileIO) DeletePrefix(location string) error {
+	return m.pick(location).DeletePrefix(location)
+}
+
+func newFileIO(cfg s3_provider.ConnectionConfig, lgr log.Logger) fileIO {
+	return &multiFileIO{
+		local: new(localFileIO),
+		s3: &s3FileIO{
+			cfg:     cfg,
+			logger:  lgr,
+			mutex:   sync.Mutex{},
+			clients: make(map[string]*s3.S3),
+		},
+	}
+}
+
+type localFileIO struct{}
+
+func localPath(location string) string {
+	return strings.TrimPrefix(location, "file://")
+}
+
+func (l *localFileIO) Read(location string) ([]byte, error) {
+	data, err := os.ReadFile(localPath(location))
+	if os.IsNotExist(err) {
+		return nil, errFileNotFound
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read file %s: %w", location, err)
+	}
+	return data, nil
+}
+
+func (l *localFileIO) Write(location string, data []byte) error {
+	path := localPath(location)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return xerrors.Errorf("unable to create directory for %s: %w", location, err)
+	}
+	// write via temporary file, so readers never see partially written file
+	tmpPath := filepath.Join(filepath.Dir(path), "."+uuid.NewString()+".tmp")
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return xerrors.Errorf("unable to write file %s: %w", location, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return xerrors.Errorf("unable to rename file %s: %w", location, err)
+	}
+	return nil
+}
+
+func (l *localFileIO) DeletePrefix(location string) error {
+	if err := os.RemoveAll(localPath(location)); err != nil {
+		return xerrors.Errorf("unable to delete %s: %w", location, err)
+	}
+	return nil
+}
+
+type s3FileIO struct {
+	cfg    s3_provider.ConnectionConfig
+	logger log.Logger
+
+	mutex   sync.Mutex
+	clients map[string]*s3.S3 // bucket -> client
+}
+
+func splitS3Location(location string) (bucket string, key string) {
+	bucket, key, _ = strings.Cut(strings.TrimPrefix(location, s3Scheme), "/")
+	return bucket, key
+}
+
+func (s *s3FileIO) client(bucket string) (*s3.S3, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if client, ok := s.clients[bucket]; ok {
+		return client, nil
+	}
+	sess, err := s3_provider.NewAWSSession(s.logger, bucket, s.cfg)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create session to s3 bucket: %w", err)
+	}
+	client := s3.New(sess)
+	s.clients[bucket] = client
+	return client, nil
+}
+
+func (s *s3FileIO) Read(location string) ([]byte, error) {
+	bucket, key := splitS3Location(location)
+	client, err := s.client(bucket)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, errFileNotFound
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read object %s: %w", location, err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read object %s: %w", location, err)
+	}
+	return data, nil
+}
+
+func (s *s3FileIO) Write(location string, data []byte) error {
+	bucket, key := splitS3Location(location)
+	client, err := s.client(bucket)
+	if err != nil {
+		return err
+	}
+	if _, err := client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	}); err != nil {
+		return xerrors.Errorf("unable to write object %s: %w", location, err)
+	}
+	return nil
+}
+
+func (s *s3FileIO) DeletePrefix(location string) error {
+	bucket, prefix := splitS3Location(location)
+	client, err := s.client(bucket)
+	if err != nil {
+		return err
+	}
+	var deleteErr error
+	err = client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(strings.TrimSuffix(prefix, "/") + "/"),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		if len(page.Contents) == 0 {
+			return true
+		}
+		objects := make([]*s3.ObjectIdentifier, 0, len(page.Contents))
+		for _, object := range page.Contents {
+			objects = append(objects, &s3.ObjectIdentifier{Key: object.Key})
+		}
+		if _, deleteErr = client.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &s3.Delete{Objects: objects},
+		}); deleteErr != nil {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return xerrors.Errorf("unable to list objects %s: %w", location, err)
+	}
+	if deleteErr != nil {
+		return xerrors.Errorf("unable to delete objects %s: %w", location, deleteErr)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package iceberg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/avro"
+)
+
+// Manifests & manifest lists are avro files, see https://iceberg.apache.org/spec/#manifests
+
+const manifestEntrySchema = `{
+	"type": "record",
+	"name": "manifest_entry",
+	"fields": [
+		{"name": "status", "type": "int", "field-id": 0},
+		{"name": "snapshot_id", "type": ["null", "long"], "default": null, "field-id": 1},
+		{"name": "sequence_number", "type": ["null", "long"], "default": null, "field-id": 3},
+		{"name": "file_sequence_number", "type": ["null", "long"], "default": null, "field-id": 4},
+		{"name": "data_file", "type": {
+			"type": "record",
+			"name": "r2",
+			"fields": [
+				{"name": "content", "type": "int", "field-id": 134},
+				{"name": "file_path", "type": "string", "field-id": 100},
+				{"name": "file_format", "type": "string", "field-id": 101},
+				{"name": "partition", "type": {"type": "record", "name": "r102", "fields": []}, "field-id": 102},
+				{"name": "record_count", "type": "long", "field-id": 103},
+				{"name": "file_size_in_bytes", "type": "long", "field-id": 104},
+				{"name": "equality_ids", "type": ["null", {"type": "array", "items": "int", "element-id": 136}], "default": null, "field-id": 135}
+			]
+		}, "field-id": 2}
+	]
+}`
+
+const manifestFileSchema = `{
+	"type": "record",
+	"name": "manifest_file",
+	"fields": [
+		{"name": "manifest_path", "type": "string", "field-id": 500},
+		{"name": "manifest_length", "type": "long", "field-id": 501},
+		{"name": "partition_spec_id", "type": "int", "field-id": 502},
+		{"name": "content", "type": "int", "field-id": 517},
+		{"name": "sequence_number", "type": "long", "field-id": 515},
+		{"name": "min_sequence_number", "type": "long", "field-id": 516},
+		{"name": "added_snapshot_id", "type": "long", "field-id": 503},
+		{"name": "added_files_count", "type": "int", "field-id": 504},
+		{"name": "existing_files_count", "type": "int", "field-id": 505},
+		{"name": "deleted_files_count", "type": "int", "field-id": 506},
+		{"name": "added_rows_count", "type": "long", "field-id": 512},
+		{"name": "existing_rows_count", "type": "long", "field-id": 513},
+		{"name": "deleted_rows_count", "type": "long", "field-id": 514}
+	]
+}`
+
+const (
+	entryStatusAdded = 1
+
+	manifestContentData    = 0
+	manifestContentDeletes = 1
+)
+
+// manifestFile is an entry of manifest list
+type manifestFile struct {
+	Path               string
+	Length             int64
+	Content            int
+	SequenceNumber     int64
+	MinSequenceNumber  int64
+	AddedSnapshotID    int64
+	AddedFilesCount    int32
+	ExistingFilesCount int32
+	DeletedFilesCount  int32
+	AddedRowsCount     int64
+	ExistingRowsCount  int64
+	DeletedRowsCount   int64
+}
+
+func (m *manifestFile) toAvro() map[string]any {
+	return map[string]any{
+		"manifest_path":        m.Path,
+		"manifest_length":      m.Length,
+		"partition_spec_id":    int32(0),
+		"content":              int32(m.Content),
+		"sequence_number":      m.SequenceNumber,
+		"min_sequence_number":  m.MinSequenceNumber,
+		"added_snapshot_id":    m.AddedSnapshotID,
+		"added_files_count":    m.AddedFilesCount,
+		"existing_files_count": m.ExistingFilesCount,
+		"deleted_files_count":  m.DeletedFilesCount,
+		"added_rows_count":     m.AddedRowsCount,
+		"existing_rows_count":  m.ExistingRowsCount,
+		"deleted_rows_count":   m.DeletedRowsCount,
+	}
+}
+
+// manifestFileFromAvro is lenient to optional counters, which may be absent in lists written by other engines
+func manifestFileFromAvro(record map[string]any) (*manifestFile, error) {
+	path, ok := record["manifest_path"].(string)
+	if !ok {
+		return nil, xerrors.Errorf("manifest_path is missing")
+	}
+	return &manifestFile{
+		Path:               path,
+		Length:             cast.ToInt64(record["manifest_length"]),
+		Content:            cast.ToInt(record["content"]),
+		SequenceNumber:     cast.ToInt64(record["sequence_number"]),
+		MinSequenceNumber:  cast.ToInt64(record["min_sequence_number"]),
+		AddedSnapshotID:    cast.ToInt64(record["added_snapshot_id"]),
+		AddedFilesCount:    cast.ToInt32(record["added_files_count"]),
+		ExistingFilesCount: cast.ToInt32(record["existing_files_count"]),
+		DeletedFilesCount:  cast.ToInt32(record["deleted_files_count"]),
+		AddedRowsCount:     cast.ToInt64(record["added_rows_count"]),
+		ExistingRowsCount:  cast.ToInt64(record["existing_rows_count"]),
+		DeletedRowsCount:   cast.ToInt64(record["deleted_rows_count"]),
+	}, nil
+}
+
+// writeManifest writes manifest of files added by snapshot, sequence numbers of entries are inherited from manifest list
+func writeManifest(fio fileIO, location string, tableSchema *Schema, snapshotID, sequenceNumber int64, content int, files []dataFile) (*manifestFile, error) {
+	rawTableSchema, err := json.Marshal(tableSchema)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to marshal table schema: %w", err)
+	}
+	contentName := "data"
+	if content == manifestContentDeletes {
+		contentName = "deletes"
+	}
+	meta := map[string][]byte{
+		"schema":            rawTableSchema,
+		"schema-id":         []byte(strconv.Itoa(tableSchema.SchemaID)),
+		"partition-spec":    []byte("[]"),
+		"partition-spec-id": []byte("0"),
+		"format-version":    []byte(strconv.Itoa(formatVersion)),
+		"content":           []byte(contentName),
+	}
+
+	var buf bytes.Buffer
+	writer, err := avro.NewOCFWriter(&buf, manifestEntrySchema, avro.CodecDeflate, meta)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create manifest writer: %w", err)
+	}
+	var rows int64
+	for _, file := range files {
+		var equalityIDs any
+		if len(file.EqualityIDs) > 0 {
+			ids := make([]any, len(file.EqualityIDs))
+			for i, id := range file.EqualityIDs {
+				ids[i] = int32(id)
+			}
+			equalityIDs = ids
+		}
+		entry := map[string]any{
+			"status":               int32(entryStatusAdded),
+			"snapshot_id":          snapshotID,
+			"sequence_number":      nil,
+			"file_sequence_number": nil,
+			"data_file": map[string]any{
+				"content":            int32(file.Content),
+				"file_path":          file.Path,
+				"file_format":        fileFormatParquet,
+				"partition":          map[string]any{},
+				"record_count":       file.RecordCount,
+				"file_size_in_bytes": file.SizeInBytes,
+				"equality_ids":       equalityIDs,
+			},
+		}
+		if err := writer.Append(entry); err != nil {
+			return nil, xerrors.Errorf("unable to write manifest entry: %w", err)
+		}
+		rows += file.RecordCount
+	}
+	if err := writer.Close(); err != nil {
+		return nil, xerrors.Errorf("unable to close manifest writer: %w", err)
+	}
+	if err := fio.Write(location, buf.Bytes()); err != nil {
+		return nil, xerrors.Errorf("unable to write manifest: %w", err)
+	}
+	return &manifestFile{
+		Path:               location,
+		Length:             int64(buf.Len()),
+		Content:            content,
+		SequenceNumber:     sequenceNumber,
+		MinSequenceNumber:  sequenceNumber,
+		AddedSnapshotID:    snapshotID,
+		AddedFilesCount:    int32(len(files)),
+		ExistingFilesCount: 0,
+		DeletedFilesCount:  0,
+		AddedRowsCount:     rows,
+		ExistingRowsCount:  0,
+		DeletedRowsCount:   0,
+	}, nil
+}
+
+func writeManifestList(fio fileIO, location string, snapshot *Snapshot, manifests []*manifestFile) error {
+	parentSnapshotID := "null"
+	if snapshot.ParentSnapshotID != nil {
+		parentSnapshotID = strconv.FormatInt(*snapshot.ParentSnapshotID, 10)
+	}
+	meta := map[string][]byte{
+		"snapshot-id":        []byte(strconv.FormatInt(snapshot.SnapshotID, 10)),
+		"parent-snapshot-id": []byte(parentSnapshotID),
+		"sequence-number":    []byte(strconv.FormatInt(snapshot.SequenceNumber, 10)),
+		"format-version":     []byte(strconv.Itoa(formatVersion)),
+	}
+
+	var buf bytes.Buffer
+	writer, err := avro.NewOCFWriter(&buf, manifestFileSchema, avro.CodecDeflate, meta)
+	if err != nil {
+		return xerrors.Errorf("unable to create manifest list writer: %w", err)
+	}
+	for _, manifest := range manifests {
+		if err := writer.Append(manifest.toAvro()); err != nil {
+			return xerrors.Errorf("unable to write manifest list entry: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return xerrors.Errorf("unable to close manifest list writer: %w", err)
+	}
+	if err := fio.Write(location, buf.Bytes()); err != nil {
+		return xerrors.Errorf("unable to write manifest list: %w", err)
+	}
+	return nil
+}
+
+func readManifestList(fio fileIO, location string) ([]*manifestFile, error) {
+	data, err := fio.Read(location)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read manifest list: %w", err)
+	}
+	reader, err := avro.NewOCFReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to open manifest list: %w", err)
+	}
+	var result []*manifestFile
+	for {
+		val, err := reader.Next()
+		if xerrors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read manifest list entry: %w", err)
+		}
+		record, ok := val.(map[string]any)
+		if !ok {
+			return nil, xerrors.Errorf("unexpected manifest list entry: %T", val)
+		}
+		manifest, err := manifestFileFromAvro(record)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid manifest list entry: %w", err)
+		}
+		result = append(result, manifest)
+	}
+	return result, nil
+}
+
+func manifestLocation(tableLocation string) string {
+	return fmt.Sprintf("%s/metadata/%s-m0.avro", tableLocation, uuid.NewString())
+}
+
+func manifestListLocation(tableLocation string, snapshotID int64) string {
+	return fmt.Sprintf("%s/metadata/snap-%d-1-%s.avro", tableLocation, snapshotID, uuid.NewString())
+}
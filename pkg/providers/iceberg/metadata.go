@@ -0,0 +1,228 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+)
+
+// Table metadata format: https://iceberg.apache.org/spec/#table-metadata-fields
+// Only format version 2 and unpartitioned tables are supported.
+
+const (
+	formatVersion = 2
+	mainBranch    = "main"
+
+	noSnapshotID = int64(-1)
+)
+
+type NestedField struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+	Type     string `json:"type"`
+	Doc      string `json:"doc,omitempty"`
+}
+
+type Schema struct {
+	Type               string         `json:"type"`
+	SchemaID           int            `json:"schema-id"`
+	IdentifierFieldIDs []int          `json:"identifier-field-ids,omitempty"`
+	Fields             []*NestedField `json:"fields"`
+}
+
+func (s *Schema) FieldByName(name string) *NestedField {
+	for _, field := range s.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+type PartitionSpec struct {
+	SpecID int               `json:"spec-id"`
+	Fields []json.RawMessage `json:"fields"`
+}
+
+type SortOrder struct {
+	OrderID int               `json:"order-id"`
+	Fields  []json.RawMessage `json:"fields"`
+}
+
+type Snapshot struct {
+	SnapshotID       int64             `json:"snapshot-id"`
+	ParentSnapshotID *int64            `json:"parent-snapshot-id,omitempty"`
+	SequenceNumber   int64             `json:"sequence-number"`
+	TimestampMs      int64             `json:"timestamp-ms"`
+	ManifestList     string            `json:"manifest-list"`
+	Summary          map[string]string `json:"summary"`
+	SchemaID         *int              `json:"schema-id,omitempty"`
+}
+
+type SnapshotRef struct {
+	SnapshotID int64  `json:"snapshot-id"`
+	Type       string `json:"type"`
+}
+
+type SnapshotLogEntry struct {
+	TimestampMs int64 `json:"timestamp-ms"`
+	SnapshotID  int64 `json:"snapshot-id"`
+}
+
+type MetadataLogEntry struct {
+	TimestampMs  int64  `json:"timestamp-ms"`
+	MetadataFile string `json:"metadata-file"`
+}
+
+type TableMetadata struct {
+	FormatVersion      int                    `json:"format-version"`
+	TableUUID          string                 `json:"table-uuid"`
+	Location           string                 `json:"location"`
+	LastSequenceNumber int64                  `json:"last-sequence-number"`
+	LastUpdatedMs      int64                  `json:"last-updated-ms"`
+	LastColumnID       int                    `json:"last-column-id"`
+	CurrentSchemaID    int                    `json:"current-schema-id"`
+	Schemas            []*Schema              `json:"schemas"`
+	DefaultSpecID      int                    `json:"default-spec-id"`
+	PartitionSpecs     []*PartitionSpec       `json:"partition-specs"`
+	LastPartitionID    int                    `json:"last-partition-id"`
+	DefaultSortOrderID int                    `json:"default-sort-order-id"`
+	SortOrders         []*SortOrder           `json:"sort-orders"`
+	Properties         map[string]string      `json:"properties,omitempty"`
+	CurrentSnapshotID  *int64                 `json:"current-snapshot-id,omitempty"`
+	Refs               map[string]SnapshotRef `json:"refs,omitempty"`
+	Snapshots          []*Snapshot            `json:"snapshots,omitempty"`
+	SnapshotLog        []SnapshotLogEntry     `json:"snapshot-log,omitempty"`
+	MetadataLog        []MetadataLogEntry     `json:"metadata-log,omitempty"`
+}
+
+func (m *TableMetadata) CurrentSchema() *Schema {
+	for _, schema := range m.Schemas {
+		if schema.SchemaID == m.CurrentSchemaID {
+			return schema
+		}
+	}
+	return nil
+}
+
+func (m *TableMetadata) CurrentSnapshot() *Snapshot {
+	snapshotID := noSnapshotID
+	if ref, ok := m.Refs[mainBranch]; ok {
+		snapshotID = ref.SnapshotID
+	} else if m.CurrentSnapshotID != nil {
+		snapshotID = *m.CurrentSnapshotID
+	}
+	for _, snapshot := range m.Snapshots {
+		if snapshot.SnapshotID == snapshotID {
+			return snapshot
+		}
+	}
+	return nil
+}
+
+func (m *TableMetadata) Validate() error {
+	if m.FormatVersion != formatVersion {
+		return xerrors.Errorf("unsupported table format version: %d", m.FormatVersion)
+	}
+	if m.CurrentSchema() == nil {
+		return xerrors.Errorf("current schema %d not found", m.CurrentSchemaID)
+	}
+	for _, spec := range m.PartitionSpecs {
+		if spec.SpecID == m.DefaultSpecID && len(spec.Fields) > 0 {
+			return xerrors.New("partitioned tables are not supported")
+		}
+	}
+	return nil
+}
+
+func (m *TableMetadata) copy() (*TableMetadata, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to marshal metadata: %w", err)
+	}
+	var result TableMetadata
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, xerrors.Errorf("unable to unmarshal metadata: %w", err)
+	}
+	return &result, nil
+}
+
+// TableUpdate is a set of changes, which are committed into table at once
+type TableUpdate struct {
+	// Schema is a new current schema, nil if schema isn't changed
+	Schema *Schema
+	// Snapshot is a new current snapshot of main branch
+	Snapshot *Snapshot
+}
+
+// applyUpdate builds new table metadata, it's used by catalogs storing metadata files by themselves
+func applyUpdate(base *TableMetadata, update *TableUpdate, previousLocation string) (*TableMetadata, error) {
+	result, err := base.copy()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to copy metadata: %w", err)
+	}
+	now := time.Now().UnixMilli()
+	result.LastUpdatedMs = now
+	if update.Schema != nil {
+		result.Schemas = append(result.Schemas, update.Schema)
+		result.CurrentSchemaID = update.Schema.SchemaID
+		for _, field := range update.Schema.Fields {
+			if field.ID > result.LastColumnID {
+				result.LastColumnID = field.ID
+			}
+		}
+	}
+	if update.Snapshot != nil {
+		result.Snapshots = append(result.Snapshots, update.Snapshot)
+		result.LastSequenceNumber = update.Snapshot.SequenceNumber
+		result.CurrentSnapshotID = &update.Snapshot.SnapshotID
+		if result.Refs == nil {
+			result.Refs = make(map[string]SnapshotRef)
+		}
+		result.Refs[mainBranch] = SnapshotRef{SnapshotID: update.Snapshot.SnapshotID, Type: "branch"}
+		result.SnapshotLog = append(result.SnapshotLog, SnapshotLogEntry{
+			TimestampMs: update.Snapshot.TimestampMs,
+			SnapshotID:  update.Snapshot.SnapshotID,
+		})
+	}
+	if previousLocation != "" {
+		result.MetadataLog = append(result.MetadataLog, MetadataLogEntry{
+			TimestampMs:  base.LastUpdatedMs,
+			MetadataFile: previousLocation,
+		})
+	}
+	return result, nil
+}
+
+func newTableMetadata(location string, schema *Schema) *TableMetadata {
+	lastColumnID := 0
+	for _, field := range schema.Fields {
+		if field.ID > lastColumnID {
+			lastColumnID = field.ID
+		}
+	}
+	return &TableMetadata{
+		FormatVersion:      formatVersion,
+		TableUUID:          uuid.NewString(),
+		Location:           location,
+		LastSequenceNumber: 0,
+		LastUpdatedMs:      time.Now().UnixMilli(),
+		LastColumnID:       lastColumnID,
+		CurrentSchemaID:    schema.SchemaID,
+		Schemas:            []*Schema{schema},
+		DefaultSpecID:      0,
+		PartitionSpecs:     []*PartitionSpec{{SpecID: 0, Fields: []json.RawMessage{}}},
+		LastPartitionID:    999, // partition field ids start from 1000
+		DefaultSortOrderID: 0,
+		SortOrders:         []*SortOrder{{OrderID: 0, Fields: []json.RawMessage{}}},
+		Properties:         map[string]string{},
+		CurrentSnapshotID:  nil,
+		Refs:               nil,
+		Snapshots:          nil,
+		SnapshotLog:        nil,
+		MetadataLog:        nil,
+	}
+}
@@ -0,0 +1,97 @@
+package iceberg
+
+import (
+	"strings"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/middlewares/async/bufferer"
+	s3_provider "github.com/transferia/transferia/pkg/providers/s3"
+)
+
+type CatalogType string
+
+const (
+	// CatalogFS keeps table metadata next to the data, `version-hint.text` points to the current metadata file.
+	// Commits are not atomic on object storages, so only one writer per table is allowed.
+	CatalogFS = CatalogType("fs")
+	// CatalogREST is a catalog implementing iceberg REST catalog API
+	CatalogREST = CatalogType("rest")
+)
+
+type IcebergDestination struct {
+	CatalogType CatalogType
+
+	// Warehouse is a root location of tables: `s3://bucket/prefix` or local path.
+	// For REST catalog it's passed to the catalog config endpoint as `warehouse`, catalog chooses location of tables by itself.
+	Warehouse string
+	// Namespace of tables, source schema name is used if empty
+	Namespace string
+
+	RESTURI   string
+	RESTToken model.SecretString
+
+	// ConnectionConfig is used for `s3://` locations
+	ConnectionConfig s3_provider.ConnectionConfig
+
+	Cleanup        model.CleanupType
+	BufferSize     model.BytesSize
+	BufferInterval time.Duration
+}
+
+var _ model.Destination = (*IcebergDestination)(nil)
+
+func (d *IcebergDestination) GetProviderType() abstract.ProviderType {
+	return ProviderType
+}
+
+func (d *IcebergDestination) Validate() error {
+	switch d.CatalogType {
+	case CatalogFS:
+		if d.Warehouse == "" {
+			return xerrors.New("warehouse is required for fs catalog")
+		}
+	case CatalogREST:
+		if d.RESTURI == "" {
+			return xerrors.New("uri is required for rest catalog")
+		}
+	default:
+		return xerrors.Errorf("unknown catalog type: %s", d.CatalogType)
+	}
+	if strings.HasPrefix(d.Warehouse, "s3a://") || strings.HasPrefix(d.Warehouse, "s3n://") {
+		return xerrors.Errorf("unsupported warehouse scheme, use s3:// instead: %s", d.Warehouse)
+	}
+	return nil
+}
+
+func (d *IcebergDestination) WithDefaults() {
+	if d.CatalogType == "" {
+		d.CatalogType = CatalogFS
+	}
+	if d.Cleanup == "" {
+		d.Cleanup = model.Drop
+	}
+	if d.BufferSize == 0 {
+		d.BufferSize = 64 * 1024 * 1024
+	}
+	if d.BufferInterval == 0 {
+		d.BufferInterval = time.Minute
+	}
+}
+
+func (d *IcebergDestination) BuffererConfig() *bufferer.BuffererConfig {
+	return &bufferer.BuffererConfig{
+		TriggingCount:    0,
+		TriggingSize:     uint64(d.BufferSize),
+		TriggingInterval: d.BufferInterval,
+	}
+}
+
+func (d *IcebergDestination) CleanupMode() model.CleanupType {
+	return d.Cleanup
+}
+
+func (d *IcebergDestination) IsDestination() {
+}
@@ -0,0 +1,167 @@
+package iceberg
+
+import (
+	"slices"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+const (
+	typeBoolean     = "boolean"
+	typeInt         = "int"
+	typeLong        = "long"
+	typeFloat       = "float"
+	typeDouble      = "double"
+	typeDate        = "date"
+	typeTimestampTZ = "timestamptz"
+	typeString      = "string"
+	typeBinary      = "binary"
+)
+
+// icebergType maps column into iceberg primitive type
+func icebergType(col abstract.ColSchema) (string, error) {
+	switch schema.Type(col.DataType) {
+	case schema.TypeInt8, schema.TypeInt16, schema.TypeInt32, schema.TypeUint8, schema.TypeUint16:
+		return typeInt, nil
+	case schema.TypeInt64, schema.TypeUint32, schema.TypeUint64:
+		// iceberg has no unsigned types, uint64 values above int64 range are rejected on write
+		return typeLong, nil
+	case schema.TypeInterval:
+		// interval is stored as a number of microseconds
+		return typeLong, nil
+	case schema.TypeFloat32:
+		return typeFloat, nil
+	case schema.TypeFloat64:
+		return typeDouble, nil
+	case schema.TypeBoolean:
+		return typeBoolean, nil
+	case schema.TypeString:
+		return typeString, nil
+	case schema.TypeBytes:
+		return typeBinary, nil
+	case schema.TypeDate:
+		return typeDate, nil
+	case schema.TypeDatetime, schema.TypeTimestamp:
+		return typeTimestampTZ, nil
+	case schema.TypeAny:
+		// any is stored as json string
+		return typeString, nil
+	default:
+		return "", xerrors.Errorf("column %s has unsupported type: %s", col.ColumnName, col.DataType)
+	}
+}
+
+// promotedType returns type of column, which can hold values of both existing & requested types.
+// Only type promotions allowed by iceberg spec are possible.
+func promotedType(existing, requested string) (string, bool) {
+	switch {
+	case existing == requested:
+		return existing, true
+	case existing == typeLong && requested == typeInt, existing == typeDouble && requested == typeFloat:
+		return existing, true
+	case existing == typeInt && requested == typeLong, existing == typeFloat && requested == typeDouble:
+		return requested, true
+	default:
+		return "", false
+	}
+}
+
+// newSchema builds schema of a new table, key columns are required & used as identifier fields
+func newSchema(columns []abstract.ColSchema) (*Schema, error) {
+	result := &Schema{Type: "struct", SchemaID: 0, IdentifierFieldIDs: nil, Fields: nil}
+	for i, col := range columns {
+		fieldType, err := icebergType(col)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to map column type: %w", err)
+		}
+		field := &NestedField{
+			ID:       i + 1,
+			Name:     col.ColumnName,
+			Required: col.IsKey() || col.Required,
+			Type:     fieldType,
+			Doc:      "",
+		}
+		if col.IsKey() {
+			result.IdentifierFieldIDs = append(result.IdentifierFieldIDs, field.ID)
+		}
+		result.Fields = append(result.Fields, field)
+	}
+	return result, nil
+}
+
+// evolveSchema builds a new schema of existing table from the current columns.
+// Fields are matched by name & keep their ids, new fields get new ids & are always optional,
+// removed columns are dropped from the schema. Returns nil if current schema matches the columns.
+func evolveSchema(columns []abstract.ColSchema, meta *TableMetadata) (*Schema, error) {
+	current := meta.CurrentSchema()
+	nextID := meta.LastColumnID + 1
+	nextSchemaID := 0
+	for _, existing := range meta.Schemas {
+		if existing.SchemaID >= nextSchemaID {
+			nextSchemaID = existing.SchemaID + 1
+		}
+	}
+
+	result := &Schema{Type: "struct", SchemaID: nextSchemaID, IdentifierFieldIDs: nil, Fields: nil}
+	for _, col := range columns {
+		requestedType, err := icebergType(col)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to map column type: %w", err)
+		}
+		var field *NestedField
+		if existing := current.FieldByName(col.ColumnName); existing != nil {
+			fieldType, ok := promotedType(existing.Type, requestedType)
+			if !ok {
+				return nil, xerrors.Errorf("unable to change type of column %s from %s to %s", col.ColumnName, existing.Type, requestedType)
+			}
+			field = &NestedField{
+				ID:       existing.ID,
+				Name:     existing.Name,
+				Required: existing.Required && (col.IsKey() || col.Required),
+				Type:     fieldType,
+				Doc:      existing.Doc,
+			}
+		} else {
+			field = &NestedField{
+				ID:       nextID,
+				Name:     col.ColumnName,
+				Required: false, // required columns can't be added into a table with data
+				Type:     requestedType,
+				Doc:      "",
+			}
+			nextID++
+		}
+		if col.IsKey() && field.Required {
+			result.IdentifierFieldIDs = append(result.IdentifierFieldIDs, field.ID)
+		}
+		result.Fields = append(result.Fields, field)
+	}
+
+	if sameSchema(current, result) {
+		return nil, nil
+	}
+	return result, nil
+}
+
+func sameSchema(a, b *Schema) bool {
+	return slices.Equal(a.IdentifierFieldIDs, b.IdentifierFieldIDs) &&
+		slices.EqualFunc(a.Fields, b.Fields, func(x, y *NestedField) bool { return *x == *y })
+}
+
+// keyFieldIDs returns ids of fields used for equality deletes
+func keyFieldIDs(columns []abstract.ColSchema, icebergSchema *Schema) ([]int, error) {
+	var result []int
+	for _, col := range columns {
+		if !col.IsKey() {
+			continue
+		}
+		field := icebergSchema.FieldByName(col.ColumnName)
+		if field == nil {
+			return nil, xerrors.Errorf("key column %s not found in table schema", col.ColumnName)
+		}
+		result = append(result, field.ID)
+	}
+	return result, nil
+}
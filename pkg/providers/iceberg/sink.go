@@ -0,0 +1,456 @@
+package iceberg
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/stats"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const defaultNamespace = "default"
+
+var _ abstract.Sinker = (*Sink)(nil)
+
+// Sink writes every pushed batch of a table as one iceberg snapshot.
+// Rows are deduplicated by primary key within the batch, updated & deleted keys are written into equality delete file,
+// which removes previous versions of rows, while the last versions are written into data file of the same snapshot.
+type Sink struct {
+	cfg     *IcebergDestination
+	fio     fileIO
+	catalog Catalog
+	logger  log.Logger
+	metrics *stats.SinkerStats
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	tables map[TableIdentifier]*Table
+}
+
+// tableBatch accumulates rows of a table with the same schema
+type tableBatch struct {
+	id          TableIdentifier
+	tableSchema *abstract.TableSchema
+	keyColumns  map[string]bool
+
+	order   []string                  // keys of upserts in order of arrival
+	upserts map[string]map[string]any // last state of rows by key
+	deletes map[string]map[string]any // key values of rows to delete by key
+}
+
+func newTableBatch(id TableIdentifier, tableSchema *abstract.TableSchema) *tableBatch {
+	keyColumns := map[string]bool{}
+	for _, col := range tableSchema.Columns() {
+		if col.IsKey() {
+			keyColumns[col.ColumnName] = true
+		}
+	}
+	return &tableBatch{
+		id:          id,
+		tableSchema: tableSchema,
+		keyColumns:  keyColumns,
+		order:       nil,
+		upserts:     map[string]map[string]any{},
+		deletes:     map[string]map[string]any{},
+	}
+}
+
+func (b *tableBatch) sameSchema(tableSchema *abstract.TableSchema) bool {
+	return b.tableSchema == tableSchema || b.tableSchema.Equal(tableSchema)
+}
+
+func (b *tableBatch) upsert(key string, row map[string]any) {
+	if _, ok := b.upserts[key]; !ok {
+		b.order = append(b.order, key)
+	}
+	b.upserts[key] = row
+}
+
+func oldKeyValues(item *abstract.ChangeItem) map[string]any {
+	if len(item.OldKeys.KeyNames) == 0 {
+		return item.KeysAsMap()
+	}
+	result := make(map[string]any, len(item.OldKeys.KeyNames))
+	for i, name := range item.OldKeys.KeyNames {
+		result[name] = item.OldKeys.KeyValues[i]
+	}
+	return result
+}
+
+func (b *tableBatch) add(item *abstract.ChangeItem) error {
+	if len(b.keyColumns) == 0 {
+		if item.Kind != abstract.InsertKind {
+			return xerrors.Errorf("%s of table %s without primary key is not supported", item.Kind, b.id.String())
+		}
+		// rows of tables without primary key are never deduplicated
+		b.upsert(strconv.Itoa(len(b.order)), item.AsMap())
+		return nil
+	}
+	if item.Kind == abstract.UpdateKind && item.IsToasted() {
+		return xerrors.Errorf("%s of table %s doesn't contain all columns, partial rows are not supported", item.Kind, b.id.String())
+	}
+
+	switch item.Kind {
+	case abstract.InsertKind:
+		b.upsert(item.CurrentKeysString(b.keyColumns), item.AsMap())
+	case abstract.UpdateKind:
+		oldKey := item.OldOrCurrentKeysString(b.keyColumns)
+		newKey := item.CurrentKeysString(b.keyColumns)
+		if oldKey != newKey {
+			b.deletes[oldKey] = oldKeyValues(item)
+			delete(b.upserts, oldKey)
+		}
+		b.deletes[newKey] = item.KeysAsMap()
+		b.upsert(newKey, item.AsMap())
+	case abstract.DeleteKind:
+		key := item.OldOrCurrentKeysString(b.keyColumns)
+		b.deletes[key] = oldKeyValues(item)
+		delete(b.upserts, key)
+	default:
+		return xerrors.Errorf("unexpected kind: %s", item.Kind)
+	}
+	return nil
+}
+
+func (b *tableBatch) rows() []map[string]any {
+	result := make([]map[string]any, 0, len(b.upserts))
+	seen := make(map[string]bool, len(b.upserts))
+	for _, key := range b.order {
+		row, ok := b.upserts[key]
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, row)
+	}
+	return result
+}
+
+func (s *Sink) tableID(item *abstract.ChangeItem) TableIdentifier {
+	namespace := s.cfg.Namespace
+	if namespace == "" {
+		namespace = item.Schema
+	}
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	return TableIdentifier{Namespace: namespace, Name: item.Table}
+}
+
+func (s *Sink) Push(items []abstract.ChangeItem) error {
+	batches := map[TableIdentifier]*tableBatch{}
+	var order []TableIdentifier
+	flush := func(id TableIdentifier) error {
+		batch, ok := batches[id]
+		if !ok {
+			return nil
+		}
+		delete(batches, id)
+		if err := s.writeBatch(batch); err != nil {
+			return xerrors.Errorf("unable to write batch of table %s: %w", id.String(), err)
+		}
+		return nil
+	}
+
+	for i := range items {
+		item := &items[i]
+		id := s.tableID(item)
+		switch item.Kind {
+		case abstract.InsertKind, abstract.UpdateKind, abstract.DeleteKind:
+			if batch, ok := batches[id]; ok && !batch.sameSchema(item.TableSchema) {
+				if err := flush(id); err != nil {
+					return err
+				}
+			}
+			if _, ok := batches[id]; !ok {
+				batches[id] = newTableBatch(id, item.TableSchema)
+				order = append(order, id)
+			}
+			if err := batches[id].add(item); err != nil {
+				return xerrors.Errorf("unable to add item: %w", err)
+			}
+		case abstract.TruncateTableKind:
+			if err := flush(id); err != nil {
+				return err
+			}
+			if err := s.truncate(id); err != nil {
+				return xerrors.Errorf("unable to truncate table %s: %w", id.String(), err)
+			}
+		case abstract.DropTableKind:
+			delete(batches, id)
+			if err := s.catalog.DropTable(s.ctx, id); err != nil {
+				return xerrors.Errorf("unable to drop table %s: %w", id.String(), err)
+			}
+			delete(s.tables, id)
+		case abstract.InitShardedTableLoad, abstract.InitTableLoad, abstract.DoneTableLoad, abstract.DoneShardedTableLoad, abstract.SynchronizeKind:
+			// not needed for now
+		case abstract.DDLKind,
+			abstract.PgDDLKind,
+			abstract.MongoCreateKind,
+			abstract.MongoRenameKind,
+			abstract.MongoDropKind,
+			abstract.ChCreateTableKind:
+			s.logger.Warnf("kind: %s not supported, skip", item.Kind)
+		default:
+			return xerrors.Errorf("kind: %v not supported", item.Kind)
+		}
+	}
+
+	for _, id := range order {
+		if err := flush(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTable returns cached table or loads it from catalog, nil is returned if table doesn't exist
+func (s *Sink) loadTable(id TableIdentifier) (*Table, error) {
+	if table, ok := s.tables[id]; ok {
+		return table, nil
+	}
+	table, err := s.catalog.LoadTable(s.ctx, id)
+	if err != nil {
+		if xerrors.Is(err, ErrTableNotFound) {
+			return nil, nil
+		}
+		return nil, xerrors.Errorf("unable to load table: %w", err)
+	}
+	if err := table.Metadata.Validate(); err != nil {
+		return nil, xerrors.Errorf("unsupported table %s: %w", id.String(), err)
+	}
+	s.tables[id] = table
+	return table, nil
+}
+
+func (s *Sink) truncate(id TableIdentifier) error {
+	table, err := s.loadTable(id)
+	if err != nil {
+		return err
+	}
+	if table == nil {
+		return nil
+	}
+	return s.commit(table, nil, nil, nil, "delete")
+}
+
+func (s *Sink) writeBatch(batch *tableBatch) error {
+	columns := batch.tableSchema.Columns()
+	table, err := s.loadTable(batch.id)
+	if err != nil {
+		return err
+	}
+	var evolvedSchema *Schema
+	if table == nil {
+		initialSchema, err := newSchema(columns)
+		if err != nil {
+			return xerrors.Errorf("unable to build table schema: %w", err)
+		}
+		table, err = s.catalog.CreateTable(s.ctx, batch.id, initialSchema)
+		if err != nil {
+			return xerrors.Errorf("unable to create table: %w", err)
+		}
+		s.tables[batch.id] = table
+	} else {
+		evolvedSchema, err = evolveSchema(columns, table.Metadata)
+		if err != nil {
+			return xerrors.Errorf("unable to evolve table schema: %w", err)
+		}
+		if evolvedSchema != nil {
+			s.logger.Info("iceberg table schema is changed", log.String("table", batch.id.String()), log.Any("schema", evolvedSchema))
+		}
+	}
+	tableSchema := evolvedSchema
+	if tableSchema == nil {
+		tableSchema = table.Metadata.CurrentSchema()
+	}
+
+	var dataFiles, deleteFiles []dataFile
+	if rows := batch.rows(); len(rows) > 0 {
+		file, err := s.writeDataFile(table.Metadata.Location, tableSchema.Fields, columns, rows, contentData, nil)
+		if err != nil {
+			return xerrors.Errorf("unable to write data file: %w", err)
+		}
+		dataFiles = append(dataFiles, *file)
+	}
+	if len(batch.deletes) > 0 {
+		equalityIDs, err := keyFieldIDs(columns, tableSchema)
+		if err != nil {
+			return xerrors.Errorf("unable to get equality fields: %w", err)
+		}
+		var keyFields []*NestedField
+		for _, field := range tableSchema.Fields {
+			if batch.keyColumns[field.Name] {
+				keyFields = append(keyFields, field)
+			}
+		}
+		keys := make([]map[string]any, 0, len(batch.deletes))
+		for _, key := range batch.deletes {
+			keys = append(keys, key)
+		}
+		file, err := s.writeDataFile(table.Metadata.Location, keyFields, columns, keys, contentEqualityDelete, equalityIDs)
+		if err != nil {
+			return xerrors.Errorf("unable to write equality delete file: %w", err)
+		}
+		deleteFiles = append(deleteFiles, *file)
+	}
+
+	operation := "append"
+	if len(deleteFiles) > 0 {
+		operation = "overwrite"
+	}
+	if err := s.commit(table, evolvedSchema, dataFiles, deleteFiles, operation); err != nil {
+		return err
+	}
+	s.metrics.Table(batch.id.String(), "rows", len(batch.upserts)+len(batch.deletes))
+	return nil
+}
+
+func (s *Sink) writeDataFile(tableLocation string, fields []*NestedField, columns abstract.TableColumns, rows []map[string]any, content int, equalityIDs []int) (*dataFile, error) {
+	fastColumns := abstract.MakeFastTableSchema(columns)
+	values := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		values[i] = make(map[string]any, len(fields))
+		for _, field := range fields {
+			val, err := icebergValue(field, fastColumns[abstract.ColumnName(field.Name)], row[field.Name])
+			if err != nil {
+				return nil, xerrors.Errorf("unable to convert value of column %s: %w", field.Name, err)
+			}
+			values[i][field.Name] = val
+		}
+	}
+	data, err := writeParquet(fields, values)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to serialize rows: %w", err)
+	}
+	suffix := ""
+	if content == contentEqualityDelete {
+		suffix = "-deletes"
+	}
+	location := fmt.Sprintf("%s/data/%s%s.parquet", tableLocation, uuid.NewString(), suffix)
+	if err := s.fio.Write(location, data); err != nil {
+		return nil, xerrors.Errorf("unable to upload file: %w", err)
+	}
+	return &dataFile{
+		Content:     content,
+		Path:        location,
+		RecordCount: int64(len(rows)),
+		SizeInBytes: int64(len(data)),
+		EqualityIDs: equalityIDs,
+	}, nil
+}
+
+// commit adds snapshot with given files. Previous manifests are carried forward, unless it's a truncate (no files at all).
+func (s *Sink) commit(table *Table, evolvedSchema *Schema, dataFiles, deleteFiles []dataFile, operation string) error {
+	meta := table.Metadata
+	snapshotID := rand.Int63()
+	sequenceNumber := meta.LastSequenceNumber + 1
+	schemaID := meta.CurrentSchemaID
+	tableSchema := meta.CurrentSchema()
+	if evolvedSchema != nil {
+		schemaID = evolvedSchema.SchemaID
+		tableSchema = evolvedSchema
+	}
+
+	var manifests []*manifestFile
+	var parentSnapshotID *int64
+	if parent := meta.CurrentSnapshot(); parent != nil {
+		parentSnapshotID = &parent.SnapshotID
+		if operation != "delete" {
+			previous, err := readManifestList(s.fio, parent.ManifestList)
+			if err != nil {
+				return xerrors.Errorf("unable to read manifests of snapshot %d: %w", parent.SnapshotID, err)
+			}
+			manifests = append(manifests, previous...)
+		}
+	}
+	for _, files := range []struct {
+		content int
+		files   []dataFile
+	}{{manifestContentData, dataFiles}, {manifestContentDeletes, deleteFiles}} {
+		if len(files.files) == 0 {
+			continue
+		}
+		manifest, err := writeManifest(s.fio, manifestLocation(meta.Location), tableSchema, snapshotID, sequenceNumber, files.content, files.files)
+		if err != nil {
+			return xerrors.Errorf("unable to write manifest: %w", err)
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	snapshot := &Snapshot{
+		SnapshotID:       snapshotID,
+		ParentSnapshotID: parentSnapshotID,
+		SequenceNumber:   sequenceNumber,
+		TimestampMs:      time.Now().UnixMilli(),
+		ManifestList:     manifestListLocation(meta.Location, snapshotID),
+		Summary:          snapshotSummary(operation, dataFiles, deleteFiles),
+		SchemaID:         &schemaID,
+	}
+	if err := writeManifestList(s.fio, snapshot.ManifestList, snapshot, manifests); err != nil {
+		return xerrors.Errorf("unable to write manifest list: %w", err)
+	}
+
+	committed, err := s.catalog.CommitTable(s.ctx, table, &TableUpdate{Schema: evolvedSchema, Snapshot: snapshot})
+	if err != nil {
+		// table could be changed concurrently, it will be reloaded on retry
+		delete(s.tables, table.Identifier)
+		return xerrors.Errorf("unable to commit snapshot: %w", err)
+	}
+	s.tables[table.Identifier] = committed
+	s.logger.Info("iceberg snapshot committed",
+		log.String("table", table.Identifier.String()),
+		log.Int64("snapshot_id", snapshotID),
+		log.Int64("sequence_number", sequenceNumber),
+		log.String("operation", operation),
+	)
+	return nil
+}
+
+func snapshotSummary(operation string, dataFiles, deleteFiles []dataFile) map[string]string {
+	var records, deletes int64
+	for _, file := range dataFiles {
+		records += file.RecordCount
+	}
+	for _, file := range deleteFiles {
+		deletes += file.RecordCount
+	}
+	return map[string]string{
+		"operation":              operation,
+		"added-data-files":       strconv.Itoa(len(dataFiles)),
+		"added-records":          strconv.FormatInt(records, 10),
+		"added-delete-files":     strconv.Itoa(len(deleteFiles)),
+		"added-equality-deletes": strconv.FormatInt(deletes, 10),
+	}
+}
+
+func (s *Sink) Close() error {
+	s.cancel()
+	return nil
+}
+
+func NewSink(cfg *IcebergDestination, lgr log.Logger, registry metrics.Registry) (*Sink, error) {
+	fio := newFileIO(cfg.ConnectionConfig, lgr)
+	catalog, err := NewCatalog(cfg, fio, lgr)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to init catalog: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Sink{
+		cfg:     cfg,
+		fio:     fio,
+		catalog: catalog,
+		logger:  lgr,
+		metrics: stats.NewSinkerStats(registry),
+		ctx:     ctx,
+		cancel:  cancel,
+		tables:  map[TableIdentifier]*Table{},
+	}, nil
+}
@@ -0,0 +1,296 @@
+package iceberg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/avro"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+var testColumns = []abstract.ColSchema{
+	{ColumnName: "id", DataType: schema.TypeInt32.String(), PrimaryKey: true},
+	{ColumnName: "name", DataType: schema.TypeString.String()},
+	{ColumnName: "created_at", DataType: schema.TypeTimestamp.String()},
+}
+
+func makeItem(kind abstract.Kind, columns []abstract.ColSchema, values ...any) abstract.ChangeItem {
+	names := make([]string, len(values))
+	for i := range values {
+		names[i] = columns[i].ColumnName
+	}
+	return abstract.ChangeItem{
+		Kind:         kind,
+		Schema:       "public",
+		Table:        "users",
+		ColumnNames:  names,
+		ColumnValues: values,
+		TableSchema:  abstract.NewTableSchema(columns),
+	}
+}
+
+func makeDelete(columns []abstract.ColSchema, id any) abstract.ChangeItem {
+	item := makeItem(abstract.DeleteKind, columns)
+	item.OldKeys = abstract.OldKeysType{KeyNames: []string{"id"}, KeyTypes: nil, KeyValues: []any{id}}
+	return item
+}
+
+func newTestSink(t *testing.T) (*Sink, *IcebergDestination) {
+	dst := &IcebergDestination{
+		CatalogType: CatalogFS,
+		Warehouse:   t.TempDir(),
+	}
+	dst.WithDefaults()
+	require.NoError(t, dst.Validate())
+	sink, err := NewSink(dst, logger.Log, solomon.NewRegistry(solomon.NewRegistryOpts()))
+	require.NoError(t, err)
+	return sink, dst
+}
+
+type dataFileEntry struct {
+	file           dataFile
+	sequenceNumber int64
+}
+
+func readManifestEntries(t *testing.T, fio fileIO, manifest *manifestFile) []dataFileEntry {
+	data, err := fio.Read(manifest.Path)
+	require.NoError(t, err)
+	reader, err := avro.NewOCFReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, "2", string(reader.Metadata()["format-version"]))
+	var result []dataFileEntry
+	for {
+		val, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		entry := val.(map[string]any)
+		file := entry["data_file"].(map[string]any)
+		var equalityIDs []int
+		if ids, ok := file["equality_ids"].([]any); ok {
+			for _, id := range ids {
+				equalityIDs = append(equalityIDs, int(id.(int32)))
+			}
+		}
+		require.Nil(t, entry["sequence_number"])
+		result = append(result, dataFileEntry{
+			file: dataFile{
+				Content:     int(file["content"].(int32)),
+				Path:        file["file_path"].(string),
+				RecordCount: file["record_count"].(int64),
+				SizeInBytes: file["file_size_in_bytes"].(int64),
+				EqualityIDs: equalityIDs,
+			},
+			sequenceNumber: manifest.SequenceNumber,
+		})
+	}
+	return result
+}
+
+func readParquetRows(t *testing.T, fio fileIO, location string, fieldIDs map[string]int) []map[string]any {
+	data, err := fio.Read(location)
+	require.NoError(t, err)
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	fields := file.Schema().Fields()
+	for _, field := range fields {
+		require.Equal(t, fieldIDs[field.Name()], field.ID(), field.Name())
+	}
+	reader := parquet.NewReader(file)
+	rows := make([]parquet.Row, file.NumRows())
+	n, err := reader.ReadRows(rows)
+	if err != io.EOF {
+		require.NoError(t, err)
+	}
+	var result []map[string]any
+	for _, row := range rows[:n] {
+		record := map[string]any{}
+		for _, val := range row {
+			name := fields[val.Column()].Name()
+			switch {
+			case val.IsNull():
+				record[name] = nil
+			case val.Kind() == parquet.Int32:
+				record[name] = val.Int32()
+			case val.Kind() == parquet.Int64:
+				record[name] = val.Int64()
+			case val.Kind() == parquet.ByteArray:
+				record[name] = string(val.ByteArray())
+			default:
+				record[name] = val.String()
+			}
+		}
+		result = append(result, record)
+	}
+	return result
+}
+
+// readTable reads current snapshot of table & applies equality deletes
+func readTable(t *testing.T, sink *Sink, id TableIdentifier) []map[string]any {
+	table, err := sink.catalog.LoadTable(sink.ctx, id)
+	require.NoError(t, err)
+	snapshot := table.Metadata.CurrentSnapshot()
+	require.NotNil(t, snapshot)
+	fieldIDs := map[string]int{}
+	for _, tableSchema := range table.Metadata.Schemas {
+		for _, field := range tableSchema.Fields {
+			fieldIDs[field.Name] = field.ID
+		}
+	}
+
+	manifests, err := readManifestList(sink.fio, snapshot.ManifestList)
+	require.NoError(t, err)
+	var dataFiles, deleteFiles []dataFileEntry
+	for _, manifest := range manifests {
+		for _, entry := range readManifestEntries(t, sink.fio, manifest) {
+			if entry.file.Content == contentData {
+				require.Equal(t, manifestContentData, manifest.Content)
+				dataFiles = append(dataFiles, entry)
+			} else {
+				require.Equal(t, manifestContentDeletes, manifest.Content)
+				deleteFiles = append(deleteFiles, entry)
+			}
+		}
+	}
+
+	var result []map[string]any
+	for _, dataEntry := range dataFiles {
+		for _, row := range readParquetRows(t, sink.fio, dataEntry.file.Path, fieldIDs) {
+			deleted := false
+			for _, deleteEntry := range deleteFiles {
+				// equality deletes are applied to data files with lower sequence numbers
+				if deleteEntry.sequenceNumber <= dataEntry.sequenceNumber {
+					continue
+				}
+				for _, key := range readParquetRows(t, sink.fio, deleteEntry.file.Path, fieldIDs) {
+					if fmt.Sprint(key["id"]) == fmt.Sprint(row["id"]) {
+						deleted = true
+					}
+				}
+			}
+			if !deleted {
+				result = append(result, row)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return fmt.Sprint(result[i]["id"]) < fmt.Sprint(result[j]["id"])
+	})
+	return result
+}
+
+func TestSinkReplication(t *testing.T) {
+	sink, _ := newTestSink(t)
+	defer sink.Close()
+	id := TableIdentifier{Namespace: "public", Name: "users"}
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		makeItem(abstract.InsertKind, testColumns, int32(1), "a", ts),
+		makeItem(abstract.InsertKind, testColumns, int32(2), "b", ts),
+		makeItem(abstract.InsertKind, testColumns, int32(3), "c", nil),
+	}))
+	require.Equal(t, []map[string]any{
+		{"id": int32(1), "name": "a", "created_at": ts.UnixMicro()},
+		{"id": int32(2), "name": "b", "created_at": ts.UnixMicro()},
+		{"id": int32(3), "name": "c", "created_at": nil},
+	}, readTable(t, sink, id))
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		makeItem(abstract.UpdateKind, testColumns, int32(1), "a1", ts),
+		makeDelete(testColumns, int32(2)),
+		makeItem(abstract.InsertKind, testColumns, int32(4), "d", nil),
+		makeItem(abstract.UpdateKind, testColumns, int32(4), "d1", nil),
+	}))
+	require.Equal(t, []map[string]any{
+		{"id": int32(1), "name": "a1", "created_at": ts.UnixMicro()},
+		{"id": int32(3), "name": "c", "created_at": nil},
+		{"id": int32(4), "name": "d1", "created_at": nil},
+	}, readTable(t, sink, id))
+
+	table, err := sink.catalog.LoadTable(sink.ctx, id)
+	require.NoError(t, err)
+	require.Len(t, table.Metadata.Snapshots, 2)
+	require.Equal(t, int64(2), table.Metadata.LastSequenceNumber)
+	require.Equal(t, "overwrite", table.Metadata.CurrentSnapshot().Summary["operation"])
+	require.Equal(t, []int{1}, table.Metadata.CurrentSchema().IdentifierFieldIDs)
+
+	// new sink continues from the committed metadata
+	sink2, err := NewSink(sink.cfg, logger.Log, solomon.NewRegistry(solomon.NewRegistryOpts()))
+	require.NoError(t, err)
+	require.NoError(t, sink2.Push([]abstract.ChangeItem{makeDelete(testColumns, int32(3))}))
+	require.Len(t, readTable(t, sink2, id), 2)
+
+	require.NoError(t, sink2.Push([]abstract.ChangeItem{{Kind: abstract.TruncateTableKind, Schema: "public", Table: "users"}}))
+	require.Empty(t, readTable(t, sink2, id))
+
+	require.NoError(t, sink2.Push([]abstract.ChangeItem{{Kind: abstract.DropTableKind, Schema: "public", Table: "users"}}))
+	_, err = sink2.catalog.LoadTable(sink2.ctx, id)
+	require.ErrorIs(t, err, ErrTableNotFound)
+}
+
+func TestSinkSchemaEvolution(t *testing.T) {
+	sink, _ := newTestSink(t)
+	defer sink.Close()
+	id := TableIdentifier{Namespace: "public", Name: "users"}
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{makeItem(abstract.InsertKind, testColumns, int32(1), "a", nil)}))
+
+	evolvedColumns := []abstract.ColSchema{
+		{ColumnName: "id", DataType: schema.TypeInt64.String(), PrimaryKey: true},
+		{ColumnName: "name", DataType: schema.TypeString.String()},
+		{ColumnName: "score", DataType: schema.TypeFloat64.String()},
+	}
+	require.NoError(t, sink.Push([]abstract.ChangeItem{makeItem(abstract.InsertKind, evolvedColumns, int64(2), "b", 1.5)}))
+
+	table, err := sink.catalog.LoadTable(sink.ctx, id)
+	require.NoError(t, err)
+	require.Len(t, table.Metadata.Schemas, 2)
+	require.Equal(t, 1, table.Metadata.CurrentSchemaID)
+	require.Equal(t, 4, table.Metadata.LastColumnID)
+	current := table.Metadata.CurrentSchema()
+	require.Equal(t, []*NestedField{
+		{ID: 1, Name: "id", Required: true, Type: typeLong},
+		{ID: 2, Name: "name", Required: false, Type: typeString},
+		{ID: 4, Name: "score", Required: false, Type: typeDouble},
+	}, current.Fields)
+	require.Len(t, readTable(t, sink, id), 2)
+
+	incompatibleColumns := []abstract.ColSchema{
+		{ColumnName: "id", DataType: schema.TypeInt64.String(), PrimaryKey: true},
+		{ColumnName: "name", DataType: schema.TypeInt64.String()},
+	}
+	require.Error(t, sink.Push([]abstract.ChangeItem{makeItem(abstract.InsertKind, incompatibleColumns, int64(3), int64(3))}))
+}
+
+func TestSinkWithoutPrimaryKey(t *testing.T) {
+	sink, _ := newTestSink(t)
+	defer sink.Close()
+	columns := []abstract.ColSchema{
+		{ColumnName: "id", DataType: schema.TypeInt32.String()},
+		{ColumnName: "payload", DataType: schema.TypeAny.String()},
+	}
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		makeItem(abstract.InsertKind, columns, int32(1), map[string]any{"a": 1}),
+		makeItem(abstract.InsertKind, columns, int32(1), []any{"b"}),
+	}))
+	rows := readTable(t, sink, TableIdentifier{Namespace: "public", Name: "users"})
+	require.Len(t, rows, 2)
+	payloads := []string{rows[0]["payload"].(string), rows[1]["payload"].(string)}
+	sort.Strings(payloads)
+	require.Equal(t, []string{`["b"]`, `{"a":1}`}, payloads)
+	require.True(t, json.Valid([]byte(payloads[0])))
+
+	require.Error(t, sink.Push([]abstract.ChangeItem{makeItem(abstract.UpdateKind, columns, int32(1), nil)}))
+}
@@ -37,6 +37,9 @@ func IsKafkaRawMessage(items []abstract.ChangeItem) bool {
 	return items[0].TableSchema == kafkaRawDataSchema
 }
 
+// MakeKafkaRawMessage makes a mirror row of the message, its QueueMessageMeta is the source partition and offset,
+// so sinks tracking source positions (transactional kafka, exactly-once clickhouse) track every partition separately
+// instead of the single LSN stream of offsets of all partitions
 func MakeKafkaRawMessage(table string, commitTime time.Time, topic string, shard int, offset int64, key, data []byte) abstract.ChangeItem {
 	return abstract.ChangeItem{
 		ID:          0,
@@ -61,7 +64,7 @@ func MakeKafkaRawMessage(table string, commitTime time.Time, topic string, shard
 		Size:             abstract.RawEventSize(uint64(len(data))),
 		TxID:             "",
 		Query:            "",
-		QueueMessageMeta: changeitem.QueueMessageMeta{TopicName: "", PartitionNum: 0, Offset: 0, Index: 0},
+		QueueMessageMeta: changeitem.QueueMessageMeta{TopicName: topic, PartitionNum: shard, Offset: uint64(offset), Index: 0},
 	}
 }
 
@@ -0,0 +1,23 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/changeitem"
+	"github.com/transferia/transferia/pkg/util/positions"
+)
+
+func TestMakeKafkaRawMessagePositions(t *testing.T) {
+	first := MakeKafkaRawMessage("src", time.Time{}, "src", 0, 7, []byte("key"), []byte("a"))
+	second := MakeKafkaRawMessage("src", time.Time{}, "src", 1, 3, []byte("key"), []byte("b"))
+	require.Equal(t, changeitem.QueueMessageMeta{TopicName: "src", PartitionNum: 1, Offset: 3, Index: 0}, second.QueueMessageMeta)
+
+	// offsets of different partitions are not comparable, so they are tracked separately
+	require.Equal(t, positions.Watermarks{
+		"src/0": {Offset: 7, Index: 0},
+		"src/1": {Offset: 3, Index: 0},
+	}, positions.Advance([]abstract.ChangeItem{first, second}))
+}
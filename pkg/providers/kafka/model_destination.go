@@ -43,6 +43,12 @@ type KafkaDestination struct {
 
 	// Compression which compression mechanism use for writer, default - None
 	Compression Encoding
+
+	// Transactional enables exactly-once replication: every push is written in one kafka transaction
+	// together with source positions of its rows, consumers should read topics with isolation.level=read_committed
+	Transactional bool
+	// TransactionalIDPrefix is a prefix of transactional IDs of producers, by default it's the transfer ID
+	TransactionalIDPrefix string
 }
 
 var _ model.Destination = (*KafkaDestination)(nil)
@@ -34,6 +34,10 @@ type KafkaSource struct {
 
 	OffsetPolicy          OffsetPolicy // specify from what topic part start message consumption
 	ParseQueueParallelism int
+
+	// ReadCommitted skips messages of aborted transactions and holds messages of open ones,
+	// it's required to read topics written by transactional producers exactly once
+	ReadCommitted bool
 }
 
 type OffsetPolicy string
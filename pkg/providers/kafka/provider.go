@@ -44,8 +44,10 @@ var (
 )
 
 var systemTopics = set.New(
-	"__consumer_offsets", // is used to store information about committed offsets for each topic:partition per group of consumers (groupID).
-	"_schema",            // is not a default kafka topic (at least at kafka 8,9). This is an internal topic used by the Schema Registry which is a distributed storage layer for Avro schemas.
+	"__consumer_offsets",  // is used to store information about committed offsets for each topic:partition per group of consumers (groupID).
+	"_schema",             // is not a default kafka topic (at least at kafka 8,9). This is an internal topic used by the Schema Registry which is a distributed storage layer for Avro schemas.
+	"__transaction_state", // is used by transaction coordinators to store states of transactions.
+	PositionsTopic,        // is used by transactional sinks to store positions of sources, see 'transactionalSink'.
 )
 
 type Provider struct {
@@ -144,6 +146,13 @@ func (p *Provider) Sink(middlewares.Config) (abstract.Sinker, error) {
 		return nil, xerrors.Errorf("unable to resolve connection for sink: %w", err)
 	}
 	cfgCopy.FormatSettings = InferFormatSettings(p.transfer.Src, cfgCopy.FormatSettings)
+	if cfgCopy.Transactional {
+		sourceGroupID := ""
+		if src, ok := p.transfer.Src.(*KafkaSource); ok && sameCluster(src.Connection, cfgCopy.Connection) {
+			sourceGroupID = p.transfer.ID
+		}
+		return NewTransactionalSink(&cfgCopy, p.registry, p.logger, TransactionalID(p.transfer, &cfgCopy), sourceGroupID)
+	}
 	return NewReplicationSink(&cfgCopy, p.registry, p.logger)
 }
 
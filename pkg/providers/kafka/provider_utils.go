@@ -1,6 +1,9 @@
 package kafka
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/abstract"
 	"github.com/transferia/transferia/pkg/abstract/model"
@@ -8,6 +11,7 @@ import (
 	debezium_prod_status "github.com/transferia/transferia/pkg/debezium/prodstatus"
 	"github.com/transferia/transferia/pkg/providers/airbyte"
 	clickhouse "github.com/transferia/transferia/pkg/providers/clickhouse/model"
+	"github.com/transferia/transferia/pkg/providers/mongo"
 	"github.com/transferia/transferia/pkg/providers/mysql"
 	"github.com/transferia/transferia/pkg/providers/postgres"
 )
@@ -49,6 +53,8 @@ func InferFormatSettings(src model.Source, formatSettings model.SerializationFor
 			result.Settings[debeziumparameters.SourceType] = "pg"
 		case *mysql.MysqlSource:
 			result.Settings[debeziumparameters.SourceType] = "mysql"
+		case *mongo.MongoSource:
+			result.Settings[debeziumparameters.SourceType] = "mongo"
 		}
 	}
 
@@ -90,3 +96,36 @@ func sourceCompatible(src model.Source, transferType abstract.TransferType, seri
 		return xerrors.Errorf("unknown serializer name: %s", serializationName)
 	}
 }
+
+// TransactionalID returns the transactional ID of producers of the current job of the transfer,
+// so a restarted worker fences its previous instance
+func TransactionalID(transfer *model.Transfer, dst *KafkaDestination) string {
+	prefix := dst.TransactionalIDPrefix
+	if prefix == "" {
+		prefix = transfer.ID
+	}
+	return fmt.Sprintf("%s-%d", prefix, transfer.CurrentJobIndex())
+}
+
+// sameCluster checks if connections point to the same kafka cluster, by its ID or by its brokers
+func sameCluster(src, dst *KafkaConnectionOptions) bool {
+	if src == nil || dst == nil {
+		return false
+	}
+	if src.ClusterID != "" || dst.ClusterID != "" {
+		return src.ClusterID == dst.ClusterID
+	}
+	if len(src.Brokers) == 0 || len(src.Brokers) != len(dst.Brokers) {
+		return false
+	}
+	srcBrokers := append([]string{}, src.Brokers...)
+	dstBrokers := append([]string{}, dst.Brokers...)
+	sort.Strings(srcBrokers)
+	sort.Strings(dstBrokers)
+	for i := range srcBrokers {
+		if srcBrokers[i] != dstBrokers[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/providers/kafka/writer"
+	"github.com/transferia/transferia/pkg/util/positions"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const (
+	// PositionsTopic keeps source positions of transactional sinks as metadata of offsets of their groups
+	PositionsTopic = "__data_transfer_positions"
+	// maxPositionsMetadata is the default of 'offset.metadata.max.bytes' of brokers
+	maxPositionsMetadata = 4096
+)
+
+// transactionalSink writes every push in one kafka transaction together with source positions of its rows.
+//
+// When the source is a Kafka topic of the same cluster, positions are offsets of the source consumer group,
+// so they are committed like in the consume-transform-produce loop of kafka streams.
+// Otherwise, positions are stored as metadata of the offset of the transactional ID group in the positions topic,
+// they are loaded on start and rows redelivered by the source after a restart are skipped.
+type transactionalSink struct {
+	*sink
+	txnWriter       writer.AbstractTransactionalWriter
+	transactionalID string
+	sourceGroupID   string
+
+	mu              sync.Mutex
+	watermarks      positions.Watermarks
+	positionsOffset int64
+}
+
+func (s *transactionalSink) Push(input []abstract.ChangeItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+
+	if err := s.txnWriter.BeginTxn(ctx); err != nil {
+		return xerrors.Errorf("unable to begin transaction %s: %w", s.transactionalID, err)
+	}
+	if err := s.pushInTxn(ctx, input); err != nil {
+		if abortErr := s.txnWriter.AbortTxn(ctx); abortErr != nil {
+			s.logger.Warn("unable to abort transaction", log.String("transactional_id", s.transactionalID), log.Error(abortErr))
+		}
+		return xerrors.Errorf("transaction %s failed: %w", s.transactionalID, err)
+	}
+	return nil
+}
+
+func (s *transactionalSink) pushInTxn(ctx context.Context, input []abstract.ChangeItem) error {
+	if s.sourceGroupID != "" {
+		if err := s.sink.Push(input); err != nil {
+			return xerrors.Errorf("unable to write messages: %w", err)
+		}
+		if offsets := sourceOffsets(input); len(offsets) > 0 {
+			if err := s.txnWriter.SendOffsets(ctx, s.sourceGroupID, offsets); err != nil {
+				return xerrors.Errorf("unable to commit offsets of group %s: %w", s.sourceGroupID, err)
+			}
+		}
+		if err := s.txnWriter.CommitTxn(ctx); err != nil {
+			return xerrors.Errorf("unable to commit: %w", err)
+		}
+		return nil
+	}
+
+	if s.watermarks == nil {
+		if err := s.loadWatermarks(ctx); err != nil {
+			return xerrors.Errorf("unable to load positions: %w", err)
+		}
+	}
+	rows := s.watermarks.Filter(input)
+	if len(rows) < len(input) {
+		s.logger.Info("skipped rows committed by previous transactions", log.Int("skipped", len(input)-len(rows)))
+	}
+	if err := s.sink.Push(rows); err != nil {
+		return xerrors.Errorf("unable to write messages: %w", err)
+	}
+	advanced := positions.Advance(rows)
+	watermarks := make(positions.Watermarks, len(s.watermarks)+len(advanced))
+	for stream, position := range s.watermarks {
+		watermarks[stream] = position
+	}
+	for stream, position := range advanced {
+		watermarks[stream] = position
+	}
+	if len(advanced) > 0 {
+		metadata, err := json.Marshal(watermarks)
+		if err != nil {
+			return xerrors.Errorf("unable to marshal positions: %w", err)
+		}
+		if len(metadata) > maxPositionsMetadata {
+			return abstract.NewFatalError(xerrors.Errorf("positions of %d source streams take %d bytes, which exceeds %d bytes of offset metadata", len(watermarks), len(metadata), maxPositionsMetadata))
+		}
+		offsets := map[string][]kafka.TxnOffsetCommit{
+			PositionsTopic: {{Partition: 0, Offset: s.positionsOffset + 1, Metadata: string(metadata)}},
+		}
+		if err := s.txnWriter.SendOffsets(ctx, s.transactionalID, offsets); err != nil {
+			return xerrors.Errorf("unable to commit positions: %w", err)
+		}
+	}
+	if err := s.txnWriter.CommitTxn(ctx); err != nil {
+		return xerrors.Errorf("unable to commit: %w", err)
+	}
+	if len(advanced) > 0 {
+		s.positionsOffset++
+	}
+	s.watermarks = watermarks
+	return nil
+}
+
+// loadWatermarks reads positions after the transaction is begun, so unfinished transactions of previous producers are aborted
+func (s *transactionalSink) loadWatermarks(ctx context.Context) error {
+	offsets, err := s.txnWriter.FetchOffsets(ctx, s.transactionalID, PositionsTopic, []int{0})
+	if err != nil {
+		return xerrors.Errorf("unable to fetch offsets of group %s: %w", s.transactionalID, err)
+	}
+	watermarks := positions.Watermarks{}
+	committed, ok := offsets[0]
+	if ok && committed.CommittedOffset >= 0 && committed.Metadata != "" {
+		if err := json.Unmarshal([]byte(committed.Metadata), &watermarks); err != nil {
+			return xerrors.Errorf("unable to unmarshal positions %q: %w", committed.Metadata, err)
+		}
+		s.positionsOffset = committed.CommittedOffset
+	}
+	s.logger.Info("loaded positions of transactional sink", log.String("transactional_id", s.transactionalID), log.Any("positions", watermarks))
+	s.watermarks = watermarks
+	return nil
+}
+
+// sourceOffsets returns offsets to commit for partitions of source topics, it's the next offset after the last row
+func sourceOffsets(input []abstract.ChangeItem) map[string][]kafka.TxnOffsetCommit {
+	type topicPartition struct {
+		topic     string
+		partition int
+	}
+	next := map[topicPartition]int64{}
+	for _, row := range input {
+		meta := row.QueueMessageMeta
+		if meta.TopicName == "" {
+			continue
+		}
+		tp := topicPartition{topic: meta.TopicName, partition: meta.PartitionNum}
+		if offset := int64(meta.Offset) + 1; offset > next[tp] {
+			next[tp] = offset
+		}
+	}
+	result := map[string][]kafka.TxnOffsetCommit{}
+	for tp, offset := range next {
+		result[tp.topic] = append(result[tp.topic], kafka.TxnOffsetCommit{Partition: tp.partition, Offset: offset, Metadata: ""})
+	}
+	for _, partitions := range result {
+		sort.Slice(partitions, func(i, j int) bool { return partitions[i].Partition < partitions[j].Partition })
+	}
+	return result
+}
+
+func newTransactionalSink(impl *sink, transactionalID string, sourceGroupID string) (*transactionalSink, error) {
+	txnWriter, ok := impl.writer.(writer.AbstractTransactionalWriter)
+	if !ok {
+		return nil, xerrors.Errorf("writer %T is not transactional", impl.writer)
+	}
+	return &transactionalSink{
+		sink:            impl,
+		txnWriter:       txnWriter,
+		transactionalID: transactionalID,
+		sourceGroupID:   sourceGroupID,
+
+		mu:              sync.Mutex{},
+		watermarks:      nil,
+		positionsOffset: 0,
+	}, nil
+}
+
+// NewTransactionalSink creates the exactly-once replication sink.
+// sourceGroupID is the consumer group of the Kafka source in the same cluster, it's empty for other sources.
+func NewTransactionalSink(cfg *KafkaDestination, registry metrics.Registry, lgr log.Logger, transactionalID string, sourceGroupID string) (abstract.Sinker, error) {
+	impl, err := NewSinkImpl(cfg, registry, lgr, writer.NewTransactionalWriterFactory(lgr, transactionalID), false)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create sink: %w", err)
+	}
+	return newTransactionalSink(impl.(*sink), transactionalID, sourceGroupID)
+}
@@ -0,0 +1,128 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	kafkaConn "github.com/transferia/transferia/pkg/connection/kafka"
+	"github.com/transferia/transferia/pkg/providers/kafka/writer"
+	serializer "github.com/transferia/transferia/pkg/serializer/queue"
+	"go.uber.org/mock/gomock"
+)
+
+func newTestTransactionalSink(t *testing.T, ctrl *gomock.Controller, sourceGroupID string) (*transactionalSink, *writer.MockAbstractTransactionalWriter) {
+	dst := &KafkaDestination{
+		Connection: &KafkaConnectionOptions{
+			TLS:     model.DefaultTLS,
+			Brokers: []string{"my_broker_0"},
+		},
+		Auth: &KafkaAuth{
+			Enabled:   true,
+			Mechanism: kafkaConn.KafkaSaslSecurityMechanism_SCRAM_SHA512,
+			User:      "user1",
+			Password:  "qwert12345",
+		},
+		Topic: "foo_bar",
+		FormatSettings: model.SerializationFormat{
+			Name: model.SerializationFormatMirror,
+		},
+		Transactional: true,
+	}
+	dst.WithDefaults()
+
+	currWriter := writer.NewMockAbstractTransactionalWriter(ctrl)
+	client := writer.NewMockAbstractWriterFactory(ctrl)
+	client.EXPECT().BuildWriter([]string{"my_broker_0"}, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(currWriter)
+
+	impl, err := NewSinkImpl(
+		dst,
+		solomon.NewRegistry(nil).WithTags(map[string]string{"ts": time.Now().String()}),
+		logger.Log,
+		client,
+		false,
+	)
+	require.NoError(t, err)
+	testSink, err := newTransactionalSink(impl.(*sink), "dtt-0", sourceGroupID)
+	require.NoError(t, err)
+	return testSink, currWriter
+}
+
+func rawMessage(offset int64, value string) abstract.ChangeItem {
+	return MakeKafkaRawMessage("src", time.Time{}, "src", 0, offset, []byte("key"), []byte(value))
+}
+
+func TestTransactionalSinkCommitsSourceOffsets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	testSink, currWriter := newTestTransactionalSink(t, ctrl, "dtt")
+
+	gomock.InOrder(
+		currWriter.EXPECT().BeginTxn(gomock.Any()).Return(nil),
+		currWriter.EXPECT().WriteMessages(gomock.Any(), gomock.Any(), "foo_bar", []serializer.SerializedMessage{
+			{Key: []byte("key"), Value: []byte("a")},
+			{Key: []byte("key"), Value: []byte("b")},
+		}).Return(nil),
+		currWriter.EXPECT().SendOffsets(gomock.Any(), "dtt", map[string][]kafka.TxnOffsetCommit{
+			"src": {{Partition: 0, Offset: 11, Metadata: ""}},
+		}).Return(nil),
+		currWriter.EXPECT().CommitTxn(gomock.Any()).Return(nil),
+	)
+
+	require.NoError(t, testSink.Push([]abstract.ChangeItem{rawMessage(5, "a"), rawMessage(10, "b")}))
+}
+
+func TestTransactionalSinkAbortsOnFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	testSink, currWriter := newTestTransactionalSink(t, ctrl, "dtt")
+
+	gomock.InOrder(
+		currWriter.EXPECT().BeginTxn(gomock.Any()).Return(nil),
+		currWriter.EXPECT().WriteMessages(gomock.Any(), gomock.Any(), "foo_bar", gomock.Any()).Return(xerrors.New("broker is down")),
+		currWriter.EXPECT().AbortTxn(gomock.Any()).Return(nil),
+	)
+
+	require.Error(t, testSink.Push([]abstract.ChangeItem{rawMessage(5, "a")}))
+}
+
+func TestTransactionalSinkSkipsCommittedPositions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	testSink, currWriter := newTestTransactionalSink(t, ctrl, "")
+
+	gomock.InOrder(
+		currWriter.EXPECT().BeginTxn(gomock.Any()).Return(nil),
+		currWriter.EXPECT().FetchOffsets(gomock.Any(), "dtt-0", PositionsTopic, []int{0}).Return(map[int]kafka.OffsetFetchPartition{
+			0: {Partition: 0, CommittedOffset: 3, Metadata: `{"src/0":{"Offset":5,"Index":0}}`, Error: nil},
+		}, nil),
+		currWriter.EXPECT().WriteMessages(gomock.Any(), gomock.Any(), "foo_bar", []serializer.SerializedMessage{
+			{Key: []byte("key"), Value: []byte("c")},
+		}).Return(nil),
+		currWriter.EXPECT().SendOffsets(gomock.Any(), "dtt-0", map[string][]kafka.TxnOffsetCommit{
+			PositionsTopic: {{Partition: 0, Offset: 4, Metadata: `{"src/0":{"Offset":6,"Index":0}}`}},
+		}).Return(nil),
+		currWriter.EXPECT().CommitTxn(gomock.Any()).Return(nil),
+
+		// positions are loaded once, everything is already committed in the second push
+		currWriter.EXPECT().BeginTxn(gomock.Any()).Return(nil),
+		currWriter.EXPECT().CommitTxn(gomock.Any()).Return(nil),
+	)
+
+	require.NoError(t, testSink.Push([]abstract.ChangeItem{rawMessage(4, "a"), rawMessage(5, "b"), rawMessage(6, "c")}))
+	require.NoError(t, testSink.Push([]abstract.ChangeItem{rawMessage(6, "c")}))
+}
+
+func TestSameCluster(t *testing.T) {
+	require.True(t, sameCluster(&KafkaConnectionOptions{ClusterID: "c1"}, &KafkaConnectionOptions{ClusterID: "c1"}))
+	require.False(t, sameCluster(&KafkaConnectionOptions{ClusterID: "c1"}, &KafkaConnectionOptions{Brokers: []string{"b1"}}))
+	require.True(t, sameCluster(&KafkaConnectionOptions{Brokers: []string{"b1", "b2"}}, &KafkaConnectionOptions{Brokers: []string{"b2", "b1"}}))
+	require.False(t, sameCluster(&KafkaConnectionOptions{Brokers: []string{"b1"}}, &KafkaConnectionOptions{Brokers: []string{"b2"}}))
+	require.False(t, sameCluster(nil, &KafkaConnectionOptions{Brokers: []string{"b2"}}))
+}
@@ -572,6 +572,9 @@ func NewSource(transferID string, cfg *KafkaSource, logger log.Logger, registry
 	if mechanism != nil {
 		opts = append(opts, kgo.SASL(mechanism))
 	}
+	if cfg.ReadCommitted {
+		opts = append(opts, kgo.FetchIsolationLevel(kgo.ReadCommitted()))
+	}
 
 	if cfg.BufferSize == 0 {
 		cfg.BufferSize = 100 * 1024 * 1024
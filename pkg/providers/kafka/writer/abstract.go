@@ -11,7 +11,7 @@ import (
 	"go.ytsaurus.tech/library/go/core/log"
 )
 
-// how to generate mock from 'AbstractWriter' and 'AbstractWriterFactory' interfaces:
+// how to generate mock from 'AbstractWriter', 'AbstractWriterFactory' and 'AbstractTransactionalWriter' interfaces:
 // > export GO111MODULE=on && ya tool mockgen -source ./abstract.go -package writer -destination ./writer_mock.go
 
 type AbstractWriter interface {
@@ -22,3 +22,14 @@ type AbstractWriter interface {
 type AbstractWriterFactory interface {
 	BuildWriter(brokers []string, compression kafka.Compression, saslMechanism sasl.Mechanism, tlsConfig *tls.Config, topicConfig [][2]string, batchBytes int64, dial func(ctx context.Context, network string, address string) (net.Conn, error)) AbstractWriter
 }
+
+// AbstractTransactionalWriter writes messages in transactions, messages become visible to read_committed consumers on commit
+type AbstractTransactionalWriter interface {
+	AbstractWriter
+	BeginTxn(ctx context.Context) error
+	// SendOffsets commits offsets of the consumer group as a part of the transaction
+	SendOffsets(ctx context.Context, groupID string, offsets map[string][]kafka.TxnOffsetCommit) error
+	CommitTxn(ctx context.Context) error
+	AbortTxn(ctx context.Context) error
+	FetchOffsets(ctx context.Context, groupID string, topic string, partitions []int) (map[int]kafka.OffsetFetchPartition, error)
+}
@@ -0,0 +1,60 @@
+package writer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/providers/kafka/client"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+// topicsRegistry creates topics before the first write to them
+type topicsRegistry struct {
+	brokers       []string
+	saslMechanism sasl.Mechanism
+	tlsConfig     *tls.Config
+	topicConfig   [][2]string
+
+	writersMutex sync.Mutex
+	knownTopics  map[string]bool
+
+	dial func(ctx context.Context, network string, address string) (net.Conn, error)
+}
+
+func (r *topicsRegistry) ensureTopicExists(lgr log.Logger, topic string) error {
+	r.writersMutex.Lock()
+	defer r.writersMutex.Unlock()
+
+	writerID := fmt.Sprintf("topic:%v", topic)
+	if r.knownTopics[writerID] {
+		return nil
+	}
+	kafkaClient, err := client.NewClient(r.brokers, r.saslMechanism, r.tlsConfig, r.dial)
+	if err != nil {
+		return xerrors.Errorf("unable to create kafka client, err: %w", err)
+	}
+	if err := kafkaClient.CreateTopicIfNotExist(lgr, topic, r.topicConfig); err != nil {
+		return xerrors.Errorf("unable to create topic, broker: %s, topic: %s, err: %w", r.brokers, topic, err)
+	}
+	r.knownTopics[writerID] = true
+	return nil
+}
+
+func newTopicsRegistry(brokers []string, saslMechanism sasl.Mechanism, tlsConfig *tls.Config, topicConfig [][2]string, dial func(ctx context.Context, network string, address string) (net.Conn, error)) *topicsRegistry {
+	return &topicsRegistry{
+		brokers:       brokers,
+		saslMechanism: saslMechanism,
+		tlsConfig:     tlsConfig,
+		topicConfig:   topicConfig,
+
+		writersMutex: sync.Mutex{},
+		knownTopics:  make(map[string]bool),
+
+		dial: dial,
+	}
+}
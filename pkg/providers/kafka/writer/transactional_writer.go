@@ -0,0 +1,487 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"hash/crc32"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	serializer "github.com/transferia/transferia/pkg/serializer/queue"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+var _ AbstractTransactionalWriter = (*TransactionalWriter)(nil)
+
+const (
+	transactionTimeout = time.Minute
+	requestTimeout     = 30 * time.Second
+
+	// default limit of the record batch size, it's the default of 'message.max.bytes' of brokers
+	defaultBatchBytes = 1000000
+	// size of the record batch header and of the record framing, see RecordSet.writeToVersion2
+	batchOverhead  = 61
+	recordOverhead = 21
+)
+
+// offsets of fields of the record batch v2 header, the protocol package always writes -1 as producer fields.
+// Encoded record set starts with 4 bytes of its size, so offsets are shifted by them.
+const (
+	batchCRCOffset        = 4 + 17
+	batchAttributesOffset = 4 + 21
+	batchProducerIDOffset = 4 + 43
+	batchEpochOffset      = 4 + 51
+	batchSequenceOffset   = 4 + 53
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+// TransactionalWriter writes messages in kafka transactions.
+// The producer ID and epoch are initialized once per transactional ID, which fences previous producers of the same ID
+// and aborts their unfinished transactions. Any failed transaction resets the producer, so the next one starts with a new epoch.
+type TransactionalWriter struct {
+	client          *kafka.Client
+	logger          log.Logger
+	topics          *topicsRegistry
+	transactionalID string
+	compression     kafka.Compression
+	batchBytes      int64
+	balancer        kafka.Balancer
+
+	mu             sync.Mutex
+	producer       *kafka.ProducerSession
+	sequences      map[topicPartition]int32
+	txnPartitions  map[topicPartition]bool
+	txnOffsets     bool
+	partitionLocks map[topicPartition]*sync.Mutex
+	topicsMeta     map[string][]int
+}
+
+func (w *TransactionalWriter) BeginTxn(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.producer == nil {
+		if err := w.initProducer(ctx); err != nil {
+			return xerrors.Errorf("unable to init transactional producer %s: %w", w.transactionalID, err)
+		}
+	}
+	w.txnPartitions = map[topicPartition]bool{}
+	w.txnOffsets = false
+	return nil
+}
+
+func (w *TransactionalWriter) initProducer(ctx context.Context) error {
+	var producer *kafka.ProducerSession
+	err := backoff.Retry(func() error {
+		res, err := w.client.InitProducerID(ctx, &kafka.InitProducerIDRequest{
+			Addr:                 nil,
+			TransactionalID:      w.transactionalID,
+			TransactionTimeoutMs: int(transactionTimeout.Milliseconds()),
+			ProducerID:           -1,
+			ProducerEpoch:        -1,
+		})
+		if err != nil {
+			return xerrors.Errorf("init producer id request failed: %w", err)
+		}
+		if res.Error != nil {
+			if isFatalTxnError(res.Error) {
+				return backoff.Permanent(abstract.NewFatalError(res.Error))
+			}
+			// the previous transaction of the ID is being completed
+			return xerrors.Errorf("init producer id error: %w", res.Error)
+		}
+		producer = res.Producer
+		return nil
+	}, backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), 10), ctx))
+	if err != nil {
+		return err
+	}
+	w.producer = producer
+	w.sequences = map[topicPartition]int32{}
+	return nil
+}
+
+func (w *TransactionalWriter) WriteMessages(ctx context.Context, lgr log.Logger, topicName string, currMessages []serializer.SerializedMessage) error {
+	if err := w.topics.ensureTopicExists(lgr, topicName); err != nil {
+		return xerrors.Errorf("unable to ensureTopicExists, topicName: %s, err: %w", topicName, err)
+	}
+	partitions, err := w.topicPartitions(ctx, topicName)
+	if err != nil {
+		return xerrors.Errorf("unable to get partitions of topic %s: %w", topicName, err)
+	}
+
+	byPartition := map[int][]serializer.SerializedMessage{}
+	for _, msg := range currMessages {
+		partition := w.balancer.Balance(kafka.Message{Topic: topicName, Key: msg.Key, Value: msg.Value}, partitions...)
+		byPartition[partition] = append(byPartition[partition], msg)
+	}
+	keys := make([]int, 0, len(byPartition))
+	for partition := range byPartition {
+		keys = append(keys, partition)
+	}
+	sort.Ints(keys)
+
+	if err := w.addPartitionsToTxn(ctx, topicName, keys); err != nil {
+		return xerrors.Errorf("unable to add partitions of topic %s to transaction: %w", topicName, err)
+	}
+	for _, partition := range keys {
+		if err := w.produce(ctx, topicPartition{topic: topicName, partition: partition}, byPartition[partition]); err != nil {
+			return xerrors.Errorf("unable to write messages, topicName: %s, partition: %d, messages: %d : %w", topicName, partition, len(byPartition[partition]), err)
+		}
+	}
+	return nil
+}
+
+func (w *TransactionalWriter) topicPartitions(ctx context.Context, topic string) ([]int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if partitions, ok := w.topicsMeta[topic]; ok {
+		return partitions, nil
+	}
+	res, err := w.client.Metadata(ctx, &kafka.MetadataRequest{Addr: nil, Topics: []string{topic}})
+	if err != nil {
+		return nil, xerrors.Errorf("metadata request failed: %w", err)
+	}
+	for _, t := range res.Topics {
+		if t.Name != topic {
+			continue
+		}
+		if t.Error != nil {
+			return nil, xerrors.Errorf("metadata error: %w", t.Error)
+		}
+		partitions := make([]int, 0, len(t.Partitions))
+		for _, p := range t.Partitions {
+			partitions = append(partitions, p.ID)
+		}
+		sort.Ints(partitions)
+		w.topicsMeta[topic] = partitions
+		return partitions, nil
+	}
+	return nil, xerrors.Errorf("no metadata of topic %s", topic)
+}
+
+func (w *TransactionalWriter) addPartitionsToTxn(ctx context.Context, topic string, partitions []int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.producer == nil {
+		return xerrors.New("transaction is not started")
+	}
+	var added []kafka.AddPartitionToTxn
+	for _, partition := range partitions {
+		if !w.txnPartitions[topicPartition{topic: topic, partition: partition}] {
+			added = append(added, kafka.AddPartitionToTxn{Partition: partition})
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	res, err := w.client.AddPartitionsToTxn(ctx, &kafka.AddPartitionsToTxnRequest{
+		Addr:            nil,
+		TransactionalID: w.transactionalID,
+		ProducerID:      w.producer.ProducerID,
+		ProducerEpoch:   w.producer.ProducerEpoch,
+		Topics:          map[string][]kafka.AddPartitionToTxn{topic: added},
+	})
+	if err != nil {
+		return xerrors.Errorf("add partitions request failed: %w", err)
+	}
+	for _, p := range res.Topics[topic] {
+		if p.Error != nil {
+			return txnError(xerrors.Errorf("partition %d: %w", p.Partition, p.Error), p.Error)
+		}
+	}
+	for _, p := range added {
+		w.txnPartitions[topicPartition{topic: topic, partition: p.Partition}] = true
+	}
+	return nil
+}
+
+// produce writes messages to the partition in batches, batches of a partition are written sequentially,
+// since the broker rejects batches with out of order sequence numbers
+func (w *TransactionalWriter) produce(ctx context.Context, tp topicPartition, messages []serializer.SerializedMessage) error {
+	lock := w.partitionLock(tp)
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, batch := range splitBatches(messages, w.batchBytes) {
+		w.mu.Lock()
+		producer := w.producer
+		sequence := w.sequences[tp]
+		w.mu.Unlock()
+		if producer == nil {
+			return xerrors.New("transaction is not started")
+		}
+
+		records, err := encodeTransactionalBatch(batch, w.compression, producer, sequence)
+		if err != nil {
+			return xerrors.Errorf("unable to encode record batch: %w", err)
+		}
+		res, err := w.client.RawProduce(ctx, &kafka.RawProduceRequest{
+			Addr:            nil,
+			Topic:           tp.topic,
+			Partition:       tp.partition,
+			RequiredAcks:    kafka.RequireAll,
+			MessageVersion:  2,
+			TransactionalID: w.transactionalID,
+			RawRecords:      records,
+		})
+		if err != nil {
+			return xerrors.Errorf("produce request failed: %w", err)
+		}
+		if res.Error != nil {
+			return txnError(xerrors.Errorf("produce error: %w", res.Error), res.Error)
+		}
+
+		w.mu.Lock()
+		w.sequences[tp] = sequence + int32(len(batch))
+		w.mu.Unlock()
+	}
+	return nil
+}
+
+func (w *TransactionalWriter) partitionLock(tp topicPartition) *sync.Mutex {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	lock, ok := w.partitionLocks[tp]
+	if !ok {
+		lock = new(sync.Mutex)
+		w.partitionLocks[tp] = lock
+	}
+	return lock
+}
+
+func (w *TransactionalWriter) SendOffsets(ctx context.Context, groupID string, offsets map[string][]kafka.TxnOffsetCommit) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.producer == nil {
+		return xerrors.New("transaction is not started")
+	}
+	addRes, err := w.client.AddOffsetsToTxn(ctx, &kafka.AddOffsetsToTxnRequest{
+		Addr:            nil,
+		TransactionalID: w.transactionalID,
+		ProducerID:      w.producer.ProducerID,
+		ProducerEpoch:   w.producer.ProducerEpoch,
+		GroupID:         groupID,
+	})
+	if err != nil {
+		return xerrors.Errorf("add offsets request failed: %w", err)
+	}
+	if addRes.Error != nil {
+		return txnError(xerrors.Errorf("add offsets error: %w", addRes.Error), addRes.Error)
+	}
+	w.txnOffsets = true
+
+	commitRes, err := w.client.TxnOffsetCommit(ctx, &kafka.TxnOffsetCommitRequest{
+		Addr:            nil,
+		TransactionalID: w.transactionalID,
+		GroupID:         groupID,
+		ProducerID:      w.producer.ProducerID,
+		ProducerEpoch:   w.producer.ProducerEpoch,
+		// offsets are committed without checks of the group generation
+		GenerationID:    -1,
+		MemberID:        "",
+		GroupInstanceID: "",
+		Topics:          offsets,
+	})
+	if err != nil {
+		return xerrors.Errorf("txn offset commit request failed: %w", err)
+	}
+	for topic, partitions := range commitRes.Topics {
+		for _, p := range partitions {
+			if p.Error != nil {
+				return txnError(xerrors.Errorf("txn offset commit error of %s/%d: %w", topic, p.Partition, p.Error), p.Error)
+			}
+		}
+	}
+	return nil
+}
+
+func (w *TransactionalWriter) CommitTxn(ctx context.Context) error {
+	return w.endTxn(ctx, true)
+}
+
+func (w *TransactionalWriter) AbortTxn(ctx context.Context) error {
+	return w.endTxn(ctx, false)
+}
+
+func (w *TransactionalWriter) endTxn(ctx context.Context, commit bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.producer == nil {
+		return nil
+	}
+	if len(w.txnPartitions) == 0 && !w.txnOffsets {
+		// nothing is added to the transaction, so coordinator doesn't know about it
+		return nil
+	}
+	res, err := w.client.EndTxn(ctx, &kafka.EndTxnRequest{
+		Addr:            nil,
+		TransactionalID: w.transactionalID,
+		ProducerID:      w.producer.ProducerID,
+		ProducerEpoch:   w.producer.ProducerEpoch,
+		Committed:       commit,
+	})
+	if err == nil && res.Error != nil {
+		err = txnError(res.Error, res.Error)
+	}
+	if err != nil {
+		// the next transaction bumps the epoch, which aborts this one if it's still open
+		w.producer = nil
+		return xerrors.Errorf("unable to end transaction (commit=%v): %w", commit, err)
+	}
+	if !commit {
+		w.producer = nil
+	}
+	w.txnPartitions = map[topicPartition]bool{}
+	w.txnOffsets = false
+	return nil
+}
+
+// FetchOffsets creates the topic if it doesn't exist, since offsets of unknown topics can't be committed
+func (w *TransactionalWriter) FetchOffsets(ctx context.Context, groupID string, topic string, partitions []int) (map[int]kafka.OffsetFetchPartition, error) {
+	if err := w.topics.ensureTopicExists(w.logger, topic); err != nil {
+		return nil, xerrors.Errorf("unable to ensure topic %s exists: %w", topic, err)
+	}
+	res, err := w.client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		Addr:    nil,
+		GroupID: groupID,
+		Topics:  map[string][]int{topic: partitions},
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("offset fetch request failed: %w", err)
+	}
+	if res.Error != nil {
+		return nil, xerrors.Errorf("offset fetch error: %w", res.Error)
+	}
+	result := map[int]kafka.OffsetFetchPartition{}
+	for _, p := range res.Topics[topic] {
+		if p.Error != nil {
+			return nil, xerrors.Errorf("offset fetch error of %s/%d: %w", topic, p.Partition, p.Error)
+		}
+		result[p.Partition] = p
+	}
+	return result, nil
+}
+
+func (w *TransactionalWriter) Close() error {
+	return w.AbortTxn(context.Background())
+}
+
+// txnError marks errors of fenced producers as fatal, another worker with the same transactional ID is running
+func txnError(err error, kafkaErr error) error {
+	if isFatalTxnError(kafkaErr) {
+		return abstract.NewFatalError(err)
+	}
+	return err
+}
+
+func isFatalTxnError(err error) bool {
+	return xerrors.Is(err, kafka.ProducerFenced) ||
+		xerrors.Is(err, kafka.InvalidProducerEpoch) ||
+		xerrors.Is(err, kafka.TransactionalIDAuthorizationFailed)
+}
+
+// splitBatches splits messages into batches which fit into the limit of the batch size before compression
+func splitBatches(messages []serializer.SerializedMessage, batchBytes int64) [][]serializer.SerializedMessage {
+	if batchBytes <= 0 {
+		batchBytes = defaultBatchBytes
+	}
+	var result [][]serializer.SerializedMessage
+	start := 0
+	size := int64(batchOverhead)
+	for i, msg := range messages {
+		msgSize := int64(len(msg.Key) + len(msg.Value) + recordOverhead)
+		if i > start && size+msgSize > batchBytes {
+			result = append(result, messages[start:i])
+			start = i
+			size = batchOverhead
+		}
+		size += msgSize
+	}
+	if start < len(messages) {
+		result = append(result, messages[start:])
+	}
+	return result
+}
+
+// encodeTransactionalBatch encodes messages as the transactional record batch of the producer
+func encodeTransactionalBatch(messages []serializer.SerializedMessage, compression kafka.Compression, producer *kafka.ProducerSession, sequence int32) (protocol.RawRecordSet, error) {
+	now := time.Now()
+	records := make([]protocol.Record, 0, len(messages))
+	for _, msg := range messages {
+		records = append(records, protocol.Record{
+			Offset:  0,
+			Time:    now,
+			Key:     protocol.NewBytes(msg.Key),
+			Value:   protocol.NewBytes(msg.Value),
+			Headers: nil,
+		})
+	}
+	recordSet := protocol.RecordSet{
+		Version:    2,
+		Attributes: protocol.Attributes(compression) | protocol.Transactional,
+		Records:    protocol.NewRecordReader(records...),
+	}
+	buf := new(bytes.Buffer)
+	if _, err := recordSet.WriteTo(buf); err != nil {
+		return protocol.RawRecordSet{Reader: nil}, xerrors.Errorf("unable to write record set: %w", err)
+	}
+	batch := buf.Bytes()
+	if len(batch) < batchSequenceOffset+4 {
+		return protocol.RawRecordSet{Reader: nil}, xerrors.Errorf("unexpected size of record batch: %d", len(batch))
+	}
+	binary.BigEndian.PutUint64(batch[batchProducerIDOffset:], uint64(producer.ProducerID))
+	binary.BigEndian.PutUint16(batch[batchEpochOffset:], uint16(producer.ProducerEpoch))
+	binary.BigEndian.PutUint32(batch[batchSequenceOffset:], uint32(sequence))
+	binary.BigEndian.PutUint32(batch[batchCRCOffset:], crc32.Checksum(batch[batchAttributesOffset:], castagnoli))
+	return protocol.RawRecordSet{Reader: bytes.NewReader(batch)}, nil
+}
+
+func NewTransactionalWriter(brokers []string, compression kafka.Compression, saslMechanism sasl.Mechanism, tlsConfig *tls.Config, topicConfig [][2]string, batchBytes int64, dial func(ctx context.Context, network string, address string) (net.Conn, error), transactionalID string, lgr log.Logger) *TransactionalWriter {
+	return &TransactionalWriter{
+		client: &kafka.Client{
+			Addr:    kafka.TCP(brokers...),
+			Timeout: requestTimeout,
+			Transport: &kafka.Transport{
+				Dial: dial,
+				TLS:  tlsConfig,
+				SASL: saslMechanism,
+			},
+			MaxMessageBytes: 0,
+		},
+		logger:          lgr,
+		topics:          newTopicsRegistry(brokers, saslMechanism, tlsConfig, topicConfig, dial),
+		transactionalID: transactionalID,
+		compression:     compression,
+		batchBytes:      batchBytes,
+		balancer:        &kafka.Hash{},
+
+		mu:             sync.Mutex{},
+		producer:       nil,
+		sequences:      map[topicPartition]int32{},
+		txnPartitions:  map[topicPartition]bool{},
+		txnOffsets:     false,
+		partitionLocks: map[topicPartition]*sync.Mutex{},
+		topicsMeta:     map[string][]int{},
+	}
+}
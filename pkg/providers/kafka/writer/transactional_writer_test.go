@@ -0,0 +1,53 @@
+package writer
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	serializer "github.com/transferia/transferia/pkg/serializer/queue"
+)
+
+func TestSplitBatches(t *testing.T) {
+	msg := serializer.SerializedMessage{Key: []byte("k"), Value: []byte(strings.Repeat("v", 100))}
+	messages := []serializer.SerializedMessage{msg, msg, msg, msg, msg}
+	msgSize := len(msg.Key) + len(msg.Value) + recordOverhead
+
+	batches := splitBatches(messages, int64(batchOverhead+2*msgSize))
+	require.Len(t, batches, 3)
+	require.Len(t, batches[0], 2)
+	require.Len(t, batches[1], 2)
+	require.Len(t, batches[2], 1)
+
+	// a message larger than the limit goes alone, the broker decides whether it fits
+	require.Len(t, splitBatches(messages, 1), 5)
+	require.Len(t, splitBatches(messages, 0), 1)
+	require.Empty(t, splitBatches(nil, 0))
+}
+
+func TestEncodeTransactionalBatch(t *testing.T) {
+	producer := &kafka.ProducerSession{ProducerID: 42, ProducerEpoch: 7}
+	messages := []serializer.SerializedMessage{
+		{Key: []byte("k1"), Value: []byte("v1")},
+		{Key: []byte("k2"), Value: []byte("v2")},
+	}
+	for _, compression := range []kafka.Compression{0, kafka.Gzip} {
+		records, err := encodeTransactionalBatch(messages, compression, producer, 100)
+		require.NoError(t, err)
+		batch, err := io.ReadAll(records.Reader)
+		require.NoError(t, err)
+
+		require.Equal(t, uint32(len(batch)-4), binary.BigEndian.Uint32(batch))
+		attributes := binary.BigEndian.Uint16(batch[batchAttributesOffset:])
+		require.NotZero(t, attributes&(1<<4), "transactional bit")
+		require.Equal(t, uint16(compression), attributes&7)
+		require.Equal(t, uint64(42), binary.BigEndian.Uint64(batch[batchProducerIDOffset:]))
+		require.Equal(t, uint16(7), binary.BigEndian.Uint16(batch[batchEpochOffset:]))
+		require.Equal(t, uint32(100), binary.BigEndian.Uint32(batch[batchSequenceOffset:]))
+		require.Equal(t, crc32.Checksum(batch[batchAttributesOffset:], castagnoli), binary.BigEndian.Uint32(batch[batchCRCOffset:]))
+	}
+}
@@ -23,3 +23,20 @@ func NewWriterFactory(lgr log.Logger) *WriterFactory {
 func (c *WriterFactory) BuildWriter(brokers []string, compression kafka.Compression, saslMechanism sasl.Mechanism, tlsConfig *tls.Config, topicConfig [][2]string, batchBytes int64, dial func(ctx context.Context, network string, address string) (net.Conn, error)) AbstractWriter {
 	return NewWriter(brokers, compression, saslMechanism, tlsConfig, topicConfig, batchBytes, dial)
 }
+
+// TransactionalWriterFactory builds writers of the transactional producer with the given transactional ID
+type TransactionalWriterFactory struct {
+	lgr             log.Logger
+	transactionalID string
+}
+
+func NewTransactionalWriterFactory(lgr log.Logger, transactionalID string) *TransactionalWriterFactory {
+	return &TransactionalWriterFactory{
+		lgr:             lgr,
+		transactionalID: transactionalID,
+	}
+}
+
+func (c *TransactionalWriterFactory) BuildWriter(brokers []string, compression kafka.Compression, saslMechanism sasl.Mechanism, tlsConfig *tls.Config, topicConfig [][2]string, batchBytes int64, dial func(ctx context.Context, network string, address string) (net.Conn, error)) AbstractWriter {
+	return NewTransactionalWriter(brokers, compression, saslMechanism, tlsConfig, topicConfig, batchBytes, dial, c.transactionalID, c.lgr)
+}
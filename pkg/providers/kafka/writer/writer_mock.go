@@ -108,3 +108,125 @@ func (mr *MockAbstractWriterFactoryMockRecorder) BuildWriter(brokers, compressio
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BuildWriter", reflect.TypeOf((*MockAbstractWriterFactory)(nil).BuildWriter), brokers, compression, saslMechanism, tlsConfig, topicConfig, batchBytes, dial)
 }
+
+// MockAbstractTransactionalWriter is a mock of AbstractTransactionalWriter interface.
+type MockAbstractTransactionalWriter struct {
+	ctrl     *gomock.Controller
+	recorder *MockAbstractTransactionalWriterMockRecorder
+}
+
+// MockAbstractTransactionalWriterMockRecorder is the mock recorder for MockAbstractTransactionalWriter.
+type MockAbstractTransactionalWriterMockRecorder struct {
+	mock *MockAbstractTransactionalWriter
+}
+
+// NewMockAbstractTransactionalWriter creates a new mock instance.
+func NewMockAbstractTransactionalWriter(ctrl *gomock.Controller) *MockAbstractTransactionalWriter {
+	mock := &MockAbstractTransactionalWriter{ctrl: ctrl}
+	mock.recorder = &MockAbstractTransactionalWriterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAbstractTransactionalWriter) EXPECT() *MockAbstractTransactionalWriterMockRecorder {
+	return m.recorder
+}
+
+// AbortTxn mocks base method.
+func (m *MockAbstractTransactionalWriter) AbortTxn(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AbortTxn", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AbortTxn indicates an expected call of AbortTxn.
+func (mr *MockAbstractTransactionalWriterMockRecorder) AbortTxn(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AbortTxn", reflect.TypeOf((*MockAbstractTransactionalWriter)(nil).AbortTxn), ctx)
+}
+
+// BeginTxn mocks base method.
+func (m *MockAbstractTransactionalWriter) BeginTxn(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginTxn", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BeginTxn indicates an expected call of BeginTxn.
+func (mr *MockAbstractTransactionalWriterMockRecorder) BeginTxn(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginTxn", reflect.TypeOf((*MockAbstractTransactionalWriter)(nil).BeginTxn), ctx)
+}
+
+// Close mocks base method.
+func (m *MockAbstractTransactionalWriter) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockAbstractTransactionalWriterMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockAbstractTransactionalWriter)(nil).Close))
+}
+
+// CommitTxn mocks base method.
+func (m *MockAbstractTransactionalWriter) CommitTxn(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CommitTxn", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CommitTxn indicates an expected call of CommitTxn.
+func (mr *MockAbstractTransactionalWriterMockRecorder) CommitTxn(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitTxn", reflect.TypeOf((*MockAbstractTransactionalWriter)(nil).CommitTxn), ctx)
+}
+
+// FetchOffsets mocks base method.
+func (m *MockAbstractTransactionalWriter) FetchOffsets(ctx context.Context, groupID, topic string, partitions []int) (map[int]kafka.OffsetFetchPartition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchOffsets", ctx, groupID, topic, partitions)
+	ret0, _ := ret[0].(map[int]kafka.OffsetFetchPartition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchOffsets indicates an expected call of FetchOffsets.
+func (mr *MockAbstractTransactionalWriterMockRecorder) FetchOffsets(ctx, groupID, topic, partitions any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchOffsets", reflect.TypeOf((*MockAbstractTransactionalWriter)(nil).FetchOffsets), ctx, groupID, topic, partitions)
+}
+
+// SendOffsets mocks base method.
+func (m *MockAbstractTransactionalWriter) SendOffsets(ctx context.Context, groupID string, offsets map[string][]kafka.TxnOffsetCommit) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendOffsets", ctx, groupID, offsets)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendOffsets indicates an expected call of SendOffsets.
+func (mr *MockAbstractTransactionalWriterMockRecorder) SendOffsets(ctx, groupID, offsets any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendOffsets", reflect.TypeOf((*MockAbstractTransactionalWriter)(nil).SendOffsets), ctx, groupID, offsets)
+}
+
+// WriteMessages mocks base method.
+func (m *MockAbstractTransactionalWriter) WriteMessages(ctx context.Context, lgr log.Logger, topicName string, currMessages []queue.SerializedMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteMessages", ctx, lgr, topicName, currMessages)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteMessages indicates an expected call of WriteMessages.
+func (mr *MockAbstractTransactionalWriterMockRecorder) WriteMessages(ctx, lgr, topicName, currMessages any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteMessages", reflect.TypeOf((*MockAbstractTransactionalWriter)(nil).WriteMessages), ctx, lgr, topicName, currMessages)
+}
@@ -23,6 +23,11 @@ func isUnsigned(rawColumnType string) bool {
 	return strings.Contains(rawColumnType, " unsigned")
 }
 
+// Represent formats the value as a MySQL literal, DBLog snapshot uses it for chunk keys and low bounds of chunks
+func Represent(val interface{}, colSchema abstract.ColSchema) (string, error) {
+	return CastToMySQL(val, colSchema), nil
+}
+
 func CastToMySQL(val interface{}, typ abstract.ColSchema) string {
 	if typ.Expression != "" {
 		return "default"
@@ -0,0 +1,233 @@
+package dblog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/dblog"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const (
+	SignalTableName = "__data_transfer_signal_table"
+
+	tableSchemaColumn = "table_schema"
+	tableNameColumn   = "table_name"
+	transferIDColumn  = "transfer_id"
+	markColumn        = "mark"
+	markTypeColumn    = "mark_type"
+)
+
+func SignalTableTableID(database string) *abstract.TableID {
+	return abstract.NewTableID(database, SignalTableName)
+}
+
+type signalTable struct {
+	db         *sql.DB
+	logger     log.Logger
+	transferID string
+	database   string
+}
+
+func buildSignalTableDDL(database string) string {
+	query := "CREATE TABLE IF NOT EXISTS `%s`.`%s`" + `
+			  (
+				  table_schema VARCHAR(64) NOT NULL,
+				  table_name VARCHAR(64) NOT NULL,
+				  transfer_id VARCHAR(255) NOT NULL,
+				  mark CHAR(36),
+				  mark_type CHAR(1) NOT NULL,
+				  low_bound TEXT,
+				  PRIMARY KEY (table_schema, table_name, transfer_id, mark_type)
+			  );`
+
+	return fmt.Sprintf(query, database, SignalTableName)
+}
+
+func NewMySQLSignalTable(
+	ctx context.Context,
+	db *sql.DB,
+	logger log.Logger,
+	transferID string,
+	database string,
+) (*signalTable, error) {
+	mysqlSignalTable := &signalTable{
+		db:         db,
+		logger:     logger,
+		transferID: transferID,
+		database:   database,
+	}
+
+	if _, err := db.ExecContext(ctx, buildSignalTableDDL(database)); err != nil {
+		return nil, xerrors.Errorf("failed to ensure existence of the signal table service table: %w", err)
+	}
+
+	return mysqlSignalTable, nil
+}
+
+func (s *signalTable) CreateWatermark(
+	ctx context.Context,
+	tableID abstract.TableID,
+	watermarkType dblog.WatermarkType,
+	lowBoundArr []string,
+) (uuid.UUID, error) {
+	newUUID := uuid.New()
+
+	lowBoundStr, err := dblog.ConvertArrayToString(lowBoundArr)
+	if err != nil {
+		return newUUID, xerrors.Errorf("unable to convert low bound array to string")
+	}
+
+	query := s.makeWatermarkQuery()
+	s.logger.Info(
+		fmt.Sprintf("CreateWatermark - query: %s", strings.ReplaceAll(query, "\n", "")),
+		log.String("tableID.Namespace", tableID.Namespace),
+		log.String("tableID.Name", tableID.Name),
+		log.String("s.transferID", s.transferID),
+		log.String("newUUID", newUUID.String()),
+		log.String("watermarkType", string(watermarkType)),
+		log.String("lowBoundStr", lowBoundStr),
+	)
+
+	// a single statement is committed at once in autocommit mode, so it's written to the binlog as its own transaction
+	if _, err := s.db.ExecContext(
+		ctx,
+		query,
+		tableID.Namespace,
+		tableID.Name,
+		s.transferID,
+		newUUID.String(),
+		string(watermarkType),
+		lowBoundStr,
+	); err != nil {
+		return newUUID, xerrors.Errorf("failed to create watermark for %s: %w", tableID.Fqtn(), err)
+	}
+
+	return newUUID, nil
+}
+
+// IsWatermark reads values by column names, since row images of the binlog do not have to contain all columns
+func (s *signalTable) IsWatermark(item *abstract.ChangeItem, tableID abstract.TableID, markUUID uuid.UUID) (bool, dblog.WatermarkType) {
+	isWatermark := item.Table == SignalTableName && item.Schema == s.database
+	if !isWatermark {
+		return false, dblog.BadWatermarkType
+	}
+
+	if item.Kind == abstract.DeleteKind ||
+		columnString(item, tableSchemaColumn) != tableID.Namespace ||
+		columnString(item, tableNameColumn) != tableID.Name ||
+		columnString(item, transferIDColumn) != s.transferID {
+		return false, dblog.BadWatermarkType
+	}
+
+	parsedUUID, err := uuid.Parse(columnString(item, markColumn))
+	if err != nil {
+		return true, dblog.BadWatermarkType
+	}
+
+	if parsedUUID != markUUID {
+		return true, dblog.BadWatermarkType
+	}
+
+	strVal := columnString(item, markTypeColumn)
+	if len(strVal) != 1 {
+		return false, dblog.BadWatermarkType
+	}
+
+	return true, dblog.WatermarkType(strVal)
+}
+
+// columnString returns the value of the text column, text values come either as strings or as bytes depending on the type system
+func columnString(item *abstract.ChangeItem, column string) string {
+	idx := item.ColumnNameIndex(column)
+	if idx < 0 || idx >= len(item.ColumnValues) {
+		return ""
+	}
+	switch v := item.ColumnValues[idx].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return ""
+	}
+}
+
+func (s *signalTable) makeWatermarkQuery() string {
+	query := "INSERT INTO `%s`.`%s` (table_schema, table_name, transfer_id, mark, mark_type, low_bound)" + `
+			  VALUES (?, ?, ?, ?, ?, ?)
+			  ON DUPLICATE KEY UPDATE
+				  mark = VALUES(mark),
+				  low_bound = VALUES(low_bound);`
+
+	return fmt.Sprintf(query, s.database, SignalTableName)
+}
+
+func (s *signalTable) resolveLowBound(ctx context.Context, tableID abstract.TableID) []string {
+	var lowBoundStr string
+
+	err := s.db.QueryRowContext(ctx, s.resolveLowBoundQuery(), tableID.Namespace, tableID.Name, s.transferID, dblog.SuccessWatermarkType).Scan(&lowBoundStr)
+	if err != nil {
+		if !xerrors.Is(err, sql.ErrNoRows) {
+			s.logger.Warn("failed to retrieve low_bound", log.String("table", tableID.Fqtn()), log.Error(err))
+		}
+		return nil
+	}
+
+	lowBoundArray, err := dblog.ConvertStringToArray(lowBoundStr)
+	if err != nil {
+		return nil
+	}
+
+	return lowBoundArray
+}
+
+func (s *signalTable) resolveLowBoundQuery() string {
+	query := "SELECT low_bound FROM `%s`.`%s`" + `
+              WHERE table_schema = ?
+              	AND table_name = ?
+                AND transfer_id = ?
+              	AND mark_type = ?;`
+
+	return fmt.Sprintf(query, s.database, SignalTableName)
+}
+
+func DeleteWatermarks(ctx context.Context, db *sql.DB, database string, transferID string) error {
+	signalTableExist, err := signalTableExist(ctx, db, database)
+	if err != nil {
+		return xerrors.Errorf("signal table check query failed err: %w", err)
+	}
+	if signalTableExist {
+		if _, err := db.ExecContext(ctx, deleteWatermarksQuery(database), transferID); err != nil {
+			return xerrors.Errorf("failed to delete watermarks err: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func deleteWatermarksQuery(database string) string {
+	query := "DELETE FROM `%s`.`%s` WHERE transfer_id = ?;"
+	return fmt.Sprintf(query, database, SignalTableName)
+}
+
+func signalTableExist(ctx context.Context, db *sql.DB, database string) (bool, error) {
+	query := `SELECT EXISTS (
+		SELECT 1
+		FROM information_schema.tables
+		WHERE table_schema = ?
+		AND table_name = ?
+	   );`
+
+	var exist bool
+	if err := db.QueryRowContext(ctx, query, database, SignalTableName).Scan(&exist); err != nil {
+		return false, err
+	}
+
+	return exist, nil
+}
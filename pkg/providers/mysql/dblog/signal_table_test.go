@@ -0,0 +1,89 @@
+package dblog
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/dblog"
+)
+
+func makeWatermarkItem(columnNames []string, columnValues []any) *abstract.ChangeItem {
+	return &abstract.ChangeItem{
+		Kind:         abstract.UpdateKind,
+		Schema:       "db",
+		Table:        SignalTableName,
+		ColumnNames:  columnNames,
+		ColumnValues: columnValues,
+	}
+}
+
+func TestIsWatermark(t *testing.T) {
+	table := &signalTable{transferID: "transfer", database: "db"}
+	tableID := *abstract.NewTableID("db", "t")
+	mark := uuid.New()
+	columnNames := []string{tableSchemaColumn, tableNameColumn, transferIDColumn, markColumn, markTypeColumn, "low_bound"}
+
+	t.Run("strings", func(t *testing.T) {
+		item := makeWatermarkItem(columnNames, []any{"db", "t", "transfer", mark.String(), "H", `["1"]`})
+		isWatermark, watermarkType := table.IsWatermark(item, tableID, mark)
+		require.True(t, isWatermark)
+		require.Equal(t, dblog.WatermarkType(dblog.HighWatermarkType), watermarkType)
+	})
+
+	t.Run("bytes in another column order", func(t *testing.T) {
+		item := makeWatermarkItem(
+			[]string{markTypeColumn, markColumn, transferIDColumn, tableNameColumn, tableSchemaColumn},
+			[]any{[]byte("L"), []byte(mark.String()), []byte("transfer"), []byte("t"), []byte("db")},
+		)
+		isWatermark, watermarkType := table.IsWatermark(item, tableID, mark)
+		require.True(t, isWatermark)
+		require.Equal(t, dblog.WatermarkType(dblog.LowWatermarkType), watermarkType)
+	})
+
+	t.Run("other mark", func(t *testing.T) {
+		item := makeWatermarkItem(columnNames, []any{"db", "t", "transfer", uuid.New().String(), "H", `["1"]`})
+		isWatermark, watermarkType := table.IsWatermark(item, tableID, mark)
+		require.True(t, isWatermark)
+		require.Equal(t, dblog.WatermarkType(dblog.BadWatermarkType), watermarkType)
+	})
+
+	t.Run("other transfer", func(t *testing.T) {
+		item := makeWatermarkItem(columnNames, []any{"db", "t", "other", mark.String(), "H", `["1"]`})
+		isWatermark, _ := table.IsWatermark(item, tableID, mark)
+		require.False(t, isWatermark)
+	})
+
+	t.Run("other table", func(t *testing.T) {
+		item := makeWatermarkItem(columnNames, []any{"db", "t2", "transfer", mark.String(), "H", `["1"]`})
+		isWatermark, _ := table.IsWatermark(item, tableID, mark)
+		require.False(t, isWatermark)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		item := makeWatermarkItem(columnNames, []any{"db", "t", "transfer", mark.String(), "H", `["1"]`})
+		item.Kind = abstract.DeleteKind
+		isWatermark, _ := table.IsWatermark(item, tableID, mark)
+		require.False(t, isWatermark)
+	})
+
+	t.Run("user table", func(t *testing.T) {
+		item := makeWatermarkItem(columnNames, []any{"db", "t", "transfer", mark.String(), "H", `["1"]`})
+		item.Table = "t"
+		isWatermark, _ := table.IsWatermark(item, tableID, mark)
+		require.False(t, isWatermark)
+	})
+}
+
+func TestCheckTypeCompatibility(t *testing.T) {
+	require.Equal(t, dblog.TypeSupported, CheckTypeCompatibility("mysql:int(11)"))
+	require.Equal(t, dblog.TypeSupported, CheckTypeCompatibility("mysql:bigint(20) unsigned zerofill"))
+	require.Equal(t, dblog.TypeSupported, CheckTypeCompatibility("mysql:varchar(255)"))
+	require.Equal(t, dblog.TypeSupported, CheckTypeCompatibility("mysql:DATETIME(6)"))
+	require.Equal(t, dblog.TypeSupported, CheckTypeCompatibility("mysql:decimal(10,2)"))
+	require.Equal(t, dblog.TypeUnsupported, CheckTypeCompatibility("mysql:enum('a','b')"))
+	require.Equal(t, dblog.TypeUnsupported, CheckTypeCompatibility("mysql:json"))
+	require.Equal(t, dblog.TypeUnknown, CheckTypeCompatibility("mysql:vector(3)"))
+	require.Equal(t, dblog.TypeUnknown, CheckTypeCompatibility(""))
+}
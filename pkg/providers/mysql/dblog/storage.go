@@ -0,0 +1,156 @@
+package dblog
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/dblog"
+	"github.com/transferia/transferia/pkg/dblog/tablequery"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+type Storage struct {
+	logger log.Logger
+
+	src          abstract.Source
+	mysqlStorage tablequery.StorageTableQueryable
+	db           *sql.DB
+
+	chunkSize uint64
+
+	transferID       string
+	represent        dblog.ChangeItemConverter
+	database         string
+	betweenMarksOpts []func()
+}
+
+func NewStorage(
+	logger log.Logger,
+	src abstract.Source,
+	mysqlStorage tablequery.StorageTableQueryable,
+	db *sql.DB,
+	chunkSize uint64,
+	transferID string,
+	database string,
+	represent dblog.ChangeItemConverter,
+	betweenMarksOpts ...func(),
+) (abstract.Storage, error) {
+	return &Storage{
+		logger:           log.With(logger, log.Any("component", "dblog")),
+		src:              src,
+		mysqlStorage:     mysqlStorage,
+		db:               db,
+		chunkSize:        chunkSize,
+		transferID:       transferID,
+		database:         database,
+		represent:        represent,
+		betweenMarksOpts: betweenMarksOpts,
+	}, nil
+}
+
+func (s *Storage) Close() {
+	s.mysqlStorage.Close()
+}
+
+func (s *Storage) Ping() error {
+	return s.mysqlStorage.Ping()
+}
+
+func (s *Storage) LoadTable(ctx context.Context, tableDescr abstract.TableDescription, pusher abstract.Pusher) error {
+	pkColNames, err := dblog.ResolvePrimaryKeyColumns(ctx, s.mysqlStorage, tableDescr.ID(), CheckTypeCompatibility)
+	if err != nil {
+		return xerrors.Errorf("unable to get primary key: %w", err)
+	}
+
+	chunkSize := s.chunkSize
+
+	if chunkSize == 0 {
+		chunkSize, err = dblog.InferChunkSize(s.mysqlStorage, tableDescr.ID(), dblog.DefaultChunkSizeInBytes)
+		if err != nil {
+			return xerrors.Errorf("unable to generate chunk size: %w", err)
+		}
+		s.logger.Infof("Storage.LoadTable - inferred chunkSize: %d", chunkSize)
+	} else {
+		s.logger.Infof("Storage.LoadTable - from config chunkSize: %d", chunkSize)
+	}
+
+	mysqlSignalTable, err := NewMySQLSignalTable(ctx, s.db, s.logger, s.transferID, s.database)
+	if err != nil {
+		return xerrors.Errorf("unable to create signal table: %w", err)
+	}
+
+	tableQuery := tablequery.NewTableQuery(tableDescr.ID(), true, "", 0, chunkSize)
+	s.logger.Infof("Storage.LoadTable - tableQuery: %v", tableQuery)
+	lowBound := mysqlSignalTable.resolveLowBound(ctx, tableDescr.ID())
+	s.logger.Infof("Storage.LoadTable - lowBound: %v", lowBound)
+
+	iterator, err := dblog.NewIncrementalIterator(
+		s.logger,
+		s.mysqlStorage,
+		tableQuery,
+		mysqlSignalTable,
+		s.represent,
+		pkColNames,
+		lowBound,
+		chunkSize,
+		s.betweenMarksOpts...,
+	)
+	if err != nil {
+		return xerrors.Errorf("unable to build iterator, err: %w", err)
+	}
+
+	items, err := iterator.Next(ctx)
+	if err != nil {
+		return xerrors.Errorf("failed to do initial iteration: %w", err)
+	}
+
+	s.logger.Infof("Storage.LoadTable - first iteration done, extacted items: %d", len(items))
+
+	chunk, err := dblog.ResolveChunkMapFromArr(items, pkColNames, s.represent)
+	if err != nil {
+		return xerrors.Errorf("failed to resolve chunk: %w", err)
+	}
+
+	asyncSink := dblog.NewIncrementalAsyncSink(
+		ctx,
+		s.logger,
+		mysqlSignalTable,
+		tableDescr.ID(),
+		iterator,
+		pkColNames,
+		chunk,
+		s.represent,
+		func() { s.src.Stop() },
+		pusher,
+	)
+
+	err = s.src.Run(asyncSink)
+	if err != nil {
+		s.src.Stop()
+		return xerrors.Errorf("unable to run worker: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) TableSchema(ctx context.Context, table abstract.TableID) (*abstract.TableSchema, error) {
+	return s.mysqlStorage.TableSchema(ctx, table)
+}
+
+func (s *Storage) TableList(filter abstract.IncludeTableList) (abstract.TableMap, error) {
+	return s.mysqlStorage.TableList(filter)
+}
+
+func (s *Storage) ExactTableRowsCount(table abstract.TableID) (uint64, error) {
+	return s.mysqlStorage.ExactTableRowsCount(table)
+}
+
+func (s *Storage) EstimateTableRowsCount(table abstract.TableID) (uint64, error) {
+	return s.mysqlStorage.EstimateTableRowsCount(table)
+}
+
+func (s *Storage) TableExists(table abstract.TableID) (bool, error) {
+	return s.mysqlStorage.TableExists(table)
+}
@@ -0,0 +1,77 @@
+package dblog
+
+import (
+	"strings"
+
+	"github.com/transferia/transferia/pkg/dblog"
+	"github.com/transferia/transferia/pkg/util/set"
+)
+
+var supportedTypesArr = []string{
+	"tinyint",
+	"smallint",
+	"mediumint",
+	"int",
+	"integer",
+	"bigint",
+	"bit",
+	"bool",
+	"boolean",
+
+	"decimal",
+	"numeric",
+	"float",
+	"double",
+	"real",
+
+	"date",
+	"datetime",
+	"timestamp",
+	"time",
+	"year",
+
+	"char",
+	"varchar",
+	"binary",
+	"varbinary",
+	"tinytext",
+	"text",
+	"mediumtext",
+	"longtext",
+	"tinyblob",
+	"blob",
+	"mediumblob",
+	"longblob",
+}
+
+var unsupportedTypesArr = []string{
+	"json",               // cannot be a part of a primary key
+	"enum",               // compared by index, while the literal is compared as a string
+	"set",                // compared by bitmask, while the literal is compared as a string
+	"geometry",           // cannot be a part of a primary key
+	"point",              // cannot be a part of a primary key
+	"linestring",         // cannot be a part of a primary key
+	"polygon",            // cannot be a part of a primary key
+	"multipoint",         // cannot be a part of a primary key
+	"multilinestring",    // cannot be a part of a primary key
+	"multipolygon",       // cannot be a part of a primary key
+	"geometrycollection", // cannot be a part of a primary key
+}
+
+var supportedTypesSet = set.New(supportedTypesArr...)
+var unsupportedTypesSet = set.New(unsupportedTypesArr...)
+
+func CheckTypeCompatibility(keyType string) dblog.TypeSupport {
+	normalKeyType := strings.ToLower(strings.TrimPrefix(keyType, "mysql:"))
+	normalKeyType = strings.Split(normalKeyType, "(")[0]
+	normalKeyType = strings.Split(normalKeyType, " ")[0] // drops attributes like "unsigned" and "zerofill"
+
+	switch {
+	case supportedTypesSet.Contains(normalKeyType):
+		return dblog.TypeSupported
+	case unsupportedTypesSet.Contains(normalKeyType):
+		return dblog.TypeUnsupported
+	default:
+		return dblog.TypeUnknown
+	}
+}
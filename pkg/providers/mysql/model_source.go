@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/abstract"
 	"github.com/transferia/transferia/pkg/abstract/model"
 )
@@ -56,6 +57,9 @@ type MysqlSource struct {
 	ConnectionID string
 
 	ReplicationFlushInterval time.Duration
+
+	DBLogEnabled bool   // force DBLog snapshot instead of common
+	ChunkSize    uint64 // number of rows in chunk, this field needed for DBLog snapshot, if it is 0, it will be calculated automatically
 }
 
 var _ model.Source = (*MysqlSource)(nil)
@@ -184,9 +188,20 @@ func (s *MysqlSource) GetProviderType() abstract.ProviderType {
 }
 
 func (s *MysqlSource) Validate() error {
+	if s.DBLogEnabled && s.SignalTableDatabase() == "" {
+		return xerrors.New("DBLog snapshot requires either Database or TrackerDatabase to be set, the signal table is created there")
+	}
 	return nil
 }
 
+// SignalTableDatabase is the database of the DBLog signal table, it's stored next to the binlog tracker tables
+func (s *MysqlSource) SignalTableDatabase() string {
+	if s.TrackerDatabase != "" {
+		return s.TrackerDatabase
+	}
+	return s.Database
+}
+
 func (s *MysqlSource) ToStorageParams() *MysqlStorageParams {
 	return &MysqlStorageParams{
 		ClusterID:   s.ClusterID,
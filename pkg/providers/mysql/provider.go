@@ -2,8 +2,10 @@ package mysql
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/cenkalti/backoff/v4"
+	"github.com/transferia/transferia/internal/logger"
 	"github.com/transferia/transferia/library/go/core/metrics"
 	"github.com/transferia/transferia/library/go/core/metrics/solomon"
 	"github.com/transferia/transferia/library/go/core/xerrors"
@@ -13,6 +15,9 @@ import (
 	debeziumparameters "github.com/transferia/transferia/pkg/debezium/parameters"
 	"github.com/transferia/transferia/pkg/middlewares"
 	"github.com/transferia/transferia/pkg/providers"
+	"github.com/transferia/transferia/pkg/providers/mysql/dblog"
+	abstract_sink "github.com/transferia/transferia/pkg/sink"
+	"github.com/transferia/transferia/pkg/util"
 	"github.com/transferia/transferia/pkg/util/gobwrapper"
 	"go.ytsaurus.tech/library/go/core/log"
 )
@@ -42,8 +47,12 @@ func init() {
 		"__tm_gtid_keeper":
 			{server_id VARCHAR(100), host VARCHAR(100), gtid VARCHAR(1000), flavor VARCHAR(100)}
 			Table for saving Global Transaction IDs.
+
+		"__data_transfer_signal_table":
+			{table_schema VARCHAR(64), table_name VARCHAR(64), transfer_id VARCHAR(255), mark CHAR(36), mark_type CHAR(1), low_bound TEXT}
+			Table for watermarks of DBLog snapshot, they delimit chunks of tables in the binlog.
 	*/
-	abstract.RegisterSystemTables(TableTransferProgress, TableTmGtidKeeper, TableTmKeeper)
+	abstract.RegisterSystemTables(TableTransferProgress, TableTmGtidKeeper, TableTmKeeper, dblog.SignalTableName)
 }
 
 const (
@@ -54,7 +63,7 @@ const (
 
 func isSystemTable(tableName string) bool {
 	switch tableName {
-	case TableTransferProgress, TableTmGtidKeeper, TableTmKeeper:
+	case TableTransferProgress, TableTmGtidKeeper, TableTmKeeper, dblog.SignalTableName:
 		return true
 	}
 	return false
@@ -209,7 +218,11 @@ func (p *Provider) Activate(ctx context.Context, task *model.TransferOperation,
 		// mysql treat views as metadata, so no need to transfer them separately
 		model.ExcludeViews(tables)
 	}
-	if !p.transfer.SnapshotOnly() {
+	if src.DBLogEnabled && !p.transfer.IncrementOnly() { // if there are present SNAPSHOT stage with turned-on DBLog
+		if err := p.DBLogInit(ctx, src); err != nil {
+			return xerrors.Errorf("unable to init dblog, err: %w", err)
+		}
+	} else if !p.transfer.SnapshotOnly() {
 		if src.IsHomo {
 			if err := CheckMySQLBinlogRowImageFormat(src); err != nil {
 				return err
@@ -232,8 +245,21 @@ func (p *Provider) Activate(ctx context.Context, task *model.TransferOperation,
 		if err := callbacks.CheckIncludes(tables); err != nil {
 			return xerrors.Errorf("Failed in accordance with configuration: %w", err)
 		}
-		if err := callbacks.Upload(tables); err != nil {
-			return xerrors.Errorf("Snapshot loading failed: %w", err)
+		if src.DBLogEnabled {
+			logger.Log.Info("DBLog enabled")
+			if err := p.DBLogUpload(ctx, tables); err != nil {
+				return xerrors.Errorf("DBLog snapshot loading failed: %w", err)
+			}
+			if p.transfer.SnapshotOnly() {
+				// binlog was read only to deduplicate chunks, the next activation must start from a new position
+				if err := RemoveTracker(src, p.transfer.ID, p.cp); err != nil {
+					return xerrors.Errorf("Unable to remove tracker: %w", err)
+				}
+			}
+		} else {
+			if err := callbacks.Upload(tables); err != nil {
+				return xerrors.Errorf("Snapshot loading failed: %w", err)
+			}
 		}
 		if p.transfer.SnapshotOnly() {
 			if err := LoadMysqlSchema(p.transfer, registry, true); err != nil {
@@ -268,7 +294,15 @@ func (p *Provider) Cleanup(ctx context.Context, task *model.TransferOperation) e
 	if !ok {
 		return xerrors.Errorf("unexpected source type: %T", p.transfer.Src)
 	}
-	return RemoveTracker(src, p.transfer.ID, p.cp)
+	if err := RemoveTracker(src, p.transfer.ID, p.cp); err != nil {
+		return xerrors.Errorf("Unable to remove tracker: %w", err)
+	}
+	if src.DBLogEnabled {
+		if err := p.DBLogCleanup(ctx, src); err != nil {
+			return xerrors.Errorf("unable to cleanup dblog resources: %w", err)
+		}
+	}
+	return nil
 }
 
 func (p *Provider) Update(ctx context.Context, addedTables []abstract.TableDescription) error {
@@ -279,6 +313,121 @@ func (p *Provider) Type() abstract.ProviderType {
 	return ProviderType
 }
 
+// DBLogInit prepares the binlog position and the signal table for DBLog snapshot.
+// Chunks are deduplicated against the binlog, so the binlog must be read from the position taken before the snapshot
+// and row images must contain all columns even for heterogeneous transfers.
+func (p *Provider) DBLogInit(ctx context.Context, src *MysqlSource) error {
+	if err := CheckMySQLBinlogRowImageFormat(src); err != nil {
+		return err
+	}
+	src.InitServerID(p.transfer.ID)
+
+	storage, err := NewStorage(src.ToStorageParams())
+	if err != nil {
+		return xerrors.Errorf("failed to create mysql storage: %w", err)
+	}
+	defer storage.Close()
+
+	// ensure SignalTable exists
+	if _, err := dblog.NewMySQLSignalTable(ctx, storage.DB, logger.Log, p.transfer.ID, src.SignalTableDatabase()); err != nil {
+		return xerrors.Errorf("unable to create signal table: %w", err)
+	}
+
+	tracker, err := NewTracker(src, p.transfer.ID, p.cp)
+	if err != nil {
+		return xerrors.Errorf("failed to create MySQL binlog tracker: %w", err)
+	}
+	positionExists, err := tracker.Exists()
+	if err != nil {
+		return xerrors.Errorf("failed to check binlog position: %w", err)
+	}
+	if positionExists {
+		// binlog position is stored - it's just dataplane restart, snapshot continues from the last success watermark
+		return nil
+	}
+	if err := SyncBinlogPosition(src, p.transfer.ID, p.cp); err != nil {
+		return xerrors.Errorf("Cannot synchronize binlog position: %w", err)
+	}
+	// delete previous watermarks - only if binlog position previously not existed
+	if err := dblog.DeleteWatermarks(ctx, storage.DB, src.SignalTableDatabase(), p.transfer.ID); err != nil {
+		return xerrors.Errorf("unable to delete watermarks: %w", err)
+	}
+	return nil
+}
+
+func (p *Provider) DBLogUpload(ctx context.Context, tables abstract.TableMap) error {
+	src, ok := p.transfer.Src.(*MysqlSource)
+	if !ok {
+		return xerrors.Errorf("unexpected type: %T", p.transfer.Src)
+	}
+	if !src.IsHomo {
+		src.IsHomo = p.transfer.DstType() == ProviderType && !src.PlzNoHomo
+	}
+	mysqlStorage, err := NewStorage(src.ToStorageParams())
+	if err != nil {
+		return xerrors.Errorf("failed to create mysql storage: %w", err)
+	}
+	defer mysqlStorage.Close()
+	mysqlStorage.IsHomo = src.IsHomo
+	failOnDecimal := isFailOnDecimal(p.transfer)
+
+	tableDescs := tables.ConvertToTableDescriptions()
+	for _, table := range tableDescs {
+		if abstract.IsSystemTable(table.Name) {
+			continue
+		}
+		asyncSink, err := abstract_sink.MakeAsyncSink(
+			p.transfer,
+			logger.Log,
+			p.registry,
+			p.cp,
+			middlewares.MakeConfig(middlewares.WithEnableRetries),
+		)
+		if err != nil {
+			return xerrors.Errorf("failed to make async sink: %w", err)
+		}
+
+		pusher := abstract.PusherFromAsyncSink(asyncSink)
+
+		if err = backoff.RetryNotify(func() error {
+			logger.Log.Infof("Starting upload table: %s", table.String())
+
+			source, err := newSource(src, p.transfer.ID, p.transfer.DataObjects, p.logger, p.registry, p.cp, failOnDecimal, true)
+			if err != nil {
+				return xerrors.Errorf("failed to create binlog source: %w", err)
+			}
+
+			dblogStorage, err := dblog.NewStorage(p.logger, source, mysqlStorage, mysqlStorage.DB, src.ChunkSize, p.transfer.ID, src.SignalTableDatabase(), Represent)
+			if err != nil {
+				return xerrors.Errorf("failed to create DBLog storage: %w", err)
+			}
+
+			err = dblogStorage.LoadTable(ctx, table, pusher)
+			if abstract.IsFatal(err) {
+				return backoff.Permanent(xerrors.Errorf("fatal error ocurred in dblogStorage.LoadTable, err: %w", err))
+			} else if err != nil {
+				return xerrors.Errorf("unable to dblogStorage.LoadTable, err: %w", err)
+			}
+			logger.Log.Infof("Upload table %s successfully", table.String())
+			return nil
+		}, util.NewExponentialBackOff(), util.BackoffLogger(logger.Log, fmt.Sprintf("loading table: %s", table.String()))); err != nil {
+			return xerrors.Errorf("failed to load table: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Provider) DBLogCleanup(ctx context.Context, src *MysqlSource) error {
+	storage, err := NewStorage(src.ToStorageParams())
+	if err != nil {
+		return xerrors.Errorf("failed to create mysql storage: %w", err)
+	}
+	defer storage.Close()
+
+	return dblog.DeleteWatermarks(ctx, storage.DB, src.SignalTableDatabase(), p.transfer.ID)
+}
+
 func New(lgr log.Logger, registry metrics.Registry, cp coordinator.Coordinator, transfer *model.Transfer) providers.Provider {
 	return &Provider{
 		logger:   lgr,
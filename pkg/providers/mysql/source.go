@@ -19,6 +19,7 @@ import (
 	"github.com/transferia/transferia/pkg/abstract/coordinator"
 	"github.com/transferia/transferia/pkg/abstract/model"
 	"github.com/transferia/transferia/pkg/format"
+	"github.com/transferia/transferia/pkg/providers/mysql/dblog"
 	unmarshaller "github.com/transferia/transferia/pkg/providers/mysql/unmarshaller/replication"
 	"github.com/transferia/transferia/pkg/stats"
 	"github.com/transferia/transferia/pkg/util"
@@ -451,6 +452,10 @@ func (p *publisher) Run(sink abstract.AsyncSink) error {
 					return xerrors.Errorf("fatal canal error: %w", abstract.NewFatalError(err))
 				}
 			}
+			if p.stopped {
+				p.logger.Info("publisher was stopped, exiting...")
+				return nil
+			}
 			p.logger.Error("canal run failed", log.Error(err))
 			return xerrors.Errorf("failed to run canal: %w", err)
 		}
@@ -494,13 +499,14 @@ func (p *publisher) Run(sink abstract.AsyncSink) error {
 
 func (p *publisher) Stop() {
 	p.once.Do(func() {
+		// canal run returns an error once canal is closed, so the publisher is marked as stopped beforehand
+		p.stopped = true
 		close(p.stopCh)
 		if err := p.handler.Close(); err != nil {
 			p.logger.Error("failed to close binlog handler", log.Error(err))
 		}
 		p.canal.Close()
 		p.storage.Close()
-		p.stopped = true
 	})
 }
 
@@ -605,6 +611,11 @@ func (p *publisher) flusher() {
 }
 
 func NewSource(src *MysqlSource, transferID string, objects *model.DataObjects, logger log.Logger, registry metrics.Registry, cp coordinator.Coordinator, failOnDecimal bool) (abstract.Source, error) {
+	return newSource(src, transferID, objects, logger, registry, cp, failOnDecimal, false)
+}
+
+// newSource creates the binlog source, dbLogSnapshot adds the DBLog signal table into replication
+func newSource(src *MysqlSource, transferID string, objects *model.DataObjects, logger log.Logger, registry metrics.Registry, cp coordinator.Coordinator, failOnDecimal bool, dbLogSnapshot bool) (abstract.Source, error) {
 	var rollbacks util.Rollbacks
 	defer rollbacks.Do()
 
@@ -650,6 +661,9 @@ func NewSource(src *MysqlSource, transferID string, objects *model.DataObjects,
 			return false
 		}
 		tid := abstract.TableID{Namespace: db, Name: table}
+		if dbLogSnapshot && tid == *dblog.SignalTableTableID(src.SignalTableDatabase()) {
+			return true
+		}
 		ok := src.Include(tid)
 		if !ok {
 			return false
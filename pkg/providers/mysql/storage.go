@@ -16,6 +16,7 @@ import (
 	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/abstract"
 	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/dblog/tablequery"
 	"github.com/transferia/transferia/pkg/util"
 	"go.ytsaurus.tech/library/go/core/log"
 )
@@ -332,6 +333,79 @@ func (s *Storage) LoadTable(ctx context.Context, table abstract.TableDescription
 	return nil
 }
 
+// LoadQueryTable reads a single chunk of the table for DBLog snapshot.
+// The chunk is read outside of the consistent snapshot, it must observe all changes committed before the low watermark.
+func (s *Storage) LoadQueryTable(ctx context.Context, tableQuery tablequery.TableQuery, pusher abstract.Pusher) error {
+	st := util.GetTimestampFromContextOrNow(ctx)
+
+	table := abstract.TableDescription{
+		Name:   tableQuery.TableID.Name,
+		Schema: tableQuery.TableID.Namespace,
+		Filter: "",
+		EtaRow: 0,
+		Offset: 0,
+	}
+
+	currTableSchema, ok := s.fqtnSchema[table.ID()]
+	if !ok {
+		return xerrors.Errorf("unable to find schema of table %v", table.Fqtn())
+	}
+
+	querySelect, err := buildQueryTableQuery(tableQuery, currTableSchema.Columns())
+	if err != nil {
+		return xerrors.Errorf("unable to build query: %w", err)
+	}
+
+	logger.Log.Info("Storage read table chunk", log.String("table", table.Fqtn()), log.String("query", querySelect))
+
+	conn, err := s.DB.Conn(ctx)
+	if err != nil {
+		return xerrors.Errorf("unable to get connection: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logger.Log.Error("Can't close connection", log.Error(err))
+		}
+	}()
+
+	timezone := timezoneOffset(s.ConnectionParams.Location)
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("set time_zone = '%s';", timezone)); err != nil {
+		return xerrors.Errorf("unable to set session timezone %s: %w", timezone, err)
+	}
+
+	colNameToColTypeName, err := makeMapColNameToColTypeName(ctx, conn, table.Name)
+	if err != nil {
+		return xerrors.Errorf("unable to get column types: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, querySelect)
+	if err != nil {
+		return xerrors.Errorf("Unable to select data from table %v: %w", table.Fqtn(), err)
+	}
+	defer rows.Close()
+
+	err = readRowsAndPushByChunks(
+		s.ConnectionParams.Location,
+		rows,
+		st,
+		table,
+		currTableSchema,
+		colNameToColTypeName,
+		tableQuery.Limit,
+		0,
+		s.IsHomo,
+		pusher,
+	)
+	if err != nil {
+		return xerrors.Errorf("unable to read rows and push by chunks: %w", err)
+	}
+
+	if err := rows.Err(); err != nil {
+		return xerrors.Errorf("Unable to read rows: %w", err)
+	}
+	return nil
+}
+
 func (s *Storage) getBinlogPosition(ctx context.Context, tx Queryable) (string, uint64, error) {
 	masterStatusQuery := "show master status;"
 	_, version, err := CheckMySQLVersion(s)
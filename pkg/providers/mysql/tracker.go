@@ -99,6 +99,17 @@ func (n *Tracker) Get() (file string, pos uint32, err error) {
 	return position.File, uint32(position.Position), nil
 }
 
+// Exists reports whether either binlog position or gtidset is stored
+func (n *Tracker) Exists() (bool, error) {
+	res, err := n.cp.GetTransferState(n.transferID)
+	if err != nil {
+		return false, xerrors.Errorf("unable to get transfer state: %w", err)
+	}
+	_, hasGtidset := res[gtidsetKey]
+	_, hasBinlogPos := res[binlogPosKey]
+	return hasGtidset || hasBinlogPos, nil
+}
+
 func NewTracker(src *MysqlSource, transferID string, cp coordinator.Coordinator) (result *Tracker, err error) {
 	connectionParams, err := NewConnectionParams(src.ToStorageParams())
 	if err != nil {
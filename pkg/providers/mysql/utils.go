@@ -14,6 +14,7 @@ import (
 	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/abstract"
 	"github.com/transferia/transferia/pkg/abstract/changeitem"
+	"github.com/transferia/transferia/pkg/dblog/tablequery"
 	"github.com/transferia/transferia/pkg/providers/mysql/unmarshaller/snapshot"
 	"github.com/transferia/transferia/pkg/util"
 	"github.com/transferia/transferia/pkg/util/size"
@@ -134,6 +135,38 @@ func buildSelectQuery(table abstract.TableDescription, tableSchema []abstract.Co
 	return resultQuery
 }
 
+// buildQueryTableQuery builds the query of the DBLog chunk, rows are ordered by the primary key
+// since the low bound of the next chunk is the key of the last row
+func buildQueryTableQuery(tableQuery tablequery.TableQuery, tableSchema []abstract.ColSchema) (string, error) {
+	colNames := MakeArrBacktickedColumnNames(&tableSchema)
+
+	resultQuery := fmt.Sprintf(
+		"SELECT %v FROM `%v`.`%v`",
+		strings.Join(colNames, ", "),
+		tableQuery.TableID.Namespace,
+		tableQuery.TableID.Name,
+	)
+
+	if tableQuery.Filter != "" {
+		resultQuery += " WHERE " + string(tableQuery.Filter)
+	}
+	if tableQuery.SortByPKeys {
+		orderBy, err := OrderByPrimaryKeys(tableSchema, "ASC")
+		if err != nil {
+			return "", xerrors.Errorf("unable to sort table %v by primary key: %w", tableQuery.TableID.Fqtn(), err)
+		}
+		resultQuery += orderBy
+	}
+	if tableQuery.Limit != 0 {
+		resultQuery += fmt.Sprintf(" LIMIT %d", tableQuery.Limit)
+	}
+	if tableQuery.Offset != 0 {
+		resultQuery += fmt.Sprintf(" OFFSET %d", tableQuery.Offset)
+	}
+
+	return resultQuery, nil
+}
+
 func IsPartition(filter abstract.WhereStatement) bool {
 	return strings.HasPrefix(string(filter), "PARTITION")
 }
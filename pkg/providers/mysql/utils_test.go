@@ -15,6 +15,7 @@ import (
 	"github.com/transferia/transferia/internal/logger"
 	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/dblog/tablequery"
 	"go.ytsaurus.tech/library/go/core/log"
 	"go.ytsaurus.tech/yt/go/schema"
 )
@@ -296,6 +297,30 @@ func Test_orderByPrimaryKeys(t *testing.T) {
 	require.Equal(t, result1, " ORDER BY `colName1` DESC,`colName2` DESC")
 }
 
+func Test_buildQueryTableQuery(t *testing.T) {
+	tableSchema := []abstract.ColSchema{
+		{ColumnName: "id1", PrimaryKey: true},
+		{ColumnName: "id2", PrimaryKey: true},
+		{ColumnName: "val"},
+	}
+	tableID := abstract.TableID{Namespace: "db", Name: "tableName"}
+
+	query0, err := buildQueryTableQuery(*tablequery.NewTableQuery(tableID, true, "", 0, 100), tableSchema)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT `id1`, `id2`, `val` FROM `db`.`tableName` ORDER BY `id1` ASC,`id2` ASC LIMIT 100", query0)
+
+	query1, err := buildQueryTableQuery(*tablequery.NewTableQuery(tableID, true, "(id1,id2) > (1,'a')", 0, 100), tableSchema)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT `id1`, `id2`, `val` FROM `db`.`tableName` WHERE (id1,id2) > (1,'a') ORDER BY `id1` ASC,`id2` ASC LIMIT 100", query1)
+
+	query2, err := buildQueryTableQuery(*tablequery.NewTableQuery(tableID, false, "", 0, 0), tableSchema)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT `id1`, `id2`, `val` FROM `db`.`tableName`", query2)
+
+	_, err = buildQueryTableQuery(*tablequery.NewTableQuery(tableID, true, "", 0, 100), []abstract.ColSchema{{ColumnName: "val"}})
+	require.Error(t, err)
+}
+
 //--------------------------------------------------------------------------------------------------------------------
 
 func Test_prepareArrayWithTypes_00(t *testing.T) {
@@ -0,0 +1,76 @@
+package nats
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract/model"
+)
+
+type NatsConnectionOptions struct {
+	// URL is a comma-separated list of servers, for example: nats://host1:4222,nats://host2:4222
+	URL string
+	// User & Password enable username/password authentication, Token - token authentication
+	User     string
+	Password model.SecretString
+	Token    model.SecretString
+	// CredsFile is a path to the user credentials file (JWT & NKey seed) of decentralized authentication
+	CredsFile string
+	// TLSFile is PEM-encoded CA certificates to verify servers, TLS is also enabled by the 'tls://' scheme of URL
+	TLSFile string `model:"PemFileContent"`
+}
+
+func (o *NatsConnectionOptions) Validate() error {
+	if strings.TrimSpace(o.URL) == "" {
+		return xerrors.New("URL is required")
+	}
+	if o.Token != "" && o.User != "" {
+		return xerrors.New("only one of Token and User can be specified")
+	}
+	return nil
+}
+
+func (o *NatsConnectionOptions) TLSConfig() (*tls.Config, error) {
+	if o.TLSFile == "" {
+		return nil, nil
+	}
+	cp := x509.NewCertPool()
+	if !cp.AppendCertsFromPEM([]byte(o.TLSFile)) {
+		return nil, xerrors.Errorf("credentials: failed to append certificates")
+	}
+	return &tls.Config{
+		RootCAs: cp,
+	}, nil
+}
+
+// Connect opens a connection, which reconnects forever - a broken connection fails operations, not the whole transfer
+func (o *NatsConnectionOptions) Connect(name string) (*nats.Conn, error) {
+	opts := []nats.Option{
+		nats.Name(name),
+		nats.MaxReconnects(-1),
+	}
+	switch {
+	case o.User != "":
+		opts = append(opts, nats.UserInfo(o.User, string(o.Password)))
+	case o.Token != "":
+		opts = append(opts, nats.Token(string(o.Token)))
+	}
+	if o.CredsFile != "" {
+		opts = append(opts, nats.UserCredentials(o.CredsFile))
+	}
+	tlsCfg, err := o.TLSConfig()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to construct tls config: %w", err)
+	}
+	if tlsCfg != nil {
+		opts = append(opts, nats.Secure(tlsCfg))
+	}
+	conn, err := nats.Connect(o.URL, opts...)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to connect to %s: %w", o.URL, err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,124 @@
+package nats
+
+import (
+	"strings"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	debeziumparameters "github.com/transferia/transferia/pkg/debezium/parameters"
+	"github.com/transferia/transferia/pkg/middlewares/async/bufferer"
+)
+
+const (
+	SchemaPlaceholder = "{schema}"
+	TablePlaceholder  = "{table}"
+
+	DefaultSubjectTemplate = SchemaPlaceholder + "." + TablePlaceholder
+)
+
+type NatsDestination struct {
+	Connection *NatsConnectionOptions
+
+	// SubjectTemplate is a subject of messages, where '{schema}' & '{table}' are replaced by the names of the table.
+	// Empty tokens of the subject are dropped, so tables without schema are published to '{table}' by the default template
+	SubjectTemplate string
+	// TableSubjectTemplates overrides SubjectTemplate for some tables, keys are fully-qualified table names: 'schema.table', or 'table' for tables without schema
+	TableSubjectTemplates map[string]string
+
+	ParralelWriterCount int
+
+	AddSystemTables bool // private options - to not skip consumer_keeper & other system tables
+	SaveTxOrder     bool
+
+	// for now, 'FormatSettings' is private option - it's WithDefaults(): SerializationFormatAuto - 'Mirror' for queues, 'Debezium' for the rest
+	FormatSettings model.SerializationFormat
+}
+
+var _ model.Destination = (*NatsDestination)(nil)
+
+func (d *NatsDestination) WithDefaults() {
+	if d.Connection == nil {
+		d.Connection = &NatsConnectionOptions{
+			URL:       "",
+			User:      "",
+			Password:  "",
+			Token:     "",
+			CredsFile: "",
+			TLSFile:   "",
+		}
+	}
+	if d.SubjectTemplate == "" {
+		d.SubjectTemplate = DefaultSubjectTemplate
+	}
+	if d.FormatSettings.Name == "" {
+		d.FormatSettings.Name = model.SerializationFormatAuto
+	}
+	if d.FormatSettings.Settings == nil {
+		d.FormatSettings.Settings = make(map[string]string)
+	}
+	if d.FormatSettings.BatchingSettings == nil {
+		d.FormatSettings.BatchingSettings = &model.Batching{
+			Enabled:        false,
+			Interval:       0,
+			MaxChangeItems: 0,
+			MaxMessageSize: 0,
+		}
+	}
+	if d.ParralelWriterCount == 0 {
+		d.ParralelWriterCount = 10
+	}
+}
+
+func (d *NatsDestination) CleanupMode() model.CleanupType {
+	return model.DisabledCleanup
+}
+
+func (d *NatsDestination) Transformer() map[string]string {
+	return nil
+}
+
+func (NatsDestination) IsDestination() {}
+
+func (d *NatsDestination) GetProviderType() abstract.ProviderType {
+	return ProviderType
+}
+
+func (d *NatsDestination) Validate() error {
+	if d.Connection == nil {
+		return xerrors.New("connection is required")
+	}
+	if err := d.Connection.Validate(); err != nil {
+		return xerrors.Errorf("invalid connection: %w", err)
+	}
+	if err := validateSubjectTemplate(d.SubjectTemplate); err != nil {
+		return xerrors.Errorf("invalid subject template: %w", err)
+	}
+	for table, template := range d.TableSubjectTemplates {
+		if err := validateSubjectTemplate(template); err != nil {
+			return xerrors.Errorf("invalid subject template of table %s: %w", table, err)
+		}
+	}
+	if d.SaveTxOrder && (strings.Contains(d.SubjectTemplate, SchemaPlaceholder) || strings.Contains(d.SubjectTemplate, TablePlaceholder) || len(d.TableSubjectTemplates) != 0) {
+		return xerrors.Errorf("option 'SaveTxOrder'=true is incompatible with subjects per table. Use either constant subject or turn off 'SaveTxOrder'.")
+	}
+	return nil
+}
+
+func (d *NatsDestination) Compatible(src model.Source, transferType abstract.TransferType) error {
+	return sourceCompatible(src, transferType, d.FormatSettings.Name)
+}
+
+func (d *NatsDestination) Serializer() (model.SerializationFormat, bool) {
+	formatSettings := d.FormatSettings
+	formatSettings.Settings = debeziumparameters.EnrichedWithDefaults(formatSettings.Settings)
+	return formatSettings, d.SaveTxOrder
+}
+
+func (d *NatsDestination) BuffererConfig() *bufferer.BuffererConfig {
+	return &bufferer.BuffererConfig{
+		TriggingCount:    d.FormatSettings.BatchingSettings.MaxChangeItems,
+		TriggingSize:     uint64(d.FormatSettings.BatchingSettings.MaxMessageSize),
+		TriggingInterval: d.FormatSettings.BatchingSettings.Interval,
+	}
+}
@@ -0,0 +1,102 @@
+package nats
+
+import (
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/parsers"
+)
+
+var (
+	_ model.Source = (*NatsSource)(nil)
+)
+
+type NatsSource struct {
+	Connection *NatsConnectionOptions
+
+	Stream string
+	// Subjects filter messages of the stream, all subjects of the stream are read if empty
+	Subjects []string
+	// Consumer is a name of the durable pull consumer, by default it's the transfer ID
+	Consumer string
+
+	// FetchBatchSize is the max number of messages fetched by one pull request
+	FetchBatchSize int
+	// AckWait is the time after which the server redelivers a message, which is not acknowledged yet.
+	// Messages are acknowledged only after they are pushed into the sink, so it should cover the push time
+	AckWait time.Duration
+
+	BufferSize            model.BytesSize // limits the size of messages, which are read but not pushed yet
+	ParserConfig          map[string]interface{}
+	ParseQueueParallelism int
+}
+
+func (s *NatsSource) GetProviderType() abstract.ProviderType {
+	return ProviderType
+}
+
+func (s *NatsSource) Validate() error {
+	if s.Connection == nil {
+		return xerrors.New("connection is required")
+	}
+	if err := s.Connection.Validate(); err != nil {
+		return xerrors.Errorf("invalid connection: %w", err)
+	}
+	if s.Stream == "" {
+		return xerrors.New("stream is required")
+	}
+	if s.ParserConfig != nil {
+		parserConfigStruct, err := parsers.ParserConfigMapToStruct(s.ParserConfig)
+		if err != nil {
+			return xerrors.Errorf("unable to create new parser config, err: %w", err)
+		}
+		return parserConfigStruct.Validate()
+	}
+	return nil
+}
+
+func (s *NatsSource) WithDefaults() {
+	if s.Connection == nil {
+		s.Connection = &NatsConnectionOptions{
+			URL:       "",
+			User:      "",
+			Password:  "",
+			Token:     "",
+			CredsFile: "",
+			TLSFile:   "",
+		}
+	}
+	if s.FetchBatchSize == 0 {
+		s.FetchBatchSize = 1000
+	}
+	if s.AckWait == 0 {
+		s.AckWait = 5 * time.Minute
+	}
+	if s.BufferSize == 0 {
+		s.BufferSize = 100 * 1024 * 1024
+	}
+}
+
+func (s *NatsSource) IsSource() {}
+
+func (s *NatsSource) IsAppendOnly() bool {
+	if s.ParserConfig == nil {
+		return false
+	} else {
+		parserConfigStruct, _ := parsers.ParserConfigMapToStruct(s.ParserConfig)
+		if parserConfigStruct == nil {
+			return false
+		}
+		return parserConfigStruct.IsAppendOnly()
+	}
+}
+
+func (s *NatsSource) IsDefaultMirror() bool {
+	return s.ParserConfig == nil
+}
+
+func (s *NatsSource) Parser() map[string]interface{} {
+	return s.ParserConfig
+}
@@ -0,0 +1,98 @@
+package nats
+
+import (
+	"context"
+
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	cpclient "github.com/transferia/transferia/pkg/abstract/coordinator"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/middlewares"
+	"github.com/transferia/transferia/pkg/providers"
+	"github.com/transferia/transferia/pkg/util/gobwrapper"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+func init() {
+	gobwrapper.Register(new(NatsSource))
+	gobwrapper.Register(new(NatsDestination))
+	model.RegisterSource(ProviderType, func() model.Source {
+		return new(NatsSource)
+	})
+	model.RegisterDestination(ProviderType, func() model.Destination {
+		return new(NatsDestination)
+	})
+	abstract.RegisterProviderName(ProviderType, "NATS JetStream")
+
+	providers.Register(ProviderType, New)
+}
+
+const ProviderType = abstract.ProviderType("nats")
+
+// To verify providers contract implementation
+var (
+	_ providers.Replication = (*Provider)(nil)
+	_ providers.Sinker      = (*Provider)(nil)
+
+	_ providers.Activator = (*Provider)(nil)
+)
+
+type Provider struct {
+	logger   log.Logger
+	registry metrics.Registry
+	cp       cpclient.Coordinator
+	transfer *model.Transfer
+}
+
+func (p *Provider) Type() abstract.ProviderType {
+	return ProviderType
+}
+
+func (p *Provider) Source() (abstract.Source, error) {
+	src, ok := p.transfer.Src.(*NatsSource)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected source type: %T", p.transfer.Src)
+	}
+	srcCopy := *src
+	if srcCopy.Consumer == "" {
+		srcCopy.Consumer = p.transfer.ID
+	}
+	return NewSource(&srcCopy, p.logger, p.registry)
+}
+
+func (p *Provider) Sink(middlewares.Config) (abstract.Sinker, error) {
+	dst, ok := p.transfer.Dst.(*NatsDestination)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected target type: %T", p.transfer.Dst)
+	}
+	cfgCopy := *dst
+	cfgCopy.FormatSettings = InferFormatSettings(p.transfer.Src, cfgCopy.FormatSettings)
+	return NewReplicationSink(&cfgCopy, p.registry, p.logger)
+}
+
+func (p *Provider) SnapshotSink(middlewares.Config) (abstract.Sinker, error) {
+	dst, ok := p.transfer.Dst.(*NatsDestination)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected target type: %T", p.transfer.Dst)
+	}
+	cfgCopy := *dst
+	cfgCopy.FormatSettings = InferFormatSettings(p.transfer.Src, cfgCopy.FormatSettings)
+	return NewSnapshotSink(&cfgCopy, p.registry, p.logger)
+}
+
+func (p *Provider) Activate(_ context.Context, _ *model.TransferOperation, _ abstract.TableMap, _ providers.ActivateCallbacks) error {
+	if p.transfer.SrcType() == ProviderType && !p.transfer.IncrementOnly() {
+		return xerrors.New("Only allowed mode for NATS source is replication")
+	}
+	return nil
+}
+
+func New(lgr log.Logger, registry metrics.Registry, cp cpclient.Coordinator, transfer *model.Transfer) providers.Provider {
+	return &Provider{
+		logger:   lgr,
+		registry: registry,
+		cp:       cp,
+		transfer: transfer,
+	}
+}
@@ -0,0 +1,169 @@
+package nats
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	serializer "github.com/transferia/transferia/pkg/serializer/queue"
+	"github.com/transferia/transferia/pkg/stats"
+	"github.com/transferia/transferia/pkg/util"
+	queues "github.com/transferia/transferia/pkg/util/queues"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const (
+	// KeyHeader is a header with the key of the serialized message, subjects have no keys
+	KeyHeader = "Message-Key"
+
+	pushTimeout = 5 * time.Minute
+	// publishWindow limits async publishes of one writer, which are not acknowledged yet
+	publishWindow = 256
+)
+
+type sink struct {
+	config     *NatsDestination
+	logger     log.Logger
+	metrics    *stats.SinkerStats
+	serializer serializer.Serializer
+	conn       *nats.Conn
+	js         jetstream.JetStream
+}
+
+func (s *sink) Push(input []abstract.ChangeItem) error {
+	start := time.Now()
+
+	// serialize
+
+	tableToMessages, err := s.serializer.Serialize(input)
+	if err != nil {
+		return xerrors.Errorf("unable to serialize: %w", err)
+	}
+	serializer.LogBatchingStat(s.logger, input, tableToMessages, start)
+
+	var pushTasks []abstract.TablePartID
+	for currTablePartID := range tableToMessages {
+		if currTablePartID.IsSystemTable() && !s.config.AddSystemTables {
+			continue
+		}
+		pushTasks = append(pushTasks, currTablePartID)
+	}
+
+	// send
+
+	startSending := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+
+	timings := queues.NewTimingsStatCollector()
+	err = util.ParallelDoWithContextAbort(ctx, len(pushTasks), s.config.ParralelWriterCount, func(i int, ctx context.Context) error {
+		currTablePartID := pushTasks[i]
+		currMessages := tableToMessages[currTablePartID]
+		timings.Started(currTablePartID)
+		subject := s.config.SubjectName(currTablePartID)
+
+		if err := s.publish(ctx, subject, currMessages); err != nil {
+			return xerrors.Errorf("unable to publish messages, len(input): %d, tableID: %s, subject: %s, messages: %d : %w", len(input), currTablePartID.Fqtn(), subject, len(currMessages), err)
+		}
+		s.metrics.Table(currTablePartID.Fqtn(), "rows", len(currMessages))
+		timings.Finished(currTablePartID)
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("unable to push messages: %w", err)
+	}
+	s.logger.Info("Sending sync timings stat", append([]log.Field{log.String("push_elapsed", time.Since(start).String()), log.String("sending_elapsed", time.Since(startSending).String())}, timings.GetResults()...)...)
+	s.metrics.Elapsed.RecordDuration(time.Since(start))
+	return nil
+}
+
+// publish publishes messages asynchronously by windows, messages of one subject keep their order
+func (s *sink) publish(ctx context.Context, subject string, messages []serializer.SerializedMessage) error {
+	for len(messages) > 0 {
+		window := messages
+		if len(window) > publishWindow {
+			window = messages[:publishWindow]
+		}
+		messages = messages[len(window):]
+
+		futures := make([]jetstream.PubAckFuture, 0, len(window))
+		for _, message := range window {
+			future, err := s.js.PublishMsgAsync(makeMsg(subject, message))
+			if err != nil {
+				return xerrors.Errorf("unable to publish message: %w", err)
+			}
+			futures = append(futures, future)
+		}
+		for _, future := range futures {
+			select {
+			case <-future.Ok():
+			case err := <-future.Err():
+				return xerrors.Errorf("message is not acknowledged: %w", err)
+			case <-ctx.Done():
+				return xerrors.Errorf("message is not acknowledged: %w", ctx.Err())
+			}
+		}
+	}
+	return nil
+}
+
+func makeMsg(subject string, message serializer.SerializedMessage) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Data = message.Value
+	if len(message.Key) != 0 {
+		msg.Header.Set(KeyHeader, string(message.Key))
+	}
+	return msg
+}
+
+func (s *sink) Close() error {
+	s.conn.Close()
+	return nil
+}
+
+func NewSinkImpl(cfg *NatsDestination, registry metrics.Registry, lgr log.Logger, isSnapshot bool) (abstract.Sinker, error) {
+	if cfg.FormatSettings.Name == model.SerializationFormatLbMirror {
+		return nil, xerrors.Errorf("serializer %s is not supported by nats destination", cfg.FormatSettings.Name)
+	}
+	currFormat := cfg.FormatSettings
+	if currFormat.Name == model.SerializationFormatDebezium {
+		currFormat = serializer.MakeFormatSettingsWithTopicPrefix(currFormat, "", "")
+	}
+
+	currSerializer, err := serializer.New(currFormat, cfg.SaveTxOrder, false, isSnapshot, lgr)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create serializer: %w", err)
+	}
+
+	conn, err := cfg.Connection.Connect("transferia-sink")
+	if err != nil {
+		return nil, xerrors.Errorf("unable to connect: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, xerrors.Errorf("unable to create jetstream context: %w", err)
+	}
+
+	return &sink{
+		config:     cfg,
+		logger:     lgr,
+		metrics:    stats.NewSinkerStats(registry),
+		serializer: currSerializer,
+		conn:       conn,
+		js:         js,
+	}, nil
+}
+
+func NewReplicationSink(cfg *NatsDestination, registry metrics.Registry, lgr log.Logger) (abstract.Sinker, error) {
+	return NewSinkImpl(cfg, registry, lgr, false)
+}
+
+func NewSnapshotSink(cfg *NatsDestination, registry metrics.Registry, lgr log.Logger) (abstract.Sinker, error) {
+	return NewSinkImpl(cfg, registry, lgr, true)
+}
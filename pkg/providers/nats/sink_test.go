@@ -0,0 +1,130 @@
+package nats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+)
+
+// runServer starts an embedded server with JetStream and creates the stream
+func runServer(t *testing.T, stream string, subjects ...string) (*NatsConnectionOptions, jetstream.JetStream) {
+	srv, err := natsserver.NewServer(&natsserver.Options{
+		Host:      "127.0.0.1",
+		Port:      natsserver.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+	go srv.Start()
+	require.True(t, srv.ReadyForConnections(10*time.Second))
+	t.Cleanup(srv.Shutdown)
+
+	connection := &NatsConnectionOptions{URL: srv.ClientURL()}
+	conn, err := connection.Connect("test")
+	require.NoError(t, err)
+	t.Cleanup(conn.Close)
+	js, err := jetstream.New(conn)
+	require.NoError(t, err)
+	_, err = js.CreateStream(context.Background(), jetstream.StreamConfig{Name: stream, Subjects: subjects})
+	require.NoError(t, err)
+	return connection, js
+}
+
+// readAll reads all messages of the stream by an ephemeral consumer
+func readAll(t *testing.T, js jetstream.JetStream, stream string, count int) []jetstream.Msg {
+	consumer, err := js.CreateOrUpdateConsumer(context.Background(), stream, jetstream.ConsumerConfig{AckPolicy: jetstream.AckNonePolicy})
+	require.NoError(t, err)
+	batch, err := consumer.Fetch(count+1, jetstream.FetchMaxWait(time.Second))
+	require.NoError(t, err)
+	var result []jetstream.Msg
+	for msg := range batch.Messages() {
+		result = append(result, msg)
+	}
+	require.NoError(t, batch.Error())
+	return result
+}
+
+func makeTestItem(schema, table string, id int32) abstract.ChangeItem {
+	return abstract.ChangeItem{
+		Kind:         abstract.InsertKind,
+		Schema:       schema,
+		Table:        table,
+		ColumnNames:  []string{"id", "val"},
+		ColumnValues: []interface{}{id, "value"},
+		TableSchema: abstract.NewTableSchema([]abstract.ColSchema{
+			{ColumnName: "id", DataType: "int32", PrimaryKey: true},
+			{ColumnName: "val", DataType: "utf8"},
+		}),
+	}
+}
+
+func TestSinkSubjectPerTable(t *testing.T) {
+	connection, js := runServer(t, "CDC", "cdc.>")
+
+	dst := &NatsDestination{
+		Connection:            connection,
+		SubjectTemplate:       "cdc." + DefaultSubjectTemplate,
+		TableSubjectTemplates: map[string]string{"public.orders": "cdc.orders"},
+		FormatSettings:        model.SerializationFormat{Name: model.SerializationFormatJSON},
+	}
+	dst.WithDefaults()
+	require.NoError(t, dst.Validate())
+
+	sink, err := NewReplicationSink(dst, solomon.NewRegistry(nil), logger.Log)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		makeTestItem("public", "users", 1),
+		makeTestItem("public", "users", 2),
+		makeTestItem("public", "orders", 3),
+	}))
+
+	messages := readAll(t, js, "CDC", 3)
+	require.Len(t, messages, 3)
+	bySubject := map[string][]string{}
+	for _, msg := range messages {
+		bySubject[msg.Subject()] = append(bySubject[msg.Subject()], string(msg.Data()))
+		require.NotEmpty(t, msg.Headers().Get(KeyHeader))
+	}
+	require.Equal(t, map[string][]string{
+		"cdc.public.users": {`{"id":1,"val":"value"}`, `{"id":2,"val":"value"}`},
+		"cdc.orders":       {`{"id":3,"val":"value"}`},
+	}, bySubject)
+}
+
+func TestSinkMirror(t *testing.T) {
+	connection, js := runServer(t, "MIRROR", "events.>")
+
+	dst := &NatsDestination{
+		Connection:     connection,
+		FormatSettings: model.SerializationFormat{Name: model.SerializationFormatMirror},
+	}
+	dst.WithDefaults()
+
+	sink, err := NewReplicationSink(dst, solomon.NewRegistry(nil), logger.Log)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		abstract.MakeRawMessage("events.a", time.Now(), "events.a", 0, 1, []byte("first")),
+		abstract.MakeRawMessage("events.a", time.Now(), "events.a", 0, 2, []byte("second")),
+	}))
+
+	messages := readAll(t, js, "MIRROR", 2)
+	require.Len(t, messages, 2)
+	for i, expected := range []string{"first", "second"} {
+		require.Equal(t, "events.a", messages[i].Subject())
+		require.Equal(t, expected, string(messages[i].Data()))
+	}
+}
@@ -0,0 +1,310 @@
+package nats
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/format"
+	"github.com/transferia/transferia/pkg/parsequeue"
+	"github.com/transferia/transferia/pkg/parsers"
+	"github.com/transferia/transferia/pkg/stats"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const (
+	fetchMaxWait = time.Second
+	setupTimeout = time.Minute
+)
+
+var (
+	_ abstract.Source = (*Source)(nil)
+)
+
+// Source reads the stream by a durable pull consumer.
+// The consumer acknowledges messages cumulatively (AckAll), so acknowledgement of the last message of a batch,
+// which is done only after the batch is pushed into the sink, acknowledges all previous messages as well
+type Source struct {
+	config  *NatsSource
+	logger  log.Logger
+	metrics *stats.SourceStats
+	parser  parsers.Parser
+
+	conn     *nats.Conn
+	consumer jetstream.Consumer
+
+	inflightMutex sync.Mutex
+	inflightBytes int
+
+	errMutex  sync.Mutex
+	lastError error
+
+	ctx    context.Context
+	cancel func()
+}
+
+func (s *Source) inLimits() bool {
+	s.inflightMutex.Lock()
+	defer s.inflightMutex.Unlock()
+	return s.config.BufferSize == 0 || int(s.config.BufferSize) > s.inflightBytes
+}
+
+func (s *Source) addInflight(size int) {
+	s.inflightMutex.Lock()
+	defer s.inflightMutex.Unlock()
+	s.inflightBytes += size
+}
+
+func (s *Source) reduceInflight(size int) {
+	s.inflightMutex.Lock()
+	defer s.inflightMutex.Unlock()
+	s.inflightBytes = s.inflightBytes - size
+}
+
+func (s *Source) setError(err error) {
+	s.errMutex.Lock()
+	defer s.errMutex.Unlock()
+	if s.lastError == nil {
+		s.lastError = err
+	}
+}
+
+func (s *Source) getError() error {
+	s.errMutex.Lock()
+	defer s.errMutex.Unlock()
+	return s.lastError
+}
+
+func (s *Source) Run(sink abstract.AsyncSink) error {
+	defer s.conn.Close()
+	parseQ := parsequeue.NewWaitable(s.logger, s.config.ParseQueueParallelism, sink, s.parse, s.ack)
+	defer parseQ.Close()
+
+	return s.run(parseQ)
+}
+
+func (s *Source) run(parseQ *parsequeue.WaitableParseQueue[[]jetstream.Msg]) error {
+	for {
+		s.metrics.Master.Set(1)
+		s.waitLimits()
+		if s.ctx.Err() != nil {
+			if err := s.getError(); err != nil {
+				return xerrors.Errorf("unable to push: %w", err)
+			}
+			return nil
+		}
+
+		batch, err := s.consumer.Fetch(s.config.FetchBatchSize, jetstream.FetchMaxWait(fetchMaxWait))
+		if err != nil {
+			return xerrors.Errorf("unable to fetch messages: %w", err)
+		}
+		var data []jetstream.Msg
+		for msg := range batch.Messages() {
+			s.addInflight(len(msg.Data()))
+			data = append(data, msg)
+		}
+		if err := batch.Error(); err != nil {
+			return xerrors.Errorf("unable to fetch messages: %w", err)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if err := parseQ.Add(data); err != nil {
+			return xerrors.Errorf("unable to add messages to parse queue: %w", err)
+		}
+	}
+}
+
+// ack must not acknowledge anything after a failed push: the cumulative acknowledgement would acknowledge the failed batch too
+func (s *Source) ack(data []jetstream.Msg, pushSt time.Time, err error) {
+	for _, msg := range data {
+		s.reduceInflight(len(msg.Data()))
+	}
+	if err != nil {
+		s.setError(err)
+		s.cancel()
+		return
+	}
+	if s.getError() != nil {
+		return
+	}
+	if err := data[len(data)-1].Ack(); err != nil {
+		// not acknowledged messages are redelivered after AckWait
+		s.logger.Warn("unable to acknowledge messages", log.Int("count", len(data)), log.Error(err))
+	}
+	s.metrics.PushTime.RecordDuration(time.Since(pushSt))
+}
+
+func (s *Source) parse(data []jetstream.Msg) []abstract.ChangeItem {
+	var result []abstract.ChangeItem
+	totalSize := 0
+	st := time.Now()
+	for _, msg := range data {
+		totalSize += len(msg.Data())
+		s.metrics.Size.Add(int64(len(msg.Data())))
+		s.metrics.Count.Inc()
+		if s.parser == nil {
+			result = append(result, s.makeRawChangeItem(msg))
+			continue
+		}
+		message, partition := s.messageAsParserMessage(msg)
+		result = append(result, s.parser.Do(message, partition)...)
+	}
+	s.logger.Infof("parse done in %v for %v messages of total size %v -> %v rows", time.Since(st), len(data), format.SizeInt(totalSize), len(result))
+	if s.parser != nil {
+		s.metrics.DecodeTime.RecordDuration(time.Since(st))
+	}
+	s.metrics.ChangeItems.Add(int64(len(result)))
+	for _, ci := range result {
+		if ci.IsRowEvent() {
+			s.metrics.Parsed.Inc()
+		}
+	}
+	return result
+}
+
+// messageMeta returns the stream sequence & the store time of the message
+func messageMeta(msg jetstream.Msg) (uint64, time.Time) {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return 0, time.Now()
+	}
+	return meta.Sequence.Stream, meta.Timestamp
+}
+
+func (s *Source) messageAsParserMessage(msg jetstream.Msg) (parsers.Message, abstract.Partition) {
+	seqNo, writeTime := messageMeta(msg)
+	var headers map[string]string
+	if len(msg.Headers()) != 0 {
+		headers = make(map[string]string, len(msg.Headers()))
+		for k := range msg.Headers() {
+			headers[k] = msg.Headers().Get(k)
+		}
+	}
+	var key []byte
+	if k := msg.Headers().Get(KeyHeader); k != "" {
+		key = []byte(k)
+	}
+	return parsers.Message{
+		Offset:     seqNo,
+		SeqNo:      0,
+		Key:        key,
+		CreateTime: writeTime,
+		WriteTime:  writeTime,
+		Value:      msg.Data(),
+		Headers:    headers,
+	}, abstract.Partition{
+		Cluster:   "", // subjects are not partitioned
+		Partition: 0,
+		Topic:     msg.Subject(),
+	}
+}
+
+func (s *Source) makeRawChangeItem(msg jetstream.Msg) abstract.ChangeItem {
+	seqNo, writeTime := messageMeta(msg)
+	return abstract.MakeRawMessage(
+		msg.Subject(),
+		writeTime,
+		msg.Subject(),
+		0,
+		int64(seqNo),
+		msg.Data(),
+	)
+}
+
+func (s *Source) Stop() {
+	s.cancel()
+}
+
+func (s *Source) waitLimits() {
+	backoffTimer := backoff.NewExponentialBackOff()
+	backoffTimer.Reset()
+	backoffTimer.MaxElapsedTime = 0
+	nextLogDuration := backoffTimer.NextBackOff()
+	logTime := time.Now()
+
+	for !s.inLimits() {
+		time.Sleep(time.Millisecond * 10)
+		if s.ctx.Err() != nil {
+			s.logger.Warn("context aborted, stop wait for limits")
+			return
+		}
+		if time.Since(logTime) > nextLogDuration {
+			logTime = time.Now()
+			nextLogDuration = backoffTimer.NextBackOff()
+			s.logger.Warnf(
+				"reader throttled for %v, limits: %v / %v",
+				backoffTimer.GetElapsedTime(),
+				format.SizeInt(s.inflightBytes),
+				format.SizeInt(int(s.config.BufferSize)),
+			)
+		}
+	}
+}
+
+func consumerConfig(cfg *NatsSource) jetstream.ConsumerConfig {
+	result := jetstream.ConsumerConfig{
+		Durable:       cfg.Consumer,
+		DeliverPolicy: jetstream.DeliverAllPolicy,
+		AckPolicy:     jetstream.AckAllPolicy,
+		AckWait:       cfg.AckWait,
+		MaxAckPending: -1, // inflight messages are limited by BufferSize
+	}
+	if len(cfg.Subjects) == 1 {
+		result.FilterSubject = cfg.Subjects[0] // is supported by servers older than 2.10 as well
+	} else {
+		result.FilterSubjects = cfg.Subjects
+	}
+	return result
+}
+
+func NewSource(cfg *NatsSource, logger log.Logger, registry metrics.Registry) (*Source, error) {
+	var parser parsers.Parser
+	if cfg.ParserConfig != nil {
+		var err error
+		parser, err = parsers.NewParserFromMap(cfg.ParserConfig, false, logger, stats.NewSourceStats(registry))
+		if err != nil {
+			return nil, xerrors.Errorf("unable to make parser, err: %w", err)
+		}
+	}
+
+	conn, err := cfg.Connection.Connect(cfg.Consumer)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to connect: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, xerrors.Errorf("unable to create jetstream context: %w", err)
+	}
+	setupCtx, setupCancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer setupCancel()
+	consumer, err := js.CreateOrUpdateConsumer(setupCtx, cfg.Stream, consumerConfig(cfg))
+	if err != nil {
+		conn.Close()
+		return nil, xerrors.Errorf("unable to create consumer %s of stream %s: %w", cfg.Consumer, cfg.Stream, err)
+	}
+	logger.Info("consumer is ready", log.String("stream", cfg.Stream), log.String("consumer", cfg.Consumer), log.Strings("subjects", cfg.Subjects))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Source{
+		config:        cfg,
+		logger:        logger,
+		metrics:       stats.NewSourceStats(registry),
+		parser:        parser,
+		conn:          conn,
+		consumer:      consumer,
+		inflightMutex: sync.Mutex{},
+		inflightBytes: 0,
+		errMutex:      sync.Mutex{},
+		lastError:     nil,
+		ctx:           ctx,
+		cancel:        cancel,
+	}, nil
+}
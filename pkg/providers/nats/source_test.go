@@ -0,0 +1,160 @@
+package nats
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/parsers"
+	jsonparser "github.com/transferia/transferia/pkg/parsers/registry/json"
+	ytschema "go.ytsaurus.tech/yt/go/schema"
+)
+
+type mockSink struct {
+	mutex sync.Mutex
+	items []abstract.ChangeItem
+	err   error
+}
+
+func (s *mockSink) AsyncPush(items []abstract.ChangeItem) chan error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	result := make(chan error, 1)
+	if s.err == nil {
+		s.items = append(s.items, items...)
+	}
+	result <- s.err
+	return result
+}
+
+func (s *mockSink) Close() error {
+	return nil
+}
+
+func (s *mockSink) rows() []abstract.ChangeItem {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var result []abstract.ChangeItem
+	for _, item := range s.items {
+		if item.IsRowEvent() {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func publish(t *testing.T, js jetstream.JetStream, subject string, values ...string) {
+	for _, value := range values {
+		_, err := js.Publish(context.Background(), subject, []byte(value))
+		require.NoError(t, err)
+	}
+}
+
+func makeTestSource(t *testing.T, connection *NatsConnectionOptions, stream string, subjects ...string) *NatsSource {
+	parserConfigMap, err := parsers.ParserConfigStructToMap(&jsonparser.ParserConfigJSONCommon{
+		Fields: []abstract.ColSchema{
+			{ColumnName: "id", DataType: ytschema.TypeInt32.String(), PrimaryKey: true},
+			{ColumnName: "val", DataType: ytschema.TypeString.String()},
+		},
+		AddRest:       false,
+		AddDedupeKeys: false,
+	})
+	require.NoError(t, err)
+	src := &NatsSource{
+		Connection:   connection,
+		Stream:       stream,
+		Subjects:     subjects,
+		Consumer:     "transfer",
+		ParserConfig: parserConfigMap,
+	}
+	src.WithDefaults()
+	require.NoError(t, src.Validate())
+	return src
+}
+
+func consumerAckFloor(t *testing.T, js jetstream.JetStream, stream string, consumer string) uint64 {
+	c, err := js.Consumer(context.Background(), stream, consumer)
+	require.NoError(t, err)
+	info, err := c.Info(context.Background())
+	require.NoError(t, err)
+	return info.AckFloor.Stream
+}
+
+func TestSource(t *testing.T) {
+	connection, js := runServer(t, "EVENTS", "events.>")
+	publish(t, js, "events.users", `{"id":1,"val":"a"}`, `{"id":2,"val":"b"}`)
+	publish(t, js, "events.orders", `{"id":3,"val":"c"}`)
+
+	source, err := NewSource(makeTestSource(t, connection, "EVENTS", "events.users"), logger.Log, solomon.NewRegistry(nil))
+	require.NoError(t, err)
+	sink := &mockSink{}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- source.Run(sink)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(sink.rows()) == 2
+	}, 10*time.Second, 10*time.Millisecond)
+	// the last message of the subject is acknowledged after the push, with all previous ones
+	require.Eventually(t, func() bool {
+		return consumerAckFloor(t, js, "EVENTS", "transfer") == 2
+	}, 10*time.Second, 10*time.Millisecond)
+	source.Stop()
+	require.NoError(t, <-errCh)
+
+	rows := sink.rows()
+	require.Equal(t, "events.users", rows[0].Table)
+	require.Equal(t, []string{"id", "val"}, rows[0].ColumnNames[:2])
+	require.Equal(t, []interface{}{int32(1), "a"}, rows[0].ColumnValues[:2])
+	require.Equal(t, []interface{}{int32(2), "b"}, rows[1].ColumnValues[:2])
+
+	// the durable consumer continues after acknowledged messages
+	publish(t, js, "events.users", `{"id":4,"val":"d"}`)
+	source, err = NewSource(makeTestSource(t, connection, "EVENTS", "events.users"), logger.Log, solomon.NewRegistry(nil))
+	require.NoError(t, err)
+	sink = &mockSink{}
+	go func() {
+		errCh <- source.Run(sink)
+	}()
+	require.Eventually(t, func() bool {
+		return len(sink.rows()) == 1
+	}, 10*time.Second, 10*time.Millisecond)
+	source.Stop()
+	require.NoError(t, <-errCh)
+	require.Equal(t, []interface{}{int32(4), "d"}, sink.rows()[0].ColumnValues[:2])
+}
+
+func TestSourceNoAckOnFailedPush(t *testing.T) {
+	connection, js := runServer(t, "EVENTS", "events.>")
+	publish(t, js, "events.users", `{"id":1,"val":"a"}`)
+
+	src := makeTestSource(t, connection, "EVENTS")
+	src.AckWait = time.Second
+	source, err := NewSource(src, logger.Log, solomon.NewRegistry(nil))
+	require.NoError(t, err)
+	sink := &mockSink{err: xerrors.New("push failed")}
+	require.Error(t, source.Run(sink))
+	require.Equal(t, uint64(0), consumerAckFloor(t, js, "EVENTS", "transfer"))
+
+	// the message is redelivered to the restarted source after AckWait
+	source, err = NewSource(src, logger.Log, solomon.NewRegistry(nil))
+	require.NoError(t, err)
+	sink = &mockSink{}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- source.Run(sink)
+	}()
+	require.Eventually(t, func() bool {
+		return len(sink.rows()) == 1
+	}, 10*time.Second, 10*time.Millisecond)
+	source.Stop()
+	require.NoError(t, <-errCh)
+}
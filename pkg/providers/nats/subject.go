@@ -0,0 +1,59 @@
+package nats
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+)
+
+// subjectPart replaces wildcards & whitespaces, which are not allowed in subjects of published messages.
+// Dots are kept, so names of raw messages, which are subjects themselves, are mirrored as is
+func subjectPart(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '*' || r == '>' || unicode.IsSpace(r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+func renderSubject(template string, schema string, table string) string {
+	rendered := strings.NewReplacer(SchemaPlaceholder, subjectPart(schema), TablePlaceholder, subjectPart(table)).Replace(template)
+	tokens := strings.Split(rendered, ".")
+	result := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if token != "" {
+			result = append(result, token)
+		}
+	}
+	return strings.Join(result, ".")
+}
+
+func validateSubjectTemplate(template string) error {
+	subject := renderSubject(template, "schema", "table")
+	if subject == "" {
+		return xerrors.Errorf("template '%s' renders to an empty subject", template)
+	}
+	if strings.ContainsAny(subject, "*> \t\r\n") {
+		return xerrors.Errorf("template '%s' contains wildcards or whitespaces", template)
+	}
+	return nil
+}
+
+func tableKey(tableID abstract.TablePartID) string {
+	if tableID.Namespace == "" {
+		return tableID.Name
+	}
+	return tableID.Namespace + "." + tableID.Name
+}
+
+// SubjectName returns the subject of messages of the table
+func (d *NatsDestination) SubjectName(tableID abstract.TablePartID) string {
+	template := d.SubjectTemplate
+	if tableTemplate, ok := d.TableSubjectTemplates[tableKey(tableID)]; ok {
+		template = tableTemplate
+	}
+	return renderSubject(template, tableID.Namespace, tableID.Name)
+}
@@ -0,0 +1,48 @@
+package nats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/abstract"
+)
+
+func TestSubjectName(t *testing.T) {
+	dst := &NatsDestination{
+		SubjectTemplate: "cdc." + DefaultSubjectTemplate,
+		TableSubjectTemplates: map[string]string{
+			"public.orders": "orders.all",
+			"logs":          "raw.{table}",
+		},
+	}
+
+	require.Equal(t, "cdc.public.users", dst.SubjectName(abstract.TablePartID{TableID: *abstract.NewTableID("public", "users"), PartID: ""}))
+	require.Equal(t, "cdc.users", dst.SubjectName(abstract.TablePartID{TableID: *abstract.NewTableID("", "users"), PartID: ""}))
+	require.Equal(t, "cdc.my_schema.a.b_c", dst.SubjectName(abstract.TablePartID{TableID: *abstract.NewTableID("my schema", "a.b*c"), PartID: ""}))
+	require.Equal(t, "orders.all", dst.SubjectName(abstract.TablePartID{TableID: *abstract.NewTableID("public", "orders"), PartID: ""}))
+	require.Equal(t, "raw.logs", dst.SubjectName(abstract.TablePartID{TableID: *abstract.NewTableID("", "logs"), PartID: ""}))
+}
+
+func TestValidateSubjectTemplate(t *testing.T) {
+	require.NoError(t, validateSubjectTemplate(DefaultSubjectTemplate))
+	require.NoError(t, validateSubjectTemplate("events"))
+	require.Error(t, validateSubjectTemplate(""))
+	require.Error(t, validateSubjectTemplate("."))
+	require.Error(t, validateSubjectTemplate("events.>"))
+	require.Error(t, validateSubjectTemplate("events.*.{table}"))
+	require.Error(t, validateSubjectTemplate("my events"))
+}
+
+func TestValidateDestination(t *testing.T) {
+	dst := &NatsDestination{Connection: &NatsConnectionOptions{URL: "nats://localhost:4222"}}
+	dst.WithDefaults()
+	require.NoError(t, dst.Validate())
+
+	dst.SaveTxOrder = true
+	require.Error(t, dst.Validate())
+	dst.SubjectTemplate = "events"
+	require.NoError(t, dst.Validate())
+
+	dst.TableSubjectTemplates = map[string]string{"public.users": "users.>"}
+	require.Error(t, dst.Validate())
+}
@@ -1,6 +1,8 @@
 package opensearch
 
 import (
+	"time"
+
 	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/abstract"
 	"github.com/transferia/transferia/pkg/abstract/model"
@@ -18,6 +20,11 @@ type OpenSearchSource struct {
 	SecurityGroupIDs     []string
 	DumpIndexWithMapping bool
 	ConnectionID         string
+
+	// CursorField is a monotonic field used by replication to find new documents, for example `@timestamp` or an ingestion time field
+	CursorField string
+	// PollInterval is a pause between replication polls, which found no new documents
+	PollInterval time.Duration
 }
 
 var _ model.Source = (*OpenSearchSource)(nil)
@@ -43,6 +50,8 @@ func (s *OpenSearchSource) ToElasticSearchSource() (*elastic.ElasticSearchSource
 		SecurityGroupIDs:     s.SecurityGroupIDs,
 		DumpIndexWithMapping: s.DumpIndexWithMapping,
 		ConnectionID:         s.ConnectionID,
+		CursorField:          s.CursorField,
+		PollInterval:         s.PollInterval,
 	}, elastic.OpenSearch
 }
 
@@ -72,6 +81,9 @@ func (s *OpenSearchSource) GetConnectionID() string {
 }
 
 func (s *OpenSearchSource) WithDefaults() {
+	if s.PollInterval == 0 {
+		s.PollInterval = elastic.DefaultPollInterval
+	}
 }
 
 func (s *OpenSearchSource) Hosts() []string {
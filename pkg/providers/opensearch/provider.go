@@ -37,9 +37,10 @@ const ProviderType = abstract.ProviderType("opensearch")
 
 // To verify providers contract implementation
 var (
-	_ providers.Sinker    = (*Provider)(nil)
-	_ providers.Snapshot  = (*Provider)(nil)
-	_ providers.Activator = (*Provider)(nil)
+	_ providers.Sinker      = (*Provider)(nil)
+	_ providers.Snapshot    = (*Provider)(nil)
+	_ providers.Activator   = (*Provider)(nil)
+	_ providers.Replication = (*Provider)(nil)
 )
 
 type Provider struct {
@@ -54,20 +55,37 @@ func (p *Provider) Type() abstract.ProviderType {
 }
 
 func (p *Provider) Activate(ctx context.Context, task *model.TransferOperation, tables abstract.TableMap, callbacks providers.ActivateCallbacks) error {
-	if !p.transfer.SnapshotOnly() {
-		return abstract.NewFatalError(xerrors.Errorf("only snapshot mode is allowed for the Opensearch source"))
-	}
-	if err := callbacks.Cleanup(tables); err != nil {
-		return xerrors.Errorf("failed to cleanup sink: %w", err)
+	src, ok := p.transfer.Src.(*OpenSearchSource)
+	if !ok {
+		return xerrors.Errorf("unexpected source type: %T", p.transfer.Src)
 	}
-	if err := callbacks.CheckIncludes(tables); err != nil {
-		return xerrors.Errorf("failed in accordance with configuration: %w", err)
+	elasticSrc, serverType := src.ToElasticSearchSource()
+	if !p.transfer.SnapshotOnly() {
+		if elasticSrc.CursorField == "" {
+			return abstract.NewFatalError(xerrors.Errorf("cursor field is required for replication from the Opensearch source"))
+		}
+		// cursors are stored before the snapshot, so documents added during the snapshot are replicated
+		storage, err := elastic.NewStorage(elasticSrc, p.logger, p.registry, serverType)
+		if err != nil {
+			return xerrors.Errorf("unable to create storage: %w", err)
+		}
+		if err := elastic.InitReplicationCursors(ctx, storage, p.cp, p.transfer.ID, elasticSrc.CursorField, tables); err != nil {
+			return xerrors.Errorf("unable to init replication cursors: %w", err)
+		}
 	}
 	if err := elastic.DumpIndexInfo(p.transfer, p.logger, p.registry); err != nil {
 		return xerrors.Errorf("failed to dump source indexes info: %w", err)
 	}
-	if err := callbacks.Upload(tables); err != nil {
-		return xerrors.Errorf("transfer (snapshot) failed: %w", err)
+	if !p.transfer.IncrementOnly() {
+		if err := callbacks.Cleanup(tables); err != nil {
+			return xerrors.Errorf("failed to cleanup sink: %w", err)
+		}
+		if err := callbacks.CheckIncludes(tables); err != nil {
+			return xerrors.Errorf("failed in accordance with configuration: %w", err)
+		}
+		if err := callbacks.Upload(tables); err != nil {
+			return xerrors.Errorf("transfer (snapshot) failed: %w", err)
+		}
 	}
 	return nil
 }
@@ -87,6 +105,18 @@ func (p *Provider) Storage() (abstract.Storage, error) {
 	return NewStorage(src, p.logger, p.registry)
 }
 
+func (p *Provider) Source() (abstract.Source, error) {
+	src, ok := p.transfer.Src.(*OpenSearchSource)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected source type: %T", p.transfer.Src)
+	}
+	elasticSrc, serverType := src.ToElasticSearchSource()
+	if _, ok := p.transfer.Dst.(elastic.IsElasticLikeDestination); ok {
+		return elastic.NewSource(elasticSrc, serverType, p.logger, p.registry, p.cp, p.transfer, elastic.WithHomo())
+	}
+	return elastic.NewSource(elasticSrc, serverType, p.logger, p.registry, p.cp, p.transfer)
+}
+
 func (p *Provider) Sink(middlewares.Config) (abstract.Sinker, error) {
 	dst, ok := p.transfer.Dst.(*OpenSearchDestination)
 	if !ok {
@@ -1,6 +1,8 @@
 package postgres
 
 import (
+	"context"
+
 	"github.com/transferia/transferia/internal/logger"
 	"github.com/transferia/transferia/library/go/core/xerrors"
 )
@@ -22,6 +24,9 @@ func DropReplicationSlot(src *PgSource, tracker ...*Tracker) error {
 	if err != nil {
 		return xerrors.Errorf("failed to drop the replication slot: %w", err)
 	}
+	if err := DropPublication(context.Background(), conn, src); err != nil {
+		return xerrors.Errorf("failed to drop the publication: %w", err)
+	}
 
 	return nil
 }
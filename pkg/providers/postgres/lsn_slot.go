@@ -124,7 +124,7 @@ func (l *LsnTrackedSlot) createFromLSN(lsn string) error {
 				return xerrors.Errorf("failed to set lock_timeout: %w", err)
 			}
 
-			if err := tx.QueryRow(ctx, "select * from pg_create_logical_replication_slot_lsn($1, 'wal2json', false, pg_lsn($2))", l.slotID, lsn).Scan(&createdSlotName, &createdSlotLSN); err != nil {
+			if err := tx.QueryRow(ctx, "select * from pg_create_logical_replication_slot_lsn($1, $2, false, pg_lsn($3))", l.slotID, string(l.src.SlotPlugin()), lsn).Scan(&createdSlotName, &createdSlotLSN); err != nil {
 				return xerrors.Errorf("could not create slot from lsn:%v because of error: %w", lsn, err)
 			}
 			return nil
@@ -39,6 +39,7 @@ type PgDestination struct {
 	BufferTriggingInterval    time.Duration
 	QueryTimeout              time.Duration
 	DisableSQLFallback        bool
+	BulkUpsert                bool // apply every replicated batch through a staging table and a single MERGE statement instead of per-row queries
 	ConnectionID              string
 }
 
@@ -194,6 +195,10 @@ func (d PgDestinationWrapper) DisableSQLFallback() bool {
 	return d.Model.DisableSQLFallback
 }
 
+func (d PgDestinationWrapper) BulkUpsert() bool {
+	return d.Model.BulkUpsert
+}
+
 func (d PgDestinationWrapper) QueryTimeout() time.Duration {
 	if d.Model.QueryTimeout <= 0 {
 		return PGDefaultQueryTimeout
@@ -47,5 +47,8 @@ type PgSinkParams interface {
 	QueryTimeout() time.Duration
 	// DisableSQLFallback returns true if the sink should never use SQL when copying snapshot and should always use "COPY FROM"
 	DisableSQLFallback() bool
+	// BulkUpsert returns true if the sink should collapse every pushed batch, COPY it into a temporary staging table
+	// and apply it to the target table with a single MERGE (or INSERT ... ON CONFLICT + DELETE ... USING) statement
+	BulkUpsert() bool
 	ConnectionID() string
 }
@@ -66,6 +66,10 @@ type PgSource struct {
 	DBLogEnabled bool   // force DBLog snapshot instead of common
 	ChunkSize    uint64 // number of rows in chunk, this field needed for DBLog snapshot, if it is 0, it will be calculated automatically
 
+	// Plugin is the logical decoding plugin of the replication slot, wal2json by default.
+	// pgoutput is built into PostgreSQL 10+, changes are read from the publication named after the slot.
+	Plugin Plugin
+
 	// Whether text or binary serialization format should be used when readeing
 	// snapshot from PostgreSQL storage snapshot (see
 	// https://www.postgresql.org/docs/current/protocol-overview.html#PROTOCOL-FORMAT-CODES).
@@ -356,9 +360,26 @@ func (s *PgSource) Validate() error {
 	if err := utils.ValidatePGTables(s.ExcludedTables); err != nil {
 		return xerrors.Errorf("validate exclude tables error: %w", err)
 	}
+	switch s.Plugin {
+	case "", Wal2JSONPlugin:
+	case PgOutputPlugin:
+		if s.UsePolling {
+			return xerrors.New("polling is not supported with pgoutput plugin")
+		}
+	default:
+		return xerrors.Errorf("unknown logical decoding plugin %q", s.Plugin)
+	}
 	return nil
 }
 
+// SlotPlugin returns the logical decoding plugin of the replication slot
+func (s *PgSource) SlotPlugin() Plugin {
+	if s.Plugin == "" {
+		return Wal2JSONPlugin
+	}
+	return s.Plugin
+}
+
 func (s *PgSource) ExtraTransformers(ctx context.Context, transfer *model.Transfer, registry metrics.Registry) ([]abstract.Transformer, error) {
 	var result []abstract.Transformer
 	if s.CollapseInheritTables {
@@ -458,6 +479,10 @@ func (d PgSourceWrapper) DisableSQLFallback() bool {
 	return false
 }
 
+func (d PgSourceWrapper) BulkUpsert() bool {
+	return false
+}
+
 func (d PgSourceWrapper) QueryTimeout() time.Duration {
 	return PGDefaultQueryTimeout
 }
@@ -0,0 +1,337 @@
+package postgres
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgtype"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+)
+
+// Messages of streamed in-progress transactions, protocol version 2 (PostgreSQL 14+).
+// They are not supported by pglogrepl, so they are decoded here.
+const (
+	pgOutputStreamStart  = 'S'
+	pgOutputStreamStop   = 'E'
+	pgOutputStreamCommit = 'c'
+	pgOutputStreamAbort  = 'A'
+	pgOutputMessage      = 'M'
+)
+
+// TypeNameResolver returns the name of the type with modifier, like format_type does
+type TypeNameResolver func(oid uint32, typmod int32) (string, error)
+
+type pgOutputColumn struct {
+	name     string
+	typeOID  pgtype.OID
+	typeName string
+	key      bool
+}
+
+type pgOutputRelation struct {
+	schema  string
+	table   string
+	columns []pgOutputColumn
+}
+
+type streamedChange struct {
+	xid  uint32
+	item *Wal2JSONItem
+}
+
+// PgOutputParser decodes messages of the pgoutput plugin into the same items as Wal2JsonParser returns for wal2json,
+// so changes of both plugins go through the same change processor.
+//
+// Changes of regular transactions are returned as soon as they are decoded, like wal2json does in chunked mode.
+// Changes of streamed in-progress transactions are kept until the transaction is committed,
+// so streaming is not requested by the replication, see newPgOutputArguments.
+type PgOutputParser struct {
+	typeNames TypeNameResolver
+	relations map[uint32]*pgOutputRelation
+
+	xid        uint32
+	commitTime uint64
+
+	inStream  bool
+	streamXid uint32
+	streams   map[uint32][]streamedChange
+}
+
+// TransactionEnd checks if the message commits a transaction
+func (p *PgOutputParser) TransactionEnd(data []byte) bool {
+	return len(data) > 0 && (data[0] == byte(pglogrepl.MessageTypeCommit) || data[0] == pgOutputStreamCommit)
+}
+
+func (p *PgOutputParser) Parse(data []byte, lsn pglogrepl.LSN) ([]*Wal2JSONItem, error) {
+	if len(data) == 0 {
+		return nil, xerrors.New("empty pgoutput message")
+	}
+	switch data[0] {
+	case pgOutputStreamStart:
+		if len(data) < 5 {
+			return nil, xerrors.Errorf("stream start message is too short: %d bytes", len(data))
+		}
+		p.inStream = true
+		p.streamXid = binary.BigEndian.Uint32(data[1:])
+		return nil, nil
+	case pgOutputStreamStop:
+		p.inStream = false
+		return nil, nil
+	case pgOutputStreamCommit:
+		return p.parseStreamCommit(data)
+	case pgOutputStreamAbort:
+		return nil, p.parseStreamAbort(data)
+	case pgOutputMessage:
+		return nil, nil
+	}
+
+	xid := p.xid
+	if p.inStream {
+		switch pglogrepl.MessageType(data[0]) {
+		case pglogrepl.MessageTypeRelation, pglogrepl.MessageTypeType, pglogrepl.MessageTypeInsert,
+			pglogrepl.MessageTypeUpdate, pglogrepl.MessageTypeDelete, pglogrepl.MessageTypeTruncate:
+			// messages of streamed transactions contain xid of the (sub)transaction after the message type
+			if len(data) < 5 {
+				return nil, xerrors.Errorf("streamed %s message is too short: %d bytes", pglogrepl.MessageType(data[0]), len(data))
+			}
+			xid = binary.BigEndian.Uint32(data[1:])
+			data = append([]byte{data[0]}, data[5:]...)
+		}
+	}
+
+	msg, err := pglogrepl.Parse(data)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to parse pgoutput message %q: %w", data[0], err)
+	}
+
+	var items []*Wal2JSONItem
+	switch m := msg.(type) {
+	case *pglogrepl.BeginMessage:
+		p.xid = m.Xid
+		p.commitTime = uint64(m.CommitTime.UnixNano())
+	case *pglogrepl.CommitMessage:
+		p.xid = 0
+		p.commitTime = 0
+	case *pglogrepl.RelationMessage:
+		if err := p.parseRelation(m); err != nil {
+			return nil, xerrors.Errorf("unable to parse relation %s.%s: %w", m.Namespace, m.RelationName, err)
+		}
+	case *pglogrepl.InsertMessage:
+		item, err := p.newItem(abstract.InsertKind, m.RelationID, len(data))
+		if err != nil {
+			return nil, xerrors.Errorf("unable to parse insert: %w", err)
+		}
+		p.fillColumns(item, m.RelationID, m.Tuple)
+		items = append(items, item)
+	case *pglogrepl.UpdateMessage:
+		item, err := p.newItem(abstract.UpdateKind, m.RelationID, len(data))
+		if err != nil {
+			return nil, xerrors.Errorf("unable to parse update: %w", err)
+		}
+		p.fillColumns(item, m.RelationID, m.NewTuple)
+		// like wal2json, keys are taken from the new tuple when the old one isn't sent, i.e. keys are not changed
+		if m.OldTuple != nil {
+			p.fillOldKeys(item, m.RelationID, m.OldTuple)
+		} else {
+			p.fillOldKeys(item, m.RelationID, m.NewTuple)
+		}
+		items = append(items, item)
+	case *pglogrepl.DeleteMessage:
+		item, err := p.newItem(abstract.DeleteKind, m.RelationID, len(data))
+		if err != nil {
+			return nil, xerrors.Errorf("unable to parse delete: %w", err)
+		}
+		p.fillOldKeys(item, m.RelationID, m.OldTuple)
+		items = append(items, item)
+	case *pglogrepl.TruncateMessage:
+		for _, relationID := range m.RelationIDs {
+			item, err := p.newItem(abstract.TruncateTableKind, relationID, len(data))
+			if err != nil {
+				return nil, xerrors.Errorf("unable to parse truncate: %w", err)
+			}
+			items = append(items, item)
+		}
+	}
+
+	for i, item := range items {
+		item.LSN = uint64(lsn)
+		item.Counter = i
+	}
+	if p.inStream && len(items) > 0 {
+		for _, item := range items {
+			p.streams[p.streamXid] = append(p.streams[p.streamXid], streamedChange{xid: xid, item: item})
+		}
+		return nil, nil
+	}
+	return items, nil
+}
+
+func (p *PgOutputParser) parseRelation(m *pglogrepl.RelationMessage) error {
+	relation := &pgOutputRelation{
+		schema:  m.Namespace,
+		table:   m.RelationName,
+		columns: make([]pgOutputColumn, 0, len(m.Columns)),
+	}
+	for _, col := range m.Columns {
+		typeName, err := p.typeNames(col.DataType, int32(col.TypeModifier))
+		if err != nil {
+			return xerrors.Errorf("unable to resolve name of type %d of column %s: %w", col.DataType, col.Name, err)
+		}
+		relation.columns = append(relation.columns, pgOutputColumn{
+			name:     col.Name,
+			typeOID:  pgtype.OID(col.DataType),
+			typeName: typeName,
+			key:      col.Flags&1 != 0,
+		})
+	}
+	p.relations[m.RelationID] = relation
+	return nil
+}
+
+func (p *PgOutputParser) newItem(kind abstract.Kind, relationID uint32, size int) (*Wal2JSONItem, error) {
+	relation, ok := p.relations[relationID]
+	if !ok {
+		return nil, xerrors.Errorf("relation %d is unknown", relationID)
+	}
+	item := new(Wal2JSONItem)
+	item.ID = p.xid
+	item.CommitTime = p.commitTime
+	item.Kind = kind
+	item.Schema = relation.schema
+	item.Table = relation.table
+	item.Size.Read = uint64(size)
+	return item, nil
+}
+
+func (p *PgOutputParser) fillColumns(item *Wal2JSONItem, relationID uint32, tuple *pglogrepl.TupleData) {
+	relation := p.relations[relationID]
+	for i, col := range tuple.Columns {
+		if i >= len(relation.columns) || col.DataType == pglogrepl.TupleDataTypeToast {
+			// unchanged TOAST values are skipped, like wal2json does
+			continue
+		}
+		item.ColumnNames = append(item.ColumnNames, relation.columns[i].name)
+		item.ColumnValues = append(item.ColumnValues, pgOutputValue(col, relation.columns[i].typeOID))
+		item.ColumnTypeOIDs = append(item.ColumnTypeOIDs, relation.columns[i].typeOID)
+	}
+}
+
+func (p *PgOutputParser) fillOldKeys(item *Wal2JSONItem, relationID uint32, tuple *pglogrepl.TupleData) {
+	if tuple == nil {
+		return
+	}
+	relation := p.relations[relationID]
+	for i, col := range tuple.Columns {
+		if i >= len(relation.columns) || !relation.columns[i].key || col.DataType == pglogrepl.TupleDataTypeToast {
+			continue
+		}
+		item.OldKeys.KeyNames = append(item.OldKeys.KeyNames, relation.columns[i].name)
+		item.OldKeys.KeyTypes = append(item.OldKeys.KeyTypes, relation.columns[i].typeName)
+		item.OldKeys.KeyValues = append(item.OldKeys.KeyValues, pgOutputValue(col, relation.columns[i].typeOID))
+		item.OldKeys.KeyTypeOids = append(item.OldKeys.KeyTypeOids, relation.columns[i].typeOID)
+	}
+}
+
+// parseStreamCommit returns changes of the streamed transaction, changes with the same LSN are told apart by counters
+func (p *PgOutputParser) parseStreamCommit(data []byte) ([]*Wal2JSONItem, error) {
+	// xid (4), flags (1), commit LSN (8), end LSN (8), commit timestamp (8)
+	if len(data) < 30 {
+		return nil, xerrors.Errorf("stream commit message is too short: %d bytes", len(data))
+	}
+	xid := binary.BigEndian.Uint32(data[1:])
+	commitTime := pgOutputTime(int64(binary.BigEndian.Uint64(data[22:])))
+
+	changes := p.streams[xid]
+	delete(p.streams, xid)
+	items := make([]*Wal2JSONItem, 0, len(changes))
+	for i, change := range changes {
+		change.item.ID = xid
+		change.item.CommitTime = uint64(commitTime.UnixNano())
+		change.item.Counter = 0
+		if i > 0 && changes[i-1].item.LSN == change.item.LSN {
+			change.item.Counter = changes[i-1].item.Counter + 1
+		}
+		items = append(items, change.item)
+	}
+	return items, nil
+}
+
+// parseStreamAbort drops changes of the aborted transaction or of its aborted subtransaction
+func (p *PgOutputParser) parseStreamAbort(data []byte) error {
+	if len(data) < 9 {
+		return xerrors.Errorf("stream abort message is too short: %d bytes", len(data))
+	}
+	xid := binary.BigEndian.Uint32(data[1:])
+	subXid := binary.BigEndian.Uint32(data[5:])
+	if xid == subXid {
+		delete(p.streams, xid)
+		return nil
+	}
+	changes := p.streams[xid]
+	kept := changes[:0]
+	for _, change := range changes {
+		if change.xid != subXid {
+			kept = append(kept, change)
+		}
+	}
+	p.streams[xid] = kept
+	return nil
+}
+
+func (p *PgOutputParser) Close() {}
+
+// pgOutputValue converts the text representation of the value into the JSON value wal2json writes for it
+func pgOutputValue(col *pglogrepl.TupleDataColumn, oid pgtype.OID) any {
+	if col.DataType != pglogrepl.TupleDataTypeText {
+		return nil
+	}
+	text := string(col.Data)
+	switch oid {
+	case pgtype.BoolOID:
+		return text == "t"
+	case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID, pgtype.OIDOID, pgtype.Float4OID, pgtype.Float8OID, pgtype.NumericOID:
+		if strings.HasPrefix(text, "NaN") || strings.HasPrefix(text, "Infinity") || strings.HasPrefix(text, "-Infinity") {
+			return text
+		}
+		return json.Number(text)
+	case pgtype.ByteaOID:
+		return strings.TrimPrefix(text, `\x`)
+	default:
+		return text
+	}
+}
+
+// pgOutputTime converts microseconds since the PostgreSQL epoch
+func pgOutputTime(microseconds int64) time.Time {
+	return time.Unix(946684800, 0).Add(time.Duration(microseconds) * time.Microsecond)
+}
+
+func NewPgOutputParser(typeNames TypeNameResolver) *PgOutputParser {
+	return &PgOutputParser{
+		typeNames: typeNames,
+		relations: map[uint32]*pgOutputRelation{},
+
+		xid:        0,
+		commitTime: 0,
+
+		inStream:  false,
+		streamXid: 0,
+		streams:   map[uint32][]streamedChange{},
+	}
+}
+
+// newPgOutputArguments returns options of pgoutput.
+// Streaming of in-progress transactions is not requested: PostgreSQL streams only transactions which don't fit
+// into logical_decoding_work_mem, and they would be kept in memory of the worker until commit,
+// while without streaming they are spilled to disk on the server.
+func newPgOutputArguments(config *PgSource) pluginArguments {
+	return pluginArguments{
+		{name: "proto_version", value: "1"},
+		{name: "publication_names", value: fmt.Sprintf(`"%s"`, PublicationName(config))},
+	}
+}
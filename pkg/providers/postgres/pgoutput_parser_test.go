@@ -0,0 +1,431 @@
+package postgres
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgtype"
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/abstract"
+)
+
+// pgoutput messages of fixtures {{{
+type pgOutputTestColumn struct {
+	name   string
+	oid    uint32
+	typmod int32
+	key    bool
+}
+
+// pgOutputTestValue is a tuple column, nil is NULL and unchangedToast is an unchanged TOAST value
+type pgOutputTestValue *string
+
+var unchangedToast = pgOutputTestValue(new(string))
+
+func text(value string) pgOutputTestValue {
+	return &value
+}
+
+type pgOutputWriter []byte
+
+func (w pgOutputWriter) u8(v byte) pgOutputWriter {
+	return append(w, v)
+}
+
+func (w pgOutputWriter) u16(v uint16) pgOutputWriter {
+	return binary.BigEndian.AppendUint16(w, v)
+}
+
+func (w pgOutputWriter) u32(v uint32) pgOutputWriter {
+	return binary.BigEndian.AppendUint32(w, v)
+}
+
+func (w pgOutputWriter) u64(v uint64) pgOutputWriter {
+	return binary.BigEndian.AppendUint64(w, v)
+}
+
+func (w pgOutputWriter) str(v string) pgOutputWriter {
+	return append(append(w, v...), 0)
+}
+
+func (w pgOutputWriter) tuple(values ...pgOutputTestValue) pgOutputWriter {
+	w = w.u16(uint16(len(values)))
+	for _, value := range values {
+		switch {
+		case value == nil:
+			w = w.u8('n')
+		case value == unchangedToast:
+			w = w.u8('u')
+		default:
+			w = append(w.u8('t').u32(uint32(len(*value))), *value...)
+		}
+	}
+	return w
+}
+
+func pgTestTime(t time.Time) uint64 {
+	return uint64(t.Sub(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).Microseconds())
+}
+
+func beginMessage(xid uint32, commitTime time.Time) []byte {
+	return pgOutputWriter{'B'}.u64(0).u64(pgTestTime(commitTime)).u32(xid)
+}
+
+func commitMessage(commitTime time.Time) []byte {
+	return pgOutputWriter{'C'}.u8(0).u64(0).u64(0).u64(pgTestTime(commitTime))
+}
+
+func relationMessage(id uint32, schema, table string, columns ...pgOutputTestColumn) []byte {
+	w := pgOutputWriter{'R'}.u32(id).str(schema).str(table).u8('d').u16(uint16(len(columns)))
+	for _, col := range columns {
+		var flags byte
+		if col.key {
+			flags = 1
+		}
+		w = w.u8(flags).str(col.name).u32(col.oid).u32(uint32(col.typmod))
+	}
+	return w
+}
+
+func insertMessage(relationID uint32, values ...pgOutputTestValue) []byte {
+	return pgOutputWriter{'I'}.u32(relationID).u8('N').tuple(values...)
+}
+
+func updateMessage(relationID uint32, oldKeys []pgOutputTestValue, values ...pgOutputTestValue) []byte {
+	w := pgOutputWriter{'U'}.u32(relationID)
+	if oldKeys != nil {
+		w = w.u8('K').tuple(oldKeys...)
+	}
+	return w.u8('N').tuple(values...)
+}
+
+func deleteMessage(relationID uint32, oldKeys ...pgOutputTestValue) []byte {
+	return pgOutputWriter{'D'}.u32(relationID).u8('K').tuple(oldKeys...)
+}
+
+func truncateMessage(relationIDs ...uint32) []byte {
+	w := pgOutputWriter{'T'}.u32(uint32(len(relationIDs))).u8(0)
+	for _, id := range relationIDs {
+		w = w.u32(id)
+	}
+	return w
+}
+
+// streamed inserts the xid of the (sub)transaction after the message type
+func streamed(xid uint32, message []byte) []byte {
+	return append(pgOutputWriter{message[0]}.u32(xid), message[1:]...)
+}
+
+func streamStartMessage(xid uint32) []byte {
+	return pgOutputWriter{'S'}.u32(xid).u8(1)
+}
+
+func streamStopMessage() []byte {
+	return []byte{'E'}
+}
+
+func streamCommitMessage(xid uint32, commitTime time.Time) []byte {
+	return pgOutputWriter{'c'}.u32(xid).u8(0).u64(0).u64(0).u64(pgTestTime(commitTime))
+}
+
+func streamAbortMessage(xid, subXid uint32) []byte {
+	return pgOutputWriter{'A'}.u32(xid).u32(subXid)
+}
+
+// }}}
+
+var (
+	pgOutputTestTime = time.Date(2020, 8, 31, 10, 20, 30, 405060000, time.UTC)
+
+	pgOutputTestTypes = map[uint32]string{
+		uint32(pgtype.Int4OID):        "integer",
+		uint32(pgtype.BoolOID):        "boolean",
+		uint32(pgtype.NumericOID):     "numeric(10,2)",
+		uint32(pgtype.Float8OID):      "double precision",
+		uint32(pgtype.TextOID):        "text",
+		uint32(pgtype.ByteaOID):       "bytea",
+		uint32(pgtype.TimestamptzOID): "timestamp with time zone",
+		uint32(pgtype.JSONBOID):       "jsonb",
+	}
+
+	pgOutputTestColumns = []pgOutputTestColumn{
+		{name: "id", oid: uint32(pgtype.Int4OID), typmod: -1, key: true},
+		{name: "b", oid: uint32(pgtype.BoolOID), typmod: -1, key: false},
+		{name: "n", oid: uint32(pgtype.NumericOID), typmod: 655366, key: false},
+		{name: "f", oid: uint32(pgtype.Float8OID), typmod: -1, key: false},
+		{name: "t", oid: uint32(pgtype.TextOID), typmod: -1, key: false},
+		{name: "bin", oid: uint32(pgtype.ByteaOID), typmod: -1, key: false},
+		{name: "ts", oid: uint32(pgtype.TimestamptzOID), typmod: -1, key: false},
+		{name: "j", oid: uint32(pgtype.JSONBOID), typmod: -1, key: false},
+	}
+
+	pgOutputTestRelation = relationMessage(16384, "public", "types", pgOutputTestColumns...)
+	pgOutputTestOther    = relationMessage(16385, "public", "other", pgOutputTestColumn{name: "id", oid: uint32(pgtype.Int4OID), typmod: -1, key: true})
+)
+
+// pluginFixture is the same transaction decoded by wal2json and pgoutput
+type pluginFixture struct {
+	name     string
+	wal2json string
+	pgoutput [][]byte
+}
+
+var pluginFixtures = []pluginFixture{
+	{
+		name: "insert",
+		wal2json: `{"xid": 100, "timestamp": "2020-08-31 10:20:30.40506+00", "change": [{
+			"kind": "insert", "schema": "public", "table": "types",
+			"columnnames": ["id", "b", "n", "f", "t", "bin", "ts", "j"],
+			"columntypes": ["integer", "boolean", "numeric(10,2)", "double precision", "text", "bytea", "timestamp with time zone", "jsonb"],
+			"columntypeoids": [23, 16, 1700, 701, 25, 17, 1184, 3802],
+			"columnvalues": [1, true, 12.50, "NaN", null, "0102ff", "2020-08-31 13:20:30.40506+03", "{\"a\": [1, 2]}"]
+		}]}`,
+		pgoutput: [][]byte{
+			beginMessage(100, pgOutputTestTime),
+			pgOutputTestRelation,
+			insertMessage(16384, text("1"), text("t"), text("12.50"), text("NaN"), nil, text(`\x0102ff`), text("2020-08-31 13:20:30.40506+03"), text(`{"a": [1, 2]}`)),
+			commitMessage(pgOutputTestTime),
+		},
+	},
+	{
+		name: "update with unchanged toast",
+		wal2json: `{"xid": 101, "timestamp": "2020-08-31 10:20:30.40506+00", "change": [{
+			"kind": "update", "schema": "public", "table": "types",
+			"columnnames": ["id", "b", "n", "f", "bin", "ts", "j"],
+			"columntypes": ["integer", "boolean", "numeric(10,2)", "double precision", "bytea", "timestamp with time zone", "jsonb"],
+			"columntypeoids": [23, 16, 1700, 701, 17, 1184, 3802],
+			"columnvalues": [2, false, -1.00, 2.5, "", null, null],
+			"oldkeys": {"keynames": ["id"], "keytypes": ["integer"], "keytypeoids": [23], "keyvalues": [2]}
+		}]}`,
+		pgoutput: [][]byte{
+			beginMessage(101, pgOutputTestTime),
+			pgOutputTestRelation,
+			updateMessage(16384, nil, text("2"), text("f"), text("-1.00"), text("2.5"), unchangedToast, text(`\x`), nil, nil),
+			commitMessage(pgOutputTestTime),
+		},
+	},
+	{
+		name: "update of key",
+		wal2json: `{"xid": 102, "timestamp": "2020-08-31 10:20:30.40506+00", "change": [{
+			"kind": "update", "schema": "public", "table": "types",
+			"columnnames": ["id", "b", "n", "f", "t", "bin", "ts", "j"],
+			"columntypes": ["integer", "boolean", "numeric(10,2)", "double precision", "text", "bytea", "timestamp with time zone", "jsonb"],
+			"columntypeoids": [23, 16, 1700, 701, 25, 17, 1184, 3802],
+			"columnvalues": [4, null, null, "-Infinity", "text", null, null, null],
+			"oldkeys": {"keynames": ["id"], "keytypes": ["integer"], "keytypeoids": [23], "keyvalues": [3]}
+		}]}`,
+		pgoutput: [][]byte{
+			beginMessage(102, pgOutputTestTime),
+			pgOutputTestRelation,
+			updateMessage(16384, []pgOutputTestValue{text("3"), nil, nil, nil, nil, nil, nil, nil}, text("4"), nil, nil, text("-Infinity"), text("text"), nil, nil, nil),
+			commitMessage(pgOutputTestTime),
+		},
+	},
+	{
+		name: "delete and truncate",
+		wal2json: `{"xid": 103, "timestamp": "2020-08-31 10:20:30.40506+00", "change": [{
+			"kind": "delete", "schema": "public", "table": "types",
+			"oldkeys": {"keynames": ["id"], "keytypes": ["integer"], "keytypeoids": [23], "keyvalues": [4]}
+		}, {
+			"kind": "truncate", "schema": "public", "table": "types"
+		}, {
+			"kind": "truncate", "schema": "public", "table": "other"
+		}]}`,
+		pgoutput: [][]byte{
+			beginMessage(103, pgOutputTestTime),
+			pgOutputTestRelation,
+			pgOutputTestOther,
+			deleteMessage(16384, text("4"), nil, nil, nil, nil, nil, nil, nil),
+			truncateMessage(16384, 16385),
+			commitMessage(pgOutputTestTime),
+		},
+	},
+	{
+		name: "streamed transaction",
+		wal2json: `{"xid": 104, "timestamp": "2020-08-31 10:20:30.40506+00", "change": [{
+			"kind": "insert", "schema": "public", "table": "other",
+			"columnnames": ["id"], "columntypes": ["integer"], "columntypeoids": [23], "columnvalues": [1]
+		}, {
+			"kind": "insert", "schema": "public", "table": "other",
+			"columnnames": ["id"], "columntypes": ["integer"], "columntypeoids": [23], "columnvalues": [3]
+		}]}`,
+		pgoutput: [][]byte{
+			streamStartMessage(104),
+			streamed(104, pgOutputTestOther),
+			streamed(104, insertMessage(16385, text("1"))),
+			streamed(105, insertMessage(16385, text("2"))),
+			streamStopMessage(),
+			// an aborted stream does not affect other transactions
+			streamStartMessage(106),
+			streamed(106, insertMessage(16385, text("10"))),
+			streamStopMessage(),
+			streamAbortMessage(106, 106),
+			// the aborted subtransaction is discarded
+			streamAbortMessage(104, 105),
+			streamStartMessage(104),
+			streamed(107, insertMessage(16385, text("3"))),
+			streamStopMessage(),
+			streamCommitMessage(104, pgOutputTestTime),
+		},
+	},
+}
+
+func testTypeNames(oid uint32, typmod int32) (string, error) {
+	name, ok := pgOutputTestTypes[oid]
+	if !ok {
+		return "", fmt.Errorf("unknown type %d", oid)
+	}
+	return name, nil
+}
+
+func parsePgOutput(t *testing.T, parser logicalParser, messages [][]byte) []*Wal2JSONItem {
+	var items []*Wal2JSONItem
+	for i, message := range messages {
+		parsed, err := parser.Parse(message, pglogrepl.LSN(i+1))
+		require.NoError(t, err)
+		require.Equal(t, i == len(messages)-1, parser.TransactionEnd(message))
+		items = append(items, parsed...)
+	}
+	return items
+}
+
+func parseWal2Json(t *testing.T, changeset string) []*Wal2JSONItem {
+	parser := wal2jsonLogicalParser{Wal2JsonParser: NewWal2JsonParser()}
+	defer parser.Close()
+	items, err := parser.Parse([]byte(changeset), 0)
+	require.NoError(t, err)
+	return items
+}
+
+// withoutPosition clears fields which depend on the encoding of changes
+func withoutPosition(items []*Wal2JSONItem) []Wal2JSONItem {
+	result := make([]Wal2JSONItem, 0, len(items))
+	for _, item := range items {
+		cleared := *item
+		cleared.LSN = 0
+		cleared.Counter = 0
+		cleared.Size = abstract.EventSize{Read: 0, Values: 0}
+		result = append(result, cleared)
+	}
+	return result
+}
+
+func pgOutputTestChangeProcessor() *changeProcessor {
+	cp := defaultChangeProcessor()
+	columns := make([]abstract.ColSchema, 0, len(pgOutputTestColumns))
+	for _, col := range pgOutputTestColumns {
+		colSchema := newColSchemaForType("pg:" + pgOutputTestTypes[col.oid])
+		colSchema.ColumnName = col.name
+		colSchema.PrimaryKey = col.key
+		columns = append(columns, *colSchema)
+	}
+	cp.schemasToEmit = abstract.DBSchema{
+		*abstract.NewTableID("public", "types"): abstract.NewTableSchema(columns),
+		*abstract.NewTableID("public", "other"): abstract.NewTableSchema(columns[:1]),
+	}
+	cp.fastSchemas = fastSchemasFromDBSchema(cp.schemasToEmit)
+	return cp
+}
+
+func toChangeItems(t *testing.T, cp *changeProcessor, items []*Wal2JSONItem) []abstract.ChangeItem {
+	require.NoError(t, validateChangeItemsPtrs(items))
+	result := make([]abstract.ChangeItem, 0, len(items))
+	for _, item := range items {
+		changeItem := item.toChangeItem()
+		require.NoError(t, cp.fixupChange(&changeItem, item.ColumnTypeOIDs, item.OldKeys.KeyTypeOids, 0, 0))
+		changeItem.Size = abstract.EventSize{Read: 0, Values: 0}
+		result = append(result, changeItem)
+	}
+	return result
+}
+
+func TestPgOutputMatchesWal2Json(t *testing.T) {
+	for _, fixture := range pluginFixtures {
+		t.Run(fixture.name, func(t *testing.T) {
+			parser := NewPgOutputParser(testTypeNames)
+			defer parser.Close()
+			pgOutputItems := parsePgOutput(t, parser, fixture.pgoutput)
+			wal2jsonItems := parseWal2Json(t, fixture.wal2json)
+			require.Equal(t, withoutPosition(wal2jsonItems), withoutPosition(pgOutputItems))
+
+			cp := pgOutputTestChangeProcessor()
+			require.Equal(t, toChangeItems(t, cp, wal2jsonItems), toChangeItems(t, cp, pgOutputItems))
+		})
+	}
+}
+
+func TestPgOutputPositions(t *testing.T) {
+	parser := NewPgOutputParser(testTypeNames)
+	defer parser.Close()
+
+	// changes of regular transactions are positioned by their messages
+	items := parsePgOutput(t, parser, pluginFixtures[3].pgoutput)
+	require.Len(t, items, 3)
+	require.Equal(t, []uint64{4, 5, 5}, []uint64{items[0].LSN, items[1].LSN, items[2].LSN})
+	require.Equal(t, []int{0, 0, 1}, []int{items[0].Counter, items[1].Counter, items[2].Counter})
+
+	// streamed changes are returned on commit only, with positions of their messages
+	messages := pluginFixtures[4].pgoutput
+	for i, message := range messages[:len(messages)-1] {
+		items, err := parser.Parse(message, pglogrepl.LSN(i+1))
+		require.NoError(t, err)
+		require.Empty(t, items)
+	}
+	items, err := parser.Parse(messages[len(messages)-1], pglogrepl.LSN(len(messages)))
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, []uint64{3, 12}, []uint64{items[0].LSN, items[1].LSN})
+	require.Equal(t, uint32(104), items[1].ID)
+	require.Equal(t, uint64(pgOutputTestTime.UnixNano()), items[1].CommitTime)
+	require.Empty(t, parser.streams)
+}
+
+func TestPgOutputUnknownRelation(t *testing.T) {
+	parser := NewPgOutputParser(testTypeNames)
+	defer parser.Close()
+	_, err := parser.Parse(insertMessage(1, text("1")), 1)
+	require.Error(t, err)
+}
+
+func TestPgOutputStreamAbortedSubtransaction(t *testing.T) {
+	parser := NewPgOutputParser(testTypeNames)
+	defer parser.Close()
+
+	// changes of the subtransaction 201 span two chunks of the stream and interleave with other changes
+	items := parsePgOutput(t, parser, [][]byte{
+		streamStartMessage(200),
+		streamed(200, pgOutputTestOther),
+		streamed(200, insertMessage(16385, text("1"))),
+		streamed(201, insertMessage(16385, text("2"))),
+		streamStopMessage(),
+		streamStartMessage(200),
+		streamed(201, insertMessage(16385, text("3"))),
+		streamed(202, insertMessage(16385, text("4"))),
+		streamStopMessage(),
+		streamAbortMessage(200, 201),
+		streamStartMessage(200),
+		streamed(200, insertMessage(16385, text("5"))),
+		streamStopMessage(),
+		streamCommitMessage(200, pgOutputTestTime),
+	})
+	var ids []any
+	for _, item := range items {
+		require.Equal(t, uint32(200), item.ID)
+		ids = append(ids, item.ColumnValues[0])
+	}
+	require.Equal(t, []any{json.Number("1"), json.Number("4"), json.Number("5")}, ids)
+	require.Equal(t, []uint64{3, 8, 12}, []uint64{items[0].LSN, items[1].LSN, items[2].LSN})
+	require.Empty(t, parser.streams)
+}
+
+func TestPgOutputArguments(t *testing.T) {
+	src := &PgSource{SlotID: "slot", Plugin: PgOutputPlugin}
+	require.Equal(t,
+		[]string{`"proto_version" '1'`, `"publication_names" '"slot"'`},
+		newPgOutputArguments(src).toReplicationFormat(),
+	)
+}
@@ -142,6 +142,11 @@ func (p *Provider) Activate(ctx context.Context, task *model.TransferOperation,
 	}
 	p.logger.Info("Preparing PostgreSQL source")
 	tracker := NewTracker(p.transfer.ID, p.cp)
+	if !p.transfer.SnapshotOnly() {
+		if err := CreatePublication(ctx, src, p.transfer.DataObjects, src.DBLogEnabled && !p.transfer.IncrementOnly()); err != nil {
+			return xerrors.Errorf("failed to create a publication %q at source: %w", PublicationName(src), err)
+		}
+	}
 	if src.DBLogEnabled && !p.transfer.IncrementOnly() { // if there are present SNAPSHOT stage with turned-on DBLog
 		if err := p.DBLogCreateSlotAndInit(ctx, tracker); err != nil {
 			return xerrors.Errorf("unable to init dblog, err: %w", err)
@@ -404,6 +409,10 @@ func (p *Provider) DBLogCreateSlotAndInit(ctx context.Context, tracker *Tracker)
 	if err != nil {
 		return xerrors.Errorf("unable to create signal table: %w", err)
 	}
+	// the signal table may be created just now, so it's added to the publication
+	if err := CreatePublication(ctx, src, p.transfer.DataObjects, true); err != nil {
+		return xerrors.Errorf("failed to add signal table to the publication: %w", err)
+	}
 	if !exists {
 		// delete previous watermarks - only if slot previously not existed. It existed - it's just dataplane restart
 		if err := dblog.DeleteWatermarks(ctx, pgStorage.Conn, src.KeeperSchema, p.transfer.ID); err != nil {
@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+// PublicationName is the name of the publication read by the pgoutput plugin, it's named after the replication slot
+func PublicationName(src *PgSource) string {
+	return src.SlotID
+}
+
+// CreatePublication creates the publication of tables included in the transfer or updates its list of tables.
+// pgoutput reads publications with the historic snapshot of the slot, so the publication must be created before the slot.
+// Tables which do not exist yet are skipped, the list is synced again when replication starts.
+func CreatePublication(ctx context.Context, src *PgSource, objects *model.DataObjects, dbLogSnapshot bool) error {
+	if src.SlotPlugin() != PgOutputPlugin {
+		return nil
+	}
+	conn, err := MakeConnPoolFromSrc(src, logger.Log)
+	if err != nil {
+		return xerrors.Errorf("failed to create a connection pool: %w", err)
+	}
+	defer conn.Close()
+	return createPublication(ctx, conn, src, objects, dbLogSnapshot)
+}
+
+func createPublication(ctx context.Context, conn *pgxpool.Pool, src *PgSource, objects *model.DataObjects, dbLogSnapshot bool) error {
+	include, err := addTablesList(src, objects, dbLogSnapshot)
+	if err != nil {
+		return xerrors.Errorf("failed to compose a list of included tables: %w", err)
+	}
+	var tables []abstract.TableID
+	if len(include) > 0 {
+		tables, err = publicationTables(ctx, conn, include)
+		if err != nil {
+			return xerrors.Errorf("failed to list tables of publication: %w", err)
+		}
+		if len(tables) == 0 {
+			return xerrors.Errorf("none of included tables %v exist", include)
+		}
+	}
+
+	name := PublicationName(src)
+	return doUnderTransaction(ctx, conn, func(ctx context.Context, tx pgx.Tx) error {
+		var allTables bool
+		err := tx.QueryRow(ctx, "SELECT puballtables FROM pg_catalog.pg_publication WHERE pubname = $1", name).Scan(&allTables)
+		exists := true
+		if xerrors.Is(err, pgx.ErrNoRows) {
+			exists = false
+		} else if err != nil {
+			return xerrors.Errorf("failed to check existence of publication %q: %w", name, err)
+		}
+
+		if exists && len(tables) > 0 && !allTables {
+			if _, err := tx.Exec(ctx, fmt.Sprintf("ALTER PUBLICATION %s SET TABLE %s", Sanitize(name), publicationTableList(tables))); err != nil {
+				return xerrors.Errorf("failed to update tables of publication %q: %w", name, err)
+			}
+			logger.Log.Info("Publication tables are updated", log.String("publication", name), log.Any("tables", tables))
+			return nil
+		}
+		if exists && len(tables) == 0 && allTables {
+			return nil
+		}
+		if exists {
+			// a publication cannot be switched between FOR ALL TABLES and a list of tables, it is recreated in the same transaction
+			if _, err := tx.Exec(ctx, fmt.Sprintf("DROP PUBLICATION %s", Sanitize(name))); err != nil {
+				return xerrors.Errorf("failed to drop publication %q: %w", name, err)
+			}
+		}
+		target := "FOR ALL TABLES"
+		if len(tables) > 0 {
+			target = "FOR TABLE " + publicationTableList(tables)
+		}
+		if _, err := tx.Exec(ctx, fmt.Sprintf("CREATE PUBLICATION %s %s", Sanitize(name), target)); err != nil {
+			return xerrors.Errorf("failed to create publication %q: %w", name, err)
+		}
+		logger.Log.Info("Publication is created", log.String("publication", name), log.Any("tables", tables))
+		return nil
+	}, logger.Log)
+}
+
+// publicationTables returns existing tables matched by the list, wildcards like `schema.*` are expanded
+func publicationTables(ctx context.Context, conn *pgxpool.Pool, include []abstract.TableID) ([]abstract.TableID, error) {
+	rows, err := conn.Query(ctx, `
+SELECT n.nspname, c.relname
+FROM pg_catalog.pg_class c
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind = 'r' AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+ORDER BY n.nspname, c.relname`)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var result []abstract.TableID
+	for rows.Next() {
+		var table abstract.TableID
+		if err := rows.Scan(&table.Namespace, &table.Name); err != nil {
+			return nil, xerrors.Errorf("failed to scan table: %w", err)
+		}
+		for _, directive := range include {
+			if directive.Includes(table) {
+				result = append(result, table)
+				break
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, xerrors.Errorf("failed to list tables: %w", err)
+	}
+	return result, nil
+}
+
+func publicationTableList(tables []abstract.TableID) string {
+	parts := make([]string, 0, len(tables))
+	for _, table := range tables {
+		parts = append(parts, pgx.Identifier{table.Namespace, table.Name}.Sanitize())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// DropPublication drops the publication of the pgoutput plugin, if any
+func DropPublication(ctx context.Context, conn *pgxpool.Pool, src *PgSource) error {
+	if src.SlotPlugin() != PgOutputPlugin {
+		return nil
+	}
+	if _, err := conn.Exec(ctx, fmt.Sprintf("DROP PUBLICATION IF EXISTS %s", Sanitize(PublicationName(src)))); err != nil {
+		return xerrors.Errorf("failed to drop publication %q: %w", PublicationName(src), err)
+	}
+	return nil
+}
+
+// newTypeNameResolver resolves names of types with format_type, they are equal to names wal2json writes for key columns
+func newTypeNameResolver(conn *pgxpool.Pool) TypeNameResolver {
+	type typeKey struct {
+		oid    uint32
+		typmod int32
+	}
+	cache := map[typeKey]string{}
+	return func(oid uint32, typmod int32) (string, error) {
+		key := typeKey{oid: oid, typmod: typmod}
+		if name, ok := cache[key]; ok {
+			return name, nil
+		}
+		var name string
+		if err := conn.QueryRow(context.Background(), "SELECT pg_catalog.format_type($1, $2)", oid, typmod).Scan(&name); err != nil {
+			return "", xerrors.Errorf("failed to format type %d(%d): %w", oid, typmod, err)
+		}
+		cache[key] = name
+		return name, nil
+	}
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -15,8 +16,14 @@ import (
 	"go.ytsaurus.tech/library/go/core/log"
 )
 
+// Plugin is the logical decoding output plugin of the replication slot
 type Plugin string
 
+const (
+	Wal2JSONPlugin = Plugin("wal2json")
+	PgOutputPlugin = Plugin("pgoutput")
+)
+
 type argument struct {
 	name, value string
 }
@@ -29,9 +36,10 @@ var commonWal2jsonArguments = []argument{
 	{name: "write-in-chunks", value: "1"},
 }
 
-type wal2jsonArguments []argument
+// pluginArguments are options of the logical decoding plugin
+type pluginArguments []argument
 
-func newWal2jsonArguments(config *PgSource, objects *model.DataObjects, dbLogSnapshot bool) (wal2jsonArguments, error) {
+func newWal2jsonArguments(config *PgSource, objects *model.DataObjects, dbLogSnapshot bool) (pluginArguments, error) {
 	var result []argument
 
 	result = append(result, commonWal2jsonArguments...)
@@ -55,7 +63,7 @@ func newWal2jsonArguments(config *PgSource, objects *model.DataObjects, dbLogSna
 	return result, nil
 }
 
-func (a wal2jsonArguments) toReplicationFormat() []string {
+func (a pluginArguments) toReplicationFormat() []string {
 	var result []string
 	for _, arg := range a {
 		result = append(result, fmt.Sprintf(`"%s" '%s'`, arg.name, arg.value))
@@ -63,7 +71,7 @@ func (a wal2jsonArguments) toReplicationFormat() []string {
 	return result
 }
 
-func (a wal2jsonArguments) toSQLFormat() string {
+func (a pluginArguments) toSQLFormat() string {
 	var result string
 	for i, arg := range a {
 		if i != 0 {
@@ -218,16 +226,28 @@ func newWalSource(config *PgSource, objects *model.DataObjects, transferID strin
 		return nil, xerrors.Errorf("error resolving pg version: %w", err)
 	}
 
-	wal2jsonArgs, err := newWal2jsonArguments(config, objects, dbLogSnapshot)
-	if err != nil {
-		return nil, xerrors.Errorf("failed to build wal2json arguments: %w", err)
+	var pluginArgs pluginArguments
+	if config.SlotPlugin() == PgOutputPlugin {
+		if version.Is9x {
+			return nil, abstract.NewFatalError(xerrors.New("pgoutput plugin requires PostgreSQL 10 or later"))
+		}
+		if err := CreatePublication(context.Background(), config, objects, dbLogSnapshot); err != nil {
+			return nil, xerrors.Errorf("failed to sync publication: %w", err)
+		}
+		pluginArgs = newPgOutputArguments(config)
+	} else {
+		pluginArgs, err = newWal2jsonArguments(config, objects, dbLogSnapshot)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to build wal2json arguments: %w", err)
+		}
 	}
 
 	// Polling is workaround for cases where replication connection is not available for some reason
 	// There should be no such cases for managed clusters
-	canFallbackToPolling := config.ClusterID == ""
+	// Polling reads changes with wal2json SQL functions, so it is not available for pgoutput
+	canFallbackToPolling := config.ClusterID == "" && config.SlotPlugin() == Wal2JSONPlugin
 	if !config.UsePolling {
-		publisher, err := newReplicationPublisher(version, connConfig, slot, wal2jsonArgs, registry, config, transferID, lgr, cp, objects)
+		publisher, err := newReplicationPublisher(version, connConfig, slot, pluginArgs, registry, config, transferID, lgr, cp, objects)
 		if err == nil {
 			return publisher, nil
 		}
@@ -237,7 +257,7 @@ func newWalSource(config *PgSource, objects *model.DataObjects, transferID strin
 		lgr.Warn("Cannot establish replication connection; falling back to polling", log.Error(err))
 	}
 
-	return newPollingPublisher(version, connConfig, slot, wal2jsonArgs, registry, config, objects, transferID, lgr, cp)
+	return newPollingPublisher(version, connConfig, slot, pluginArgs, registry, config, objects, transferID, lgr, cp)
 }
 
 func resolveVersion(connConfig *pgx.ConnConfig) (PgVersion, error) {
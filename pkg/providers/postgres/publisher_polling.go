@@ -37,7 +37,7 @@ type poller struct {
 	once            sync.Once
 	config          *PgSource
 	transferID      string
-	wal2jsonArgs    wal2jsonArguments
+	wal2jsonArgs    pluginArguments
 	wg              sync.WaitGroup
 	slotMonitor     *SlotMonitor
 	slot            AbstractSlot
@@ -542,7 +542,7 @@ func newPollingPublisher(
 	version PgVersion,
 	connConfig *pgx.ConnConfig,
 	slot AbstractSlot,
-	wal2jsonArgs wal2jsonArguments,
+	wal2jsonArgs pluginArguments,
 	registry *stats.SourceStats,
 	cfg *PgSource,
 	objects *model.DataObjects,
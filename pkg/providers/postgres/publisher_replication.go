@@ -32,7 +32,7 @@ type replication struct {
 	conn            *pgxpool.Pool
 	replConn        *mutexedPgConn
 	metrics         *stats.SourceStats
-	wal2jsonParser  *Wal2JsonParser
+	parser          logicalParser
 	error           chan error
 	once            sync.Once
 	config          *PgSource
@@ -60,6 +60,34 @@ type replication struct {
 	skippedTables map[abstract.TableID]bool
 }
 
+// logicalParser decodes messages of the logical decoding plugin into wal2json items, with positions of changes set
+type logicalParser interface {
+	Parse(data []byte, lsn pglogrepl.LSN) ([]*Wal2JSONItem, error)
+	// TransactionEnd checks if the message is the last one of a transaction
+	TransactionEnd(data []byte) bool
+	Close()
+}
+
+type wal2jsonLogicalParser struct {
+	*Wal2JsonParser
+}
+
+func (p wal2jsonLogicalParser) Parse(data []byte, lsn pglogrepl.LSN) ([]*Wal2JSONItem, error) {
+	items, err := p.Wal2JsonParser.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range items {
+		item.LSN = uint64(lsn)
+		item.Counter = i
+	}
+	return items, nil
+}
+
+func (p wal2jsonLogicalParser) TransactionEnd(data []byte) bool {
+	return string(data) == "]}"
+}
+
 var pgFatalCode = map[string]bool{
 	"XX000": true, // TM-1332
 	"58P01": true, // TM-2082
@@ -109,7 +137,7 @@ func (p *replication) Stop() {
 		}
 		p.wg.Wait()
 		p.slotMonitor.Close()
-		p.wal2jsonParser.Close()
+		p.parser.Close()
 		p.conn.Close()
 		p.parseQ.Close()
 	})
@@ -339,7 +367,7 @@ func (p *replication) receiver(slotTroubleCh <-chan error) {
 			p.metrics.Count.Inc()
 			p.metrics.DelayTime.RecordDuration(time.Since(xld.ServerTime))
 
-			transactionComplete := string(xld.WALData) == "]}"
+			transactionComplete := p.parser.TransactionEnd(xld.WALData)
 			shouldFlush := (bufferSize > BufferLimit) || transactionComplete
 
 			if !parsed && bufferSize > BufferLimit {
@@ -418,10 +446,10 @@ func (p *replication) receiver(slotTroubleCh <-chan error) {
 
 func (p *replication) parseWal2JsonChanges(cp *changeProcessor, xld *pglogrepl.XLogData) ([]abstract.ChangeItem, error) {
 	st := time.Now()
-	items, err := p.wal2jsonParser.Parse(xld.WALData)
+	items, err := p.parser.Parse(xld.WALData, xld.WALStart)
 	if err != nil {
-		logger.Log.Error("Cannot parse wal2json message", log.Error(err), log.String("data", walDataSample(xld.WALData)))
-		return nil, xerrors.Errorf("Cannot parse wal2json message: %w", err)
+		logger.Log.Error("Cannot parse logical decoding message", log.Error(err), log.String("data", walDataSample(xld.WALData)))
+		return nil, xerrors.Errorf("Cannot parse %s message: %w", p.config.SlotPlugin(), err)
 	}
 	objIncleadable, err := abstract.BuildIncludeMap(p.objects.GetIncludeObjects())
 	if err != nil {
@@ -433,7 +461,7 @@ func (p *replication) parseWal2JsonChanges(cp *changeProcessor, xld *pglogrepl.X
 		return nil, err
 	}
 	changes := make([]abstract.ChangeItem, 0, 1)
-	for i, item := range items {
+	for _, item := range items {
 		changeItem := item.toChangeItem()
 		if err := abstract.ValidateChangeItem(&changeItem); err != nil {
 			logger.Log.Error(err.Error())
@@ -470,7 +498,7 @@ func (p *replication) parseWal2JsonChanges(cp *changeProcessor, xld *pglogrepl.X
 				continue
 			}
 		}
-		if err := cp.fixupChange(&changeItem, item.ColumnTypeOIDs, item.OldKeys.KeyTypeOids, i, xld.WALStart); err != nil {
+		if err := cp.fixupChange(&changeItem, item.ColumnTypeOIDs, item.OldKeys.KeyTypeOids, item.Counter, pglogrepl.LSN(item.LSN)); err != nil {
 			//nolint:descriptiveerrors
 			return nil, err
 		}
@@ -492,7 +520,7 @@ func newReplicationPublisher(
 	version PgVersion,
 	connConfig *pgx.ConnConfig,
 	slot AbstractSlot,
-	wal2jsonArgs wal2jsonArguments,
+	pluginArgs pluginArguments,
 	stats *stats.SourceStats,
 	source *PgSource,
 	transferID string,
@@ -502,7 +530,7 @@ func newReplicationPublisher(
 ) (abstract.Source, error) {
 	var rb util.Rollbacks
 	defer rb.Do()
-	replConn, err := startReplication(connConfig, version, source.SlotID, wal2jsonArgs, lgr)
+	replConn, err := startReplication(connConfig, version, source.SlotID, pluginArgs, lgr)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to start replication: %w", err)
 	}
@@ -515,6 +543,10 @@ func newReplicationPublisher(
 	if err != nil {
 		return nil, xerrors.Errorf("unable to create conn pool: %w", err)
 	}
+	var parser logicalParser = wal2jsonLogicalParser{Wal2JsonParser: NewWal2JsonParser()}
+	if source.SlotPlugin() == PgOutputPlugin {
+		parser = NewPgOutputParser(newTypeNameResolver(connPool))
+	}
 
 	rb.Cancel()
 	ctx, cancel := context.WithCancel(context.Background())
@@ -523,7 +555,7 @@ func newReplicationPublisher(
 		conn:            connPool,
 		replConn:        replConn,
 		metrics:         stats,
-		wal2jsonParser:  NewWal2JsonParser(),
+		parser:          parser,
 		error:           make(chan error, 1),
 		once:            sync.Once{},
 		config:          source,
@@ -556,7 +588,7 @@ func startReplication(
 	connConfig *pgx.ConnConfig,
 	version PgVersion,
 	slotName string,
-	wal2jsonArgs wal2jsonArguments,
+	pluginArgs pluginArguments,
 	lgr log.Logger,
 ) (*mutexedPgConn, error) {
 	return backoff.RetryNotifyWithData(func() (*mutexedPgConn, error) {
@@ -578,11 +610,11 @@ func startReplication(
 			}
 		})
 
-		lgr.Infof("Start replication process with args: %v", wal2jsonArgs)
+		lgr.Infof("Start replication process with args: %v", pluginArgs)
 		err = rConn.StartReplication(context.Background(), slotName, 0, pglogrepl.StartReplicationOptions{
 			Timeline:   -1,
 			Mode:       pglogrepl.LogicalReplication,
-			PluginArgs: wal2jsonArgs.toReplicationFormat(),
+			PluginArgs: pluginArgs.toReplicationFormat(),
 		})
 		if err != nil {
 			defer lgr.Warn("Cannot start replication via replication connection", log.Error(err))
@@ -86,6 +86,7 @@ type sink struct {
 	transferID          string
 	lsnTrack            map[abstract.TableID]uint64
 	pendingTableCounts  map[abstract.TableID]int
+	version             PgVersion
 }
 
 func (s *sink) Close() error {
@@ -346,6 +347,7 @@ type txBatch struct {
 	txID           uint32
 	lsn            uint64
 	queries        []string
+	rows           []abstract.ChangeItem // row items to be applied through a staging table, see BulkUpsert
 	size           int
 	tableRowCounts map[abstract.TableID]int
 }
@@ -385,6 +387,13 @@ func (s *sink) runTX(batch txBatch) error {
 		}
 		s.currentTXID = batch.txID
 	}
+	if len(batch.rows) > 0 {
+		bulkCtx, bulkCtxCancel := context.WithTimeout(context.Background(), s.config.QueryTimeout())
+		defer bulkCtxCancel()
+		if err := s.bulkUpsertTables(bulkCtx, s.currentTX, batch.rows); err != nil {
+			return xerrors.Errorf("unable to upsert rows for tx: %v: %w", batch.txID, err)
+		}
+	}
 	txQuery := batch.txQuery(s.transferID)
 	s.logger.Infof("execute tx%v query: \n%v", batch.txID, util.Sample(txQuery, 1000))
 	_, err = s.currentTX.Exec(context.TODO(), txQuery)
@@ -423,6 +432,7 @@ func (s *sink) perTransactionPush(input []abstract.ChangeItem) error {
 		size := 0
 		tableRowCounts := map[abstract.TableID]int{}
 		queries := make([]string, len(batch))
+		var rows []abstract.ChangeItem
 		for i, r := range batch {
 			if r.LSN > 0 && r.ID > 0 {
 				if savedLSN, ok := s.lsnTrack[r.TableID()]; ok && savedLSN >= r.LSN {
@@ -441,6 +451,11 @@ func (s *sink) perTransactionPush(input []abstract.ChangeItem) error {
 				continue
 			}
 			s.metrics.Table(r.PgName(), "rows", 1)
+			if s.config.BulkUpsert() {
+				rows = append(rows, r)
+				tableRowCounts[r.TableID()]++
+				continue
+			}
 			queries[i], err = s.buildQuery(r.PgName(), r.TableSchema.Columns(), batch[i:i+1])
 			if err != nil {
 				return xerrors.Errorf("unable to build query: %w", err)
@@ -448,12 +463,13 @@ func (s *sink) perTransactionPush(input []abstract.ChangeItem) error {
 			size += len(queries[i])
 			tableRowCounts[r.TableID()]++
 		}
-		if size > 0 {
+		if size > 0 || len(rows) > 0 {
 			txs = append(txs, txBatch{
 				txID:           batch[0].ID,
 				lsn:            batch[len(batch)-1].LSN,
 				size:           size,
 				queries:        queries,
+				rows:           rows,
 				tableRowCounts: tableRowCounts,
 			})
 		}
@@ -481,6 +497,7 @@ func (s *sink) perTransactionPush(input []abstract.ChangeItem) error {
 		for _, tx := range txs[0 : len(txs)-1] {
 			squashedTXs = append(squashedTXs, tx.txID)
 			inBetweenTX.queries = append(inBetweenTX.queries, tx.queries...)
+			inBetweenTX.rows = append(inBetweenTX.rows, tx.rows...)
 			inBetweenTX.txID = tx.txID
 			inBetweenTX.lsn = tx.lsn
 			for table, count := range tx.tableRowCounts {
@@ -609,6 +626,19 @@ func (s *sink) batchInsert(input []abstract.ChangeItem) error {
 				continue
 			}
 		}
+		if s.config.BulkUpsert() && s.isTableWithKeys(pgTable) {
+			bulkCtx, bulkCtxCancel := context.WithTimeout(context.Background(), s.config.QueryTimeout())
+			defer bulkCtxCancel()
+			if err := s.bulkUpsertInTX(bulkCtx, pgTable, tableSchema, batch); err != nil {
+				if s.config.DisableSQLFallback() {
+					return abstract.NewFatalError(xerrors.Errorf("bulk upsert through staging table failed: %w; SQL fallback is disabled", err))
+				}
+				s.logger.Warn("Bulk upsert through staging table failed. Will retry using common INSERT", log.Error(err))
+			} else {
+				s.metrics.Table(table, "rows", len(batch))
+				continue
+			}
+		}
 		insertCtx, insertCtxCancel := context.WithTimeout(context.Background(), s.config.QueryTimeout())
 		defer insertCtxCancel()
 		if err := s.insert(insertCtx, pgTable, tableSchema, batch); err != nil {
@@ -1208,6 +1238,7 @@ func NewSinkWithPool(ctx context.Context, lgr log.Logger, transferID string, con
 		transferID:          transferID,
 		lsnTrack:            map[abstract.TableID]uint64{},
 		pendingTableCounts:  map[abstract.TableID]int{},
+		version:             NewPgVersion("unknown"),
 	}
 
 	if config.PerTransactionPush() {
@@ -1220,6 +1251,9 @@ func NewSinkWithPool(ctx context.Context, lgr log.Logger, transferID string, con
 		}
 		result.lsnTrack = lsnTrack
 	}
+	if config.BulkUpsert() {
+		result.version = ResolveVersion(pool)
+	}
 
 	return result, nil
 }
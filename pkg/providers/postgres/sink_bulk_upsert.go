@@ -0,0 +1,361 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const (
+	bulkStageTable   = "__transferia_bulk_stage"
+	bulkDeletedCol   = "__transferia_deleted"
+	bulkUnchangedCol = "__transferia_unchanged"
+)
+
+// bulkBatch is a collapsed batch of a single table, prepared to be COPY'd into a staging table
+// and applied to the target table with a single statement.
+type bulkBatch struct {
+	table string
+	// columns are the names of non-generated columns, in the order of the table schema
+	columns []string
+	// keys are the quoted names of the columns of the target table unique key
+	keys []string
+	// rows are staging table rows: the deleted flag, the list of unchanged (TOASTed) columns and the values of columns
+	rows [][]interface{}
+	// toasted are the columns which are unchanged in at least one of the rows
+	toasted map[string]bool
+	// perRow are the items which cannot be staged and must be applied with per-row queries before the staged rows
+	perRow []abstract.ChangeItem
+}
+
+// supportsMerge returns true if the MERGE statement is available, i.e. the server is PostgreSQL 15+
+func supportsMerge(version PgVersion) bool {
+	return strings.HasPrefix(version.Version, "PostgreSQL ") &&
+		!version.Is9x && !version.Is10x && !version.Is11x && !version.Is12x && !version.Is13x && !version.Is14x
+}
+
+// prepareBulkBatch distributes the given collapsed items of a single table into staging rows and per-row items.
+// Updates changing the key are left for per-row queries, as well as inserts which do not contain all columns.
+// Updates which do not contain all columns (TOASTed) are staged along with the list of their unchanged columns,
+// so that these columns keep their current values in the target table.
+func (s *sink) prepareBulkBatch(table string, schema []abstract.ColSchema, items []abstract.ChangeItem) (*bulkBatch, error) {
+	generatedCols := s.getGeneratedCols(schema)
+	batch := &bulkBatch{
+		table:   table,
+		columns: make([]string, 0, len(schema)),
+		keys:    s.keys[table],
+		rows:    make([][]interface{}, 0, len(items)),
+		toasted: map[string]bool{},
+		perRow:  nil,
+	}
+	colIDXs := map[string]int{}
+	for _, col := range schema {
+		if generatedCols[col.ColumnName] {
+			continue
+		}
+		colIDXs[col.ColumnName] = len(batch.columns)
+		batch.columns = append(batch.columns, col.ColumnName)
+	}
+	for _, key := range batch.keys {
+		if _, ok := colIDXs[strings.Trim(key, "\"")]; !ok {
+			return nil, xerrors.Errorf("key column %s of table %s is not present in the source table schema", key, table)
+		}
+	}
+	if len(items) == 0 {
+		return batch, nil
+	}
+
+	keyCols := items[0].MakeMapKeys()
+	upsertKeys := map[string]bool{}
+	var deletes []abstract.ChangeItem
+	for _, item := range items {
+		switch item.Kind {
+		case abstract.DeleteKind:
+			deletes = append(deletes, item)
+			continue
+		case abstract.InsertKind, abstract.UpdateKind:
+		default:
+			continue
+		}
+		if item.Kind == abstract.UpdateKind && item.KeysChanged() {
+			batch.perRow = append(batch.perRow, item)
+			continue
+		}
+		row := make([]interface{}, len(batch.columns)+2)
+		present := map[string]bool{}
+		for i, colName := range item.ColumnNames {
+			if generatedCols[colName] {
+				continue
+			}
+			idx, ok := colIDXs[colName]
+			if !ok {
+				return nil, xerrors.Errorf("column %q is not present in the schema of table %s", colName, table)
+			}
+			row[idx+2] = item.ColumnValues[i]
+			present[colName] = true
+		}
+		unchanged := []string{}
+		for _, colName := range batch.columns {
+			if !present[colName] {
+				unchanged = append(unchanged, colName)
+			}
+		}
+		if len(unchanged) > 0 && item.Kind != abstract.UpdateKind {
+			batch.perRow = append(batch.perRow, item)
+			continue
+		}
+		for _, colName := range unchanged {
+			batch.toasted[colName] = true
+		}
+		row[0] = false
+		row[1] = unchanged
+		batch.rows = append(batch.rows, row)
+		upsertKeys[item.CurrentKeysString(keyCols)] = true
+	}
+	for _, item := range deletes {
+		if upsertKeys[item.OldOrCurrentKeysString(keyCols)] {
+			// the row is deleted and then inserted again within the batch
+			continue
+		}
+		if len(item.OldKeys.KeyNames) == 0 {
+			s.logger.Errorf("no key values for delete statement in change item:\n%s", item.ToJSONString())
+			return nil, abstract.NewFatalError(xerrors.New("Unable to stage a deleted row, no key names presented"))
+		}
+		row := make([]interface{}, len(batch.columns)+2)
+		for i, keyName := range item.OldKeys.KeyNames {
+			idx, ok := colIDXs[keyName]
+			if !ok {
+				return nil, xerrors.Errorf("Key \"%v\" not found in source table schema", keyName)
+			}
+			row[idx+2] = item.OldKeys.KeyValues[i]
+		}
+		row[0] = true
+		row[1] = []string(nil)
+		batch.rows = append(batch.rows, row)
+	}
+	return batch, nil
+}
+
+func (b *bulkBatch) stageColumns() []string {
+	return append([]string{bulkDeletedCol, bulkUnchangedCol}, b.columns...)
+}
+
+func quoteColumns(columns []string, prefix string) []string {
+	result := make([]string, len(columns))
+	for i, col := range columns {
+		result[i] = fmt.Sprintf("%v\"%v\"", prefix, col)
+	}
+	return result
+}
+
+// stageQuery creates a temporary staging table with the types of the target table columns, but without any constraints
+func (b *bulkBatch) stageQuery() string {
+	return fmt.Sprintf(
+		"create temp table \"%v\" as select null::boolean as \"%v\", null::text[] as \"%v\", %v from %v with no data;",
+		bulkStageTable,
+		bulkDeletedCol,
+		bulkUnchangedCol,
+		strings.Join(quoteColumns(b.columns, ""), ", "),
+		b.table,
+	)
+}
+
+func (b *bulkBatch) joinCondition() string {
+	conditions := make([]string, len(b.keys))
+	for i, key := range b.keys {
+		conditions[i] = fmt.Sprintf("t.%v = s.%v", key, key)
+	}
+	return strings.Join(conditions, " and ")
+}
+
+// setters keep TOASTed columns of the target table unchanged
+func (b *bulkBatch) setters() string {
+	setters := make([]string, len(b.columns))
+	for i, col := range b.columns {
+		if b.toasted[col] {
+			setters[i] = fmt.Sprintf(
+				"\"%v\" = case when '%v' = any(s.\"%v\") then t.\"%v\" else s.\"%v\" end",
+				col, escapeSingleQuotes(col), bulkUnchangedCol, col, col,
+			)
+		} else {
+			setters[i] = fmt.Sprintf("\"%v\" = s.\"%v\"", col, col)
+		}
+	}
+	return strings.Join(setters, ", ")
+}
+
+// applyQueries returns the statements applying the staged rows to the target table:
+// a single MERGE if it is supported, and DELETE ... USING + INSERT ... ON CONFLICT otherwise.
+func (b *bulkBatch) applyQueries(mergeSupported bool) []string {
+	columns := strings.Join(quoteColumns(b.columns, ""), ", ")
+	stageColumns := strings.Join(quoteColumns(b.columns, "s."), ", ")
+	if mergeSupported {
+		return []string{fmt.Sprintf(
+			"merge into %v as t using \"%v\" as s on %v"+
+				" when matched and s.\"%v\" then delete"+
+				" when matched then update set %v"+
+				" when not matched and not s.\"%v\" and cardinality(s.\"%v\") = 0 then insert (%v) values (%v);",
+			b.table, bulkStageTable, b.joinCondition(),
+			bulkDeletedCol,
+			b.setters(),
+			bulkDeletedCol, bulkUnchangedCol, columns, stageColumns,
+		)}
+	}
+
+	queries := []string{fmt.Sprintf(
+		"delete from %v as t using \"%v\" as s where s.\"%v\" and %v;",
+		b.table, bulkStageTable, bulkDeletedCol, b.joinCondition(),
+	)}
+	if len(b.toasted) > 0 {
+		queries = append(queries, fmt.Sprintf(
+			"update %v as t set %v from \"%v\" as s where not s.\"%v\" and cardinality(s.\"%v\") > 0 and %v;",
+			b.table, b.setters(), bulkStageTable, bulkDeletedCol, bulkUnchangedCol, b.joinCondition(),
+		))
+	}
+	queries = append(queries, fmt.Sprintf(
+		"insert into %v (%v) select %v from \"%v\" as s where not s.\"%v\" and cardinality(s.\"%v\") = 0"+
+			" on conflict (%v) do update set (%v)=row(%v);",
+		b.table, columns, stageColumns, bulkStageTable, bulkDeletedCol, bulkUnchangedCol,
+		strings.Join(b.keys, ", "), columns, strings.Join(quoteColumns(b.columns, "excluded."), ", "),
+	))
+	return queries
+}
+
+// bulkUpsert applies the given items of a single table within the given transaction:
+// collapsed items are COPY'd into a temporary staging table and applied with a single statement.
+func (s *sink) bulkUpsert(ctx context.Context, tx pgx.Tx, table string, schema []abstract.ColSchema, items []abstract.ChangeItem) error {
+	batch, err := s.prepareBulkBatch(table, schema, items)
+	if err != nil {
+		return xerrors.Errorf("failed to prepare staging rows: %w", err)
+	}
+
+	execStart := time.Now()
+	if len(batch.perRow) > 0 {
+		// updates changing the key go first, as the rows with their new keys may be staged as well
+		queries, err := s.buildQueries(table, schema, batch.perRow)
+		if err != nil {
+			return xerrors.Errorf("failed to build queries for %d items which cannot be staged: %w", len(batch.perRow), err)
+		}
+		if _, err := tx.Exec(ctx, strings.Join(queries, "\n")); err != nil {
+			return xerrors.Errorf("failed to execute %d queries for items which cannot be staged: %w", len(queries), err)
+		}
+	}
+	if len(batch.rows) == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, batch.stageQuery()); err != nil {
+		return xerrors.Errorf("failed to create staging table for %s: %w", table, err)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{bulkStageTable}, batch.stageColumns(), pgx.CopyFromRows(batch.rows)); err != nil {
+		return xerrors.Errorf("failed to COPY %d rows into staging table for %s: %w", len(batch.rows), table, err)
+	}
+	for _, query := range batch.applyQueries(supportsMerge(s.version)) {
+		if _, err := tx.Exec(ctx, query); err != nil {
+			return xerrors.Errorf("failed to apply staging table to %s: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec(ctx, fmt.Sprintf("drop table \"%v\";", bulkStageTable)); err != nil {
+		return xerrors.Errorf("failed to drop staging table for %s: %w", table, err)
+	}
+	s.logger.Info("applied batch through staging table", log.String("table", table), log.Int("staged", len(batch.rows)), log.Int("per_row", len(batch.perRow)), log.Duration("elapsed", time.Since(execStart)))
+	return nil
+}
+
+// bulkUpsertTables applies the given row items of possibly several tables within the given transaction
+func (s *sink) bulkUpsertTables(ctx context.Context, tx pgx.Tx, items []abstract.ChangeItem) error {
+	var tables []string
+	batches := map[string][]abstract.ChangeItem{}
+	for _, item := range items {
+		if _, ok := batches[item.PgName()]; !ok {
+			tables = append(tables, item.PgName())
+		}
+		batches[item.PgName()] = append(batches[item.PgName()], item)
+	}
+	for _, table := range tables {
+		batch := batches[table]
+		schema := batch[0].TableSchema.Columns()
+		if err := prepareOriginalTypes(schema); err != nil {
+			return xerrors.Errorf("unable to prepare original types of table %s: %w", table, err)
+		}
+		if !s.isTableWithKeys(table) {
+			if err := s.execQueriesInTX(ctx, tx, table, schema, batch); err != nil {
+				return xerrors.Errorf("unable to exec queries for table %s without keys: %w", table, err)
+			}
+			continue
+		}
+		batch = abstract.Collapse(batch)
+		if err := s.bulkUpsertInSavepoint(ctx, tx, table, schema, batch); err != nil {
+			if s.config.DisableSQLFallback() {
+				return abstract.NewFatalError(xerrors.Errorf("bulk upsert through staging table failed: %w; SQL fallback is disabled", err))
+			}
+			s.logger.Warn("Bulk upsert through staging table failed. Will retry using common INSERT", log.String("table", table), log.Error(err))
+			if err := s.execQueriesInTX(ctx, tx, table, schema, batch); err != nil {
+				return xerrors.Errorf("unable to exec queries for %d items of %s: %w", len(batch), table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// bulkUpsertInSavepoint applies the given items of a single table within a savepoint of the given transaction,
+// so that the transaction stays usable if the staging table cannot be applied
+func (s *sink) bulkUpsertInSavepoint(ctx context.Context, tx pgx.Tx, table string, schema []abstract.ColSchema, items []abstract.ChangeItem) error {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return xerrors.Errorf("unable to create savepoint: %w", err)
+	}
+	if err := s.bulkUpsert(ctx, savepoint, table, schema, items); err != nil {
+		if rollbackErr := savepoint.Rollback(ctx); rollbackErr != nil {
+			return xerrors.Errorf("unable to rollback to savepoint: %v, after bulk upsert failure: %w", rollbackErr, err)
+		}
+		//nolint:descriptiveerrors
+		return err
+	}
+	if err := savepoint.Commit(ctx); err != nil {
+		return xerrors.Errorf("unable to release savepoint: %w", err)
+	}
+	return nil
+}
+
+// execQueriesInTX applies the given items of a single table with per-row queries within the given transaction
+func (s *sink) execQueriesInTX(ctx context.Context, tx pgx.Tx, table string, schema []abstract.ColSchema, items []abstract.ChangeItem) error {
+	queries, err := s.buildQueries(table, schema, items)
+	if err != nil {
+		return xerrors.Errorf("unable to build queries: %w", err)
+	}
+	if _, err := tx.Exec(ctx, strings.Join(queries, "\n")); err != nil {
+		return xerrors.Errorf("unable to exec %d queries: %w", len(queries), err)
+	}
+	return nil
+}
+
+// bulkUpsertInTX applies the given items of a single table within a separate transaction
+func (s *sink) bulkUpsertInTX(ctx context.Context, table string, schema []abstract.ColSchema, items []abstract.ChangeItem) error {
+	if err := prepareOriginalTypes(schema); err != nil {
+		return err
+	}
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return xerrors.Errorf("unable to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !xerrors.Is(err, pgx.ErrTxClosed) {
+			s.logger.Warn("Failed to rollback the bulk upsert transaction", log.Error(err))
+		}
+	}()
+	if err := s.bulkUpsert(ctx, tx, table, schema, items); err != nil {
+		//nolint:descriptiveerrors
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return xerrors.Errorf("unable to commit transaction: %w", err)
+	}
+	return nil
+}
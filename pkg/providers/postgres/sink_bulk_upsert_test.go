@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/pkg/abstract"
+)
+
+func TestBulkUpsert(t *testing.T) {
+	schema := []abstract.ColSchema{
+		{ColumnName: "id", DataType: "int64", OriginalType: "pg:bigint", PrimaryKey: true},
+		{ColumnName: "name", DataType: "utf8", OriginalType: "pg:text"},
+		{ColumnName: "payload", DataType: "utf8", OriginalType: "pg:text"},
+		{ColumnName: "name_length", DataType: "int64", OriginalType: "pg:bigint", Expression: "length(name)"},
+	}
+	tableSchema := abstract.NewTableSchema(schema)
+	oldKeys := func(id int64) abstract.OldKeysType {
+		return abstract.OldKeysType{KeyNames: []string{"id"}, KeyTypes: nil, KeyValues: []interface{}{id}}
+	}
+	items := []abstract.ChangeItem{
+		{Kind: abstract.InsertKind, Schema: "public", Table: "t", ColumnNames: []string{"id", "name", "payload", "name_length"}, ColumnValues: []interface{}{int64(1), "a", "x", int64(1)}, TableSchema: tableSchema},
+		{Kind: abstract.UpdateKind, Schema: "public", Table: "t", ColumnNames: []string{"id", "name"}, ColumnValues: []interface{}{int64(2), "b"}, OldKeys: oldKeys(2), TableSchema: tableSchema},
+		{Kind: abstract.UpdateKind, Schema: "public", Table: "t", ColumnNames: []string{"id", "name", "payload"}, ColumnValues: []interface{}{int64(30), "c", "z"}, OldKeys: oldKeys(3), TableSchema: tableSchema},
+		{Kind: abstract.DeleteKind, Schema: "public", Table: "t", OldKeys: oldKeys(4), TableSchema: tableSchema},
+		{Kind: abstract.DeleteKind, Schema: "public", Table: "t", OldKeys: oldKeys(5), TableSchema: tableSchema},
+		{Kind: abstract.InsertKind, Schema: "public", Table: "t", ColumnNames: []string{"id", "name", "payload"}, ColumnValues: []interface{}{int64(5), "e", "w"}, TableSchema: tableSchema},
+	}
+
+	sink := new(sink)
+	sink.config = (&PgDestination{BulkUpsert: true}).ToSinkParams()
+	sink.logger = logger.Log
+	sink.keys = map[string][]string{`"public"."t"`: {`"id"`}}
+
+	batch, err := sink.prepareBulkBatch(`"public"."t"`, schema, abstract.Collapse(items))
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "name", "payload"}, batch.columns)
+	require.Equal(t, []string{bulkDeletedCol, bulkUnchangedCol, "id", "name", "payload"}, batch.stageColumns())
+	require.Equal(t, [][]interface{}{
+		{false, []string{}, int64(1), "a", "x"},
+		{false, []string{"payload"}, int64(2), "b", nil},
+		{false, []string{}, int64(5), "e", "w"},
+		{true, []string(nil), int64(4), nil, nil},
+	}, batch.rows)
+	require.Equal(t, map[string]bool{"payload": true}, batch.toasted)
+	require.Len(t, batch.perRow, 1)
+	require.Equal(t, int64(30), batch.perRow[0].ColumnValues[0])
+
+	require.Equal(t,
+		`create temp table "__transferia_bulk_stage" as select null::boolean as "__transferia_deleted", null::text[] as "__transferia_unchanged", "id", "name", "payload" from "public"."t" with no data;`,
+		batch.stageQuery(),
+	)
+
+	t.Run("merge", func(t *testing.T) {
+		require.Equal(t, []string{
+			`merge into "public"."t" as t using "__transferia_bulk_stage" as s on t."id" = s."id"` +
+				` when matched and s."__transferia_deleted" then delete` +
+				` when matched then update set "id" = s."id", "name" = s."name", "payload" = case when 'payload' = any(s."__transferia_unchanged") then t."payload" else s."payload" end` +
+				` when not matched and not s."__transferia_deleted" and cardinality(s."__transferia_unchanged") = 0 then insert ("id", "name", "payload") values (s."id", s."name", s."payload");`,
+		}, batch.applyQueries(true))
+	})
+
+	t.Run("insert on conflict", func(t *testing.T) {
+		require.Equal(t, []string{
+			`delete from "public"."t" as t using "__transferia_bulk_stage" as s where s."__transferia_deleted" and t."id" = s."id";`,
+			`update "public"."t" as t set "id" = s."id", "name" = s."name", "payload" = case when 'payload' = any(s."__transferia_unchanged") then t."payload" else s."payload" end from "__transferia_bulk_stage" as s where not s."__transferia_deleted" and cardinality(s."__transferia_unchanged") > 0 and t."id" = s."id";`,
+			`insert into "public"."t" ("id", "name", "payload") select s."id", s."name", s."payload" from "__transferia_bulk_stage" as s where not s."__transferia_deleted" and cardinality(s."__transferia_unchanged") = 0 on conflict ("id") do update set ("id", "name", "payload")=row(excluded."id", excluded."name", excluded."payload");`,
+		}, batch.applyQueries(false))
+	})
+}
+
+func TestSupportsMerge(t *testing.T) {
+	require.False(t, supportsMerge(NewPgVersion("unknown")))
+	require.False(t, supportsMerge(NewPgVersion("PostgreSQL 14.9 on x86_64-pc-linux-gnu")))
+	require.True(t, supportsMerge(NewPgVersion("PostgreSQL 15.4 on x86_64-pc-linux-gnu")))
+	require.True(t, supportsMerge(NewPgVersion("PostgreSQL 16.1 (Debian 16.1-1.pgdg120+1)")))
+}
@@ -72,7 +72,7 @@ func (slot *Slot) Create() error {
 		if _, err := tx.Exec(ctx, "SET LOCAL lock_timeout = '0'"); err != nil {
 			return xerrors.Errorf("failed to set lock_timeout: %w", err)
 		}
-		stmt, err := tx.Exec(ctx, "SELECT pg_create_logical_replication_slot($1, 'wal2json')", slot.slotID)
+		stmt, err := tx.Exec(ctx, "SELECT pg_create_logical_replication_slot($1, $2)", slot.slotID, string(slot.src.SlotPlugin()))
 		slot.logger.Info("Create slot", log.Any("stmt", stmt))
 		return err
 	})
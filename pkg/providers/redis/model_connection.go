@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract/model"
+)
+
+type RedisConnectionOptions struct {
+	// Addr is host:port of the server
+	Addr     string
+	User     string
+	Password model.SecretString
+	DB       int
+	// TLSFile is PEM-encoded CA certificates to verify the server, TLS is enabled if it's set or if EnableTLS is true
+	TLSFile   string `model:"PemFileContent"`
+	EnableTLS bool
+}
+
+func (o *RedisConnectionOptions) Validate() error {
+	if o.Addr == "" {
+		return xerrors.New("address is required")
+	}
+	return nil
+}
+
+func (o *RedisConnectionOptions) TLSConfig() (*tls.Config, error) {
+	if o.TLSFile == "" {
+		if o.EnableTLS {
+			return &tls.Config{}, nil
+		}
+		return nil, nil
+	}
+	cp := x509.NewCertPool()
+	if !cp.AppendCertsFromPEM([]byte(o.TLSFile)) {
+		return nil, xerrors.Errorf("credentials: failed to append certificates")
+	}
+	return &tls.Config{
+		RootCAs: cp,
+	}, nil
+}
+
+// Connect creates a client & checks the connection
+func (o *RedisConnectionOptions) Connect(ctx context.Context, name string) (*goredis.Client, error) {
+	tlsCfg, err := o.TLSConfig()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to construct tls config: %w", err)
+	}
+	client := goredis.NewClient(&goredis.Options{
+		Addr:       o.Addr,
+		ClientName: name,
+		Username:   o.User,
+		Password:   string(o.Password),
+		DB:         o.DB,
+		TLSConfig:  tlsCfg,
+	})
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, xerrors.Errorf("unable to connect to %s: %w", o.Addr, err)
+	}
+	return client, nil
+}
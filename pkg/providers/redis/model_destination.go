@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"strings"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/middlewares/async/bufferer"
+)
+
+type WriteMode string
+
+const (
+	// WriteModeHash writes each row as a hash key named by the primary key of the row
+	WriteModeHash = WriteMode("hash")
+	// WriteModeStream appends each change as an entry of the stream of the table
+	WriteModeStream = WriteMode("stream")
+
+	defaultKeyTemplate = "{table}"
+)
+
+type RedisDestination struct {
+	Connection *RedisConnectionOptions
+
+	Mode WriteMode
+	// KeyTemplate is a name of the stream of a table or a prefix of hash keys of a table, {schema} & {table} are replaced by the table name parts.
+	// Hash keys are the prefix and primary key values separated by ':'
+	KeyTemplate string
+	// MaxLen trims streams approximately to the given number of entries, streams are not trimmed if 0
+	MaxLen int64
+
+	BufferTriggingCount    int
+	BufferTriggingSize     uint64
+	BufferTriggingInterval time.Duration
+}
+
+var _ model.Destination = (*RedisDestination)(nil)
+
+func (d *RedisDestination) WithDefaults() {
+	if d.Connection == nil {
+		d.Connection = &RedisConnectionOptions{
+			Addr:      "",
+			User:      "",
+			Password:  "",
+			DB:        0,
+			TLSFile:   "",
+			EnableTLS: false,
+		}
+	}
+	if d.Mode == "" {
+		d.Mode = WriteModeHash
+	}
+	if d.KeyTemplate == "" {
+		d.KeyTemplate = defaultKeyTemplate
+	}
+}
+
+func (d *RedisDestination) CleanupMode() model.CleanupType {
+	return model.DisabledCleanup
+}
+
+func (RedisDestination) IsDestination() {}
+
+func (d *RedisDestination) GetProviderType() abstract.ProviderType {
+	return ProviderType
+}
+
+func (d *RedisDestination) Validate() error {
+	if d.Connection == nil {
+		return xerrors.New("connection is required")
+	}
+	if err := d.Connection.Validate(); err != nil {
+		return xerrors.Errorf("invalid connection: %w", err)
+	}
+	switch d.Mode {
+	case WriteModeHash, WriteModeStream:
+	default:
+		return xerrors.Errorf("unknown write mode: %s", d.Mode)
+	}
+	return nil
+}
+
+// Key returns the stream name or the hash keys prefix of a table
+func (d *RedisDestination) Key(tID abstract.TableID) string {
+	return strings.NewReplacer("{schema}", tID.Namespace, "{table}", tID.Name).Replace(d.KeyTemplate)
+}
+
+func (d *RedisDestination) BuffererConfig() *bufferer.BuffererConfig {
+	return &bufferer.BuffererConfig{
+		TriggingCount:    d.BufferTriggingCount,
+		TriggingSize:     d.BufferTriggingSize,
+		TriggingInterval: d.BufferTriggingInterval,
+	}
+}
@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/parsers"
+)
+
+const (
+	defaultConsumer     = "transfer"
+	defaultGroupStartID = "0"
+	defaultBatchSize    = 1000
+	defaultKeysTable    = "keys"
+)
+
+var (
+	_ model.Source = (*RedisSource)(nil)
+)
+
+type RedisSource struct {
+	Connection *RedisConnectionOptions
+
+	// Streams are read by replication
+	Streams []string
+	// Group is a name of the consumer group, by default it's the transfer ID
+	Group string
+	// Consumer is a name of the consumer within the group, it must be stable between restarts
+	// to recover entries, which are delivered but not acknowledged yet
+	Consumer string
+	// GroupStartID is the ID after which a newly created group reads a stream: 0 reads the whole stream, $ reads only new entries
+	GroupStartID string
+	// ValueField is a field of stream entries passed to the parser as a message,
+	// all fields of an entry are passed as a JSON object if empty
+	ValueField string
+	// KeyField is a field of stream entries passed to the parser as a message key
+	KeyField string
+
+	// KeyPattern is a glob-style pattern of keys loaded by snapshot, all keys are loaded if empty. Streams are never loaded
+	KeyPattern string
+	// KeysTable is a name of the table keys are loaded into
+	KeysTable string
+
+	// BatchSize is the max number of entries or keys read by one command
+	BatchSize int
+
+	BufferSize            model.BytesSize // limits the size of entries, which are read but not pushed yet
+	ParserConfig          map[string]interface{}
+	ParseQueueParallelism int
+}
+
+func (s *RedisSource) GetProviderType() abstract.ProviderType {
+	return ProviderType
+}
+
+func (s *RedisSource) Validate() error {
+	if s.Connection == nil {
+		return xerrors.New("connection is required")
+	}
+	if err := s.Connection.Validate(); err != nil {
+		return xerrors.Errorf("invalid connection: %w", err)
+	}
+	if s.ParserConfig != nil {
+		parserConfigStruct, err := parsers.ParserConfigMapToStruct(s.ParserConfig)
+		if err != nil {
+			return xerrors.Errorf("unable to create new parser config, err: %w", err)
+		}
+		return parserConfigStruct.Validate()
+	}
+	return nil
+}
+
+func (s *RedisSource) WithDefaults() {
+	if s.Connection == nil {
+		s.Connection = &RedisConnectionOptions{
+			Addr:      "",
+			User:      "",
+			Password:  "",
+			DB:        0,
+			TLSFile:   "",
+			EnableTLS: false,
+		}
+	}
+	if s.Consumer == "" {
+		s.Consumer = defaultConsumer
+	}
+	if s.GroupStartID == "" {
+		s.GroupStartID = defaultGroupStartID
+	}
+	if s.KeysTable == "" {
+		s.KeysTable = defaultKeysTable
+	}
+	if s.BatchSize == 0 {
+		s.BatchSize = defaultBatchSize
+	}
+	if s.BufferSize == 0 {
+		s.BufferSize = 100 * 1024 * 1024
+	}
+}
+
+func (s *RedisSource) IsSource() {}
+
+func (s *RedisSource) IsAppendOnly() bool {
+	if s.ParserConfig == nil {
+		return false
+	} else {
+		parserConfigStruct, _ := parsers.ParserConfigMapToStruct(s.ParserConfig)
+		if parserConfigStruct == nil {
+			return false
+		}
+		return parserConfigStruct.IsAppendOnly()
+	}
+}
+
+func (s *RedisSource) IsDefaultMirror() bool {
+	return s.ParserConfig == nil
+}
+
+func (s *RedisSource) Parser() map[string]interface{} {
+	return s.ParserConfig
+}
+
+// KeysTableID is the table keys are loaded into by snapshot
+func (s *RedisSource) KeysTableID() abstract.TableID {
+	return abstract.TableID{Namespace: "", Name: s.KeysTable}
+}
@@ -0,0 +1,132 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	cpclient "github.com/transferia/transferia/pkg/abstract/coordinator"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/middlewares"
+	"github.com/transferia/transferia/pkg/providers"
+	"github.com/transferia/transferia/pkg/util/gobwrapper"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+func init() {
+	gobwrapper.Register(new(RedisSource))
+	gobwrapper.Register(new(RedisDestination))
+	model.RegisterSource(ProviderType, func() model.Source {
+		return new(RedisSource)
+	})
+	model.RegisterDestination(ProviderType, func() model.Destination {
+		return new(RedisDestination)
+	})
+	abstract.RegisterProviderName(ProviderType, "Redis")
+
+	providers.Register(ProviderType, New)
+}
+
+const ProviderType = abstract.ProviderType("redis")
+
+// To verify providers contract implementation
+var (
+	_ providers.Snapshot    = (*Provider)(nil)
+	_ providers.Replication = (*Provider)(nil)
+	_ providers.Sinker      = (*Provider)(nil)
+
+	_ providers.Activator = (*Provider)(nil)
+)
+
+type Provider struct {
+	logger   log.Logger
+	registry metrics.Registry
+	cp       cpclient.Coordinator
+	transfer *model.Transfer
+}
+
+func (p *Provider) Type() abstract.ProviderType {
+	return ProviderType
+}
+
+// sourceConfig returns the source with the consumer group defaulted to the transfer ID
+func (p *Provider) sourceConfig() (*RedisSource, error) {
+	src, ok := p.transfer.Src.(*RedisSource)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected source type: %T", p.transfer.Src)
+	}
+	srcCopy := *src
+	if srcCopy.Group == "" {
+		srcCopy.Group = p.transfer.ID
+	}
+	return &srcCopy, nil
+}
+
+func (p *Provider) Storage() (abstract.Storage, error) {
+	src, err := p.sourceConfig()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get source config: %w", err)
+	}
+	return NewStorage(src, p.logger, p.registry)
+}
+
+func (p *Provider) Source() (abstract.Source, error) {
+	src, err := p.sourceConfig()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get source config: %w", err)
+	}
+	return NewSource(src, p.logger, p.registry)
+}
+
+func (p *Provider) Sink(middlewares.Config) (abstract.Sinker, error) {
+	dst, ok := p.transfer.Dst.(*RedisDestination)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected target type: %T", p.transfer.Dst)
+	}
+	return NewSink(dst, p.logger, p.registry)
+}
+
+// Activate creates consumer groups before the snapshot, so stream entries added during the snapshot are not missed
+// by groups starting from new entries
+func (p *Provider) Activate(ctx context.Context, _ *model.TransferOperation, tables abstract.TableMap, callbacks providers.ActivateCallbacks) error {
+	if p.transfer.SrcType() != ProviderType {
+		return nil
+	}
+	src, err := p.sourceConfig()
+	if err != nil {
+		return xerrors.Errorf("unable to get source config: %w", err)
+	}
+	if !p.transfer.SnapshotOnly() && len(src.Streams) > 0 {
+		client, err := src.Connection.Connect(ctx, src.Consumer)
+		if err != nil {
+			return xerrors.Errorf("unable to connect: %w", err)
+		}
+		defer client.Close()
+		if err := CreateGroups(ctx, client, src); err != nil {
+			return xerrors.Errorf("unable to prepare consumer groups: %w", err)
+		}
+	}
+	if p.transfer.IncrementOnly() {
+		return nil
+	}
+	if err := callbacks.Cleanup(tables); err != nil {
+		return xerrors.Errorf("Sinker cleanup failed: %w", err)
+	}
+	if err := callbacks.CheckIncludes(tables); err != nil {
+		return xerrors.Errorf("Failed in accordance with configuration: %w", err)
+	}
+	if err := callbacks.Upload(tables); err != nil {
+		return xerrors.Errorf("Snapshot loading failed: %w", err)
+	}
+	return nil
+}
+
+func New(lgr log.Logger, registry metrics.Registry, cp cpclient.Coordinator, transfer *model.Transfer) providers.Provider {
+	return &Provider{
+		logger:   lgr,
+		registry: registry,
+		cp:       cp,
+		transfer: transfer,
+	}
+}
@@ -0,0 +1,227 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/stats"
+	"github.com/transferia/transferia/pkg/util/castx"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+// KindField is a field of stream entries written by the sink, which holds the kind of the change
+const KindField = "kind"
+
+var _ abstract.Sinker = (*sink)(nil)
+
+type sink struct {
+	client  *goredis.Client
+	config  *RedisDestination
+	logger  log.Logger
+	metrics *stats.SinkerStats
+}
+
+func (s *sink) Close() error {
+	return s.client.Close()
+}
+
+// Push writes all rows of the batch in a single MULTI/EXEC transaction keeping their order
+func (s *sink) Push(input []abstract.ChangeItem) error {
+	ctx := context.Background()
+	rowsByTable := make(map[string]int)
+	var writeErr error
+	_, err := s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for _, item := range input {
+			if !isApplicableRow(item.Kind) {
+				continue
+			}
+			if err := s.write(ctx, pipe, item); err != nil {
+				writeErr = xerrors.Errorf("unable to write %s of table %s: %w", item.Kind, item.Fqtn(), err)
+				return writeErr
+			}
+			rowsByTable[item.Fqtn()]++
+		}
+		return nil
+	})
+	if writeErr != nil {
+		return abstract.NewFatalError(writeErr)
+	}
+	if err != nil {
+		return xerrors.Errorf("unable to execute transaction: %w", err)
+	}
+	for table, rows := range rowsByTable {
+		s.metrics.Table(table, "rows", rows)
+	}
+	return nil
+}
+
+func isApplicableRow(kind abstract.Kind) bool {
+	return kind == abstract.InsertKind || kind == abstract.UpdateKind || kind == abstract.DeleteKind
+}
+
+func (s *sink) write(ctx context.Context, pipe goredis.Pipeliner, item abstract.ChangeItem) error {
+	switch s.config.Mode {
+	case WriteModeStream:
+		return s.writeStream(ctx, pipe, item)
+	case WriteModeHash:
+		return s.writeHash(ctx, pipe, item)
+	default:
+		return xerrors.Errorf("unknown write mode: %s", s.config.Mode)
+	}
+}
+
+// writeStream appends the change as an entry with the kind field followed by the columns, deletes carry the old keys only
+func (s *sink) writeStream(ctx context.Context, pipe goredis.Pipeliner, item abstract.ChangeItem) error {
+	names, values := item.ColumnNames, item.ColumnValues
+	if item.Kind == abstract.DeleteKind {
+		names, values = item.OldKeys.KeyNames, item.OldKeys.KeyValues
+	}
+	fields := make([]string, 0, 2*len(names)+2)
+	fields = append(fields, KindField, string(item.Kind))
+	for i, name := range names {
+		if values[i] == nil {
+			continue
+		}
+		value, err := stringify(values[i])
+		if err != nil {
+			return xerrors.Errorf("unable to convert value of column %s: %w", name, err)
+		}
+		fields = append(fields, name, value)
+	}
+	pipe.XAdd(ctx, &goredis.XAddArgs{
+		Stream:     s.config.Key(item.TableID()),
+		NoMkStream: false,
+		MaxLen:     s.config.MaxLen,
+		MinID:      "",
+		Approx:     true,
+		Limit:      0,
+		ID:         "",
+		Values:     fields,
+	})
+	return nil
+}
+
+// writeHash keeps a hash key per row: inserts replace the whole hash, updates set present columns & remove nulls,
+// updates of the primary key move the row to a new key, deletes remove the key
+func (s *sink) writeHash(ctx context.Context, pipe goredis.Pipeliner, item abstract.ChangeItem) error {
+	keyCols := item.KeyCols()
+	if len(keyCols) == 0 {
+		return xerrors.New("hash mode requires a primary key")
+	}
+	prefix := s.config.Key(item.TableID())
+
+	if item.Kind == abstract.DeleteKind || item.KeysChanged() {
+		oldKey, err := hashKey(prefix, keyCols, oldKeysMap(item))
+		if err != nil {
+			return xerrors.Errorf("unable to build old key: %w", err)
+		}
+		pipe.Del(ctx, oldKey)
+		if item.Kind == abstract.DeleteKind {
+			return nil
+		}
+	}
+
+	values := make(map[string]interface{}, len(item.ColumnNames))
+	for i, name := range item.ColumnNames {
+		values[name] = item.ColumnValues[i]
+	}
+	key, err := hashKey(prefix, keyCols, values)
+	if err != nil {
+		return xerrors.Errorf("unable to build key: %w", err)
+	}
+	if item.Kind == abstract.InsertKind {
+		pipe.Del(ctx, key)
+	}
+	var fields []string
+	var nulls []string
+	for i, name := range item.ColumnNames {
+		if item.ColumnValues[i] == nil {
+			nulls = append(nulls, name)
+			continue
+		}
+		value, err := stringify(item.ColumnValues[i])
+		if err != nil {
+			return xerrors.Errorf("unable to convert value of column %s: %w", name, err)
+		}
+		fields = append(fields, name, value)
+	}
+	if len(fields) > 0 {
+		pipe.HSet(ctx, key, fields)
+	}
+	if len(nulls) > 0 && item.Kind == abstract.UpdateKind {
+		pipe.HDel(ctx, key, nulls...)
+	}
+	return nil
+}
+
+// oldKeysMap returns the old keys of a row, the current key values are used if the old keys are absent
+func oldKeysMap(item abstract.ChangeItem) map[string]interface{} {
+	if len(item.OldKeys.KeyNames) == 0 {
+		return item.KeysAsMap()
+	}
+	result := make(map[string]interface{}, len(item.OldKeys.KeyNames))
+	for i, name := range item.OldKeys.KeyNames {
+		result[name] = item.OldKeys.KeyValues[i]
+	}
+	return result
+}
+
+func hashKey(prefix string, keyCols []string, values map[string]interface{}) (string, error) {
+	parts := make([]string, 0, len(keyCols)+1)
+	parts = append(parts, prefix)
+	for _, col := range keyCols {
+		value, ok := values[col]
+		if !ok {
+			return "", xerrors.Errorf("no value of key column %s", col)
+		}
+		str, err := stringify(value)
+		if err != nil {
+			return "", xerrors.Errorf("unable to convert value of key column %s: %w", col, err)
+		}
+		parts = append(parts, str)
+	}
+	return strings.Join(parts, ":"), nil
+}
+
+// stringify converts a value to a field value: scalars are formatted as is, times as RFC 3339, other values as JSON
+func stringify(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", xerrors.Errorf("unable to marshal: %w", err)
+		}
+		return string(data), nil
+	}
+	if str, err := castx.ToStringE(value); err == nil {
+		return str, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", xerrors.Errorf("unable to marshal %T: %w", value, err)
+	}
+	return string(data), nil
+}
+
+func NewSink(config *RedisDestination, lgr log.Logger, registry metrics.Registry) (abstract.Sinker, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+	client, err := config.Connection.Connect(ctx, "transfer")
+	if err != nil {
+		return nil, xerrors.Errorf("unable to connect: %w", err)
+	}
+	return &sink{
+		client:  client,
+		config:  config,
+		logger:  lgr,
+		metrics: stats.NewSinkerStats(registry),
+	}, nil
+}
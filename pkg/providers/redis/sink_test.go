@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/pkg/abstract"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+var testSchema = abstract.NewTableSchema(abstract.TableColumns{
+	abstract.NewColSchema("id", schema.TypeInt64, true),
+	abstract.NewColSchema("name", schema.TypeString, false),
+	abstract.NewColSchema("meta", schema.TypeAny, false),
+})
+
+func makeItem(kind abstract.Kind, values []interface{}, oldID interface{}) abstract.ChangeItem {
+	item := abstract.ChangeItem{
+		Kind:         kind,
+		Schema:       "public",
+		Table:        "users",
+		ColumnNames:  []string{"id", "name", "meta"},
+		ColumnValues: values,
+		TableSchema:  testSchema,
+		OldKeys:      abstract.EmptyOldKeys(),
+	}
+	if oldID != nil {
+		item.OldKeys = abstract.OldKeysType{KeyNames: []string{"id"}, KeyTypes: nil, KeyValues: []interface{}{oldID}}
+	}
+	if kind == abstract.DeleteKind {
+		item.ColumnNames, item.ColumnValues = nil, nil
+	}
+	return item
+}
+
+func newTestSink(t *testing.T, connection *RedisConnectionOptions, mode WriteMode) abstract.Sinker {
+	dst := &RedisDestination{Connection: connection, Mode: mode}
+	dst.WithDefaults()
+	require.NoError(t, dst.Validate())
+	sink, err := NewSink(dst, logger.Log, solomon.NewRegistry(nil))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sink.Close() })
+	return sink
+}
+
+func TestSinkHash(t *testing.T) {
+	connection, client := runServer(t)
+	ctx := context.Background()
+	sink := newTestSink(t, connection, WriteModeHash)
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		makeItem(abstract.InsertKind, []interface{}{int64(1), "alice", map[string]interface{}{"a": 1}}, nil),
+		makeItem(abstract.InsertKind, []interface{}{int64(2), "bob", nil}, nil),
+		makeItem(abstract.InsertKind, []interface{}{int64(3), "carol", nil}, nil),
+	}))
+	require.Equal(t, map[string]string{"id": "1", "name": "alice", "meta": `{"a":1}`}, client.HGetAll(ctx, "users:1").Val())
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		makeItem(abstract.UpdateKind, []interface{}{int64(1), "alice", nil}, int64(1)),
+		makeItem(abstract.UpdateKind, []interface{}{int64(20), "bob", nil}, int64(2)),
+		makeItem(abstract.DeleteKind, nil, int64(3)),
+	}))
+	require.Equal(t, map[string]string{"id": "1", "name": "alice"}, client.HGetAll(ctx, "users:1").Val())
+	require.Equal(t, map[string]string{"id": "20", "name": "bob"}, client.HGetAll(ctx, "users:20").Val())
+	require.ElementsMatch(t, []string{"users:1", "users:20"}, client.Keys(ctx, "*").Val())
+
+	noKeys := makeItem(abstract.InsertKind, []interface{}{int64(1), "alice", nil}, nil)
+	noKeys.TableSchema = abstract.NewTableSchema(abstract.TableColumns{
+		abstract.NewColSchema("id", schema.TypeInt64, false),
+		abstract.NewColSchema("name", schema.TypeString, false),
+		abstract.NewColSchema("meta", schema.TypeAny, false),
+	})
+	require.True(t, abstract.IsFatal(sink.Push([]abstract.ChangeItem{noKeys})))
+}
+
+func TestSinkStream(t *testing.T) {
+	connection, client := runServer(t)
+	sink := newTestSink(t, connection, WriteModeStream)
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		makeItem(abstract.InsertKind, []interface{}{int64(1), "alice", created}, nil),
+		makeItem(abstract.DeleteKind, nil, int64(1)),
+	}))
+	entries, err := client.XRange(context.Background(), "users", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, map[string]interface{}{"kind": "insert", "id": "1", "name": "alice", "meta": "2024-01-02T03:04:05Z"}, entries[0].Values)
+	require.Equal(t, map[string]interface{}{"kind": "delete", "id": "1"}, entries[1].Values)
+}
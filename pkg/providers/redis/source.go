@@ -0,0 +1,384 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/format"
+	"github.com/transferia/transferia/pkg/parsequeue"
+	"github.com/transferia/transferia/pkg/parsers"
+	"github.com/transferia/transferia/pkg/stats"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const (
+	readBlock    = time.Second
+	setupTimeout = time.Minute
+	// pendingID reads entries delivered to the consumer but not acknowledged yet, newEntriesID reads entries never delivered
+	pendingID    = "0"
+	newEntriesID = ">"
+)
+
+var (
+	_ abstract.Source = (*Source)(nil)
+)
+
+// entry is a stream entry prepared for parsing
+type entry struct {
+	stream string
+	id     string
+	key    []byte
+	value  []byte
+}
+
+// Source reads streams by a consumer group.
+// Entries are acknowledged by XACK only after they are pushed into the sink, so entries which are delivered
+// but not acknowledged before a restart are read again from the pending entries list of the consumer
+type Source struct {
+	config  *RedisSource
+	logger  log.Logger
+	metrics *stats.SourceStats
+	parser  parsers.Parser
+	client  *goredis.Client
+
+	inflightMutex sync.Mutex
+	inflightBytes int
+
+	errMutex  sync.Mutex
+	lastError error
+
+	ctx    context.Context
+	cancel func()
+}
+
+func (s *Source) inLimits() bool {
+	s.inflightMutex.Lock()
+	defer s.inflightMutex.Unlock()
+	return s.config.BufferSize == 0 || int(s.config.BufferSize) > s.inflightBytes
+}
+
+func (s *Source) addInflight(size int) {
+	s.inflightMutex.Lock()
+	defer s.inflightMutex.Unlock()
+	s.inflightBytes += size
+}
+
+func (s *Source) reduceInflight(size int) {
+	s.inflightMutex.Lock()
+	defer s.inflightMutex.Unlock()
+	s.inflightBytes = s.inflightBytes - size
+}
+
+func (s *Source) setError(err error) {
+	s.errMutex.Lock()
+	defer s.errMutex.Unlock()
+	if s.lastError == nil {
+		s.lastError = err
+	}
+}
+
+func (s *Source) getError() error {
+	s.errMutex.Lock()
+	defer s.errMutex.Unlock()
+	return s.lastError
+}
+
+func (s *Source) Run(sink abstract.AsyncSink) error {
+	defer s.client.Close()
+	parseQ := parsequeue.NewWaitable(s.logger, s.config.ParseQueueParallelism, sink, s.parse, s.ack)
+	defer parseQ.Close()
+
+	return s.run(parseQ)
+}
+
+func (s *Source) run(parseQ *parsequeue.WaitableParseQueue[[]entry]) error {
+	// every stream starts from its pending entries, the stream switches to new entries once they are read
+	ids := make(map[string]string, len(s.config.Streams))
+	for _, stream := range s.config.Streams {
+		ids[stream] = pendingID
+	}
+	for {
+		s.metrics.Master.Set(1)
+		s.waitLimits()
+		if s.ctx.Err() != nil {
+			if err := s.getError(); err != nil {
+				return xerrors.Errorf("unable to push: %w", err)
+			}
+			return nil
+		}
+
+		streams, err := s.read(ids)
+		if err != nil {
+			return xerrors.Errorf("unable to read streams: %w", err)
+		}
+		var data []entry
+		for _, stream := range streams {
+			if ids[stream.Stream] != newEntriesID {
+				if len(stream.Messages) == 0 {
+					s.logger.Info("pending entries are recovered", log.String("stream", stream.Stream))
+					ids[stream.Stream] = newEntriesID
+					continue
+				}
+				ids[stream.Stream] = stream.Messages[len(stream.Messages)-1].ID
+			}
+			for _, msg := range stream.Messages {
+				e, err := s.makeEntry(stream.Stream, msg)
+				if err != nil {
+					return xerrors.Errorf("unable to read entry %s of stream %s: %w", msg.ID, stream.Stream, err)
+				}
+				s.addInflight(len(e.value))
+				data = append(data, e)
+			}
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if err := parseQ.Add(data); err != nil {
+			return xerrors.Errorf("unable to add entries to parse queue: %w", err)
+		}
+	}
+}
+
+// read reads a batch of entries after the given IDs, it blocks only if all streams are read for new entries
+func (s *Source) read(ids map[string]string) ([]goredis.XStream, error) {
+	block := readBlock
+	args := make([]string, 0, 2*len(s.config.Streams))
+	args = append(args, s.config.Streams...)
+	for _, stream := range s.config.Streams {
+		args = append(args, ids[stream])
+		if ids[stream] != newEntriesID {
+			block = -1
+		}
+	}
+	streams, err := s.client.XReadGroup(s.ctx, &goredis.XReadGroupArgs{
+		Group:    s.config.Group,
+		Consumer: s.config.Consumer,
+		Streams:  args,
+		Count:    int64(s.config.BatchSize),
+		Block:    block,
+		NoAck:    false,
+	}).Result()
+	if xerrors.Is(err, goredis.Nil) || s.ctx.Err() != nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("XREADGROUP failed: %w", err)
+	}
+	return streams, nil
+}
+
+func (s *Source) makeEntry(stream string, msg goredis.XMessage) (entry, error) {
+	result := entry{stream: stream, id: msg.ID, key: nil, value: nil}
+	if s.config.KeyField != "" {
+		if key, ok := msg.Values[s.config.KeyField]; ok {
+			result.key = []byte(toString(key))
+		}
+	}
+	if s.config.ValueField != "" {
+		if value, ok := msg.Values[s.config.ValueField]; ok {
+			result.value = []byte(toString(value))
+		}
+		return result, nil
+	}
+	value, err := json.Marshal(msg.Values)
+	if err != nil {
+		return result, xerrors.Errorf("unable to marshal fields: %w", err)
+	}
+	result.value = value
+	return result, nil
+}
+
+func toString(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// ack must not acknowledge anything after a failed push: the failed entries are read again from the pending entries list
+// after a restart, and acknowledged later entries would break the order
+func (s *Source) ack(data []entry, pushSt time.Time, err error) {
+	for _, e := range data {
+		s.reduceInflight(len(e.value))
+	}
+	if err != nil {
+		s.setError(err)
+		s.cancel()
+		return
+	}
+	if s.getError() != nil {
+		return
+	}
+	ids := make(map[string][]string)
+	for _, e := range data {
+		ids[e.stream] = append(ids[e.stream], e.id)
+	}
+	for stream, streamIDs := range ids {
+		// acknowledgement does not depend on the source context, so entries pushed before a stop are acknowledged
+		if err := s.client.XAck(context.Background(), stream, s.config.Group, streamIDs...).Err(); err != nil {
+			// not acknowledged entries are read again after a restart
+			s.logger.Warn("unable to acknowledge entries", log.String("stream", stream), log.Int("count", len(streamIDs)), log.Error(err))
+		}
+	}
+	s.metrics.PushTime.RecordDuration(time.Since(pushSt))
+}
+
+func (s *Source) parse(data []entry) []abstract.ChangeItem {
+	var result []abstract.ChangeItem
+	totalSize := 0
+	st := time.Now()
+	for _, e := range data {
+		totalSize += len(e.value)
+		s.metrics.Size.Add(int64(len(e.value)))
+		s.metrics.Count.Inc()
+		if s.parser == nil {
+			result = append(result, makeRawChangeItem(e))
+			continue
+		}
+		message, partition := entryAsParserMessage(e)
+		result = append(result, s.parser.Do(message, partition)...)
+	}
+	s.logger.Infof("parse done in %v for %v entries of total size %v -> %v rows", time.Since(st), len(data), format.SizeInt(totalSize), len(result))
+	if s.parser != nil {
+		s.metrics.DecodeTime.RecordDuration(time.Since(st))
+	}
+	s.metrics.ChangeItems.Add(int64(len(result)))
+	for _, ci := range result {
+		if ci.IsRowEvent() {
+			s.metrics.Parsed.Inc()
+		}
+	}
+	return result
+}
+
+// parseID splits an entry ID into the milliseconds time & the sequence number
+func parseID(id string) (uint64, uint64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseUint(msPart, 10, 64)
+	seq, _ := strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq
+}
+
+func entryAsParserMessage(e entry) (parsers.Message, abstract.Partition) {
+	ms, seq := parseID(e.id)
+	writeTime := time.UnixMilli(int64(ms))
+	return parsers.Message{
+		Offset:     ms,
+		SeqNo:      seq,
+		Key:        e.key,
+		CreateTime: writeTime,
+		WriteTime:  writeTime,
+		Value:      e.value,
+		Headers:    map[string]string{"id": e.id},
+	}, abstract.Partition{
+		Cluster:   "", // streams are not partitioned
+		Partition: 0,
+		Topic:     e.stream,
+	}
+}
+
+// makeRawChangeItem uses the sequence number of an entry as a shard, so entries added within the same millisecond have different keys
+func makeRawChangeItem(e entry) abstract.ChangeItem {
+	ms, seq := parseID(e.id)
+	return abstract.MakeRawMessage(
+		e.stream,
+		time.UnixMilli(int64(ms)),
+		e.stream,
+		int(seq),
+		int64(ms),
+		e.value,
+	)
+}
+
+func (s *Source) Stop() {
+	s.cancel()
+}
+
+func (s *Source) waitLimits() {
+	backoffTimer := backoff.NewExponentialBackOff()
+	backoffTimer.Reset()
+	backoffTimer.MaxElapsedTime = 0
+	nextLogDuration := backoffTimer.NextBackOff()
+	logTime := time.Now()
+
+	for !s.inLimits() {
+		time.Sleep(time.Millisecond * 10)
+		if s.ctx.Err() != nil {
+			s.logger.Warn("context aborted, stop wait for limits")
+			return
+		}
+		if time.Since(logTime) > nextLogDuration {
+			logTime = time.Now()
+			nextLogDuration = backoffTimer.NextBackOff()
+			s.logger.Warnf(
+				"reader throttled for %v, limits: %v / %v",
+				backoffTimer.GetElapsedTime(),
+				format.SizeInt(s.inflightBytes),
+				format.SizeInt(int(s.config.BufferSize)),
+			)
+		}
+	}
+}
+
+// CreateGroups creates the consumer group of every stream, streams are created if they do not exist.
+// Existing groups are kept as is, so a restarted transfer continues from the last acknowledged entries
+func CreateGroups(ctx context.Context, client *goredis.Client, cfg *RedisSource) error {
+	for _, stream := range cfg.Streams {
+		err := client.XGroupCreateMkStream(ctx, stream, cfg.Group, cfg.GroupStartID).Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return xerrors.Errorf("unable to create group %s of stream %s: %w", cfg.Group, stream, err)
+		}
+	}
+	return nil
+}
+
+func NewSource(cfg *RedisSource, logger log.Logger, registry metrics.Registry) (*Source, error) {
+	if len(cfg.Streams) == 0 {
+		return nil, xerrors.New("no streams to read")
+	}
+	var parser parsers.Parser
+	if cfg.ParserConfig != nil {
+		var err error
+		parser, err = parsers.NewParserFromMap(cfg.ParserConfig, false, logger, stats.NewSourceStats(registry))
+		if err != nil {
+			return nil, xerrors.Errorf("unable to make parser, err: %w", err)
+		}
+	}
+
+	setupCtx, setupCancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer setupCancel()
+	client, err := cfg.Connection.Connect(setupCtx, cfg.Consumer)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to connect: %w", err)
+	}
+	if err := CreateGroups(setupCtx, client, cfg); err != nil {
+		_ = client.Close()
+		return nil, xerrors.Errorf("unable to prepare consumer groups: %w", err)
+	}
+	logger.Info("consumer group is ready", log.Strings("streams", cfg.Streams), log.String("group", cfg.Group), log.String("consumer", cfg.Consumer))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Source{
+		config:        cfg,
+		logger:        logger,
+		metrics:       stats.NewSourceStats(registry),
+		parser:        parser,
+		client:        client,
+		inflightMutex: sync.Mutex{},
+		inflightBytes: 0,
+		errMutex:      sync.Mutex{},
+		lastError:     nil,
+		ctx:           ctx,
+		cancel:        cancel,
+	}, nil
+}
@@ -0,0 +1,187 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/parsers"
+	jsonparser "github.com/transferia/transferia/pkg/parsers/registry/json"
+	ytschema "go.ytsaurus.tech/yt/go/schema"
+)
+
+type mockSink struct {
+	mutex sync.Mutex
+	items []abstract.ChangeItem
+	err   error
+}
+
+func (s *mockSink) AsyncPush(items []abstract.ChangeItem) chan error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	result := make(chan error, 1)
+	if s.err == nil {
+		s.items = append(s.items, items...)
+	}
+	result <- s.err
+	return result
+}
+
+func (s *mockSink) Close() error {
+	return nil
+}
+
+func (s *mockSink) rows() []abstract.ChangeItem {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var result []abstract.ChangeItem
+	for _, item := range s.items {
+		if item.IsRowEvent() {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// runServer starts an in-process server, it returns connection options & a client of the server
+func runServer(t *testing.T) (*RedisConnectionOptions, *goredis.Client) {
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return &RedisConnectionOptions{Addr: server.Addr()}, client
+}
+
+func add(t *testing.T, client *goredis.Client, stream string, values ...string) {
+	for _, value := range values {
+		require.NoError(t, client.XAdd(context.Background(), &goredis.XAddArgs{Stream: stream, Values: []string{"data", value}}).Err())
+	}
+}
+
+func pending(t *testing.T, client *goredis.Client, stream string) int64 {
+	info, err := client.XPending(context.Background(), stream, "group").Result()
+	require.NoError(t, err)
+	return info.Count
+}
+
+func makeTestSource(t *testing.T, connection *RedisConnectionOptions, streams ...string) *RedisSource {
+	parserConfigMap, err := parsers.ParserConfigStructToMap(&jsonparser.ParserConfigJSONCommon{
+		Fields: []abstract.ColSchema{
+			{ColumnName: "id", DataType: ytschema.TypeInt32.String(), PrimaryKey: true},
+			{ColumnName: "val", DataType: ytschema.TypeString.String()},
+		},
+		AddRest:       false,
+		AddDedupeKeys: false,
+	})
+	require.NoError(t, err)
+	src := &RedisSource{
+		Connection:   connection,
+		Streams:      streams,
+		Group:        "group",
+		ValueField:   "data",
+		ParserConfig: parserConfigMap,
+	}
+	src.WithDefaults()
+	require.NoError(t, src.Validate())
+	return src
+}
+
+func runSource(t *testing.T, src *RedisSource, sink *mockSink) (*Source, chan error) {
+	source, err := NewSource(src, logger.Log, solomon.NewRegistry(nil))
+	require.NoError(t, err)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- source.Run(sink)
+	}()
+	return source, errCh
+}
+
+func TestSource(t *testing.T) {
+	connection, client := runServer(t)
+	add(t, client, "users", `{"id":1,"val":"a"}`, `{"id":2,"val":"b"}`)
+	add(t, client, "orders", `{"id":3,"val":"c"}`)
+
+	sink := &mockSink{}
+	source, errCh := runSource(t, makeTestSource(t, connection, "users", "orders"), sink)
+	require.Eventually(t, func() bool {
+		return len(sink.rows()) == 3
+	}, 10*time.Second, 10*time.Millisecond)
+	// entries are acknowledged after the push
+	require.Eventually(t, func() bool {
+		return pending(t, client, "users") == 0 && pending(t, client, "orders") == 0
+	}, 10*time.Second, 10*time.Millisecond)
+	source.Stop()
+	require.NoError(t, <-errCh)
+
+	tables := map[string][][]interface{}{}
+	for _, row := range sink.rows() {
+		tables[row.Table] = append(tables[row.Table], row.ColumnValues[:2])
+	}
+	require.Equal(t, [][]interface{}{{int32(1), "a"}, {int32(2), "b"}}, tables["users"])
+	require.Equal(t, [][]interface{}{{int32(3), "c"}}, tables["orders"])
+
+	// the group continues after acknowledged entries
+	add(t, client, "users", `{"id":4,"val":"d"}`)
+	sink = &mockSink{}
+	source, errCh = runSource(t, makeTestSource(t, connection, "users", "orders"), sink)
+	require.Eventually(t, func() bool {
+		return len(sink.rows()) == 1
+	}, 10*time.Second, 10*time.Millisecond)
+	source.Stop()
+	require.NoError(t, <-errCh)
+	require.Equal(t, []interface{}{int32(4), "d"}, sink.rows()[0].ColumnValues[:2])
+}
+
+func TestSourcePendingRecovery(t *testing.T) {
+	connection, client := runServer(t)
+	add(t, client, "users", `{"id":1,"val":"a"}`)
+
+	src := makeTestSource(t, connection, "users")
+	source, err := NewSource(src, logger.Log, solomon.NewRegistry(nil))
+	require.NoError(t, err)
+	require.Error(t, source.Run(&mockSink{err: xerrors.New("push failed")}))
+	// the entry is delivered to the consumer but not acknowledged
+	require.Equal(t, int64(1), pending(t, client, "users"))
+
+	add(t, client, "users", `{"id":2,"val":"b"}`)
+	sink := &mockSink{}
+	source, errCh := runSource(t, src, sink)
+	require.Eventually(t, func() bool {
+		return len(sink.rows()) == 2
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return pending(t, client, "users") == 0
+	}, 10*time.Second, 10*time.Millisecond)
+	source.Stop()
+	require.NoError(t, <-errCh)
+	// the pending entry is read before new ones
+	require.Equal(t, []interface{}{int32(1), "a"}, sink.rows()[0].ColumnValues[:2])
+	require.Equal(t, []interface{}{int32(2), "b"}, sink.rows()[1].ColumnValues[:2])
+}
+
+func TestSourceRaw(t *testing.T) {
+	connection, client := runServer(t)
+	require.NoError(t, client.XAdd(context.Background(), &goredis.XAddArgs{Stream: "events", ID: "5-1", Values: []string{"a", "1"}}).Err())
+
+	src := makeTestSource(t, connection, "events")
+	src.ParserConfig = nil
+	src.ValueField = ""
+	sink := &mockSink{}
+	source, errCh := runSource(t, src, sink)
+	require.Eventually(t, func() bool {
+		return len(sink.rows()) == 1
+	}, 10*time.Second, 10*time.Millisecond)
+	source.Stop()
+	require.NoError(t, <-errCh)
+
+	row := sink.rows()[0]
+	require.Equal(t, "events", row.Table)
+	require.Equal(t, []interface{}{"events", 1, uint64(5), time.UnixMilli(5), `{"a":"1"}`}, row.ColumnValues)
+}
@@ -0,0 +1,247 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/changeitem"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/stats"
+	"github.com/transferia/transferia/pkg/util"
+	"go.ytsaurus.tech/library/go/core/log"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+var _ abstract.Storage = (*Storage)(nil)
+
+var keysSchema = abstract.NewTableSchema(abstract.TableColumns{
+	abstract.NewColSchema("key", schema.TypeString, true),
+	abstract.NewColSchema("type", schema.TypeString, false),
+	abstract.NewColSchema("value", schema.TypeAny, false),
+})
+
+// Storage loads keys matching the pattern into a single table of key, type & value columns.
+// Values are strings, maps of hash fields, lists of list or set members and maps of sorted set members to scores
+type Storage struct {
+	client  *goredis.Client
+	config  *RedisSource
+	logger  log.Logger
+	metrics *stats.SourceStats
+}
+
+func (s *Storage) Close() {
+	if err := s.client.Close(); err != nil {
+		s.logger.Warn("unable to close client", log.Error(err))
+	}
+}
+
+func (s *Storage) Ping() error {
+	return s.client.Ping(context.Background()).Err()
+}
+
+func (s *Storage) TableSchema(ctx context.Context, table abstract.TableID) (*abstract.TableSchema, error) {
+	if table != s.config.KeysTableID() {
+		return nil, xerrors.Errorf("table %s not found", table.Fqtn())
+	}
+	return keysSchema, nil
+}
+
+func (s *Storage) TableList(filter abstract.IncludeTableList) (abstract.TableMap, error) {
+	eta, err := s.EstimateTableRowsCount(s.config.KeysTableID())
+	if err != nil {
+		return nil, xerrors.Errorf("unable to estimate keys count: %w", err)
+	}
+	tables := abstract.TableMap{
+		s.config.KeysTableID(): abstract.TableInfo{EtaRow: eta, IsView: false, Schema: keysSchema},
+	}
+	return model.FilteredMap(tables, filter), nil
+}
+
+func (s *Storage) pattern() string {
+	if s.config.KeyPattern == "" {
+		return "*"
+	}
+	return s.config.KeyPattern
+}
+
+func (s *Storage) LoadTable(ctx context.Context, table abstract.TableDescription, pusher abstract.Pusher) error {
+	if table.ID() != s.config.KeysTableID() {
+		return xerrors.Errorf("table %s not found", table.Fqtn())
+	}
+	st := util.GetTimestampFromContextOrNow(ctx)
+	partID := table.PartID()
+	colNames := keysSchema.Columns().ColumnNames()
+
+	var cursor uint64
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, s.pattern(), int64(s.config.BatchSize)).Result()
+		if err != nil {
+			return xerrors.Errorf("SCAN failed: %w", err)
+		}
+		rows, err := s.loadKeys(ctx, keys)
+		if err != nil {
+			return xerrors.Errorf("unable to load %d keys: %w", len(keys), err)
+		}
+		if len(rows) > 0 {
+			batch := make([]abstract.ChangeItem, len(rows))
+			for i, vals := range rows {
+				batch[i] = abstract.ChangeItem{
+					ID:               0,
+					LSN:              0,
+					CommitTime:       uint64(st.UnixNano()),
+					Counter:          0,
+					Kind:             abstract.InsertKind,
+					Schema:           table.Schema,
+					Table:            table.Name,
+					PartID:           partID,
+					ColumnNames:      colNames,
+					ColumnValues:     vals,
+					TableSchema:      keysSchema,
+					OldKeys:          abstract.EmptyOldKeys(),
+					Size:             abstract.RawEventSize(util.DeepSizeof(vals)),
+					TxID:             "",
+					Query:            "",
+					QueueMessageMeta: changeitem.QueueMessageMeta{TopicName: "", PartitionNum: 0, Offset: 0, Index: 0},
+				}
+			}
+			s.metrics.ChangeItems.Add(int64(len(batch)))
+			if err := pusher(batch); err != nil {
+				return xerrors.Errorf("unable to push %d rows of table %s: %w", len(batch), table.Fqtn(), err)
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// loadKeys reads types & values of keys by two pipelines, keys which are removed meanwhile & streams are skipped
+func (s *Storage) loadKeys(ctx context.Context, keys []string) ([][]interface{}, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	typeCmds := make([]*goredis.StatusCmd, len(keys))
+	if _, err := s.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for i, key := range keys {
+			typeCmds[i] = pipe.Type(ctx, key)
+		}
+		return nil
+	}); err != nil {
+		return nil, xerrors.Errorf("TYPE failed: %w", err)
+	}
+
+	types := make([]string, len(keys))
+	valueCmds := make([]goredis.Cmder, len(keys))
+	if _, err := s.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for i, key := range keys {
+			types[i] = typeCmds[i].Val()
+			switch types[i] {
+			case "string":
+				valueCmds[i] = pipe.Get(ctx, key)
+			case "hash":
+				valueCmds[i] = pipe.HGetAll(ctx, key)
+			case "list":
+				valueCmds[i] = pipe.LRange(ctx, key, 0, -1)
+			case "set":
+				valueCmds[i] = pipe.SMembers(ctx, key)
+			case "zset":
+				valueCmds[i] = pipe.ZRangeWithScores(ctx, key, 0, -1)
+			}
+		}
+		return nil
+	}); err != nil && !xerrors.Is(err, goredis.Nil) {
+		return nil, xerrors.Errorf("unable to read values: %w", err)
+	}
+
+	var result [][]interface{}
+	for i, key := range keys {
+		if valueCmds[i] == nil {
+			continue
+		}
+		value, err := cmdValue(valueCmds[i])
+		if xerrors.Is(err, goredis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read value of key %s: %w", key, err)
+		}
+		result = append(result, []interface{}{key, types[i], value})
+	}
+	return result, nil
+}
+
+func cmdValue(cmd goredis.Cmder) (interface{}, error) {
+	switch c := cmd.(type) {
+	case *goredis.StringCmd:
+		return c.Result()
+	case *goredis.MapStringStringCmd:
+		return c.Result()
+	case *goredis.StringSliceCmd:
+		return c.Result()
+	case *goredis.ZSliceCmd:
+		members, err := c.Result()
+		if err != nil {
+			return nil, err
+		}
+		result := make(map[string]float64, len(members))
+		for _, member := range members {
+			result[toString(member.Member)] = member.Score
+		}
+		return result, nil
+	default:
+		return nil, xerrors.Errorf("unexpected command %T", cmd)
+	}
+}
+
+// ExactTableRowsCount counts keys matching the pattern, streams are counted as well
+func (s *Storage) ExactTableRowsCount(table abstract.TableID) (uint64, error) {
+	ctx := context.Background()
+	var count uint64
+	var cursor uint64
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, s.pattern(), int64(s.config.BatchSize)).Result()
+		if err != nil {
+			return 0, xerrors.Errorf("SCAN failed: %w", err)
+		}
+		count += uint64(len(keys))
+		if next == 0 {
+			return count, nil
+		}
+		cursor = next
+	}
+}
+
+// EstimateTableRowsCount returns the number of all keys of the database, which is the upper bound for keys matching the pattern
+func (s *Storage) EstimateTableRowsCount(table abstract.TableID) (uint64, error) {
+	count, err := s.client.DBSize(context.Background()).Result()
+	if err != nil {
+		return 0, xerrors.Errorf("DBSIZE failed: %w", err)
+	}
+	return uint64(count), nil
+}
+
+func (s *Storage) TableExists(table abstract.TableID) (bool, error) {
+	return table == s.config.KeysTableID(), nil
+}
+
+func NewStorage(config *RedisSource, lgr log.Logger, registry metrics.Registry) (*Storage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+	start := time.Now()
+	client, err := config.Connection.Connect(ctx, config.Consumer)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to connect: %w", err)
+	}
+	lgr.Info("connected to source", log.String("addr", config.Connection.Addr), log.Duration("elapsed", time.Since(start)))
+	return &Storage{
+		client:  client,
+		config:  config,
+		logger:  lgr,
+		metrics: stats.NewSourceStats(registry),
+	}, nil
+}
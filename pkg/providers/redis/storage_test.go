@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/pkg/abstract"
+)
+
+func TestStorage(t *testing.T) {
+	connection, client := runServer(t)
+	ctx := context.Background()
+	require.NoError(t, client.Set(ctx, "user:1", "alice", 0).Err())
+	require.NoError(t, client.HSet(ctx, "user:2", "name", "bob").Err())
+	require.NoError(t, client.RPush(ctx, "user:3", "a", "b").Err())
+	require.NoError(t, client.SAdd(ctx, "user:4", "x").Err())
+	require.NoError(t, client.ZAdd(ctx, "user:5", goredis.Z{Score: 1.5, Member: "m"}).Err())
+	require.NoError(t, client.XAdd(ctx, &goredis.XAddArgs{Stream: "user:6", Values: []string{"a", "1"}}).Err())
+	require.NoError(t, client.Set(ctx, "other", "skipped", 0).Err())
+
+	src := &RedisSource{Connection: connection, KeyPattern: "user:*", BatchSize: 2}
+	src.WithDefaults()
+	storage, err := NewStorage(src, logger.Log, solomon.NewRegistry(nil))
+	require.NoError(t, err)
+	defer storage.Close()
+	require.NoError(t, storage.Ping())
+
+	tables, err := storage.TableList(nil)
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	require.Equal(t, uint64(7), tables[abstract.TableID{Namespace: "", Name: "keys"}].EtaRow)
+	count, err := storage.ExactTableRowsCount(src.KeysTableID())
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), count)
+
+	values := map[string][]interface{}{}
+	require.NoError(t, storage.LoadTable(ctx, abstract.TableDescription{Name: "keys", Schema: "", Filter: "", EtaRow: 0, Offset: 0}, func(items []abstract.ChangeItem) error {
+		for _, item := range items {
+			require.Equal(t, []string{"key", "type", "value"}, item.ColumnNames)
+			values[item.ColumnValues[0].(string)] = item.ColumnValues[1:]
+		}
+		return nil
+	}))
+	require.Equal(t, map[string][]interface{}{
+		"user:1": {"string", "alice"},
+		"user:2": {"hash", map[string]string{"name": "bob"}},
+		"user:3": {"list", []string{"a", "b"}},
+		"user:4": {"set", []string{"x"}},
+		"user:5": {"zset", map[string]float64{"m": 1.5}},
+	}, values)
+}
@@ -1,6 +1,7 @@
 package s3
 
 import (
+	"slices"
 	"time"
 
 	"github.com/transferia/transferia/library/go/core/xerrors"
@@ -26,10 +27,15 @@ const (
 type Encoding string
 
 const (
-	NoEncoding   = Encoding("UNCOMPRESSED")
-	GzipEncoding = Encoding("GZIP")
+	NoEncoding     = Encoding("UNCOMPRESSED")
+	GzipEncoding   = Encoding("GZIP")
+	ZstdEncoding   = Encoding("ZSTD")
+	Lz4Encoding    = Encoding("LZ4")
+	SnappyEncoding = Encoding("SNAPPY")
 )
 
+var knownEncodings = []Encoding{NoEncoding, GzipEncoding, ZstdEncoding, Lz4Encoding, SnappyEncoding}
+
 type S3Destination struct {
 	OutputFormat     dp_model.ParsingFormat
 	OutputEncoding   Encoding
@@ -50,14 +56,23 @@ type S3Destination struct {
 	PartSize         int64
 	Concurrency      int64
 	AnyAsString      bool
+
+	// PartitionBy enables Hive-style partitioning: objects are written under `<layout>/<table>/<name>=<value>/...` prefixes
+	PartitionBy []PartitionField
+	// MaxOpenPartitions limits the number of partitions of a table uploaded at once by snapshot,
+	// the least recently written partition object is completed when the limit is reached
+	MaxOpenPartitions int
 }
 
 var _ dp_model.Destination = (*S3Destination)(nil)
 
 func (d *S3Destination) WithDefaults() {
-	if d.Layout == "" {
+	if d.Layout == "" && len(d.PartitionBy) == 0 {
 		d.Layout = "2006/01/02"
 	}
+	if d.MaxOpenPartitions == 0 {
+		d.MaxOpenPartitions = DefaultMaxOpenPartitions
+	}
 	if d.BufferInterval == 0 {
 		d.BufferInterval = time.Second * 30
 	}
@@ -106,6 +121,25 @@ func (d *S3Destination) GetProviderType() abstract.ProviderType {
 }
 
 func (d *S3Destination) Validate() error {
+	if d.OutputEncoding != "" && !slices.Contains(knownEncodings, d.OutputEncoding) {
+		return xerrors.Errorf("unknown output encoding: %s", d.OutputEncoding)
+	}
+	names := map[string]bool{}
+	for _, field := range d.PartitionBy {
+		if field.Column == "" {
+			return xerrors.New("partition column is required")
+		}
+		if _, _, err := field.ParseTransform(); err != nil {
+			return xerrors.Errorf("invalid partitioning: %w", err)
+		}
+		if names[field.SegmentName()] {
+			return xerrors.Errorf("duplicate partition name: %s", field.SegmentName())
+		}
+		names[field.SegmentName()] = true
+	}
+	if d.MaxOpenPartitions < 0 {
+		return xerrors.Errorf("max open partitions must be positive: %d", d.MaxOpenPartitions)
+	}
 	return nil
 }
 
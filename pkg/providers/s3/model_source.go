@@ -50,6 +50,8 @@ type S3Source struct {
 
 	InputFormat  model.ParsingFormat
 	OutputSchema []abstract.ColSchema
+	// PartitionColumns are filled with values of Hive-style `<column>=<value>/` segments of object keys
+	PartitionColumns []abstract.ColSchema
 
 	AirbyteFormat string // this is for backward compatibility with airbyte. we store raw format for later parsing.
 	PathPattern   string
@@ -0,0 +1,121 @@
+package s3
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+)
+
+const (
+	DefaultMaxOpenPartitions = 32
+
+	// HiveDefaultPartition is the path value of null partition values
+	HiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+)
+
+type PartitionTransform string
+
+const (
+	IdentityTransform = PartitionTransform("identity")
+	YearTransform     = PartitionTransform("year")
+	MonthTransform    = PartitionTransform("month")
+	DayTransform      = PartitionTransform("day")
+	HourTransform     = PartitionTransform("hour")
+	BucketTransform   = PartitionTransform("bucket")
+	TruncateTransform = PartitionTransform("truncate")
+)
+
+var parametrizedTransform = regexp.MustCompile(`^(bucket|truncate)\((\d+)\)$`)
+
+// PartitionField is a column of Hive-style partitioning, objects of a partition are written under the `<name>=<value>/` path segment
+type PartitionField struct {
+	Column string
+	// Transform of the column value: identity (default), year, month, day, hour, bucket(N) or truncate(N)
+	Transform string
+	// Name of the path segment, it's the column name for identity transform and `<column>_<transform>` for others by default
+	Name string
+}
+
+// ParseTransform returns the transform kind and its parameter, which is the number of buckets or the truncation width
+func (f PartitionField) ParseTransform() (PartitionTransform, int, error) {
+	switch transform := PartitionTransform(strings.ToLower(strings.ReplaceAll(f.Transform, " ", ""))); transform {
+	case "", IdentityTransform:
+		return IdentityTransform, 0, nil
+	case YearTransform, MonthTransform, DayTransform, HourTransform:
+		return transform, 0, nil
+	default:
+		match := parametrizedTransform.FindStringSubmatch(string(transform))
+		if match == nil {
+			return "", 0, xerrors.Errorf("unknown partition transform %q of column %s", f.Transform, f.Column)
+		}
+		param, err := strconv.Atoi(match[2])
+		if err != nil || param <= 0 {
+			return "", 0, xerrors.Errorf("partition transform %q of column %s requires positive parameter", f.Transform, f.Column)
+		}
+		return PartitionTransform(match[1]), param, nil
+	}
+}
+
+// SegmentName is the name of the path segment of the field
+func (f PartitionField) SegmentName() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	transform, _, err := f.ParseTransform()
+	if err != nil || transform == IdentityTransform {
+		return f.Column
+	}
+	if transform == TruncateTransform {
+		return fmt.Sprintf("%s_trunc", f.Column)
+	}
+	return fmt.Sprintf("%s_%s", f.Column, transform)
+}
+
+// EscapeHivePathValue escapes the value of a path segment in the same way as Hive does
+func EscapeHivePathValue(value string) string {
+	var sb strings.Builder
+	for _, b := range []byte(value) {
+		if b < ' ' || b == 0x7f || strings.IndexByte("\"#%'*/:=?\\{[]^", b) >= 0 {
+			sb.WriteString(fmt.Sprintf("%%%02X", b))
+			continue
+		}
+		sb.WriteByte(b)
+	}
+	return sb.String()
+}
+
+// UnescapeHivePathValue reverts EscapeHivePathValue
+func UnescapeHivePathValue(value string) (string, error) {
+	unescaped, err := url.PathUnescape(value)
+	if err != nil {
+		return "", xerrors.Errorf("invalid escaping of path value %q: %w", value, err)
+	}
+	return unescaped, nil
+}
+
+// ParseHivePath returns values of `<name>=<value>` segments of the object key, nil value for the default partition
+func ParseHivePath(key string) (map[string]*string, error) {
+	segments := strings.Split(key, "/")
+	result := map[string]*string{}
+	// the last segment is the object name
+	for _, segment := range segments[:len(segments)-1] {
+		name, value, ok := strings.Cut(segment, "=")
+		if !ok || name == "" {
+			continue
+		}
+		if value == HiveDefaultPartition {
+			result[name] = nil
+			continue
+		}
+		unescaped, err := UnescapeHivePathValue(value)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to parse segment %s: %w", segment, err)
+		}
+		result[name] = &unescaped
+	}
+	return result, nil
+}
@@ -37,6 +37,16 @@ type RowsCountEstimator interface {
 	EstimateRowsCountOneObject(ctx context.Context, obj *aws_s3.Object) (uint64, error)
 }
 
+// SplitReader is implemented by readers of formats, which objects consist of independently readable parts,
+// like blocks of Avro or stripes of ORC, so big objects are read by several parts in parallel
+type SplitReader interface {
+	// Splittable is false if objects are read only as a whole, it's used by wrappers of readers
+	Splittable() bool
+
+	// ReadSplit reads parts of the object, which start in the [from, to) range of bytes
+	ReadSplit(ctx context.Context, filePath string, from, to int64, pusher pusher.Pusher) error
+}
+
 // ObjectsFilter returns true for needful objects, false for objects that should be ignored (skipped).
 type ObjectsFilter func(file *aws_s3.Object) bool
 
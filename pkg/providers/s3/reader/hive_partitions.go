@@ -0,0 +1,222 @@
+package reader
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	aws_s3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/providers/s3"
+	chunk_pusher "github.com/transferia/transferia/pkg/providers/s3/pusher"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+var (
+	_ Reader             = (*HivePartitionsReader)(nil)
+	_ RowsCountEstimator = (*HivePartitionsReader)(nil)
+	_ SplitReader        = (*HivePartitionsReader)(nil)
+)
+
+// HivePartitionsReader fills partition columns of rows with values of `<name>=<value>/` segments of object keys
+type HivePartitionsReader struct {
+	impl    Reader
+	columns []abstract.ColSchema
+
+	mu      sync.Mutex
+	schemas map[*abstract.TableSchema]*abstract.TableSchema
+}
+
+func (r *HivePartitionsReader) Read(ctx context.Context, filePath string, pusher chunk_pusher.Pusher) error {
+	values, err := r.partitionValues(filePath)
+	if err != nil {
+		return xerrors.Errorf("unable to parse partitions of %s: %w", filePath, err)
+	}
+	return r.impl.Read(ctx, filePath, &hivePartitionsPusher{
+		Pusher: pusher,
+		reader: r,
+		values: values,
+	})
+}
+
+func (r *HivePartitionsReader) ParsePassthrough(chunk chunk_pusher.Chunk) []abstract.ChangeItem {
+	return r.impl.ParsePassthrough(chunk)
+}
+
+func (r *HivePartitionsReader) ObjectsFilter() ObjectsFilter {
+	return r.impl.ObjectsFilter()
+}
+
+func (r *HivePartitionsReader) ResolveSchema(ctx context.Context) (*abstract.TableSchema, error) {
+	tableSchema, err := r.impl.ResolveSchema(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to resolve schema: %w", err)
+	}
+	return r.extendSchema(tableSchema), nil
+}
+
+func (r *HivePartitionsReader) EstimateRowsCountAllObjects(ctx context.Context) (uint64, error) {
+	rowCounter, ok := r.impl.(RowsCountEstimator)
+	if !ok {
+		return 0, xerrors.Errorf("unable to cast r.impl to RowsCountEstimator, type of r.impl: %T", r.impl)
+	}
+	return rowCounter.EstimateRowsCountAllObjects(ctx)
+}
+
+func (r *HivePartitionsReader) EstimateRowsCountOneObject(ctx context.Context, obj *aws_s3.Object) (uint64, error) {
+	rowCounter, ok := r.impl.(RowsCountEstimator)
+	if !ok {
+		return 0, xerrors.Errorf("unable to cast r.impl to RowsCountEstimator, type of r.impl: %T", r.impl)
+	}
+	return rowCounter.EstimateRowsCountOneObject(ctx, obj)
+}
+
+func (r *HivePartitionsReader) ReadSplit(ctx context.Context, filePath string, from, to int64, pusher chunk_pusher.Pusher) error {
+	splitReader, ok := r.impl.(SplitReader)
+	if !ok {
+		return xerrors.Errorf("unable to cast r.impl to SplitReader, type of r.impl: %T", r.impl)
+	}
+	values, err := r.partitionValues(filePath)
+	if err != nil {
+		return xerrors.Errorf("unable to parse partitions of %s: %w", filePath, err)
+	}
+	return splitReader.ReadSplit(ctx, filePath, from, to, &hivePartitionsPusher{
+		Pusher: pusher,
+		reader: r,
+		values: values,
+	})
+}
+
+func (r *HivePartitionsReader) Splittable() bool {
+	splitReader, ok := r.impl.(SplitReader)
+	return ok && splitReader.Splittable()
+}
+
+// partitionValues returns values of partition columns, in the order of columns, parsed from the object key
+func (r *HivePartitionsReader) partitionValues(filePath string) ([]any, error) {
+	segments, err := s3.ParseHivePath(filePath)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to parse object key: %w", err)
+	}
+	values := make([]any, len(r.columns))
+	for i, col := range r.columns {
+		value, ok := segments[col.ColumnName]
+		if !ok || value == nil {
+			continue
+		}
+		values[i], err = castPartitionValue(*value, col.DataType)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to cast value of partition column %s: %w", col.ColumnName, err)
+		}
+	}
+	return values, nil
+}
+
+// extendSchema appends partition columns missing in the table schema, results are cached by the source schema
+func (r *HivePartitionsReader) extendSchema(tableSchema *abstract.TableSchema) *abstract.TableSchema {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if extended, ok := r.schemas[tableSchema]; ok {
+		return extended
+	}
+	cols := tableSchema.Columns().Copy()
+	fastCols := tableSchema.FastColumns()
+	for _, col := range r.columns {
+		if _, ok := fastCols[abstract.ColumnName(col.ColumnName)]; !ok {
+			cols = append(cols, col)
+		}
+	}
+	extended := abstract.NewTableSchema(cols)
+	r.schemas[tableSchema] = extended
+	return extended
+}
+
+func (r *HivePartitionsReader) fillRow(row *abstract.ChangeItem, values []any) {
+	if !row.IsRowEvent() {
+		return
+	}
+	for i, col := range r.columns {
+		if idx := row.ColumnNameIndex(col.ColumnName); idx >= 0 {
+			row.ColumnValues[idx] = values[i]
+			continue
+		}
+		row.ColumnNames = append(row.ColumnNames, col.ColumnName)
+		row.ColumnValues = append(row.ColumnValues, values[i])
+	}
+	if row.TableSchema != nil {
+		row.SetTableSchema(r.extendSchema(row.TableSchema))
+	}
+}
+
+type hivePartitionsPusher struct {
+	chunk_pusher.Pusher
+	reader *HivePartitionsReader
+	values []any
+}
+
+func (p *hivePartitionsPusher) Push(ctx context.Context, chunk chunk_pusher.Chunk) error {
+	for i := range chunk.Items {
+		p.reader.fillRow(&chunk.Items[i], p.values)
+	}
+	return p.Pusher.Push(ctx, chunk)
+}
+
+func castPartitionValue(value string, dataType string) (any, error) {
+	switch schema.Type(dataType) {
+	case schema.TypeInt8:
+		v, err := strconv.ParseInt(value, 10, 8)
+		return int8(v), err
+	case schema.TypeInt16:
+		v, err := strconv.ParseInt(value, 10, 16)
+		return int16(v), err
+	case schema.TypeInt32:
+		v, err := strconv.ParseInt(value, 10, 32)
+		return int32(v), err
+	case schema.TypeInt64:
+		return strconv.ParseInt(value, 10, 64)
+	case schema.TypeUint8:
+		v, err := strconv.ParseUint(value, 10, 8)
+		return uint8(v), err
+	case schema.TypeUint16:
+		v, err := strconv.ParseUint(value, 10, 16)
+		return uint16(v), err
+	case schema.TypeUint32:
+		v, err := strconv.ParseUint(value, 10, 32)
+		return uint32(v), err
+	case schema.TypeUint64:
+		return strconv.ParseUint(value, 10, 64)
+	case schema.TypeFloat32:
+		v, err := strconv.ParseFloat(value, 32)
+		return float32(v), err
+	case schema.TypeFloat64:
+		return strconv.ParseFloat(value, 64)
+	case schema.TypeBoolean:
+		return strconv.ParseBool(value)
+	case schema.TypeDate:
+		return time.Parse("2006-01-02", value)
+	case schema.TypeDatetime, schema.TypeTimestamp:
+		for _, layout := range []string{"2006-01-02 15:04:05.999999999", time.RFC3339Nano, "2006-01-02-15", "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+		}
+		return nil, xerrors.Errorf("unknown time format of %q", value)
+	default:
+		return value, nil
+	}
+}
+
+// NewHivePartitionsReader returns the reader as is if there are no partition columns
+func NewHivePartitionsReader(impl Reader, columns []abstract.ColSchema) Reader {
+	if len(columns) == 0 {
+		return impl
+	}
+	return &HivePartitionsReader{
+		impl:    impl,
+		columns: columns,
+		mu:      sync.Mutex{},
+		schemas: map[*abstract.TableSchema]*abstract.TableSchema{},
+	}
+}
@@ -0,0 +1,99 @@
+package reader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/abstract"
+	chunk_pusher "github.com/transferia/transferia/pkg/providers/s3/pusher"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+type fakeRowsReader struct {
+	tableSchema *abstract.TableSchema
+}
+
+func (r *fakeRowsReader) Read(ctx context.Context, filePath string, pusher chunk_pusher.Pusher) error {
+	items := []abstract.ChangeItem{{
+		Kind:         abstract.InsertKind,
+		ColumnNames:  []string{"id", "country"},
+		ColumnValues: []any{int64(1), "from file"},
+		TableSchema:  r.tableSchema,
+	}}
+	return FlushChunk(ctx, filePath, 0, 1, items, pusher)
+}
+
+func (r *fakeRowsReader) ParsePassthrough(chunk chunk_pusher.Chunk) []abstract.ChangeItem {
+	return chunk.Items
+}
+
+func (r *fakeRowsReader) ObjectsFilter() ObjectsFilter { return IsNotEmpty }
+
+func (r *fakeRowsReader) ResolveSchema(ctx context.Context) (*abstract.TableSchema, error) {
+	return r.tableSchema, nil
+}
+
+func TestHivePartitionsReader(t *testing.T) {
+	tableSchema := abstract.NewTableSchema([]abstract.ColSchema{
+		abstract.NewColSchema("id", schema.TypeInt64, true),
+		abstract.NewColSchema("country", schema.TypeString, false),
+	})
+	partitionColumns := []abstract.ColSchema{
+		abstract.NewColSchema("country", schema.TypeString, false),
+		abstract.NewColSchema("dt", schema.TypeDate, false),
+		abstract.NewColSchema("bucket", schema.TypeUint32, false),
+	}
+	impl := &fakeRowsReader{tableSchema: tableSchema}
+	require.Equal(t, impl, NewHivePartitionsReader(impl, nil))
+	hiveReader := NewHivePartitionsReader(impl, partitionColumns)
+
+	resolved, err := hiveReader.ResolveSchema(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "country", "dt", "bucket"}, resolved.ColumnNames())
+
+	var pushed []abstract.ChangeItem
+	pusher := chunk_pusher.NewSyncPusher(func(items []abstract.ChangeItem) error {
+		pushed = append(pushed, items...)
+		return nil
+	})
+	require.NoError(t, hiveReader.Read(context.Background(), "table/country=US%2FCA/dt=2026-10-17/bucket=__HIVE_DEFAULT_PARTITION__/part.json", pusher))
+	require.Len(t, pushed, 1)
+	require.Equal(t, []string{"id", "country", "dt", "bucket"}, pushed[0].ColumnNames)
+	require.Equal(t, []any{int64(1), "US/CA", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), nil}, pushed[0].ColumnValues)
+	require.Equal(t, resolved, pushed[0].TableSchema)
+
+	require.Error(t, hiveReader.Read(context.Background(), "table/bucket=abc/part.json", pusher))
+}
+
+func TestCastPartitionValue(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		dataType schema.Type
+		expected any
+	}{
+		{value: "-5", dataType: schema.TypeInt8, expected: int8(-5)},
+		{value: "-5", dataType: schema.TypeInt16, expected: int16(-5)},
+		{value: "-5", dataType: schema.TypeInt32, expected: int32(-5)},
+		{value: "-5", dataType: schema.TypeInt64, expected: int64(-5)},
+		{value: "5", dataType: schema.TypeUint8, expected: uint8(5)},
+		{value: "5", dataType: schema.TypeUint16, expected: uint16(5)},
+		{value: "5", dataType: schema.TypeUint32, expected: uint32(5)},
+		{value: "5", dataType: schema.TypeUint64, expected: uint64(5)},
+		{value: "1.5", dataType: schema.TypeFloat32, expected: float32(1.5)},
+		{value: "1.5", dataType: schema.TypeFloat64, expected: 1.5},
+		{value: "true", dataType: schema.TypeBoolean, expected: true},
+		{value: "2026-10-17 13:05:00", dataType: schema.TypeTimestamp, expected: time.Date(2026, 10, 17, 13, 5, 0, 0, time.UTC)},
+		{value: "2026-10-17-13", dataType: schema.TypeDatetime, expected: time.Date(2026, 10, 17, 13, 0, 0, 0, time.UTC)},
+		{value: "US", dataType: schema.TypeString, expected: "US"},
+	} {
+		value, err := castPartitionValue(tc.value, tc.dataType.String())
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, value)
+	}
+	_, err := castPartitionValue("yesterday", schema.TypeDate.String())
+	require.Error(t, err)
+	_, err = castPartitionValue("300", schema.TypeInt8.String())
+	require.Error(t, err)
+}
@@ -51,5 +51,5 @@ func New(
 	if err != nil {
 		return nil, xerrors.Errorf("unable to create new reader: %w", err)
 	}
-	return NewReaderContractor(result), nil
+	return NewReaderContractor(NewHivePartitionsReader(result, src.PartitionColumns)), nil
 }
@@ -63,6 +63,20 @@ func (c *ReaderContractor) EstimateRowsCountOneObject(ctx context.Context, obj *
 	return rowCounter.EstimateRowsCountOneObject(ctx, obj)
 }
 
+// ReadSplit doesn't mark the object as completed, since it's read by parts
+func (c *ReaderContractor) ReadSplit(ctx context.Context, filePath string, from, to int64, pusher chunk_pusher.Pusher) error {
+	splitReader, ok := c.impl.(SplitReader)
+	if !ok {
+		return xerrors.Errorf("unable to cast c.impl to SplitReader, type of c.impl: %T", c.impl)
+	}
+	return splitReader.ReadSplit(ctx, filePath, from, to, pusher)
+}
+
+func (c *ReaderContractor) Splittable() bool {
+	splitReader, ok := c.impl.(SplitReader)
+	return ok && splitReader.Splittable()
+}
+
 func NewReaderContractor(in Reader) *ReaderContractor {
 	return &ReaderContractor{
 		impl: in,
@@ -0,0 +1,440 @@
+package reader
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	aws_s3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	yslices "github.com/transferia/transferia/library/go/slices"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/changeitem"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/avro"
+	"github.com/transferia/transferia/pkg/providers/s3"
+	chunk_pusher "github.com/transferia/transferia/pkg/providers/s3/pusher"
+	abstract_reader "github.com/transferia/transferia/pkg/providers/s3/reader"
+	"github.com/transferia/transferia/pkg/providers/s3/reader/s3raw"
+	"github.com/transferia/transferia/pkg/providers/s3/s3util"
+	"github.com/transferia/transferia/pkg/stats"
+	"github.com/transferia/transferia/pkg/util"
+	"go.ytsaurus.tech/library/go/core/log"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+var (
+	_ abstract_reader.Reader             = (*ReaderAvro)(nil)
+	_ abstract_reader.RowsCountEstimator = (*ReaderAvro)(nil)
+	_ abstract_reader.SplitReader        = (*ReaderAvro)(nil)
+)
+
+const (
+	// headerBufferSize is enough to read the header of most files by one ranged request
+	headerBufferSize = 64 * 1024
+	// blocksBufferSize is the size of ranged requests, which blocks are read by
+	blocksBufferSize = 8 * 1024 * 1024
+
+	// blockRowBits is the number of low bits of row index of split reads, which hold the index of row in its block,
+	// high bits hold the offset of block
+	blockRowBits = 20
+)
+
+func init() {
+	abstract_reader.RegisterReader(model.ParsingFormatAVRO, NewAvro)
+}
+
+// ReaderAvro reads Avro object container files, their blocks are independently readable,
+// so big files are read by parts, see ReadSplit
+type ReaderAvro struct {
+	table          abstract.TableID
+	bucket         string
+	client         s3iface.S3API
+	logger         log.Logger
+	tableSchema    *abstract.TableSchema
+	colNames       []string
+	hideSystemCols bool
+	batchSize      int
+	pathPrefix     string
+	pathPattern    string
+	metrics        *stats.SourceStats
+}
+
+// EstimateRowsCountOneObject extrapolates the number of rows of the first block to the whole file,
+// since the header of Avro file doesn't contain the number of rows
+func (r *ReaderAvro) EstimateRowsCountOneObject(ctx context.Context, obj *aws_s3.Object) (uint64, error) {
+	sr, ocf, err := r.openFile(ctx, *obj.Key, blocksBufferSize)
+	if err != nil {
+		return 0, xerrors.Errorf("unable to read file header: %s: %w", *obj.Key, err)
+	}
+	defer sr.Close()
+
+	rows := uint64(0)
+	for ocf.NextOffset() == ocf.HeaderSize() {
+		if _, err := ocf.Next(); err != nil {
+			if xerrors.Is(err, io.EOF) {
+				return rows, nil
+			}
+			return 0, xerrors.Errorf("unable to read first block: %s: %w", *obj.Key, err)
+		}
+		rows++
+	}
+	blockSize := ocf.NextOffset() - ocf.HeaderSize()
+	return uint64(float64(rows) * float64(sr.Size()-ocf.HeaderSize()) / float64(blockSize)), nil
+}
+
+func (r *ReaderAvro) EstimateRowsCountAllObjects(ctx context.Context) (uint64, error) {
+	res := uint64(0)
+	files, err := s3util.ListFiles(r.bucket, r.pathPrefix, r.pathPattern, r.client, r.logger, nil, r.ObjectsFilter())
+	if err != nil {
+		return 0, xerrors.Errorf("unable to load file list: %w", err)
+	}
+	for i, file := range files {
+		rows, err := r.EstimateRowsCountOneObject(ctx, file)
+		if err != nil {
+			return 0, xerrors.Errorf("unable to estimate rows of file: %s: %w", *file.Key, err)
+		}
+		res += rows
+		// once we reach limit of files to estimate - stop and approximate
+		if i > abstract_reader.EstimateFilesLimit {
+			break
+		}
+	}
+	if len(files) > abstract_reader.EstimateFilesLimit {
+		multiplier := float64(len(files)) / float64(abstract_reader.EstimateFilesLimit)
+		return uint64(float64(res) * multiplier), nil
+	}
+	return res, nil
+}
+
+func (r *ReaderAvro) ResolveSchema(ctx context.Context) (*abstract.TableSchema, error) {
+	if r.tableSchema != nil && len(r.tableSchema.Columns()) != 0 {
+		return r.tableSchema, nil
+	}
+
+	files, err := s3util.ListFiles(r.bucket, r.pathPrefix, r.pathPattern, r.client, r.logger, aws.Int(1), r.ObjectsFilter())
+	if err != nil {
+		return nil, xerrors.Errorf("unable to load file list: %w", err)
+	}
+
+	if len(files) < 1 {
+		return nil, xerrors.Errorf("unable to resolve schema, no avro files found for prefix '%s'", r.pathPrefix)
+	}
+
+	return r.resolveSchema(ctx, *files[0].Key)
+}
+
+func (r *ReaderAvro) ObjectsFilter() abstract_reader.ObjectsFilter {
+	return func(file *aws_s3.Object) bool {
+		if !abstract_reader.IsNotEmpty(file) {
+			return false
+		}
+		return strings.HasSuffix(*file.Key, ".avro")
+	}
+}
+
+func (r *ReaderAvro) resolveSchema(ctx context.Context, filePath string) (*abstract.TableSchema, error) {
+	sr, ocf, err := r.openFile(ctx, filePath, headerBufferSize)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read header: %s: %w", filePath, err)
+	}
+	defer sr.Close()
+
+	record := ocf.Schema()
+	if record.Type != avro.TypeRecord {
+		return nil, xerrors.Errorf("schema of file %s must be record, but got: %s", filePath, record.Type)
+	}
+	cols := make([]abstract.ColSchema, 0, len(record.Fields))
+	for _, field := range record.Fields {
+		typ, required := avroToYtType(field.Type)
+		col := abstract.NewColSchema(field.Name, typ, false)
+		col.Required = required
+		col.OriginalType = fmt.Sprintf("avro:%s", avroTypeName(field.Type))
+		cols = append(cols, col)
+	}
+	return abstract.NewTableSchema(cols), nil
+}
+
+// openFile reads the header of file, blocks are read by ranged requests of bufferSize bytes
+func (r *ReaderAvro) openFile(ctx context.Context, filePath string, bufferSize int) (s3raw.S3RawReader, *avro.OCFReader, error) {
+	sr, err := s3raw.NewS3RawReader(ctx, r.client, r.bucket, filePath, r.metrics)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("unable to create reader at: %w", err)
+	}
+	ocf, err := avro.NewOCFReader(bufio.NewReaderSize(io.NewSectionReader(sr, 0, sr.Size()), bufferSize))
+	if err != nil {
+		_ = sr.Close()
+		return nil, nil, xerrors.Errorf("unable to read header: %w", err)
+	}
+	return sr, ocf, nil
+}
+
+func (r *ReaderAvro) Read(ctx context.Context, filePath string, pusher chunk_pusher.Pusher) error {
+	sr, err := s3raw.NewS3RawReader(ctx, r.client, r.bucket, filePath, r.metrics)
+	if err != nil {
+		return xerrors.Errorf("unable to create reader at: %w", err)
+	}
+	defer sr.Close()
+	ocf, err := avro.NewOCFReader(sr)
+	if err != nil {
+		return xerrors.Errorf("unable to open file: %w", err)
+	}
+
+	rowIndex := uint64(0)
+	return r.readBlocks(ctx, filePath, ocf, sr.LastModified(), sr.Size(), pusher, func(int64) (uint64, error) {
+		rowIndex++
+		return rowIndex, nil
+	})
+}
+
+// ReadSplit reads blocks, which start in the [from, to) range, blocks are found by sync markers.
+// Row indexes of split reads are offsets of blocks shifted by blockRowBits, combined with indexes of rows in blocks
+func (r *ReaderAvro) ReadSplit(ctx context.Context, filePath string, from, to int64, pusher chunk_pusher.Pusher) error {
+	sr, header, err := r.openFile(ctx, filePath, headerBufferSize)
+	if err != nil {
+		return xerrors.Errorf("unable to open file: %w", err)
+	}
+	defer sr.Close()
+	start, err := header.FindBlock(sr, sr.Size(), from)
+	if err != nil {
+		return xerrors.Errorf("unable to find block after %d: %w", from, err)
+	}
+	if start >= to {
+		r.logger.Infof("part: %s has no blocks in range [%d, %d)", filePath, from, to)
+		return nil
+	}
+	ocf := header.Resume(bufio.NewReaderSize(io.NewSectionReader(sr, start, sr.Size()-start), blocksBufferSize), start)
+
+	blockOffset, blockRow := int64(-1), uint64(0)
+	return r.readBlocks(ctx, filePath, ocf, sr.LastModified(), to, pusher, func(offset int64) (uint64, error) {
+		if offset != blockOffset {
+			blockOffset, blockRow = offset, 0
+		}
+		if blockRow >= 1<<blockRowBits {
+			return 0, xerrors.Errorf("block at %d has more than %d rows", offset, 1<<blockRowBits)
+		}
+		blockRow++
+		return uint64(offset)<<blockRowBits | (blockRow - 1), nil
+	})
+}
+
+func (r *ReaderAvro) Splittable() bool {
+	return true
+}
+
+// readBlocks reads rows of blocks, which start before the to offset, rowIndex returns index of row of block at offset
+func (r *ReaderAvro) readBlocks(
+	ctx context.Context,
+	filePath string,
+	ocf *avro.OCFReader,
+	lastModified time.Time,
+	to int64,
+	pusher chunk_pusher.Pusher,
+	rowIndex func(offset int64) (uint64, error),
+) error {
+	var buff []abstract.ChangeItem
+	var currentSize int64
+	var idx uint64
+	for ocf.NextOffset() < to {
+		if ctx.Err() != nil {
+			r.logger.Info("Read canceled")
+			return nil
+		}
+		offset := ocf.NextOffset()
+		val, err := ocf.Next()
+		if err != nil {
+			if xerrors.Is(err, io.EOF) {
+				break
+			}
+			return xerrors.Errorf("unable to read row: %w", err)
+		}
+		row, ok := val.(map[string]any)
+		if !ok {
+			return xerrors.Errorf("row expected to be record, but got: %T", val)
+		}
+		idx, err = rowIndex(offset)
+		if err != nil {
+			return xerrors.Errorf("unable to index row: %w", err)
+		}
+		ci := r.constructCI(row, filePath, lastModified, idx)
+		currentSize += int64(ci.Size.Values)
+		buff = append(buff, ci)
+		if len(buff) > r.batchSize {
+			if err := abstract_reader.FlushChunk(ctx, filePath, idx, currentSize, buff, pusher); err != nil {
+				return xerrors.Errorf("unable to push avro batch: %w", err)
+			}
+			currentSize = 0
+			buff = []abstract.ChangeItem{}
+		}
+	}
+	if err := abstract_reader.FlushChunk(ctx, filePath, idx, currentSize, buff, pusher); err != nil {
+		return xerrors.Errorf("unable to push avro last batch: %w", err)
+	}
+	return nil
+}
+
+func (r *ReaderAvro) constructCI(row map[string]any, fname string, lModified time.Time, idx uint64) abstract.ChangeItem {
+	vals := make([]interface{}, len(r.tableSchema.Columns()))
+	for i, col := range r.tableSchema.Columns() {
+		if abstract_reader.SystemColumnNames[col.ColumnName] {
+			if r.hideSystemCols {
+				continue
+			}
+			switch col.ColumnName {
+			case abstract_reader.FileNameSystemCol:
+				vals[i] = fname
+			case abstract_reader.RowIndexSystemCol:
+				vals[i] = idx
+			}
+			continue
+		}
+		vals[i] = abstract.Restore(col, row[col.ColumnName])
+	}
+
+	return abstract.ChangeItem{
+		ID:               0,
+		LSN:              0,
+		CommitTime:       uint64(lModified.UnixNano()),
+		Counter:          int(idx),
+		Kind:             abstract.InsertKind,
+		Schema:           r.table.Namespace,
+		Table:            r.table.Name,
+		PartID:           fname,
+		ColumnNames:      r.colNames,
+		ColumnValues:     vals,
+		TableSchema:      r.tableSchema,
+		OldKeys:          abstract.EmptyOldKeys(),
+		Size:             abstract.RawEventSize(util.DeepSizeof(vals)),
+		TxID:             "",
+		Query:            "",
+		QueueMessageMeta: changeitem.QueueMessageMeta{TopicName: "", PartitionNum: 0, Offset: 0, Index: 0},
+	}
+}
+
+func (r *ReaderAvro) ParsePassthrough(chunk chunk_pusher.Chunk) []abstract.ChangeItem {
+	return chunk.Items
+}
+
+func NewAvro(src *s3.S3Source, lgr log.Logger, sess *session.Session, metrics *stats.SourceStats) (abstract_reader.Reader, error) {
+	if src == nil {
+		return nil, xerrors.New("uninitialized settings for avro reader")
+	}
+	return newReader(src, lgr, aws_s3.New(sess), metrics)
+}
+
+func newReader(src *s3.S3Source, lgr log.Logger, client s3iface.S3API, metrics *stats.SourceStats) (*ReaderAvro, error) {
+	reader := &ReaderAvro{
+		bucket:         src.Bucket,
+		hideSystemCols: src.HideSystemCols,
+		batchSize:      src.ReadBatchSize,
+		pathPrefix:     src.PathPrefix,
+		pathPattern:    src.PathPattern,
+		client:         client,
+		logger:         lgr,
+		table: abstract.TableID{
+			Namespace: src.TableNamespace,
+			Name:      src.TableName,
+		},
+		tableSchema: abstract.NewTableSchema(src.OutputSchema),
+		colNames:    nil,
+		metrics:     metrics,
+	}
+
+	if len(reader.tableSchema.Columns()) == 0 {
+		var err error
+		reader.tableSchema, err = reader.ResolveSchema(context.Background())
+		if err != nil {
+			return nil, xerrors.Errorf("unable to resolve schema: %w", err)
+		}
+	}
+
+	// append system columns at the end if necessary
+	if !reader.hideSystemCols {
+		cols := reader.tableSchema.Columns()
+		userDefinedSchemaHasPkey := reader.tableSchema.Columns().HasPrimaryKey()
+		reader.tableSchema = abstract_reader.AppendSystemColsTableSchema(cols, !userDefinedSchemaHasPkey)
+	}
+
+	reader.colNames = yslices.Map(reader.tableSchema.Columns(), func(t abstract.ColSchema) string { return t.ColumnName })
+	return reader, nil
+}
+
+var avroSchemaTypes = map[avro.Type]schema.Type{
+	avro.TypeNull:    schema.TypeAny,
+	avro.TypeBoolean: schema.TypeBoolean,
+	avro.TypeInt:     schema.TypeInt32,
+	avro.TypeLong:    schema.TypeInt64,
+	avro.TypeFloat:   schema.TypeFloat32,
+	avro.TypeDouble:  schema.TypeFloat64,
+	avro.TypeBytes:   schema.TypeBytes,
+	avro.TypeString:  schema.TypeString,
+	avro.TypeRecord:  schema.TypeAny,
+	avro.TypeEnum:    schema.TypeString,
+	avro.TypeArray:   schema.TypeAny,
+	avro.TypeMap:     schema.TypeAny,
+	avro.TypeFixed:   schema.TypeBytes,
+	avro.TypeUnion:   schema.TypeAny,
+}
+
+var avroLogicalTypes = map[avro.LogicalType]schema.Type{
+	avro.LogicalTypeDecimal:              schema.TypeString, // to not lose precision
+	avro.LogicalTypeUUID:                 schema.TypeString,
+	avro.LogicalTypeDate:                 schema.TypeDate,
+	avro.LogicalTypeTimeMillis:           schema.TypeInterval,
+	avro.LogicalTypeTimeMicros:           schema.TypeInterval,
+	avro.LogicalTypeTimestampMillis:      schema.TypeTimestamp,
+	avro.LogicalTypeTimestampMicros:      schema.TypeTimestamp,
+	avro.LogicalTypeTimestampNanos:       schema.TypeTimestamp,
+	avro.LogicalTypeLocalTimestampMillis: schema.TypeTimestamp,
+	avro.LogicalTypeLocalTimestampMicros: schema.TypeTimestamp,
+	avro.LogicalTypeLocalTimestampNanos:  schema.TypeTimestamp,
+}
+
+// avroToYtType returns yt type of column & 'required' flag.
+// Unions like ["null", T] are nullable columns of type T, any other unions are columns of type 'any'
+func avroToYtType(fieldSchema *avro.Schema) (schema.Type, bool) {
+	required := !fieldSchema.IsNullable()
+	notNullBranches := fieldSchema.NotNullBranches()
+	if len(notNullBranches) != 1 {
+		return schema.TypeAny, required
+	}
+	branch := notNullBranches[0]
+	if ytType, ok := avroLogicalTypes[branch.LogicalType]; ok && logicalTypeIsApplicable(branch) {
+		return ytType, required
+	}
+	return avroSchemaTypes[branch.Type], required
+}
+
+// logicalTypeIsApplicable checks the underlying type, since the decoder ignores logical types of other types
+func logicalTypeIsApplicable(fieldSchema *avro.Schema) bool {
+	switch fieldSchema.LogicalType {
+	case avro.LogicalTypeDecimal:
+		return fieldSchema.Type == avro.TypeBytes || fieldSchema.Type == avro.TypeFixed
+	case avro.LogicalTypeUUID:
+		return fieldSchema.Type == avro.TypeString
+	case avro.LogicalTypeDate, avro.LogicalTypeTimeMillis:
+		return fieldSchema.Type == avro.TypeInt
+	default:
+		return fieldSchema.Type == avro.TypeLong
+	}
+}
+
+// avroTypeName returns short description of the field type, like 'long', 'union<null,string>' or 'bytes:decimal'
+func avroTypeName(fieldSchema *avro.Schema) string {
+	switch {
+	case fieldSchema.Type == avro.TypeUnion:
+		return fmt.Sprintf("union<%s>", strings.Join(yslices.Map(fieldSchema.Branches, avroTypeName), ","))
+	case fieldSchema.LogicalType != "":
+		return fmt.Sprintf("%s:%s", fieldSchema.Type, fieldSchema.LogicalType)
+	case fieldSchema.Name != "":
+		return fmt.Sprintf("%s:%s", fieldSchema.Type, fieldSchema.Name)
+	default:
+		return fieldSchema.Type.String()
+	}
+}
@@ -0,0 +1,114 @@
+package reader
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/avro"
+	chunk_pusher "github.com/transferia/transferia/pkg/providers/s3/pusher"
+	abstract_reader "github.com/transferia/transferia/pkg/providers/s3/reader"
+	"github.com/transferia/transferia/pkg/providers/s3/reader/testutil"
+)
+
+const testSchema = `{
+	"type": "record",
+	"name": "event",
+	"namespace": "test",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "name", "type": ["null", "string"]},
+		{"name": "created", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+		{"name": "kind", "type": {"type": "enum", "name": "kind", "symbols": ["A", "B"]}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}}
+	]
+}`
+
+func testRow(i int) map[string]any {
+	row := testutil.Row(i)
+	return map[string]any{
+		"id":      row[0],
+		"name":    row[1],
+		"created": row[2],
+		"amount":  row[3],
+		"kind":    []string{"A", "B"}[i%2],
+		"tags":    []any{"t"},
+	}
+}
+
+// writeTestFile writes rows in blocks of blockRows rows
+func writeTestFile(t *testing.T, rows, blockRows int) []byte {
+	var buf bytes.Buffer
+	writer, err := avro.NewOCFWriter(&buf, testSchema, avro.CodecDeflate, nil)
+	require.NoError(t, err)
+	for i := 0; i < rows; i++ {
+		require.NoError(t, writer.Append(testRow(i)))
+		if (i+1)%blockRows == 0 {
+			require.NoError(t, writer.Flush())
+		}
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func newTestReader(t *testing.T, objects map[string][]byte) *ReaderAvro {
+	return testutil.NewReader(t, newReader, objects)
+}
+
+func TestResolveSchema(t *testing.T) {
+	reader := newTestReader(t, map[string][]byte{"data/a.avro": writeTestFile(t, 10, 4), "data/readme.txt": []byte("not avro")})
+
+	columns := reader.tableSchema.Columns()
+	require.Equal(t, []string{"__file_name", "__row_index", "id", "name", "created", "amount", "kind", "tags"}, reader.colNames)
+	require.Equal(t, []string{"utf8", "uint64", "int64", "utf8", "timestamp", "utf8", "utf8", "any"}, abstract_reader.DataTypes(columns))
+	require.Equal(t, "avro:long", columns[2].OriginalType)
+	require.Equal(t, "avro:union<null,string>", columns[3].OriginalType)
+	require.Equal(t, "avro:bytes:decimal", columns[5].OriginalType)
+	require.Equal(t, "avro:enum:test.kind", columns[6].OriginalType)
+	require.True(t, columns[2].Required)
+	require.False(t, columns[3].Required)
+}
+
+func TestRead(t *testing.T) {
+	file := writeTestFile(t, 100, 8)
+	reader := newTestReader(t, map[string][]byte{"data/a.avro": file})
+
+	items := testutil.CollectRows(t, func(pusher chunk_pusher.Pusher) error {
+		return reader.Read(context.Background(), "data/a.avro", pusher)
+	})
+	require.Len(t, items, 100)
+	for i, item := range items {
+		row := testRow(i)
+		require.Equal(t, []any{"data/a.avro", uint64(i + 1), row["id"], row["name"], row["created"], row["amount"], row["kind"], row["tags"]}, item.ColumnValues)
+	}
+
+	rows, err := reader.EstimateRowsCountAllObjects(context.Background())
+	require.NoError(t, err)
+	require.InDelta(t, 100, rows, 10)
+}
+
+func TestReadSplit(t *testing.T) {
+	file := writeTestFile(t, 1000, 50)
+	reader := newTestReader(t, map[string][]byte{"data/a.avro": file})
+	require.True(t, reader.Splittable())
+
+	partSize := int64(len(file) / 7)
+	ids := map[int64]bool{}
+	rowIndexes := map[uint64]bool{}
+	for from := int64(0); from < int64(len(file)); from += partSize {
+		items := testutil.CollectRows(t, func(pusher chunk_pusher.Pusher) error {
+			return reader.ReadSplit(context.Background(), "data/a.avro", from, min(from+partSize, int64(len(file))), pusher)
+		})
+		for _, item := range items {
+			id := item.ColumnValues[2].(int64)
+			require.False(t, ids[id], "row %d is read twice", id)
+			ids[id] = true
+			rowIndex := item.ColumnValues[1].(uint64)
+			require.False(t, rowIndexes[rowIndex], "row index %d is duplicated", rowIndex)
+			rowIndexes[rowIndex] = true
+		}
+	}
+	require.Len(t, ids, 1000)
+}
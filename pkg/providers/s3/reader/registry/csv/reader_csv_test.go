@@ -109,19 +109,6 @@ func TestResolveCSVSchema(t *testing.T) {
 func TestEstimateRows_NoCompleteLinesReturnsZero(t *testing.T) {
 	src := s3recipe.PrepareCfg(t, "estimate_rows", "")
 
-	key := "estimate_rows/no_newline.csv"
-	abs, err := os.Getwd()
-	require.NoError(t, err)
-	localPath := abs + "/" + key
-	require.NoError(t, os.MkdirAll(filepath.Dir(localPath), 0o755))
-	f, err := os.Create(localPath)
-	require.NoError(t, err)
-	_, err = f.WriteString("col1,col2")
-	require.NoError(t, err)
-	require.NoError(t, f.Close())
-
-	s3recipe.UploadOne(t, src, key)
-
 	sess, err := session.NewSession(&aws.Config{
 		Endpoint:         aws.String(src.ConnectionConfig.Endpoint),
 		Region:           aws.String(src.ConnectionConfig.Region),
@@ -132,6 +119,18 @@ func TestEstimateRows_NoCompleteLinesReturnsZero(t *testing.T) {
 	})
 	require.NoError(t, err)
 
+	localPath := filepath.Join(t.TempDir(), "no_newline.csv")
+	require.NoError(t, os.WriteFile(localPath, []byte("col1,col2"), 0o644))
+	f, err := os.Open(localPath)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = aws_s3.New(sess).PutObject(&aws_s3.PutObjectInput{
+		Body:   f,
+		Bucket: aws.String(src.Bucket),
+		Key:    aws.String("estimate_rows/no_newline.csv"),
+	})
+	require.NoError(t, err)
+
 	r := &CSVReader{
 		client:          aws_s3.New(sess),
 		bucket:          src.Bucket,
@@ -0,0 +1,44 @@
+"""Writes gotest/dump/pyarrow_<compression>.orc with the rows of testRow of reader_orc_test.go.
+
+Usage: python3 generate_pyarrow.py (requires pyarrow)
+"""
+
+import datetime
+import decimal
+import os
+
+import pyarrow as pa
+import pyarrow.orc as orc
+
+ROWS = 100
+
+schema = pa.schema([
+    ("id", pa.int64()),
+    ("name", pa.string()),
+    ("created", pa.timestamp("ns")),
+    ("amount", pa.decimal128(10, 2)),
+    ("attrs", pa.map_(pa.string(), pa.int32())),
+])
+
+start = datetime.datetime(2024, 1, 1)
+table = pa.table({
+    "id": [i for i in range(ROWS)],
+    "name": [None if i % 3 == 0 else f"name-{i}" for i in range(ROWS)],
+    "created": [start + datetime.timedelta(seconds=i) for i in range(ROWS)],
+    "amount": [decimal.Decimal(f"{i}.{i % 100:02d}") for i in range(ROWS)],
+    "attrs": [[("k", i)] for i in range(ROWS)],
+}, schema=schema)
+
+dump = os.path.join(os.path.dirname(os.path.abspath(__file__)), "dump")
+# strings of the zlib file are written with direct encoding, strings of the snappy file - with dictionary one
+for compression, dictionary_threshold in (("zlib", 0.0), ("snappy", 1.0)):
+    # small stripes & row groups, so the file has several of them
+    orc.write_table(
+        table,
+        os.path.join(dump, f"pyarrow_{compression}.orc"),
+        compression=compression,
+        stripe_size=4096,
+        batch_size=16,
+        row_index_stride=10,
+        dictionary_key_size_threshold=dictionary_threshold,
+    )
@@ -0,0 +1,334 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	aws_s3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	yslices "github.com/transferia/transferia/library/go/slices"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/changeitem"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/orc"
+	"github.com/transferia/transferia/pkg/providers/s3"
+	chunk_pusher "github.com/transferia/transferia/pkg/providers/s3/pusher"
+	abstract_reader "github.com/transferia/transferia/pkg/providers/s3/reader"
+	"github.com/transferia/transferia/pkg/providers/s3/reader/s3raw"
+	"github.com/transferia/transferia/pkg/providers/s3/s3util"
+	"github.com/transferia/transferia/pkg/stats"
+	"github.com/transferia/transferia/pkg/util"
+	"go.ytsaurus.tech/library/go/core/log"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+var (
+	_ abstract_reader.Reader             = (*ReaderORC)(nil)
+	_ abstract_reader.RowsCountEstimator = (*ReaderORC)(nil)
+	_ abstract_reader.SplitReader        = (*ReaderORC)(nil)
+)
+
+func init() {
+	abstract_reader.RegisterReader(model.ParsingFormatORC, NewORC)
+}
+
+// ReaderORC reads ORC files stripe by stripe, stripes are independently readable,
+// so big files are read by parts, see ReadSplit
+type ReaderORC struct {
+	table          abstract.TableID
+	bucket         string
+	client         s3iface.S3API
+	logger         log.Logger
+	tableSchema    *abstract.TableSchema
+	colNames       []string
+	hideSystemCols bool
+	batchSize      int
+	pathPrefix     string
+	pathPattern    string
+	metrics        *stats.SourceStats
+}
+
+func (r *ReaderORC) EstimateRowsCountOneObject(ctx context.Context, obj *aws_s3.Object) (uint64, error) {
+	_, file, err := r.openFile(ctx, *obj.Key)
+	if err != nil {
+		return 0, xerrors.Errorf("unable to read file meta: %s: %w", *obj.Key, err)
+	}
+	return file.NumRows(), nil
+}
+
+func (r *ReaderORC) EstimateRowsCountAllObjects(ctx context.Context) (uint64, error) {
+	res := uint64(0)
+	files, err := s3util.ListFiles(r.bucket, r.pathPrefix, r.pathPattern, r.client, r.logger, nil, r.ObjectsFilter())
+	if err != nil {
+		return 0, xerrors.Errorf("unable to load file list: %w", err)
+	}
+	for i, file := range files {
+		rows, err := r.EstimateRowsCountOneObject(ctx, file)
+		if err != nil {
+			return 0, xerrors.Errorf("unable to estimate rows of file: %s: %w", *file.Key, err)
+		}
+		res += rows
+		// once we reach limit of files to estimate - stop and approximate
+		if i > abstract_reader.EstimateFilesLimit {
+			break
+		}
+	}
+	if len(files) > abstract_reader.EstimateFilesLimit {
+		multiplier := float64(len(files)) / float64(abstract_reader.EstimateFilesLimit)
+		return uint64(float64(res) * multiplier), nil
+	}
+	return res, nil
+}
+
+func (r *ReaderORC) ResolveSchema(ctx context.Context) (*abstract.TableSchema, error) {
+	if r.tableSchema != nil && len(r.tableSchema.Columns()) != 0 {
+		return r.tableSchema, nil
+	}
+
+	files, err := s3util.ListFiles(r.bucket, r.pathPrefix, r.pathPattern, r.client, r.logger, aws.Int(1), r.ObjectsFilter())
+	if err != nil {
+		return nil, xerrors.Errorf("unable to load file list: %w", err)
+	}
+
+	if len(files) < 1 {
+		return nil, xerrors.Errorf("unable to resolve schema, no orc files found for prefix '%s'", r.pathPrefix)
+	}
+
+	return r.resolveSchema(ctx, *files[0].Key)
+}
+
+func (r *ReaderORC) ObjectsFilter() abstract_reader.ObjectsFilter {
+	return func(file *aws_s3.Object) bool {
+		if !abstract_reader.IsNotEmpty(file) {
+			return false
+		}
+		return strings.HasSuffix(*file.Key, ".orc")
+	}
+}
+
+func (r *ReaderORC) resolveSchema(ctx context.Context, filePath string) (*abstract.TableSchema, error) {
+	_, file, err := r.openFile(ctx, filePath)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read meta: %s: %w", filePath, err)
+	}
+	root := file.Schema()
+	cols := make([]abstract.ColSchema, 0, len(root.Children))
+	for i, child := range root.Children {
+		col := abstract.NewColSchema(root.FieldNames[i], orcToYtType(child), false)
+		col.OriginalType = fmt.Sprintf("orc:%s", child.String())
+		cols = append(cols, col)
+	}
+	return abstract.NewTableSchema(cols), nil
+}
+
+// openFile reads the tail of file, stripes are read by ranged requests on demand
+func (r *ReaderORC) openFile(ctx context.Context, filePath string) (s3raw.S3RawReader, *orc.File, error) {
+	sr, err := s3raw.NewS3RawReader(ctx, r.client, r.bucket, filePath, r.metrics)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("unable to create reader at: %w", err)
+	}
+	file, err := orc.Open(sr, sr.Size())
+	if err != nil {
+		return nil, nil, xerrors.Errorf("unable to open file: %w", err)
+	}
+	return sr, file, nil
+}
+
+func (r *ReaderORC) Read(ctx context.Context, filePath string, pusher chunk_pusher.Pusher) error {
+	sr, file, err := r.openFile(ctx, filePath)
+	if err != nil {
+		return xerrors.Errorf("unable to open file: %w", err)
+	}
+	r.logger.Infof("part: %s extracted row count: %v", filePath, file.NumRows())
+	return r.readStripes(ctx, filePath, file, sr.LastModified(), 0, sr.Size(), pusher)
+}
+
+// ReadSplit reads stripes, which start in the [from, to) range.
+// Numbers of rows of stripes are known from the footer, so row indexes are the same as of whole file reads
+func (r *ReaderORC) ReadSplit(ctx context.Context, filePath string, from, to int64, pusher chunk_pusher.Pusher) error {
+	sr, file, err := r.openFile(ctx, filePath)
+	if err != nil {
+		return xerrors.Errorf("unable to open file: %w", err)
+	}
+	return r.readStripes(ctx, filePath, file, sr.LastModified(), from, to, pusher)
+}
+
+func (r *ReaderORC) Splittable() bool {
+	return true
+}
+
+func (r *ReaderORC) readStripes(
+	ctx context.Context,
+	filePath string,
+	file *orc.File,
+	lastModified time.Time,
+	from, to int64,
+	pusher chunk_pusher.Pusher,
+) error {
+	var buff []abstract.ChangeItem
+	var currentSize int64
+	idx := uint64(0)
+	for stripeIdx, stripe := range file.Stripes() {
+		if int64(stripe.Offset) < from || int64(stripe.Offset) >= to {
+			idx += stripe.NumberOfRows
+			continue
+		}
+		rows, err := file.Stripe(stripeIdx)
+		if err != nil {
+			return xerrors.Errorf("unable to read stripe: %w", err)
+		}
+		for {
+			if ctx.Err() != nil {
+				r.logger.Info("Read canceled")
+				return nil
+			}
+			row, err := rows.Next()
+			if err != nil {
+				if xerrors.Is(err, io.EOF) {
+					break
+				}
+				return xerrors.Errorf("unable to read row of stripe %d: %w", stripeIdx, err)
+			}
+			idx++
+			ci := r.constructCI(file.Schema(), row, filePath, lastModified, idx)
+			currentSize += int64(ci.Size.Values)
+			buff = append(buff, ci)
+			if len(buff) > r.batchSize {
+				if err := abstract_reader.FlushChunk(ctx, filePath, idx, currentSize, buff, pusher); err != nil {
+					return xerrors.Errorf("unable to push orc batch: %w", err)
+				}
+				currentSize = 0
+				buff = []abstract.ChangeItem{}
+			}
+		}
+	}
+	if err := abstract_reader.FlushChunk(ctx, filePath, idx, currentSize, buff, pusher); err != nil {
+		return xerrors.Errorf("unable to push orc last batch: %w", err)
+	}
+	return nil
+}
+
+func (r *ReaderORC) constructCI(root *orc.Type, row []any, fname string, lModified time.Time, idx uint64) abstract.ChangeItem {
+	fields := make(map[string]any, len(row))
+	for i, name := range root.FieldNames {
+		fields[name] = row[i]
+	}
+	vals := make([]interface{}, len(r.tableSchema.Columns()))
+	for i, col := range r.tableSchema.Columns() {
+		if abstract_reader.SystemColumnNames[col.ColumnName] {
+			if r.hideSystemCols {
+				continue
+			}
+			switch col.ColumnName {
+			case abstract_reader.FileNameSystemCol:
+				vals[i] = fname
+			case abstract_reader.RowIndexSystemCol:
+				vals[i] = idx
+			}
+			continue
+		}
+		vals[i] = abstract.Restore(col, fields[col.ColumnName])
+	}
+
+	return abstract.ChangeItem{
+		ID:               0,
+		LSN:              0,
+		CommitTime:       uint64(lModified.UnixNano()),
+		Counter:          int(idx),
+		Kind:             abstract.InsertKind,
+		Schema:           r.table.Namespace,
+		Table:            r.table.Name,
+		PartID:           fname,
+		ColumnNames:      r.colNames,
+		ColumnValues:     vals,
+		TableSchema:      r.tableSchema,
+		OldKeys:          abstract.EmptyOldKeys(),
+		Size:             abstract.RawEventSize(util.DeepSizeof(vals)),
+		TxID:             "",
+		Query:            "",
+		QueueMessageMeta: changeitem.QueueMessageMeta{TopicName: "", PartitionNum: 0, Offset: 0, Index: 0},
+	}
+}
+
+func (r *ReaderORC) ParsePassthrough(chunk chunk_pusher.Chunk) []abstract.ChangeItem {
+	return chunk.Items
+}
+
+func NewORC(src *s3.S3Source, lgr log.Logger, sess *session.Session, metrics *stats.SourceStats) (abstract_reader.Reader, error) {
+	if src == nil {
+		return nil, xerrors.New("uninitialized settings for orc reader")
+	}
+	return newReader(src, lgr, aws_s3.New(sess), metrics)
+}
+
+func newReader(src *s3.S3Source, lgr log.Logger, client s3iface.S3API, metrics *stats.SourceStats) (*ReaderORC, error) {
+	reader := &ReaderORC{
+		bucket:         src.Bucket,
+		hideSystemCols: src.HideSystemCols,
+		batchSize:      src.ReadBatchSize,
+		pathPrefix:     src.PathPrefix,
+		pathPattern:    src.PathPattern,
+		client:         client,
+		logger:         lgr,
+		table: abstract.TableID{
+			Namespace: src.TableNamespace,
+			Name:      src.TableName,
+		},
+		tableSchema: abstract.NewTableSchema(src.OutputSchema),
+		colNames:    nil,
+		metrics:     metrics,
+	}
+
+	if len(reader.tableSchema.Columns()) == 0 {
+		var err error
+		reader.tableSchema, err = reader.ResolveSchema(context.Background())
+		if err != nil {
+			return nil, xerrors.Errorf("unable to resolve schema: %w", err)
+		}
+	}
+
+	// append system columns at the end if necessary
+	if !reader.hideSystemCols {
+		cols := reader.tableSchema.Columns()
+		userDefinedSchemaHasPkey := reader.tableSchema.Columns().HasPrimaryKey()
+		reader.tableSchema = abstract_reader.AppendSystemColsTableSchema(cols, !userDefinedSchemaHasPkey)
+	}
+
+	reader.colNames = yslices.Map(reader.tableSchema.Columns(), func(t abstract.ColSchema) string { return t.ColumnName })
+	return reader, nil
+}
+
+var orcTypes = map[orc.Kind]schema.Type{
+	orc.KindBoolean:          schema.TypeBoolean,
+	orc.KindByte:             schema.TypeInt8,
+	orc.KindShort:            schema.TypeInt16,
+	orc.KindInt:              schema.TypeInt32,
+	orc.KindLong:             schema.TypeInt64,
+	orc.KindFloat:            schema.TypeFloat32,
+	orc.KindDouble:           schema.TypeFloat64,
+	orc.KindString:           schema.TypeString,
+	orc.KindVarchar:          schema.TypeString,
+	orc.KindChar:             schema.TypeString,
+	orc.KindBinary:           schema.TypeBytes,
+	orc.KindDecimal:          schema.TypeString, // to not lose precision
+	orc.KindDate:             schema.TypeDate,
+	orc.KindTimestamp:        schema.TypeTimestamp,
+	orc.KindTimestampInstant: schema.TypeTimestamp,
+	orc.KindList:             schema.TypeAny,
+	orc.KindMap:              schema.TypeAny,
+	orc.KindStruct:           schema.TypeAny,
+	orc.KindUnion:            schema.TypeAny,
+}
+
+func orcToYtType(typ *orc.Type) schema.Type {
+	if ytType, ok := orcTypes[typ.Kind]; ok {
+		return ytType
+	}
+	return schema.TypeAny
+}
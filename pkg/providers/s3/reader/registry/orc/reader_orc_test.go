@@ -0,0 +1,112 @@
+package reader
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/abstract"
+	chunk_pusher "github.com/transferia/transferia/pkg/providers/s3/pusher"
+	abstract_reader "github.com/transferia/transferia/pkg/providers/s3/reader"
+	"github.com/transferia/transferia/pkg/providers/s3/reader/testutil"
+)
+
+// Files of gotest/dump/rows_* are written by the writer of pkg/orc tests with zlib compression,
+// their schema is struct<id:bigint,name:string,created:timestamp,amount:decimal(10,2),attrs:map<string,int>>
+// and rows are testRow(0), testRow(1), ...
+var (
+	//go:embed gotest/dump/rows_10_stripe_4.orc
+	rows10Stripe4 []byte
+	//go:embed gotest/dump/rows_100_stripe_30.orc
+	rows100Stripe30 []byte
+	//go:embed gotest/dump/rows_1000_stripe_60.orc
+	rows1000Stripe60 []byte
+)
+
+func testRow(i int) []any {
+	return append(testutil.Row(i), map[string]any{"k": int32(i)})
+}
+
+func newTestReader(t *testing.T, objects map[string][]byte) *ReaderORC {
+	return testutil.NewReader(t, newReader, objects)
+}
+
+func TestResolveSchema(t *testing.T) {
+	reader := newTestReader(t, map[string][]byte{"data/a.orc": rows10Stripe4, "data/readme.txt": []byte("not orc")})
+
+	columns := reader.tableSchema.Columns()
+	require.Equal(t, []string{"__file_name", "__row_index", "id", "name", "created", "amount", "attrs"}, reader.colNames)
+	require.Equal(t, []string{"utf8", "uint64", "int64", "utf8", "timestamp", "utf8", "any"}, abstract_reader.DataTypes(columns))
+	require.Equal(t, "orc:bigint", columns[2].OriginalType)
+	require.Equal(t, "orc:decimal(10,2)", columns[5].OriginalType)
+	require.Equal(t, "orc:map<string,int>", columns[6].OriginalType)
+}
+
+func TestRead(t *testing.T) {
+	reader := newTestReader(t, map[string][]byte{"data/a.orc": rows100Stripe30})
+
+	items := testutil.CollectRows(t, func(pusher chunk_pusher.Pusher) error {
+		return reader.Read(context.Background(), "data/a.orc", pusher)
+	})
+	require.Len(t, items, 100)
+	for i, item := range items {
+		require.Equal(t, append([]any{"data/a.orc", uint64(i + 1)}, testRow(i)...), item.ColumnValues)
+	}
+
+	rows, err := reader.EstimateRowsCountAllObjects(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), rows)
+}
+
+func TestReadSplit(t *testing.T) {
+	file := rows1000Stripe60
+	reader := newTestReader(t, map[string][]byte{"data/a.orc": file})
+	require.True(t, reader.Splittable())
+
+	partSize := int64(len(file) / 5)
+	var items []abstract.ChangeItem
+	for from := int64(0); from < int64(len(file)); from += partSize {
+		items = append(items, testutil.CollectRows(t, func(pusher chunk_pusher.Pusher) error {
+			return reader.ReadSplit(context.Background(), "data/a.orc", from, min(from+partSize, int64(len(file))), pusher)
+		})...)
+	}
+	require.Len(t, items, 1000)
+	for i, item := range items {
+		// row indexes of parts are the same as of the whole file
+		require.Equal(t, uint64(i+1), item.ColumnValues[1])
+		require.Equal(t, int64(i), item.ColumnValues[2])
+	}
+}
+
+// TestReadPyarrow reads files written by pyarrow (Apache ORC C++ writer) with gotest/generate_pyarrow.py,
+// their schema and rows are the same as of files written by pkg/orc tests
+func TestReadPyarrow(t *testing.T) {
+	for _, compression := range []string{"zlib", "snappy"} {
+		t.Run(compression, func(t *testing.T) {
+			path := fmt.Sprintf("gotest/dump/pyarrow_%s.orc", compression)
+			file, err := os.ReadFile(path)
+			if os.IsNotExist(err) {
+				t.Skipf("%s is not generated, run gotest/generate_pyarrow.py", path)
+			}
+			require.NoError(t, err)
+			reader := newTestReader(t, map[string][]byte{"data/a.orc": file})
+
+			columns := reader.tableSchema.Columns()
+			require.Equal(t, []string{"__file_name", "__row_index", "id", "name", "created", "amount", "attrs"}, reader.colNames)
+			require.Equal(t, []string{"utf8", "uint64", "int64", "utf8", "timestamp", "utf8", "any"}, abstract_reader.DataTypes(columns))
+			require.Equal(t, "orc:decimal(10,2)", columns[5].OriginalType)
+			require.Equal(t, "orc:map<string,int>", columns[6].OriginalType)
+
+			items := testutil.CollectRows(t, func(pusher chunk_pusher.Pusher) error {
+				return reader.Read(context.Background(), "data/a.orc", pusher)
+			})
+			require.Len(t, items, 100)
+			for i, item := range items {
+				require.Equal(t, append([]any{"data/a.orc", uint64(i + 1)}, testRow(i)...), item.ColumnValues)
+			}
+		})
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/transferia/transferia/pkg/providers/s3"
 	"github.com/transferia/transferia/pkg/providers/s3/reader"
+	_ "github.com/transferia/transferia/pkg/providers/s3/reader/registry/avro"
 	_ "github.com/transferia/transferia/pkg/providers/s3/reader/registry/csv"
 	_ "github.com/transferia/transferia/pkg/providers/s3/reader/registry/json"
 	_ "github.com/transferia/transferia/pkg/providers/s3/reader/registry/line"
+	_ "github.com/transferia/transferia/pkg/providers/s3/reader/registry/orc"
 	_ "github.com/transferia/transferia/pkg/providers/s3/reader/registry/parquet"
 	_ "github.com/transferia/transferia/pkg/providers/s3/reader/registry/proto"
 	"github.com/transferia/transferia/pkg/stats"
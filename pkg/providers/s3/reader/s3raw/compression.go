@@ -0,0 +1,104 @@
+package s3raw
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+)
+
+type decompressor struct {
+	name     string
+	suffixes []string
+	magic    []byte
+	wrapper  wrapper[io.ReadCloser]
+}
+
+var decompressors = []decompressor{
+	{
+		name:     "gzip",
+		suffixes: []string{".gz"},
+		magic:    []byte{0x1f, 0x8b},
+		wrapper: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	{
+		// zlib header has no reliable magic bytes
+		name:     "zlib",
+		suffixes: []string{".zlib"},
+		magic:    nil,
+		wrapper: func(r io.Reader) (io.ReadCloser, error) {
+			return zlib.NewReader(r)
+		},
+	},
+	{
+		name:     "zstd",
+		suffixes: []string{".zst", ".zstd"},
+		magic:    []byte{0x28, 0xb5, 0x2f, 0xfd},
+		wrapper: func(r io.Reader) (io.ReadCloser, error) {
+			decoder, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return decoder.IOReadCloser(), nil
+		},
+	},
+	{
+		name:     "lz4",
+		suffixes: []string{".lz4"},
+		magic:    []byte{0x04, 0x22, 0x4d, 0x18},
+		wrapper: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(lz4.NewReader(r)), nil
+		},
+	},
+	{
+		// snappy framing format starts with the stream identifier chunk
+		name:     "snappy",
+		suffixes: []string{".sz"},
+		magic:    []byte("\xff\x06\x00\x00sNaPpY"),
+		wrapper: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(snappy.NewReader(r)), nil
+		},
+	},
+}
+
+// magicHeaderSize is enough bytes to detect any of decompressors by magic bytes
+const magicHeaderSize = 10
+
+// decompressorByKey detects compression by object key extension
+func decompressorByKey(key string) *decompressor {
+	for i := range decompressors {
+		for _, suffix := range decompressors[i].suffixes {
+			if strings.HasSuffix(key, suffix) {
+				return &decompressors[i]
+			}
+		}
+	}
+	return nil
+}
+
+// decompressorByMagic detects compression by leading bytes of the object
+func decompressorByMagic(header []byte) *decompressor {
+	for i := range decompressors {
+		if len(decompressors[i].magic) > 0 && bytes.HasPrefix(header, decompressors[i].magic) {
+			return &decompressors[i]
+		}
+	}
+	return nil
+}
+
+// isPlainKey reports whether the key has an extension of uncompressed format, such objects are not sniffed for magic bytes
+func isPlainKey(key string) bool {
+	for _, ext := range []string{".json", ".jsonl", ".ndjson", ".csv", ".tsv", ".txt", ".log", ".parquet", ".pb", ".proto", ".avro", ".orc"} {
+		if strings.HasSuffix(strings.ToLower(key), ext) {
+			return true
+		}
+	}
+	return false
+}
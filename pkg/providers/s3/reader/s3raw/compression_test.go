@@ -0,0 +1,60 @@
+package s3raw
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecompressorByKey(t *testing.T) {
+	require.Equal(t, "gzip", decompressorByKey("2024/01/02/table.json.gz").name)
+	require.Equal(t, "zlib", decompressorByKey("table.csv.zlib").name)
+	require.Equal(t, "zstd", decompressorByKey("table.json.zst").name)
+	require.Equal(t, "zstd", decompressorByKey("table.json.zstd").name)
+	require.Equal(t, "lz4", decompressorByKey("table.csv.lz4").name)
+	require.Equal(t, "snappy", decompressorByKey("table.raw.sz").name)
+	require.Nil(t, decompressorByKey("table.parquet"))
+
+	require.True(t, isPlainKey("table.JSON"))
+	require.False(t, isPlainKey("table-1_10"))
+}
+
+func TestDecompressorByMagic(t *testing.T) {
+	data := []byte("line of data\nline of data\n")
+	compress := func(writer io.WriteCloser, buf *bytes.Buffer) []byte {
+		_, err := writer.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		return buf.Bytes()
+	}
+
+	var gzipBuf, zstdBuf, lz4Buf, snappyBuf bytes.Buffer
+	zstdWriter, err := zstd.NewWriter(&zstdBuf)
+	require.NoError(t, err)
+	for name, compressed := range map[string][]byte{
+		"gzip":   compress(gzip.NewWriter(&gzipBuf), &gzipBuf),
+		"zstd":   compress(zstdWriter, &zstdBuf),
+		"lz4":    compress(lz4.NewWriter(&lz4Buf), &lz4Buf),
+		"snappy": compress(snappy.NewBufferedWriter(&snappyBuf), &snappyBuf),
+	} {
+		dec := decompressorByMagic(compressed[:magicHeaderSize])
+		require.NotNil(t, dec, name)
+		require.Equal(t, name, dec.name)
+		reader, err := dec.wrapper(bytes.NewReader(compressed))
+		require.NoError(t, err)
+		decoded, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		require.Equal(t, data, decoded, name)
+	}
+
+	require.Nil(t, decompressorByMagic([]byte("PAR1")))
+	require.Nil(t, decompressorByMagic([]byte(`{"id":1}`)))
+	require.Nil(t, decompressorByMagic(nil))
+}
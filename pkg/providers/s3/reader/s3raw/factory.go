@@ -1,10 +1,7 @@
 package s3raw
 
 import (
-	"compress/gzip"
-	"compress/zlib"
 	"context"
-	"strings"
 
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/transferia/transferia/library/go/core/xerrors"
@@ -19,15 +16,19 @@ func NewS3RawReader(ctx context.Context, client s3iface.S3API, bucket, key strin
 
 	var reader S3RawReader
 
-	if strings.HasSuffix(key, ".gz") {
-		reader, err = newWrappedReader(fetcher, client, metrics, gzip.NewReader)
+	dec := decompressorByKey(key)
+	if dec == nil && !isPlainKey(key) {
+		header, err := fetcher.readHeader(magicHeaderSize)
 		if err != nil {
-			return nil, xerrors.Errorf("failed to initialize new gzip reader: %w", err)
+			return nil, xerrors.Errorf("failed to read header of %s: %w", key, err)
 		}
-	} else if strings.HasSuffix(key, ".zlib") {
-		reader, err = newWrappedReader(fetcher, client, metrics, zlib.NewReader)
+		dec = decompressorByMagic(header)
+	}
+
+	if dec != nil {
+		reader, err = newWrappedReader(fetcher, client, metrics, dec.wrapper)
 		if err != nil {
-			return nil, xerrors.Errorf("failed to initialize new zlib reader: %w", err)
+			return nil, xerrors.Errorf("failed to initialize new %s reader: %w", dec.name, err)
 		}
 	} else {
 		reader, err = newS3RawReader(fetcher, metrics)
@@ -2,10 +2,12 @@ package s3raw
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/transferia/transferia/internal/logger"
@@ -112,6 +114,28 @@ func (f *s3Fetcher) makeReader() (io.ReadCloser, error) {
 	return resp.Body, nil
 }
 
+// readHeader returns up to n leading bytes of the object, empty for empty objects
+func (f *s3Fetcher) readHeader(n int) ([]byte, error) {
+	resp, err := f.getObject(&s3.GetObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.key),
+		Range:  aws.String(fmt.Sprintf("bytes=0-%d", n-1)),
+	})
+	if err != nil {
+		var awsErr awserr.Error
+		if xerrors.As(err, &awsErr) && awsErr.Code() == "InvalidRange" {
+			return nil, nil
+		}
+		return nil, xerrors.Errorf("failed to get header of object %s: %w", f.key, err)
+	}
+	defer resp.Body.Close()
+	header, err := io.ReadAll(io.LimitReader(resp.Body, int64(n)))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read header of object %s: %w", f.key, err)
+	}
+	return header, nil
+}
+
 func newS3Fetcher(ctx context.Context, client s3iface.S3API, bucket string, key string) (*s3Fetcher, error) {
 	return &s3Fetcher{
 		ctx:                   ctx,
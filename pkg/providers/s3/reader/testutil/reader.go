@@ -0,0 +1,63 @@
+package testutil
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/providers/s3"
+	chunk_pusher "github.com/transferia/transferia/pkg/providers/s3/pusher"
+	"github.com/transferia/transferia/pkg/providers/s3/source/object_fetcher/fake_s3"
+	"github.com/transferia/transferia/pkg/stats"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+// ReaderFactory is a constructor of a reader of the given format
+type ReaderFactory[T any] func(src *s3.S3Source, lgr log.Logger, client s3iface.S3API, metrics *stats.SourceStats) (T, error)
+
+// NewReader creates a reader of table 'events' from objects of 'data/' prefix, which are served by the fake s3 client
+func NewReader[T any](t *testing.T, newReader ReaderFactory[T], objects map[string][]byte) T {
+	client := fake_s3.NewFakeS3Client(t)
+	for fileName, body := range objects {
+		client.AddFile(fake_s3.NewFile(fileName, body, time.Now().UnixNano()))
+	}
+	src := new(s3.S3Source)
+	src.Bucket = "bucket"
+	src.PathPrefix = "data/"
+	src.TableName = "events"
+	src.ReadBatchSize = 7
+	reader, err := newReader(src, logger.Log, client, stats.NewSourceStats(solomon.NewRegistry(solomon.NewRegistryOpts())))
+	require.NoError(t, err)
+	return reader
+}
+
+// CollectRows returns rows pushed by read
+func CollectRows(t *testing.T, read func(pusher chunk_pusher.Pusher) error) []abstract.ChangeItem {
+	var items []abstract.ChangeItem
+	pusher := chunk_pusher.New(func(batch []abstract.ChangeItem) error {
+		items = append(items, batch...)
+		return nil
+	}, nil, logger.Log, 0)
+	require.NoError(t, read(pusher))
+	return items
+}
+
+// Row returns values of columns 'id', 'name', 'created' & 'amount' of the i-th test row,
+// 'name' is null in every third row and 'amount' is a decimal(10,2) as string
+func Row(i int) []any {
+	var name any
+	if i%3 != 0 {
+		name = fmt.Sprintf("name-%d", i)
+	}
+	return []any{
+		int64(i),
+		name,
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Second),
+		fmt.Sprintf("%d.%02d", i, i%100),
+	}
+}
@@ -0,0 +1,100 @@
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+	"github.com/pierrec/lz4"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	s3_provider "github.com/transferia/transferia/pkg/providers/s3"
+)
+
+type compressWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// objectEncoder compresses whole objects, the object key gets the codec suffix
+type objectEncoder struct {
+	suffix    string
+	newWriter func(w io.Writer) (compressWriter, error)
+}
+
+var objectEncoders = map[s3_provider.Encoding]objectEncoder{
+	s3_provider.GzipEncoding: {
+		suffix: ".gz",
+		newWriter: func(w io.Writer) (compressWriter, error) {
+			return gzip.NewWriter(w), nil
+		},
+	},
+	s3_provider.ZstdEncoding: {
+		suffix: ".zst",
+		newWriter: func(w io.Writer) (compressWriter, error) {
+			return zstd.NewWriter(w)
+		},
+	},
+	s3_provider.Lz4Encoding: {
+		suffix: ".lz4",
+		newWriter: func(w io.Writer) (compressWriter, error) {
+			return lz4.NewWriter(w), nil
+		},
+	},
+	s3_provider.SnappyEncoding: {
+		// snappy framing format
+		suffix: ".sz",
+		newWriter: func(w io.Writer) (compressWriter, error) {
+			return snappy.NewBufferedWriter(w), nil
+		},
+	},
+}
+
+// parquetCodecs are used for page compression of parquet files instead of compression of whole objects,
+// so files stay readable by lake engines. Gzip compresses whole objects as before.
+var parquetCodecs = map[s3_provider.Encoding]compress.Codec{
+	s3_provider.ZstdEncoding:   &parquet.Zstd,
+	s3_provider.Lz4Encoding:    &parquet.Lz4Raw,
+	s3_provider.SnappyEncoding: &parquet.Snappy,
+}
+
+// newObjectEncoder returns encoder of whole objects, nil if objects are not compressed
+func newObjectEncoder(cfg *s3_provider.S3Destination) *objectEncoder {
+	if cfg.OutputFormat == model.ParsingFormatPARQUET {
+		if _, ok := parquetCodecs[cfg.OutputEncoding]; ok {
+			return nil
+		}
+	}
+	encoder, ok := objectEncoders[cfg.OutputEncoding]
+	if !ok {
+		return nil
+	}
+	return &encoder
+}
+
+// objectSuffix returns the suffix of object keys for the configured encoding
+func objectSuffix(cfg *s3_provider.S3Destination) string {
+	if encoder := newObjectEncoder(cfg); encoder != nil {
+		return encoder.suffix
+	}
+	return ""
+}
+
+func (e *objectEncoder) encode(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer, err := e.newWriter(buf)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create %s writer: %w", e.suffix, err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, xerrors.Errorf("unable to compress data: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, xerrors.Errorf("unable to close %s writer: %w", e.suffix, err)
+	}
+	return buf.Bytes(), nil
+}
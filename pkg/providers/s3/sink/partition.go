@@ -0,0 +1,182 @@
+package sink
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	s3_provider "github.com/transferia/transferia/pkg/providers/s3"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+const hiveTimestampLayout = "2006-01-02 15:04:05.999999999"
+
+type partitionField struct {
+	column    string
+	name      string
+	transform s3_provider.PartitionTransform
+	param     int
+}
+
+// partitioner builds Hive-style path of the row partition, like `country=US/dt=2026-10-17`
+type partitioner struct {
+	fields []partitionField
+}
+
+func (p *partitioner) path(row abstract.ChangeItem) (string, error) {
+	segments := make([]string, 0, len(p.fields))
+	for _, field := range p.fields {
+		idx := row.ColumnNameIndex(field.column)
+		if idx < 0 {
+			return "", xerrors.Errorf("no partition column %s", field.column)
+		}
+		dataType := ""
+		if row.TableSchema != nil {
+			if col, ok := row.TableSchema.FastColumns()[abstract.ColumnName(field.column)]; ok {
+				dataType = col.DataType
+			}
+		}
+		value, err := field.value(row.ColumnValues[idx], dataType)
+		if err != nil {
+			return "", xerrors.Errorf("unable to get partition %s of column %s: %w", field.name, field.column, err)
+		}
+		if value == nil {
+			segments = append(segments, fmt.Sprintf("%s=%s", field.name, s3_provider.HiveDefaultPartition))
+			continue
+		}
+		segments = append(segments, fmt.Sprintf("%s=%s", field.name, s3_provider.EscapeHivePathValue(*value)))
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// value returns the partition value of the column value, nil for the default partition
+func (f *partitionField) value(value any, dataType string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	var result string
+	switch f.transform {
+	case s3_provider.IdentityTransform:
+		result = formatPartitionValue(value, dataType)
+	case s3_provider.YearTransform, s3_provider.MonthTransform, s3_provider.DayTransform, s3_provider.HourTransform:
+		t, ok := value.(time.Time)
+		if !ok {
+			return nil, xerrors.Errorf("%s transform requires time value, got %T", f.transform, value)
+		}
+		t = t.UTC()
+		switch f.transform {
+		case s3_provider.YearTransform:
+			result = t.Format("2006")
+		case s3_provider.MonthTransform:
+			result = t.Format("2006-01")
+		case s3_provider.DayTransform:
+			result = t.Format("2006-01-02")
+		default:
+			result = t.Format("2006-01-02-15")
+		}
+	case s3_provider.BucketTransform:
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(formatPartitionValue(value, dataType)))
+		result = fmt.Sprint(hash.Sum32() % uint32(f.param))
+	case s3_provider.TruncateTransform:
+		truncated, err := truncateValue(value, f.param)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to truncate value: %w", err)
+		}
+		result = truncated
+	default:
+		return nil, xerrors.Errorf("unknown transform: %s", f.transform)
+	}
+	return &result, nil
+}
+
+func formatPartitionValue(value any, dataType string) string {
+	switch v := value.(type) {
+	case time.Time:
+		if dataType == schema.TypeDate.String() {
+			return v.Format("2006-01-02")
+		}
+		return v.UTC().Format(hiveTimestampLayout)
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// truncateValue truncates integers to the lower multiple of width and strings to width characters
+func truncateValue(value any, width int) (string, error) {
+	switch v := value.(type) {
+	case string:
+		if utf8.RuneCountInString(v) <= width {
+			return v, nil
+		}
+		return string([]rune(v)[:width]), nil
+	case []byte:
+		return truncateValue(string(v), width)
+	case int, int8, int16, int32, int64:
+		n := toInt64(v)
+		w := int64(width)
+		return fmt.Sprint(n - ((n%w)+w)%w), nil
+	case uint8, uint16, uint32, uint64, uint:
+		n := toUint64(v)
+		return fmt.Sprint(n - n%uint64(width)), nil
+	default:
+		return "", xerrors.Errorf("truncate transform is not supported for %T", value)
+	}
+}
+
+func toInt64(value any) int64 {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	default:
+		return value.(int64)
+	}
+}
+
+func toUint64(value any) uint64 {
+	switch v := value.(type) {
+	case uint:
+		return uint64(v)
+	case uint8:
+		return uint64(v)
+	case uint16:
+		return uint64(v)
+	case uint32:
+		return uint64(v)
+	default:
+		return value.(uint64)
+	}
+}
+
+// newPartitioner returns nil if partitioning is not configured
+func newPartitioner(fields []s3_provider.PartitionField) (*partitioner, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	result := &partitioner{fields: make([]partitionField, 0, len(fields))}
+	for _, field := range fields {
+		transform, param, err := field.ParseTransform()
+		if err != nil {
+			return nil, xerrors.Errorf("invalid partitioning: %w", err)
+		}
+		result.fields = append(result.fields, partitionField{
+			column:    field.Column,
+			name:      field.SegmentName(),
+			transform: transform,
+			param:     param,
+		})
+	}
+	return result, nil
+}
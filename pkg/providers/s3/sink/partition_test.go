@@ -0,0 +1,119 @@
+package sink
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/abstract"
+	s3_provider "github.com/transferia/transferia/pkg/providers/s3"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+func TestPartitionerPath(t *testing.T) {
+	tableSchema := abstract.NewTableSchema([]abstract.ColSchema{
+		abstract.NewColSchema("id", schema.TypeInt64, true),
+		abstract.NewColSchema("country", schema.TypeString, false),
+		abstract.NewColSchema("created_at", schema.TypeTimestamp, false),
+		abstract.NewColSchema("birthday", schema.TypeDate, false),
+	})
+	row := abstract.ChangeItem{
+		Kind:         abstract.InsertKind,
+		ColumnNames:  []string{"id", "country", "created_at", "birthday"},
+		ColumnValues: []any{int64(-17), "US/CA", time.Date(2026, 10, 17, 13, 5, 0, 0, time.UTC), time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)},
+		TableSchema:  tableSchema,
+	}
+
+	for _, tc := range []struct {
+		fields []s3_provider.PartitionField
+		path   string
+	}{
+		{fields: []s3_provider.PartitionField{{Column: "country"}}, path: "country=US%2FCA"},
+		{fields: []s3_provider.PartitionField{{Column: "created_at"}}, path: "created_at=2026-10-17 13%3A05%3A00"},
+		{fields: []s3_provider.PartitionField{{Column: "birthday"}}, path: "birthday=1990-01-02"},
+		{fields: []s3_provider.PartitionField{{Column: "created_at", Transform: "year"}}, path: "created_at_year=2026"},
+		{fields: []s3_provider.PartitionField{{Column: "created_at", Transform: "month"}}, path: "created_at_month=2026-10"},
+		{fields: []s3_provider.PartitionField{{Column: "created_at", Transform: "day", Name: "dt"}}, path: "dt=2026-10-17"},
+		{fields: []s3_provider.PartitionField{{Column: "created_at", Transform: "hour"}}, path: "created_at_hour=2026-10-17-13"},
+		{fields: []s3_provider.PartitionField{{Column: "id", Transform: "truncate(10)"}}, path: "id_trunc=-20"},
+		{fields: []s3_provider.PartitionField{{Column: "country", Transform: "truncate(2)"}}, path: "country_trunc=US"},
+		{
+			fields: []s3_provider.PartitionField{{Column: "country"}, {Column: "created_at", Transform: "day"}},
+			path:   "country=US%2FCA/created_at_day=2026-10-17",
+		},
+	} {
+		p, err := newPartitioner(tc.fields)
+		require.NoError(t, err)
+		path, err := p.path(row)
+		require.NoError(t, err)
+		require.Equal(t, tc.path, path)
+	}
+
+	p, err := newPartitioner([]s3_provider.PartitionField{{Column: "id", Transform: "bucket(4)"}})
+	require.NoError(t, err)
+	path, err := p.path(row)
+	require.NoError(t, err)
+	require.Regexp(t, `^id_bucket=[0-3]$`, path)
+	samePath, err := p.path(row)
+	require.NoError(t, err)
+	require.Equal(t, path, samePath)
+
+	nullRow := row
+	nullRow.ColumnValues = []any{int64(1), nil, nil, nil}
+	p, err = newPartitioner([]s3_provider.PartitionField{{Column: "country"}, {Column: "created_at", Transform: "day"}})
+	require.NoError(t, err)
+	path, err = p.path(nullRow)
+	require.NoError(t, err)
+	require.Equal(t, "country=__HIVE_DEFAULT_PARTITION__/created_at_day=__HIVE_DEFAULT_PARTITION__", path)
+
+	p, err = newPartitioner([]s3_provider.PartitionField{{Column: "country", Transform: "day"}})
+	require.NoError(t, err)
+	_, err = p.path(row)
+	require.Error(t, err)
+
+	p, err = newPartitioner([]s3_provider.PartitionField{{Column: "unknown"}})
+	require.NoError(t, err)
+	_, err = p.path(row)
+	require.Error(t, err)
+
+	_, err = newPartitioner([]s3_provider.PartitionField{{Column: "id", Transform: "bucket(0)"}})
+	require.Error(t, err)
+	p, err = newPartitioner(nil)
+	require.NoError(t, err)
+	require.Nil(t, p)
+}
+
+func TestParseHivePath(t *testing.T) {
+	value := "a/b=c:d%e"
+	key := joinPath("2026/10/17", "db_table", "country="+s3_provider.EscapeHivePathValue(value), "dt="+s3_provider.HiveDefaultPartition, "db_table.json")
+	require.Equal(t, "2026/10/17/db_table/country=a%2Fb%3Dc%3Ad%25e/dt=__HIVE_DEFAULT_PARTITION__/db_table.json", key)
+
+	segments, err := s3_provider.ParseHivePath(key)
+	require.NoError(t, err)
+	require.Len(t, segments, 2)
+	require.Equal(t, value, *segments["country"])
+	require.Nil(t, segments["dt"])
+	_, ok := segments["dt"]
+	require.True(t, ok)
+
+	segments, err = s3_provider.ParseHivePath("x=1/object=name.json")
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+}
+
+func TestPartitioningValidate(t *testing.T) {
+	validate := func(fields []s3_provider.PartitionField, maxOpen int) error {
+		cfg := &s3_provider.S3Destination{
+			OutputFormat:      "JSON",
+			OutputEncoding:    s3_provider.NoEncoding,
+			PartitionBy:       fields,
+			MaxOpenPartitions: maxOpen,
+		}
+		return cfg.Validate()
+	}
+	require.NoError(t, validate([]s3_provider.PartitionField{{Column: "country"}, {Column: "ts", Transform: "Bucket(16)"}}, 4))
+	require.Error(t, validate([]s3_provider.PartitionField{{Column: ""}}, 0))
+	require.Error(t, validate([]s3_provider.PartitionField{{Column: "ts", Transform: "minute"}}, 0))
+	require.Error(t, validate([]s3_provider.PartitionField{{Column: "ts", Transform: "day"}, {Column: "dt", Name: "ts_day"}}, 0))
+	require.Error(t, validate([]s3_provider.PartitionField{{Column: "country"}}, -1))
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/parquet-go/parquet-go"
 	"github.com/transferia/transferia/library/go/core/metrics"
 	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/abstract"
@@ -32,28 +33,36 @@ type Sink struct {
 	uploader            *s3manager.Uploader
 	metrics             *stats.SinkerStats
 	replicationUploader *replicationUploader
+	partitioner         *partitioner
 	mu                  sync.Mutex
+
+	// completedSnapshotObjects counts objects of snapshot completed by the open partitions limit, by buffer file and bucket
+	completedSnapshotObjects map[string]map[string]int
+	// snapshotWrites is incremented by every write to snapshot holders, it orders holders by recency
+	snapshotWrites uint64
 }
 
 func (s *Sink) Close() error {
 	return nil
 }
 
-func (s *Sink) initSnapshotLoaderIfNotInited(fullTableName string, bucket string, bufferFile string, key *string) error {
-	snapshotHolders, ok := s.snapshots[bufferFile]
-	if !ok {
-		return nil
+// snapshotHolder returns holder of snapshot object of the bucket, starts its upload if needed
+func (s *Sink) snapshotHolder(fullTableName string, bucket string, bufferFile string) (*snapshotHolder, error) {
+	snapshotHolders := s.snapshots[bufferFile]
+	if holder, ok := snapshotHolders[bucket]; ok {
+		return holder, nil
 	}
-	snapshotHolder, ok := snapshotHolders[bucket]
-	if ok {
-		return nil
+	if s.partitioner != nil && len(snapshotHolders) >= s.cfg.MaxOpenPartitions {
+		if err := s.completeLeastRecentSnapshot(fullTableName, bufferFile); err != nil {
+			return nil, xerrors.Errorf("unable to complete snapshot object: %w", err)
+		}
 	}
-	var err error
-	snapshotHolder, err = s.createSnapshotIOHolder()
+	snapshotHolder, err := s.createSnapshotIOHolder()
 	if err != nil {
-		return xerrors.Errorf("unable to init snapshot holder :%v:%w", fullTableName, err)
+		return nil, xerrors.Errorf("unable to init snapshot holder :%v:%w", fullTableName, err)
 	}
 	snapshotHolders[bucket] = snapshotHolder
+	key := s.snapshotKey(bucket, fullTableName, s.completedSnapshotObjects[bufferFile][bucket])
 
 	go func() {
 		s.logger.Info("start uploading table part", log.String("table", fullTableName), log.String("key", *key))
@@ -66,6 +75,32 @@ func (s *Sink) initSnapshotLoaderIfNotInited(fullTableName string, bucket string
 		snapshotHolder.uploadDone <- err
 		close(snapshotHolder.uploadDone)
 	}()
+	return snapshotHolder, nil
+}
+
+// completeLeastRecentSnapshot finishes upload of the least recently written partition object,
+// next rows of the partition are written to a new object
+func (s *Sink) completeLeastRecentSnapshot(fullTableName string, bufferFile string) error {
+	var leastRecentBucket string
+	var leastRecent *snapshotHolder
+	for bucket, holder := range s.snapshots[bufferFile] {
+		if leastRecent == nil || holder.lastWrite < leastRecent.lastWrite {
+			leastRecentBucket, leastRecent = bucket, holder
+		}
+	}
+	if leastRecent == nil {
+		return nil
+	}
+	s.logger.Info("open partitions limit is reached, complete partition object", log.String("table", fullTableName), log.String("bucket", leastRecentBucket))
+	leastRecent.snapshot.Close()
+	if err := <-leastRecent.uploadDone; err != nil {
+		return xerrors.Errorf("unable to finish uploading partition %s of table %q: %w", leastRecentBucket, fullTableName, err)
+	}
+	delete(s.snapshots[bufferFile], leastRecentBucket)
+	if _, ok := s.completedSnapshotObjects[bufferFile]; !ok {
+		s.completedSnapshotObjects[bufferFile] = map[string]int{}
+	}
+	s.completedSnapshotObjects[bufferFile][leastRecentBucket]++
 	return nil
 }
 
@@ -78,13 +113,12 @@ func (s *Sink) Push(input []abstract.ChangeItem) error {
 	buckets := map[string]map[string]*FileCache{}
 	for i := range input {
 		row := &input[i]
-		bucket := s.bucket(*row)
-		key := s.bucketKey(*row)
 		bufferFile := s.rowPart(*row)
 		switch row.Kind {
 		case abstract.InsertKind:
-			if err := s.initSnapshotLoaderIfNotInited(s.fqtn(row), bucket, bufferFile, key); err != nil {
-				return xerrors.Errorf("unable to init snapshot loader: %w", err)
+			bucket, err := s.objectDir(*row)
+			if err != nil {
+				return xerrors.Errorf("unable to resolve object path of table %s: %w", s.fqtn(row), err)
 			}
 			if _, ok := buckets[bucket]; !ok {
 				buckets[bucket] = map[string]*FileCache{}
@@ -100,6 +134,13 @@ func (s *Sink) Push(input []abstract.ChangeItem) error {
 			fallthrough
 		case abstract.DropTableKind:
 			s.logger.Info("drop table", log.String("table", s.fqtn(row)))
+			if s.partitioner != nil {
+				if err := s.deleteTablePartitions(*row); err != nil {
+					return xerrors.Errorf("unable to delete partitions of table %s: %w", s.fqtn(row), err)
+				}
+				continue
+			}
+			key := s.snapshotKey(s.bucket(*row), s.fqtn(row), 0)
 			res, err := s.client.DeleteObject(&s3.DeleteObjectInput{
 				Bucket: aws.String(s.cfg.Bucket),
 				Key:    key,
@@ -114,6 +155,7 @@ func (s *Sink) Push(input []abstract.ChangeItem) error {
 			fullTableName := s.fqtn(row)
 			s.logger.Info("init table load", log.String("table", fullTableName))
 			s.snapshots[bufferFile] = make(map[string]*snapshotHolder)
+			delete(s.completedSnapshotObjects, bufferFile)
 		case abstract.DoneTableLoad:
 			fullTableName := s.fqtn(row)
 			snapshots := s.snapshots[bufferFile]
@@ -142,12 +184,13 @@ func (s *Sink) Push(input []abstract.ChangeItem) error {
 	for bucket, fileCaches := range buckets {
 		for filename, cache := range fileCaches {
 			// Process snapshots
-			if snapshotHoldersInBucket, ok := s.snapshots[filename]; ok {
-				snapshotHolder, ok := snapshotHoldersInBucket[bucket]
-				if !ok {
-					s.logger.Warnf("snapshot holder not fund for %s/%s", bucket, filename)
-					return xerrors.Errorf("snapshot holder not fund for %s/%s", bucket, filename)
+			if _, ok := s.snapshots[filename]; ok {
+				snapshotHolder, err := s.snapshotHolder(s.fqtn(cache.items[0]), bucket, filename)
+				if err != nil {
+					return xerrors.Errorf("unable to init snapshot loader: %w", err)
 				}
+				s.snapshotWrites++
+				snapshotHolder.lastWrite = s.snapshotWrites
 				data, err := snapshotHolder.serializer.Serialize(cache.items)
 				if err != nil {
 					return xerrors.Errorf("unable to upload table %s/%s: %w", bucket, filename, err)
@@ -257,14 +300,68 @@ func (s *Sink) bucket(row abstract.ChangeItem) string {
 	return rowBucketTime.Format(s.cfg.Layout)
 }
 
-func (s *Sink) bucketKey(row abstract.ChangeItem) *string {
-	fileName := s.fqtn(&row)
-	bucketKey := aws.String(fmt.Sprintf("%s/%s.%s", s.bucket(row), fileName, strings.ToLower(string(s.cfg.OutputFormat))))
+// objectDir returns the directory of row objects: time bucket of the layout, with the table directory and partition path for partitioned tables
+func (s *Sink) objectDir(row abstract.ChangeItem) (string, error) {
+	if s.partitioner == nil {
+		return s.bucket(row), nil
+	}
+	partitionPath, err := s.partitioner.path(row)
+	if err != nil {
+		return "", xerrors.Errorf("unable to build partition path: %w", err)
+	}
+	return joinPath(s.bucket(row), s.fqtn(&row), partitionPath), nil
+}
 
-	if s.cfg.OutputEncoding == s3_provider.GzipEncoding {
-		bucketKey = aws.String(*bucketKey + ".gz")
+// snapshotKey returns the key of the snapshot object of the table, seq is the number of already completed objects of the bucket
+func (s *Sink) snapshotKey(bucket string, fullTableName string, seq int) *string {
+	fileName := fullTableName
+	if seq > 0 {
+		fileName = fmt.Sprintf("%s-%d", fullTableName, seq)
 	}
-	return bucketKey
+	return aws.String(joinPath(bucket, fmt.Sprintf("%s.%s%s", fileName, strings.ToLower(string(s.cfg.OutputFormat)), objectSuffix(s.cfg))))
+}
+
+// deleteTablePartitions deletes all objects of the partitioned table
+func (s *Sink) deleteTablePartitions(row abstract.ChangeItem) error {
+	prefix := joinPath(s.bucket(row), s.fqtn(&row)) + "/"
+	var deleteErr error
+	err := s.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.cfg.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		if len(page.Contents) == 0 {
+			return true
+		}
+		objects := make([]*s3.ObjectIdentifier, 0, len(page.Contents))
+		for _, object := range page.Contents {
+			objects = append(objects, &s3.ObjectIdentifier{Key: object.Key})
+		}
+		if _, deleteErr = s.client.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(s.cfg.Bucket),
+			Delete: &s3.Delete{Objects: objects},
+		}); deleteErr != nil {
+			return false
+		}
+		s.logger.Info("deleted partition objects", log.String("prefix", prefix), log.Int("count", len(objects)))
+		return true
+	})
+	if err != nil {
+		return xerrors.Errorf("unable to list objects with prefix %s: %w", prefix, err)
+	}
+	if deleteErr != nil {
+		return xerrors.Errorf("unable to delete objects with prefix %s: %w", prefix, deleteErr)
+	}
+	return nil
+}
+
+func joinPath(parts ...string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, "/")
 }
 
 func (s *Sink) fqtn(row *abstract.ChangeItem) string {
@@ -293,8 +390,12 @@ func (s *Sink) UpdateOutputFormat(f model.ParsingFormat) {
 func (s *Sink) createSnapshotIOHolder() (*snapshotHolder, error) {
 	uploadDone := make(chan error)
 	var snapshot Snapshot
-	if s.cfg.OutputEncoding == s3_provider.GzipEncoding {
-		snapshot = NewSnapshotGzip()
+	if encoder := newObjectEncoder(s.cfg); encoder != nil {
+		compressed, err := newSnapshotCompressed(encoder)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to init compressed snapshot: %w", err)
+		}
+		snapshot = compressed
 	} else {
 		snapshot = NewSnapshotRaw()
 	}
@@ -335,6 +436,9 @@ func createSerializer(cfg *s3_provider.S3Destination) (serializer.BatchSerialize
 	case model.ParsingFormatCSV:
 		return serializer.NewCsvBatchSerializer(nil), nil
 	case model.ParsingFormatPARQUET:
+		if codec, ok := parquetCodecs[cfg.OutputEncoding]; ok {
+			return serializer.NewParquetBatchSerializer(parquet.Compression(codec)), nil
+		}
 		return serializer.NewParquetBatchSerializer(), nil
 	default:
 		return nil, xerrors.New("s3_sink: Unsupported format")
@@ -364,6 +468,11 @@ func NewSink(lgr log.Logger, cfg *s3_provider.S3Destination, mtrcs metrics.Regis
 	uploader := s3manager.NewUploader(sess)
 	uploader.PartSize = cfg.PartSize
 
+	partitioner, err := newPartitioner(cfg.PartitionBy)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to init partitioning: %w", err)
+	}
+
 	return &Sink{
 		client:              s3Client,
 		cfg:                 cfg,
@@ -373,5 +482,9 @@ func NewSink(lgr log.Logger, cfg *s3_provider.S3Destination, mtrcs metrics.Regis
 		mu:                  sync.Mutex{},
 		snapshots:           map[string]map[string]*snapshotHolder{},
 		replicationUploader: buffer,
+		partitioner:         partitioner,
+
+		completedSnapshotObjects: map[string]map[string]int{},
+		snapshotWrites:           0,
 	}, nil
 }
@@ -12,4 +12,6 @@ type snapshotHolder struct {
 	uploadDone chan error
 	snapshot   Snapshot
 	serializer serializer.BatchSerializer
+	// lastWrite orders holders by the last write for the open partitions limit
+	lastWrite uint64
 }
@@ -0,0 +1,77 @@
+package sink
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+)
+
+// SnapshotCompressed compresses snapshot data on the fly with the writer of the object encoder
+type SnapshotCompressed struct {
+	feedChannel chan []byte
+	writer      compressWriter
+	buffer      *bytes.Buffer
+	closed      bool
+	remainder   []byte
+}
+
+func (b *SnapshotCompressed) Read(buf []byte) (n int, err error) {
+	for {
+		deltaN := copy(buf, b.remainder)
+		n += deltaN
+		b.remainder = b.remainder[deltaN:]
+		if n > 0 {
+			return n, nil
+		}
+		if b.closed {
+			return 0, io.EOF
+		}
+		// remainder exhausted, read the next chunk from the feed channel
+		data, ok := <-b.feedChannel
+		if !ok {
+			if err := b.writer.Flush(); err != nil {
+				return 0, xerrors.Errorf("unable to flush data: %w", err)
+			}
+			if err := b.writer.Close(); err != nil {
+				return 0, xerrors.Errorf("unable to close compressor: %w", err)
+			}
+			// after writer close we have compression footer, so add it to remainder and close reader
+			b.remainder = b.buffer.Bytes()
+			b.closed = true
+			continue
+		}
+		if _, err := b.writer.Write(data); err != nil {
+			return 0, xerrors.Errorf("unable to compress part: %w", err)
+		}
+		if err := b.writer.Flush(); err != nil {
+			return 0, xerrors.Errorf("unable to flush data: %w", err)
+		}
+		b.remainder = b.buffer.Bytes()
+		b.buffer.Reset()
+	}
+}
+
+func (b *SnapshotCompressed) FeedChannel() chan<- []byte {
+	return b.feedChannel
+}
+
+func (b *SnapshotCompressed) Close() {
+	close(b.feedChannel)
+}
+
+func newSnapshotCompressed(encoder *objectEncoder) (*SnapshotCompressed, error) {
+	var bb bytes.Buffer
+	w, err := encoder.newWriter(&bb)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create %s writer: %w", encoder.suffix, err)
+	}
+	reader := &SnapshotCompressed{
+		feedChannel: make(chan []byte),
+		writer:      w,
+		buffer:      &bb,
+		closed:      false,
+		remainder:   nil,
+	}
+	return reader, nil
+}
@@ -0,0 +1,91 @@
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	s3_provider "github.com/transferia/transferia/pkg/providers/s3"
+)
+
+func decompress(t *testing.T, encoding s3_provider.Encoding, data []byte) []byte {
+	var reader io.Reader
+	switch encoding {
+	case s3_provider.GzipEncoding:
+		gzReader, err := gzip.NewReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		reader = gzReader
+	case s3_provider.ZstdEncoding:
+		zstdReader, err := zstd.NewReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		defer zstdReader.Close()
+		reader = zstdReader
+	case s3_provider.Lz4Encoding:
+		reader = lz4.NewReader(bytes.NewReader(data))
+	case s3_provider.SnappyEncoding:
+		reader = snappy.NewReader(bytes.NewReader(data))
+	}
+	decoded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return decoded
+}
+
+func TestCompressedSnapshot(t *testing.T) {
+	for _, encoding := range []s3_provider.Encoding{
+		s3_provider.GzipEncoding,
+		s3_provider.ZstdEncoding,
+		s3_provider.Lz4Encoding,
+		s3_provider.SnappyEncoding,
+	} {
+		t.Run(string(encoding), func(t *testing.T) {
+			encoder := newObjectEncoder(&s3_provider.S3Destination{OutputFormat: model.ParsingFormatJSON, OutputEncoding: encoding})
+			require.NotNil(t, encoder)
+			rdr, err := newSnapshotCompressed(encoder)
+			require.NoError(t, err)
+			line := "line of data\n"
+			repeats := 100
+			go func() {
+				for i := 0; i < repeats; i++ {
+					rdr.FeedChannel() <- []byte(strings.Repeat(line, 1000))
+				}
+				rdr.Close()
+			}()
+			data, err := io.ReadAll(rdr)
+			require.NoError(t, err)
+			decoded := decompress(t, encoding, data)
+			require.True(t, strings.Contains(string(decoded), "line of data"))
+			require.Equal(t, len(decoded), repeats*1000*len(line))
+
+			encoded, err := encoder.encode([]byte(line))
+			require.NoError(t, err)
+			require.Equal(t, line, string(decompress(t, encoding, encoded)))
+		})
+	}
+}
+
+func TestObjectSuffix(t *testing.T) {
+	for _, tc := range []struct {
+		format   model.ParsingFormat
+		encoding s3_provider.Encoding
+		suffix   string
+	}{
+		{format: model.ParsingFormatJSON, encoding: s3_provider.NoEncoding, suffix: ""},
+		{format: model.ParsingFormatJSON, encoding: s3_provider.GzipEncoding, suffix: ".gz"},
+		{format: model.ParsingFormatJSON, encoding: s3_provider.ZstdEncoding, suffix: ".zst"},
+		{format: model.ParsingFormatCSV, encoding: s3_provider.Lz4Encoding, suffix: ".lz4"},
+		{format: model.ParsingFormatRaw, encoding: s3_provider.SnappyEncoding, suffix: ".sz"},
+		// parquet pages are compressed instead of objects
+		{format: model.ParsingFormatPARQUET, encoding: s3_provider.ZstdEncoding, suffix: ""},
+		{format: model.ParsingFormatPARQUET, encoding: s3_provider.SnappyEncoding, suffix: ""},
+		{format: model.ParsingFormatPARQUET, encoding: s3_provider.GzipEncoding, suffix: ".gz"},
+	} {
+		require.Equal(t, tc.suffix, objectSuffix(&s3_provider.S3Destination{OutputFormat: tc.format, OutputEncoding: tc.encoding}), "%s %s", tc.format, tc.encoding)
+	}
+}
@@ -2,7 +2,6 @@ package sink
 
 import (
 	"bytes"
-	"compress/gzip"
 	"errors"
 	"fmt"
 	"strings"
@@ -31,28 +30,20 @@ type replicationUploader struct {
 
 func (u *replicationUploader) Upload(name string, lsns []uint64, data []byte) error {
 	st := time.Now()
-	buf := &bytes.Buffer{}
 	fileName := fmt.Sprintf("%v.%v", name, strings.ToLower(string(u.cfg.OutputFormat)))
 	if len(lsns) > 0 && lsns[len(lsns)-1] != 0 {
 		fileName = fmt.Sprintf("%v-%v_%v.%v", name, lsns[0], lsns[len(lsns)-1], strings.ToLower(string(u.cfg.OutputFormat)))
 	}
-	if u.cfg.OutputEncoding == s3_provider.GzipEncoding {
-		fileName = fileName + ".gz"
-		gzWriter := gzip.NewWriter(buf)
-		if _, err := gzWriter.Write(data); err != nil {
-			return err
-		}
-		if err := gzWriter.Close(); err != nil {
-			return xerrors.Errorf("unable to close gzip writer: %w", err)
-		}
-	} else {
-		_, err := buf.Write(data)
+	if encoder := newObjectEncoder(u.cfg); encoder != nil {
+		fileName = fileName + encoder.suffix
+		encoded, err := encoder.encode(data)
 		if err != nil {
-			return xerrors.Errorf("unable to write: %w", err)
+			return xerrors.Errorf("unable to encode object: %w", err)
 		}
+		data = encoded
 	}
 	res, err := u.uploader.Upload(&s3manager.UploadInput{
-		Body:                    bytes.NewReader(buf.Bytes()),
+		Body:                    bytes.NewReader(data),
 		Bucket:                  aws.String(u.cfg.Bucket),
 		Key:                     aws.String(fileName),
 		Metadata:                nil,
@@ -69,7 +60,7 @@ func (u *replicationUploader) Upload(name string, lsns []uint64, data []byte) er
 		}
 		return xerrors.Errorf("upload failed: %w", err)
 	} else {
-		u.logger.Infof("upload done: %v %v in %v", fileName, format.SizeInt(buf.Len()), time.Since(st))
+		u.logger.Infof("upload done: %v %v in %v", fileName, format.SizeInt(len(data)), time.Since(st))
 	}
 	return nil
 }
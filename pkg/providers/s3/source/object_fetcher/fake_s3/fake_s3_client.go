@@ -1,9 +1,15 @@
 package fake_s3
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
@@ -34,6 +40,75 @@ func (c *FakeS3Client) ListObjectsPagesWithContext(_ aws.Context, _ *s3.ListObje
 	return nil
 }
 
+// ListObjects lists files in the order of keys, like S3 does, it's used by s3util.ListFiles
+func (c *FakeS3Client) ListObjects(input *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+	var files []*File
+	for _, currFile := range c.files {
+		if strings.HasPrefix(currFile.FileName, aws.StringValue(input.Prefix)) && currFile.FileName > aws.StringValue(input.Marker) {
+			files = append(files, currFile)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].FileName < files[j].FileName })
+	if input.MaxKeys != nil && int64(len(files)) > *input.MaxKeys {
+		files = files[:*input.MaxKeys]
+	}
+	output := &s3.ListObjectsOutput{
+		Contents: []*s3.Object{},
+	}
+	for _, currFile := range files {
+		output.Contents = append(output.Contents, &s3.Object{
+			Key:          aws.String(currFile.FileName),
+			Size:         aws.Int64(int64(len(currFile.Body))),
+			LastModified: aws.Time(currFile.LastModified),
+		})
+	}
+	return output, nil
+}
+
+func (c *FakeS3Client) HeadObjectWithContext(_ aws.Context, input *s3.HeadObjectInput, _ ...request.Option) (*s3.HeadObjectOutput, error) {
+	currFile, err := c.file(aws.StringValue(input.Key))
+	if err != nil {
+		return nil, err
+	}
+	return &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(currFile.Body))),
+		LastModified:  aws.Time(currFile.LastModified),
+	}, nil
+}
+
+// GetObjectWithContext supports ranges of the 'bytes=<first>-<last>' form, which are used by s3raw readers
+func (c *FakeS3Client) GetObjectWithContext(_ aws.Context, input *s3.GetObjectInput, _ ...request.Option) (*s3.GetObjectOutput, error) {
+	currFile, err := c.file(aws.StringValue(input.Key))
+	if err != nil {
+		return nil, err
+	}
+	body := currFile.Body
+	if input.Range != nil {
+		var first, last int
+		if _, err := fmt.Sscanf(*input.Range, "bytes=%d-%d", &first, &last); err != nil {
+			return nil, awserr.New("InvalidRange", err.Error(), nil)
+		}
+		if first >= len(body) {
+			return nil, awserr.New("InvalidRange", fmt.Sprintf("range %s is out of %d bytes", *input.Range, len(body)), nil)
+		}
+		body = body[first:min(last+1, len(body))]
+	}
+	return &s3.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: aws.Int64(int64(len(body))),
+		LastModified:  aws.Time(currFile.LastModified),
+	}, nil
+}
+
+func (c *FakeS3Client) file(fileName string) (*File, error) {
+	for _, currFile := range c.files {
+		if currFile.FileName == fileName {
+			return currFile, nil
+		}
+	}
+	return nil, awserr.New(s3.ErrCodeNoSuchKey, fmt.Sprintf("file %s not found", fileName), nil)
+}
+
 func (c *FakeS3Client) validate() {
 	uniqFiles := set.New[string]()
 	for _, file := range c.files {
@@ -136,6 +136,20 @@ func (s *Storage) readFile(ctx context.Context, part abstract.TableDescription,
 		return xerrors.Errorf("%s expected to be string, but got: %T", s3FileNameCol, fileOp.Val)
 	}
 	syncPusher := pusher.New(inPusher, nil, s.logger, 0)
+	from, to, isPart, err := objectPartRange(part.Filter)
+	if err != nil {
+		return xerrors.Errorf("unable to extract range of file: %w", err)
+	}
+	if isPart {
+		splitReader, ok := s.reader.(reader.SplitReader)
+		if !ok || !splitReader.Splittable() {
+			return xerrors.Errorf("unable to read range of file: %s: reader is not splittable", part.Filter)
+		}
+		if err := splitReader.ReadSplit(ctx, fileName, from, to, syncPusher); err != nil {
+			return xerrors.Errorf("unable to read range of file: %s: %w", part.Filter, err)
+		}
+		return nil
+	}
 	if err := s.reader.Read(ctx, fileName, syncPusher); err != nil {
 		return xerrors.Errorf("unable to read file: %s: %w", part.Filter, err)
 	}
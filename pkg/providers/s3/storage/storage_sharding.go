@@ -20,8 +20,12 @@ var (
 )
 
 const (
-	s3FileNameCol = "s3_file_name"
-	s3VersionCol  = "s3_file_version"
+	s3FileNameCol   = "s3_file_name"
+	s3VersionCol    = "s3_file_version"
+	s3FileOffsetCol = "s3_file_offset"
+
+	// objectPartSize is the size of ranges, which big objects of splittable formats are sharded by
+	objectPartSize = 128 * 1024 * 1024
 )
 
 // defaultShardingFilter used for shardDefault.
@@ -29,6 +33,46 @@ func defaultShardingFilter(filepath string) abstract.WhereStatement {
 	return abstract.WhereStatement(fmt.Sprintf(`"%s" = '%s'`, s3FileNameCol, filepath))
 }
 
+// objectPartShardingFilter used for shardDefault of big objects, which are read by ranges of bytes.
+// Conditions are parenthesized, since pkg/predicate extracts operands of chains of more than two ANDs incorrectly.
+func objectPartShardingFilter(filepath string, from, to int64) abstract.WhereStatement {
+	rangeFilter := abstract.WhereStatement(fmt.Sprintf(`"%s" >= %d AND "%s" < %d`, s3FileOffsetCol, from, s3FileOffsetCol, to))
+	return abstract.FiltersIntersection(defaultShardingFilter(filepath), rangeFilter)
+}
+
+// objectPartRange extracts range of bytes from filter of objectPartShardingFilter, ok is false for filters of whole objects.
+func objectPartRange(filter abstract.WhereStatement) (from, to int64, ok bool, err error) {
+	operands, err := predicate.InclusionOperands(filter, s3FileOffsetCol)
+	if err != nil {
+		return 0, 0, false, xerrors.Errorf("unable to extract '%s' filter: %w", s3FileOffsetCol, err)
+	}
+	if len(operands) == 0 {
+		return 0, 0, false, nil
+	}
+	if len(operands) != 2 {
+		return 0, 0, false, xerrors.Errorf("expect range of %s in filter: %s, but got %d operands", s3FileOffsetCol, filter, len(operands))
+	}
+	var hasFrom, hasTo bool
+	for _, operand := range operands {
+		val, isNumber := operand.Val.(float64)
+		if !isNumber {
+			return 0, 0, false, xerrors.Errorf("%s expected to be number, but got: %T", s3FileOffsetCol, operand.Val)
+		}
+		switch operand.Op {
+		case predicate.GTE:
+			from, hasFrom = int64(val), true
+		case predicate.LT:
+			to, hasTo = int64(val), true
+		default:
+			return 0, 0, false, xerrors.Errorf("%s predicate expected to be `>=` or `<`, but got: %s", s3FileOffsetCol, operand)
+		}
+	}
+	if !hasFrom || !hasTo {
+		return 0, 0, false, xerrors.Errorf("expect both bounds of %s in filter: %s", s3FileOffsetCol, filter)
+	}
+	return from, to, true, nil
+}
+
 // ManyFilesShardingFilter used for shardByLimits.
 func ManyFilesShardingFilter(filepaths []string) abstract.WhereStatement {
 	return abstract.WhereStatement(fmt.Sprintf("%s IN ('%s')", s3FileNameCol, strings.Join(filepaths, "','")))
@@ -103,8 +147,14 @@ func (s *Storage) ShardTable(ctx context.Context, tdesc abstract.TableDescriptio
 }
 
 func (s *Storage) shardDefault(tdesc abstract.TableDescription, files []*FileWithStats) []abstract.TableDescription {
+	splitReader, splittable := s.reader.(reader.SplitReader)
+	splittable = splittable && splitReader.Splittable()
 	res := make([]abstract.TableDescription, 0, len(files))
 	for _, file := range files {
+		if splittable && file.Object.Size != nil && *file.Object.Size > objectPartSize {
+			res = append(res, s.shardObject(tdesc, file)...)
+			continue
+		}
 		res = append(res, abstract.TableDescription{
 			Name:   s.cfg.TableName,
 			Schema: s.cfg.TableNamespace,
@@ -116,6 +166,23 @@ func (s *Storage) shardDefault(tdesc abstract.TableDescription, files []*FileWit
 	return res
 }
 
+// shardObject splits big object into ranges of objectPartSize bytes, rows are distributed between ranges evenly
+func (s *Storage) shardObject(tdesc abstract.TableDescription, file *FileWithStats) []abstract.TableDescription {
+	size := *file.Object.Size
+	parts := (size + objectPartSize - 1) / objectPartSize
+	res := make([]abstract.TableDescription, 0, parts)
+	for from := int64(0); from < size; from += objectPartSize {
+		res = append(res, abstract.TableDescription{
+			Name:   s.cfg.TableName,
+			Schema: s.cfg.TableNamespace,
+			Filter: abstract.FiltersIntersection(tdesc.Filter, objectPartShardingFilter(*file.Key, from, min(from+objectPartSize, size))),
+			EtaRow: file.Rows / uint64(parts),
+			Offset: 0,
+		})
+	}
+	return res
+}
+
 func (s *Storage) shardByLimits(files []*FileWithStats) ([]abstract.TableDescription, error) {
 	if s.cfg.ShardingParams == nil {
 		return nil, xerrors.New("sharding params is not set")
@@ -9,12 +9,15 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	aws_s3 "github.com/aws/aws-sdk-go/service/s3"
 	"github.com/dustin/go-humanize"
 	"github.com/stretchr/testify/require"
 	"github.com/transferia/transferia/internal/logger"
 	"github.com/transferia/transferia/library/go/core/metrics/solomon"
 	"github.com/transferia/transferia/pkg/abstract"
 	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/predicate"
 	"github.com/transferia/transferia/pkg/providers/s3"
 	"github.com/transferia/transferia/pkg/providers/s3/s3recipe"
 )
@@ -131,3 +134,30 @@ func TestCustomSharding(t *testing.T) {
 		})
 	}
 }
+
+func TestObjectPartSharding(t *testing.T) {
+	storage := &Storage{cfg: &s3.S3Source{TableName: "events", TableNamespace: "ns"}}
+	size := int64(2*objectPartSize + 10)
+	file := &FileWithStats{Object: &aws_s3.Object{Key: aws.String("data/a.avro"), Size: aws.Int64(size)}, Rows: 300, Size: 0}
+
+	parts := storage.shardObject(abstract.TableDescription{Name: "events", Schema: "ns", Filter: `"s3_file_version" > '2024'`}, file)
+	require.Len(t, parts, 3)
+	var ranges [][2]int64
+	for _, part := range parts {
+		require.Equal(t, uint64(100), part.EtaRow)
+		from, to, ok, err := objectPartRange(part.Filter)
+		require.NoError(t, err)
+		require.True(t, ok)
+		ranges = append(ranges, [2]int64{from, to})
+		fileOps, err := predicate.InclusionOperands(part.Filter, s3FileNameCol)
+		require.NoError(t, err)
+		require.Equal(t, []predicate.Operand{{Op: predicate.EQ, Val: "data/a.avro"}}, fileOps)
+	}
+	require.Equal(t, [][2]int64{{0, objectPartSize}, {objectPartSize, 2 * objectPartSize}, {2 * objectPartSize, size}}, ranges)
+
+	_, _, ok, err := objectPartRange(defaultShardingFilter("data/a.avro"))
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, _, _, err = objectPartRange(`"s3_file_offset" > 10`)
+	require.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package sqlite
+
+import (
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+)
+
+var _ model.Destination = (*SqliteDestination)(nil)
+
+type SqliteDestination struct {
+	// Path is a path to the database file, it is created if it does not exist
+	Path string
+	// AltNames renames tables: keys are the names resolved from source tables, values are the names in the database file
+	AltNames map[string]string
+	Cleanup  model.CleanupType
+}
+
+func (d *SqliteDestination) GetProviderType() abstract.ProviderType {
+	return ProviderType
+}
+
+func (d *SqliteDestination) Validate() error {
+	if d.Path == "" {
+		return xerrors.New("path to the database file is required")
+	}
+	return nil
+}
+
+func (d *SqliteDestination) WithDefaults() {
+	if d.Cleanup == "" {
+		d.Cleanup = model.Drop
+	}
+}
+
+func (d *SqliteDestination) CleanupMode() model.CleanupType {
+	return d.Cleanup
+}
+
+func (SqliteDestination) IsDestination() {}
+
+// TableName returns the name of the table in the database file for a source table,
+// tables of the default schemas keep their names while others are prefixed with the schema name
+func (d *SqliteDestination) TableName(tID abstract.TableID) string {
+	name := tID.Name
+	switch tID.Namespace {
+	case "", "public", "main":
+	default:
+		name = tID.Namespace + "_" + tID.Name
+	}
+	if altName, ok := d.AltNames[name]; ok {
+		return altName
+	}
+	return name
+}
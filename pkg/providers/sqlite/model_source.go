@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"slices"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+)
+
+var (
+	_ model.Source            = (*SqliteSource)(nil)
+	_ model.IncrementalSource = (*SqliteSource)(nil)
+	_ model.Includeable       = (*SqliteSource)(nil)
+)
+
+const (
+	defaultBatchSize       = 10_000
+	defaultDesiredPartRows = 1_000_000
+)
+
+type SqliteSource struct {
+	// Path is a path to the database file
+	Path string
+
+	IncludeTables []string
+	ExcludeTables []string
+
+	// BatchSize is the max number of rows pushed at once
+	BatchSize int
+	// DesiredPartRows is the number of rows above which a table is sharded into parts by rowid ranges
+	DesiredPartRows uint64
+}
+
+func (s *SqliteSource) GetProviderType() abstract.ProviderType {
+	return ProviderType
+}
+
+func (s *SqliteSource) Validate() error {
+	if s.Path == "" {
+		return xerrors.New("path to the database file is required")
+	}
+	return nil
+}
+
+func (s *SqliteSource) WithDefaults() {
+	if s.BatchSize == 0 {
+		s.BatchSize = defaultBatchSize
+	}
+	if s.DesiredPartRows == 0 {
+		s.DesiredPartRows = defaultDesiredPartRows
+	}
+}
+
+func (*SqliteSource) IsSource()                      {}
+func (*SqliteSource) IsStrictSource()                {}
+func (*SqliteSource) IsIncremental()                 {}
+func (*SqliteSource) SupportsStartCursorValue() bool { return true }
+
+func (s *SqliteSource) Include(tID abstract.TableID) bool {
+	return len(s.fulfilledIncludesImpl(tID, true)) > 0
+}
+
+func (s *SqliteSource) FulfilledIncludes(tID abstract.TableID) []string {
+	return s.fulfilledIncludesImpl(tID, false)
+}
+
+func (s *SqliteSource) AllIncludes() []string {
+	return s.IncludeTables
+}
+
+func (s *SqliteSource) fulfilledIncludesImpl(tID abstract.TableID, firstIncludeOnly bool) []string {
+	if tID.Namespace != "" || slices.Contains(s.ExcludeTables, tID.Name) {
+		return nil
+	}
+	if len(s.IncludeTables) == 0 {
+		return []string{""}
+	}
+	var result []string
+	for _, table := range s.IncludeTables {
+		if table == tID.Name {
+			result = append(result, table)
+			if firstIncludeOnly {
+				return result
+			}
+		}
+	}
+	return result
+}
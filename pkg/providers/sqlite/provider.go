@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	cpclient "github.com/transferia/transferia/pkg/abstract/coordinator"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/middlewares"
+	"github.com/transferia/transferia/pkg/providers"
+	"github.com/transferia/transferia/pkg/util/gobwrapper"
+	"go.ytsaurus.tech/library/go/core/log"
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	gobwrapper.Register(new(SqliteSource))
+	gobwrapper.Register(new(SqliteDestination))
+	model.RegisterSource(ProviderType, func() model.Source {
+		return new(SqliteSource)
+	})
+	model.RegisterDestination(ProviderType, func() model.Destination {
+		return new(SqliteDestination)
+	})
+	abstract.RegisterProviderName(ProviderType, "SQLite")
+
+	providers.Register(ProviderType, New)
+}
+
+const (
+	ProviderType = abstract.ProviderType("sqlite")
+
+	driverName = "sqlite"
+)
+
+// To verify providers contract implementation
+var (
+	_ providers.Snapshot = (*Provider)(nil)
+	_ providers.Sinker   = (*Provider)(nil)
+
+	_ providers.Activator = (*Provider)(nil)
+)
+
+type Provider struct {
+	logger   log.Logger
+	registry metrics.Registry
+	cp       cpclient.Coordinator
+	transfer *model.Transfer
+}
+
+func (p *Provider) Type() abstract.ProviderType {
+	return ProviderType
+}
+
+func (p *Provider) Storage() (abstract.Storage, error) {
+	src, ok := p.transfer.Src.(*SqliteSource)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected source type: %T", p.transfer.Src)
+	}
+	return NewStorage(src, p.logger, p.registry)
+}
+
+func (p *Provider) Sink(middlewares.Config) (abstract.Sinker, error) {
+	dst, ok := p.transfer.Dst.(*SqliteDestination)
+	if !ok {
+		return nil, xerrors.Errorf("unexpected target type: %T", p.transfer.Dst)
+	}
+	return NewSink(dst, p.logger, p.registry)
+}
+
+func (p *Provider) Activate(_ context.Context, _ *model.TransferOperation, tables abstract.TableMap, callbacks providers.ActivateCallbacks) error {
+	if p.transfer.SrcType() != ProviderType {
+		return nil
+	}
+	if !p.transfer.SnapshotOnly() {
+		return xerrors.New("Only allowed mode for SQLite source is snapshot")
+	}
+	if err := callbacks.Cleanup(tables); err != nil {
+		return xerrors.Errorf("Sinker cleanup failed: %w", err)
+	}
+	if err := callbacks.CheckIncludes(tables); err != nil {
+		return xerrors.Errorf("Failed in accordance with configuration: %w", err)
+	}
+	if err := callbacks.Upload(tables); err != nil {
+		return xerrors.Errorf("Snapshot loading failed: %w", err)
+	}
+	return nil
+}
+
+func New(lgr log.Logger, registry metrics.Registry, cp cpclient.Coordinator, transfer *model.Transfer) providers.Provider {
+	return &Provider{
+		logger:   lgr,
+		registry: registry,
+		cp:       cp,
+		transfer: transfer,
+	}
+}
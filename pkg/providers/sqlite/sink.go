@@ -0,0 +1,306 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/stats"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+var _ abstract.Sinker = (*sink)(nil)
+
+type sink struct {
+	db      *sql.DB
+	config  *SqliteDestination
+	logger  log.Logger
+	metrics *stats.SinkerStats
+
+	// SQLite has a single writer, so pushes are serialized instead of waiting for the database lock
+	mu      sync.Mutex
+	created map[string]bool
+}
+
+func (s *sink) Close() error {
+	return s.db.Close()
+}
+
+func (s *sink) Push(input []abstract.ChangeItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx := context.Background()
+	for i := 0; i < len(input); {
+		item := input[i]
+		switch item.Kind {
+		case abstract.DropTableKind:
+			if s.config.Cleanup == model.Drop {
+				if err := s.dropTable(ctx, item.TableID()); err != nil {
+					return xerrors.Errorf("unable to drop table %s: %w", item.Fqtn(), err)
+				}
+			}
+			i++
+		case abstract.TruncateTableKind:
+			if s.config.Cleanup == model.Truncate {
+				if err := s.truncateTable(ctx, item.TableID()); err != nil {
+					return xerrors.Errorf("unable to truncate table %s: %w", item.Fqtn(), err)
+				}
+			}
+			i++
+		case abstract.InsertKind, abstract.UpdateKind, abstract.DeleteKind:
+			end := i + 1
+			for end < len(input) && isApplicableRow(input[end].Kind) {
+				end++
+			}
+			if err := s.applyRows(ctx, input[i:end]); err != nil {
+				return xerrors.Errorf("unable to apply %d rows: %w", end-i, err)
+			}
+			i = end
+		case abstract.InitShardedTableLoad, abstract.InitTableLoad, abstract.DoneTableLoad, abstract.DoneShardedTableLoad, abstract.SynchronizeKind:
+			i++
+		default:
+			s.logger.Infof("kind: %v not supported", item.Kind)
+			i++
+		}
+	}
+	return nil
+}
+
+func isApplicableRow(kind abstract.Kind) bool {
+	return kind == abstract.InsertKind || kind == abstract.UpdateKind || kind == abstract.DeleteKind
+}
+
+func (s *sink) dropTable(ctx context.Context, tID abstract.TableID) error {
+	table := s.config.TableName(tID)
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("drop table if exists %s", quoteIdentifier(table))); err != nil {
+		return xerrors.Errorf("unable to execute drop: %w", err)
+	}
+	delete(s.created, table)
+	return nil
+}
+
+func (s *sink) truncateTable(ctx context.Context, tID abstract.TableID) error {
+	table := s.config.TableName(tID)
+	exists, err := s.tableExists(ctx, table)
+	if err != nil {
+		return xerrors.Errorf("unable to check table existence: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("delete from %s", quoteIdentifier(table))); err != nil {
+		return xerrors.Errorf("unable to execute delete: %w", err)
+	}
+	return nil
+}
+
+func (s *sink) tableExists(ctx context.Context, table string) (bool, error) {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `select count(*) > 0 from sqlite_master where type = 'table' and name = ?`, table).Scan(&exists); err != nil {
+		return false, xerrors.Errorf("unable to select table: %w", err)
+	}
+	return exists, nil
+}
+
+// applyRows applies row events in a single transaction keeping their order
+func (s *sink) applyRows(ctx context.Context, items []abstract.ChangeItem) error {
+	for _, item := range items {
+		if err := s.createTable(ctx, item); err != nil {
+			return xerrors.Errorf("unable to create table %s: %w", item.Fqtn(), err)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return xerrors.Errorf("unable to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rowsByTable := make(map[string]int)
+	for _, item := range items {
+		query, args, err := s.buildQuery(item)
+		if err != nil {
+			return xerrors.Errorf("unable to build query for table %s: %w", item.Fqtn(), err)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return xerrors.Errorf("unable to execute %s for table %s: %w", item.Kind, item.Fqtn(), err)
+		}
+		rowsByTable[item.Fqtn()]++
+	}
+	if err := tx.Commit(); err != nil {
+		return xerrors.Errorf("unable to commit transaction: %w", err)
+	}
+	for table, rows := range rowsByTable {
+		s.metrics.Table(table, "rows", rows)
+	}
+	return nil
+}
+
+func (s *sink) createTable(ctx context.Context, item abstract.ChangeItem) error {
+	table := s.config.TableName(item.TableID())
+	if s.created[table] {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, buildCreateTableQuery(table, item.TableSchema.Columns())); err != nil {
+		return xerrors.Errorf("unable to execute create: %w", err)
+	}
+	s.created[table] = true
+	return nil
+}
+
+func buildCreateTableQuery(table string, columns abstract.TableColumns) string {
+	defs := make([]string, 0, len(columns)+1)
+	var keys []string
+	for _, col := range columns {
+		def := quoteIdentifier(col.ColumnName)
+		if typ := ColumnType(col); typ != "" {
+			def += " " + typ
+		}
+		if col.Required {
+			def += " not null"
+		}
+		defs = append(defs, def)
+		if col.IsKey() {
+			keys = append(keys, quoteIdentifier(col.ColumnName))
+		}
+	}
+	if len(keys) > 0 {
+		defs = append(defs, fmt.Sprintf("primary key (%s)", strings.Join(keys, ", ")))
+	}
+	return fmt.Sprintf("create table if not exists %s (%s)", quoteIdentifier(table), strings.Join(defs, ", "))
+}
+
+func (s *sink) buildQuery(item abstract.ChangeItem) (string, []interface{}, error) {
+	table := quoteIdentifier(s.config.TableName(item.TableID()))
+	switch item.Kind {
+	case abstract.InsertKind:
+		return buildUpsertQuery(table, item)
+	case abstract.UpdateKind:
+		if len(item.KeyCols()) == 0 || item.IsToasted() || item.KeysChanged() {
+			return buildUpdateQuery(table, item)
+		}
+		return buildUpsertQuery(table, item)
+	case abstract.DeleteKind:
+		return buildDeleteQuery(table, item)
+	default:
+		return "", nil, xerrors.Errorf("unexpected kind %s", item.Kind)
+	}
+}
+
+// buildUpsertQuery inserts a row replacing the row with the same key
+func buildUpsertQuery(table string, item abstract.ChangeItem) (string, []interface{}, error) {
+	colSchemas := abstract.MakeFastTableSchema(item.TableSchema.Columns())
+	cols := make([]string, len(item.ColumnNames))
+	placeholders := make([]string, len(item.ColumnNames))
+	args := make([]interface{}, len(item.ColumnNames))
+	var updates []string
+	for i, name := range item.ColumnNames {
+		col := colSchemas[abstract.ColumnName(name)]
+		val, err := toSqlite(item.ColumnValues[i], col)
+		if err != nil {
+			return "", nil, xerrors.Errorf("unable to convert value of column %s: %w", name, err)
+		}
+		cols[i] = quoteIdentifier(name)
+		placeholders[i] = "?"
+		args[i] = val
+		if !col.IsKey() {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%[1]s", cols[i]))
+		}
+	}
+	query := fmt.Sprintf("insert into %s (%s) values (%s)", table, strings.Join(cols, ", "), strings.Join(placeholders, ", "))
+
+	keys := item.KeyCols()
+	if len(keys) == 0 {
+		return query, args, nil
+	}
+	for i, key := range keys {
+		keys[i] = quoteIdentifier(key)
+	}
+	if len(updates) == 0 {
+		return fmt.Sprintf("%s on conflict (%s) do nothing", query, strings.Join(keys, ", ")), args, nil
+	}
+	return fmt.Sprintf("%s on conflict (%s) do update set %s", query, strings.Join(keys, ", "), strings.Join(updates, ", ")), args, nil
+}
+
+// buildUpdateQuery updates the present columns of a row found by the old keys
+func buildUpdateQuery(table string, item abstract.ChangeItem) (string, []interface{}, error) {
+	colSchemas := abstract.MakeFastTableSchema(item.TableSchema.Columns())
+	sets := make([]string, len(item.ColumnNames))
+	args := make([]interface{}, 0, len(item.ColumnNames))
+	for i, name := range item.ColumnNames {
+		val, err := toSqlite(item.ColumnValues[i], colSchemas[abstract.ColumnName(name)])
+		if err != nil {
+			return "", nil, xerrors.Errorf("unable to convert value of column %s: %w", name, err)
+		}
+		sets[i] = fmt.Sprintf("%s = ?", quoteIdentifier(name))
+		args = append(args, val)
+	}
+	where, whereArgs, err := buildWhereOldKeys(item)
+	if err != nil {
+		return "", nil, xerrors.Errorf("unable to build condition: %w", err)
+	}
+	return fmt.Sprintf("update %s set %s where %s", table, strings.Join(sets, ", "), where), append(args, whereArgs...), nil
+}
+
+func buildDeleteQuery(table string, item abstract.ChangeItem) (string, []interface{}, error) {
+	where, args, err := buildWhereOldKeys(item)
+	if err != nil {
+		return "", nil, xerrors.Errorf("unable to build condition: %w", err)
+	}
+	return fmt.Sprintf("delete from %s where %s", table, where), args, nil
+}
+
+// buildWhereOldKeys builds a null-safe condition matching the old keys of a row,
+// the current key values are used if the old keys are absent
+func buildWhereOldKeys(item abstract.ChangeItem) (string, []interface{}, error) {
+	names, values := item.OldKeys.KeyNames, item.OldKeys.KeyValues
+	if len(names) == 0 {
+		keysMap := item.KeysAsMap()
+		for _, key := range item.KeyCols() {
+			if val, ok := keysMap[key]; ok {
+				names = append(names, key)
+				values = append(values, val)
+			}
+		}
+	}
+	if len(names) == 0 || len(names) != len(values) {
+		return "", nil, xerrors.Errorf("no keys to find a row of table %s", item.Fqtn())
+	}
+
+	colSchemas := abstract.MakeFastTableSchema(item.TableSchema.Columns())
+	conditions := make([]string, len(names))
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		val, err := toSqlite(values[i], colSchemas[abstract.ColumnName(name)])
+		if err != nil {
+			return "", nil, xerrors.Errorf("unable to convert value of column %s: %w", name, err)
+		}
+		conditions[i] = fmt.Sprintf("%s is ?", quoteIdentifier(name))
+		args[i] = val
+	}
+	return strings.Join(conditions, " and "), args, nil
+}
+
+func NewSink(config *SqliteDestination, lgr log.Logger, registry metrics.Registry) (abstract.Sinker, error) {
+	db, err := openDB(config.Path, false)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to open target database: %w", err)
+	}
+	// a single connection avoids lock contention between writers of the same file
+	db.SetMaxOpenConns(1)
+	return &sink{
+		db:      db,
+		config:  config,
+		logger:  lgr,
+		metrics: stats.NewSinkerStats(registry),
+		mu:      sync.Mutex{},
+		created: make(map[string]bool),
+	}, nil
+}
@@ -0,0 +1,100 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+var testTableSchema = abstract.NewTableSchema([]abstract.ColSchema{
+	abstract.NewColSchema("id", schema.TypeInt32, true),
+	abstract.NewColSchema("name", schema.TypeString, false),
+	abstract.NewColSchema("created", schema.TypeTimestamp, false),
+	abstract.NewColSchema("meta", schema.TypeAny, false),
+})
+
+func makeRow(kind abstract.Kind, id int32, name string, oldID int32) abstract.ChangeItem {
+	item := abstract.ChangeItem{
+		Kind:         kind,
+		Schema:       "public",
+		Table:        "users",
+		ColumnNames:  []string{"id", "name", "created", "meta"},
+		ColumnValues: []interface{}{id, name, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), map[string]interface{}{"name": name}},
+		TableSchema:  testTableSchema,
+		OldKeys:      abstract.EmptyOldKeys(),
+	}
+	if kind != abstract.InsertKind {
+		item.OldKeys = abstract.OldKeysType{KeyNames: []string{"id"}, KeyTypes: nil, KeyValues: []interface{}{oldID}}
+	}
+	if kind == abstract.DeleteKind {
+		item.ColumnNames, item.ColumnValues = nil, nil
+	}
+	return item
+}
+
+func TestSink(t *testing.T) {
+	dst := &SqliteDestination{Path: filepath.Join(t.TempDir(), "target.db"), AltNames: nil, Cleanup: ""}
+	dst.WithDefaults()
+	sink, err := NewSink(dst, logger.Log, solomon.NewRegistry(nil))
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		{Kind: abstract.DropTableKind, Schema: "public", Table: "users"},
+		makeRow(abstract.InsertKind, 1, "alice", 0),
+		makeRow(abstract.InsertKind, 2, "bob", 0),
+		makeRow(abstract.InsertKind, 3, "carol", 0),
+	}))
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		makeRow(abstract.InsertKind, 1, "alice-upserted", 0),
+		makeRow(abstract.UpdateKind, 20, "bob-moved", 2),
+		makeRow(abstract.DeleteKind, 0, "", 3),
+		{
+			Kind:         abstract.UpdateKind,
+			Schema:       "public",
+			Table:        "users",
+			ColumnNames:  []string{"id", "name"},
+			ColumnValues: []interface{}{int32(1), "alice-toasted"},
+			TableSchema:  testTableSchema,
+			OldKeys:      abstract.OldKeysType{KeyNames: []string{"id"}, KeyTypes: nil, KeyValues: []interface{}{int32(1)}},
+		},
+	}))
+
+	storage := newTestStorage(t, &SqliteSource{Path: dst.Path})
+	tableSchema, err := storage.TableSchema(context.Background(), abstract.TableID{Namespace: "", Name: "users"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "name", "created", "meta"}, tableSchema.Columns().ColumnNames())
+	require.Equal(t, "sqlite:INT", tableSchema.Columns()[0].OriginalType)
+	require.True(t, tableSchema.Columns()[0].PrimaryKey)
+
+	items := loadAll(t, storage, abstract.TableDescription{Name: "users", Schema: "", Filter: "", EtaRow: 0, Offset: 0})
+	require.Len(t, items, 2)
+	require.Equal(t, []interface{}{int32(1), "alice-toasted", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), map[string]interface{}{"name": "alice-upserted"}}, items[0].ColumnValues)
+	require.Equal(t, []interface{}{int32(20), "bob-moved", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), map[string]interface{}{"name": "bob-moved"}}, items[1].ColumnValues)
+
+	// the table is emptied by truncate only in the truncate cleanup mode
+	dst.Cleanup = model.Truncate
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		{Kind: abstract.DropTableKind, Schema: "public", Table: "users"},
+		{Kind: abstract.TruncateTableKind, Schema: "public", Table: "users"},
+	}))
+	count, err := storage.ExactTableRowsCount(abstract.TableID{Namespace: "", Name: "users"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), count)
+}
+
+func TestTableName(t *testing.T) {
+	dst := &SqliteDestination{Path: "target.db", AltNames: map[string]string{"sales_orders": "orders"}}
+	require.Equal(t, "users", dst.TableName(abstract.TableID{Namespace: "public", Name: "users"}))
+	require.Equal(t, "orders", dst.TableName(abstract.TableID{Namespace: "sales", Name: "orders"}))
+	require.Equal(t, "orders", dst.TableName(abstract.TableID{Namespace: "", Name: "sales_orders"}))
+	require.Equal(t, "crm_users", dst.TableName(abstract.TableID{Namespace: "crm", Name: "users"}))
+}
@@ -0,0 +1,255 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/changeitem"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/stats"
+	"github.com/transferia/transferia/pkg/util"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+var _ abstract.Storage = (*Storage)(nil)
+
+type Storage struct {
+	db      *sql.DB
+	config  *SqliteSource
+	logger  log.Logger
+	metrics *stats.SourceStats
+}
+
+func (s *Storage) Close() {
+	if err := s.db.Close(); err != nil {
+		s.logger.Warn("unable to close database", log.Error(err))
+	}
+}
+
+func (s *Storage) Ping() error {
+	return s.db.Ping()
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (s *Storage) TableSchema(ctx context.Context, table abstract.TableID) (*abstract.TableSchema, error) {
+	return loadTableSchema(ctx, s.db, table.Name)
+}
+
+func loadTableSchema(ctx context.Context, db *sql.DB, table string) (*abstract.TableSchema, error) {
+	rows, err := db.QueryContext(ctx, `select name, type, "notnull", pk from pragma_table_info(?) order by cid`, table)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to select columns of table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns abstract.TableColumns
+	for rows.Next() {
+		var name, typ string
+		var notNull bool
+		var pk int
+		if err := rows.Scan(&name, &typ, &notNull, &pk); err != nil {
+			return nil, xerrors.Errorf("unable to scan column of table %s: %w", table, err)
+		}
+		col := abstract.NewColSchema(name, TypeToYt(typ), pk > 0)
+		col.TableName = table
+		col.Required = notNull
+		col.OriginalType = "sqlite:" + typ
+		columns = append(columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, xerrors.Errorf("unable to read columns of table %s: %w", table, err)
+	}
+	if len(columns) == 0 {
+		return nil, xerrors.Errorf("table %s not found", table)
+	}
+	return abstract.NewTableSchema(columns), nil
+}
+
+func (s *Storage) TableList(filter abstract.IncludeTableList) (abstract.TableMap, error) {
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, `select name, type = 'view' from sqlite_master where type in ('table', 'view') and name not like 'sqlite\_%' escape '\' order by name`)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to select tables: %w", err)
+	}
+	defer rows.Close()
+
+	tables := make(abstract.TableMap)
+	for rows.Next() {
+		var tID abstract.TableID
+		var tInfo abstract.TableInfo
+		if err := rows.Scan(&tID.Name, &tInfo.IsView); err != nil {
+			return nil, xerrors.Errorf("unable to scan table: %w", err)
+		}
+		tables[tID] = tInfo
+	}
+	if err := rows.Err(); err != nil {
+		return nil, xerrors.Errorf("unable to read tables: %w", err)
+	}
+
+	tables = model.FilteredMap(tables, s.config)
+	for tID, tInfo := range tables {
+		tInfo.Schema, err = loadTableSchema(ctx, s.db, tID.Name)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to load schema of table %s: %w", tID.Fqtn(), err)
+		}
+		if tInfo.EtaRow, err = s.ExactTableRowsCount(tID); err != nil {
+			return nil, xerrors.Errorf("unable to count rows of table %s: %w", tID.Fqtn(), err)
+		}
+		tables[tID] = tInfo
+	}
+	return model.FilteredMap(tables, filter), nil
+}
+
+func (s *Storage) LoadTable(ctx context.Context, table abstract.TableDescription, pusher abstract.Pusher) error {
+	st := util.GetTimestampFromContextOrNow(ctx)
+
+	tableSchema, err := s.TableSchema(ctx, table.ID())
+	if err != nil {
+		return xerrors.Errorf("unable to load schema of table %s: %w", table.Fqtn(), err)
+	}
+	columns := tableSchema.Columns()
+	colNames := columns.ColumnNames()
+
+	query := buildSelectQuery(table, colNames)
+	s.logger.Info("Storage read table", log.String("table", table.Fqtn()), log.String("query", query))
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return xerrors.Errorf("unable to select data from table %s: %w", table.Fqtn(), err)
+	}
+	defer rows.Close()
+
+	partID := table.PartID()
+	batch := make([]abstract.ChangeItem, 0, s.config.BatchSize)
+	pushBatch := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		s.metrics.ChangeItems.Add(int64(len(batch)))
+		if err := pusher(batch); err != nil {
+			return xerrors.Errorf("unable to push %d rows of table %s: %w", len(batch), table.Fqtn(), err)
+		}
+		batch = make([]abstract.ChangeItem, 0, s.config.BatchSize)
+		return nil
+	}
+
+	raw := make([]interface{}, len(columns))
+	rawPtrs := make([]interface{}, len(columns))
+	for i := range raw {
+		rawPtrs[i] = &raw[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(rawPtrs...); err != nil {
+			return xerrors.Errorf("unable to scan row of table %s: %w", table.Fqtn(), err)
+		}
+		vals := make([]interface{}, len(columns))
+		for i, col := range columns {
+			if vals[i], err = fromSqlite(raw[i], col); err != nil {
+				return xerrors.Errorf("unable to convert value of column %s of table %s: %w", col.ColumnName, table.Fqtn(), err)
+			}
+		}
+		batch = append(batch, abstract.ChangeItem{
+			ID:               0,
+			LSN:              0,
+			CommitTime:       uint64(st.UnixNano()),
+			Counter:          0,
+			Kind:             abstract.InsertKind,
+			Schema:           table.Schema,
+			Table:            table.Name,
+			PartID:           partID,
+			ColumnNames:      colNames,
+			ColumnValues:     vals,
+			TableSchema:      tableSchema,
+			OldKeys:          abstract.EmptyOldKeys(),
+			Size:             abstract.RawEventSize(util.DeepSizeof(vals)),
+			TxID:             "",
+			Query:            "",
+			QueueMessageMeta: changeitem.QueueMessageMeta{TopicName: "", PartitionNum: 0, Offset: 0, Index: 0},
+		})
+		if len(batch) >= s.config.BatchSize {
+			if err := pushBatch(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return xerrors.Errorf("unable to read rows of table %s: %w", table.Fqtn(), err)
+	}
+	//nolint:descriptiveerrors
+	return pushBatch()
+}
+
+func buildSelectQuery(table abstract.TableDescription, colNames []string) string {
+	quoted := make([]string, len(colNames))
+	for i, name := range colNames {
+		quoted[i] = quoteIdentifier(name)
+	}
+	query := fmt.Sprintf("select %s from %s", strings.Join(quoted, ", "), quoteIdentifier(table.Name))
+	if table.Filter != "" {
+		query += fmt.Sprintf(" where %s", table.Filter)
+	}
+	if table.Offset != 0 {
+		query += fmt.Sprintf(" limit -1 offset %d", table.Offset)
+	}
+	return query
+}
+
+func (s *Storage) ExactTableRowsCount(table abstract.TableID) (uint64, error) {
+	var count uint64
+	if err := s.db.QueryRow(fmt.Sprintf("select count(*) from %s", quoteIdentifier(table.Name))).Scan(&count); err != nil {
+		return 0, xerrors.Errorf("unable to count rows of table %s: %w", table.Fqtn(), err)
+	}
+	return count, nil
+}
+
+func (s *Storage) EstimateTableRowsCount(table abstract.TableID) (uint64, error) {
+	return s.ExactTableRowsCount(table)
+}
+
+func (s *Storage) TableExists(table abstract.TableID) (bool, error) {
+	var exists bool
+	if err := s.db.QueryRow(`select count(*) > 0 from sqlite_master where type in ('table', 'view') and name = ?`, table.Name).Scan(&exists); err != nil {
+		return false, xerrors.Errorf("unable to check table %s existence: %w", table.Fqtn(), err)
+	}
+	return exists, nil
+}
+
+func openDB(path string, readOnly bool) (*sql.DB, error) {
+	dsn := "file:" + path + "?_pragma=busy_timeout(10000)"
+	if readOnly {
+		dsn += "&mode=ro"
+	}
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to open database %s: %w", path, err)
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, xerrors.Errorf("unable to connect to database %s: %w", path, err)
+	}
+	return db, nil
+}
+
+func NewStorage(config *SqliteSource, lgr log.Logger, registry metrics.Registry) (*Storage, error) {
+	db, err := openDB(config.Path, true)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to open source database: %w", err)
+	}
+	start := time.Now()
+	storage := &Storage{
+		db:      db,
+		config:  config,
+		logger:  lgr,
+		metrics: stats.NewSourceStats(registry),
+	}
+	lgr.Info("opened source database", log.String("path", config.Path), log.Duration("elapsed", time.Since(start)))
+	return storage, nil
+}
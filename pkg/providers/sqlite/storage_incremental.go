@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+var _ abstract.IncrementalStorage = (*Storage)(nil)
+
+// GetNextIncrementalState returns the max value of the cursor column of each table as an SQL literal,
+// so it can be put into a filter as is
+func (s *Storage) GetNextIncrementalState(ctx context.Context, incremental []abstract.IncrementalTable) ([]abstract.IncrementalState, error) {
+	var res []abstract.IncrementalState
+	for _, table := range incremental {
+		var maxVal sql.NullString
+		if err := s.db.QueryRowContext(
+			ctx,
+			fmt.Sprintf("select quote(max(%s)) from %s", quoteIdentifier(table.CursorField), quoteIdentifier(table.Name)),
+		).Scan(&maxVal); err != nil {
+			return nil, xerrors.Errorf("unable to get max %s from table %s: %w", table.CursorField, table.TableID().Fqtn(), err)
+		}
+		if !maxVal.Valid || maxVal.String == "NULL" {
+			s.logger.Warn(fmt.Sprintf("unable to get max %s from empty table", table.CursorField), log.String("table", table.TableID().Fqtn()))
+			continue
+		}
+		res = append(res, abstract.IncrementalState{
+			Name:    table.Name,
+			Schema:  table.Namespace,
+			Payload: abstract.WhereStatement(fmt.Sprintf("%s > %s", quoteIdentifier(table.CursorField), maxVal.String)),
+		})
+	}
+	return res, nil
+}
+
+func (s *Storage) BuildArrTableDescriptionWithIncrementalState(tables []abstract.TableDescription, incrementalTables []abstract.IncrementalTable) []abstract.TableDescription {
+	result := slices.Clone(tables)
+	for i, table := range result {
+		if table.Filter != "" || table.Offset != 0 {
+			// table already contains predicate
+			continue
+		}
+		for _, incremental := range incrementalTables {
+			if !incremental.Initialized() {
+				continue
+			}
+			if table.ID() == incremental.TableID() {
+				result[i] = abstract.TableDescription{
+					Name:   incremental.Name,
+					Schema: incremental.Namespace,
+					Filter: abstract.WhereStatement(fmt.Sprintf("%s > %s", quoteIdentifier(incremental.CursorField), incremental.InitialState)),
+					EtaRow: 0,
+					Offset: 0,
+				}
+			}
+		}
+	}
+	return result
+}
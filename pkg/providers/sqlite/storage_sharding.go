@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/transferia/transferia/pkg/abstract"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+var _ abstract.ShardingStorage = (*Storage)(nil)
+
+// ShardTable splits a table into parts by equal ranges of rowid,
+// tables created WITHOUT ROWID and views are loaded as a whole
+func (s *Storage) ShardTable(ctx context.Context, table abstract.TableDescription) ([]abstract.TableDescription, error) {
+	if table.Filter != "" || table.Offset != 0 {
+		s.logger.Infof("Table %v will not be sharded, filter: [%v], offset: %v", table.Fqtn(), table.Filter, table.Offset)
+		return []abstract.TableDescription{table}, nil
+	}
+
+	var minRowID, maxRowID, count int64
+	if err := s.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf("select coalesce(min(_rowid_), 0), coalesce(max(_rowid_), 0), count(*) from %s", quoteIdentifier(table.Name)),
+	).Scan(&minRowID, &maxRowID, &count); err != nil {
+		s.logger.Warn("Table has no rowid and will not be sharded", log.String("table", table.Fqtn()), log.Error(err))
+		return []abstract.TableDescription{table}, nil
+	}
+	if uint64(count) <= s.config.DesiredPartRows {
+		return []abstract.TableDescription{table}, nil
+	}
+
+	partsCount := (uint64(count) + s.config.DesiredPartRows - 1) / s.config.DesiredPartRows
+	step := (maxRowID - minRowID + 1) / int64(partsCount)
+	if step == 0 {
+		return []abstract.TableDescription{table}, nil
+	}
+
+	res := make([]abstract.TableDescription, 0, partsCount)
+	for i := int64(0); i < int64(partsCount); i++ {
+		var filter string
+		lower, upper := minRowID+i*step, minRowID+(i+1)*step
+		switch {
+		case i == 0:
+			filter = fmt.Sprintf("_rowid_ < %d", upper)
+		case i == int64(partsCount)-1:
+			filter = fmt.Sprintf("_rowid_ >= %d", lower)
+		default:
+			filter = fmt.Sprintf("_rowid_ >= %d and _rowid_ < %d", lower, upper)
+		}
+		res = append(res, abstract.TableDescription{
+			Name:   table.Name,
+			Schema: table.Schema,
+			Filter: abstract.WhereStatement(filter),
+			EtaRow: uint64(count) / partsCount,
+			Offset: 0,
+		})
+	}
+	s.logger.Infof("Table %v sharded into %v parts by rowid ranges of %v", table.Fqtn(), len(res), step)
+	return res, nil
+}
@@ -0,0 +1,139 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/pkg/abstract"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+// prepareDB creates a database file with the given statements
+func prepareDB(t *testing.T, statements ...string) string {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open(driverName, path)
+	require.NoError(t, err)
+	defer db.Close()
+	for _, stmt := range statements {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err)
+	}
+	return path
+}
+
+func newTestStorage(t *testing.T, src *SqliteSource) *Storage {
+	src.WithDefaults()
+	storage, err := NewStorage(src, logger.Log, solomon.NewRegistry(nil))
+	require.NoError(t, err)
+	t.Cleanup(storage.Close)
+	return storage
+}
+
+func loadAll(t *testing.T, storage *Storage, table abstract.TableDescription) []abstract.ChangeItem {
+	var result []abstract.ChangeItem
+	require.NoError(t, storage.LoadTable(context.Background(), table, func(items []abstract.ChangeItem) error {
+		result = append(result, items...)
+		return nil
+	}))
+	return result
+}
+
+func TestStorage(t *testing.T) {
+	path := prepareDB(t,
+		`create table users (id integer primary key, name varchar(32) not null, score real, data blob, created datetime, meta json)`,
+		`create table skipped (id integer)`,
+		`create view named as select name from users`,
+		`insert into users values (1, 'alice', 1.5, x'0102', '2024-01-02 03:04:05', '{"a":1}')`,
+		`insert into users values (2, 'bob', null, null, null, null)`,
+	)
+	storage := newTestStorage(t, &SqliteSource{Path: path, ExcludeTables: []string{"skipped"}})
+	require.NoError(t, storage.Ping())
+
+	tables, err := storage.TableList(nil)
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+	require.Equal(t, uint64(2), tables[abstract.TableID{Namespace: "", Name: "users"}].EtaRow)
+	require.True(t, tables[abstract.TableID{Namespace: "", Name: "named"}].IsView)
+
+	tableSchema, err := storage.TableSchema(context.Background(), abstract.TableID{Namespace: "", Name: "users"})
+	require.NoError(t, err)
+	columns := tableSchema.Columns()
+	require.Equal(t, []string{"id", "name", "score", "data", "created", "meta"}, columns.ColumnNames())
+	require.True(t, columns[0].PrimaryKey)
+	require.Equal(t, "sqlite:varchar(32)", columns[1].OriginalType)
+	require.True(t, columns[1].Required)
+	require.Equal(t, schema.TypeTimestamp.String(), columns[4].DataType)
+
+	items := loadAll(t, storage, abstract.TableDescription{Name: "users", Schema: "", Filter: "", EtaRow: 0, Offset: 0})
+	require.Len(t, items, 2)
+	require.Equal(t, int64(1), items[0].ColumnValues[0])
+	require.Equal(t, "alice", items[0].ColumnValues[1])
+	require.Equal(t, 1.5, items[0].ColumnValues[2])
+	require.Equal(t, []byte{1, 2}, items[0].ColumnValues[3])
+	require.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), items[0].ColumnValues[4])
+	require.Equal(t, map[string]interface{}{"a": float64(1)}, items[0].ColumnValues[5])
+	require.Equal(t, []interface{}{int64(2), "bob", nil, nil, nil, nil}, items[1].ColumnValues)
+
+	exists, err := storage.TableExists(abstract.TableID{Namespace: "", Name: "missing"})
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+func TestShardTable(t *testing.T) {
+	statements := []string{`create table items (id integer primary key, val text)`}
+	for i := 1; i <= 100; i++ {
+		statements = append(statements, fmt.Sprintf(`insert into items values (%d, 'v%d')`, i, i))
+	}
+	path := prepareDB(t, append(statements, `create table keyed (k text primary key) without rowid`, `insert into keyed values ('a'), ('b')`)...)
+	storage := newTestStorage(t, &SqliteSource{Path: path, DesiredPartRows: 30})
+
+	parts, err := storage.ShardTable(context.Background(), abstract.TableDescription{Name: "items", Schema: "", Filter: "", EtaRow: 0, Offset: 0})
+	require.NoError(t, err)
+	require.Len(t, parts, 4)
+	ids := map[int64]bool{}
+	for _, part := range parts {
+		for _, item := range loadAll(t, storage, part) {
+			id := item.ColumnValues[0].(int64)
+			require.False(t, ids[id], "row %d is loaded twice", id)
+			ids[id] = true
+		}
+	}
+	require.Len(t, ids, 100)
+
+	parts, err = storage.ShardTable(context.Background(), abstract.TableDescription{Name: "keyed", Schema: "", Filter: "", EtaRow: 0, Offset: 0})
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+}
+
+func TestIncrementalState(t *testing.T) {
+	path := prepareDB(t,
+		`create table events (id integer primary key, updated text)`,
+		`create table empty (id integer primary key)`,
+		`insert into events values (1, '2024-01-01'), (2, '2024-02-01'), (3, '2024-03-01')`,
+	)
+	storage := newTestStorage(t, &SqliteSource{Path: path})
+
+	incremental := []abstract.IncrementalTable{
+		{Name: "events", Namespace: "", CursorField: "updated", InitialState: ""},
+		{Name: "empty", Namespace: "", CursorField: "id", InitialState: ""},
+	}
+	states, err := storage.GetNextIncrementalState(context.Background(), incremental)
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+	require.Equal(t, abstract.WhereStatement(`"updated" > '2024-03-01'`), states[0].Payload)
+
+	incremental[0].InitialState = "'2024-01-15'"
+	tables := storage.BuildArrTableDescriptionWithIncrementalState(
+		[]abstract.TableDescription{{Name: "events", Schema: "", Filter: "", EtaRow: 0, Offset: 0}},
+		incremental,
+	)
+	require.Equal(t, abstract.WhereStatement(`"updated" > '2024-01-15'`), tables[0].Filter)
+	require.Len(t, loadAll(t, storage, tables[0]), 2)
+}
@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/typesystem"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+func init() {
+	typesystem.SourceRules(ProviderType, map[schema.Type][]string{
+		schema.TypeInt64:   {"INTEGER", "BIGINT", "INT8", "UNSIGNED BIG INT"},
+		schema.TypeInt32:   {"INT", "MEDIUMINT"},
+		schema.TypeInt16:   {"SMALLINT", "INT2"},
+		schema.TypeInt8:    {"TINYINT"},
+		schema.TypeUint64:  {},
+		schema.TypeUint32:  {},
+		schema.TypeUint16:  {},
+		schema.TypeUint8:   {},
+		schema.TypeFloat32: {},
+		schema.TypeFloat64: {"REAL", "DOUBLE", "DOUBLE PRECISION", "FLOAT", "NUMERIC", "DECIMAL", typesystem.RestPlaceholder},
+		schema.TypeBytes:   {"BLOB"},
+		schema.TypeString: {
+			"TEXT", "CLOB", "CHARACTER", "VARCHAR", "VARYING CHARACTER", "NCHAR", "NATIVE CHARACTER", "NVARCHAR",
+		},
+		schema.TypeBoolean:   {"BOOLEAN"},
+		schema.TypeDate:      {"DATE"},
+		schema.TypeDatetime:  {},
+		schema.TypeTimestamp: {"DATETIME", "TIMESTAMP"},
+		schema.TypeAny:       {"JSON"},
+	})
+	typesystem.TargetRule(ProviderType, map[schema.Type]string{
+		schema.TypeInt64:     "BIGINT",
+		schema.TypeInt32:     "INT",
+		schema.TypeInt16:     "SMALLINT",
+		schema.TypeInt8:      "TINYINT",
+		schema.TypeUint64:    "BIGINT",
+		schema.TypeUint32:    "BIGINT",
+		schema.TypeUint16:    "INT",
+		schema.TypeUint8:     "SMALLINT",
+		schema.TypeFloat32:   "REAL",
+		schema.TypeFloat64:   "DOUBLE",
+		schema.TypeBytes:     "BLOB",
+		schema.TypeString:    "TEXT",
+		schema.TypeBoolean:   "BOOLEAN",
+		schema.TypeAny:       "JSON",
+		schema.TypeDate:      "DATE",
+		schema.TypeDatetime:  "DATETIME",
+		schema.TypeTimestamp: "TIMESTAMP",
+	})
+}
+
+var typeParamsRe = regexp.MustCompile(`\s*\(.*\)`)
+
+// ClearDeclaredType returns the declared type of a column without parameters, e.g. `VARCHAR` for `varchar(255)`
+func ClearDeclaredType(declared string) string {
+	return strings.TrimSpace(typeParamsRe.ReplaceAllString(strings.ToUpper(declared), ""))
+}
+
+// TypeToYt maps the declared type of a column to the transfer type.
+// The types which are not listed in the rules are resolved according to the SQLite type affinity rules,
+// see https://www.sqlite.org/datatype3.html#determination_of_column_affinity
+func TypeToYt(declared string) schema.Type {
+	typ := ClearDeclaredType(declared)
+	if ytType, ok := typesystem.RuleFor(ProviderType).Source[typ]; ok {
+		return ytType
+	}
+	switch {
+	case typ == "":
+		return schema.TypeAny
+	case strings.Contains(typ, "INT"):
+		return schema.TypeInt64
+	case strings.Contains(typ, "CHAR"), strings.Contains(typ, "CLOB"), strings.Contains(typ, "TEXT"):
+		return schema.TypeString
+	case strings.Contains(typ, "BLOB"):
+		return schema.TypeBytes
+	default:
+		return schema.TypeFloat64
+	}
+}
+
+// ColumnType returns the type of a column created for the given column schema
+func ColumnType(col abstract.ColSchema) string {
+	if strings.HasPrefix(col.OriginalType, "sqlite:") {
+		return strings.TrimPrefix(col.OriginalType, "sqlite:")
+	}
+	if typ, ok := typesystem.RuleFor(ProviderType).Target[schema.Type(col.DataType)]; ok {
+		return typ
+	}
+	return "BLOB"
+}
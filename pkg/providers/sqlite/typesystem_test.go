@@ -0,0 +1,35 @@
+package sqlite
+
+import (
+	_ "embed"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/abstract/typesystem"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+var (
+	//go:embed typesystem.md
+	canonDoc string
+)
+
+func TestTypeSystem(t *testing.T) {
+	rules := typesystem.RuleFor(ProviderType)
+	require.NotNil(t, rules.Source)
+	require.NotNil(t, rules.Target)
+	doc := typesystem.Doc(ProviderType, "SQLite")
+	fmt.Print(doc)
+	require.Equal(t, canonDoc, doc)
+}
+
+func TestTypeToYt(t *testing.T) {
+	require.Equal(t, schema.TypeInt64, TypeToYt("integer"))
+	require.Equal(t, schema.TypeString, TypeToYt("varchar(255)"))
+	require.Equal(t, schema.TypeFloat64, TypeToYt("decimal(10, 2)"))
+	require.Equal(t, schema.TypeInt64, TypeToYt("UNSIGNED INTEGER"))
+	require.Equal(t, schema.TypeString, TypeToYt("LONGTEXT"))
+	require.Equal(t, schema.TypeBytes, TypeToYt("MEDIUMBLOB"))
+	require.Equal(t, schema.TypeAny, TypeToYt(""))
+}
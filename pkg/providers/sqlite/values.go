@@ -0,0 +1,186 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+// timeLayouts are the layouts of time values stored as text, the first one is used by the driver to store time.Time
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSuffix(s, "Z")
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, xerrors.Errorf("unable to parse time %q", s)
+}
+
+// fromSqlite converts a value read from a column of SQLite dynamic type into the value of the column data type
+func fromSqlite(val interface{}, col abstract.ColSchema) (interface{}, error) {
+	if val == nil {
+		return nil, nil
+	}
+	switch schema.Type(col.DataType) {
+	case schema.TypeInt64, schema.TypeInt32, schema.TypeInt16, schema.TypeInt8:
+		var i int64
+		switch v := val.(type) {
+		case int64:
+			i = v
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, xerrors.Errorf("unable to convert non-integer %v into %s", v, col.DataType)
+			}
+			i = int64(v)
+		case string:
+			parsed, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to parse %q as %s: %w", v, col.DataType, err)
+			}
+			i = parsed
+		default:
+			return nil, xerrors.Errorf("unexpected value of type %T for %s", val, col.DataType)
+		}
+		switch schema.Type(col.DataType) {
+		case schema.TypeInt32:
+			return int32(i), nil
+		case schema.TypeInt16:
+			return int16(i), nil
+		case schema.TypeInt8:
+			return int8(i), nil
+		default:
+			return i, nil
+		}
+	case schema.TypeFloat64:
+		switch v := val.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case string:
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to parse %q as %s: %w", v, col.DataType, err)
+			}
+			return parsed, nil
+		}
+	case schema.TypeBoolean:
+		switch v := val.(type) {
+		case int64:
+			return v != 0, nil
+		case float64:
+			return v != 0, nil
+		case string:
+			parsed, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to parse %q as %s: %w", v, col.DataType, err)
+			}
+			return parsed, nil
+		}
+	case schema.TypeString:
+		switch v := val.(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		case int64, float64:
+			return fmt.Sprint(v), nil
+		}
+	case schema.TypeBytes:
+		switch v := val.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		case int64, float64:
+			return []byte(fmt.Sprint(v)), nil
+		}
+	case schema.TypeDate, schema.TypeTimestamp:
+		switch v := val.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			return parseTime(v)
+		case int64:
+			return time.Unix(v, 0).UTC(), nil
+		}
+	case schema.TypeAny:
+		switch v := val.(type) {
+		case string:
+			var res interface{}
+			if ClearDeclaredType(strings.TrimPrefix(col.OriginalType, "sqlite:")) == "JSON" && json.Unmarshal([]byte(v), &res) == nil {
+				return res, nil
+			}
+			return v, nil
+		default:
+			return v, nil
+		}
+	}
+	return nil, xerrors.Errorf("unexpected value of type %T for %s", val, col.DataType)
+}
+
+// toSqlite converts a value of the column data type into the value which can be written by the driver
+func toSqlite(val interface{}, col abstract.ColSchema) (interface{}, error) {
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case string, []byte, int64, float64, bool:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case uint:
+		return toSqlite(uint64(v), col)
+	case uint32:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return strconv.FormatUint(v, 10), nil
+		}
+		return int64(v), nil
+	case float32:
+		return float64(v), nil
+	case json.Number:
+		return v.String(), nil
+	case time.Time:
+		if schema.Type(col.DataType) == schema.TypeDate {
+			return v.Format("2006-01-02"), nil
+		}
+		return v, nil
+	case time.Duration:
+		return int64(v), nil
+	default:
+		res, err := json.Marshal(v)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to marshal value of type %T: %w", val, err)
+		}
+		return string(res), nil
+	}
+}
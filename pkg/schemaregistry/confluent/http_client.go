@@ -70,6 +70,14 @@ type schemaResponse struct {
 	References []schemaReference `json:"references"`
 }
 
+// schemaTypeOrDefault - schema registry omits 'schemaType' field for avro schemas, bcs avro is the default type
+func (r *schemaResponse) schemaTypeOrDefault() SchemaType {
+	if r.SchemaType == "" {
+		return AVRO
+	}
+	return r.SchemaType
+}
+
 func schemaResponseReferencesToSchemaReference(in []schemaReference) []SchemaReference {
 	result := make([]SchemaReference, 0, len(in))
 	for _, el := range in {
@@ -185,7 +193,7 @@ func (c *SchemaRegistryClient) GetSchema(schemaID int) (*Schema, error) {
 	var schema = &Schema{
 		ID:         schemaID,
 		Schema:     schemaResp.Schema,
-		SchemaType: schemaResp.SchemaType,
+		SchemaType: schemaResp.schemaTypeOrDefault(),
 		References: schemaResponseReferencesToSchemaReference(schemaResp.References),
 	}
 
@@ -211,7 +219,7 @@ func (c *SchemaRegistryClient) ResolveReferencesRecursive(references []SchemaRef
 			return nil, xerrors.Errorf("unable to GetSchemaBySubjectVersion - err: %w", err)
 		}
 		for _, ref := range schema.References {
-			referencedSchemas.addTask(referenceName, ref.SubjectName, ref.Version)
+			referencedSchemas.addTask(ref.Name, ref.SubjectName, ref.Version)
 		}
 		err = referencedSchemas.doneTask(referenceName, subject, version, *schema)
 		if err != nil {
@@ -247,7 +255,7 @@ func (c *SchemaRegistryClient) GetSchemaBySubjectVersion(subject string, version
 	var schema = &Schema{
 		ID:         -1,
 		Schema:     schemaResp.Schema,
-		SchemaType: schemaResp.SchemaType,
+		SchemaType: schemaResp.schemaTypeOrDefault(),
 		References: schemaResponseReferencesToSchemaReference(schemaResp.References),
 	}
 
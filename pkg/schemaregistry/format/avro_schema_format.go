@@ -0,0 +1,421 @@
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/avro"
+)
+
+// Conversion between kafka-connect schemas and avro schemas, compatible with confluent AvroConverter:
+//   - struct becomes record, unnamed structs get names 'io.confluent.connect.avro.ConnectDefaultN'
+//   - optional field becomes union ["null", T] with default null
+//   - int8 & int16 become int with 'connect.type' attribute
+//   - org.apache.kafka.connect.data.Decimal becomes bytes with logical type decimal
+//   - name, version, parameters & default are kept in 'connect.*' attributes
+//   - names, which are not valid for avro, are sanitized - original field name is kept in field aliases
+
+const (
+	avroConnectDefaultNamespace = "io.confluent.connect.avro"
+	avroConnectDefaultName      = "ConnectDefault"
+
+	avroPropConnectName       = "connect.name"
+	avroPropConnectVersion    = "connect.version"
+	avroPropConnectDoc        = "connect.doc"
+	avroPropConnectParameters = "connect.parameters"
+	avroPropConnectType       = "connect.type"
+	avroPropConnectDefault    = "connect.default"
+	avroPropDtOriginalTypes   = "__dt_original_type_info"
+
+	avroDefaultDecimalPrecision = 64 // the same default, as confluent AvroConverter has
+
+	connectDecimalName   = "org.apache.kafka.connect.data.Decimal"
+	connectDateName      = "org.apache.kafka.connect.data.Date"
+	connectTimeName      = "org.apache.kafka.connect.data.Time"
+	connectTimestampName = "org.apache.kafka.connect.data.Timestamp"
+)
+
+type kafkaToAvroConverter struct {
+	definedNames       map[string]bool
+	connectDefaultsNum int
+}
+
+// ToAvroSchema converts kafka-connect schema into avro schema, ready to be marshalled into json
+func (p KafkaJSONSchema) ToAvroSchema() (any, error) {
+	converter := &kafkaToAvroConverter{
+		definedNames:       make(map[string]bool),
+		connectDefaultsNum: 0,
+	}
+	result, err := converter.convert(p)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to convert kafka schema into avro: %w", err)
+	}
+	return result, nil
+}
+
+func (c *kafkaToAvroConverter) convert(p KafkaJSONSchema) (any, error) {
+	result, err := c.convertNotOptional(p)
+	if err != nil {
+		return nil, err
+	}
+	if p.Optional {
+		return []any{string(avro.TypeNull), result}, nil
+	}
+	return result, nil
+}
+
+func (c *kafkaToAvroConverter) convertNotOptional(p KafkaJSONSchema) (any, error) {
+	switch p.Type {
+	case structType:
+		return c.convertStruct(p)
+	case arrayType:
+		if p.Items == nil {
+			return nil, xerrors.Errorf("array %s doesn't have items schema", p.Field)
+		}
+		items, err := c.convert(*p.Items)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to convert items of array %s: %w", p.Field, err)
+		}
+		result := map[string]any{"type": string(avro.TypeArray), "items": items}
+		addConnectProps(result, p)
+		return result, nil
+	}
+
+	avroType, ok := kafkaTypeToAvro(p.Type)
+	if !ok {
+		return nil, xerrors.Errorf("unsupported kafka type %s of field %s", p.Type, p.Field)
+	}
+	result := map[string]any{"type": string(avroType)}
+	if p.Type == int8Type || p.Type == int16Type {
+		result[avroPropConnectType] = p.Type
+	}
+	switch {
+	case p.Name == connectDecimalName && avroType == avro.TypeBytes:
+		precision := avroDefaultDecimalPrecision
+		scale := 0
+		if p.Parameters != nil {
+			if parsed, err := strconv.Atoi(p.Parameters.ConnectDecimalPrecision); err == nil {
+				precision = parsed
+			}
+			if parsed, err := strconv.Atoi(p.Parameters.Scale); err == nil {
+				scale = parsed
+			}
+		}
+		result["logicalType"] = string(avro.LogicalTypeDecimal)
+		result["precision"] = precision
+		result["scale"] = scale
+	case p.Name == connectDateName && avroType == avro.TypeInt:
+		result["logicalType"] = string(avro.LogicalTypeDate)
+	case p.Name == connectTimeName && avroType == avro.TypeInt:
+		result["logicalType"] = string(avro.LogicalTypeTimeMillis)
+	case p.Name == connectTimestampName && avroType == avro.TypeLong:
+		result["logicalType"] = string(avro.LogicalTypeTimestampMillis)
+	}
+	addConnectProps(result, p)
+	if len(result) == 1 {
+		return string(avroType), nil // just primitive type name
+	}
+	return result, nil
+}
+
+func (c *kafkaToAvroConverter) convertStruct(p KafkaJSONSchema) (any, error) {
+	var name string
+	if p.Name != "" {
+		name = sanitizeAvroFullName(p.Name)
+	} else {
+		c.connectDefaultsNum++
+		name = fmt.Sprintf("%s.%s%d", avroConnectDefaultNamespace, avroConnectDefaultName, c.connectDefaultsNum)
+	}
+	if c.definedNames[name] {
+		// avro doesn't allow to define named type twice, so next occurrences are references
+		return name, nil
+	}
+	c.definedNames[name] = true
+
+	fields := make([]any, 0, len(p.Fields))
+	for _, field := range p.Fields {
+		fieldType, err := c.convert(field)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to convert field %s of struct %s: %w", field.Field, p.Name, err)
+		}
+		avroField := map[string]any{"name": sanitizeAvroName(field.Field), "type": fieldType}
+		if field.Optional {
+			avroField["default"] = nil
+		}
+		if avroField["name"] != field.Field {
+			avroField["aliases"] = []any{field.Field}
+		}
+		fields = append(fields, avroField)
+	}
+	result := map[string]any{"type": string(avro.TypeRecord), "name": name, "fields": fields}
+	addConnectProps(result, p)
+	if p.Name == "" || p.Name == name {
+		delete(result, avroPropConnectName)
+	}
+	return result, nil
+}
+
+func addConnectProps(result map[string]any, p KafkaJSONSchema) {
+	if p.Name != "" {
+		result[avroPropConnectName] = p.Name
+	}
+	if p.Version != 0 {
+		result[avroPropConnectVersion] = p.Version
+	}
+	if p.Doc != "" {
+		result[avroPropConnectDoc] = p.Doc
+	}
+	if p.Parameters != nil {
+		result[avroPropConnectParameters] = p.Parameters
+	}
+	if p.Default != nil {
+		result[avroPropConnectDefault] = p.Default
+	}
+	if p.DtOriginalTypes != nil {
+		result[avroPropDtOriginalTypes] = p.DtOriginalTypes
+	}
+}
+
+func kafkaTypeToAvro(kafkaType string) (avro.Type, bool) {
+	switch kafkaType {
+	case int8Type, int16Type, int32Type:
+		return avro.TypeInt, true
+	case int64Type:
+		return avro.TypeLong, true
+	case floatType:
+		return avro.TypeFloat, true
+	case doubleType:
+		return avro.TypeDouble, true
+	case boolType:
+		return avro.TypeBoolean, true
+	case stringType:
+		return avro.TypeString, true
+	case bytesType:
+		return avro.TypeBytes, true
+	}
+	return "", false
+}
+
+// AvroToKafkaJSONSchema converts avro schema into kafka-connect schema - it's the opposite of KafkaJSONSchema.ToAvroSchema
+// Avro schemas without 'connect.*' attributes are converted as well, as confluent AvroConverter does.
+func AvroToKafkaJSONSchema(schema *avro.Schema) (*KafkaJSONSchema, error) {
+	result, err := avroToKafka(schema, make(map[*avro.Schema]bool))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to convert avro schema into kafka schema: %w", err)
+	}
+	return result, nil
+}
+
+func avroToKafka(schema *avro.Schema, inProgress map[*avro.Schema]bool) (*KafkaJSONSchema, error) {
+	optional := false
+	if schema.Type == avro.TypeUnion {
+		notNullBranches := schema.NotNullBranches()
+		if len(notNullBranches) != 1 {
+			return nil, xerrors.Errorf("only unions with null and one another type are supported, got %d not-null branches", len(notNullBranches))
+		}
+		optional = schema.IsNullable()
+		schema = notNullBranches[0]
+	}
+
+	result := &KafkaJSONSchema{
+		Type:            "",
+		Fields:          nil,
+		Optional:        optional,
+		Name:            stringProp(schema, avroPropConnectName),
+		Version:         0,
+		Doc:             stringProp(schema, avroPropConnectDoc),
+		Parameters:      nil,
+		Default:         schema.Props[avroPropConnectDefault],
+		Items:           nil,
+		Field:           "",
+		DtOriginalTypes: schema.Props[avroPropDtOriginalTypes],
+	}
+	if version, ok := schema.Props[avroPropConnectVersion].(float64); ok {
+		result.Version = int(version)
+	}
+	if rawParameters, ok := schema.Props[avroPropConnectParameters].(map[string]any); ok {
+		result.Parameters = &JSONSchemaParameters{
+			Length:                  stringFromMap(rawParameters, "length"),
+			ConnectDecimalPrecision: stringFromMap(rawParameters, "connect.decimal.precision"),
+			Scale:                   stringFromMap(rawParameters, "scale"),
+			Allowed:                 stringFromMap(rawParameters, "allowed"),
+		}
+	}
+
+	switch schema.Type {
+	case avro.TypeRecord:
+		if inProgress[schema] {
+			return nil, xerrors.Errorf("recursive record %s is not supported", schema.Name)
+		}
+		inProgress[schema] = true
+		defer delete(inProgress, schema)
+
+		result.Type = structType
+		if result.Name == "" && !strings.HasPrefix(schema.Name, avroConnectDefaultNamespace+"."+avroConnectDefaultName) {
+			result.Name = schema.Name
+		}
+		if result.Doc == "" {
+			result.Doc = schema.Doc
+		}
+		for _, field := range schema.Fields {
+			fieldSchema, err := avroToKafka(field.Type, inProgress)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to convert field %s of record %s: %w", field.Name, schema.Name, err)
+			}
+			fieldSchema.Field = avroFieldOriginalName(field)
+			result.Fields = append(result.Fields, *fieldSchema)
+		}
+	case avro.TypeArray:
+		items, err := avroToKafka(schema.Items, inProgress)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to convert array items: %w", err)
+		}
+		result.Type = arrayType
+		result.Items = items
+	case avro.TypeInt:
+		result.Type = int32Type
+		if connectType := stringProp(schema, avroPropConnectType); connectType == int8Type || connectType == int16Type {
+			result.Type = connectType
+		}
+		fillFromLogicalType(result, schema)
+	case avro.TypeLong:
+		result.Type = int64Type
+		fillFromLogicalType(result, schema)
+	case avro.TypeFloat:
+		result.Type = floatType
+	case avro.TypeDouble:
+		result.Type = doubleType
+	case avro.TypeBoolean:
+		result.Type = boolType
+	case avro.TypeString, avro.TypeEnum:
+		result.Type = stringType
+	case avro.TypeBytes, avro.TypeFixed:
+		result.Type = bytesType
+		fillFromLogicalType(result, schema)
+	default:
+		return nil, xerrors.Errorf("unsupported avro type: %s", schema.Type)
+	}
+	return result, nil
+}
+
+// fillFromLogicalType sets kafka-connect logical type for plain avro schemas, which don't have 'connect.name' attribute
+func fillFromLogicalType(result *KafkaJSONSchema, schema *avro.Schema) {
+	if result.Name != "" {
+		return
+	}
+	switch {
+	case schema.LogicalType == avro.LogicalTypeDecimal && (schema.Type == avro.TypeBytes || schema.Type == avro.TypeFixed):
+		result.Name = connectDecimalName
+		result.Version = 1
+		result.Parameters = &JSONSchemaParameters{
+			Length:                  "",
+			ConnectDecimalPrecision: strconv.Itoa(schema.Precision),
+			Scale:                   strconv.Itoa(schema.Scale),
+			Allowed:                 "",
+		}
+	case schema.LogicalType == avro.LogicalTypeDate && schema.Type == avro.TypeInt:
+		result.Name = connectDateName
+		result.Version = 1
+	case schema.LogicalType == avro.LogicalTypeTimeMillis && schema.Type == avro.TypeInt:
+		result.Name = connectTimeName
+		result.Version = 1
+	case schema.LogicalType == avro.LogicalTypeTimestampMillis && schema.Type == avro.TypeLong:
+		result.Name = connectTimestampName
+		result.Version = 1
+	}
+}
+
+func avroFieldOriginalName(field *avro.Field) string {
+	if len(field.Aliases) > 0 {
+		return field.Aliases[0]
+	}
+	return field.Name
+}
+
+func stringProp(schema *avro.Schema, name string) string {
+	result, _ := schema.Props[name].(string)
+	return result
+}
+
+func stringFromMap(in map[string]any, key string) string {
+	val, ok := in[key]
+	if !ok || val == nil {
+		return ""
+	}
+	return fmt.Sprint(val)
+}
+
+// AvroValueToKafkaJSON converts value, decoded by avro.DecodeRaw, into the value of kafka-connect json payload:
+// original field names are restored from aliases, bytes are left as is - json marshaller encodes them into base64, as kafka-connect does
+func AvroValueToKafkaJSON(schema *avro.Schema, val any) any {
+	if val == nil {
+		return nil
+	}
+	if schema.Type == avro.TypeUnion {
+		// decoded value doesn't contain branch index, but only ["null", T] unions are produced by kafka-connect
+		notNullBranches := schema.NotNullBranches()
+		if len(notNullBranches) != 1 {
+			return val
+		}
+		schema = notNullBranches[0]
+	}
+	switch schema.Type {
+	case avro.TypeRecord:
+		record, ok := val.(map[string]any)
+		if !ok {
+			return val
+		}
+		result := make(map[string]any, len(record))
+		for _, field := range schema.Fields {
+			result[avroFieldOriginalName(field)] = AvroValueToKafkaJSON(field.Type, record[field.Name])
+		}
+		return result
+	case avro.TypeArray:
+		arr, ok := val.([]any)
+		if !ok {
+			return val
+		}
+		result := make([]any, len(arr))
+		for i, item := range arr {
+			result[i] = AvroValueToKafkaJSON(schema.Items, item)
+		}
+		return result
+	default:
+		return val
+	}
+}
+
+// sanitizeAvroName replaces all symbols, which are not allowed in avro names, by '_'
+// Avro name must start with [A-Za-z_] and contain only [A-Za-z0-9_]
+func sanitizeAvroName(name string) string {
+	var builder strings.Builder
+	for i, r := range name {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_'
+		isDigit := r >= '0' && r <= '9'
+		switch {
+		case isLetter:
+			builder.WriteRune(r)
+		case isDigit && i == 0:
+			builder.WriteRune('_')
+			builder.WriteRune(r)
+		case isDigit:
+			builder.WriteRune(r)
+		default:
+			builder.WriteRune('_')
+		}
+	}
+	if builder.Len() == 0 {
+		return "_"
+	}
+	return builder.String()
+}
+
+func sanitizeAvroFullName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = sanitizeAvroName(part)
+	}
+	return strings.Join(parts, ".")
+}
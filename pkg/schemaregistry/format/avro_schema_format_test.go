@@ -0,0 +1,80 @@
+package format
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/avro"
+)
+
+func kafkaToAvroToKafka(t *testing.T, kafkaSchema KafkaJSONSchema) (*avro.Schema, *KafkaJSONSchema) {
+	avroSchemaObj, err := kafkaSchema.ToAvroSchema()
+	require.NoError(t, err)
+	rawAvroSchema, err := json.Marshal(avroSchemaObj)
+	require.NoError(t, err)
+	avroSchema, err := avro.ParseSchema(string(rawAvroSchema))
+	require.NoError(t, err)
+	kafkaSchema2, err := AvroToKafkaJSONSchema(avroSchema)
+	require.NoError(t, err)
+	return avroSchema, kafkaSchema2
+}
+
+func TestKafkaToAvroToKafka(t *testing.T) {
+	t.Parallel()
+	for _, rawSchema := range [][]byte{jsonKafkaSchema, jsonKafkaSchemaArrays} {
+		var kafkaSchema KafkaJSONSchema
+		require.NoError(t, json.Unmarshal(rawSchema, &kafkaSchema))
+		avroSchema, kafkaSchema2 := kafkaToAvroToKafka(t, kafkaSchema)
+		require.Equal(t, avro.TypeRecord, avroSchema.Type)
+		require.Equal(t, kafkaSchema, *kafkaSchema2)
+	}
+}
+
+func TestKafkaToAvroNames(t *testing.T) {
+	t.Parallel()
+	kafkaSchema := KafkaJSONSchema{
+		Type: structType,
+		Name: "prefix.public.my-table.Value",
+		Fields: []KafkaJSONSchema{
+			{Type: int16Type, Field: "1st-column"},
+			{Type: bytesType, Name: connectDecimalName, Version: 1, Parameters: &JSONSchemaParameters{Scale: "2", ConnectDecimalPrecision: "10"}, Optional: true, Field: "amount"},
+			{Type: structType, Fields: []KafkaJSONSchema{{Type: int64Type, Field: "id"}}, Field: "transaction", Optional: true},
+		},
+	}
+	avroSchema, kafkaSchema2 := kafkaToAvroToKafka(t, kafkaSchema)
+	require.Equal(t, kafkaSchema, *kafkaSchema2)
+
+	require.Equal(t, "prefix.public.my_table.Value", avroSchema.Name)
+	require.Equal(t, "_1st_column", avroSchema.Fields[0].Name)
+	require.Equal(t, []string{"1st-column"}, avroSchema.Fields[0].Aliases)
+	require.Equal(t, avro.TypeInt, avroSchema.Fields[0].Type.Type)
+
+	amount := avroSchema.Fields[1].Type.NotNullBranches()[0]
+	require.Equal(t, avro.LogicalTypeDecimal, amount.LogicalType)
+	require.Equal(t, 10, amount.Precision)
+	require.Equal(t, 2, amount.Scale)
+	require.Equal(t, "io.confluent.connect.avro.ConnectDefault1", avroSchema.Fields[2].Type.NotNullBranches()[0].Name)
+
+	decoded := map[string]any{"_1st_column": int32(1), "amount": []byte{0x04, 0xd2}, "transaction": nil}
+	require.Equal(t, map[string]any{"1st-column": int32(1), "amount": []byte{0x04, 0xd2}, "transaction": nil}, AvroValueToKafkaJSON(avroSchema, decoded))
+}
+
+func TestPlainAvroToKafka(t *testing.T) {
+	t.Parallel()
+	avroSchema, err := avro.ParseSchema(`{"type": "record", "name": "r", "namespace": "ns", "fields": [
+		{"name": "d", "type": {"type": "int", "logicalType": "date"}},
+		{"name": "n", "type": ["null", {"type": "bytes", "logicalType": "decimal", "precision": 5, "scale": 1}]},
+		{"name": "e", "type": {"type": "enum", "name": "E", "symbols": ["A"]}}
+	]}`)
+	require.NoError(t, err)
+	kafkaSchema, err := AvroToKafkaJSONSchema(avroSchema)
+	require.NoError(t, err)
+	require.Equal(t, "ns.r", kafkaSchema.Name)
+	require.Equal(t, int32Type, kafkaSchema.Fields[0].Type)
+	require.Equal(t, connectDateName, kafkaSchema.Fields[0].Name)
+	require.True(t, kafkaSchema.Fields[1].Optional)
+	require.Equal(t, connectDecimalName, kafkaSchema.Fields[1].Name)
+	require.Equal(t, "1", kafkaSchema.Fields[1].Parameters.Scale)
+	require.Equal(t, stringType, kafkaSchema.Fields[2].Type)
+}
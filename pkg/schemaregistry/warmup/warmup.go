@@ -11,48 +11,55 @@ import (
 	"github.com/transferia/transferia/pkg/parsers"
 	"github.com/transferia/transferia/pkg/schemaregistry/confluent"
 	"github.com/transferia/transferia/pkg/util"
-	"github.com/transferia/transferia/pkg/util/set"
 	"go.ytsaurus.tech/library/go/core/log"
 )
 
 // It's important to warn-up Schema-Registry cache single-thread, to not to DDoS Schema-Registry
 // mutex we need not bcs of something is thread-unsafe, but to reduce schema-registry RPS
 func WarmUpSRCache(logger log.Logger, mutex *sync.Mutex, batch parsers.MessageBatch, schemaRegistryClient *confluent.SchemaRegistryClient, notFoundIsOk bool) {
+	// only json messages are zero-terminated, so only them can be concatenated into one kafka message
+	// for binary formats (protobuf, avro) zero byte can be found in the middle of message - so we extract only first schemaID
 	extractSchemaID := func(buf []byte) (uint32, []byte) {
 		msgLen := len(buf)
-		if len(buf) != 0 {
-			if buf[0] == 0 {
-				zeroIndex := bytes.Index(buf[5:], []byte{0})
-				if zeroIndex != -1 {
-					msgLen = 5 + zeroIndex
-				}
+		if buf[0] == 0 {
+			zeroIndex := bytes.Index(buf[5:], []byte{0})
+			if zeroIndex != -1 {
+				msgLen = 5 + zeroIndex
 			}
 		}
 		return binary.BigEndian.Uint32(buf[1:5]), buf[msgLen:]
 	}
 
-	schemaIDs := set.New[uint32]()
-	var schemaID uint32 = 0
-	for _, currMsg := range batch.Messages {
-		leastBuf := currMsg.Value
-		for {
-			if len(leastBuf) == 0 {
-				break
-			}
-			schemaID, leastBuf = extractSchemaID(leastBuf)
-			schemaIDs.Add(schemaID)
-		}
-	}
-
 	mutex.Lock()
 	defer mutex.Unlock()
-	for _, currSchemaID := range schemaIDs.Slice() {
+
+	isJSONSchema := make(map[uint32]bool)
+	warmUp := func(schemaID uint32) bool {
+		var schema *confluent.Schema
 		_ = backoff.RetryNotify(func() error {
-			_, err := schemaRegistryClient.GetSchema(int(currSchemaID))
+			var err error
+			schema, err = schemaRegistryClient.GetSchema(int(schemaID))
 			if notFoundIsOk && err != nil && strings.Contains(err.Error(), "Error code: 404") {
 				return nil
 			}
 			return err
 		}, backoff.NewConstantBackOff(time.Second), util.BackoffLogger(logger, "getting schema (warm-up cache)"))
+		isJSONSchema[schemaID] = schema != nil && schema.SchemaType == confluent.JSON
+		return isJSONSchema[schemaID]
+	}
+
+	for _, currMsg := range batch.Messages {
+		leastBuf := currMsg.Value
+		for len(leastBuf) >= 5 {
+			var schemaID uint32
+			schemaID, leastBuf = extractSchemaID(leastBuf)
+			isJSON, ok := isJSONSchema[schemaID]
+			if !ok {
+				isJSON = warmUp(schemaID)
+			}
+			if !isJSON {
+				break
+			}
+		}
 	}
 }
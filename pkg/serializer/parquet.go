@@ -13,12 +13,14 @@ type parquetStreamSerializer struct {
 	schema      *parquet.Schema
 	writer      *parquet.GenericWriter[struct{}]
 	tableSchema abstract.FastTableSchema
+	options     []parquet.WriterOption
 }
 
 // works via stream serializer
 type parquetBatchSerializer struct {
 	schema      *parquet.Schema
 	tableSchema abstract.FastTableSchema
+	options     []parquet.WriterOption
 }
 
 func (s *parquetBatchSerializer) Serialize(items []*abstract.ChangeItem) ([]byte, error) {
@@ -31,7 +33,7 @@ func (s *parquetBatchSerializer) Serialize(items []*abstract.ChangeItem) ([]byte
 		s.schema = parquetSchema
 		s.tableSchema = items[0].TableSchema.FastColumns()
 	}
-	streamSerializer, err := NewParquetStreamSerializer(buffer, s.schema, s.tableSchema)
+	streamSerializer, err := NewParquetStreamSerializer(buffer, s.schema, s.tableSchema, s.options...)
 	if err != nil {
 		return nil, xerrors.Errorf("ParquetBatchSerialize: unable to build underlying stream serializer: %w", err)
 	}
@@ -49,7 +51,7 @@ func (s *parquetStreamSerializer) SetStream(ostream io.Writer) error {
 		return xerrors.Errorf("parquetStreamSerializer: failed to close sink: %w", err)
 	}
 
-	s.writer = parquet.NewGenericWriter[struct{}](ostream, s.schema)
+	s.writer = parquet.NewGenericWriter[struct{}](ostream, append([]parquet.WriterOption{s.schema}, s.options...)...)
 
 	return nil
 }
@@ -99,11 +101,13 @@ func (s *parquetStreamSerializer) Close() (err error) {
 	return err
 }
 
-func NewParquetStreamSerializer(ostream io.Writer, schema *parquet.Schema, tableSchema abstract.FastTableSchema) (*parquetStreamSerializer, error) {
+// NewParquetStreamSerializer writes parquet file with the given schema, options are passed to the writer, for example page compression
+func NewParquetStreamSerializer(ostream io.Writer, schema *parquet.Schema, tableSchema abstract.FastTableSchema, options ...parquet.WriterOption) (*parquetStreamSerializer, error) {
 	pqSerializer := parquetStreamSerializer{
 		schema:      schema,
 		writer:      nil,
 		tableSchema: tableSchema,
+		options:     options,
 	}
 
 	err := pqSerializer.SetStream(ostream)
@@ -114,9 +118,10 @@ func NewParquetStreamSerializer(ostream io.Writer, schema *parquet.Schema, table
 	return &pqSerializer, nil
 }
 
-func NewParquetBatchSerializer() *parquetBatchSerializer {
+func NewParquetBatchSerializer(options ...parquet.WriterOption) *parquetBatchSerializer {
 	return &parquetBatchSerializer{
 		schema:      nil,
 		tableSchema: nil,
+		options:     options,
 	}
 }
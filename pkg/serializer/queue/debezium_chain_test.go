@@ -1,6 +1,7 @@
 package queue
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
@@ -101,3 +102,47 @@ func TestBatchingWithSR(t *testing.T) {
 	require.Equal(t, 2, try(t, schemaRegistryMock, 0))
 	require.Equal(t, 1, try(t, schemaRegistryMock, 1048576))
 }
+
+func TestAvroWithSR(t *testing.T) {
+	schemaRegistryMock := confluentsrmock.NewConfluentSRMock(nil, nil)
+	defer schemaRegistryMock.Close()
+
+	changeItem := getUpdateChangeItem()
+	changeItem.Table = "my-coll"
+	changeItem.ColumnNames = append(changeItem.ColumnNames, "name", "price", "created_at")
+	changeItem.ColumnValues = append(changeItem.ColumnValues, "John", json.Number("12.34"), time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC))
+	changeItem.TableSchema = abstract.NewTableSchema(append(changeItem.TableSchema.Columns(),
+		abstract.ColSchema{ColumnName: "name", DataType: ytschema.TypeString.String(), OriginalType: "pg:text"},
+		abstract.ColSchema{ColumnName: "price", DataType: ytschema.TypeString.String(), OriginalType: "pg:numeric(10,2)"},
+		abstract.ColSchema{ColumnName: "created_at", DataType: ytschema.TypeTimestamp.String(), OriginalType: "pg:timestamp without time zone"},
+	))
+
+	paramsMap := map[string]string{
+		debeziumparameters.DatabaseDBName:                  "public",
+		debeziumparameters.TopicPrefix:                     "my_topic",
+		debeziumparameters.SourceType:                      "pg",
+		debeziumparameters.KeyConverter:                    debeziumparameters.ConverterConfluentAvro,
+		debeziumparameters.KeyConverterSchemaRegistryURL:   schemaRegistryMock.URL(),
+		debeziumparameters.ValueConverter:                  debeziumparameters.ConverterConfluentAvro,
+		debeziumparameters.ValueConverterSchemaRegistryURL: schemaRegistryMock.URL(),
+		debeziumparameters.AddOriginalTypes:                debeziumparameters.BoolTrue,
+	}
+	recoveredChangeItems, dbzMsgCount := makeChainConversionWithSR(t, []abstract.ChangeItem{changeItem}, paramsMap)
+	require.Equal(t, 1, dbzMsgCount)
+	require.Len(t, recoveredChangeItems, 1)
+	abstract.Dump(recoveredChangeItems)
+
+	recovered := recoveredChangeItems[0]
+	require.Equal(t, abstract.UpdateKind, recovered.Kind)
+	require.Equal(t, "my-coll", recovered.Table)
+	require.Equal(t, []string{"id", "val", "name", "price", "created_at"}, recovered.ColumnNames)
+	require.Equal(t, int32(1), recovered.ColumnValues[0])
+	require.Equal(t, "John", recovered.ColumnValues[2])
+	require.Equal(t, json.Number("12.34"), recovered.ColumnValues[3])
+	require.Equal(t, time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC), recovered.ColumnValues[4])
+
+	// avro messages are not self-delimited, so batching is forbidden
+	paramsMap[debeziumparameters.BatchingMaxSize] = "1048576"
+	_, err := NewDebeziumSerializer(paramsMap, true, true, false, logger.Log)
+	require.Error(t, err)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -64,10 +65,11 @@ func TestSerialize(t *testing.T) {
 			data, err := test.Serializer.Serialize(&item)
 			require.NoError(t, err)
 
-			err = os.WriteFile("result", data, 0644)
+			result := filepath.Join(t.TempDir(), "result")
+			err = os.WriteFile(result, data, 0644)
 			require.NoError(t, err)
 
-			canon.SaveFile(t, "result", canon.WithLocal(true))
+			canon.SaveFile(t, result, canon.WithLocal(true))
 		})
 	}
 }
@@ -130,10 +132,11 @@ func TestBatchSerializer(t *testing.T) {
 			data, err := test.Serializer.Serialize(items)
 			require.NoError(t, err)
 
-			err = os.WriteFile("result", data, 0644)
+			result := filepath.Join(t.TempDir(), "result")
+			err = os.WriteFile(result, data, 0644)
 			require.NoError(t, err)
 
-			canon.SaveFile(t, "result", canon.WithLocal(true))
+			canon.SaveFile(t, result, canon.WithLocal(true))
 		})
 	}
 }
@@ -214,10 +217,11 @@ func TestStreamSerializer(t *testing.T) {
 			err = s.Close()
 			require.NoError(t, err)
 
-			err = os.WriteFile("result", buf.Bytes(), 0644)
+			result := filepath.Join(t.TempDir(), "result")
+			err = os.WriteFile(result, buf.Bytes(), 0644)
 			require.NoError(t, err)
 
-			canon.SaveFile(t, "result", canon.WithLocal(true))
+			canon.SaveFile(t, result, canon.WithLocal(true))
 		})
 	}
 }
@@ -2,6 +2,7 @@ package serverutil
 
 import (
 	"bytes"
+	"errors"
 	"net"
 	"net/http"
 	"net/http/pprof"
@@ -40,6 +41,21 @@ func (s *Server) Serve() error {
 	return http.Serve(s.listener, mux)
 }
 
+// ServeHandler serves the handler on the listener of the server, over TLS if the certificate & the key files are set.
+// It returns nil once the server is closed
+func (s *Server) ServeHandler(handler http.Handler, certFile, keyFile string) error {
+	var err error
+	if certFile != "" {
+		err = http.ServeTLS(s.listener, handler, certFile, keyFile)
+	} else {
+		err = http.Serve(s.listener, handler)
+	}
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
 func (s *Server) Close() error {
 	return s.listener.Close()
 }
@@ -0,0 +1,95 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/transferia/transferia/library/go/core/metrics"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/coordinator"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/deadletter"
+	"github.com/transferia/transferia/pkg/middlewares"
+	"github.com/transferia/transferia/pkg/transformer"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+// deadLetterConfig returns the config of the dead letter destination, or nil if errors are not written to a destination
+func deadLetterConfig(transfer *model.Transfer) (*deadletter.Config, error) {
+	if transfer.Transformation == nil || transfer.Transformation.Transformers == nil {
+		return nil, nil
+	}
+	errorsOutput := transfer.Transformation.Transformers.ErrorsOutput
+	if errorsOutput == nil || errorsOutput.Type != transformer.DestinationErrorsOutput {
+		return nil, nil
+	}
+	cfg, err := deadletter.ParseConfig(errorsOutput.Config)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid errors output config: %w", err)
+	}
+	return cfg, nil
+}
+
+// DeadLetterDestination builds the dead letter destination from the errors output config
+func DeadLetterDestination(cfg *deadletter.Config) (model.Destination, error) {
+	if dst, ok := cfg.Params.(model.Destination); ok {
+		return dst, nil
+	}
+	params, ok := cfg.Params.(string)
+	if !ok {
+		raw, err := json.Marshal(cfg.Params)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to marshal params: %w", err)
+		}
+		params = string(raw)
+	}
+	dst, err := model.NewDestination(cfg.Type, params)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to make %s destination: %w", cfg.Type, err)
+	}
+	return dst, nil
+}
+
+// makeDeadLetterSink creates the sink of the dead letter destination through the regular destination factory,
+// it returns nil if errors of the transfer are not written to a destination
+func makeDeadLetterSink(transfer *model.Transfer, lgr log.Logger, mtrcs metrics.Registry, cp coordinator.Coordinator) (*deadletter.Sink, error) {
+	cfg, err := deadLetterConfig(transfer)
+	if err != nil || cfg == nil {
+		return nil, err
+	}
+	dst, err := DeadLetterDestination(cfg)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to build dead letter destination: %w", err)
+	}
+
+	dlqTransfer := *transfer
+	dlqTransfer.Dst = dst
+	dlqTransfer.Transformation = nil
+	dlqMetrics := mtrcs.WithTags(map[string]string{"sink": "dead_letter"})
+	sink, err := ConstructBaseSink(&dlqTransfer, lgr, dlqMetrics, cp, middlewares.MakeConfig(middlewares.AtReplicationStage))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to construct %s sink: %w", cfg.Type, err)
+	}
+	sink = middlewares.ErrorTracker(dlqMetrics)(sink)
+	sink = middlewares.Retrier(lgr, context.Background())(sink)
+	lgr.Info("errors are written to dead letter destination", log.String("type", string(cfg.Type)), log.String("table", cfg.Table))
+	return deadletter.NewSink(sink, cfg.Table, lgr, dlqMetrics), nil
+}
+
+// deadLetterClosingSink closes the dead letter sink after the pipeline, which uses it
+type deadLetterClosingSink struct {
+	abstract.AsyncSink
+	deadLetters *deadletter.Sink
+}
+
+func (s *deadLetterClosingSink) Close() error {
+	err := s.AsyncSink.Close()
+	if dlqErr := s.deadLetters.Close(); dlqErr != nil {
+		if err != nil {
+			return xerrors.Errorf("unable to close pipeline: %w; unable to close dead letter sink: %v", err, dlqErr)
+		}
+		return xerrors.Errorf("unable to close dead letter sink: %w", dlqErr)
+	}
+	return err
+}
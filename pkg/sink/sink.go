@@ -12,6 +12,7 @@ import (
 	"github.com/transferia/transferia/pkg/abstract/coordinator"
 	"github.com/transferia/transferia/pkg/abstract/model"
 	"github.com/transferia/transferia/pkg/config/env"
+	"github.com/transferia/transferia/pkg/deadletter"
 	"github.com/transferia/transferia/pkg/errors"
 	"github.com/transferia/transferia/pkg/errors/categories"
 	"github.com/transferia/transferia/pkg/middlewares"
@@ -30,29 +31,48 @@ var NoAsyncSinkErr = xerrors.NewSentinel("no applicable AsyncSink for this trans
 // The pipeline may include multiple middlewares and transformations. Their concrete set depends on transfer settings, its source and destination.
 func MakeAsyncSink(transfer *model.Transfer, lgr log.Logger, mtrcs metrics.Registry, cp coordinator.Coordinator, config middlewares.Config, opts ...abstract.SinkOption) (abstract.AsyncSink, error) {
 	var pipelineAsync abstract.AsyncSink = nil
-	middleware, err := syncMiddleware(transfer, lgr, mtrcs, cp, opts...)
+	deadLetters, err := makeDeadLetterSink(transfer, lgr, mtrcs, cp)
 	if err != nil {
+		return nil, errors.CategorizedErrorf(categories.Target, "failed to construct dead letter sink: %w", err)
+	}
+	middleware, err := syncMiddleware(transfer, lgr, mtrcs, cp, deadLetters, opts...)
+	if err != nil {
+		closeDeadLetters(deadLetters, lgr)
 		return nil, xerrors.Errorf("error building sync middleware pipeline: %w", err)
 	}
 
 	pipelineAsync, err = constructBaseAsyncSink(transfer, lgr, mtrcs, cp, middleware)
 	if err != nil {
 		if !xerrors.Is(err, NoAsyncSinkErr) {
+			closeDeadLetters(deadLetters, lgr)
 			return nil, errors.CategorizedErrorf(categories.Target, "failed to construct async sink: %w", err)
 		}
 		sink, err := ConstructBaseSink(transfer, lgr, mtrcs, cp, config)
 		if err != nil {
+			closeDeadLetters(deadLetters, lgr)
 			return nil, errors.CategorizedErrorf(categories.Target, "failed to construct sink: %w", err)
 		}
 		pipelineAsync = wrapSinkIntoAsyncPipeline(sink, transfer, lgr, mtrcs, middleware, config)
 	}
 
 	pipelineAsync = async.Measurer(lgr)(pipelineAsync)
+	if deadLetters != nil {
+		pipelineAsync = &deadLetterClosingSink{AsyncSink: pipelineAsync, deadLetters: deadLetters}
+	}
 	return pipelineAsync, nil
 }
 
-func syncMiddleware(transfer *model.Transfer, lgr log.Logger, mtrcs metrics.Registry, cp coordinator.Coordinator, opts ...abstract.SinkOption) (abstract.Middleware, error) {
-	transformer, err := middlewares.Transformation(transfer, lgr, mtrcs)
+func closeDeadLetters(deadLetters *deadletter.Sink, lgr log.Logger) {
+	if deadLetters == nil {
+		return
+	}
+	if err := deadLetters.Close(); err != nil {
+		lgr.Warn("unable to close dead letter sink", log.Error(err))
+	}
+}
+
+func syncMiddleware(transfer *model.Transfer, lgr log.Logger, mtrcs metrics.Registry, cp coordinator.Coordinator, deadLetters *deadletter.Sink, opts ...abstract.SinkOption) (abstract.Middleware, error) {
+	transformer, err := middlewares.Transformation(transfer, lgr, mtrcs, deadLetters)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to set transformation middleware: %w", err)
 	}
@@ -78,6 +98,10 @@ func syncMiddleware(transfer *model.Transfer, lgr log.Logger, mtrcs metrics.Regi
 
 		pipeline = middlewares.PluggableTransformersChain(transfer, mtrcs, cp)(pipeline)
 		pipeline = transformer(pipeline)
+		if deadLetters != nil {
+			// unparsed items are routed before transformation, so transformers never see them
+			pipeline = middlewares.DeadLetter(deadLetters)(pipeline)
+		}
 
 		for i := range opts {
 			pipeline = opts[i](pipeline)
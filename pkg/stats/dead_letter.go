@@ -0,0 +1,20 @@
+package stats
+
+import "github.com/transferia/transferia/library/go/core/metrics"
+
+type DeadLetterStats struct {
+	Transformer metrics.Counter
+	Parser      metrics.Counter
+	Failures    metrics.Counter
+	Elapsed     metrics.Timer
+}
+
+func NewDeadLetterStats(r metrics.Registry) *DeadLetterStats {
+	rWT := r.WithTags(map[string]string{"component": "dead_letter"})
+	return &DeadLetterStats{
+		Transformer: rWT.Counter("dead_letter.transformer"),
+		Parser:      rWT.Counter("dead_letter.parser"),
+		Failures:    rWT.Counter("dead_letter.failures"),
+		Elapsed:     rWT.DurationHistogram("dead_letter.elapsed", MillisecondDurationBuckets()),
+	}
+}
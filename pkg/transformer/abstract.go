@@ -58,6 +58,8 @@ type OutputType string
 const (
 	SinkErrorsOutput    = OutputType("sink")
 	DevnullErrorsOutput = OutputType("devnull")
+	// DestinationErrorsOutput writes failed items into a separate destination, Config is deadletter.Config
+	DestinationErrorsOutput = OutputType("destination")
 )
 
 type ErrorsOutput struct {
@@ -0,0 +1,243 @@
+package addcolumns
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/expression"
+	"github.com/transferia/transferia/pkg/transformer"
+	"github.com/transferia/transferia/pkg/transformer/registry/filter"
+	"github.com/transferia/transferia/pkg/util/set"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const Type = abstract.TransformerType("add_columns")
+
+func init() {
+	transformer.Register[Config](Type, func(cfg Config, lgr log.Logger, runtime abstract.TransformationRuntimeOpts) (abstract.Transformer, error) {
+		return NewAddColumnsTransformer(cfg, lgr)
+	})
+}
+
+type Config struct {
+	Tables filter.Tables `json:"tables"`
+	// Columns are computed one by one, so an expression may refer to the columns computed before it
+	Columns []Column `json:"columns"`
+	// DropColumns are removed after all columns are computed
+	DropColumns []string `json:"dropColumns"`
+}
+
+// Column is a computed column, an existing column with the same name is overwritten
+type Column struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+}
+
+type column struct {
+	name string
+	expr expression.Expr
+}
+
+type AddColumnsTransformer struct {
+	Tables  filter.Filter
+	Logger  log.Logger
+	columns []column
+	drop    *set.Set[string]
+
+	plansMu sync.Mutex
+	plans   map[string]*plan // plans by hashes of table schemas
+}
+
+// plan is the configuration compiled for a table schema
+type plan struct {
+	programs     []*expression.Program
+	resultSchema *abstract.TableSchema
+}
+
+func (t *AddColumnsTransformer) Type() abstract.TransformerType {
+	return Type
+}
+
+func (t *AddColumnsTransformer) Apply(input []abstract.ChangeItem) abstract.TransformerResult {
+	transformed := make([]abstract.ChangeItem, 0, len(input))
+	errors := make([]abstract.TransformerError, 0)
+	for _, item := range input {
+		if !item.IsRowEvent() {
+			transformed = append(transformed, item)
+			continue
+		}
+		result, err := t.transformItem(item)
+		if err != nil {
+			errors = append(errors, abstract.TransformerError{Input: item, Error: err})
+			continue
+		}
+		transformed = append(transformed, result)
+	}
+	return abstract.TransformerResult{
+		Transformed: transformed,
+		Errors:      errors,
+	}
+}
+
+func (t *AddColumnsTransformer) transformItem(item abstract.ChangeItem) (abstract.ChangeItem, error) {
+	p, err := t.plan(item.TableSchema)
+	if err != nil {
+		return item, xerrors.Errorf("unable to compute columns of table %s: %w", item.Fqtn(), err)
+	}
+	// deletions carry keys only, keys are neither overwritten nor dropped
+	if item.Kind != abstract.DeleteKind {
+		current := item
+		current.ColumnNames = append(make([]string, 0, len(item.ColumnNames)+len(t.columns)), item.ColumnNames...)
+		current.ColumnValues = append(make([]interface{}, 0, len(item.ColumnValues)+len(t.columns)), item.ColumnValues...)
+		for i, col := range t.columns {
+			value, err := p.programs[i].Eval(&current)
+			if err != nil {
+				return item, xerrors.Errorf("unable to compute column %s: %w", col.name, err)
+			}
+			if index := current.ColumnNameIndex(col.name); index >= 0 {
+				current.ColumnValues[index] = value
+			} else {
+				current.ColumnNames = append(current.ColumnNames, col.name)
+				current.ColumnValues = append(current.ColumnValues, value)
+			}
+		}
+		item.ColumnNames, item.ColumnValues = t.dropColumns(current.ColumnNames, current.ColumnValues)
+	}
+	item.SetTableSchema(p.resultSchema)
+	return item, nil
+}
+
+func (t *AddColumnsTransformer) dropColumns(names []string, values []interface{}) ([]string, []interface{}) {
+	if t.drop.Empty() {
+		return names, values
+	}
+	resultNames := make([]string, 0, len(names))
+	resultValues := make([]interface{}, 0, len(values))
+	for i, name := range names {
+		if !t.drop.Contains(name) {
+			resultNames = append(resultNames, name)
+			resultValues = append(resultValues, values[i])
+		}
+	}
+	return resultNames, resultValues
+}
+
+func (t *AddColumnsTransformer) plan(tableSchema *abstract.TableSchema) (*plan, error) {
+	hash, err := tableSchema.Hash()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to hash table schema: %w", err)
+	}
+	t.plansMu.Lock()
+	defer t.plansMu.Unlock()
+	if p, ok := t.plans[hash]; ok {
+		return p, nil
+	}
+	p, err := t.newPlan(tableSchema)
+	if err != nil {
+		return nil, err
+	}
+	t.plans[hash] = p
+	return p, nil
+}
+
+// newPlan compiles expressions one by one against the schema extended by the columns computed before
+func (t *AddColumnsTransformer) newPlan(original *abstract.TableSchema) (*plan, error) {
+	result := &plan{programs: make([]*expression.Program, len(t.columns)), resultSchema: nil}
+	columns := original.Columns().Copy()
+	current := original
+	for i, col := range t.columns {
+		program, err := expression.Compile(col.expr, current)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to compile column %s: %w", col.name, err)
+		}
+		result.programs[i] = program
+
+		colSchema := abstract.NewColSchema(col.name, program.Type(), false)
+		if len(columns) > 0 {
+			colSchema.TableSchema = columns[0].TableSchema
+			colSchema.TableName = columns[0].TableName
+		}
+		if index := slices.IndexFunc(columns, func(c abstract.ColSchema) bool { return c.ColumnName == col.name }); index >= 0 {
+			if columns[index].PrimaryKey {
+				return nil, xerrors.Errorf("cannot overwrite primary key column %s", col.name)
+			}
+			columns[index] = colSchema
+		} else {
+			columns = append(columns, colSchema)
+		}
+		current = abstract.NewTableSchema(columns.Copy())
+	}
+
+	kept := make(abstract.TableColumns, 0, len(columns))
+	for _, col := range columns {
+		if !t.drop.Contains(col.ColumnName) {
+			kept = append(kept, col)
+			continue
+		}
+		if col.PrimaryKey {
+			return nil, xerrors.Errorf("cannot drop primary key column %s", col.ColumnName)
+		}
+	}
+	result.resultSchema = abstract.NewTableSchema(kept)
+	return result, nil
+}
+
+func (t *AddColumnsTransformer) Suitable(table abstract.TableID, schema *abstract.TableSchema) bool {
+	if !filter.MatchAnyTableNameVariant(t.Tables, table) {
+		return false
+	}
+	_, err := t.plan(schema)
+	return err == nil
+}
+
+func (t *AddColumnsTransformer) ResultSchema(original *abstract.TableSchema) (*abstract.TableSchema, error) {
+	p, err := t.plan(original)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to compute columns: %w", err)
+	}
+	return p.resultSchema, nil
+}
+
+func (t *AddColumnsTransformer) Description() string {
+	names := make([]string, len(t.columns))
+	for i, col := range t.columns {
+		names[i] = col.name
+	}
+	if t.drop.Empty() {
+		return fmt.Sprintf("Compute columns: %s", strings.Join(names, ", "))
+	}
+	return fmt.Sprintf("Compute columns: %s, drop columns: %s", strings.Join(names, ", "), strings.Join(t.drop.SortedSliceFunc(func(a, b string) bool { return a < b }), ", "))
+}
+
+func NewAddColumnsTransformer(cfg Config, lgr log.Logger) (*AddColumnsTransformer, error) {
+	tables, err := filter.NewFilter(cfg.Tables.IncludeTables, cfg.Tables.ExcludeTables)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to init table filter: %w", err)
+	}
+	if len(cfg.Columns) == 0 && len(cfg.DropColumns) == 0 {
+		return nil, xerrors.New("neither columns nor dropColumns are set")
+	}
+	columns := make([]column, len(cfg.Columns))
+	for i, col := range cfg.Columns {
+		if col.Name == "" {
+			return nil, xerrors.Errorf("column %d has no name", i)
+		}
+		expr, err := expression.Parse(col.Expression)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to parse expression of column %s: %w", col.Name, err)
+		}
+		columns[i] = column{name: col.Name, expr: expr}
+	}
+	return &AddColumnsTransformer{
+		Tables:  tables,
+		Logger:  lgr,
+		columns: columns,
+		drop:    set.New(cfg.DropColumns...),
+		plansMu: sync.Mutex{},
+		plans:   map[string]*plan{},
+	}, nil
+}
@@ -0,0 +1,127 @@
+package addcolumns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/transformer/registry/filter"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+var (
+	table       = abstract.TableID{Namespace: "db", Name: "orders"}
+	tableSchema = abstract.NewTableSchema(abstract.TableColumns{
+		abstract.NewColSchema("id", schema.TypeInt64, true),
+		abstract.NewColSchema("price", schema.TypeFloat64, false),
+		abstract.NewColSchema("quantity", schema.TypeInt32, false),
+		abstract.NewColSchema("email", schema.TypeString, false),
+		abstract.NewColSchema("created_at", schema.TypeTimestamp, false),
+	})
+)
+
+func makeItem(kind abstract.Kind, values []interface{}) abstract.ChangeItem {
+	return abstract.ChangeItem{
+		Kind:         kind,
+		Schema:       table.Namespace,
+		Table:        table.Name,
+		ColumnNames:  tableSchema.Columns().ColumnNames(),
+		ColumnValues: values,
+		TableSchema:  tableSchema,
+	}
+}
+
+func TestAddColumns(t *testing.T) {
+	tr, err := NewAddColumnsTransformer(Config{
+		Tables: filter.Tables{IncludeTables: []string{"db.orders"}},
+		Columns: []Column{
+			{Name: "total", Expression: "price * quantity"},
+			{Name: "domain", Expression: "split_part(email, '@', 2)"},
+			{Name: "day", Expression: "toDate(created_at)"},
+			{Name: "big", Expression: "total > 100"},
+			{Name: "quantity", Expression: "CAST(quantity AS utf8)"},
+		},
+		DropColumns: []string{"email"},
+	}, logger.Log)
+	require.NoError(t, err)
+	require.True(t, tr.Suitable(table, tableSchema))
+	require.False(t, tr.Suitable(abstract.TableID{Namespace: "db", Name: "users"}, tableSchema))
+
+	resultSchema, err := tr.ResultSchema(tableSchema)
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "price", "quantity", "created_at", "total", "domain", "day", "big"}, resultSchema.Columns().ColumnNames())
+	types := make([]schema.Type, 0, len(resultSchema.Columns()))
+	for _, col := range resultSchema.Columns() {
+		types = append(types, schema.Type(col.DataType))
+	}
+	require.Equal(t, []schema.Type{
+		schema.TypeInt64, schema.TypeFloat64, schema.TypeString, schema.TypeTimestamp,
+		schema.TypeFloat64, schema.TypeString, schema.TypeDate, schema.TypeBoolean,
+	}, types)
+	require.True(t, resultSchema.Columns()[0].PrimaryKey)
+
+	createdAt := time.Date(2024, time.May, 2, 10, 30, 0, 0, time.UTC)
+	deleted := makeItem(abstract.DeleteKind, nil)
+	deleted.ColumnNames = nil
+	deleted.OldKeys = abstract.OldKeysType{KeyNames: []string{"id"}, KeyTypes: nil, KeyValues: []interface{}{int64(2)}}
+	result := tr.Apply([]abstract.ChangeItem{
+		makeItem(abstract.InsertKind, []interface{}{int64(1), 25.5, int32(4), "ada@example.com", createdAt}),
+		makeItem(abstract.UpdateKind, []interface{}{int64(2), 10.0, int32(1), nil, createdAt}),
+		deleted,
+		{Kind: abstract.DropTableKind, Schema: table.Namespace, Table: table.Name},
+	})
+	require.Empty(t, result.Errors)
+	require.Len(t, result.Transformed, 4)
+
+	day := time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, resultSchema.Columns().ColumnNames(), result.Transformed[0].ColumnNames)
+	require.Equal(t, []interface{}{int64(1), 25.5, "4", createdAt, 102.0, "example.com", day, true}, result.Transformed[0].ColumnValues)
+	require.Equal(t, []interface{}{int64(2), 10.0, "1", createdAt, 10.0, nil, day, false}, result.Transformed[1].ColumnValues)
+	require.Equal(t, resultSchema, result.Transformed[0].TableSchema)
+	require.Equal(t, resultSchema, result.Transformed[2].TableSchema)
+	require.Nil(t, result.Transformed[2].ColumnNames)
+	require.Equal(t, abstract.DropTableKind, result.Transformed[3].Kind)
+}
+
+func TestAddColumnsErrors(t *testing.T) {
+	t.Run("Empty config", func(t *testing.T) {
+		_, err := NewAddColumnsTransformer(Config{Tables: filter.Tables{}, Columns: nil, DropColumns: nil}, logger.Log)
+		require.Error(t, err)
+	})
+
+	t.Run("Unparseable expression", func(t *testing.T) {
+		_, err := NewAddColumnsTransformer(Config{Columns: []Column{{Name: "x", Expression: "price *"}}}, logger.Log)
+		require.Error(t, err)
+	})
+
+	for name, cfg := range map[string]Config{
+		"Unknown column":         {Columns: []Column{{Name: "x", Expression: "discount * 2"}}},
+		"Mismatched types":       {Columns: []Column{{Name: "x", Expression: "email + 1"}}},
+		"Overwrite primary key":  {Columns: []Column{{Name: "id", Expression: "id + 1"}}},
+		"Drop primary key":       {DropColumns: []string{"id"}},
+		"Refer to later columns": {Columns: []Column{{Name: "x", Expression: "y"}, {Name: "y", Expression: "1"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tr, err := NewAddColumnsTransformer(cfg, logger.Log)
+			require.NoError(t, err)
+			require.False(t, tr.Suitable(table, tableSchema))
+			_, err = tr.ResultSchema(tableSchema)
+			require.Error(t, err)
+		})
+	}
+
+	t.Run("Evaluation error", func(t *testing.T) {
+		tr, err := NewAddColumnsTransformer(Config{Columns: []Column{{Name: "unit_price", Expression: "price / (quantity - 4)"}}}, logger.Log)
+		require.NoError(t, err)
+		require.True(t, tr.Suitable(table, tableSchema))
+		result := tr.Apply([]abstract.ChangeItem{
+			makeItem(abstract.InsertKind, []interface{}{int64(1), 25.5, int32(4), "ada@example.com", time.Now()}),
+			makeItem(abstract.InsertKind, []interface{}{int64(2), 25.5, int32(5), "ada@example.com", time.Now()}),
+		})
+		require.Len(t, result.Transformed, 1)
+		require.Len(t, result.Errors, 1)
+		require.Equal(t, int64(1), result.Errors[0].Input.ColumnValues[0])
+	})
+}
@@ -6,11 +6,12 @@ import (
 	"fmt"
 	"math"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/cast"
-	parser "github.com/transferia/transferia/library/go/yandex/cloud/filter"
 	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/expression"
 	"github.com/transferia/transferia/pkg/transformer"
 	"github.com/transferia/transferia/pkg/transformer/registry/filter"
 	"github.com/transferia/transferia/pkg/util/set"
@@ -32,12 +33,44 @@ func init() {
 }
 
 type termWithValues struct {
-	Term      parser.Term
+	Term      expression.Term
 	ValuesSet *set.Set[interface{}] // not nil, when Term.Operator is IN/NOT IN
 	ByteValue []byte                // not nil, when Term.Value.IsString()
 }
 
-type filteringExpression []termWithValues
+// filteringExpression is either a conjunction of terms written in the filter syntax or an arbitrary predicate.
+type filteringExpression struct {
+	terms     []termWithValues
+	predicate *predicate // not nil, when the expression is not a conjunction of terms
+}
+
+// predicate is a boolean expression, which is compiled once per table schema.
+type predicate struct {
+	expr     expression.Expr
+	mutex    sync.Mutex
+	programs map[string]*expression.Program // programs by hashes of table schemas
+}
+
+func (p *predicate) program(tableSchema *abstract.TableSchema) (*expression.Program, error) {
+	hash, err := tableSchema.Hash()
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to hash table schema: %w", err)
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if program, ok := p.programs[hash]; ok {
+		return program, nil
+	}
+	program, err := expression.Compile(p.expr, tableSchema)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to compile filter: %w", err)
+	}
+	if program.Type() != yts.TypeBoolean {
+		return nil, xerrors.Errorf("Filter '%s' is of type %s instead of boolean", p.expr, program.Type())
+	}
+	p.programs[hash] = program
+	return program, nil
+}
 
 type Config struct {
 	Tables  filter.Tables `json:"tables"`
@@ -57,15 +90,27 @@ func (c *Config) expressions() ([]filteringExpression, error) {
 	result := make([]filteringExpression, len(filters))
 
 	for i, filter := range filters {
-		terms, err := parser.Parse(filter)
+		if strings.TrimSpace(filter) == "" {
+			result[i] = filteringExpression{terms: nil, predicate: nil}
+			continue
+		}
+		expr, err := expression.Parse(filter)
 		if err != nil {
 			return nil, xerrors.Errorf("Unable to parse filter '%s': %w", filter, err)
 		}
+		terms, ok := expression.Terms(expr)
+		if !ok {
+			result[i] = filteringExpression{
+				terms:     nil,
+				predicate: &predicate{expr: expr, mutex: sync.Mutex{}, programs: map[string]*expression.Program{}},
+			}
+			continue
+		}
 		termsWithValues, err := prepareTermValues(terms)
 		if err != nil {
 			return nil, xerrors.Errorf("Unable to prepare term values: %w", err)
 		}
-		result[i] = termsWithValues
+		result[i] = filteringExpression{terms: termsWithValues, predicate: nil}
 	}
 	return result, nil
 }
@@ -131,8 +176,8 @@ func (r *FilterRowsTransformer) Type() abstract.TransformerType {
 
 // matchItem returns true if item matches at least one of filters and should be transferred.
 func (r *FilterRowsTransformer) matchItem(item *abstract.ChangeItem) (bool, error) {
-	for i, expression := range r.Expressions {
-		isMatch, err := r.matchExpression(item, expression)
+	for i, expr := range r.Expressions {
+		isMatch, err := r.matchExpression(item, expr)
 		if err != nil {
 			return false, xerrors.Errorf("Unable to check expression %d: %w", i, err)
 		}
@@ -143,8 +188,11 @@ func (r *FilterRowsTransformer) matchItem(item *abstract.ChangeItem) (bool, erro
 	return false, nil
 }
 
-func (r *FilterRowsTransformer) matchExpression(item *abstract.ChangeItem, terms filteringExpression) (bool, error) {
-	for _, term := range terms {
+func (r *FilterRowsTransformer) matchExpression(item *abstract.ChangeItem, expr filteringExpression) (bool, error) {
+	if expr.predicate != nil {
+		return matchPredicate(item, expr.predicate)
+	}
+	for _, term := range expr.terms {
 		for i, columnName := range item.ColumnNames {
 			// term.Attribute is a name of column which we are filtering by.
 			if columnName != term.Term.Attribute {
@@ -176,6 +224,19 @@ func (r *FilterRowsTransformer) matchExpression(item *abstract.ChangeItem, terms
 	return true, nil
 }
 
+// matchPredicate returns true if the predicate is true, NULL does not match like in SQL WHERE.
+func matchPredicate(item *abstract.ChangeItem, predicate *predicate) (bool, error) {
+	program, err := predicate.program(item.TableSchema)
+	if err != nil {
+		return false, xerrors.Errorf("Unable to prepare filter for table '%s': %w", item.TableID().Name, err)
+	}
+	result, err := program.Eval(item)
+	if err != nil {
+		return false, xerrors.Errorf("Unable to evaluate filter: %w", err)
+	}
+	return result == true, nil
+}
+
 // matchValue checks if "val1 *op* val2" (e.g. val1 < val2) is true.
 // val1 is column's value, val2 is filter's value.
 func matchValue(val1 interface{}, term termWithValues) (bool, error) {
@@ -284,9 +345,9 @@ func matchValue(val1 interface{}, term termWithValues) (bool, error) {
 	case val2.IsString(), val2.IsStringList():
 		byt, ok := val1.([]byte)
 		if val2.IsString() && ok {
-			if op == parser.Match {
+			if op == expression.Match {
 				return bytes.Contains(byt, term.ByteValue), nil
-			} else if op == parser.NotMatch {
+			} else if op == expression.NotMatch {
 				return !bytes.Contains(byt, term.ByteValue), nil
 			}
 
@@ -306,9 +367,9 @@ func matchValue(val1 interface{}, term termWithValues) (bool, error) {
 			ok = isString
 		}
 		if ok {
-			if op == parser.Match {
+			if op == expression.Match {
 				return strings.Contains(str1, val2.AsString()), nil
-			} else if op == parser.NotMatch {
+			} else if op == expression.NotMatch {
 				return !strings.Contains(str1, val2.AsString()), nil
 			}
 
@@ -352,9 +413,9 @@ func matchValue(val1 interface{}, term termWithValues) (bool, error) {
 		}
 
 	case val2.IsNull():
-		if op == parser.Equals {
+		if op == expression.Equals {
 			return val1 == nil, nil
-		} else if op == parser.NotEquals {
+		} else if op == expression.NotEquals {
 			return val1 != nil, nil
 		}
 
@@ -365,58 +426,58 @@ func matchValue(val1 interface{}, term termWithValues) (bool, error) {
 	return false, xerrors.New("Unsupported type pair")
 }
 
-func matchOrderedValue[T constraints.Ordered](val1, val2 T, op parser.OperatorType) (bool, error) {
+func matchOrderedValue[T constraints.Ordered](val1, val2 T, op expression.OperatorType) (bool, error) {
 	switch op {
-	case parser.Equals:
+	case expression.Equals:
 		return val1 == val2, nil
-	case parser.NotEquals:
+	case expression.NotEquals:
 		return val1 != val2, nil
-	case parser.Less:
+	case expression.Less:
 		return val1 < val2, nil
-	case parser.LessOrEquals:
+	case expression.LessOrEquals:
 		return val1 <= val2, nil
-	case parser.Greater:
+	case expression.Greater:
 		return val1 > val2, nil
-	case parser.GreaterOrEquals:
+	case expression.GreaterOrEquals:
 		return val1 >= val2, nil
 	}
 	return false, xerrors.Errorf("Unknown operation %s", op)
 }
 
-func matchBytesValue(val1, val2 []byte, op parser.OperatorType) (bool, error) {
+func matchBytesValue(val1, val2 []byte, op expression.OperatorType) (bool, error) {
 	switch op {
-	case parser.Equals:
+	case expression.Equals:
 		return bytes.Equal(val1, val2), nil
-	case parser.NotEquals:
+	case expression.NotEquals:
 		return !bytes.Equal(val1, val2), nil
-	case parser.Less:
+	case expression.Less:
 		return bytes.Compare(val1, val2) < 0, nil
-	case parser.LessOrEquals:
+	case expression.LessOrEquals:
 		return bytes.Compare(val1, val2) < 1, nil
-	case parser.Greater:
+	case expression.Greater:
 		return bytes.Compare(val1, val2) > 0, nil
-	case parser.GreaterOrEquals:
+	case expression.GreaterOrEquals:
 		return bytes.Compare(val1, val2) > -1, nil
 	}
 	return false, xerrors.Errorf("Unknown operation %s", op)
 }
 
-func matchValueToSet[T constraints.Ordered](val T, set *set.Set[interface{}], op parser.OperatorType) (bool, error) {
+func matchValueToSet[T constraints.Ordered](val T, set *set.Set[interface{}], op expression.OperatorType) (bool, error) {
 	if set == nil {
 		return false, xerrors.Errorf("unable to check value matching without set, operator: %s", op.String())
 	}
 
 	switch op {
-	case parser.In:
+	case expression.In:
 		return set.Contains(val), nil
-	case parser.NotIn:
+	case expression.NotIn:
 		return !set.Contains(val), nil
 	}
 
 	return false, xerrors.Errorf("Unknown set operation %s", op)
 }
 
-func prepareTermValues(terms []parser.Term) ([]termWithValues, error) {
+func prepareTermValues(terms []expression.Term) ([]termWithValues, error) {
 	termsWithValues := make([]termWithValues, 0, len(terms))
 	for _, term := range terms {
 		currentTerm := termWithValues{
@@ -443,8 +504,8 @@ func prepareTermValues(terms []parser.Term) ([]termWithValues, error) {
 	return termsWithValues, nil
 }
 
-func isOperationWithSet(op parser.OperatorType) bool {
-	return op == parser.In || op == parser.NotIn
+func isOperationWithSet(op expression.OperatorType) bool {
+	return op == expression.In || op == expression.NotIn
 }
 
 func (r *FilterRowsTransformer) Suitable(table abstract.TableID, schema *abstract.TableSchema) bool {
@@ -452,9 +513,14 @@ func (r *FilterRowsTransformer) Suitable(table abstract.TableID, schema *abstrac
 		return false
 	}
 	cols := schema.Columns()
-	for _, expression := range r.Expressions {
-		isSuitable := r.checkExpressionSuitable(cols, expression)
-		if !isSuitable {
+	for _, expr := range r.Expressions {
+		if expr.predicate != nil {
+			if _, err := expr.predicate.program(schema); err != nil {
+				return false
+			}
+			continue
+		}
+		if !r.checkExpressionSuitable(cols, expr.terms) {
 			return false
 		}
 	}
@@ -489,8 +555,8 @@ func (r *FilterRowsTransformer) Description() string {
 	return "Transformer for filtering rows by provided filter."
 }
 
-// checkColumnSuitable checks if column.DataType could be casted to parser.Value's type.
-func (r *FilterRowsTransformer) checkColumnSuitable(value parser.Value, column abstract.ColSchema) bool {
+// checkColumnSuitable checks if column.DataType could be casted to expression.Value's type.
+func (r *FilterRowsTransformer) checkColumnSuitable(value expression.Value, column abstract.ColSchema) bool {
 	switch {
 	case value.IsBool():
 		return column.DataType == yts.TypeBoolean.String()
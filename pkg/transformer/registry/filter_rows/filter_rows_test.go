@@ -758,3 +758,32 @@ func TestManyFilters(t *testing.T) {
 	expectedResult := abstract.TransformerResult{Transformed: expected, Errors: []abstract.TransformerError{}}
 	require.Equal(t, expectedResult, tr.Apply(items))
 }
+
+func TestExpressionFiltering(t *testing.T) {
+	filter := `column1 > 10 OR lower(column2) = "str" OR (column1 + 1) * 2 = 6`
+	tr, err := NewFilterRowsTransformer(Config{Filter: filter}, logger.Log)
+	require.NoError(t, err)
+
+	schema := abstract.NewTableSchema(abstract.TableColumns{
+		abstract.MakeTypedColSchema("column1", yts.TypeInt8.String(), true),
+		abstract.MakeTypedColSchema("column2", yts.TypeString.String(), false),
+	})
+	checkSuitable(t, tr, abstract.TableID{Namespace: "db", Name: "table"}, schema)
+
+	items := makeChangeItems(schema, abstract.InsertKind, [][]interface{}{
+		{15, "aaa"},
+		{5, "STR"},
+		{2, "aaa"},
+		{5, "aaa"},
+		{5, nil},
+	})
+	expected := []abstract.ChangeItem{items[0], items[1], items[2]}
+	expectedResult := abstract.TransformerResult{Transformed: expected, Errors: []abstract.TransformerError{}}
+	require.Equal(t, expectedResult, tr.Apply(items))
+
+	t.Run("Non-boolean expression", func(t *testing.T) {
+		tr, err := NewFilterRowsTransformer(Config{Filter: "column1 + 1"}, logger.Log)
+		require.NoError(t, err)
+		require.False(t, tr.Suitable(abstract.TableID{Namespace: "db", Name: "table"}, schema))
+	})
+}
@@ -6,7 +6,7 @@ import (
 	"time"
 
 	"github.com/transferia/transferia/library/go/core/xerrors"
-	"github.com/transferia/transferia/library/go/yandex/cloud/filter"
+	"github.com/transferia/transferia/pkg/expression"
 	"github.com/transferia/transferia/pkg/util/set"
 )
 
@@ -78,7 +78,7 @@ func toInt64E(i interface{}) (int64, error) {
 	}
 }
 
-func valuesListToSet(valList filter.Value) (*set.Set[interface{}], error) {
+func valuesListToSet(valList expression.Value) (*set.Set[interface{}], error) {
 	values := make([]interface{}, 0)
 	if valList.IsIntList() {
 		for _, val := range valList.AsIntList() {
@@ -0,0 +1,184 @@
+package flatten
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+// document converts a value of an expanded column into a generic JSON value of maps, slices & scalars.
+// Texts are parsed as JSON, values of other Go types, e.g. Mongo documents, are converted through their JSON representation
+func document(value any) (any, error) {
+	switch v := value.(type) {
+	case nil, map[string]any, []any:
+		return v, nil
+	case string:
+		return parseJSON([]byte(v))
+	case []byte:
+		return parseJSON(v)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to marshal %T: %w", value, err)
+	}
+	return parseJSON(data)
+}
+
+func parseJSON(data []byte) (any, error) {
+	var result any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
+		return nil, xerrors.Errorf("unable to parse JSON: %w", err)
+	}
+	return result, nil
+}
+
+// object returns the document of a flattened column, it must be a JSON object or NULL
+func object(value any) (map[string]any, error) {
+	doc, err := document(value)
+	if err != nil {
+		return nil, err
+	}
+	switch v := doc.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		return v, nil
+	}
+	return nil, xerrors.Errorf("value of type %T is not a JSON object", doc)
+}
+
+// array returns the document of an unnested column, it must be a JSON array or NULL
+func array(value any) ([]any, error) {
+	doc, err := document(value)
+	if err != nil {
+		return nil, err
+	}
+	switch v := doc.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		return v, nil
+	}
+	return nil, xerrors.Errorf("value of type %T is not a JSON array", doc)
+}
+
+// lookup returns the value of the field of an object, missing fields are NULL
+func lookup(doc map[string]any, path []string) any {
+	var value any = doc
+	for _, key := range path {
+		parent, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = parent[key]
+	}
+	return value
+}
+
+// walk visits the fields of an object down to the max depth, deeper objects are visited as values
+func (t *FlattenTransformer) walk(path []string, doc map[string]any, visit func(path []string, value any)) {
+	for key, value := range doc {
+		fieldPath := append(path[:len(path):len(path)], key)
+		if child, ok := value.(map[string]any); ok && len(fieldPath) < t.maxDepth {
+			t.walk(fieldPath, child, visit)
+			continue
+		}
+		visit(fieldPath, value)
+	}
+}
+
+// inferFields returns the fields found in the documents ordered by their names, fields which are always NULL are of type any
+func (t *FlattenTransformer) inferFields(column string, docs []any) ([]field, error) {
+	fields := make(map[string]*field)
+	var conflict error
+	for _, doc := range docs {
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			continue
+		}
+		t.walk(nil, obj, func(path []string, value any) {
+			name := column + t.separator + strings.Join(path, t.separator)
+			f, ok := fields[name]
+			if !ok {
+				f = &field{path: path, name: name, typ: ""}
+				fields[name] = f
+			} else if strings.Join(f.path, ".") != strings.Join(path, ".") && conflict == nil {
+				conflict = xerrors.Errorf("fields %s and %s are both flattened into column %s", strings.Join(f.path, "."), strings.Join(path, "."), name)
+			}
+			if value != nil {
+				f.typ = unifyTypes(f.typ, typeOf(value))
+			}
+		})
+	}
+	if conflict != nil {
+		return nil, conflict
+	}
+	result := make([]field, 0, len(fields))
+	for _, f := range fields {
+		if f.typ == "" {
+			f.typ = schema.TypeAny
+		}
+		result = append(result, *f)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result, nil
+}
+
+// inferElementType returns the common type of the elements of the arrays, arrays of objects have elements of type any
+func inferElementType(docs []any) schema.Type {
+	var result schema.Type
+	for _, doc := range docs {
+		elements, ok := doc.([]any)
+		if !ok {
+			continue
+		}
+		for _, element := range elements {
+			if element != nil {
+				result = unifyTypes(result, typeOf(element))
+			}
+		}
+	}
+	if result == "" {
+		return schema.TypeAny
+	}
+	return result
+}
+
+func typeOf(value any) schema.Type {
+	switch v := value.(type) {
+	case bool:
+		return schema.TypeBoolean
+	case string:
+		return schema.TypeString
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return schema.TypeInt64
+		}
+		return schema.TypeFloat64
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return schema.TypeInt64
+	case float32, float64:
+		return schema.TypeFloat64
+	case time.Time:
+		return schema.TypeTimestamp
+	}
+	return schema.TypeAny
+}
+
+// unifyTypes returns a type which holds values of both types, an empty type stands for no values seen yet
+func unifyTypes(a, b schema.Type) schema.Type {
+	switch {
+	case a == "" || a == b:
+		return b
+	case (a == schema.TypeInt64 || a == schema.TypeFloat64) && (b == schema.TypeInt64 || b == schema.TypeFloat64):
+		return schema.TypeFloat64
+	}
+	return schema.TypeAny
+}
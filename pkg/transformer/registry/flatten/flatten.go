@@ -0,0 +1,245 @@
+package flatten
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/expression"
+	"github.com/transferia/transferia/pkg/transformer"
+	"github.com/transferia/transferia/pkg/transformer/registry/filter"
+	"github.com/transferia/transferia/pkg/util/set"
+	"go.ytsaurus.tech/library/go/core/log"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+const Type = abstract.TransformerType("flatten")
+
+const (
+	defaultSeparator  = "_"
+	defaultMaxDepth   = 1
+	defaultSampleSize = 100
+)
+
+func init() {
+	transformer.Register[Config](Type, func(cfg Config, lgr log.Logger, runtime abstract.TransformationRuntimeOpts) (abstract.Transformer, error) {
+		return NewFlattenTransformer(cfg, lgr)
+	})
+}
+
+type Mode string
+
+const (
+	// ModeFlatten expands objects of JSON columns into columns named by the paths of their fields
+	ModeFlatten = Mode("flatten")
+	// ModeUnnest explodes an array column into a row per element
+	ModeUnnest = Mode("unnest")
+)
+
+type Config struct {
+	Tables filter.Tables `json:"tables"`
+	// Mode is flatten by default
+	Mode Mode `json:"mode"`
+	// Columns are the JSON or any columns to expand, the unnest mode takes a single array column
+	Columns []string `json:"columns"`
+	// Separator joins names of parent & child fields, "_" by default
+	Separator string `json:"separator"`
+	// MaxDepth is the number of nesting levels expanded, deeper objects are kept in any columns. 1 by default
+	MaxDepth int `json:"maxDepth"`
+	// Fields declare the expanded columns & their types, columns without declared fields are inferred from a sample
+	Fields []Field `json:"fields"`
+	// SampleSize is the maximum number of rows types are inferred from, 100 by default
+	SampleSize int `json:"sampleSize"`
+	// KeepSource keeps the expanded column in flatten mode
+	KeepSource bool `json:"keepSource"`
+	// IndexColumn is the column of indexes of array elements in unnest mode, `<column>_index` by default
+	IndexColumn string `json:"indexColumn"`
+	// MaxElements limits arrays in unnest mode, it's required to delete rows of elements on updates & deletions without old values
+	MaxElements int `json:"maxElements"`
+}
+
+// Field is an expanded column
+type Field struct {
+	// Path is the column & keys of the field joined by dots, e.g. `address.geo.lat`, the unnest mode takes the column itself
+	Path string `json:"path"`
+	// Name is the name of the column, the path joined by the separator by default
+	Name string `json:"name"`
+	// Type is a type name accepted by CAST of the expression language, e.g. int64, double, utf8 or any
+	Type string `json:"type"`
+}
+
+// field is a column expanded from a column, path is the path of keys of the field inside the column
+type field struct {
+	path []string
+	name string
+	typ  schema.Type
+}
+
+type FlattenTransformer struct {
+	Tables filter.Filter
+	Logger log.Logger
+
+	mode        Mode
+	columns     []string
+	separator   string
+	maxDepth    int
+	declared    map[string][]field
+	sampleSize  int
+	keepSource  bool
+	indexColumn string
+	maxElements int
+
+	plansMu sync.Mutex
+	plans   map[planKey]*plan
+}
+
+func (t *FlattenTransformer) Type() abstract.TransformerType {
+	return Type
+}
+
+func (t *FlattenTransformer) Apply(input []abstract.ChangeItem) abstract.TransformerResult {
+	transformed := make([]abstract.ChangeItem, 0, len(input))
+	errors := make([]abstract.TransformerError, 0)
+	for _, item := range input {
+		if !item.IsRowEvent() {
+			transformed = append(transformed, item)
+			continue
+		}
+		p, err := t.resolvedPlan(item, input)
+		if err != nil {
+			errors = append(errors, abstract.TransformerError{Input: item, Error: xerrors.Errorf("unable to expand columns of table %s: %w", item.Fqtn(), err)})
+			continue
+		}
+		var result []abstract.ChangeItem
+		if t.mode == ModeUnnest {
+			result, err = t.unnestItem(item, p)
+		} else {
+			result, err = t.flattenItem(item, p)
+		}
+		if err != nil {
+			errors = append(errors, abstract.TransformerError{Input: item, Error: err})
+			continue
+		}
+		transformed = append(transformed, result...)
+	}
+	return abstract.TransformerResult{
+		Transformed: transformed,
+		Errors:      errors,
+	}
+}
+
+func (t *FlattenTransformer) Suitable(table abstract.TableID, schema *abstract.TableSchema) bool {
+	if !filter.MatchAnyTableNameVariant(t.Tables, table) {
+		return false
+	}
+	_, err := t.plan(table, schema)
+	return err == nil
+}
+
+// ResultSchema returns the schema with declared fields, the fields inferred from the rows are added once the rows are seen
+func (t *FlattenTransformer) ResultSchema(original *abstract.TableSchema) (*abstract.TableSchema, error) {
+	p, err := t.plan(abstract.TableID{Namespace: "", Name: ""}, original)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to expand columns: %w", err)
+	}
+	return p.resultSchema, nil
+}
+
+func (t *FlattenTransformer) Description() string {
+	if t.mode == ModeUnnest {
+		return fmt.Sprintf("Unnest column %s with index %s", t.columns[0], t.indexColumn)
+	}
+	return fmt.Sprintf("Flatten columns %s up to depth %d", strings.Join(t.columns, ", "), t.maxDepth)
+}
+
+func NewFlattenTransformer(cfg Config, lgr log.Logger) (*FlattenTransformer, error) {
+	tables, err := filter.NewFilter(cfg.Tables.IncludeTables, cfg.Tables.ExcludeTables)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to init table filter: %w", err)
+	}
+	mode := cfg.Mode
+	if mode == "" {
+		mode = ModeFlatten
+	}
+	if mode != ModeFlatten && mode != ModeUnnest {
+		return nil, xerrors.Errorf("unknown mode %s", mode)
+	}
+	if len(cfg.Columns) == 0 {
+		return nil, xerrors.New("columns are not set")
+	}
+	if mode == ModeUnnest && len(cfg.Columns) != 1 {
+		return nil, xerrors.Errorf("unnest mode takes a single column, got %d", len(cfg.Columns))
+	}
+	columns := set.New(cfg.Columns...)
+	if columns.Len() != len(cfg.Columns) {
+		return nil, xerrors.Errorf("columns are repeated: %s", strings.Join(cfg.Columns, ", "))
+	}
+	if cfg.MaxDepth < 0 || cfg.SampleSize < 0 || cfg.MaxElements < 0 {
+		return nil, xerrors.New("maxDepth, sampleSize and maxElements cannot be negative")
+	}
+	separator := cfg.Separator
+	if separator == "" {
+		separator = defaultSeparator
+	}
+	maxDepth := cfg.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = defaultMaxDepth
+	}
+	sampleSize := cfg.SampleSize
+	if sampleSize == 0 {
+		sampleSize = defaultSampleSize
+	}
+	indexColumn := cfg.IndexColumn
+	if indexColumn == "" {
+		indexColumn = cfg.Columns[0] + separator + "index"
+	}
+
+	declared := make(map[string][]field)
+	names := set.New[string]()
+	for _, f := range cfg.Fields {
+		path := strings.Split(f.Path, ".")
+		if !columns.Contains(path[0]) {
+			return nil, xerrors.Errorf("field %s is not a field of columns %s", f.Path, strings.Join(cfg.Columns, ", "))
+		}
+		if mode == ModeFlatten && len(path) < 2 {
+			return nil, xerrors.Errorf("field %s has no keys, paths look like column.key", f.Path)
+		}
+		if mode == ModeUnnest && len(path) != 1 {
+			return nil, xerrors.Errorf("field %s of unnest mode must be the column itself", f.Path)
+		}
+		typ, ok := expression.ParseType(f.Type)
+		if !ok {
+			return nil, xerrors.Errorf("unknown type %s of field %s", f.Type, f.Path)
+		}
+		name := f.Name
+		if name == "" {
+			name = strings.Join(path, separator)
+		}
+		if mode == ModeUnnest {
+			name = path[0]
+		}
+		if names.Contains(name) {
+			return nil, xerrors.Errorf("column %s is declared twice", name)
+		}
+		names.Add(name)
+		declared[path[0]] = append(declared[path[0]], field{path: path[1:], name: name, typ: typ})
+	}
+
+	return &FlattenTransformer{
+		Tables:      tables,
+		Logger:      lgr,
+		mode:        mode,
+		columns:     cfg.Columns,
+		separator:   separator,
+		maxDepth:    maxDepth,
+		declared:    declared,
+		sampleSize:  sampleSize,
+		keepSource:  cfg.KeepSource,
+		indexColumn: indexColumn,
+		maxElements: cfg.MaxElements,
+		plansMu:     sync.Mutex{},
+		plans:       map[planKey]*plan{},
+	}, nil
+}
@@ -0,0 +1,289 @@
+package flatten
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/transformer/registry/filter"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+var (
+	events       = abstract.TableID{Namespace: "db", Name: "events"}
+	eventsSchema = abstract.NewTableSchema(abstract.TableColumns{
+		abstract.NewColSchema("id", schema.TypeInt64, true),
+		abstract.NewColSchema("payload", schema.TypeAny, false),
+		abstract.NewColSchema("attrs", schema.TypeString, false),
+	})
+	orders       = abstract.TableID{Namespace: "db", Name: "orders"}
+	ordersSchema = abstract.NewTableSchema(abstract.TableColumns{
+		abstract.NewColSchema("id", schema.TypeInt64, true),
+		abstract.NewColSchema("items", schema.TypeAny, false),
+		abstract.NewColSchema("status", schema.TypeString, false),
+	})
+)
+
+func makeItem(table abstract.TableID, tableSchema *abstract.TableSchema, kind abstract.Kind, values []interface{}) abstract.ChangeItem {
+	return abstract.ChangeItem{
+		Kind:         kind,
+		Schema:       table.Namespace,
+		Table:        table.Name,
+		ColumnNames:  tableSchema.Columns().ColumnNames(),
+		ColumnValues: values,
+		TableSchema:  tableSchema,
+	}
+}
+
+func columnTypes(tableSchema *abstract.TableSchema) []schema.Type {
+	types := make([]schema.Type, 0, len(tableSchema.Columns()))
+	for _, col := range tableSchema.Columns() {
+		types = append(types, schema.Type(col.DataType))
+	}
+	return types
+}
+
+func TestFlatten(t *testing.T) {
+	tr, err := NewFlattenTransformer(Config{
+		Tables:  filter.Tables{IncludeTables: []string{"db.events"}},
+		Columns: []string{"payload", "attrs"},
+		Fields: []Field{
+			{Path: "payload.user.id", Name: "user_id", Type: "int64"},
+			{Path: "payload.kind", Type: "utf8"},
+		},
+	}, logger.Log)
+	require.NoError(t, err)
+	require.True(t, tr.Suitable(events, eventsSchema))
+	require.False(t, tr.Suitable(orders, ordersSchema))
+
+	// attrs are inferred from the rows, so they are kept as they are until the rows are seen
+	resultSchema, err := tr.ResultSchema(eventsSchema)
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "user_id", "payload_kind", "attrs"}, resultSchema.Columns().ColumnNames())
+	require.Equal(t, []schema.Type{schema.TypeInt64, schema.TypeInt64, schema.TypeString, schema.TypeString}, columnTypes(resultSchema))
+
+	update := makeItem(events, eventsSchema, abstract.UpdateKind, []interface{}{int64(2), nil, `{"a": 2.5, "d": null}`})
+	update.OldKeys = abstract.OldKeysType{
+		KeyNames:  []string{"id", "attrs"},
+		KeyTypes:  []string{"int64", "string"},
+		KeyValues: []interface{}{int64(2), `{"a": 3}`},
+	}
+	result := tr.Apply([]abstract.ChangeItem{
+		makeItem(events, eventsSchema, abstract.InsertKind, []interface{}{
+			int64(1),
+			map[string]interface{}{"user": map[string]interface{}{"id": "7"}, "kind": "click"},
+			`{"a": 1, "b": {"c": true}}`,
+		}),
+		update,
+		{Kind: abstract.DropTableKind, Schema: events.Namespace, Table: events.Name},
+	})
+	require.Empty(t, result.Errors)
+	require.Len(t, result.Transformed, 3)
+
+	resultSchema = result.Transformed[0].TableSchema
+	require.Equal(t, []string{"id", "user_id", "payload_kind", "attrs_a", "attrs_b", "attrs_d"}, resultSchema.Columns().ColumnNames())
+	require.Equal(t, []schema.Type{
+		schema.TypeInt64, schema.TypeInt64, schema.TypeString, schema.TypeFloat64, schema.TypeAny, schema.TypeAny,
+	}, columnTypes(resultSchema))
+	require.Equal(t, resultSchema.Columns().ColumnNames(), result.Transformed[0].ColumnNames)
+	require.Equal(t, []interface{}{int64(1), int64(7), "click", 1.0, map[string]interface{}{"c": true}, nil}, result.Transformed[0].ColumnValues)
+	require.Equal(t, []interface{}{int64(2), nil, nil, 2.5, nil, nil}, result.Transformed[1].ColumnValues)
+	require.Equal(t, []string{"id", "attrs_a", "attrs_b", "attrs_d"}, result.Transformed[1].OldKeys.KeyNames)
+	require.Equal(t, []string{"int64", "double", "any", "any"}, result.Transformed[1].OldKeys.KeyTypes)
+	require.Equal(t, []interface{}{int64(2), 3.0, nil, nil}, result.Transformed[1].OldKeys.KeyValues)
+	require.Equal(t, abstract.DropTableKind, result.Transformed[2].Kind)
+
+	// inferred fields are frozen once they are seen
+	result = tr.Apply([]abstract.ChangeItem{
+		makeItem(events, eventsSchema, abstract.InsertKind, []interface{}{int64(3), nil, `{"a": 4, "e": "new"}`}),
+	})
+	require.Empty(t, result.Errors)
+	require.Equal(t, resultSchema, result.Transformed[0].TableSchema)
+	require.Equal(t, []interface{}{int64(3), nil, nil, 4.0, nil, nil}, result.Transformed[0].ColumnValues)
+}
+
+func TestFlattenDepth(t *testing.T) {
+	tr, err := NewFlattenTransformer(Config{
+		Columns:    []string{"attrs"},
+		Separator:  ".",
+		MaxDepth:   2,
+		KeepSource: true,
+	}, logger.Log)
+	require.NoError(t, err)
+	attrs := `{"a": 1, "b": {"c": true, "d": {"e": "x"}}}`
+	result := tr.Apply([]abstract.ChangeItem{
+		makeItem(events, eventsSchema, abstract.InsertKind, []interface{}{int64(1), nil, attrs}),
+	})
+	require.Empty(t, result.Errors)
+	resultSchema := result.Transformed[0].TableSchema
+	require.Equal(t, []string{"id", "payload", "attrs", "attrs.a", "attrs.b.c", "attrs.b.d"}, resultSchema.Columns().ColumnNames())
+	require.Equal(t, []schema.Type{
+		schema.TypeInt64, schema.TypeAny, schema.TypeString, schema.TypeInt64, schema.TypeBoolean, schema.TypeAny,
+	}, columnTypes(resultSchema))
+	require.Equal(t, []interface{}{int64(1), nil, attrs, int64(1), true, map[string]interface{}{"e": "x"}}, result.Transformed[0].ColumnValues)
+
+	result = tr.Apply([]abstract.ChangeItem{
+		makeItem(events, eventsSchema, abstract.InsertKind, []interface{}{int64(2), nil, `[1, 2]`}),
+	})
+	require.Len(t, result.Errors, 1)
+}
+
+func TestUnnest(t *testing.T) {
+	tr, err := NewFlattenTransformer(Config{
+		Mode:        ModeUnnest,
+		Columns:     []string{"items"},
+		MaxElements: 3,
+	}, logger.Log)
+	require.NoError(t, err)
+	require.True(t, tr.Suitable(orders, ordersSchema))
+	require.False(t, tr.Suitable(events, eventsSchema))
+
+	resultSchema, err := tr.ResultSchema(ordersSchema)
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "items_index", "items", "status"}, resultSchema.Columns().ColumnNames())
+	require.Equal(t, []schema.Type{schema.TypeInt64, schema.TypeInt64, schema.TypeAny, schema.TypeString}, columnTypes(resultSchema))
+	require.True(t, resultSchema.Columns()[1].PrimaryKey)
+
+	updateWithOld := makeItem(orders, ordersSchema, abstract.UpdateKind, []interface{}{int64(1), `["x", "y", "z"]`, "paid"})
+	updateWithOld.OldKeys = abstract.OldKeysType{
+		KeyNames:  []string{"id", "items"},
+		KeyTypes:  []string{"int64", "any"},
+		KeyValues: []interface{}{int64(1), []interface{}{"a", "b"}},
+	}
+	updateWithoutOld := makeItem(orders, ordersSchema, abstract.UpdateKind, []interface{}{int64(2), []interface{}{"c"}, "paid"})
+	deletion := makeItem(orders, ordersSchema, abstract.DeleteKind, nil)
+	deletion.ColumnNames = nil
+	deletion.OldKeys = abstract.OldKeysType{KeyNames: []string{"id"}, KeyTypes: []string{"int64"}, KeyValues: []interface{}{int64(3)}}
+	result := tr.Apply([]abstract.ChangeItem{
+		makeItem(orders, ordersSchema, abstract.InsertKind, []interface{}{int64(1), []interface{}{"a", "b"}, "new"}),
+		makeItem(orders, ordersSchema, abstract.InsertKind, []interface{}{int64(4), nil, "new"}),
+		updateWithOld,
+		updateWithoutOld,
+		deletion,
+	})
+	require.Empty(t, result.Errors)
+
+	resultSchema = result.Transformed[0].TableSchema
+	require.Equal(t, []schema.Type{schema.TypeInt64, schema.TypeInt64, schema.TypeString, schema.TypeString}, columnTypes(resultSchema))
+	type row struct {
+		kind    abstract.Kind
+		values  []interface{}
+		oldKeys []interface{}
+	}
+	rows := make([]row, 0, len(result.Transformed))
+	for _, item := range result.Transformed {
+		require.Equal(t, resultSchema, item.TableSchema)
+		rows = append(rows, row{kind: item.Kind, values: item.ColumnValues, oldKeys: item.OldKeys.KeyValues})
+	}
+	require.Equal(t, []row{
+		{kind: abstract.InsertKind, values: []interface{}{int64(1), int64(0), "a", "new"}, oldKeys: nil},
+		{kind: abstract.InsertKind, values: []interface{}{int64(1), int64(1), "b", "new"}, oldKeys: nil},
+		// the old array is known, so the new element is inserted
+		{kind: abstract.UpdateKind, values: []interface{}{int64(1), int64(0), "x", "paid"}, oldKeys: []interface{}{int64(1), int64(0), "a"}},
+		{kind: abstract.UpdateKind, values: []interface{}{int64(1), int64(1), "y", "paid"}, oldKeys: []interface{}{int64(1), int64(1), "b"}},
+		{kind: abstract.InsertKind, values: []interface{}{int64(1), int64(2), "z", "paid"}, oldKeys: nil},
+		// the old array is unknown, so the elements up to maxElements are deleted
+		{kind: abstract.UpdateKind, values: []interface{}{int64(2), int64(0), "c", "paid"}, oldKeys: nil},
+		{kind: abstract.DeleteKind, values: nil, oldKeys: []interface{}{int64(2), int64(1)}},
+		{kind: abstract.DeleteKind, values: nil, oldKeys: []interface{}{int64(2), int64(2)}},
+		{kind: abstract.DeleteKind, values: nil, oldKeys: []interface{}{int64(3), int64(0)}},
+		{kind: abstract.DeleteKind, values: nil, oldKeys: []interface{}{int64(3), int64(1)}},
+		{kind: abstract.DeleteKind, values: nil, oldKeys: []interface{}{int64(3), int64(2)}},
+	}, rows)
+
+	result = tr.Apply([]abstract.ChangeItem{
+		makeItem(orders, ordersSchema, abstract.InsertKind, []interface{}{int64(5), []interface{}{"a", "b", "c", "d"}, "new"}),
+	})
+	require.Len(t, result.Errors, 1)
+}
+
+func TestUnnestWithoutMaxElements(t *testing.T) {
+	tr, err := NewFlattenTransformer(Config{
+		Mode:        ModeUnnest,
+		Columns:     []string{"items"},
+		IndexColumn: "position",
+		Fields:      []Field{{Path: "items", Type: "int64"}},
+	}, logger.Log)
+	require.NoError(t, err)
+
+	noKeys := abstract.NewTableSchema(abstract.TableColumns{
+		abstract.NewColSchema("status", schema.TypeString, false),
+		abstract.NewColSchema("items", schema.TypeAny, false),
+	})
+	resultSchema, err := tr.ResultSchema(noKeys)
+	require.NoError(t, err)
+	require.Equal(t, []string{"status", "items", "position"}, resultSchema.Columns().ColumnNames())
+	require.False(t, resultSchema.Columns()[2].PrimaryKey)
+
+	deletion := makeItem(orders, ordersSchema, abstract.DeleteKind, nil)
+	deletion.ColumnNames = nil
+	deletion.OldKeys = abstract.OldKeysType{KeyNames: []string{"id"}, KeyTypes: []string{"int64"}, KeyValues: []interface{}{int64(1)}}
+	fullDeletion := makeItem(orders, ordersSchema, abstract.DeleteKind, []interface{}{int64(2), `[10, 20]`, "paid"})
+	// rows of removed elements of the update are unknown, so they can't be deleted
+	update := makeItem(orders, ordersSchema, abstract.UpdateKind, []interface{}{int64(3), `[10]`, "paid"})
+	result := tr.Apply([]abstract.ChangeItem{deletion, fullDeletion, update})
+	require.Len(t, result.Errors, 2)
+	require.Len(t, result.Transformed, 2)
+	require.Equal(t, []interface{}{int64(2), int64(0), int64(10), "paid"}, result.Transformed[0].ColumnValues)
+	require.Equal(t, []interface{}{int64(2), int64(1)}, result.Transformed[1].OldKeys.KeyValues)
+}
+
+func TestUnnestShrinkingArray(t *testing.T) {
+	tr, err := NewFlattenTransformer(Config{
+		Mode:    ModeUnnest,
+		Columns: []string{"items"},
+	}, logger.Log)
+	require.NoError(t, err)
+
+	update := makeItem(orders, ordersSchema, abstract.UpdateKind, []interface{}{int64(1), `["x"]`, "paid"})
+	update.OldKeys = abstract.OldKeysType{
+		KeyNames:  []string{"id", "items"},
+		KeyTypes:  []string{"int64", "any"},
+		KeyValues: []interface{}{int64(1), []interface{}{"a", "b", "c"}},
+	}
+	result := tr.Apply([]abstract.ChangeItem{update})
+	require.Empty(t, result.Errors)
+	require.Len(t, result.Transformed, 3)
+	require.Equal(t, abstract.UpdateKind, result.Transformed[0].Kind)
+	require.Equal(t, []interface{}{int64(1), int64(0), "x", "paid"}, result.Transformed[0].ColumnValues)
+	require.Equal(t, []interface{}{int64(1), int64(0), "a"}, result.Transformed[0].OldKeys.KeyValues)
+	// rows of the removed elements are deleted up to the old length
+	for i, item := range result.Transformed[1:] {
+		require.Equal(t, abstract.DeleteKind, item.Kind)
+		require.Equal(t, []interface{}{int64(1), int64(i + 1), []string{"b", "c"}[i]}, item.OldKeys.KeyValues)
+	}
+}
+
+func TestFlattenErrors(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"No columns":           {},
+		"Unknown mode":         {Mode: "explode", Columns: []string{"payload"}},
+		"Unnest many columns":  {Mode: ModeUnnest, Columns: []string{"payload", "attrs"}},
+		"Repeated columns":     {Columns: []string{"payload", "payload"}},
+		"Negative depth":       {Columns: []string{"payload"}, MaxDepth: -1},
+		"Field of no column":   {Columns: []string{"payload"}, Fields: []Field{{Path: "attrs.a", Type: "int64"}}},
+		"Field without keys":   {Columns: []string{"payload"}, Fields: []Field{{Path: "payload", Type: "int64"}}},
+		"Unknown type":         {Columns: []string{"payload"}, Fields: []Field{{Path: "payload.a", Type: "integer"}}},
+		"Repeated field names": {Columns: []string{"payload"}, Fields: []Field{{Path: "payload.a", Name: "x", Type: "int64"}, {Path: "payload.b", Name: "x", Type: "utf8"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewFlattenTransformer(cfg, logger.Log)
+			require.Error(t, err)
+		})
+	}
+
+	for name, cfg := range map[string]Config{
+		"Missing column":     {Columns: []string{"body"}},
+		"Primary key column": {Columns: []string{"id"}},
+		"Existing column":    {Columns: []string{"payload"}, Fields: []Field{{Path: "payload.a", Name: "attrs", Type: "int64"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tr, err := NewFlattenTransformer(cfg, logger.Log)
+			require.NoError(t, err)
+			require.False(t, tr.Suitable(events, eventsSchema))
+			_, err = tr.ResultSchema(eventsSchema)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package flatten
+
+import (
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/expression"
+)
+
+func (t *FlattenTransformer) flattenItem(item abstract.ChangeItem, p *plan) ([]abstract.ChangeItem, error) {
+	values := toMap(item.ColumnNames, item.ColumnValues)
+	if err := t.flattenValues(values, p); err != nil {
+		return nil, err
+	}
+	// sources which put whole old rows into old keys get them flattened as well
+	oldKeys, err := mapOldKeys(item.OldKeys, p.resultSchema, func(keys map[string]any) error {
+		return t.flattenValues(keys, p)
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("unable to flatten old keys: %w", err)
+	}
+	result := item
+	result.ColumnNames, result.ColumnValues = ordered(p.resultSchema, item.ColumnNames, values)
+	result.OldKeys = oldKeys
+	result.SetTableSchema(p.resultSchema)
+	return []abstract.ChangeItem{result}, nil
+}
+
+func (t *FlattenTransformer) flattenValues(values map[string]any, p *plan) error {
+	for _, column := range t.columns {
+		value, present := values[column]
+		columnFields, resolved := p.fields[column]
+		if !present || !resolved {
+			continue
+		}
+		doc, err := object(value)
+		if err != nil {
+			return xerrors.Errorf("unable to flatten column %s: %w", column, err)
+		}
+		for _, f := range columnFields {
+			fieldValue, err := expression.Convert(lookup(doc, f.path), f.typ)
+			if err != nil {
+				return xerrors.Errorf("unable to convert field %s to %s: %w", f.name, f.typ, err)
+			}
+			values[f.name] = fieldValue
+		}
+		if !t.keepSource {
+			delete(values, column)
+		}
+	}
+	return nil
+}
+
+// unnestItem returns an item per element of the array. Elements removed by updates & deletions are deleted
+// up to the length of the old array if old values are known or up to maxElements otherwise,
+// changes without both are errors
+func (t *FlattenTransformer) unnestItem(item abstract.ChangeItem, p *plan) ([]abstract.ChangeItem, error) {
+	column := t.columns[0]
+	current, hasCurrent, err := elements(item.ColumnNames, item.ColumnValues, column)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to unnest column %s: %w", column, err)
+	}
+	old, hasOld, err := elements(item.OldKeys.KeyNames, item.OldKeys.KeyValues, column)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to unnest old value of column %s: %w", column, err)
+	}
+
+	var result []abstract.ChangeItem
+	switch item.Kind {
+	case abstract.InsertKind, abstract.UpdateKind:
+		if !hasCurrent {
+			if !hasOld {
+				return nil, xerrors.Errorf("%s of table %s has no value of column %s", item.Kind, item.Fqtn(), column)
+			}
+			current = old
+		}
+		if t.maxElements > 0 && len(current) > t.maxElements {
+			return nil, xerrors.Errorf("column %s has %d elements, more than maxElements %d", column, len(current), t.maxElements)
+		}
+		for i := range current {
+			kind := item.Kind
+			if kind == abstract.UpdateKind && hasOld && i >= len(old) {
+				kind = abstract.InsertKind
+			}
+			element, err := t.element(item, p, kind, i, current, old)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, element)
+		}
+		if item.Kind == abstract.UpdateKind {
+			deletions, err := t.deletions(item, p, len(current), old, hasOld)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, deletions...)
+		}
+	case abstract.DeleteKind:
+		if hasCurrent {
+			old, hasOld = current, true
+		}
+		deletions, err := t.deletions(item, p, 0, old, hasOld)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, deletions...)
+	default:
+		return nil, xerrors.Errorf("unable to unnest %s", item.Kind)
+	}
+	return result, nil
+}
+
+// element returns the item of the i-th element, old keys of the item identify the row of the element
+func (t *FlattenTransformer) element(item abstract.ChangeItem, p *plan, kind abstract.Kind, i int, current, old []any) (abstract.ChangeItem, error) {
+	column := t.columns[0]
+	value, err := t.elementValue(current, i, p)
+	if err != nil {
+		return item, xerrors.Errorf("unable to convert element %d of column %s: %w", i, column, err)
+	}
+	values := toMap(item.ColumnNames, item.ColumnValues)
+	values[column] = value
+	values[t.indexColumn] = int64(i)
+
+	result := item
+	result.Kind = kind
+	result.ColumnNames, result.ColumnValues = ordered(p.resultSchema, item.ColumnNames, values)
+	result.OldKeys = abstract.OldKeysType{KeyNames: nil, KeyTypes: nil, KeyValues: nil}
+	if kind != abstract.InsertKind {
+		result.OldKeys, err = t.elementKeys(item.OldKeys, p, i, old)
+		if err != nil {
+			return item, err
+		}
+	}
+	result.SetTableSchema(p.resultSchema)
+	return result, nil
+}
+
+// deletions returns deletions of the elements of the old array starting from the index
+func (t *FlattenTransformer) deletions(item abstract.ChangeItem, p *plan, from int, old []any, hasOld bool) ([]abstract.ChangeItem, error) {
+	end := t.maxElements
+	if hasOld {
+		end = len(old)
+	} else if end == 0 {
+		// rows of removed elements would be orphaned
+		return nil, xerrors.Errorf("unable to delete unnested rows of %s of table %s without the old value of column %s, set maxElements", item.Kind, item.Fqtn(), t.columns[0])
+	}
+	keys := oldKeysOf(item, p)
+	result := make([]abstract.ChangeItem, 0, max(end-from, 0))
+	for i := from; i < end; i++ {
+		deletion := item
+		deletion.Kind = abstract.DeleteKind
+		deletion.ColumnNames, deletion.ColumnValues = nil, nil
+		if item.Kind == abstract.DeleteKind && len(item.ColumnNames) > 0 {
+			// deletions with whole old rows are unnested like the rows
+			values := toMap(item.ColumnNames, item.ColumnValues)
+			if err := t.setElement(values, p, i, old); err != nil {
+				return nil, err
+			}
+			values[t.indexColumn] = int64(i)
+			deletion.ColumnNames, deletion.ColumnValues = ordered(p.resultSchema, item.ColumnNames, values)
+		}
+		var err error
+		deletion.OldKeys, err = t.elementKeys(keys, p, i, old)
+		if err != nil {
+			return nil, err
+		}
+		deletion.SetTableSchema(p.resultSchema)
+		result = append(result, deletion)
+	}
+	return result, nil
+}
+
+// elementKeys returns the old keys of the i-th element, the old element replaces the old array if the keys have it
+func (t *FlattenTransformer) elementKeys(keys abstract.OldKeysType, p *plan, i int, old []any) (abstract.OldKeysType, error) {
+	result, err := mapOldKeys(keys, p.resultSchema, func(values map[string]any) error {
+		if err := t.setElement(values, p, i, old); err != nil {
+			return err
+		}
+		values[t.indexColumn] = int64(i)
+		return nil
+	})
+	if err != nil {
+		return keys, xerrors.Errorf("unable to unnest old keys: %w", err)
+	}
+	return result, nil
+}
+
+// setElement replaces the array with its i-th element in the values which have the array
+func (t *FlattenTransformer) setElement(values map[string]any, p *plan, i int, elements []any) error {
+	column := t.columns[0]
+	if _, ok := values[column]; !ok {
+		return nil
+	}
+	if i >= len(elements) {
+		delete(values, column)
+		return nil
+	}
+	value, err := t.elementValue(elements, i, p)
+	if err != nil {
+		return xerrors.Errorf("unable to convert element %d of column %s: %w", i, column, err)
+	}
+	values[column] = value
+	return nil
+}
+
+func (t *FlattenTransformer) elementValue(elements []any, i int, p *plan) (any, error) {
+	column := t.columns[0]
+	if columnFields, ok := p.fields[column]; ok {
+		return expression.Convert(elements[i], columnFields[0].typ)
+	}
+	return elements[i], nil
+}
+
+// elements returns the array of the column if the values have it
+func elements(names []string, values []any, column string) ([]any, bool, error) {
+	for i, name := range names {
+		if name == column && i < len(values) {
+			result, err := array(values[i])
+			return result, true, err
+		}
+	}
+	return nil, false, nil
+}
+
+// oldKeysOf returns old keys of the item, the keys of its values are used if it has no old keys
+func oldKeysOf(item abstract.ChangeItem, p *plan) abstract.OldKeysType {
+	if len(item.OldKeys.KeyNames) > 0 {
+		return item.OldKeys
+	}
+	values := toMap(item.ColumnNames, item.ColumnValues)
+	result := abstract.OldKeysType{KeyNames: nil, KeyTypes: nil, KeyValues: nil}
+	for _, col := range p.original.Columns() {
+		value, ok := values[col.ColumnName]
+		if !col.PrimaryKey || !ok {
+			continue
+		}
+		result.KeyNames = append(result.KeyNames, col.ColumnName)
+		result.KeyTypes = append(result.KeyTypes, col.DataType)
+		result.KeyValues = append(result.KeyValues, value)
+	}
+	return result
+}
+
+// mapOldKeys transforms old keys as values of a row, types of the new keys are taken from the result schema
+func mapOldKeys(keys abstract.OldKeysType, resultSchema *abstract.TableSchema, transform func(values map[string]any) error) (abstract.OldKeysType, error) {
+	if len(keys.KeyNames) == 0 {
+		return keys, nil
+	}
+	values := toMap(keys.KeyNames, keys.KeyValues)
+	if err := transform(values); err != nil {
+		return keys, err
+	}
+	names, keyValues := ordered(resultSchema, keys.KeyNames, values)
+	var types []string
+	if len(keys.KeyTypes) == len(keys.KeyNames) {
+		originalTypes := make(map[string]string, len(keys.KeyNames))
+		for i, name := range keys.KeyNames {
+			originalTypes[name] = keys.KeyTypes[i]
+		}
+		columnTypes := make(map[string]string, len(resultSchema.Columns()))
+		for _, col := range resultSchema.Columns() {
+			columnTypes[col.ColumnName] = col.DataType
+		}
+		types = make([]string, len(names))
+		for i, name := range names {
+			if typ, ok := originalTypes[name]; ok {
+				types[i] = typ
+			} else {
+				types[i] = columnTypes[name]
+			}
+		}
+	}
+	return abstract.OldKeysType{KeyNames: names, KeyTypes: types, KeyValues: keyValues}, nil
+}
+
+func toMap(names []string, values []any) map[string]any {
+	result := make(map[string]any, len(names))
+	for i, name := range names {
+		if i < len(values) {
+			result[name] = values[i]
+		}
+	}
+	return result
+}
+
+// ordered returns the values in the order of the columns of the schema, values of columns missing in the schema follow them in the original order
+func ordered(tableSchema *abstract.TableSchema, originalNames []string, values map[string]any) ([]string, []any) {
+	names := make([]string, 0, len(values))
+	result := make([]any, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, col := range tableSchema.Columns() {
+		if value, ok := values[col.ColumnName]; ok {
+			names = append(names, col.ColumnName)
+			result = append(result, value)
+			seen[col.ColumnName] = true
+		}
+	}
+	for _, name := range originalNames {
+		if value, ok := values[name]; ok && !seen[name] {
+			names = append(names, name)
+			result = append(result, value)
+			seen[name] = true
+		}
+	}
+	return names, result
+}
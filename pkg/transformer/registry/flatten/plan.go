@@ -0,0 +1,229 @@
+package flatten
+
+import (
+	"slices"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/util/set"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+// planKey identifies a plan, fields are inferred per table since tables may share a schema
+type planKey struct {
+	table abstract.TableID
+	hash  string
+}
+
+// plan is the configuration compiled for a table schema, it's never modified once it's built.
+// fields has the fields of the expanded columns which are declared or already inferred,
+// the other expanded columns are kept as they are until rows with their values are seen
+type plan struct {
+	original     *abstract.TableSchema
+	fields       map[string][]field
+	resultSchema *abstract.TableSchema
+}
+
+func (p *plan) resolved(columns []string) bool {
+	for _, column := range columns {
+		if _, ok := p.fields[column]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *FlattenTransformer) plan(table abstract.TableID, tableSchema *abstract.TableSchema) (*plan, error) {
+	t.plansMu.Lock()
+	defer t.plansMu.Unlock()
+	return t.planLocked(table, tableSchema)
+}
+
+func (t *FlattenTransformer) planLocked(table abstract.TableID, tableSchema *abstract.TableSchema) (*plan, error) {
+	hash, err := tableSchema.Hash()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to hash table schema: %w", err)
+	}
+	key := planKey{table: table, hash: hash}
+	if p, ok := t.plans[key]; ok {
+		return p, nil
+	}
+	p, err := t.newPlan(tableSchema)
+	if err != nil {
+		return nil, err
+	}
+	t.plans[key] = p
+	return p, nil
+}
+
+// resolvedPlan returns the plan of the table of the item, fields which are not declared are inferred from the rows of the input
+func (t *FlattenTransformer) resolvedPlan(item abstract.ChangeItem, input []abstract.ChangeItem) (*plan, error) {
+	t.plansMu.Lock()
+	defer t.plansMu.Unlock()
+	p, err := t.planLocked(item.TableID(), item.TableSchema)
+	if err != nil {
+		return nil, err
+	}
+	if p.resolved(t.columns) {
+		return p, nil
+	}
+	hash, _ := item.TableSchema.Hash()
+	inferred, err := t.infer(p, sample(item.TableID(), hash, input, t.columns, t.sampleSize))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to infer fields: %w", err)
+	}
+	t.plans[planKey{table: item.TableID(), hash: hash}] = inferred
+	return inferred, nil
+}
+
+func (t *FlattenTransformer) newPlan(original *abstract.TableSchema) (*plan, error) {
+	for _, column := range t.columns {
+		index := slices.IndexFunc(original.Columns(), func(c abstract.ColSchema) bool { return c.ColumnName == column })
+		if index < 0 {
+			return nil, xerrors.Errorf("column %s is not found", column)
+		}
+		colSchema := original.Columns()[index]
+		if colSchema.PrimaryKey {
+			return nil, xerrors.Errorf("cannot expand primary key column %s", column)
+		}
+		switch schema.Type(colSchema.DataType) {
+		case schema.TypeAny, schema.TypeString, schema.TypeBytes:
+		default:
+			return nil, xerrors.Errorf("cannot expand column %s of type %s, only any, utf8 and string columns are supported", column, colSchema.DataType)
+		}
+	}
+	fields := make(map[string][]field)
+	for column, declared := range t.declared {
+		fields[column] = declared
+	}
+	return t.newResolvedPlan(original, fields)
+}
+
+func (t *FlattenTransformer) newResolvedPlan(original *abstract.TableSchema, fields map[string][]field) (*plan, error) {
+	var resultSchema *abstract.TableSchema
+	var err error
+	if t.mode == ModeUnnest {
+		resultSchema, err = t.unnestSchema(original, fields)
+	} else {
+		resultSchema, err = t.flattenSchema(original, fields)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &plan{original: original, fields: fields, resultSchema: resultSchema}, nil
+}
+
+// infer returns the plan with fields inferred from the sample, columns without values in the sample stay unresolved
+func (t *FlattenTransformer) infer(p *plan, samples map[string][]any) (*plan, error) {
+	fields := make(map[string][]field, len(t.columns))
+	for column, columnFields := range p.fields {
+		fields[column] = columnFields
+	}
+	for _, column := range t.columns {
+		if _, ok := fields[column]; ok || len(samples[column]) == 0 {
+			continue
+		}
+		if t.mode == ModeUnnest {
+			fields[column] = []field{{path: nil, name: column, typ: inferElementType(samples[column])}}
+			continue
+		}
+		inferred, err := t.inferFields(column, samples[column])
+		if err != nil {
+			return nil, xerrors.Errorf("unable to infer fields of column %s: %w", column, err)
+		}
+		fields[column] = inferred
+	}
+	return t.newResolvedPlan(p.original, fields)
+}
+
+// sample collects documents of the expanded columns of the rows of the table with the schema
+func sample(table abstract.TableID, hash string, input []abstract.ChangeItem, columns []string, size int) map[string][]any {
+	result := make(map[string][]any, len(columns))
+	for _, item := range input {
+		if !item.IsRowEvent() || item.TableID() != table {
+			continue
+		}
+		if itemHash, err := item.TableSchema.Hash(); err != nil || itemHash != hash {
+			continue
+		}
+		for _, column := range columns {
+			index := item.ColumnNameIndex(column)
+			if index < 0 || index >= len(item.ColumnValues) || len(result[column]) >= size {
+				continue
+			}
+			doc, err := document(item.ColumnValues[index])
+			if err != nil || doc == nil {
+				continue
+			}
+			result[column] = append(result[column], doc)
+		}
+	}
+	return result
+}
+
+// flattenSchema replaces each resolved expanded column with its fields
+func (t *FlattenTransformer) flattenSchema(original *abstract.TableSchema, fields map[string][]field) (*abstract.TableSchema, error) {
+	result := make(abstract.TableColumns, 0, len(original.Columns()))
+	for _, col := range original.Columns() {
+		columnFields, resolved := fields[col.ColumnName]
+		if !resolved || t.keepSource {
+			result = append(result, col)
+		}
+		for _, f := range columnFields {
+			colSchema := abstract.NewColSchema(f.name, f.typ, false)
+			colSchema.TableSchema = col.TableSchema
+			colSchema.TableName = col.TableName
+			result = append(result, colSchema)
+		}
+	}
+	if err := checkNames(result); err != nil {
+		return nil, err
+	}
+	return abstract.NewTableSchema(result), nil
+}
+
+// unnestSchema replaces the array column with its element & adds the index of the element which complements the primary key
+func (t *FlattenTransformer) unnestSchema(original *abstract.TableSchema, fields map[string][]field) (*abstract.TableSchema, error) {
+	column := t.columns[0]
+	elementType := schema.TypeAny
+	if columnFields, ok := fields[column]; ok {
+		elementType = columnFields[0].typ
+	}
+	hasKeys := original.Columns().HasPrimaryKey()
+	lastKey := -1
+	for i, col := range original.Columns() {
+		if col.PrimaryKey {
+			lastKey = i
+		}
+	}
+
+	result := make(abstract.TableColumns, 0, len(original.Columns())+1)
+	for i, col := range original.Columns() {
+		if col.ColumnName == column {
+			col.DataType = elementType.String()
+			col.OriginalType = ""
+		}
+		result = append(result, col)
+		if (hasKeys && i == lastKey) || (!hasKeys && col.ColumnName == column) {
+			index := abstract.NewColSchema(t.indexColumn, schema.TypeInt64, hasKeys)
+			index.TableSchema = col.TableSchema
+			index.TableName = col.TableName
+			result = append(result, index)
+		}
+	}
+	if err := checkNames(result); err != nil {
+		return nil, err
+	}
+	return abstract.NewTableSchema(result), nil
+}
+
+func checkNames(columns abstract.TableColumns) error {
+	names := set.New[string]()
+	for _, col := range columns {
+		if names.Contains(col.ColumnName) {
+			return xerrors.Errorf("column %s already exists", col.ColumnName)
+		}
+		names.Add(col.ColumnName)
+	}
+	return nil
+}
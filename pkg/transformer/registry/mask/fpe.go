@@ -0,0 +1,287 @@
+package mask
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"math"
+	"math/big"
+	"slices"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+)
+
+// minDomainSize is the minimal number of distinct values of a numeral string allowed by NIST SP 800-38G
+const minDomainSize = 1_000_000
+
+// numeralCipher encrypts strings of numerals preserving their radix & length
+type numeralCipher interface {
+	encrypt(numerals []uint16) ([]uint16, error)
+	decrypt(numerals []uint16) ([]uint16, error)
+	// minLength & maxLength bound lengths of numeral strings, maxLength is 0 if there is no practical limit
+	minLength() int
+	maxLength() int
+}
+
+// minLength returns the minimal length of numeral strings of the radix having at least minDomainSize values
+func minLength(radix int) int {
+	length := 1
+	for size := radix; size < minDomainSize; size *= radix {
+		length++
+	}
+	return length
+}
+
+// num returns the number represented by the numerals in the radix, the most significant numeral goes first
+func num(numerals []uint16, radix *big.Int) *big.Int {
+	result := new(big.Int)
+	for _, numeral := range numerals {
+		result.Mul(result, radix)
+		result.Add(result, big.NewInt(int64(numeral)))
+	}
+	return result
+}
+
+// str returns the m numerals representing x in the radix, the most significant numeral goes first
+func str(x *big.Int, radix *big.Int, m int) []uint16 {
+	result := make([]uint16, m)
+	x = new(big.Int).Set(x)
+	digit := new(big.Int)
+	for i := m - 1; i >= 0; i-- {
+		x.DivMod(x, radix, digit)
+		result[i] = uint16(digit.Uint64())
+	}
+	return result
+}
+
+// bytesOf returns x as a big-endian byte string of the length, higher bytes of x are dropped
+func bytesOf(x *big.Int, length int) []byte {
+	result := make([]byte, length)
+	data := x.Bytes()
+	if len(data) > length {
+		data = data[len(data)-length:]
+	}
+	copy(result[length-len(data):], data)
+	return result
+}
+
+func xor(dst, a, b []byte) {
+	for i := range dst {
+		dst[i] = a[i] ^ b[i]
+	}
+}
+
+// ff1 is the FF1 mode of NIST SP 800-38G
+type ff1 struct {
+	block cipher.Block
+	radix int
+	tweak []byte
+}
+
+func newFF1(key []byte, radix int, tweak []byte) (*ff1, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid FF1 key: %w", err)
+	}
+	if radix < 2 || radix > math.MaxUint16+1 {
+		return nil, xerrors.Errorf("radix %d is out of range [2, 65536]", radix)
+	}
+	return &ff1{block: block, radix: radix, tweak: tweak}, nil
+}
+
+func (c *ff1) minLength() int {
+	return max(minLength(c.radix), 2)
+}
+
+func (c *ff1) maxLength() int {
+	return 0
+}
+
+// prf is the CBC-MAC of the data with the zero IV, the length of the data is a multiple of the block size
+func (c *ff1) prf(data []byte) []byte {
+	result := make([]byte, aes.BlockSize)
+	for i := 0; i < len(data); i += aes.BlockSize {
+		xor(result, result, data[i:i+aes.BlockSize])
+		c.block.Encrypt(result, result)
+	}
+	return result
+}
+
+func (c *ff1) encrypt(numerals []uint16) ([]uint16, error) {
+	return c.crypt(numerals, true)
+}
+
+func (c *ff1) decrypt(numerals []uint16) ([]uint16, error) {
+	return c.crypt(numerals, false)
+}
+
+func (c *ff1) crypt(numerals []uint16, encrypt bool) ([]uint16, error) {
+	n := len(numerals)
+	if n < c.minLength() {
+		return nil, xerrors.Errorf("%d numerals are too few, FF1 of radix %d requires at least %d", n, c.radix, c.minLength())
+	}
+	u := n / 2
+	v := n - u
+	a, b := slices.Clone(numerals[:u]), slices.Clone(numerals[u:])
+	radix := big.NewInt(int64(c.radix))
+	bLen := int(math.Ceil(math.Ceil(float64(v)*math.Log2(float64(c.radix))) / 8))
+	dLen := 4*((bLen+3)/4) + 4
+	modU := new(big.Int).Exp(radix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(radix, big.NewInt(int64(v)), nil)
+
+	t := len(c.tweak)
+	pad := ((-t-bLen-1)%aes.BlockSize + aes.BlockSize) % aes.BlockSize
+	pq := make([]byte, aes.BlockSize+t+pad+1+bLen)
+	copy(pq, []byte{1, 2, 1, byte(c.radix >> 16), byte(c.radix >> 8), byte(c.radix), 10, byte(u)})
+	binary.BigEndian.PutUint32(pq[8:12], uint32(n))
+	binary.BigEndian.PutUint32(pq[12:16], uint32(t))
+	copy(pq[aes.BlockSize:], c.tweak)
+	q := pq[aes.BlockSize+t+pad:]
+
+	s := make([]byte, ((dLen+aes.BlockSize-1)/aes.BlockSize)*aes.BlockSize)
+	y := new(big.Int)
+	for round := 0; round < 10; round++ {
+		i := round
+		if !encrypt {
+			i = 9 - round
+		}
+		source, target := b, a
+		if !encrypt {
+			source, target = a, b
+		}
+		q[0] = byte(i)
+		copy(q[1:], bytesOf(num(source, radix), bLen))
+		r := c.prf(pq)
+		copy(s, r)
+		for j := 1; j*aes.BlockSize < dLen; j++ {
+			block := s[j*aes.BlockSize : (j+1)*aes.BlockSize]
+			copy(block, r)
+			binary.BigEndian.PutUint64(block[8:], binary.BigEndian.Uint64(r[8:])^uint64(j))
+			c.block.Encrypt(block, block)
+		}
+		y.SetBytes(s[:dLen])
+
+		m, mod := u, modU
+		if i%2 == 1 {
+			m, mod = v, modV
+		}
+		x := num(target, radix)
+		if encrypt {
+			x.Add(x, y)
+		} else {
+			x.Sub(x, y)
+		}
+		x.Mod(x, mod)
+		if encrypt {
+			a, b = b, str(x, radix, m)
+		} else {
+			a, b = str(x, radix, m), a
+		}
+	}
+	return append(a, b...), nil
+}
+
+// ff3 is the FF3-1 mode of NIST SP 800-38G Revision 1, the tweak is split into halves like FF3 does
+type ff3 struct {
+	block  cipher.Block
+	radix  int
+	tweakL []byte
+	tweakR []byte
+}
+
+// newFF31 returns the FF3-1 cipher of the 56-bit tweak
+func newFF31(key []byte, radix int, tweak []byte) (*ff3, error) {
+	if len(tweak) != 7 {
+		return nil, xerrors.Errorf("FF3-1 tweak must be 7 bytes long, got %d", len(tweak))
+	}
+	tweakL := []byte{tweak[0], tweak[1], tweak[2], tweak[3] & 0xf0}
+	tweakR := []byte{tweak[4], tweak[5], tweak[6], tweak[3] << 4}
+	return newFF3(key, radix, tweakL, tweakR)
+}
+
+func newFF3(key []byte, radix int, tweakL, tweakR []byte) (*ff3, error) {
+	reversed := slices.Clone(key)
+	slices.Reverse(reversed)
+	block, err := aes.NewCipher(reversed)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid FF3-1 key: %w", err)
+	}
+	if radix < 2 || radix > math.MaxUint16+1 {
+		return nil, xerrors.Errorf("radix %d is out of range [2, 65536]", radix)
+	}
+	return &ff3{block: block, radix: radix, tweakL: tweakL, tweakR: tweakR}, nil
+}
+
+func (c *ff3) minLength() int {
+	return max(minLength(c.radix), 2)
+}
+
+func (c *ff3) maxLength() int {
+	return 2 * int(math.Floor(96/math.Log2(float64(c.radix))))
+}
+
+func (c *ff3) encrypt(numerals []uint16) ([]uint16, error) {
+	return c.crypt(numerals, true)
+}
+
+func (c *ff3) decrypt(numerals []uint16) ([]uint16, error) {
+	return c.crypt(numerals, false)
+}
+
+func (c *ff3) crypt(numerals []uint16, encrypt bool) ([]uint16, error) {
+	n := len(numerals)
+	if n < c.minLength() || n > c.maxLength() {
+		return nil, xerrors.Errorf("%d numerals are out of range [%d, %d] of FF3-1 of radix %d", n, c.minLength(), c.maxLength(), c.radix)
+	}
+	u := (n + 1) / 2
+	v := n - u
+	a, b := slices.Clone(numerals[:u]), slices.Clone(numerals[u:])
+	radix := big.NewInt(int64(c.radix))
+	modU := new(big.Int).Exp(radix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(radix, big.NewInt(int64(v)), nil)
+
+	p := make([]byte, aes.BlockSize)
+	y := new(big.Int)
+	for round := 0; round < 8; round++ {
+		i := round
+		if !encrypt {
+			i = 7 - round
+		}
+		source, target := b, a
+		if !encrypt {
+			source, target = a, b
+		}
+		m, mod, w := u, modU, c.tweakR
+		if i%2 == 1 {
+			m, mod, w = v, modV, c.tweakL
+		}
+		copy(p, w)
+		p[3] ^= byte(i)
+		copy(p[4:], bytesOf(num(reversedNumerals(source), radix), 12))
+		slices.Reverse(p)
+		c.block.Encrypt(p, p)
+		slices.Reverse(p)
+		y.SetBytes(p)
+
+		x := num(reversedNumerals(target), radix)
+		if encrypt {
+			x.Add(x, y)
+		} else {
+			x.Sub(x, y)
+		}
+		x.Mod(x, mod)
+		result := reversedNumerals(str(x, radix, m))
+		if encrypt {
+			a, b = b, result
+		} else {
+			a, b = result, a
+		}
+	}
+	return append(a, b...), nil
+}
+
+func reversedNumerals(numerals []uint16) []uint16 {
+	result := slices.Clone(numerals)
+	slices.Reverse(result)
+	return result
+}
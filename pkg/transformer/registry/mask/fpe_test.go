@@ -0,0 +1,86 @@
+package mask
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const base36 = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func toNumerals(t *testing.T, value string) []uint16 {
+	result := make([]uint16, 0, len(value))
+	for _, char := range value {
+		index := strings.IndexRune(base36, char)
+		require.GreaterOrEqual(t, index, 0)
+		result = append(result, uint16(index))
+	}
+	return result
+}
+
+func fromNumerals(numerals []uint16) string {
+	var result strings.Builder
+	for _, numeral := range numerals {
+		result.WriteByte(base36[numeral])
+	}
+	return result.String()
+}
+
+func decodeHex(t *testing.T, value string) []byte {
+	result, err := hex.DecodeString(value)
+	require.NoError(t, err)
+	return result
+}
+
+func checkCipher(t *testing.T, cipher numeralCipher, plaintext, ciphertext string) {
+	encrypted, err := cipher.encrypt(toNumerals(t, plaintext))
+	require.NoError(t, err)
+	require.Equal(t, ciphertext, fromNumerals(encrypted))
+	decrypted, err := cipher.decrypt(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, fromNumerals(decrypted))
+}
+
+// Samples of NIST SP 800-38G
+func TestFF1(t *testing.T) {
+	for _, sample := range []struct {
+		key, tweak, plaintext, ciphertext string
+		radix                             int
+	}{
+		{key: "2B7E151628AED2A6ABF7158809CF4F3C", tweak: "", radix: 10, plaintext: "0123456789", ciphertext: "2433477484"},
+		{key: "2B7E151628AED2A6ABF7158809CF4F3C", tweak: "39383736353433323130", radix: 10, plaintext: "0123456789", ciphertext: "6124200773"},
+		{key: "2B7E151628AED2A6ABF7158809CF4F3C", tweak: "3737373770717273373737", radix: 36, plaintext: "0123456789abcdefghi", ciphertext: "a9tv40mll9kdu509eum"},
+		{key: "2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F7F036D6F04FC6A94", tweak: "", radix: 10, plaintext: "0123456789", ciphertext: "6657667009"},
+	} {
+		cipher, err := newFF1(decodeHex(t, sample.key), sample.radix, decodeHex(t, sample.tweak))
+		require.NoError(t, err)
+		checkCipher(t, cipher, sample.plaintext, sample.ciphertext)
+	}
+}
+
+func TestFF3(t *testing.T) {
+	// FF3-1 differs from FF3 in the tweak only, so the sample of FF3 checks the rounds
+	tweak := decodeHex(t, "D8E7920AFA330A73")
+	cipher, err := newFF3(decodeHex(t, "EF4359D8D580AA4F7F036D6F04FC6A94"), 10, tweak[:4], tweak[4:])
+	require.NoError(t, err)
+	checkCipher(t, cipher, "890121234567890000", "750918814058654607")
+
+	cipher, err = newFF31(decodeHex(t, "EF4359D8D580AA4F7F036D6F04FC6A94"), 10, decodeHex(t, "D8E7920AFA330A"))
+	require.NoError(t, err)
+	encrypted, err := cipher.encrypt(toNumerals(t, "890121234567890000"))
+	require.NoError(t, err)
+	decrypted, err := cipher.decrypt(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, "890121234567890000", fromNumerals(decrypted))
+
+	require.Equal(t, 6, cipher.minLength())
+	require.Equal(t, 56, cipher.maxLength())
+	_, err = cipher.encrypt(toNumerals(t, "12345"))
+	require.Error(t, err)
+	_, err = cipher.encrypt(toNumerals(t, strings.Repeat("1", 57)))
+	require.Error(t, err)
+	_, err = newFF31(decodeHex(t, "EF4359D8D580AA4F7F036D6F04FC6A94"), 10, tweak)
+	require.Error(t, err)
+}
@@ -0,0 +1,167 @@
+package mask
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/transformer/registry/filter"
+	"github.com/transferia/transferia/pkg/util/set"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+// FunctionTransformer applies a mask function, or its reverse, to columns of rows and their old keys
+type FunctionTransformer struct {
+	Tables   filter.Filter
+	Columns  *set.Set[string]
+	function function
+	typ      abstract.TransformerType
+	lgr      log.Logger
+}
+
+func (ft *FunctionTransformer) Type() abstract.TransformerType {
+	return ft.typ
+}
+
+func (ft *FunctionTransformer) Apply(input []abstract.ChangeItem) abstract.TransformerResult {
+	transformed := make([]abstract.ChangeItem, 0, len(input))
+	errors := make([]abstract.TransformerError, 0)
+
+	for _, ci := range input {
+		if !ci.IsRowEvent() {
+			transformed = append(transformed, ci)
+			continue
+		}
+		result, err := ft.applyItem(ci)
+		if err != nil {
+			errors = append(errors, abstract.TransformerError{Input: ci, Error: err})
+			continue
+		}
+		transformed = append(transformed, result)
+	}
+
+	return abstract.TransformerResult{
+		Transformed: transformed,
+		Errors:      errors,
+	}
+}
+
+func (ft *FunctionTransformer) applyItem(ci abstract.ChangeItem) (abstract.ChangeItem, error) {
+	resultSchema, err := ft.ResultSchema(ci.TableSchema)
+	if err != nil {
+		return ci, err
+	}
+	values, err := ft.applyValues(ci.ColumnNames, ci.ColumnValues, row{
+		names:          ci.ColumnNames,
+		values:         ci.ColumnValues,
+		fallbackNames:  ci.OldKeys.KeyNames,
+		fallbackValues: ci.OldKeys.KeyValues,
+		tableSchema:    ci.TableSchema,
+	})
+	if err != nil {
+		return ci, err
+	}
+	keyValues, err := ft.applyValues(ci.OldKeys.KeyNames, ci.OldKeys.KeyValues, row{
+		names:          ci.OldKeys.KeyNames,
+		values:         ci.OldKeys.KeyValues,
+		fallbackNames:  ci.ColumnNames,
+		fallbackValues: ci.ColumnValues,
+		tableSchema:    ci.TableSchema,
+	})
+	if err != nil {
+		return ci, xerrors.Errorf("unable to mask old keys: %w", err)
+	}
+
+	result := ci
+	result.ColumnValues = values
+	result.OldKeys = abstract.OldKeysType{
+		KeyNames:  ci.OldKeys.KeyNames,
+		KeyTypes:  ci.OldKeys.KeyTypes,
+		KeyValues: keyValues,
+	}
+	if len(ci.OldKeys.KeyTypes) == len(ci.OldKeys.KeyNames) {
+		resultColumns := resultSchema.FastColumns()
+		result.OldKeys.KeyTypes = make([]string, len(ci.OldKeys.KeyTypes))
+		for i, name := range ci.OldKeys.KeyNames {
+			result.OldKeys.KeyTypes[i] = ci.OldKeys.KeyTypes[i]
+			if col, ok := resultColumns[abstract.ColumnName(name)]; ok && ft.Columns.Contains(name) {
+				result.OldKeys.KeyTypes[i] = col.DataType
+			}
+		}
+	}
+	result.SetTableSchema(resultSchema)
+	return result, nil
+}
+
+// applyValues returns a copy of the values with the function applied to the columns, the row is read before any changes
+func (ft *FunctionTransformer) applyValues(names []string, values []any, r row) ([]any, error) {
+	if len(values) == 0 {
+		return values, nil
+	}
+	fastCols := r.tableSchema.FastColumns()
+	result := make([]any, len(values))
+	copy(result, values)
+	for i, name := range names {
+		if i >= len(values) || !ft.Columns.Contains(name) {
+			continue
+		}
+		col, ok := fastCols[abstract.ColumnName(name)]
+		if !ok {
+			return nil, xerrors.Errorf("column %s is not found in the table schema", name)
+		}
+		value, err := ft.function.apply(values[i], col, r)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to apply %s to column %s: %w", ft.function.description(), name, err)
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+func (ft *FunctionTransformer) Suitable(table abstract.TableID, schema *abstract.TableSchema) bool {
+	if !filter.MatchAnyTableNameVariant(ft.Tables, table) {
+		return false
+	}
+	hasColumns := false
+	for _, colSchema := range schema.Columns() {
+		if ft.Columns.Contains(colSchema.ColumnName) {
+			hasColumns = true
+			break
+		}
+	}
+	if !hasColumns {
+		return false
+	}
+	_, err := ft.ResultSchema(schema)
+	return err == nil
+}
+
+func (ft *FunctionTransformer) ResultSchema(original *abstract.TableSchema) (*abstract.TableSchema, error) {
+	result := make([]abstract.ColSchema, 0, len(original.Columns()))
+	for _, col := range original.Columns() {
+		if ft.Columns.Contains(col.ColumnName) {
+			var err error
+			col, err = ft.function.column(col, original)
+			if err != nil {
+				return nil, xerrors.Errorf("unable to apply %s: %w", ft.function.description(), err)
+			}
+		}
+		result = append(result, col)
+	}
+	return abstract.NewTableSchema(result), nil
+}
+
+func (ft *FunctionTransformer) Description() string {
+	tablesIncluded := strings.Join(ft.Tables.IncludeRegexp, ",")
+	tablesExcluded := strings.Join(ft.Tables.ExcludeRegexp, ",")
+	columnsIncluded := strings.Join(ft.Columns.SortedSliceFunc(func(a, b string) bool { return a < b }), ",")
+
+	action := "Mask"
+	if ft.typ == UnmaskTransformerType {
+		action = "Unmask"
+	}
+	return fmt.Sprintf("%s table columns with %s: columns: %s, "+
+		"included tables: %s, excluded tables: %s", action, ft.function.description(),
+		columnsIncluded, tablesIncluded, tablesExcluded)
+}
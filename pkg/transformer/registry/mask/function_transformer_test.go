@@ -0,0 +1,218 @@
+package mask
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/pkg/abstract"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+var (
+	customers       = *abstract.NewTableID("db", "customers")
+	customersSchema = abstract.NewTableSchema(abstract.TableColumns{
+		abstract.MakeTypedColSchema("id", string(schema.TypeInt64), true),
+		abstract.MakeTypedColSchema("phone", string(schema.TypeString), false),
+		abstract.MakeTypedColSchema("email", string(schema.TypeString), false),
+		abstract.MakeTypedColSchema("birthday", string(schema.TypeDate), false),
+		abstract.MakeTypedColSchema("signed_up", string(schema.TypeTimestamp), false),
+	})
+)
+
+func makeCustomer(kind abstract.Kind, id int64, phone string, email any, birthday, signedUp time.Time) abstract.ChangeItem {
+	return abstract.ChangeItem{
+		Kind:         kind,
+		Schema:       customers.Namespace,
+		Table:        customers.Name,
+		ColumnNames:  customersSchema.Columns().ColumnNames(),
+		ColumnValues: []interface{}{id, phone, email, birthday, signedUp},
+		TableSchema:  customersSchema,
+	}
+}
+
+// writeKeys writes a key file of keys of the length filled with their IDs
+func writeKeys(t *testing.T, length int, ids ...string) string {
+	encoded := make([]string, 0, len(ids))
+	for _, id := range ids {
+		encoded = append(encoded, fmt.Sprintf("%q: %q", id, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte(id[:1]), length))))
+	}
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"+strings.Join(encoded, ", ")+"}"), 0o600))
+	return path
+}
+
+func applyOne(t *testing.T, tr abstract.Transformer, item abstract.ChangeItem) abstract.ChangeItem {
+	require.True(t, tr.Suitable(item.TableID(), item.TableSchema))
+	result := tr.Apply([]abstract.ChangeItem{item})
+	require.Empty(t, result.Errors)
+	require.Len(t, result.Transformed, 1)
+	return result.Transformed[0]
+}
+
+func TestFPE(t *testing.T) {
+	fpe := &MaskFunctionFPE{
+		Keys:      Keys{KeyFile: writeKeys(t, 32, "a"), KeyEnv: "", KeyID: ""},
+		Algorithm: AlgorithmFF31,
+		Alphabet:  "",
+		Tweak:     "",
+	}
+	masker, err := NewMaskTransformer(Config{MaskFunctionFPE: fpe, Columns: []string{"phone"}}, logger.Log)
+	require.NoError(t, err)
+	unmasker, err := NewUnmaskTransformer(UnmaskConfig{MaskFunctionFPE: fpe, Columns: []string{"phone"}}, logger.Log)
+	require.NoError(t, err)
+
+	day := time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC)
+	item := makeCustomer(abstract.UpdateKind, 1, "+1 (555) 010-9999", "ada@example.com", day, day)
+	item.OldKeys = abstract.OldKeysType{KeyNames: []string{"id", "phone"}, KeyTypes: []string{"int64", "utf8"}, KeyValues: []interface{}{int64(1), "+1 (555) 010-9999"}}
+	masked := applyOne(t, masker, item)
+	phone := masked.ColumnValues[1].(string)
+	require.NotEqual(t, "+1 (555) 010-9999", phone)
+	require.Regexp(t, `^\+\d \(\d{3}\) \d{3}-\d{4}$`, phone)
+	require.Equal(t, phone, masked.OldKeys.KeyValues[1])
+	require.Equal(t, "ada@example.com", masked.ColumnValues[2])
+	require.Equal(t, "+1 (555) 010-9999", item.ColumnValues[1])
+
+	unmasked := applyOne(t, unmasker, masked)
+	require.Equal(t, item.ColumnValues, unmasked.ColumnValues)
+	require.Equal(t, item.OldKeys, unmasked.OldKeys)
+
+	result := masker.Apply([]abstract.ChangeItem{makeCustomer(abstract.InsertKind, 2, "010-99", nil, day, day)})
+	require.Len(t, result.Errors, 1)
+}
+
+func TestSIV(t *testing.T) {
+	keyFile := writeKeys(t, 64, "old", "new")
+	siv := func(keyID string) *MaskFunctionSIV {
+		return &MaskFunctionSIV{Keys: Keys{KeyFile: keyFile, KeyEnv: "", KeyID: keyID}}
+	}
+	oldMasker, err := NewMaskTransformer(Config{MaskFunctionSIV: siv("old"), Columns: []string{"email", "birthday"}}, logger.Log)
+	require.NoError(t, err)
+	newMasker, err := NewMaskTransformer(Config{MaskFunctionSIV: siv("new"), Columns: []string{"email", "birthday"}}, logger.Log)
+	require.NoError(t, err)
+	unmasker, err := NewUnmaskTransformer(UnmaskConfig{MaskFunctionSIV: siv("new"), Columns: []string{"email", "birthday"}}, logger.Log)
+	require.NoError(t, err)
+
+	resultSchema, err := oldMasker.ResultSchema(customersSchema)
+	require.NoError(t, err)
+	require.Equal(t, string(schema.TypeString), resultSchema.Columns()[3].DataType)
+
+	day := time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC)
+	first := applyOne(t, oldMasker, makeCustomer(abstract.InsertKind, 1, "", "ada@example.com", day, day))
+	second := applyOne(t, oldMasker, makeCustomer(abstract.InsertKind, 2, "", "ada@example.com", day, day))
+	rotated := applyOne(t, newMasker, makeCustomer(abstract.InsertKind, 3, "", "ada@example.com", day, day))
+	require.Equal(t, first.ColumnValues[2], second.ColumnValues[2])
+	require.True(t, strings.HasPrefix(first.ColumnValues[2].(string), "old:"))
+	require.True(t, strings.HasPrefix(rotated.ColumnValues[2].(string), "new:"))
+	require.Equal(t, resultSchema.Columns(), first.TableSchema.Columns())
+
+	for _, masked := range []abstract.ChangeItem{first, rotated} {
+		unmasked := applyOne(t, unmasker, masked)
+		require.Equal(t, "ada@example.com", unmasked.ColumnValues[2])
+		require.Equal(t, "2024-05-02", unmasked.ColumnValues[3])
+	}
+
+	tampered := first
+	tampered.ColumnValues = []interface{}{int64(1), "", "old:AAAA", "old:AAAA", day}
+	require.Len(t, unmasker.Apply([]abstract.ChangeItem{tampered}).Errors, 1)
+}
+
+func TestDateShift(t *testing.T) {
+	t.Setenv("MASK_TEST_KEYS", `{"2024": "c2VjcmV0"}`)
+	dateShift := &MaskFunctionDateShift{
+		Keys:        Keys{KeyFile: "", KeyEnv: "MASK_TEST_KEYS", KeyID: "2024"},
+		MaxDays:     30,
+		SeedColumns: nil,
+	}
+	masker, err := NewMaskTransformer(Config{MaskFunctionDateShift: dateShift, Columns: []string{"birthday", "signed_up"}}, logger.Log)
+	require.NoError(t, err)
+	unmasker, err := NewUnmaskTransformer(UnmaskConfig{MaskFunctionDateShift: dateShift, Columns: []string{"birthday", "signed_up"}}, logger.Log)
+	require.NoError(t, err)
+
+	birthday := time.Date(1990, time.March, 4, 0, 0, 0, 0, time.UTC)
+	signedUp := time.Date(2024, time.May, 2, 10, 30, 0, 0, time.UTC)
+	item := makeCustomer(abstract.InsertKind, 1, "", nil, birthday, signedUp)
+	masked := applyOne(t, masker, item)
+	shifted := masked.ColumnValues[3].(time.Time)
+	require.LessOrEqual(t, shifted.Sub(birthday).Abs(), 30*24*time.Hour)
+	require.Equal(t, signedUp.Sub(birthday), masked.ColumnValues[4].(time.Time).Sub(shifted))
+	require.Equal(t, item.ColumnValues, applyOne(t, unmasker, masked).ColumnValues)
+
+	shifts := map[time.Time]bool{}
+	for id := int64(0); id < 20; id++ {
+		shifts[applyOne(t, masker, makeCustomer(abstract.InsertKind, id, "", nil, birthday, signedUp)).ColumnValues[3].(time.Time)] = true
+	}
+	require.Greater(t, len(shifts), 1)
+}
+
+func TestPartialAndNull(t *testing.T) {
+	partial, err := NewMaskTransformer(Config{
+		MaskFunctionPartial: &MaskFunctionPartial{KeepFirst: 0, KeepLast: 4, MaskChar: ""},
+		Columns:             []string{"phone", "birthday"},
+	}, logger.Log)
+	require.NoError(t, err)
+	null, err := NewMaskTransformer(Config{MaskFunctionNull: &MaskFunctionNull{}, Columns: []string{"email"}}, logger.Log)
+	require.NoError(t, err)
+
+	day := time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC)
+	masked := applyOne(t, partial, makeCustomer(abstract.InsertKind, 1, "4111-1111-1111-1234", "ada@example.com", day, day))
+	require.Equal(t, "***************1234", masked.ColumnValues[1])
+	require.Equal(t, "******5-02", masked.ColumnValues[3])
+	require.Equal(t, string(schema.TypeString), masked.TableSchema.Columns()[3].DataType)
+	masked = applyOne(t, partial, makeCustomer(abstract.InsertKind, 1, "123", "ada@example.com", day, day))
+	require.Equal(t, "***", masked.ColumnValues[1])
+
+	masked = applyOne(t, null, makeCustomer(abstract.InsertKind, 1, "123", "ada@example.com", day, day))
+	require.Equal(t, []interface{}{int64(1), "123", nil, day, day}, masked.ColumnValues)
+}
+
+func TestMaskFunctionErrors(t *testing.T) {
+	keyFile := writeKeys(t, 32, "a", "b")
+	for name, cfg := range map[string]Config{
+		"Two functions":    {MaskFunctionNull: &MaskFunctionNull{}, MaskFunctionPartial: &MaskFunctionPartial{}, Columns: []string{"email"}},
+		"Hash and null":    {MaskFunctionHash: MaskFunctionHash{UserDefinedSalt: "salt"}, MaskFunctionNull: &MaskFunctionNull{}, Columns: []string{"email"}},
+		"No columns":       {MaskFunctionNull: &MaskFunctionNull{}},
+		"No key ID":        {MaskFunctionFPE: &MaskFunctionFPE{Keys: Keys{KeyFile: keyFile}}, Columns: []string{"phone"}},
+		"Unknown key ID":   {MaskFunctionFPE: &MaskFunctionFPE{Keys: Keys{KeyFile: keyFile, KeyID: "c"}}, Columns: []string{"phone"}},
+		"No keys":          {MaskFunctionFPE: &MaskFunctionFPE{}, Columns: []string{"phone"}},
+		"Missing env":      {MaskFunctionFPE: &MaskFunctionFPE{Keys: Keys{KeyEnv: "MASK_TEST_MISSING_KEYS"}}, Columns: []string{"phone"}},
+		"Short SIV key":    {MaskFunctionSIV: &MaskFunctionSIV{Keys: Keys{KeyFile: keyFile, KeyID: "a"}}, Columns: []string{"email"}},
+		"Unknown FPE":      {MaskFunctionFPE: &MaskFunctionFPE{Keys: Keys{KeyFile: keyFile, KeyID: "a"}, Algorithm: "ff2"}, Columns: []string{"phone"}},
+		"Shifted seed":     {MaskFunctionDateShift: &MaskFunctionDateShift{Keys: Keys{KeyFile: keyFile, KeyID: "a"}, MaxDays: 10, SeedColumns: []string{"birthday"}}, Columns: []string{"birthday"}},
+		"Zero shift":       {MaskFunctionDateShift: &MaskFunctionDateShift{Keys: Keys{KeyFile: keyFile, KeyID: "a"}}, Columns: []string{"birthday"}},
+		"Negative partial": {MaskFunctionPartial: &MaskFunctionPartial{KeepLast: -1}, Columns: []string{"phone"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewMaskTransformer(cfg, logger.Log)
+			require.Error(t, err)
+		})
+	}
+
+	for name, cfg := range map[string]Config{
+		"Partial primary key":  {MaskFunctionPartial: &MaskFunctionPartial{KeepLast: 4}, Columns: []string{"id"}},
+		"Null primary key":     {MaskFunctionNull: &MaskFunctionNull{}, Columns: []string{"id"}},
+		"FPE of dates":         {MaskFunctionFPE: &MaskFunctionFPE{Keys: Keys{KeyFile: keyFile, KeyID: "a"}}, Columns: []string{"birthday"}},
+		"Shift of strings":     {MaskFunctionDateShift: &MaskFunctionDateShift{Keys: Keys{KeyFile: keyFile, KeyID: "a"}, MaxDays: 10}, Columns: []string{"email"}},
+		"Shift of primary key": {MaskFunctionDateShift: &MaskFunctionDateShift{Keys: Keys{KeyFile: keyFile, KeyID: "a"}, MaxDays: 10}, Columns: []string{"id", "birthday"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tr, err := NewMaskTransformer(cfg, logger.Log)
+			require.NoError(t, err)
+			require.False(t, tr.Suitable(customers, customersSchema))
+			_, err = tr.ResultSchema(customersSchema)
+			require.Error(t, err)
+		})
+	}
+
+	t.Run("Irreversible unmask", func(t *testing.T) {
+		_, err := NewUnmaskTransformer(UnmaskConfig{Columns: []string{"email"}}, logger.Log)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,457 @@
+package mask
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	daead "github.com/google/tink/go/daead/subtle"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	tostring "github.com/transferia/transferia/pkg/transformer/registry/to_string"
+	"github.com/transferia/transferia/pkg/util/set"
+	ytschema "go.ytsaurus.tech/yt/go/schema"
+)
+
+const (
+	AlgorithmFF1  = "ff1"
+	AlgorithmFF31 = "ff3-1"
+
+	defaultAlphabet = "0123456789"
+	defaultMaskChar = "*"
+	// sivSeparator separates the key ID and the ciphertext of values encrypted with AES-SIV
+	sivSeparator = ":"
+)
+
+// MaskFunctionFPE encrypts characters of the alphabet with format-preserving encryption, other characters are kept in place,
+// so `+1 (555) 010-9999` is encrypted into something like `+7 (093) 822-4170`. Values must have at least 6 digits
+type MaskFunctionFPE struct {
+	// Keys are AES keys of 16, 24 or 32 bytes
+	Keys Keys `json:"keys"`
+	// Algorithm is ff1 (default) or ff3-1
+	Algorithm string `json:"algorithm"`
+	// Alphabet is the characters which are encrypted, digits by default
+	Alphabet string `json:"alphabet"`
+	// Tweak is a hex-encoded public value mixed into the encryption, FF3-1 requires 7 bytes and uses zero bytes by default
+	Tweak string `json:"tweak"`
+}
+
+// MaskFunctionSIV encrypts values deterministically with AES-SIV, so equal values have equal ciphertexts and encrypted columns can be joined.
+// Values are encrypted as text and become `<key ID>:<base64 ciphertext>` utf8 values
+type MaskFunctionSIV struct {
+	// Keys are AES-SIV keys of 64 bytes
+	Keys Keys `json:"keys"`
+}
+
+// MaskFunctionPartial replaces all characters of values but the first and the last ones, e.g. `****1234`
+type MaskFunctionPartial struct {
+	KeepFirst int `json:"keepFirst"`
+	KeepLast  int `json:"keepLast"`
+	// MaskChar replaces hidden characters, `*` by default
+	MaskChar string `json:"maskChar"`
+}
+
+// MaskFunctionDateShift shifts dates and times by a number of days derived from the seed columns of the row,
+// so dates of the same entity keep their intervals
+type MaskFunctionDateShift struct {
+	// Keys are secret keys of any length the shifts are derived with
+	Keys Keys `json:"keys"`
+	// MaxDays is the maximal shift in days back or forth
+	MaxDays int `json:"maxDays"`
+	// SeedColumns are the columns whose values choose the shift, the primary key by default
+	SeedColumns []string `json:"seedColumns"`
+}
+
+// MaskFunctionNull replaces values with NULL
+type MaskFunctionNull struct{}
+
+// row is a row with the values being masked, values missing in names are looked up in fallback names
+type row struct {
+	names          []string
+	values         []any
+	fallbackNames  []string
+	fallbackValues []any
+	tableSchema    *abstract.TableSchema
+}
+
+func (r row) value(name string) (any, bool) {
+	for i, n := range r.names {
+		if n == name && i < len(r.values) {
+			return r.values[i], true
+		}
+	}
+	for i, n := range r.fallbackNames {
+		if n == name && i < len(r.fallbackValues) {
+			return r.fallbackValues[i], true
+		}
+	}
+	return nil, false
+}
+
+// function masks or unmasks values of columns
+type function interface {
+	// column returns the schema of the column with the values of the function
+	column(col abstract.ColSchema, tableSchema *abstract.TableSchema) (abstract.ColSchema, error)
+	apply(value any, col abstract.ColSchema, r row) (any, error)
+	description() string
+}
+
+type fpeFunction struct {
+	cipher    numeralCipher
+	algorithm string
+	alphabet  []rune
+	numerals  map[rune]uint16
+	unmask    bool
+}
+
+func newFPEFunction(config MaskFunctionFPE, unmask bool) (*fpeFunction, error) {
+	keys, err := loadKeys(config.Keys)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to load keys: %w", err)
+	}
+	alphabet := []rune(config.Alphabet)
+	if len(alphabet) == 0 {
+		alphabet = []rune(defaultAlphabet)
+	}
+	numerals := make(map[rune]uint16, len(alphabet))
+	for i, char := range alphabet {
+		if _, ok := numerals[char]; ok {
+			return nil, xerrors.Errorf("character %q is repeated in the alphabet", char)
+		}
+		numerals[char] = uint16(i)
+	}
+	tweak, err := hex.DecodeString(config.Tweak)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to decode tweak: %w", err)
+	}
+
+	algorithm := strings.ToLower(config.Algorithm)
+	var cipher numeralCipher
+	switch algorithm {
+	case "", AlgorithmFF1:
+		algorithm = AlgorithmFF1
+		cipher, err = newFF1(keys.active(), len(alphabet), tweak)
+	case AlgorithmFF31:
+		if config.Tweak == "" {
+			tweak = make([]byte, 7)
+		}
+		cipher, err = newFF31(keys.active(), len(alphabet), tweak)
+	default:
+		return nil, xerrors.Errorf("unknown algorithm %s, %s and %s are supported", config.Algorithm, AlgorithmFF1, AlgorithmFF31)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &fpeFunction{cipher: cipher, algorithm: algorithm, alphabet: alphabet, numerals: numerals, unmask: unmask}, nil
+}
+
+func (f *fpeFunction) column(col abstract.ColSchema, _ *abstract.TableSchema) (abstract.ColSchema, error) {
+	switch ytschema.Type(col.DataType) {
+	case ytschema.TypeString, ytschema.TypeBytes:
+		return col, nil
+	}
+	return col, xerrors.Errorf("column %s of type %s cannot be encrypted with %s, only utf8 and string columns are supported", col.ColumnName, col.DataType, f.algorithm)
+}
+
+func (f *fpeFunction) apply(value any, _ abstract.ColSchema, _ row) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return f.crypt(v)
+	case []byte:
+		result, err := f.crypt(string(v))
+		return []byte(result), err
+	}
+	return nil, xerrors.Errorf("value of type %T cannot be encrypted with %s", value, f.algorithm)
+}
+
+// crypt encrypts or decrypts the characters of the alphabet leaving other characters in place
+func (f *fpeFunction) crypt(value string) (string, error) {
+	chars := []rune(value)
+	numerals := make([]uint16, 0, len(chars))
+	for _, char := range chars {
+		if numeral, ok := f.numerals[char]; ok {
+			numerals = append(numerals, numeral)
+		}
+	}
+	var result []uint16
+	var err error
+	if f.unmask {
+		result, err = f.cipher.decrypt(numerals)
+	} else {
+		result, err = f.cipher.encrypt(numerals)
+	}
+	if err != nil {
+		return "", xerrors.Errorf("value has %d characters of the alphabet: %w", len(numerals), err)
+	}
+	for i, j := 0, 0; i < len(chars); i++ {
+		if _, ok := f.numerals[chars[i]]; ok {
+			chars[i] = f.alphabet[result[j]]
+			j++
+		}
+	}
+	return string(chars), nil
+}
+
+func (f *fpeFunction) description() string {
+	return fmt.Sprintf("%s encryption", strings.ToUpper(f.algorithm))
+}
+
+type sivFunction struct {
+	keys    *keyring
+	ciphers map[string]*daead.AESSIV
+	unmask  bool
+}
+
+func newSIVFunction(config MaskFunctionSIV, unmask bool) (*sivFunction, error) {
+	keys, err := loadKeys(config.Keys)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to load keys: %w", err)
+	}
+	ciphers := make(map[string]*daead.AESSIV, len(keys.keys))
+	for id, key := range keys.keys {
+		cipher, err := daead.NewAESSIV(key)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid AES-SIV key %s: %w", id, err)
+		}
+		ciphers[id] = cipher
+	}
+	return &sivFunction{keys: keys, ciphers: ciphers, unmask: unmask}, nil
+}
+
+func (f *sivFunction) column(col abstract.ColSchema, _ *abstract.TableSchema) (abstract.ColSchema, error) {
+	col.DataType = ytschema.TypeString.String()
+	col.OriginalType = ""
+	return col, nil
+}
+
+func (f *sivFunction) apply(value any, col abstract.ColSchema, _ row) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if f.unmask {
+		return f.decrypt(value)
+	}
+	ciphertext, err := f.ciphers[f.keys.id].EncryptDeterministically([]byte(tostring.SerializeToString(value, col.DataType)), nil)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to encrypt value: %w", err)
+	}
+	return f.keys.id + sivSeparator + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// decrypt decrypts the value with the key it was encrypted with
+func (f *sivFunction) decrypt(value any) (any, error) {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return nil, xerrors.Errorf("value of type %T is not encrypted with AES-SIV", value)
+	}
+	id, encoded, ok := strings.Cut(text, sivSeparator)
+	if !ok {
+		return nil, xerrors.New("value has no key ID, values encrypted with AES-SIV look like <key ID>:<ciphertext>")
+	}
+	cipher, ok := f.ciphers[id]
+	if !ok {
+		return nil, xerrors.Errorf("key %s of the value is not found", id)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to decode ciphertext: %w", err)
+	}
+	plaintext, err := cipher.DecryptDeterministically(ciphertext, nil)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func (f *sivFunction) description() string {
+	return "AES-SIV encryption"
+}
+
+type partialFunction struct {
+	keepFirst int
+	keepLast  int
+	maskChar  rune
+}
+
+func newPartialFunction(config MaskFunctionPartial) (*partialFunction, error) {
+	if config.KeepFirst < 0 || config.KeepLast < 0 {
+		return nil, xerrors.New("keepFirst and keepLast cannot be negative")
+	}
+	maskChar := []rune(config.MaskChar)
+	if len(maskChar) == 0 {
+		maskChar = []rune(defaultMaskChar)
+	}
+	if len(maskChar) != 1 {
+		return nil, xerrors.Errorf("maskChar %q must be a single character", config.MaskChar)
+	}
+	return &partialFunction{keepFirst: config.KeepFirst, keepLast: config.KeepLast, maskChar: maskChar[0]}, nil
+}
+
+func (f *partialFunction) column(col abstract.ColSchema, _ *abstract.TableSchema) (abstract.ColSchema, error) {
+	if col.PrimaryKey {
+		return col, xerrors.Errorf("primary key column %s cannot be masked partially", col.ColumnName)
+	}
+	switch ytschema.Type(col.DataType) {
+	case ytschema.TypeString, ytschema.TypeBytes:
+	default:
+		col.DataType = ytschema.TypeString.String()
+		col.OriginalType = ""
+	}
+	return col, nil
+}
+
+func (f *partialFunction) apply(value any, col abstract.ColSchema, _ row) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return f.mask(v), nil
+	case []byte:
+		return []byte(f.mask(string(v))), nil
+	}
+	return f.mask(tostring.SerializeToString(value, col.DataType)), nil
+}
+
+// mask hides all characters of values which are not longer than the kept characters
+func (f *partialFunction) mask(value string) string {
+	chars := []rune(value)
+	if len(chars) <= f.keepFirst+f.keepLast {
+		return strings.Repeat(string(f.maskChar), len(chars))
+	}
+	for i := f.keepFirst; i < len(chars)-f.keepLast; i++ {
+		chars[i] = f.maskChar
+	}
+	return string(chars)
+}
+
+func (f *partialFunction) description() string {
+	return fmt.Sprintf("partial masking keeping %d first and %d last characters", f.keepFirst, f.keepLast)
+}
+
+type dateShiftFunction struct {
+	key         []byte
+	maxDays     int
+	seedColumns []string
+	columns     *set.Set[string]
+	unmask      bool
+}
+
+func newDateShiftFunction(config MaskFunctionDateShift, columns *set.Set[string], unmask bool) (*dateShiftFunction, error) {
+	keys, err := loadKeys(config.Keys)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to load keys: %w", err)
+	}
+	if config.MaxDays <= 0 {
+		return nil, xerrors.New("maxDays must be positive")
+	}
+	for _, seed := range config.SeedColumns {
+		if columns.Contains(seed) {
+			return nil, xerrors.Errorf("seed column %s cannot be shifted", seed)
+		}
+	}
+	return &dateShiftFunction{key: keys.active(), maxDays: config.MaxDays, seedColumns: config.SeedColumns, columns: columns, unmask: unmask}, nil
+}
+
+// seeds returns the seed columns of the table
+func (f *dateShiftFunction) seeds(tableSchema *abstract.TableSchema) []string {
+	if len(f.seedColumns) > 0 {
+		return f.seedColumns
+	}
+	var result []string
+	for _, col := range tableSchema.Columns() {
+		if col.PrimaryKey && !f.columns.Contains(col.ColumnName) {
+			result = append(result, col.ColumnName)
+		}
+	}
+	return result
+}
+
+func (f *dateShiftFunction) column(col abstract.ColSchema, tableSchema *abstract.TableSchema) (abstract.ColSchema, error) {
+	switch ytschema.Type(col.DataType) {
+	case ytschema.TypeDate, ytschema.TypeDatetime, ytschema.TypeTimestamp:
+	default:
+		return col, xerrors.Errorf("column %s of type %s cannot be shifted, only date, datetime and timestamp columns are supported", col.ColumnName, col.DataType)
+	}
+	seeds := f.seeds(tableSchema)
+	if len(seeds) == 0 {
+		return col, xerrors.New("table has no primary key columns which are not shifted, set seedColumns")
+	}
+	for _, seed := range seeds {
+		if _, ok := tableSchema.FastColumns()[abstract.ColumnName(seed)]; !ok {
+			return col, xerrors.Errorf("seed column %s is not found", seed)
+		}
+	}
+	return col, nil
+}
+
+func (f *dateShiftFunction) apply(value any, _ abstract.ColSchema, r row) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	t, ok := value.(time.Time)
+	if !ok {
+		return nil, xerrors.Errorf("value of type %T cannot be shifted", value)
+	}
+	days, err := f.shift(r)
+	if err != nil {
+		return nil, err
+	}
+	if f.unmask {
+		days = -days
+	}
+	return t.AddDate(0, 0, days), nil
+}
+
+// shift returns the number of days in [-maxDays, maxDays] derived from the values of the seed columns
+func (f *dateShiftFunction) shift(r row) (int, error) {
+	sig := hmac.New(sha256.New, f.key)
+	for _, seed := range f.seeds(r.tableSchema) {
+		value, ok := r.value(seed)
+		if !ok {
+			return 0, xerrors.Errorf("row has no value of seed column %s", seed)
+		}
+		colType := ""
+		if col, ok := r.tableSchema.FastColumns()[abstract.ColumnName(seed)]; ok {
+			colType = col.DataType
+		}
+		text := tostring.SerializeToString(value, colType)
+		_ = binary.Write(sig, binary.BigEndian, uint64(len(text)))
+		sig.Write([]byte(text))
+	}
+	sum := binary.BigEndian.Uint64(sig.Sum(nil))
+	return int(sum%uint64(2*f.maxDays+1)) - f.maxDays, nil
+}
+
+func (f *dateShiftFunction) description() string {
+	return fmt.Sprintf("date shift up to %d days", f.maxDays)
+}
+
+type nullFunction struct{}
+
+func (f *nullFunction) column(col abstract.ColSchema, _ *abstract.TableSchema) (abstract.ColSchema, error) {
+	if col.PrimaryKey {
+		return col, xerrors.Errorf("primary key column %s cannot be nulled", col.ColumnName)
+	}
+	col.Required = false
+	return col, nil
+}
+
+func (f *nullFunction) apply(any, abstract.ColSchema, row) (any, error) {
+	return nil, nil
+}
+
+func (f *nullFunction) description() string {
+	return "nulling"
+}
@@ -0,0 +1,88 @@
+package mask
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+)
+
+// Keys reference secret keys of a mask function. Keys are a JSON object of key IDs and base64-encoded keys,
+// e.g. `{"2024-01": "<base64>", "2024-07": "<base64>"}`, read from a local file or an environment variable.
+// Several keys allow rotation: values are masked with the key of KeyID and unmasked with the key they were masked with when it's known
+type Keys struct {
+	KeyFile string `json:"keyFile"`
+	KeyEnv  string `json:"keyEnv"`
+	// KeyID is the ID of the key values are masked with, it may be omitted if there is a single key
+	KeyID string `json:"keyId"`
+}
+
+// keyring is the set of loaded keys, id is the ID of the active key
+type keyring struct {
+	id   string
+	keys map[string][]byte
+}
+
+func (k *keyring) active() []byte {
+	return k.keys[k.id]
+}
+
+func loadKeys(config Keys) (*keyring, error) {
+	var data []byte
+	switch {
+	case config.KeyFile != "" && config.KeyEnv != "":
+		return nil, xerrors.New("keyFile and keyEnv cannot be set both")
+	case config.KeyFile != "":
+		var err error
+		data, err = os.ReadFile(config.KeyFile)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read key file: %w", err)
+		}
+	case config.KeyEnv != "":
+		value, ok := os.LookupEnv(config.KeyEnv)
+		if !ok {
+			return nil, xerrors.Errorf("environment variable %s is not set", config.KeyEnv)
+		}
+		data = []byte(value)
+	default:
+		return nil, xerrors.New("keys are not set, either keyFile or keyEnv is required")
+	}
+
+	var encoded map[string]string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, xerrors.Errorf("unable to parse keys, a JSON object of key IDs and base64-encoded keys is expected: %w", err)
+	}
+	keys := make(map[string][]byte, len(encoded))
+	for id, value := range encoded {
+		if id == "" || strings.Contains(id, sivSeparator) {
+			return nil, xerrors.Errorf("key ID %q must be non-empty and must not contain %q", id, sivSeparator)
+		}
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to decode key %s: %w", id, err)
+		}
+		keys[id] = key
+	}
+
+	id := config.KeyID
+	if id == "" {
+		if len(keys) != 1 {
+			ids := make([]string, 0, len(keys))
+			for id := range keys {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			return nil, xerrors.Errorf("keyId is required to choose one of keys %s", strings.Join(ids, ", "))
+		}
+		for keyID := range keys {
+			id = keyID
+		}
+	}
+	if _, ok := keys[id]; !ok {
+		return nil, xerrors.Errorf("key %s is not found", id)
+	}
+	return &keyring{id: id, keys: keys}, nil
+}
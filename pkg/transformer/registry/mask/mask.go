@@ -5,6 +5,7 @@ import (
 	"github.com/transferia/transferia/pkg/abstract"
 	"github.com/transferia/transferia/pkg/transformer"
 	"github.com/transferia/transferia/pkg/transformer/registry/filter"
+	"github.com/transferia/transferia/pkg/util/set"
 	"go.ytsaurus.tech/library/go/core/log"
 )
 
@@ -17,10 +18,16 @@ func init() {
 	)
 }
 
+// Config has a single mask function, columns are hashed with MaskFunctionHash if no other function is set
 type Config struct {
-	MaskFunctionHash MaskFunctionHash `json:"maskFunctionHash"`
-	Tables           filter.Tables    `json:"tables"`
-	Columns          []string         `json:"columns"`
+	MaskFunctionHash      MaskFunctionHash       `json:"maskFunctionHash"`
+	MaskFunctionFPE       *MaskFunctionFPE       `json:"maskFunctionFpe"`
+	MaskFunctionSIV       *MaskFunctionSIV       `json:"maskFunctionSiv"`
+	MaskFunctionPartial   *MaskFunctionPartial   `json:"maskFunctionPartial"`
+	MaskFunctionDateShift *MaskFunctionDateShift `json:"maskFunctionDateShift"`
+	MaskFunctionNull      *MaskFunctionNull      `json:"maskFunctionNull"`
+	Tables                filter.Tables          `json:"tables"`
+	Columns               []string               `json:"columns"`
 }
 
 type MaskFunctionHash struct {
@@ -34,9 +41,57 @@ func NewMaskTransformer(config Config, lgr log.Logger) (abstract.Transformer, er
 	}
 	columns := config.Columns
 
+	functions := 0
+	for _, isSet := range []bool{
+		config.MaskFunctionFPE != nil,
+		config.MaskFunctionSIV != nil,
+		config.MaskFunctionPartial != nil,
+		config.MaskFunctionDateShift != nil,
+		config.MaskFunctionNull != nil,
+	} {
+		if isSet {
+			functions++
+		}
+	}
+	if functions > 1 || (functions == 1 && config.MaskFunctionHash.UserDefinedSalt != "") {
+		return nil, xerrors.New("only one mask function can be set")
+	}
+	if functions == 1 {
+		if len(columns) == 0 {
+			return nil, xerrors.New("columns are not set")
+		}
+		columnsSet := set.New(columns...)
+		f, err := newMaskFunction(config, columnsSet)
+		if err != nil {
+			return nil, xerrors.Errorf("cannot make mask function: %w", err)
+		}
+		return &FunctionTransformer{
+			Tables:   tables,
+			Columns:  columnsSet,
+			function: f,
+			typ:      MaskFieldTransformerType,
+			lgr:      lgr,
+		}, nil
+	}
+
 	hashingTransformer, err := NewHmacHasherTransformer(config.MaskFunctionHash, lgr, tables, columns)
 	if err != nil {
 		return nil, xerrors.Errorf("cannot make hash transformer: %w", err)
 	}
 	return hashingTransformer, nil
 }
+
+func newMaskFunction(config Config, columns *set.Set[string]) (function, error) {
+	switch {
+	case config.MaskFunctionFPE != nil:
+		return newFPEFunction(*config.MaskFunctionFPE, false)
+	case config.MaskFunctionSIV != nil:
+		return newSIVFunction(*config.MaskFunctionSIV, false)
+	case config.MaskFunctionPartial != nil:
+		return newPartialFunction(*config.MaskFunctionPartial)
+	case config.MaskFunctionDateShift != nil:
+		return newDateShiftFunction(*config.MaskFunctionDateShift, columns, false)
+	default:
+		return new(nullFunction), nil
+	}
+}
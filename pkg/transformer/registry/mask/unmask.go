@@ -0,0 +1,63 @@
+package mask
+
+import (
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/transformer"
+	"github.com/transferia/transferia/pkg/transformer/registry/filter"
+	"github.com/transferia/transferia/pkg/util/set"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const UnmaskTransformerType = abstract.TransformerType("unmask")
+
+func init() {
+	transformer.Register[UnmaskConfig](
+		UnmaskTransformerType,
+		func(protoConfig UnmaskConfig, lgr log.Logger, runtime abstract.TransformationRuntimeOpts) (abstract.Transformer, error) {
+			return NewUnmaskTransformer(protoConfig, lgr)
+		},
+	)
+}
+
+// UnmaskConfig reverses a reversible mask function of mask_field, the function takes the same config as for masking
+type UnmaskConfig struct {
+	MaskFunctionFPE       *MaskFunctionFPE       `json:"maskFunctionFpe"`
+	MaskFunctionSIV       *MaskFunctionSIV       `json:"maskFunctionSiv"`
+	MaskFunctionDateShift *MaskFunctionDateShift `json:"maskFunctionDateShift"`
+	Tables                filter.Tables          `json:"tables"`
+	Columns               []string               `json:"columns"`
+}
+
+func NewUnmaskTransformer(config UnmaskConfig, lgr log.Logger) (abstract.Transformer, error) {
+	tables, err := filter.NewFilter(config.Tables.IncludeTables, config.Tables.ExcludeTables)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to init tables filter: %w", err)
+	}
+	if len(config.Columns) == 0 {
+		return nil, xerrors.New("columns are not set")
+	}
+	columns := set.New(config.Columns...)
+
+	var f function
+	switch {
+	case config.MaskFunctionFPE != nil && config.MaskFunctionSIV == nil && config.MaskFunctionDateShift == nil:
+		f, err = newFPEFunction(*config.MaskFunctionFPE, true)
+	case config.MaskFunctionSIV != nil && config.MaskFunctionFPE == nil && config.MaskFunctionDateShift == nil:
+		f, err = newSIVFunction(*config.MaskFunctionSIV, true)
+	case config.MaskFunctionDateShift != nil && config.MaskFunctionFPE == nil && config.MaskFunctionSIV == nil:
+		f, err = newDateShiftFunction(*config.MaskFunctionDateShift, columns, true)
+	default:
+		return nil, xerrors.New("exactly one of maskFunctionFpe, maskFunctionSiv and maskFunctionDateShift must be set")
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("cannot make unmask function: %w", err)
+	}
+	return &FunctionTransformer{
+		Tables:   tables,
+		Columns:  columns,
+		function: f,
+		typ:      UnmaskTransformerType,
+		lgr:      lgr,
+	}, nil
+}
@@ -1,11 +1,13 @@
 package registry
 
 import (
+	_ "github.com/transferia/transferia/pkg/transformer/registry/add_columns"
 	_ "github.com/transferia/transferia/pkg/transformer/registry/batch_splitter"
 	_ "github.com/transferia/transferia/pkg/transformer/registry/clickhouse"
 	_ "github.com/transferia/transferia/pkg/transformer/registry/custom"
 	_ "github.com/transferia/transferia/pkg/transformer/registry/filter"
 	_ "github.com/transferia/transferia/pkg/transformer/registry/filter_rows"
+	_ "github.com/transferia/transferia/pkg/transformer/registry/flatten"
 	_ "github.com/transferia/transferia/pkg/transformer/registry/logger"
 	_ "github.com/transferia/transferia/pkg/transformer/registry/mask"
 	_ "github.com/transferia/transferia/pkg/transformer/registry/number_to_float"
@@ -16,5 +18,6 @@ import (
 	_ "github.com/transferia/transferia/pkg/transformer/registry/sharder"
 	_ "github.com/transferia/transferia/pkg/transformer/registry/table_splitter"
 	_ "github.com/transferia/transferia/pkg/transformer/registry/to_string"
+	_ "github.com/transferia/transferia/pkg/transformer/registry/wasm"
 	_ "github.com/transferia/transferia/pkg/transformer/registry/yt_dict"
 )
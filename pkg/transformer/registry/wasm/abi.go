@@ -0,0 +1,224 @@
+package wasm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+// Column is a column of a table schema passed through the ABI, Type is a type of the transfer type system
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Key  bool   `json:"key"`
+}
+
+type Table struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// SchemaRequest is passed to transferia_schema, the response is SchemaResponse
+type SchemaRequest struct {
+	Table   Table    `json:"table"`
+	Columns []Column `json:"columns"`
+}
+
+type SchemaResponse struct {
+	Columns []Column `json:"columns"`
+}
+
+// TransformRequest is passed to transferia_transform, rows are of the same table & schema
+type TransformRequest struct {
+	Table   Table    `json:"table"`
+	Columns []Column `json:"columns"`
+	Rows    []Row    `json:"rows"`
+}
+
+type Row struct {
+	// Index is the index of the input row, output rows inherit its position in the source
+	Index   int            `json:"index"`
+	Kind    abstract.Kind  `json:"kind"`
+	Values  map[string]any `json:"values"`
+	OldKeys map[string]any `json:"old_keys,omitempty"`
+}
+
+// TransformResponse is returned by transferia_transform, input rows which are neither in Rows nor in Errors are dropped
+type TransformResponse struct {
+	Rows   []Row      `json:"rows"`
+	Errors []RowError `json:"errors"`
+}
+
+type RowError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+var knownTypes = map[schema.Type]bool{
+	schema.TypeInt64:     true,
+	schema.TypeInt32:     true,
+	schema.TypeInt16:     true,
+	schema.TypeInt8:      true,
+	schema.TypeUint64:    true,
+	schema.TypeUint32:    true,
+	schema.TypeUint16:    true,
+	schema.TypeUint8:     true,
+	schema.TypeFloat32:   true,
+	schema.TypeFloat64:   true,
+	schema.TypeBytes:     true,
+	schema.TypeString:    true,
+	schema.TypeBoolean:   true,
+	schema.TypeAny:       true,
+	schema.TypeDate:      true,
+	schema.TypeDatetime:  true,
+	schema.TypeTimestamp: true,
+	schema.TypeInterval:  true,
+}
+
+func toColumns(tableSchema *abstract.TableSchema) []Column {
+	columns := make([]Column, len(tableSchema.Columns()))
+	for i, col := range tableSchema.Columns() {
+		columns[i] = Column{Name: col.ColumnName, Type: col.DataType, Key: col.PrimaryKey}
+	}
+	return columns
+}
+
+func fromColumns(columns []Column) (*abstract.TableSchema, error) {
+	if len(columns) == 0 {
+		return nil, xerrors.New("schema has no columns")
+	}
+	result := make(abstract.TableColumns, len(columns))
+	for i, col := range columns {
+		if col.Name == "" {
+			return nil, xerrors.Errorf("column %d has no name", i)
+		}
+		typ := schema.Type(col.Type)
+		if !knownTypes[typ] {
+			return nil, xerrors.Errorf("column %s has unknown type %s", col.Name, col.Type)
+		}
+		result[i] = abstract.NewColSchema(col.Name, typ, col.Key)
+		result[i].OriginalType = "wasm:" + col.Type
+	}
+	return abstract.NewTableSchema(result), nil
+}
+
+func makeRequest(rows []abstract.ChangeItem) ([]byte, error) {
+	request := TransformRequest{
+		Table:   Table{Namespace: rows[0].Schema, Name: rows[0].Table},
+		Columns: toColumns(rows[0].TableSchema),
+		Rows:    make([]Row, len(rows)),
+	}
+	for i, row := range rows {
+		request.Rows[i] = Row{Index: i, Kind: row.Kind, Values: row.AsMap(), OldKeys: nil}
+		if len(row.OldKeys.KeyNames) > 0 {
+			request.Rows[i].OldKeys = make(map[string]any, len(row.OldKeys.KeyNames))
+			for j, name := range row.OldKeys.KeyNames {
+				request.Rows[i].OldKeys[name] = row.OldKeys.KeyValues[j]
+			}
+		}
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to marshal request: %w", err)
+	}
+	return data, nil
+}
+
+// parseResponse builds output rows of the result schema, output rows inherit metadata of their input rows
+func parseResponse(data []byte, input []abstract.ChangeItem, resultSchema *abstract.TableSchema) (abstract.TransformerResult, error) {
+	var response TransformResponse
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&response); err != nil {
+		return abstract.TransformerResult{Transformed: nil, Errors: nil}, xerrors.Errorf("unable to unmarshal response: %w", err)
+	}
+
+	result := abstract.TransformerResult{
+		Transformed: make([]abstract.ChangeItem, 0, len(response.Rows)),
+		Errors:      make([]abstract.TransformerError, 0, len(response.Errors)),
+	}
+	for _, rowErr := range response.Errors {
+		if rowErr.Index < 0 || rowErr.Index >= len(input) {
+			return result, xerrors.Errorf("error refers to row %d out of %d rows", rowErr.Index, len(input))
+		}
+		result.Errors = append(result.Errors, abstract.TransformerError{Input: input[rowErr.Index], Error: xerrors.New(rowErr.Error)})
+	}
+	columns := resultSchema.Columns()
+	for _, row := range response.Rows {
+		if row.Index < 0 || row.Index >= len(input) {
+			return result, xerrors.Errorf("row refers to row %d out of %d rows", row.Index, len(input))
+		}
+		item := input[row.Index]
+		if row.Kind != "" {
+			item.Kind = row.Kind
+		}
+		item.TableSchema = resultSchema
+		item.ColumnNames = nil
+		item.ColumnValues = nil
+		if item.Kind != abstract.DeleteKind || len(row.Values) > 0 {
+			item.ColumnNames = columns.ColumnNames()
+			item.ColumnValues = make([]any, len(columns))
+			for i, col := range columns {
+				value, err := restore(col, row.Values[col.ColumnName])
+				if err != nil {
+					return result, xerrors.Errorf("unable to restore row %d: %w", row.Index, err)
+				}
+				item.ColumnValues[i] = value
+			}
+		}
+		if row.OldKeys != nil {
+			item.OldKeys = abstract.OldKeysType{KeyNames: nil, KeyTypes: nil, KeyValues: nil}
+			for _, col := range columns {
+				value, ok := row.OldKeys[col.ColumnName]
+				if !ok {
+					continue
+				}
+				restored, err := restore(col, value)
+				if err != nil {
+					return result, xerrors.Errorf("unable to restore old keys of row %d: %w", row.Index, err)
+				}
+				item.OldKeys.KeyNames = append(item.OldKeys.KeyNames, col.ColumnName)
+				item.OldKeys.KeyTypes = append(item.OldKeys.KeyTypes, col.DataType)
+				item.OldKeys.KeyValues = append(item.OldKeys.KeyValues, restored)
+			}
+		}
+		result.Transformed = append(result.Transformed, item)
+	}
+	return result, nil
+}
+
+// restore converts a JSON value into a value of the column type
+func restore(col abstract.ColSchema, value any) (result any, err error) {
+	if text, ok := value.(string); ok && col.DataType == string(schema.TypeBytes) {
+		// bytes are encoded in base64 the same way as encoding/json does
+		decoded, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, xerrors.Errorf("column %s: unable to decode base64: %w", col.ColumnName, err)
+		}
+		return decoded, nil
+	}
+	if number, ok := value.(json.Number); ok {
+		switch strings.ToLower(col.DataType) {
+		case string(schema.TypeFloat64), string(schema.TypeFloat32):
+			f, err := number.Float64()
+			if err != nil {
+				return nil, xerrors.Errorf("column %s: %w", col.ColumnName, err)
+			}
+			if col.DataType == string(schema.TypeFloat32) {
+				return float32(f), nil
+			}
+			return f, nil
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = xerrors.Errorf("unable to restore value %v of column %s of type %s: %v", value, col.ColumnName, col.DataType, r)
+		}
+	}()
+	return abstract.Restore(col, value), nil
+}
@@ -0,0 +1,304 @@
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	aws_s3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/providers/s3"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const (
+	// exports of the ABI, see README.md
+	allocExport     = "transferia_alloc"
+	freeExport      = "transferia_free"
+	transformExport = "transferia_transform"
+	schemaExport    = "transferia_schema"
+	// reactor modules initialize their runtime in _initialize, commands are not supported since _start exits
+	initializeExport = "_initialize"
+
+	pageSize = 64 * 1024
+
+	// maxLogLine limits lines of the module output, longer lines are split into several records
+	maxLogLine = 64 * 1024
+	// maxBinarySize & downloadTimeout limit downloads of modules from s3 & http(s) URLs
+	maxBinarySize   = 256 * 1024 * 1024
+	downloadTimeout = 5 * time.Minute
+)
+
+// module runs calls of a compiled module one by one in a single instance,
+// the instance is recreated after a failed call since its state is unknown after a trap or a timeout
+type module struct {
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	config   wazero.ModuleConfig
+	timeout  time.Duration
+	stdout   *logWriter
+	stderr   *logWriter
+
+	mutex    sync.Mutex
+	instance api.Module
+}
+
+func (m *module) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.runtime.Close(context.Background())
+}
+
+func (m *module) hasExport(name string) bool {
+	_, ok := m.compiled.ExportedFunctions()[name]
+	return ok
+}
+
+// call passes the payload to the exported function & returns its result, the call is limited by the timeout
+func (m *module) call(function string, payload []byte) ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	// the output of a call is not lost if its last line is not terminated
+	defer m.stdout.Flush()
+	defer m.stderr.Flush()
+	if m.instance == nil {
+		instance, err := m.runtime.InstantiateModule(ctx, m.compiled, m.config)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to instantiate module: %w", err)
+		}
+		m.instance = instance
+	}
+	result, err := m.callInstance(ctx, function, payload)
+	if err != nil {
+		if closeErr := m.instance.Close(context.Background()); closeErr != nil {
+			err = xerrors.Errorf("%w; unable to close instance: %v", err, closeErr)
+		}
+		m.instance = nil
+		if ctx.Err() != nil {
+			return nil, xerrors.Errorf("%s is not completed in %v: %w", function, m.timeout, err)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+func (m *module) callInstance(ctx context.Context, function string, payload []byte) ([]byte, error) {
+	ptr, err := m.write(ctx, payload)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to pass payload: %w", err)
+	}
+	packed, err := m.instance.ExportedFunction(function).Call(ctx, uint64(ptr), uint64(len(payload)))
+	if err != nil {
+		return nil, xerrors.Errorf("%s failed: %w", function, err)
+	}
+	if err := m.free(ctx, ptr, uint32(len(payload))); err != nil {
+		return nil, xerrors.Errorf("unable to free payload: %w", err)
+	}
+
+	resultPtr, resultLen := uint32(packed[0]>>32), uint32(packed[0])
+	data, ok := m.instance.Memory().Read(resultPtr, resultLen)
+	if !ok {
+		return nil, xerrors.Errorf("%s returned %d bytes at %d, which are out of memory of %d bytes", function, resultLen, resultPtr, m.instance.Memory().Size())
+	}
+	// the memory is reused by the next calls
+	result := bytes.Clone(data)
+	if err := m.free(ctx, resultPtr, resultLen); err != nil {
+		return nil, xerrors.Errorf("unable to free result: %w", err)
+	}
+	return result, nil
+}
+
+func (m *module) write(ctx context.Context, payload []byte) (uint32, error) {
+	res, err := m.instance.ExportedFunction(allocExport).Call(ctx, uint64(len(payload)))
+	if err != nil {
+		return 0, xerrors.Errorf("%s failed: %w", allocExport, err)
+	}
+	ptr := uint32(res[0])
+	if !m.instance.Memory().Write(ptr, payload) {
+		return 0, xerrors.Errorf("%s returned %d, which is out of memory of %d bytes", allocExport, ptr, m.instance.Memory().Size())
+	}
+	return ptr, nil
+}
+
+func (m *module) free(ctx context.Context, ptr, size uint32) error {
+	free := m.instance.ExportedFunction(freeExport)
+	if free == nil {
+		return nil
+	}
+	if _, err := free.Call(ctx, uint64(ptr), uint64(size)); err != nil {
+		return xerrors.Errorf("%s failed: %w", freeExport, err)
+	}
+	return nil
+}
+
+// logWriter writes output of the module into the log line by line, the unterminated line is kept until the next write or flush
+type logWriter struct {
+	logger log.Logger
+	stream string
+	buf    []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	rest := w.buf
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(rest[:i])
+		rest = rest[i+1:]
+	}
+	for len(rest) >= maxLogLine {
+		w.emit(rest[:maxLogLine])
+		rest = rest[maxLogLine:]
+	}
+	w.buf = append(w.buf[:0], rest...)
+	return len(p), nil
+}
+
+// Flush writes the unterminated line
+func (w *logWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+	}
+	w.buf = nil
+}
+
+func (w *logWriter) emit(line []byte) {
+	w.logger.Info("wasm module output", log.String("stream", w.stream), log.String("output", strings.TrimRight(string(line), "\r")))
+}
+
+func newLogWriter(lgr log.Logger, stream string) *logWriter {
+	return &logWriter{logger: lgr, stream: stream, buf: nil}
+}
+
+func newModule(cfg *Config, binary []byte, lgr log.Logger) (*module, error) {
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32((cfg.MemoryLimit+pageSize-1)/pageSize)).
+		WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		_ = runtime.Close(ctx)
+		return nil, xerrors.Errorf("unable to instantiate WASI: %w", err)
+	}
+	compiled, err := runtime.CompileModule(ctx, binary)
+	if err != nil {
+		_ = runtime.Close(ctx)
+		return nil, xerrors.Errorf("unable to compile module: %w", err)
+	}
+	for _, export := range []string{allocExport, transformExport} {
+		if _, ok := compiled.ExportedFunctions()[export]; !ok {
+			_ = runtime.Close(ctx)
+			return nil, xerrors.Errorf("module does not export %s function", export)
+		}
+	}
+	if _, ok := compiled.ExportedMemories()["memory"]; !ok {
+		_ = runtime.Close(ctx)
+		return nil, xerrors.New("module does not export memory")
+	}
+	stdout := newLogWriter(lgr, "stdout")
+	stderr := newLogWriter(lgr, "stderr")
+	return &module{
+		runtime:  runtime,
+		compiled: compiled,
+		config: wazero.NewModuleConfig().
+			WithName("").
+			WithStartFunctions(initializeExport).
+			WithStdout(stdout).
+			WithStderr(stderr).
+			WithSysWalltime().
+			WithSysNanotime(),
+		timeout:  cfg.Timeout(),
+		stdout:   stdout,
+		stderr:   stderr,
+		mutex:    sync.Mutex{},
+		instance: nil,
+	}, nil
+}
+
+// loadBinary reads the module from a local path, file://, s3:// or http(s):// URL & verifies its checksum if any
+func loadBinary(cfg *Config, lgr log.Logger) ([]byte, error) {
+	binary, err := readBinary(cfg, lgr)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read module %s: %w", cfg.Module, err)
+	}
+	if cfg.SHA256 != "" {
+		sum := sha256.Sum256(binary)
+		if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, cfg.SHA256) {
+			return nil, xerrors.Errorf("checksum of module %s mismatch: expected %s, actual %s", cfg.Module, cfg.SHA256, actual)
+		}
+	}
+	return binary, nil
+}
+
+func readBinary(cfg *Config, lgr log.Logger) ([]byte, error) {
+	location, err := url.Parse(cfg.Module)
+	if err != nil || location.Scheme == "" {
+		return os.ReadFile(cfg.Module)
+	}
+	switch location.Scheme {
+	case "file":
+		return os.ReadFile(location.Path)
+	case "http", "https":
+		client := &http.Client{Timeout: downloadTimeout}
+		resp, err := client.Get(cfg.Module)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to download: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, xerrors.Errorf("unable to download: %s", resp.Status)
+		}
+		return readLimited(resp.Body, maxBinarySize)
+	case "s3":
+		var connection s3.ConnectionConfig
+		if cfg.S3 != nil {
+			connection = *cfg.S3
+		}
+		sess, err := s3.NewAWSSession(lgr, location.Host, connection)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to create session: %w", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), downloadTimeout)
+		defer cancel()
+		obj, err := aws_s3.New(sess).GetObjectWithContext(ctx, &aws_s3.GetObjectInput{
+			Bucket: aws.String(location.Host),
+			Key:    aws.String(strings.TrimPrefix(location.Path, "/")),
+		})
+		if err != nil {
+			return nil, xerrors.Errorf("unable to get object: %w", err)
+		}
+		defer obj.Body.Close()
+		return readLimited(obj.Body, maxBinarySize)
+	default:
+		return nil, xerrors.Errorf("unsupported scheme %s", location.Scheme)
+	}
+}
+
+// readLimited reads the downloaded module, modules larger than limit are rejected
+func readLimited(body io.Reader, limit int64) ([]byte, error) {
+	binary, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read: %w", err)
+	}
+	if int64(len(binary)) > limit {
+		return nil, xerrors.Errorf("module is larger than %d bytes", limit)
+	}
+	return binary, nil
+}
@@ -0,0 +1,110 @@
+// Guest module of the wasm transformer tests, it's built by the tests with:
+// GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o guest.wasm main.go
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"unsafe"
+)
+
+// buffers keep allocated memory alive until it's freed by the host
+var buffers = map[uint32][]byte{}
+
+//go:wasmexport transferia_alloc
+func alloc(size uint32) uint32 {
+	buf := make([]byte, size+1)
+	ptr := uint32(uintptr(unsafe.Pointer(unsafe.SliceData(buf))))
+	buffers[ptr] = buf
+	return ptr
+}
+
+//go:wasmexport transferia_free
+func free(ptr, _ uint32) {
+	delete(buffers, ptr)
+}
+
+func input(ptr, size uint32) []byte {
+	return buffers[ptr][:size]
+}
+
+func output(data []byte) uint64 {
+	ptr := alloc(uint32(len(data)))
+	copy(buffers[ptr], data)
+	return uint64(ptr)<<32 | uint64(len(data))
+}
+
+type column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Key  bool   `json:"key"`
+}
+
+type row struct {
+	Index  int            `json:"index"`
+	Kind   string         `json:"kind,omitempty"`
+	Values map[string]any `json:"values"`
+}
+
+type rowError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+//go:wasmexport transferia_schema
+func schema(ptr, size uint32) uint64 {
+	var request struct {
+		Columns []column `json:"columns"`
+	}
+	if err := json.Unmarshal(input(ptr, size), &request); err != nil {
+		panic(err)
+	}
+	request.Columns = append(request.Columns, column{Name: "name_length", Type: "int64", Key: false})
+	data, _ := json.Marshal(request)
+	return output(data)
+}
+
+// leak is never freed, it's used to break the memory limit
+var leak [][]byte
+
+//go:wasmexport transferia_transform
+func transform(ptr, size uint32) uint64 {
+	var request struct {
+		Rows []row `json:"rows"`
+	}
+	if err := json.Unmarshal(input(ptr, size), &request); err != nil {
+		panic(err)
+	}
+	var response struct {
+		Rows   []row      `json:"rows"`
+		Errors []rowError `json:"errors"`
+	}
+	response.Rows = []row{}
+	response.Errors = []rowError{}
+	for _, r := range request.Rows {
+		name, _ := r.Values["name"].(string)
+		switch name {
+		case "fail":
+			response.Errors = append(response.Errors, rowError{Index: r.Index, Error: "name is fail"})
+			continue
+		case "drop":
+			continue
+		case "loop":
+			for {
+			}
+		case "grow":
+			for {
+				leak = append(leak, make([]byte, 1024*1024))
+			}
+		case "twice":
+			response.Rows = append(response.Rows, row{Index: r.Index, Kind: "", Values: r.Values})
+		}
+		r.Values["name"] = strings.ToUpper(name)
+		r.Values["name_length"] = len(name)
+		response.Rows = append(response.Rows, row{Index: r.Index, Kind: "", Values: r.Values})
+	}
+	data, _ := json.Marshal(response)
+	return output(data)
+}
+
+func main() {}
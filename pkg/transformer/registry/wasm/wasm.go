@@ -0,0 +1,243 @@
+package wasm
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/providers/s3"
+	"github.com/transferia/transferia/pkg/transformer"
+	"github.com/transferia/transferia/pkg/transformer/registry/filter"
+	"go.ytsaurus.tech/library/go/core/log"
+)
+
+const (
+	Type = abstract.TransformerType("wasm")
+
+	defaultMemoryLimit    = 64 * 1024 * 1024
+	defaultTimeoutSeconds = 10
+	defaultBatchSize      = 1000
+)
+
+func init() {
+	transformer.Register[Config](Type, func(cfg Config, lgr log.Logger, _ abstract.TransformationRuntimeOpts) (abstract.Transformer, error) {
+		return New(cfg, lgr)
+	})
+}
+
+var (
+	//go:embed README.md
+	readme []byte
+	_      model.Describable = (*Config)(nil)
+)
+
+type Config struct {
+	Tables filter.Tables `json:"tables" yaml:"tables"`
+	// Module is a path or a file://, s3://bucket/key, http:// or https:// URL of the WASI module
+	Module string `json:"module" yaml:"module"`
+	// SHA256 is an optional hex digest of the module, the module is rejected if it does not match
+	SHA256 string `json:"sha256" yaml:"sha256"`
+	// S3 is the connection used to download s3:// modules, anonymous access is used if it's not set
+	S3 *s3.ConnectionConfig `json:"s3" yaml:"s3"`
+	// MemoryLimit is the limit of the memory of the module in bytes
+	MemoryLimit uint64 `json:"memoryLimit" yaml:"memory_limit"`
+	// TimeoutSeconds limits the time of a call of the module
+	TimeoutSeconds int `json:"timeoutSeconds" yaml:"timeout_seconds"`
+	// BatchSize is the maximum number of rows passed to the module in one call
+	BatchSize int `json:"batchSize" yaml:"batch_size"`
+}
+
+func (c *Config) WithDefaults() {
+	if c.MemoryLimit == 0 {
+		c.MemoryLimit = defaultMemoryLimit
+	}
+	if c.TimeoutSeconds == 0 {
+		c.TimeoutSeconds = defaultTimeoutSeconds
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = defaultBatchSize
+	}
+}
+
+func (c *Config) Timeout() time.Duration {
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+func (c Config) Describe() model.Doc {
+	return model.Doc{
+		Usage: string(readme),
+		Example: `
+tables:
+	include_tables:
+	- '"public"."users"'
+module: s3://my-bucket/transforms/users.wasm
+sha256: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+memoryLimit: 67108864
+timeoutSeconds: 10
+batchSize: 1000
+`,
+	}
+}
+
+type WasmTransformer struct {
+	tables    filter.Filter
+	module    *module
+	batchSize int
+	logger    log.Logger
+	name      string
+
+	// resultSchemas are result schemas by hashes of input schemas
+	schemaMutex   sync.Mutex
+	resultSchemas map[string]*abstract.TableSchema
+}
+
+func (t *WasmTransformer) Type() abstract.TransformerType {
+	return Type
+}
+
+func (t *WasmTransformer) Description() string {
+	return fmt.Sprintf("wasm module: %s", t.name)
+}
+
+func (t *WasmTransformer) Suitable(table abstract.TableID, _ *abstract.TableSchema) bool {
+	return filter.MatchAnyTableNameVariant(t.tables, table)
+}
+
+// ResultSchema calls transferia_schema of the module, the schema is not changed if the module does not export it
+func (t *WasmTransformer) ResultSchema(original *abstract.TableSchema) (*abstract.TableSchema, error) {
+	hash, err := original.Hash()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to hash schema: %w", err)
+	}
+	t.schemaMutex.Lock()
+	defer t.schemaMutex.Unlock()
+	if resultSchema, ok := t.resultSchemas[hash]; ok {
+		return resultSchema, nil
+	}
+
+	resultSchema := original
+	if t.module.hasExport(schemaExport) {
+		table := Table{Namespace: "", Name: ""}
+		if columns := original.Columns(); len(columns) > 0 {
+			table = Table{Namespace: columns[0].TableSchema, Name: columns[0].TableName}
+		}
+		request, err := json.Marshal(SchemaRequest{Table: table, Columns: toColumns(original)})
+		if err != nil {
+			return nil, xerrors.Errorf("unable to marshal schema request: %w", err)
+		}
+		data, err := t.module.call(schemaExport, request)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to call module: %w", err)
+		}
+		var response SchemaResponse
+		if err := json.Unmarshal(data, &response); err != nil {
+			return nil, xerrors.Errorf("unable to unmarshal schema response: %w", err)
+		}
+		if resultSchema, err = fromColumns(response.Columns); err != nil {
+			return nil, xerrors.Errorf("invalid result schema: %w", err)
+		}
+	}
+	t.resultSchemas[hash] = resultSchema
+	return resultSchema, nil
+}
+
+func (t *WasmTransformer) Apply(input []abstract.ChangeItem) abstract.TransformerResult {
+	result := abstract.TransformerResult{
+		Transformed: make([]abstract.ChangeItem, 0, len(input)),
+		Errors:      make([]abstract.TransformerError, 0),
+	}
+	// rows are passed to the module in batches, other items keep their places between batches
+	var batch []abstract.ChangeItem
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		batchResult := t.applyBatch(batch)
+		result.Transformed = append(result.Transformed, batchResult.Transformed...)
+		result.Errors = append(result.Errors, batchResult.Errors...)
+		batch = nil
+	}
+	for _, item := range input {
+		if !item.IsRowEvent() {
+			flush()
+			result.Transformed = append(result.Transformed, item)
+			continue
+		}
+		batch = append(batch, item)
+		if len(batch) >= t.batchSize {
+			flush()
+		}
+	}
+	flush()
+	return result
+}
+
+func (t *WasmTransformer) applyBatch(batch []abstract.ChangeItem) abstract.TransformerResult {
+	resultSchema, err := t.ResultSchema(batch[0].TableSchema)
+	if err != nil {
+		return allWithError(batch, xerrors.Errorf("unable to get result schema: %w", err))
+	}
+	request, err := makeRequest(batch)
+	if err != nil {
+		return allWithError(batch, err)
+	}
+	st := time.Now()
+	response, err := t.module.call(transformExport, request)
+	if err != nil {
+		t.logger.Warn("wasm module failed", log.String("module", t.name), log.Int("rows", len(batch)), log.Error(err))
+		return allWithError(batch, xerrors.Errorf("unable to call module: %w", err))
+	}
+	result, err := parseResponse(response, batch, resultSchema)
+	if err != nil {
+		return allWithError(batch, xerrors.Errorf("invalid module response: %w", err))
+	}
+	t.logger.Debugf("wasm module %s transformed %d rows into %d rows with %d errors in %v", t.name, len(batch), len(result.Transformed), len(result.Errors), time.Since(st))
+	return result
+}
+
+func allWithError(input []abstract.ChangeItem, err error) abstract.TransformerResult {
+	res := abstract.TransformerResult{
+		Transformed: nil,
+		Errors:      make([]abstract.TransformerError, len(input)),
+	}
+	for i, row := range input {
+		res.Errors[i] = abstract.TransformerError{
+			Input: row,
+			Error: err,
+		}
+	}
+	return res
+}
+
+func New(cfg Config, lgr log.Logger) (*WasmTransformer, error) {
+	cfg.WithDefaults()
+	if cfg.Module == "" {
+		return nil, xerrors.New("module is required")
+	}
+	tables, err := filter.NewFilter(cfg.Tables.IncludeTables, cfg.Tables.ExcludeTables)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create tables filter: %w", err)
+	}
+	binary, err := loadBinary(&cfg, lgr)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to load module: %w", err)
+	}
+	mod, err := newModule(&cfg, binary, lgr)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to init module %s: %w", cfg.Module, err)
+	}
+	return &WasmTransformer{
+		tables:        tables,
+		module:        mod,
+		batchSize:     cfg.BatchSize,
+		logger:        lgr,
+		name:          cfg.Module,
+		schemaMutex:   sync.Mutex{},
+		resultSchemas: map[string]*abstract.TableSchema{},
+	}, nil
+}
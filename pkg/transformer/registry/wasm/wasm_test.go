@@ -0,0 +1,228 @@
+package wasm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/xerrors"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/transformer/registry/filter"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	ytzap "go.ytsaurus.tech/library/go/core/log/zap"
+	"go.ytsaurus.tech/yt/go/schema"
+)
+
+var (
+	guestOnce sync.Once
+	guestPath string
+	guestErr  error
+)
+
+// buildGuest compiles testdata/guest into a WASI reactor once per test run
+func buildGuest(t *testing.T) string {
+	guestOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "wasm-guest")
+		if err != nil {
+			guestErr = err
+			return
+		}
+		guestPath = filepath.Join(dir, "guest.wasm")
+		cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", guestPath, "main.go")
+		cmd.Dir = filepath.Join("testdata", "guest")
+		cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			guestErr = xerrors.Errorf("%w: %s", err, output)
+		}
+	})
+	if guestErr != nil {
+		t.Skipf("unable to build guest module: %v", guestErr)
+	}
+	return guestPath
+}
+
+func newTestTransformer(t *testing.T, cfg Config) *WasmTransformer {
+	if cfg.Module == "" {
+		cfg.Module = buildGuest(t)
+	}
+	tr, err := New(cfg, logger.Log)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, tr.module.Close()) })
+	return tr
+}
+
+var testSchema = abstract.NewTableSchema([]abstract.ColSchema{
+	abstract.NewColSchema("id", schema.TypeInt64, true),
+	abstract.NewColSchema("name", schema.TypeString, false),
+	abstract.NewColSchema("score", schema.TypeFloat64, false),
+	abstract.NewColSchema("payload", schema.TypeBytes, false),
+})
+
+func testRow(id int64, name string) abstract.ChangeItem {
+	item := abstract.ChangeItemFromMap(map[string]interface{}{
+		"id":      id,
+		"name":    name,
+		"score":   1.5,
+		"payload": []byte{1, 2},
+	}, testSchema, "users", string(abstract.InsertKind))
+	item.Schema = "public"
+	item.LSN = uint64(id)
+	return item
+}
+
+func TestTransform(t *testing.T) {
+	tr := newTestTransformer(t, Config{Tables: filter.Tables{IncludeTables: []string{"users"}}})
+	require.True(t, tr.Suitable(abstract.TableID{Namespace: "public", Name: "users"}, testSchema))
+	require.False(t, tr.Suitable(abstract.TableID{Namespace: "public", Name: "orders"}, testSchema))
+
+	resultSchema, err := tr.ResultSchema(testSchema)
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "name", "score", "payload", "name_length"}, resultSchema.Columns().ColumnNames())
+	require.True(t, resultSchema.Columns()[0].PrimaryKey)
+	require.Equal(t, schema.TypeInt64.String(), resultSchema.Columns()[4].DataType)
+
+	done := abstract.ChangeItemFromMap(nil, testSchema, "users", string(abstract.DoneTableLoad))
+	res := tr.Apply([]abstract.ChangeItem{
+		testRow(1, "alice"),
+		testRow(2, "fail"),
+		testRow(3, "drop"),
+		testRow(4, "twice"),
+		done,
+	})
+	require.Len(t, res.Errors, 1)
+	require.Equal(t, uint64(2), res.Errors[0].Input.LSN)
+	require.EqualError(t, res.Errors[0].Error, "name is fail")
+
+	require.Len(t, res.Transformed, 4)
+	require.Equal(t, uint64(1), res.Transformed[0].LSN)
+	require.Equal(t, abstract.InsertKind, res.Transformed[0].Kind)
+	require.Equal(t, resultSchema, res.Transformed[0].TableSchema)
+	require.Equal(t, map[string]interface{}{
+		"id":          int64(1),
+		"name":        "ALICE",
+		"score":       1.5,
+		"payload":     []byte{1, 2},
+		"name_length": int64(5),
+	}, res.Transformed[0].AsMap())
+	require.Equal(t, uint64(4), res.Transformed[1].LSN)
+	require.Equal(t, uint64(4), res.Transformed[2].LSN)
+	require.Equal(t, abstract.DoneTableLoad, res.Transformed[3].Kind)
+}
+
+func TestBatches(t *testing.T) {
+	tr := newTestTransformer(t, Config{BatchSize: 2})
+	var input []abstract.ChangeItem
+	for i := int64(0); i < 5; i++ {
+		input = append(input, testRow(i, "bob"))
+	}
+	res := tr.Apply(input)
+	require.Empty(t, res.Errors)
+	require.Len(t, res.Transformed, 5)
+	for i, item := range res.Transformed {
+		require.Equal(t, int64(i), item.AsMap()["id"])
+	}
+}
+
+func TestLimits(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		tr := newTestTransformer(t, Config{TimeoutSeconds: 1})
+		res := tr.Apply([]abstract.ChangeItem{testRow(1, "alice"), testRow(2, "loop")})
+		require.Len(t, res.Errors, 2)
+		require.Contains(t, res.Errors[0].Error.Error(), "is not completed in 1s")
+
+		// the module is instantiated again after a failure
+		res = tr.Apply([]abstract.ChangeItem{testRow(1, "alice")})
+		require.Empty(t, res.Errors)
+		require.Len(t, res.Transformed, 1)
+	})
+	t.Run("memory", func(t *testing.T) {
+		tr := newTestTransformer(t, Config{MemoryLimit: 32 * 1024 * 1024})
+		res := tr.Apply([]abstract.ChangeItem{testRow(1, "grow")})
+		require.Len(t, res.Errors, 1)
+
+		res = tr.Apply([]abstract.ChangeItem{testRow(1, "alice")})
+		require.Empty(t, res.Errors)
+		require.Len(t, res.Transformed, 1)
+	})
+}
+
+func TestLoadBinary(t *testing.T) {
+	path := buildGuest(t)
+	binary, err := os.ReadFile(path)
+	require.NoError(t, err)
+	sum := sha256.Sum256(binary)
+
+	loaded, err := loadBinary(&Config{Module: "file://" + path, SHA256: hex.EncodeToString(sum[:])}, logger.Log)
+	require.NoError(t, err)
+	require.Equal(t, binary, loaded)
+
+	_, err = loadBinary(&Config{Module: path, SHA256: "00"}, logger.Log)
+	require.ErrorContains(t, err, "checksum")
+
+	_, err = loadBinary(&Config{Module: "ftp://host/guest.wasm"}, logger.Log)
+	require.ErrorContains(t, err, "unsupported scheme")
+}
+
+func TestLoadBinaryHTTP(t *testing.T) {
+	path := buildGuest(t)
+	binary, err := os.ReadFile(path)
+	require.NoError(t, err)
+	server := httptest.NewServer(http.FileServer(http.Dir(filepath.Dir(path))))
+	defer server.Close()
+
+	loaded, err := loadBinary(&Config{Module: server.URL + "/" + filepath.Base(path)}, logger.Log)
+	require.NoError(t, err)
+	require.Equal(t, binary, loaded)
+
+	_, err = loadBinary(&Config{Module: server.URL + "/absent.wasm"}, logger.Log)
+	require.ErrorContains(t, err, "404")
+}
+
+func TestReadLimited(t *testing.T) {
+	data, err := readLimited(strings.NewReader("0123456789"), 10)
+	require.NoError(t, err)
+	require.Equal(t, []byte("0123456789"), data)
+
+	_, err = readLimited(strings.NewReader("0123456789x"), 10)
+	require.ErrorContains(t, err, "larger than 10 bytes")
+}
+
+func TestLogWriter(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	w := newLogWriter(ytzap.NewWithCore(core), "stdout")
+	output := func() []string {
+		var result []string
+		for _, entry := range logs.TakeAll() {
+			result = append(result, entry.ContextMap()["output"].(string))
+		}
+		return result
+	}
+
+	_, err := w.Write([]byte("first line\nsecond "))
+	require.NoError(t, err)
+	require.Equal(t, []string{"first line"}, output())
+	_, err = w.Write([]byte("line\r\nthird"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"second line"}, output())
+	w.Flush()
+	require.Equal(t, []string{"third"}, output())
+	w.Flush()
+	require.Empty(t, output())
+
+	_, err = w.Write(bytes.Repeat([]byte("x"), maxLogLine+1))
+	require.NoError(t, err)
+	require.Equal(t, []string{strings.Repeat("x", maxLogLine)}, output())
+	w.Flush()
+	require.Equal(t, []string{"x"}, output())
+}
@@ -2,6 +2,7 @@ package transformer
 
 import (
 	"fmt"
+	"reflect"
 	"sync"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/abstract"
 	"github.com/transferia/transferia/pkg/abstract/changeitem"
+	"github.com/transferia/transferia/pkg/deadletter"
 	"github.com/transferia/transferia/pkg/stats"
 	"github.com/transferia/transferia/pkg/util"
 	"go.ytsaurus.tech/library/go/core/log"
@@ -17,11 +19,20 @@ import (
 
 const transformErrorColumn string = "__transform_error"
 
+// planResult is a result of a table plan, errors are accompanied by IDs of transformers which produced them
+type planResult struct {
+	transformed    []abstract.ChangeItem
+	errors         []abstract.TransformerError
+	transformerIDs []string
+}
+
 type transformation struct {
 	config       *Transformers
 	transformers []abstract.Transformer
 	plan         map[abstract.TableID]map[string][]abstract.Transformer
 	sink         abstract.Sinker
+	// deadLetters receives failed items in the destination errors output mode, it's shared between clones & closed by its owner
+	deadLetters *deadletter.Sink
 
 	registry metrics.Registry
 	sta      *stats.MiddlewareTransformerStats
@@ -38,6 +49,7 @@ func (u *transformation) clone() *transformation {
 		transformers: u.transformers,
 		plan:         make(map[abstract.TableID]map[string][]abstract.Transformer),
 		sink:         nil,
+		deadLetters:  u.deadLetters,
 
 		registry: u.registry,
 		sta:      stats.NewMiddlewareTransformerStats(u.registry),
@@ -139,20 +151,22 @@ func (u *transformation) Push(items []abstract.ChangeItem) error {
 		return xerrors.Errorf("unable to prepare transformation: %w", err)
 	}
 	tableItems := abstract.SplitByTableID(items)
-	result := make(chan chan abstract.TransformerResult, len(plans))
+	result := make(chan chan planResult, len(plans))
 
 	for tid, plan := range plans {
-		resICh := make(chan abstract.TransformerResult)
+		resICh := make(chan planResult)
 		result <- resICh
 		go u.do(tid, plan, tableItems[tid], resICh)
 	}
 
 	transformed := make([]abstract.ChangeItem, 0)
 	errors := make([]abstract.TransformerError, 0)
+	transformerIDs := make([]string, 0)
 	for i := 0; i < len(plans); i++ {
 		iRes := <-<-result
-		transformed = append(transformed, iRes.Transformed...)
-		errors = append(errors, iRes.Errors...)
+		transformed = append(transformed, iRes.transformed...)
+		errors = append(errors, iRes.errors...)
+		transformerIDs = append(transformerIDs, iRes.transformerIDs...)
 	}
 	close(result)
 
@@ -169,7 +183,7 @@ func (u *transformation) Push(items []abstract.ChangeItem) error {
 	u.logIfErrors(errors, "transformation of %d plans applied in %s; converted %d into %d items (%+d items), %d errors", len(plans), elapsed.String(), itemsIncomingCount, itemsTransformedCount, -itemsDroppedCount, len(errors))
 
 	if len(errors) > 0 {
-		if err := u.pushErrors(errors); err != nil {
+		if err := u.pushErrors(errors, transformerIDs); err != nil {
 			return xerrors.Errorf("failed to process transformation errors: %w", err)
 		}
 	}
@@ -192,7 +206,7 @@ func (u *transformation) logIfErrors(errors []abstract.TransformerError, msg str
 	lgr(msg, args...)
 }
 
-func (u *transformation) pushErrors(errors []abstract.TransformerError) error {
+func (u *transformation) pushErrors(errors []abstract.TransformerError, transformerIDs []string) error {
 	if len(errors) == 0 {
 		return nil
 	}
@@ -211,6 +225,13 @@ func (u *transformation) pushErrors(errors []abstract.TransformerError) error {
 		u.logger.Warn("transformation ignores errors", log.Int("len", len(errors)))
 		u.logger.Warnf("error sample: %v, \nitem: %s", errors[0].Error, errors[0].Input.ToJSONString())
 		return nil
+	case DestinationErrorsOutput:
+		if u.deadLetters == nil {
+			return xerrors.New("dead letter destination is not initialized")
+		}
+		if err := u.deadLetters.PushTransformerErrors(errors, transformerIDs); err != nil {
+			return xerrors.Errorf("failed to push untransformable (errorneous) items to dead letter destination: %w", err)
+		}
 	default:
 		return xerrors.Errorf("output format %s not implemented", u.config.ErrorsOutput.Type)
 	}
@@ -257,10 +278,27 @@ func errorChangeItems(errors []abstract.TransformerError) []abstract.ChangeItem
 	return res
 }
 
-func (u *transformation) do(tid abstract.TableID, tablePlans map[string][]abstract.Transformer, items []abstract.ChangeItem, resCh chan abstract.TransformerResult) {
-	result := abstract.TransformerResult{
-		Transformed: make([]abstract.ChangeItem, 0),
-		Errors:      make([]abstract.TransformerError, 0),
+// transformerID returns the ID of a transformer set in the config, or its type if the ID is not set
+func (u *transformation) transformerID(tr abstract.Transformer) string {
+	// transformers of non-comparable types can't be matched with their configs
+	if u.config != nil && reflect.TypeOf(tr).Comparable() {
+		for i, candidate := range u.transformers {
+			if i >= len(u.config.Transformers) || reflect.TypeOf(candidate) != reflect.TypeOf(tr) || candidate != tr {
+				continue
+			}
+			if id, ok := u.config.Transformers[i][ID].(string); ok && id != "" {
+				return id
+			}
+		}
+	}
+	return string(tr.Type())
+}
+
+func (u *transformation) do(tid abstract.TableID, tablePlans map[string][]abstract.Transformer, items []abstract.ChangeItem, resCh chan planResult) {
+	result := planResult{
+		transformed:    make([]abstract.ChangeItem, 0),
+		errors:         make([]abstract.TransformerError, 0),
+		transformerIDs: make([]string, 0),
 	}
 	input := items
 	u.logger.Debugf("for '%s' are '%v' plans to transform '%v' changeitems", tid.String(), len(tablePlans), len(input))
@@ -279,7 +317,10 @@ func (u *transformation) do(tid abstract.TableID, tablePlans map[string][]abstra
 		for _, tr := range tablePlans[currentSchemaHash] {
 			st := time.Now()
 			iResult := tr.Apply(toApply)
-			result.Errors = append(result.Errors, iResult.Errors...)
+			result.errors = append(result.errors, iResult.Errors...)
+			for range iResult.Errors {
+				result.transformerIDs = append(result.transformerIDs, u.transformerID(tr))
+			}
 			toApply = iResult.Transformed
 			u.logIfErrors(
 				iResult.Errors,
@@ -293,7 +334,7 @@ func (u *transformation) do(tid abstract.TableID, tablePlans map[string][]abstra
 				time.Since(st).Milliseconds(),
 			)
 		}
-		result.Transformed = append(result.Transformed, toApply...)
+		result.transformed = append(result.transformed, toApply...)
 		if i < len(input) {
 			lastIndex = i
 			currentSchemaHash = hash
@@ -330,6 +371,7 @@ func (u *transformation) RuntimeOpts() abstract.TransformationRuntimeOpts {
 
 func Sinker(
 	config *Transformers,
+	deadLetters *deadletter.Sink,
 	runtime abstract.TransformationRuntimeOpts,
 	transformers []abstract.Transformer,
 	lgr log.Logger,
@@ -341,6 +383,7 @@ func Sinker(
 			transformers: transformers,
 			plan:         make(map[abstract.TableID]map[string][]abstract.Transformer),
 			sink:         s,
+			deadLetters:  deadLetters,
 
 			registry: registry,
 			sta:      stats.NewMiddlewareTransformerStats(registry),
@@ -1,15 +1,20 @@
 package transformer_test
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/transferia/transferia/internal/logger"
 	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/library/go/core/xerrors"
 	"github.com/transferia/transferia/pkg/abstract"
 	"github.com/transferia/transferia/pkg/abstract/coordinator"
 	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/deadletter"
 	"github.com/transferia/transferia/pkg/middlewares"
+	"github.com/transferia/transferia/pkg/parsers/generic"
 	"github.com/transferia/transferia/pkg/sink"
 	transformers_registry "github.com/transferia/transferia/pkg/transformer"
 	"github.com/transferia/transferia/pkg/transformer/registry/filter"
@@ -108,3 +113,100 @@ func TestMultipleTransformers(t *testing.T) {
 	require.Equal(t, mockSinker.gotItems[1].ColumnValues, []interface{}{"test", 2, "{}"})
 
 }
+
+type failingTransformer struct{}
+
+func (f *failingTransformer) Apply(input []abstract.ChangeItem) abstract.TransformerResult {
+	result := abstract.TransformerResult{Transformed: nil, Errors: nil}
+	for _, item := range input {
+		if item.AsMap()["fail"] == true {
+			result.Errors = append(result.Errors, abstract.TransformerError{Input: item, Error: xerrors.New("fail is set")})
+			continue
+		}
+		result.Transformed = append(result.Transformed, item)
+	}
+	return result
+}
+func (f *failingTransformer) Suitable(abstract.TableID, *abstract.TableSchema) bool { return true }
+func (f *failingTransformer) ResultSchema(original *abstract.TableSchema) (*abstract.TableSchema, error) {
+	return original, nil
+}
+func (f *failingTransformer) Description() string            { return "failing transformer" }
+func (f *failingTransformer) Type() abstract.TransformerType { return "failing" }
+
+func TestDestinationErrorsOutput(t *testing.T) {
+	tableName := "test_table"
+	mainSinker := new(mockSinker)
+	dlqSinker := new(mockSinker)
+	transfer := &model.Transfer{
+		Src: &model.MockSource{},
+		Dst: &model.MockDestination{
+			SinkerFactory: func() abstract.Sinker { return mainSinker },
+			Cleanup:       model.Drop,
+		},
+		Transformation: &model.Transformation{
+			Transformers: &transformers_registry.Transformers{
+				DebugMode:    false,
+				Transformers: nil,
+				ErrorsOutput: &transformers_registry.ErrorsOutput{
+					Type: transformers_registry.DestinationErrorsOutput,
+					Config: &deadletter.Config{
+						Type: "mock",
+						Params: &model.MockDestination{
+							SinkerFactory: func() abstract.Sinker { return dlqSinker },
+							Cleanup:       model.DisabledCleanup,
+						},
+						Table: "errors",
+					},
+				},
+			},
+			ExtraTransformers: []abstract.Transformer{new(failingTransformer)},
+		},
+	}
+	asink, err := sink.MakeAsyncSink(
+		transfer,
+		logger.Log,
+		solomon.NewRegistry(solomon.NewRegistryOpts()),
+		coordinator.NewFakeClient(),
+		middlewares.MakeConfig(middlewares.WithNoData),
+	)
+	require.NoError(t, err)
+
+	tableSchema := abstract.NewTableSchema([]abstract.ColSchema{
+		{ColumnName: "id", PrimaryKey: true},
+		{ColumnName: "fail"},
+	})
+	partition := abstract.Partition{Cluster: "", Partition: 3, Topic: "topic"}
+	data := []abstract.ChangeItem{
+		abstract.ChangeItemFromMap(map[string]interface{}{"id": 1, "fail": false}, tableSchema, tableName, string(abstract.InsertKind)),
+		abstract.ChangeItemFromMap(map[string]interface{}{"id": 2, "fail": true}, tableSchema, tableName, string(abstract.InsertKind)),
+		generic.NewUnparsed(partition, "topic", "not a json", "unexpected token", 0, 42, time.Now()),
+	}
+	require.NoError(t, <-asink.AsyncPush(data))
+	require.NoError(t, asink.Close())
+
+	require.Len(t, mainSinker.gotItems, 1)
+	require.Equal(t, 1, mainSinker.gotItems[0].AsMap()["id"])
+
+	require.Len(t, dlqSinker.gotItems, 2)
+	var rows []*deadletter.Row
+	for _, item := range dlqSinker.gotItems {
+		require.Equal(t, "errors", item.Table)
+		row, err := deadletter.ParseRow(item)
+		require.NoError(t, err)
+		rows = append(rows, row)
+	}
+	require.Equal(t, deadletter.OriginParser, rows[0].Origin)
+	require.Equal(t, "not a json", rows[0].Payload)
+	require.Equal(t, "unexpected token", rows[0].Error)
+	require.Equal(t, uint64(42), rows[0].Position.Offset)
+	require.Equal(t, &partition, rows[0].Position.Partition)
+
+	require.Equal(t, deadletter.OriginTransformer, rows[1].Origin)
+	require.Equal(t, "failing", rows[1].TransformerID)
+	require.Equal(t, "fail is set", rows[1].Error)
+	restored, err := rows[1].ChangeItem()
+	require.NoError(t, err)
+	require.Equal(t, tableName, restored.Table)
+	require.Equal(t, json.Number("2"), restored.AsMap()["id"])
+}
@@ -0,0 +1,73 @@
+// Package positions tracks positions of rows in source streams, like LSN of CDC sources or offsets of queue partitions
+package positions
+
+import (
+	"fmt"
+
+	"github.com/transferia/transferia/pkg/abstract"
+)
+
+// LSNStream is a name of the source stream of CDC sources, whose positions are LSN of rows
+const LSNStream = "lsn"
+
+// Position of a row in the source stream, rows of a stream are delivered in order of positions
+type Position struct {
+	Offset uint64
+	Index  uint64
+}
+
+func (p Position) Less(other Position) bool {
+	if p.Offset != other.Offset {
+		return p.Offset < other.Offset
+	}
+	return p.Index < other.Index
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d.%d", p.Offset, p.Index)
+}
+
+// RowPosition returns the source stream and position of the row, snapshot rows have no position
+func RowPosition(row *abstract.ChangeItem) (string, Position, bool) {
+	if row.QueueMessageMeta.TopicName != "" {
+		stream := fmt.Sprintf("%s/%d", row.QueueMessageMeta.TopicName, row.QueueMessageMeta.PartitionNum)
+		return stream, Position{Offset: row.QueueMessageMeta.Offset, Index: uint64(row.QueueMessageMeta.Index)}, true
+	}
+	if row.LSN != 0 {
+		return LSNStream, Position{Offset: row.LSN, Index: uint64(row.Counter)}, true
+	}
+	return "", Position{Offset: 0, Index: 0}, false
+}
+
+// Watermarks are the last committed positions by source streams
+type Watermarks map[string]Position
+
+// Filter drops rows at or before watermarks of their streams, rows without position are kept
+func (w Watermarks) Filter(items []abstract.ChangeItem) []abstract.ChangeItem {
+	result := make([]abstract.ChangeItem, 0, len(items))
+	for i := range items {
+		stream, position, ok := RowPosition(&items[i])
+		if ok {
+			if watermark, committed := w[stream]; committed && !watermark.Less(position) {
+				continue
+			}
+		}
+		result = append(result, items[i])
+	}
+	return result
+}
+
+// Advance returns the last positions of streams of the rows
+func Advance(items []abstract.ChangeItem) Watermarks {
+	result := Watermarks{}
+	for i := range items {
+		stream, position, ok := RowPosition(&items[i])
+		if !ok {
+			continue
+		}
+		if last, found := result[stream]; !found || last.Less(position) {
+			result[stream] = position
+		}
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+package positions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/abstract"
+)
+
+func queueRow(partition int, offset uint64, id int) abstract.ChangeItem {
+	row := abstract.ChangeItem{
+		Kind:         abstract.InsertKind,
+		Table:        "t",
+		ColumnNames:  []string{"id"},
+		ColumnValues: []any{id},
+	}
+	row.FillQueueMessageMeta("topic", partition, offset, 0)
+	return row
+}
+
+func TestRowPosition(t *testing.T) {
+	stream, position, ok := RowPosition(&abstract.ChangeItem{LSN: 10, Counter: 2})
+	require.True(t, ok)
+	require.Equal(t, LSNStream, stream)
+	require.Equal(t, Position{Offset: 10, Index: 2}, position)
+
+	row := queueRow(3, 7, 1)
+	stream, position, ok = RowPosition(&row)
+	require.True(t, ok)
+	require.Equal(t, "topic/3", stream)
+	require.Equal(t, Position{Offset: 7, Index: 0}, position)
+
+	_, _, ok = RowPosition(&abstract.ChangeItem{Kind: abstract.InsertKind})
+	require.False(t, ok)
+}
+
+func TestWatermarks(t *testing.T) {
+	items := []abstract.ChangeItem{
+		queueRow(0, 1, 1),
+		queueRow(0, 2, 2),
+		queueRow(1, 1, 3),
+		{Kind: abstract.InsertKind, ColumnValues: []any{4}},
+	}
+	filtered := Watermarks{"topic/0": {Offset: 1, Index: 0}}.Filter(items)
+	require.Len(t, filtered, 3)
+	require.Equal(t, 2, filtered[0].ColumnValues[0])
+
+	require.Equal(t, Watermarks{
+		"topic/0": {Offset: 2, Index: 0},
+		"topic/1": {Offset: 1, Index: 0},
+	}, Advance(items))
+}
@@ -64,12 +64,7 @@ func SniffReplicationData(
 				ctx:    context.Background(),
 				cancel: func() {},
 			}
-			transfer.Transformation.Transformers.ErrorsOutput = &transformer.ErrorsOutput{
-				// force sink error, so user can see all transformer errors in discover results
-				Type:   transformer.SinkErrorsOutput,
-				Config: nil,
-			}
-			wrapper, err := middlewares.Transformation(transfer, logger.Log, solomon.NewRegistry(solomon.NewRegistryOpts()))
+			wrapper, err := middlewares.Transformation(withSinkErrorsOutput(transfer), logger.Log, solomon.NewRegistry(solomon.NewRegistryOpts()), nil)
 			if err != nil {
 				return tr.NotOk(ConfigCheckType, xerrors.Errorf("unable to assign transformer: %w", err))
 			}
@@ -88,6 +83,21 @@ func SniffReplicationData(
 	return tr
 }
 
+// withSinkErrorsOutput returns a copy of the transfer with errors output forced to sink,
+// so user can see all transformer errors in discover results instead of devnull or dead letter destination
+func withSinkErrorsOutput(transfer *model.Transfer) *model.Transfer {
+	transformers := *transfer.Transformation.Transformers
+	transformers.ErrorsOutput = &transformer.ErrorsOutput{
+		Type:   transformer.SinkErrorsOutput,
+		Config: nil,
+	}
+	transformation := *transfer.Transformation
+	transformation.Transformers = &transformers
+	result := *transfer
+	result.Transformation = &transformation
+	return &result
+}
+
 var _ abstract.Sinker = (*sampleCollector)(nil)
 
 type sampleCollector struct {
@@ -128,6 +138,10 @@ func SniffSnapshotData(ctx context.Context, tr *abstract.TestResult, transfer *m
 	tr.Ok(ConfigCheckType)
 	tr.Preview = map[abstract.TableID][]abstract.ChangeItem{}
 	defer sourceStorage.Close()
+	previewTransfer := transfer
+	if transfer.HasPublicTransformation() {
+		previewTransfer = withSinkErrorsOutput(transfer)
+	}
 	var errs util.Errors
 	previewSchemas := abstract.TableMap{}
 	for table := range tables {
@@ -137,7 +151,7 @@ func SniffSnapshotData(ctx context.Context, tr *abstract.TestResult, transfer *m
 			ctx:    cctx,
 			cancel: cancel,
 		}
-		wrapper, err := middlewares.Transformation(transfer, logger.Log, metrics)
+		wrapper, err := middlewares.Transformation(previewTransfer, logger.Log, metrics, nil)
 		if err != nil {
 			return tr.NotOk(ConfigCheckType, xerrors.Errorf("unable to assign transformer: %w", err))
 		}
@@ -0,0 +1,23 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/pkg/abstract/model"
+	"github.com/transferia/transferia/pkg/transformer"
+)
+
+func TestWithSinkErrorsOutputKeepsTransfer(t *testing.T) {
+	devnull := &transformer.ErrorsOutput{Type: transformer.DevnullErrorsOutput, Config: nil}
+	transfer := &model.Transfer{ID: "dtt", Transformation: &model.Transformation{
+		Transformers:      &transformer.Transformers{DebugMode: false, Transformers: nil, ErrorsOutput: devnull},
+		ExtraTransformers: nil,
+		RuntimeJobIndex:   0,
+	}}
+
+	preview := withSinkErrorsOutput(transfer)
+	require.Equal(t, "dtt", preview.ID)
+	require.Equal(t, transformer.SinkErrorsOutput, preview.Transformation.Transformers.ErrorsOutput.Type)
+	require.Same(t, devnull, transfer.Transformation.Transformers.ErrorsOutput)
+}
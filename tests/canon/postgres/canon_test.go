@@ -42,6 +42,18 @@ func TestCanonSource(t *testing.T) {
 		))
 	}()
 
+	runTableCases(t, Source)
+
+	// pgoutput must produce exactly the same rows as wal2json
+	pgOutputSource := *Source
+	pgOutputSource.SlotID = "test_slot_id_pgoutput"
+	pgOutputSource.Plugin = postgres.PgOutputPlugin
+	t.Run("pgoutput", func(t *testing.T) {
+		runTableCases(t, &pgOutputSource)
+	})
+}
+
+func runTableCases(t *testing.T, Source *postgres.PgSource) {
 	tableCase := func(tableName string) func(t *testing.T) {
 		return func(t *testing.T) {
 			conn, err := postgres.MakeConnPoolFromSrc(Source, logger.Log)
@@ -0,0 +1,96 @@
+package bulkupsert
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/transferia/transferia/internal/logger"
+	"github.com/transferia/transferia/library/go/core/metrics/solomon"
+	"github.com/transferia/transferia/pkg/abstract"
+	"github.com/transferia/transferia/pkg/providers/postgres"
+	"github.com/transferia/transferia/pkg/providers/postgres/pgrecipe"
+)
+
+var tableSchema = abstract.NewTableSchema([]abstract.ColSchema{
+	{TableSchema: "public", TableName: "t", ColumnName: "id", DataType: "int64", OriginalType: "pg:bigint", PrimaryKey: true},
+	{TableSchema: "public", TableName: "t", ColumnName: "name", DataType: "utf8", OriginalType: "pg:text"},
+	{TableSchema: "public", TableName: "t", ColumnName: "payload", DataType: "utf8", OriginalType: "pg:text"},
+})
+
+func item(kind abstract.Kind, txID uint32, lsn uint64, names []string, values []any, oldID int64) abstract.ChangeItem {
+	result := abstract.ChangeItem{
+		ID:           txID,
+		LSN:          lsn,
+		Kind:         kind,
+		Schema:       "public",
+		Table:        "t",
+		ColumnNames:  names,
+		ColumnValues: values,
+		TableSchema:  tableSchema,
+		OldKeys:      abstract.EmptyOldKeys(),
+	}
+	if oldID != 0 {
+		result.OldKeys = abstract.OldKeysType{KeyNames: []string{"id"}, KeyTypes: []string{"bigint"}, KeyValues: []any{oldID}}
+	}
+	return result
+}
+
+type row struct {
+	id      int64
+	name    string
+	payload string
+}
+
+func readRows(t *testing.T, dst *postgres.PgDestination) []row {
+	conn, err := postgres.MakeConnPoolFromDst(dst, logger.Log)
+	require.NoError(t, err)
+	defer conn.Close()
+	rows, err := conn.Query(context.Background(), "select id, name, payload from t order by id")
+	require.NoError(t, err)
+	defer rows.Close()
+	var result []row
+	for rows.Next() {
+		var r row
+		require.NoError(t, rows.Scan(&r.id, &r.name, &r.payload))
+		result = append(result, r)
+	}
+	require.NoError(t, rows.Err())
+	return result
+}
+
+// TestPerTransactionBulkUpsert applies inserts, updates, deletes, key changes and TOASTed updates through the staging table
+func TestPerTransactionBulkUpsert(t *testing.T) {
+	dst := pgrecipe.RecipeTarget(pgrecipe.WithInitDir("dump"))
+	dst.PerTransactionPush = true
+	dst.BulkUpsert = true
+	dst.DisableSQLFallback = true
+
+	sink, err := postgres.NewSink(logger.Log, "bulk_upsert", dst.ToSinkParams(), solomon.NewRegistry(solomon.NewRegistryOpts()))
+	require.NoError(t, err)
+	defer sink.Close()
+
+	all := []string{"id", "name", "payload"}
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		item(abstract.InsertKind, 1, 1, all, []any{int64(10), "j", "p"}, 0),
+		// TOASTed payload is not present in the update and must keep its value
+		item(abstract.UpdateKind, 1, 2, []string{"id", "name"}, []any{int64(1), "a2"}, 1),
+		// key change
+		item(abstract.UpdateKind, 1, 3, all, []any{int64(20), "b", "y"}, 2),
+		item(abstract.DeleteKind, 1, 4, nil, nil, 3),
+		item(abstract.UpdateKind, 1, 5, all, []any{int64(4), "d2", "w2"}, 4),
+		item(abstract.UpdateKind, 1, 6, all, []any{int64(10), "j2", "p2"}, 10),
+	}))
+	// the next transaction commits the previous one
+	require.NoError(t, sink.Push([]abstract.ChangeItem{
+		item(abstract.InsertKind, 2, 7, all, []any{int64(30), "k", "q"}, 0),
+	}))
+
+	require.Equal(t, []row{
+		{id: 1, name: "a2", payload: strings.Repeat("x", 100000)},
+		{id: 4, name: "d2", payload: "w2"},
+		{id: 10, name: "j2", payload: "p2"},
+		{id: 20, name: "b", payload: "y"},
+	}, readRows(t, dst))
+}
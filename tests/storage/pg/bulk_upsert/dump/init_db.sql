@@ -0,0 +1,7 @@
+CREATE TABLE t(id BIGINT PRIMARY KEY, name TEXT, payload TEXT);
+
+INSERT INTO t VALUES
+    (1, 'a', repeat('x', 100000)),
+    (2, 'b', 'y'),
+    (3, 'c', 'z'),
+    (4, 'd', 'w');